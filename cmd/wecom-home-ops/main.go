@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"net/http"
@@ -15,17 +16,26 @@ import (
 	"strings"
 	"syscall"
 	"time"
+	// 镜像为 scratch，内嵌时区数据以支持 core.scheduler.timezone。
+	_ "time/tzdata"
 
 	"github.com/zcw199604/wecom-home-ops/internal/app"
 	"github.com/zcw199604/wecom-home-ops/internal/config"
 	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+
+	// 服务后端：在 init 中向 registry 登记，新增后端只需在此追加导入。
+	_ "github.com/zcw199604/wecom-home-ops/internal/pve"
+	_ "github.com/zcw199604/wecom-home-ops/internal/qinglong"
+	_ "github.com/zcw199604/wecom-home-ops/internal/unraid"
 )
 
 func main() {
 	var configPath string
 	var wecomSyncMenu bool
+	var wecomMenuDiff bool
 	flag.StringVar(&configPath, "config", "config.yaml", "配置文件路径（YAML）")
 	flag.BoolVar(&wecomSyncMenu, "wecom-sync-menu", false, "同步企业微信应用自定义菜单（menu/create）后退出")
+	flag.BoolVar(&wecomMenuDiff, "wecom-menu-diff", false, "读取当前应用自定义菜单（menu/get）并输出同步后的变更，不做修改")
 	flag.Parse()
 
 	startedAt := time.Now()
@@ -46,7 +56,13 @@ func main() {
 	}))
 	slog.SetDefault(logger)
 
-	if wecomSyncMenu {
+	if wecomSyncMenu || wecomMenuDiff {
+		menu, err := app.NewMenu(cfg)
+		if err != nil {
+			slog.Error("生成企业微信自定义菜单失败", "error", err)
+			os.Exit(1)
+		}
+
 		httpClient := &http.Client{
 			Timeout: cfg.Server.HTTPClientTimeout.ToDuration(),
 		}
@@ -60,7 +76,17 @@ func main() {
 		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 		defer cancel()
 
-		if err := wecomClient.CreateMenu(ctx, wecom.DefaultMenu()); err != nil {
+		if wecomMenuDiff {
+			current, err := wecomClient.GetMenu(ctx)
+			if err != nil {
+				slog.Error("读取企业微信自定义菜单失败", "error", err)
+				os.Exit(1)
+			}
+			printMenuDiff(os.Stdout, current, menu)
+			return
+		}
+
+		if err := wecomClient.CreateMenu(ctx, menu); err != nil {
 			slog.Error("企业微信自定义菜单同步失败", "error", err)
 			os.Exit(1)
 		}
@@ -107,12 +133,23 @@ func main() {
 	}
 }
 
-func sendStartupSuccessNotification(ctx context.Context, cfg config.Config, configPath string, listenerAddr string, startedAt, readyAt time.Time) {
-	userIDs := uniqueNonEmpty(cfg.Auth.AllowedUserIDs)
-	if len(userIDs) == 0 {
+// printMenuDiff 输出当前菜单与待同步菜单的逐行差异（“-”将删除，“+”将新增）。
+func printMenuDiff(w io.Writer, current, next wecom.Menu) {
+	diff := wecom.DiffMenu(current, next)
+	if len(diff) == 0 {
+		fmt.Fprintln(w, "企业微信自定义菜单已是最新，无需同步。")
 		return
 	}
+	if len(current.Buttons) == 0 {
+		fmt.Fprintln(w, "当前应用尚未设置自定义菜单。")
+	}
+	fmt.Fprintln(w, "同步后的菜单变更（-wecom-sync-menu 生效）：")
+	for _, line := range diff {
+		fmt.Fprintln(w, line)
+	}
+}
 
+func sendStartupSuccessNotification(ctx context.Context, cfg config.Config, configPath string, listenerAddr string, startedAt, readyAt time.Time) {
 	httpClient := &http.Client{
 		Timeout: cfg.Server.HTTPClientTimeout.ToDuration(),
 	}
@@ -123,6 +160,19 @@ func sendStartupSuccessNotification(ctx context.Context, cfg config.Config, conf
 		Secret:     cfg.WeCom.Secret,
 	}, httpClient)
 
+	authorizer, err := app.NewAuthorizer(cfg.Auth, wecom.NewDirectory(wecom.DirectoryDeps{
+		Client: wecomClient,
+		TTL:    cfg.Auth.DirectoryTTL.ToDuration(),
+	}))
+	if err != nil {
+		slog.Error("启动成功通知跳过：授权配置无效", "error", err)
+		return
+	}
+	userIDs := uniqueNonEmpty(authorizer.Users())
+	if len(userIDs) == 0 {
+		return
+	}
+
 	content := buildStartupSuccessMessage(cfg, configPath, listenerAddr, startedAt, readyAt)
 	toUser := strings.Join(userIDs, "|")
 
@@ -169,11 +219,17 @@ func buildStartupSuccessMessage(cfg config.Config, configPath string, listenerAd
 		b.WriteString("\nBaseURL: <未配置>\n")
 	}
 
-	unraidEnabled := strings.TrimSpace(cfg.Unraid.Endpoint) != "" && strings.TrimSpace(cfg.Unraid.APIKey) != ""
 	b.WriteString("\n后端:\n")
-	if unraidEnabled {
+	switch {
+	case len(cfg.Unraid.Instances) > 0:
+		var instances []string
+		for _, ins := range cfg.Unraid.Instances {
+			instances = append(instances, strings.TrimSpace(ins.ID)+"("+strings.TrimSpace(ins.Name)+")")
+		}
+		fmt.Fprintf(&b, "- Unraid: %d 个实例（%s）\n", len(cfg.Unraid.Instances), strings.Join(instances, ", "))
+	case len(cfg.Unraid.EffectiveInstances()) > 0:
 		b.WriteString("- Unraid: 已启用\n")
-	} else {
+	default:
 		b.WriteString("- Unraid: 未启用\n")
 	}
 
@@ -8,25 +8,36 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/zcw199604/wecom-home-ops/internal/audit"
 	"github.com/zcw199604/wecom-home-ops/internal/config"
 	"github.com/zcw199604/wecom-home-ops/internal/core"
-	"github.com/zcw199604/wecom-home-ops/internal/pve"
-	"github.com/zcw199604/wecom-home-ops/internal/qinglong"
-	"github.com/zcw199604/wecom-home-ops/internal/unraid"
+	"github.com/zcw199604/wecom-home-ops/internal/registry"
+	"github.com/zcw199604/wecom-home-ops/internal/store"
 	"github.com/zcw199604/wecom-home-ops/internal/wecom"
 )
 
 type Server struct {
 	cfg        config.Config
 	server     *http.Server
-	stateStore *core.StateStore
-	deduper    *wecom.Deduper
-	pveAlerts  *pve.AlertManager
+	stateStore core.StateStore
+	kv         *store.FileStore
+	jobs       *core.JobRunner
+	audit      core.AuditSink
+	deduper    wecom.CallbackDeduper
+	services   []registry.Service
+	scheduler  *core.Scheduler
 }
 
 func NewServer(cfg config.Config) (*Server, error) {
+	if err := validateMenuServices(cfg.WeCom.Menu); err != nil {
+		return nil, err
+	}
+
 	httpClient := &http.Client{
 		Timeout: cfg.Server.HTTPClientTimeout.ToDuration(),
 	}
@@ -38,127 +49,115 @@ func NewServer(cfg config.Config) (*Server, error) {
 		Secret:     cfg.WeCom.Secret,
 	}, httpClient)
 
-	stateStore := core.NewStateStore(cfg.Core.StateTTL.ToDuration())
+	var (
+		stateStore    core.StateStore
+		deduper       wecom.CallbackDeduper
+		kv            *store.FileStore
+		scheduleStore core.ScheduleStore
+		historyStore  core.HistoryStore
+		favoriteStore core.FavoriteStore
+	)
+	switch strings.ToLower(strings.TrimSpace(cfg.Core.StateBackend)) {
+	case "file":
+		var err error
+		kv, err = store.Open(cfg.Core.StatePath)
+		if err != nil {
+			return nil, fmt.Errorf("打开持久化存储失败: %w", err)
+		}
+		stateStore = core.NewFileStateStore(kv, cfg.Core.StateTTL.ToDuration())
+		deduper = wecom.NewFileDeduper(kv, 10*time.Minute)
+		scheduleStore = core.NewFileScheduleStore(kv)
+		historyStore = core.NewFileHistoryStore(kv)
+		favoriteStore = core.NewFileFavoriteStore(kv)
+		slog.Info("会话状态、回调去重、定时任务、操作记录与收藏使用文件持久化", "path", kv.Path())
+	default:
+		stateStore = core.NewMemoryStateStore(cfg.Core.StateTTL.ToDuration())
+		deduper = wecom.NewDeduper(10 * time.Minute)
+		scheduleStore = core.NewMemoryScheduleStore()
+		historyStore = core.NewMemoryHistoryStore()
+		favoriteStore = core.NewMemoryFavoriteStore()
+	}
+	directory := wecom.NewDirectory(wecom.DirectoryDeps{
+		Client: wecomClient,
+		TTL:    cfg.Auth.DirectoryTTL.ToDuration(),
+	})
+	authorizer, err := NewAuthorizer(cfg.Auth, directory)
+	if err != nil {
+		return nil, err
+	}
+
+	// router 在 Provider 装配完成后创建；按钮过滤在发送时才调用，此处通过闭包延迟引用。
+	var router *core.Router
 	wecomSender := core.NewTemplateCardSender(core.TemplateCardSenderDeps{
 		Base:  wecomClient,
 		State: stateStore,
 		Mode:  core.TemplateCardMode(cfg.WeCom.TemplateCardMode),
+		ButtonFilter: func(userID, eventKey string) bool {
+			return router == nil || router.CanEvent(userID, eventKey)
+		},
+	})
+	auditSink, err := newAuditSink(cfg.Core.Audit)
+	if err != nil {
+		return nil, err
+	}
+	jobs := core.NewJobRunner(core.JobRunnerDeps{
+		WeCom:     wecomSender,
+		Audit:     auditSink,
+		Workers:   cfg.Core.Jobs.Workers,
+		QueueSize: cfg.Core.Jobs.QueueSize,
+		Timeout:   cfg.Core.Jobs.Timeout.ToDuration(),
 	})
-	deduper := wecom.NewDeduper(10 * time.Minute)
-
-	var providers []core.ServiceProvider
-
-	if cfg.Unraid.Endpoint != "" && cfg.Unraid.APIKey != "" {
-		unraidClient := unraid.NewClient(unraid.ClientConfig{
-			Endpoint: cfg.Unraid.Endpoint,
-			APIKey:   cfg.Unraid.APIKey,
-			Origin:   cfg.Unraid.Origin,
-
-			WebGUICommandURL: cfg.Unraid.WebGUICommandURL,
-			WebGUIEventsURL:  cfg.Unraid.WebGUIEventsURL,
-			WebGUICSRFToken:  cfg.Unraid.WebGUICSRFToken,
-			WebGUICookie:     cfg.Unraid.WebGUICookie,
-
-			LogsField:        cfg.Unraid.LogsField,
-			LogsTailArg:      cfg.Unraid.LogsTailArg,
-			LogsPayloadField: cfg.Unraid.LogsPayloadField,
-
-			StatsField:  cfg.Unraid.StatsField,
-			StatsFields: cfg.Unraid.StatsFields,
-
-			ForceUpdateMutation:     cfg.Unraid.ForceUpdateMutation,
-			ForceUpdateArgName:      cfg.Unraid.ForceUpdateArgName,
-			ForceUpdateArgType:      cfg.Unraid.ForceUpdateArgType,
-			ForceUpdateReturnFields: cfg.Unraid.ForceUpdateReturnFields,
-		}, httpClient)
-		providers = append(providers, unraid.NewProvider(unraid.ProviderDeps{
-			WeCom:  wecomSender,
-			Client: unraidClient,
-			State:  stateStore,
-		}))
-	}
-
-	if len(cfg.Qinglong.Instances) > 0 {
-		var instances []qinglong.Instance
-		for _, ins := range cfg.Qinglong.Instances {
-			client, err := qinglong.NewClient(qinglong.ClientConfig{
-				BaseURL:      ins.BaseURL,
-				ClientID:     ins.ClientID,
-				ClientSecret: ins.ClientSecret,
-			}, httpClient)
-			if err != nil {
-				return nil, err
-			}
-			instances = append(instances, qinglong.Instance{
-				ID:     ins.ID,
-				Name:   ins.Name,
-				Client: client,
-			})
-		}
-		providers = append(providers, qinglong.NewProvider(qinglong.ProviderDeps{
-			WeCom:     wecomSender,
-			State:     stateStore,
-			Instances: instances,
-		}))
-	}
-
-	var pveAlerts *pve.AlertManager
-	if len(cfg.PVE.Instances) > 0 {
-		var instances []pve.Instance
-		for _, ins := range cfg.PVE.Instances {
-			client, err := pve.NewClient(pve.ClientConfig{
-				BaseURL:            ins.BaseURL,
-				APIToken:           ins.APIToken,
-				InsecureSkipVerify: ins.InsecureSkipVerify,
-			}, httpClient)
-			if err != nil {
-				return nil, err
-			}
-			instances = append(instances, pve.Instance{
-				ID:     ins.ID,
-				Name:   ins.Name,
-				Client: client,
-			})
-		}
-
-		enabled := cfg.PVE.Alert.Enabled != nil && *cfg.PVE.Alert.Enabled
-		alertCfg := pve.AlertConfig{
-			Enabled: enabled,
-
-			Interval: cfg.PVE.Alert.Interval.ToDuration(),
-			Cooldown: cfg.PVE.Alert.Cooldown.ToDuration(),
-			MuteFor:  cfg.PVE.Alert.MuteFor.ToDuration(),
 
-			CPUUsageThreshold:     cfg.PVE.Alert.CPUUsageThreshold,
-			MemUsageThreshold:     cfg.PVE.Alert.MemUsageThreshold,
-			StorageUsageThreshold: cfg.PVE.Alert.StorageUsageThreshold,
+	location := time.Local
+	if tz := cfg.Core.Scheduler.Timezone; tz != "" {
+		if location, err = time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("加载时区失败: %w", err)
 		}
+	}
+	scheduler, err := core.NewScheduler(core.SchedulerDeps{
+		Store:      scheduleStore,
+		Location:   location,
+		MaxPerUser: cfg.Core.Scheduler.MaxPerUser,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	services, disabled, err := registry.Build(registry.Deps{
+		Config:     cfg,
+		WeCom:      wecomSender,
+		Notifier:   wecomClient,
+		State:      stateStore,
+		Auth:       authorizer,
+		HTTPClient: httpClient,
+		Store:      kv,
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		pveAlerts = pve.NewAlertManager(pve.AlertManagerDeps{
-			WeCom:     wecomClient,
-			UserIDs:   cfg.Auth.AllowedUserIDs,
-			Instances: instances,
-			Config:    alertCfg,
-		})
-		pveAlerts.Start()
-
-		providers = append(providers, pve.NewProvider(pve.ProviderDeps{
-			WeCom:       wecomSender,
-			State:       stateStore,
-			Instances:   instances,
-			AlertConfig: alertCfg,
-			Alerts:      pveAlerts,
-		}))
-	}
-
-	router := core.NewRouter(core.RouterDeps{
-		WeCom:         wecomSender,
-		AllowedUserID: make(map[string]struct{}),
-		Providers:     providers,
-		State:         stateStore,
+	router = core.NewRouter(core.RouterDeps{
+		WeCom:     wecomSender,
+		Auth:      authorizer,
+		Providers: registry.Providers(services),
+		Disabled:  disabled,
+		State:     stateStore,
+		Jobs:      jobs,
+		Audit:     auditSink,
+		Approval: core.ApprovalPolicy{
+			Actions: cfg.Core.Approval.Actions,
+			Timeout: cfg.Core.Approval.Timeout.ToDuration(),
+		},
+		Scheduler: scheduler,
+		History:   historyStore,
+		Favorites: favoriteStore,
+		Menu:      newMenuOptions(cfg.WeCom.Menu),
 	})
-	for _, id := range cfg.Auth.AllowedUserIDs {
-		router.AllowedUserID[id] = struct{}{}
+	scheduler.Start()
+	for _, svc := range services {
+		if svc.Start != nil {
+			svc.Start()
+		}
 	}
 
 	crypto, err := wecom.NewCrypto(wecom.CryptoConfig{
@@ -176,9 +175,18 @@ func NewServer(cfg config.Config) (*Server, error) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
 	})
-	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("services") == "" {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("ok"))
+			return
+		}
+		// ?services=1：逐个检查已启用服务的后端可达性（默认不检查，避免后端故障影响回调入口就绪）。
+		body, ok := checkServices(r.Context(), services)
+		if !ok {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_, _ = w.Write([]byte(body))
 	})
 
 	mux.Handle("GET /wecom/callback", wecom.NewCallbackVerifyHandler(crypto))
@@ -198,8 +206,12 @@ func NewServer(cfg config.Config) (*Server, error) {
 		cfg:        cfg,
 		server:     s,
 		stateStore: stateStore,
+		kv:         kv,
+		jobs:       jobs,
+		audit:      auditSink,
 		deduper:    deduper,
-		pveAlerts:  pveAlerts,
+		services:   services,
+		scheduler:  scheduler,
 	}, nil
 }
 
@@ -229,18 +241,174 @@ func (s *Server) Serve(listener net.Listener) error {
 func (s *Server) Shutdown(ctx context.Context) error {
 	slog.Info("HTTP 服务关闭中")
 	err := s.server.Shutdown(ctx)
+	if s.scheduler != nil {
+		s.scheduler.Close()
+	}
+	if s.jobs != nil {
+		if jobErr := s.jobs.Shutdown(ctx); jobErr != nil {
+			slog.Warn("等待异步任务结束超时", "error", jobErr)
+		}
+	}
+	// 先停止各服务的后台任务（会等待轮询 goroutine 退出），再关闭其依赖的存储。
+	for _, svc := range s.services {
+		if svc.Close != nil {
+			svc.Close()
+		}
+	}
+	if s.audit != nil {
+		if auditErr := s.audit.Close(); auditErr != nil {
+			slog.Warn("关闭审计存储失败", "error", auditErr)
+		}
+	}
 	if s.stateStore != nil {
 		s.stateStore.Close()
 	}
 	if s.deduper != nil {
 		s.deduper.Close()
 	}
-	if s.pveAlerts != nil {
-		s.pveAlerts.Close()
+	if s.kv != nil {
+		if kvErr := s.kv.Close(); kvErr != nil {
+			slog.Warn("关闭持久化存储失败", "error", kvErr)
+		}
 	}
 	return err
 }
 
+// NewAuthorizer 将 auth 配置转换为 core.Authorizer（allowed_userids 视为 admin）。
+// resolver 用于解析 department:<id> / tag:<id> 主体，通常为基于企业微信通讯录的 wecom.Directory。
+func NewAuthorizer(cfg config.AuthConfig, resolver core.SubjectResolver) (*core.Authorizer, error) {
+	bindings := make([]core.RoleBinding, 0, len(cfg.Bindings))
+	for i, b := range cfg.Bindings {
+		scope, err := newAuthScope(b.Scope)
+		if err != nil {
+			return nil, fmt.Errorf("auth.bindings[%d].scope: %w", i, err)
+		}
+		bindings = append(bindings, core.RoleBinding{Role: b.Role, Subjects: b.Subjects, Scope: scope})
+	}
+	return core.NewAuthorizer(core.AuthorizerConfig{
+		AdminUserIDs: cfg.AllowedUserIDs,
+		Roles:        cfg.Roles,
+		Bindings:     bindings,
+		Resolver:     resolver,
+	})
+}
+
+// NewMenu 按配置生成应用自定义菜单（与会话中“同步菜单”一致），供命令行同步/对比使用；
+// 仅按配置判断服务是否启用，不连接后端。
+func NewMenu(cfg config.Config) (wecom.Menu, error) {
+	if err := validateMenuServices(cfg.WeCom.Menu); err != nil {
+		return wecom.Menu{}, err
+	}
+	services, _, err := registry.Build(registry.Deps{Config: cfg, HTTPClient: http.DefaultClient})
+	if err != nil {
+		return wecom.Menu{}, err
+	}
+	return core.BuildMenu(registry.Providers(services), newMenuOptions(cfg.WeCom.Menu))
+}
+
+// checkServices 并发执行已启用服务的健康检查（单项 5 秒超时），返回逐行结果与是否全部通过。
+func checkServices(ctx context.Context, services []registry.Service) (string, bool) {
+	results := make([]string, len(services))
+	failed := make([]bool, len(services))
+	var wg sync.WaitGroup
+	for i, svc := range services {
+		key := svc.Provider.Key()
+		results[i] = key + ": ok"
+		if svc.Health == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			if err := svc.Health(ctx); err != nil {
+				results[i], failed[i] = key+": "+err.Error(), true
+			}
+		}()
+	}
+	wg.Wait()
+	return strings.Join(results, "\n"), !slices.Contains(failed, true)
+}
+
+// validateMenuServices 校验 wecom.menu.services 均为已登记的服务后端（config 包不感知注册表）。
+func validateMenuServices(cfg config.WeComMenuConfig) error {
+	for _, key := range cfg.Services {
+		if _, ok := registry.Lookup(key); ok {
+			continue
+		}
+		var keys []string
+		for _, f := range registry.Factories() {
+			keys = append(keys, f.Key)
+		}
+		return fmt.Errorf("配置校验失败: wecom.menu.services 不支持 %q（已登记的服务：%s）", key, strings.Join(keys, "/"))
+	}
+	return nil
+}
+
+func newMenuOptions(cfg config.WeComMenuConfig) core.MenuOptions {
+	return core.MenuOptions{Services: cfg.Services, Buttons: newMenuButtons(cfg.Buttons)}
+}
+
+func newMenuButtons(cfg []config.MenuButtonConfig) []wecom.MenuButton {
+	var out []wecom.MenuButton
+	for _, b := range cfg {
+		btn := wecom.MenuButton{
+			Name:       strings.TrimSpace(b.Name),
+			SubButtons: newMenuButtons(b.SubButtons),
+		}
+		if len(btn.SubButtons) == 0 {
+			btn.Type = b.Type
+			btn.Key = strings.TrimSpace(b.Key)
+			btn.URL = strings.TrimSpace(b.URL)
+		}
+		out = append(out, btn)
+	}
+	return out
+}
+
+// newAuthScope 转换绑定作用范围；未配置任何维度时返回 nil（不限制）。
+func newAuthScope(cfg config.AuthScopeConfig) (*core.Scope, error) {
+	if len(cfg.Instances) == 0 && len(cfg.Containers) == 0 && len(cfg.VMs) == 0 && len(cfg.VMIDs) == 0 && len(cfg.Tags) == 0 {
+		return nil, nil
+	}
+	scope := &core.Scope{
+		Instances:  cfg.Instances,
+		Containers: cfg.Containers,
+		VMs:        cfg.VMs,
+		Tags:       cfg.Tags,
+	}
+	for _, s := range cfg.VMIDs {
+		r, err := core.ParseVMIDRange(s)
+		if err != nil {
+			return nil, err
+		}
+		scope.VMIDs = append(scope.VMIDs, r)
+	}
+	return scope, nil
+}
+
+func newAuditSink(cfg config.AuditConfig) (core.AuditSink, error) {
+	switch cfg.Sink {
+	case "jsonl":
+		sink, err := audit.NewJSONLSink(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("操作审计已启用", "sink", cfg.Sink, "path", cfg.Path)
+		return sink, nil
+	case "sqlite":
+		sink, err := audit.NewSQLiteSink(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("操作审计已启用", "sink", cfg.Sink, "path", cfg.Path)
+		return sink, nil
+	default:
+		return nil, nil
+	}
+}
+
 func withRequestLogging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -0,0 +1,104 @@
+// 审计落地（JSONL/SQLite）单元测试。
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+)
+
+func testSinkRecordAndQuery(t *testing.T, sink core.AuditSink) {
+	t.Helper()
+	ctx := context.Background()
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	entries := []core.AuditEntry{
+		{Time: base, UserID: "a", ServiceKey: "unraid", Action: core.ActionUnraidRestart, Target: "app", Result: core.AuditResultSuccess, DurationMS: 12},
+		{Time: base.Add(time.Second), UserID: "b", ServiceKey: "pve", InstanceID: "pve1", Action: core.ActionPVEStop, Target: "VM 101（pve）", ApprovalID: "9", Approver: "c", Result: core.AuditResultFailed, Error: "boom", DurationMS: 34},
+		{Time: base.Add(2 * time.Second), UserID: "a", ServiceKey: "qinglong", InstanceID: "ql", Action: core.ActionQinglongRun, Target: "任务ID 7", JobID: "3", Result: core.AuditResultTimeout, DurationMS: 56},
+	}
+	for _, e := range entries {
+		if err := sink.Record(ctx, e); err != nil {
+			t.Fatalf("Record() error: %v", err)
+		}
+	}
+
+	got, err := sink.Query(ctx, core.AuditQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("Query() error: %v", err)
+	}
+	if len(got) != 2 || got[0].ServiceKey != "qinglong" || got[1].ServiceKey != "pve" {
+		t.Fatalf("Query(limit=2) = %+v, want newest first", got)
+	}
+	if got[0].JobID != "3" || got[0].Result != core.AuditResultTimeout || !got[0].Time.Equal(entries[2].Time) {
+		t.Fatalf("Query()[0] = %+v", got[0])
+	}
+	if got[1].Error != "boom" || got[1].InstanceID != "pve1" || got[1].DurationMS != 34 || got[1].ApprovalID != "9" || got[1].Approver != "c" {
+		t.Fatalf("Query()[1] = %+v", got[1])
+	}
+
+	byUser, err := sink.Query(ctx, core.AuditQuery{UserID: "a", Limit: 10})
+	if err != nil {
+		t.Fatalf("Query(user) error: %v", err)
+	}
+	if len(byUser) != 2 {
+		t.Fatalf("Query(user=a) len = %d, want 2", len(byUser))
+	}
+
+	byService, err := sink.Query(ctx, core.AuditQuery{ServiceKey: "unraid", Limit: 10})
+	if err != nil {
+		t.Fatalf("Query(service) error: %v", err)
+	}
+	if len(byService) != 1 || byService[0].Target != "app" {
+		t.Fatalf("Query(service=unraid) = %+v", byService)
+	}
+}
+
+func TestJSONLSink_RecordAndQuery(t *testing.T) {
+	t.Parallel()
+
+	sink, err := NewJSONLSink(filepath.Join(t.TempDir(), "audit", "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("NewJSONLSink() error: %v", err)
+	}
+	t.Cleanup(func() { _ = sink.Close() })
+	testSinkRecordAndQuery(t, sink)
+}
+
+func TestSQLiteSink_RecordAndQuery(t *testing.T) {
+	t.Parallel()
+
+	sink, err := NewSQLiteSink(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteSink() error: %v", err)
+	}
+	t.Cleanup(func() { _ = sink.Close() })
+	testSinkRecordAndQuery(t, sink)
+}
+
+func TestSQLiteSink_ReopenKeepsEntries(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.db")
+	sink, err := NewSQLiteSink(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteSink() error: %v", err)
+	}
+	if err := sink.Record(context.Background(), core.AuditEntry{Time: time.Now(), UserID: "u", ServiceKey: "unraid", Action: core.ActionUnraidStop, Result: core.AuditResultSuccess}); err != nil {
+		t.Fatalf("Record() error: %v", err)
+	}
+	_ = sink.Close()
+
+	reopened, err := NewSQLiteSink(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteSink(reopen) error: %v", err)
+	}
+	t.Cleanup(func() { _ = reopened.Close() })
+	got, err := reopened.Query(context.Background(), core.AuditQuery{})
+	if err != nil || len(got) != 1 {
+		t.Fatalf("Query() = %+v, %v; want 1 entry", got, err)
+	}
+}
@@ -0,0 +1,125 @@
+// Package audit 提供 core.AuditSink 的落地实现：JSONL 追加文件与 SQLite（可查询）。
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+)
+
+// maxJSONLLineBytes 为单条审计记录的最大长度（超过视为损坏并跳过）。
+const maxJSONLLineBytes = 1 << 20
+
+// JSONLSink 将审计记录逐行追加到文件（每行一个 JSON 对象），便于 grep/jq 与日志采集。
+type JSONLSink struct {
+	path string
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+func NewJSONLSink(path string) (*JSONLSink, error) {
+	if path == "" {
+		return nil, errors.New("audit: path 不能为空")
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("audit: 创建目录失败: %w", err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: 打开文件失败: %w", err)
+	}
+	return &JSONLSink{path: path, f: f}, nil
+}
+
+func (s *JSONLSink) Record(_ context.Context, entry core.AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("audit: 序列化失败: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return errors.New("audit: 已关闭")
+	}
+	if _, err := s.f.Write(line); err != nil {
+		return fmt.Errorf("audit: 写入失败: %w", err)
+	}
+	return s.f.Sync()
+}
+
+// Query 顺序扫描文件并保留最近 Limit 条匹配记录（审计量级较小，无需索引）。
+func (s *JSONLSink) Query(ctx context.Context, q core.AuditQuery) ([]core.AuditEntry, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("audit: 打开文件失败: %w", err)
+	}
+	defer f.Close()
+
+	ring := make([]core.AuditEntry, 0, limit)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), maxJSONLLineBytes)
+	for sc.Scan() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var e core.AuditEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			continue
+		}
+		if !matchQuery(e, q) {
+			continue
+		}
+		if len(ring) == limit {
+			ring = ring[1:]
+		}
+		ring = append(ring, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("audit: 读取失败: %w", err)
+	}
+
+	sort.SliceStable(ring, func(i, j int) bool { return ring[i].Time.After(ring[j].Time) })
+	return ring, nil
+}
+
+func (s *JSONLSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+func matchQuery(e core.AuditEntry, q core.AuditQuery) bool {
+	if q.UserID != "" && e.UserID != q.UserID {
+		return false
+	}
+	if q.ServiceKey != "" && e.ServiceKey != q.ServiceKey {
+		return false
+	}
+	return true
+}
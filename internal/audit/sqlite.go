@@ -0,0 +1,138 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+
+	// 纯 Go 实现的 SQLite 驱动，兼容 CGO_ENABLED=0 与 scratch 镜像。
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS audit_log (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	ts          INTEGER NOT NULL,
+	user_id     TEXT    NOT NULL,
+	service     TEXT    NOT NULL,
+	instance_id TEXT    NOT NULL DEFAULT '',
+	action      TEXT    NOT NULL,
+	target      TEXT    NOT NULL DEFAULT '',
+	job_id      TEXT    NOT NULL DEFAULT '',
+	approval_id TEXT    NOT NULL DEFAULT '',
+	approver    TEXT    NOT NULL DEFAULT '',
+	result      TEXT    NOT NULL,
+	error       TEXT    NOT NULL DEFAULT '',
+	duration_ms INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_ts ON audit_log(ts);
+CREATE INDEX IF NOT EXISTS idx_audit_log_user_ts ON audit_log(user_id, ts);
+CREATE INDEX IF NOT EXISTS idx_audit_log_service_ts ON audit_log(service, ts);
+`
+
+// SQLiteSink 将审计记录写入 SQLite 表 audit_log，可直接用 sqlite3 CLI 做任意条件查询。
+type SQLiteSink struct {
+	db *sql.DB
+}
+
+func NewSQLiteSink(path string) (*SQLiteSink, error) {
+	if path == "" {
+		return nil, errors.New("audit: path 不能为空")
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("audit: 创建目录失败: %w", err)
+		}
+	}
+
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("audit: 打开 SQLite 失败: %w", err)
+	}
+	// SQLite 单写者：串行化写入，避免 database is locked。
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("audit: 初始化表结构失败: %w", err)
+	}
+	return &SQLiteSink{db: db}, nil
+}
+
+func (s *SQLiteSink) Record(ctx context.Context, e core.AuditEntry) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO audit_log (ts, user_id, service, instance_id, action, target, job_id, approval_id, approver, result, error, duration_ms)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Time.UnixMilli(), e.UserID, e.ServiceKey, e.InstanceID, string(e.Action), e.Target, e.JobID, e.ApprovalID, e.Approver, string(e.Result), e.Error, e.DurationMS,
+	)
+	if err != nil {
+		return fmt.Errorf("audit: 写入失败: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteSink) Query(ctx context.Context, q core.AuditQuery) ([]core.AuditEntry, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	var (
+		where []string
+		args  []any
+	)
+	if q.UserID != "" {
+		where = append(where, "user_id = ?")
+		args = append(args, q.UserID)
+	}
+	if q.ServiceKey != "" {
+		where = append(where, "service = ?")
+		args = append(args, q.ServiceKey)
+	}
+	query := `SELECT ts, user_id, service, instance_id, action, target, job_id, approval_id, approver, result, error, duration_ms FROM audit_log`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY ts DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("audit: 查询失败: %w", err)
+	}
+	defer rows.Close()
+
+	var out []core.AuditEntry
+	for rows.Next() {
+		var (
+			e              core.AuditEntry
+			ts             int64
+			action, result string
+		)
+		if err := rows.Scan(&ts, &e.UserID, &e.ServiceKey, &e.InstanceID, &action, &e.Target, &e.JobID, &e.ApprovalID, &e.Approver, &result, &e.Error, &e.DurationMS); err != nil {
+			return nil, fmt.Errorf("audit: 读取失败: %w", err)
+		}
+		e.Time = time.UnixMilli(ts)
+		e.Action = core.Action(action)
+		e.Result = core.AuditResult(result)
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("audit: 读取失败: %w", err)
+	}
+	return out, nil
+}
+
+func (s *SQLiteSink) Close() error {
+	return s.db.Close()
+}
@@ -8,7 +8,9 @@ import (
 	"log/slog"
 	"net/url"
 	"os"
+	"path"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -57,6 +59,47 @@ type ServerConfig struct {
 
 type CoreConfig struct {
 	StateTTL Duration `yaml:"state_ttl"`
+	// StateBackend 为会话状态与回调去重的存储后端：memory（默认，重启丢失）| file（JSON Lines 本地文件，重启可恢复）。
+	StateBackend string `yaml:"state_backend"`
+	// StatePath 为 file 后端的数据文件路径（默认 data/state.jsonl）。
+	StatePath string `yaml:"state_path"`
+
+	Jobs      JobsConfig      `yaml:"jobs"`
+	Audit     AuditConfig     `yaml:"audit"`
+	Approval  ApprovalConfig  `yaml:"approval"`
+	Scheduler SchedulerConfig `yaml:"scheduler"`
+}
+
+// SchedulerConfig 控制聊天中创建的定时/延时任务（如“重启 jellyfin 凌晨3点”）。
+// 定时任务随 core.state_backend 持久化：file 后端写入同一数据文件，memory 后端重启后丢失。
+type SchedulerConfig struct {
+	// Timezone 为解释“凌晨3点”等时间的 IANA 时区（如 Asia/Shanghai）；为空时使用进程本地时区（TZ 环境变量）。
+	Timezone string `yaml:"timezone"`
+	// MaxPerUser 为单个账号的定时任务上限（默认 20）。
+	MaxPerUser int `yaml:"max_per_user"`
+}
+
+// ApprovalConfig 控制危险操作的双人审批：命中 actions 的确认动作需另一名具备同等权限的账号批准。
+type ApprovalConfig struct {
+	// Actions 为需要审批的权限模式（如 pve.vm.stop、pve.*.stop、unraid.force_update）；为空表示不启用。
+	Actions []string `yaml:"actions"`
+	// Timeout 为审批时限（默认 10m），超时自动作废并通知发起人。
+	Timeout Duration `yaml:"timeout"`
+}
+
+// AuditConfig 控制确认类操作的审计落地。
+type AuditConfig struct {
+	// Sink 为审计落地方式：none（默认，仅结构化日志）| jsonl（追加文件）| sqlite（可查询数据库）。
+	Sink string `yaml:"sink"`
+	// Path 为审计文件路径（默认 jsonl=data/audit.jsonl，sqlite=data/audit.db）。
+	Path string `yaml:"path"`
+}
+
+// JobsConfig 控制确认类操作的异步执行（有界 worker 池 + 队列 + 单任务超时）。
+type JobsConfig struct {
+	Workers   int      `yaml:"workers"`
+	QueueSize int      `yaml:"queue_size"`
+	Timeout   Duration `yaml:"timeout"`
 }
 
 type Duration time.Duration
@@ -101,8 +144,29 @@ type WeComConfig struct {
 	// - 文本通知/图文展示/按钮交互型：企业微信 3.1.6+ 支持
 	// - 微工作台（原企业号）不支持展示模板卡片消息
 	TemplateCardMode string `yaml:"template_card_mode"`
+	// Menu 覆盖应用自定义菜单；默认按已启用的服务自动生成。
+	Menu WeComMenuConfig `yaml:"menu"`
+}
+
+// WeComMenuConfig 为应用自定义菜单的覆盖配置（企业微信限制：一级最多 3 个，二级最多 5 个）。
+type WeComMenuConfig struct {
+	// Services 指定占用一级菜单的服务（unraid/qinglong/pve）及顺序，最多 2 个，其余服务并入“常用”。
+	Services []string `yaml:"services"`
+	// Buttons 非空时完全替换自动生成的菜单。
+	Buttons []MenuButtonConfig `yaml:"buttons"`
 }
 
+type MenuButtonConfig struct {
+	// Type 为 click（需 key）或 view（需 url）；为空时按 url 是否填写推导，含二级菜单时忽略。
+	Type       string             `yaml:"type"`
+	Name       string             `yaml:"name"`
+	Key        string             `yaml:"key"`
+	URL        string             `yaml:"url"`
+	SubButtons []MenuButtonConfig `yaml:"sub_buttons"`
+}
+
+// UnraidConfig 支持两种写法：直接在 unraid 下配置 endpoint/api_key（单台，兼容旧配置），
+// 或在 instances 中配置多台；二者不能同时使用。GraphQL 字段（logs_*/stats_*/force_update_*）为各实例共用。
 type UnraidConfig struct {
 	Endpoint            string `yaml:"endpoint"`
 	APIKey              string `yaml:"api_key"`
@@ -117,6 +181,12 @@ type UnraidConfig struct {
 	WebGUICSRFToken  string `yaml:"webgui_csrf_token"`
 	WebGUICookie     string `yaml:"webgui_cookie"`
 
+	// GraphQL 订阅（WebSocket，用于跟踪日志等）。
+	// - subscription_url 默认由 endpoint 推导（http→ws、https→wss）
+	// - subscription_protocol 为 graphql-transport-ws 或 graphql-ws（旧版），为空时握手协商
+	SubscriptionURL      string `yaml:"subscription_url"`
+	SubscriptionProtocol string `yaml:"subscription_protocol"`
+
 	LogsField        string  `yaml:"logs_field"`
 	LogsTailArg      *string `yaml:"logs_tail_arg"`
 	LogsPayloadField string  `yaml:"logs_payload_field"`
@@ -127,6 +197,79 @@ type UnraidConfig struct {
 	ForceUpdateArgName      string   `yaml:"force_update_arg"`
 	ForceUpdateArgType      string   `yaml:"force_update_arg_type"`
 	ForceUpdateReturnFields []string `yaml:"force_update_return_fields"`
+
+	Instances []UnraidInstance `yaml:"instances"`
+
+	// Alert 为阵列/磁盘健康告警（各实例共用）。
+	Alert UnraidAlertConfig `yaml:"alert"`
+	// Notify 为 Unraid 通知转发（各实例共用）。
+	Notify UnraidNotifyConfig `yaml:"notify"`
+}
+
+// UnraidNotifyConfig 为 Unraid 通知转发：轮询未读通知并按重要程度推送到企业微信。
+type UnraidNotifyConfig struct {
+	Enabled *bool `yaml:"enabled"`
+
+	// Interval 为轮询间隔（建议 30s~5m）。
+	Interval Duration `yaml:"interval"`
+	// Importance 为需要转发的重要程度（alert/warning/info）。
+	Importance []string `yaml:"importance"`
+}
+
+// UnraidAlertConfig 为 Unraid 阵列/磁盘健康告警：磁盘过热、磁盘被禁用、阵列降级。
+type UnraidAlertConfig struct {
+	Enabled *bool `yaml:"enabled"`
+
+	// Interval 为告警轮询间隔（建议 1m~10m）。
+	Interval Duration `yaml:"interval"`
+	// Cooldown 为同类告警的冷却时间（避免重复刷屏）。
+	Cooldown Duration `yaml:"cooldown"`
+
+	// DiskTempThreshold 为磁盘温度告警阈值（摄氏度）。
+	DiskTempThreshold int `yaml:"disk_temp_threshold"`
+}
+
+// UnraidInstance 为一台 Unraid 的连接、WebGUI 兜底与订阅配置。
+type UnraidInstance struct {
+	ID       string `yaml:"id"`
+	Name     string `yaml:"name"`
+	Endpoint string `yaml:"endpoint"`
+	APIKey   string `yaml:"api_key"`
+	// Origin 为空时沿用 unraid.origin。
+	Origin string `yaml:"origin"`
+
+	WebGUICommandURL string `yaml:"webgui_command_url"`
+	WebGUIEventsURL  string `yaml:"webgui_events_url"`
+	WebGUICSRFToken  string `yaml:"webgui_csrf_token"`
+	WebGUICookie     string `yaml:"webgui_cookie"`
+
+	SubscriptionURL      string `yaml:"subscription_url"`
+	SubscriptionProtocol string `yaml:"subscription_protocol"`
+}
+
+// EffectiveInstances 返回生效的 Unraid 实例：配置了 instances 时原样返回，
+// 否则将 unraid.endpoint/api_key 转换为单个实例（ID 为空，授权与操作记录与旧版本一致）；均未配置时返回 nil。
+func (c UnraidConfig) EffectiveInstances() []UnraidInstance {
+	if len(c.Instances) > 0 {
+		return c.Instances
+	}
+	if strings.TrimSpace(c.Endpoint) == "" && strings.TrimSpace(c.APIKey) == "" {
+		return nil
+	}
+	return []UnraidInstance{{
+		Name:     "Unraid",
+		Endpoint: c.Endpoint,
+		APIKey:   c.APIKey,
+		Origin:   c.Origin,
+
+		WebGUICommandURL: c.WebGUICommandURL,
+		WebGUIEventsURL:  c.WebGUIEventsURL,
+		WebGUICSRFToken:  c.WebGUICSRFToken,
+		WebGUICookie:     c.WebGUICookie,
+
+		SubscriptionURL:      c.SubscriptionURL,
+		SubscriptionProtocol: c.SubscriptionProtocol,
+	}}
 }
 
 type QinglongConfig struct {
@@ -170,11 +313,46 @@ type PVEAlertConfig struct {
 }
 
 type AuthConfig struct {
+	// AllowedUserIDs 为兼容旧配置的白名单：列出的账号视为 admin 角色（拥有全部权限）。
 	AllowedUserIDs []string `yaml:"allowed_userids"`
+	// Roles 为自定义角色：角色名 → 权限列表（如 unraid.view、unraid.restart、pve.vm.*）；与内置角色同名时覆盖。
+	// 内置角色：viewer（*.view）、operator（各服务全部操作）、admin（*，含 core.menu_sync/core.audit）。
+	Roles map[string][]string `yaml:"roles"`
+	// Bindings 将账号绑定到角色；同一账号可出现在多个绑定中，权限取并集。
+	Bindings []RoleBindingConfig `yaml:"bindings"`
+	// DirectoryTTL 为 department:<id> / tag:<id> 主体成员的缓存时长（经企业微信通讯录接口解析）。
+	DirectoryTTL Duration `yaml:"directory_ttl"`
+}
+
+type RoleBindingConfig struct {
+	Role     string   `yaml:"role"`
+	Subjects []string `yaml:"subjects"`
+	// Scope 可选：限定该绑定的作用范围（未配置 instances 时不限实例；配置了任一目标维度时仅覆盖所列目标）。
+	Scope AuthScopeConfig `yaml:"scope"`
 }
 
+type AuthScopeConfig struct {
+	// Instances 限定青龙/PVE/Unraid 实例 ID。
+	Instances []string `yaml:"instances"`
+	// Containers 为 Unraid 容器名通配模式（如 jellyfin、media-*）。
+	Containers []string `yaml:"containers"`
+	// VMs 为 Unraid 虚拟机名通配模式（如 win10、ha-*）。
+	VMs []string `yaml:"vms"`
+	// VMIDs 为 PVE VMID 或区间（如 101、100-199）；与 Tags 命中任一即可。
+	VMIDs []string `yaml:"vmids"`
+	Tags  []string `yaml:"tags"`
+}
+
+var builtinAuthRoles = map[string]struct{}{"viewer": {}, "operator": {}, "admin": {}}
+
+var unraidInstanceIDPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,31}$`)
 var qinglongInstanceIDPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,31}$`)
 var pveInstanceIDPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,31}$`)
+var vmidRangePattern = regexp.MustCompile(`^\s*([1-9][0-9]*)\s*(-\s*([1-9][0-9]*)\s*)?$`)
+var permissionPattern = regexp.MustCompile(`^(\*|[a-z0-9_]+)(\.(\*|[a-z0-9_]+))*$`)
+
+// contactSubjectPattern 匹配通讯录主体 department:<部门ID> / tag:<标签ID>。
+var contactSubjectPattern = regexp.MustCompile(`^(?i)(department|tag):\s*[1-9][0-9]*$`)
 var graphqlIdentifierPattern = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
 
 func Load(path string) (Config, error) {
@@ -222,20 +400,39 @@ func Load(path string) (Config, error) {
 		"server.http_client_timeout", cfg.Server.HTTPClientTimeout.ToDuration().String(),
 		"server.read_header_timeout", cfg.Server.ReadHeaderTimeout.ToDuration().String(),
 		"core.state_ttl", cfg.Core.StateTTL.ToDuration().String(),
+		"core.state_backend", cfg.Core.StateBackend,
+		"core.state_path", cfg.Core.StatePath,
+		"core.jobs.workers", cfg.Core.Jobs.Workers,
+		"core.jobs.queue_size", cfg.Core.Jobs.QueueSize,
+		"core.jobs.timeout", cfg.Core.Jobs.Timeout.ToDuration().String(),
+		"core.audit.sink", cfg.Core.Audit.Sink,
+		"core.audit.path", cfg.Core.Audit.Path,
+		"core.approval.actions", cfg.Core.Approval.Actions,
+		"core.approval.timeout", cfg.Core.Approval.Timeout.ToDuration().String(),
+		"core.scheduler.timezone", cfg.Core.Scheduler.Timezone,
+		"core.scheduler.max_per_user", cfg.Core.Scheduler.MaxPerUser,
 		"log.level", string(cfg.Log.Level),
 
 		"wecom.corpid", maskSensitive(cfg.WeCom.CorpID),
 		"wecom.agentid", cfg.WeCom.AgentID,
 		"wecom.api_base_url", cfg.WeCom.APIBaseURL,
 		"wecom.template_card_mode", cfg.WeCom.TemplateCardMode,
+		"wecom.menu.services", cfg.WeCom.Menu.Services,
+		"wecom.menu.buttons_count", len(cfg.WeCom.Menu.Buttons),
 		"wecom.token_len", len(cfg.WeCom.Token),
 		"wecom.encoding_aes_key_len", len(cfg.WeCom.EncodingAESKey),
 		"wecom.secret_len", len(cfg.WeCom.Secret),
 
 		"auth.allowed_userids_count", len(cfg.Auth.AllowedUserIDs),
 		"auth.allowed_userids_sample", maskSensitiveSlice(cfg.Auth.AllowedUserIDs, 3),
+		"auth.roles_count", len(cfg.Auth.Roles),
+		"auth.bindings_count", len(cfg.Auth.Bindings),
+		"auth.directory_ttl", cfg.Auth.DirectoryTTL.ToDuration().String(),
 
-		"unraid.enabled", strings.TrimSpace(cfg.Unraid.Endpoint) != "" && strings.TrimSpace(cfg.Unraid.APIKey) != "",
+		"unraid.enabled", len(cfg.Unraid.EffectiveInstances()) > 0,
+		"unraid.instances_count", len(cfg.Unraid.Instances),
+		"unraid.alert_enabled", len(cfg.Unraid.EffectiveInstances()) > 0 && cfg.Unraid.Alert.Enabled != nil && *cfg.Unraid.Alert.Enabled,
+		"unraid.notify_enabled", len(cfg.Unraid.EffectiveInstances()) > 0 && cfg.Unraid.Notify.Enabled != nil && *cfg.Unraid.Notify.Enabled,
 		"qinglong.instances_count", len(cfg.Qinglong.Instances),
 		"pve.instances_count", len(cfg.PVE.Instances),
 		"pve.enabled", len(cfg.PVE.Instances) > 0,
@@ -261,12 +458,53 @@ func applyDefaults(cfg *Config) {
 	if cfg.Core.StateTTL == 0 {
 		cfg.Core.StateTTL = Duration(30 * time.Minute)
 	}
+	if strings.TrimSpace(cfg.Core.StateBackend) == "" {
+		cfg.Core.StateBackend = "memory"
+	}
+	if strings.TrimSpace(cfg.Core.StatePath) == "" {
+		cfg.Core.StatePath = "data/state.jsonl"
+	}
+	if cfg.Core.Jobs.Workers == 0 {
+		cfg.Core.Jobs.Workers = 4
+	}
+	if cfg.Core.Jobs.QueueSize == 0 {
+		cfg.Core.Jobs.QueueSize = 32
+	}
+	if cfg.Core.Jobs.Timeout == 0 {
+		cfg.Core.Jobs.Timeout = Duration(5 * time.Minute)
+	}
+	if cfg.Core.Approval.Timeout == 0 {
+		cfg.Core.Approval.Timeout = Duration(10 * time.Minute)
+	}
+	cfg.Core.Scheduler.Timezone = strings.TrimSpace(cfg.Core.Scheduler.Timezone)
+	if cfg.Core.Scheduler.MaxPerUser == 0 {
+		cfg.Core.Scheduler.MaxPerUser = 20
+	}
+	if cfg.Auth.DirectoryTTL == 0 {
+		cfg.Auth.DirectoryTTL = Duration(10 * time.Minute)
+	}
+	cfg.Core.Audit.Sink = strings.ToLower(strings.TrimSpace(cfg.Core.Audit.Sink))
+	if cfg.Core.Audit.Sink == "" {
+		cfg.Core.Audit.Sink = "none"
+	}
+	if strings.TrimSpace(cfg.Core.Audit.Path) == "" {
+		switch cfg.Core.Audit.Sink {
+		case "jsonl":
+			cfg.Core.Audit.Path = "data/audit.jsonl"
+		case "sqlite":
+			cfg.Core.Audit.Path = "data/audit.db"
+		}
+	}
 	if cfg.WeCom.APIBaseURL == "" {
 		cfg.WeCom.APIBaseURL = "https://qyapi.weixin.qq.com/cgi-bin"
 	}
 	if strings.TrimSpace(cfg.WeCom.TemplateCardMode) == "" {
 		cfg.WeCom.TemplateCardMode = "template_card"
 	}
+	for i := range cfg.WeCom.Menu.Services {
+		cfg.WeCom.Menu.Services[i] = strings.ToLower(strings.TrimSpace(cfg.WeCom.Menu.Services[i]))
+	}
+	applyMenuButtonDefaults(cfg.WeCom.Menu.Buttons)
 	if cfg.Unraid.Origin == "" {
 		cfg.Unraid.Origin = "wecom-home-ops"
 	}
@@ -295,6 +533,35 @@ func applyDefaults(cfg *Config) {
 	if cfg.Unraid.ForceUpdateReturnFields == nil {
 		cfg.Unraid.ForceUpdateReturnFields = []string{"__typename"}
 	}
+	for i := range cfg.Unraid.Instances {
+		if cfg.Unraid.Instances[i].Origin == "" {
+			cfg.Unraid.Instances[i].Origin = cfg.Unraid.Origin
+		}
+	}
+
+	if cfg.Unraid.Alert.Enabled == nil {
+		v := true
+		cfg.Unraid.Alert.Enabled = &v
+	}
+	if cfg.Unraid.Alert.Interval == 0 {
+		cfg.Unraid.Alert.Interval = Duration(5 * time.Minute)
+	}
+	if cfg.Unraid.Alert.Cooldown == 0 {
+		cfg.Unraid.Alert.Cooldown = Duration(30 * time.Minute)
+	}
+	if cfg.Unraid.Alert.DiskTempThreshold == 0 {
+		cfg.Unraid.Alert.DiskTempThreshold = 50
+	}
+	if cfg.Unraid.Notify.Enabled == nil {
+		v := true
+		cfg.Unraid.Notify.Enabled = &v
+	}
+	if cfg.Unraid.Notify.Interval == 0 {
+		cfg.Unraid.Notify.Interval = Duration(time.Minute)
+	}
+	if cfg.Unraid.Notify.Importance == nil {
+		cfg.Unraid.Notify.Importance = []string{"alert", "warning"}
+	}
 
 	if cfg.PVE.Alert.Enabled == nil {
 		v := true
@@ -320,6 +587,20 @@ func applyDefaults(cfg *Config) {
 	}
 }
 
+func applyMenuButtonDefaults(buttons []MenuButtonConfig) {
+	for i := range buttons {
+		b := &buttons[i]
+		b.Type = strings.ToLower(strings.TrimSpace(b.Type))
+		if b.Type == "" && len(b.SubButtons) == 0 {
+			b.Type = "click"
+			if strings.TrimSpace(b.URL) != "" {
+				b.Type = "view"
+			}
+		}
+		applyMenuButtonDefaults(b.SubButtons)
+	}
+}
+
 func validate(cfg Config) error {
 	var problems []string
 
@@ -335,6 +616,45 @@ func validate(cfg Config) error {
 	if cfg.Core.StateTTL.ToDuration() <= 0 {
 		problems = append(problems, "core.state_ttl 不能为空且必须为正数（例如 30m）")
 	}
+	switch strings.ToLower(strings.TrimSpace(cfg.Core.StateBackend)) {
+	case "memory":
+	case "file":
+		if strings.TrimSpace(cfg.Core.StatePath) == "" {
+			problems = append(problems, "core.state_path 不能为空（core.state_backend=file 时必填）")
+		}
+	default:
+		problems = append(problems, "core.state_backend 不合法（仅支持 memory/file）")
+	}
+	switch cfg.Core.Audit.Sink {
+	case "none", "jsonl", "sqlite":
+	default:
+		problems = append(problems, "core.audit.sink 不合法（仅支持 none/jsonl/sqlite）")
+	}
+	if cfg.Core.Jobs.Workers <= 0 {
+		problems = append(problems, "core.jobs.workers 必须为正整数")
+	}
+	if cfg.Core.Jobs.QueueSize <= 0 {
+		problems = append(problems, "core.jobs.queue_size 必须为正整数")
+	}
+	if cfg.Core.Jobs.Timeout.ToDuration() <= 0 {
+		problems = append(problems, "core.jobs.timeout 不能为空且必须为正数（例如 5m）")
+	}
+	for _, perm := range cfg.Core.Approval.Actions {
+		if !permissionPattern.MatchString(strings.TrimSpace(perm)) {
+			problems = append(problems, fmt.Sprintf("core.approval.actions 权限 %q 不合法（示例：pve.vm.stop、pve.*.stop、unraid.force_update）", perm))
+		}
+	}
+	if cfg.Core.Approval.Timeout.ToDuration() < 0 {
+		problems = append(problems, "core.approval.timeout 不能为负数")
+	}
+	if tz := cfg.Core.Scheduler.Timezone; tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			problems = append(problems, fmt.Sprintf("core.scheduler.timezone 不合法（%v），示例：Asia/Shanghai", err))
+		}
+	}
+	if cfg.Core.Scheduler.MaxPerUser < 0 {
+		problems = append(problems, "core.scheduler.max_per_user 不能为负数")
+	}
 
 	if cfg.WeCom.CorpID == "" {
 		problems = append(problems, "wecom.corpid 不能为空")
@@ -360,13 +680,36 @@ func validate(cfg Config) error {
 		problems = append(problems, "wecom.template_card_mode 不合法（仅支持 template_card/both/text）")
 	}
 
-	hasUnraid := strings.TrimSpace(cfg.Unraid.Endpoint) != "" || strings.TrimSpace(cfg.Unraid.APIKey) != ""
+	problems = append(problems, validateWeComMenu(cfg.WeCom.Menu)...)
+
+	hasLegacyUnraid := strings.TrimSpace(cfg.Unraid.Endpoint) != "" || strings.TrimSpace(cfg.Unraid.APIKey) != ""
+	if hasLegacyUnraid && len(cfg.Unraid.Instances) > 0 {
+		problems = append(problems, "unraid.endpoint/api_key 与 unraid.instances 不能同时配置（多台请全部写入 instances）")
+	}
+	hasUnraid := hasLegacyUnraid || len(cfg.Unraid.Instances) > 0
 	if hasUnraid {
-		if cfg.Unraid.Endpoint == "" {
-			problems = append(problems, "unraid.endpoint 不能为空")
-		}
-		if cfg.Unraid.APIKey == "" {
-			problems = append(problems, "unraid.api_key 不能为空")
+		if len(cfg.Unraid.Instances) > 0 {
+			seen := make(map[string]struct{})
+			for i, ins := range cfg.Unraid.Instances {
+				prefix := fmt.Sprintf("unraid.instances[%d].", i)
+				if strings.TrimSpace(ins.ID) == "" {
+					problems = append(problems, prefix+"id 不能为空")
+				} else {
+					if !unraidInstanceIDPattern.MatchString(ins.ID) {
+						problems = append(problems, prefix+"id 不合法（仅允许字母数字及 _ -，长度≤32，且首字符为字母数字）")
+					}
+					if _, ok := seen[ins.ID]; ok {
+						problems = append(problems, prefix+"id 重复")
+					}
+					seen[ins.ID] = struct{}{}
+				}
+				if strings.TrimSpace(ins.Name) == "" {
+					problems = append(problems, prefix+"name 不能为空")
+				}
+				problems = append(problems, validateUnraidInstance(prefix, ins)...)
+			}
+		} else {
+			problems = append(problems, validateUnraidInstance("unraid.", cfg.Unraid.EffectiveInstances()[0])...)
 		}
 		if strings.TrimSpace(cfg.Unraid.LogsField) == "" {
 			problems = append(problems, "unraid.logs_field 不能为空")
@@ -422,24 +765,34 @@ func validate(cfg Config) error {
 			}
 		}
 
-		hasWebGUIFallback := strings.TrimSpace(cfg.Unraid.WebGUICSRFToken) != "" ||
-			strings.TrimSpace(cfg.Unraid.WebGUICookie) != "" ||
-			strings.TrimSpace(cfg.Unraid.WebGUICommandURL) != "" ||
-			strings.TrimSpace(cfg.Unraid.WebGUIEventsURL) != ""
-		if hasWebGUIFallback {
-			if strings.TrimSpace(cfg.Unraid.WebGUICSRFToken) == "" {
-				problems = append(problems, "unraid.webgui_csrf_token 不能为空（启用 WebGUI 兜底时必填）")
+		if cfg.Unraid.Alert.Enabled == nil {
+			problems = append(problems, "unraid.alert.enabled 缺失（请设为 true/false）")
+		} else if *cfg.Unraid.Alert.Enabled {
+			if cfg.Unraid.Alert.Interval.ToDuration() <= 0 {
+				problems = append(problems, "unraid.alert.interval 不能为空且必须为正数（例如 5m）")
 			}
-			if strings.TrimSpace(cfg.Unraid.WebGUICommandURL) != "" {
-				u, err := url.Parse(cfg.Unraid.WebGUICommandURL)
-				if err != nil || u.Scheme == "" || u.Host == "" {
-					problems = append(problems, "unraid.webgui_command_url 不合法（示例：http://<ip>/webGui/include/StartCommand.php）")
-				}
+			if cfg.Unraid.Alert.Cooldown.ToDuration() <= 0 {
+				problems = append(problems, "unraid.alert.cooldown 不能为空且必须为正数（例如 30m）")
 			}
-			if strings.TrimSpace(cfg.Unraid.WebGUIEventsURL) != "" {
-				u, err := url.Parse(cfg.Unraid.WebGUIEventsURL)
-				if err != nil || u.Scheme == "" || u.Host == "" {
-					problems = append(problems, "unraid.webgui_events_url 不合法（示例：http://<ip>/plugins/dynamix.docker.manager/include/Events.php）")
+			if cfg.Unraid.Alert.DiskTempThreshold <= 0 || cfg.Unraid.Alert.DiskTempThreshold > 100 {
+				problems = append(problems, "unraid.alert.disk_temp_threshold 不合法（范围 1~100，单位 ℃）")
+			}
+		}
+
+		if cfg.Unraid.Notify.Enabled == nil {
+			problems = append(problems, "unraid.notify.enabled 缺失（请设为 true/false）")
+		} else if *cfg.Unraid.Notify.Enabled {
+			if cfg.Unraid.Notify.Interval.ToDuration() <= 0 {
+				problems = append(problems, "unraid.notify.interval 不能为空且必须为正数（例如 1m）")
+			}
+			if len(cfg.Unraid.Notify.Importance) == 0 {
+				problems = append(problems, "unraid.notify.importance 不能为空（可选 alert/warning/info）")
+			}
+			for i, v := range cfg.Unraid.Notify.Importance {
+				switch strings.ToLower(strings.TrimSpace(v)) {
+				case "alert", "warning", "info":
+				default:
+					problems = append(problems, fmt.Sprintf("unraid.notify.importance[%d] 不合法（可选 alert/warning/info）", i))
 				}
 			}
 		}
@@ -535,14 +888,74 @@ func validate(cfg Config) error {
 		}
 	}
 
-	if len(cfg.Auth.AllowedUserIDs) == 0 {
-		problems = append(problems, "auth.allowed_userids 不能为空（MVP 仅支持白名单）")
+	if len(cfg.Auth.AllowedUserIDs) == 0 && len(cfg.Auth.Bindings) == 0 {
+		problems = append(problems, "auth.allowed_userids 与 auth.bindings 不能同时为空")
+	}
+	problems = append(problems, validateAuthSubjects("auth.allowed_userids", cfg.Auth.AllowedUserIDs)...)
+	if cfg.Auth.DirectoryTTL.ToDuration() < 0 {
+		problems = append(problems, "auth.directory_ttl 不能为负数")
+	}
+	for name, perms := range cfg.Auth.Roles {
+		prefix := fmt.Sprintf("auth.roles.%s", name)
+		if strings.TrimSpace(name) == "" {
+			problems = append(problems, "auth.roles 角色名不能为空")
+			continue
+		}
+		if len(perms) == 0 {
+			problems = append(problems, prefix+" 权限列表不能为空")
+		}
+		for _, perm := range perms {
+			if !permissionPattern.MatchString(strings.TrimSpace(perm)) {
+				problems = append(problems, fmt.Sprintf("%s 权限 %q 不合法（示例：unraid.restart、pve.vm.*、*.view）", prefix, perm))
+			}
+		}
+	}
+	for i, b := range cfg.Auth.Bindings {
+		prefix := fmt.Sprintf("auth.bindings[%d].", i)
+		role := strings.TrimSpace(b.Role)
+		if role == "" {
+			problems = append(problems, prefix+"role 不能为空")
+		} else {
+			_, builtin := builtinAuthRoles[role]
+			_, custom := cfg.Auth.Roles[role]
+			if !builtin && !custom {
+				problems = append(problems, prefix+"role 未定义（内置：viewer/operator/admin，或在 auth.roles 中自定义）")
+			}
+		}
+		if len(b.Subjects) == 0 {
+			problems = append(problems, prefix+"subjects 不能为空")
+		}
+		problems = append(problems, validateAuthSubjects(prefix+"subjects", b.Subjects)...)
+		for _, pattern := range b.Scope.Containers {
+			if _, err := path.Match(pattern, ""); err != nil || strings.TrimSpace(pattern) == "" {
+				problems = append(problems, fmt.Sprintf("%sscope.containers 模式 %q 不合法", prefix, pattern))
+			}
+		}
+		for _, pattern := range b.Scope.VMs {
+			if _, err := path.Match(pattern, ""); err != nil || strings.TrimSpace(pattern) == "" {
+				problems = append(problems, fmt.Sprintf("%sscope.vms 模式 %q 不合法", prefix, pattern))
+			}
+		}
+		for _, r := range b.Scope.VMIDs {
+			m := vmidRangePattern.FindStringSubmatch(r)
+			if m == nil {
+				problems = append(problems, fmt.Sprintf("%sscope.vmids %q 不合法（示例：101 或 100-199）", prefix, r))
+				continue
+			}
+			if m[3] != "" {
+				lo, _ := strconv.Atoi(m[1])
+				hi, _ := strconv.Atoi(m[3])
+				if hi < lo {
+					problems = append(problems, fmt.Sprintf("%sscope.vmids %q 区间上限小于下限", prefix, r))
+				}
+			}
+		}
 	}
 
 	hasQinglong := len(cfg.Qinglong.Instances) > 0
 	hasPVE := len(cfg.PVE.Instances) > 0
 	if !hasUnraid && !hasQinglong && !hasPVE {
-		problems = append(problems, "至少配置一个后端服务：unraid 或 qinglong.instances 或 pve.instances")
+		problems = append(problems, "至少配置一个后端服务：unraid（endpoint 或 instances）或 qinglong.instances 或 pve.instances")
 	}
 
 	if len(problems) > 0 {
@@ -551,6 +964,126 @@ func validate(cfg Config) error {
 	return nil
 }
 
+// validateUnraidInstance 校验单台 Unraid 的连接、订阅与 WebGUI 兜底配置（prefix 为 unraid. 或 unraid.instances[i].）。
+func validateUnraidInstance(prefix string, ins UnraidInstance) []string {
+	var problems []string
+	if strings.TrimSpace(ins.Endpoint) == "" {
+		problems = append(problems, prefix+"endpoint 不能为空")
+	}
+	if strings.TrimSpace(ins.APIKey) == "" {
+		problems = append(problems, prefix+"api_key 不能为空")
+	}
+	if strings.TrimSpace(ins.SubscriptionURL) != "" {
+		u, err := url.Parse(ins.SubscriptionURL)
+		if err != nil || (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+			problems = append(problems, prefix+"subscription_url 不合法（示例：ws://<ip>/graphql 或 wss://<host>/graphql）")
+		}
+	}
+	switch strings.TrimSpace(ins.SubscriptionProtocol) {
+	case "", "graphql-transport-ws", "graphql-ws":
+	default:
+		problems = append(problems, prefix+"subscription_protocol 仅支持 graphql-transport-ws 或 graphql-ws")
+	}
+
+	hasWebGUIFallback := strings.TrimSpace(ins.WebGUICSRFToken) != "" ||
+		strings.TrimSpace(ins.WebGUICookie) != "" ||
+		strings.TrimSpace(ins.WebGUICommandURL) != "" ||
+		strings.TrimSpace(ins.WebGUIEventsURL) != ""
+	if !hasWebGUIFallback {
+		return problems
+	}
+	if strings.TrimSpace(ins.WebGUICSRFToken) == "" {
+		problems = append(problems, prefix+"webgui_csrf_token 不能为空（启用 WebGUI 兜底时必填）")
+	}
+	if strings.TrimSpace(ins.WebGUICommandURL) != "" {
+		u, err := url.Parse(ins.WebGUICommandURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, prefix+"webgui_command_url 不合法（示例：http://<ip>/webGui/include/StartCommand.php）")
+		}
+	}
+	if strings.TrimSpace(ins.WebGUIEventsURL) != "" {
+		u, err := url.Parse(ins.WebGUIEventsURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, prefix+"webgui_events_url 不合法（示例：http://<ip>/plugins/dynamix.docker.manager/include/Events.php）")
+		}
+	}
+	return problems
+}
+
+// validateWeComMenu 校验应用自定义菜单配置（服务列表与自定义按钮）；服务 Key 是否已登记由 app 按注册表校验。
+func validateWeComMenu(menu WeComMenuConfig) []string {
+	var problems []string
+	if len(menu.Services) > 2 {
+		problems = append(problems, "wecom.menu.services 最多 2 个（一级菜单共 3 个，首个固定为“常用”）")
+	}
+	seen := make(map[string]struct{}, len(menu.Services))
+	for _, svc := range menu.Services {
+		if _, ok := seen[svc]; ok {
+			problems = append(problems, fmt.Sprintf("wecom.menu.services 重复：%s", svc))
+		}
+		seen[svc] = struct{}{}
+	}
+
+	if len(menu.Buttons) > 3 {
+		problems = append(problems, "wecom.menu.buttons 最多 3 个一级菜单")
+	}
+	for i, b := range menu.Buttons {
+		field := fmt.Sprintf("wecom.menu.buttons[%d]", i)
+		problems = append(problems, validateMenuButton(field, b)...)
+		if len(b.SubButtons) > 5 {
+			problems = append(problems, field+".sub_buttons 最多 5 个")
+		}
+		for j, sb := range b.SubButtons {
+			subField := fmt.Sprintf("%s.sub_buttons[%d]", field, j)
+			if len(sb.SubButtons) > 0 {
+				problems = append(problems, subField+" 不支持三级菜单")
+			}
+			problems = append(problems, validateMenuButton(subField, sb)...)
+		}
+	}
+	return problems
+}
+
+func validateMenuButton(field string, b MenuButtonConfig) []string {
+	var problems []string
+	if strings.TrimSpace(b.Name) == "" {
+		problems = append(problems, field+".name 不能为空")
+	}
+	if len(b.SubButtons) > 0 {
+		return problems
+	}
+	switch b.Type {
+	case "click":
+		if strings.TrimSpace(b.Key) == "" {
+			problems = append(problems, field+".key 不能为空（type=click）")
+		}
+	case "view":
+		if strings.TrimSpace(b.URL) == "" {
+			problems = append(problems, field+".url 不能为空（type=view）")
+		}
+	default:
+		problems = append(problems, field+".type 不合法（仅支持 click/view）")
+	}
+	return problems
+}
+
+// validateAuthSubjects 校验授权主体：普通 UserID 原样接受，department:/tag: 前缀必须跟正整数 ID。
+func validateAuthSubjects(field string, subjects []string) []string {
+	var problems []string
+	for _, s := range subjects {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			problems = append(problems, field+" 存在空主体")
+			continue
+		}
+		lower := strings.ToLower(s)
+		if (strings.HasPrefix(lower, "department:") || strings.HasPrefix(lower, "tag:")) && !contactSubjectPattern.MatchString(s) {
+			problems = append(problems, fmt.Sprintf("%s 主体 %q 不合法（示例：department:2、tag:1）", field, s))
+		}
+	}
+	return problems
+}
+
 func isGraphQLTypeRef(s string) bool {
 	input := strings.TrimSpace(s)
 	if input == "" {
@@ -127,6 +127,15 @@ func TestApplyDefaults(t *testing.T) {
 	if cfg.Core.StateTTL.ToDuration() != 30*time.Minute {
 		t.Fatalf("Core.StateTTL = %s, want %s", cfg.Core.StateTTL.ToDuration(), 30*time.Minute)
 	}
+	if cfg.Core.StateBackend != "memory" || cfg.Core.StatePath != "data/state.jsonl" {
+		t.Fatalf("Core.StateBackend/StatePath = %q/%q, want memory/data/state.jsonl", cfg.Core.StateBackend, cfg.Core.StatePath)
+	}
+	if cfg.Core.Jobs.Workers != 4 || cfg.Core.Jobs.QueueSize != 32 || cfg.Core.Jobs.Timeout.ToDuration() != 5*time.Minute {
+		t.Fatalf("Core.Jobs = %+v, want workers=4 queue_size=32 timeout=5m", cfg.Core.Jobs)
+	}
+	if cfg.Auth.DirectoryTTL.ToDuration() != 10*time.Minute {
+		t.Fatalf("Auth.DirectoryTTL = %s, want %s", cfg.Auth.DirectoryTTL.ToDuration(), 10*time.Minute)
+	}
 	if cfg.WeCom.APIBaseURL != "https://qyapi.weixin.qq.com/cgi-bin" {
 		t.Fatalf("WeCom.APIBaseURL = %q, want default", cfg.WeCom.APIBaseURL)
 	}
@@ -261,6 +270,333 @@ func TestValidate_UnraidGraphQLConfig(t *testing.T) {
 	}
 }
 
+func TestValidate_CoreStateBackend(t *testing.T) {
+	t.Parallel()
+
+	base := func() Config {
+		cfg := Config{
+			WeCom: WeComConfig{
+				CorpID:         "ww",
+				AgentID:        1,
+				Secret:         "s",
+				Token:          "t",
+				EncodingAESKey: "k",
+			},
+			Auth: AuthConfig{
+				AllowedUserIDs: []string{"u"},
+			},
+			Unraid: UnraidConfig{
+				Endpoint: "http://x/graphql",
+				APIKey:   "k",
+			},
+		}
+		applyDefaults(&cfg)
+		return cfg
+	}
+
+	cfg := base()
+	cfg.Core.StateBackend = "file"
+	if err := validate(cfg); err != nil {
+		t.Fatalf("validate(file) error: %v", err)
+	}
+
+	cfg = base()
+	cfg.Core.StateBackend = "redis"
+	if err := validate(cfg); err == nil {
+		t.Fatalf("validate(redis) error = nil, want not nil")
+	}
+}
+
+func TestValidate_CoreApproval(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{
+		WeCom: WeComConfig{
+			CorpID:         "ww",
+			AgentID:        1,
+			Secret:         "s",
+			Token:          "t",
+			EncodingAESKey: "k",
+		},
+		Auth: AuthConfig{
+			AllowedUserIDs: []string{"u"},
+		},
+		Unraid: UnraidConfig{
+			Endpoint: "http://x/graphql",
+			APIKey:   "k",
+		},
+	}
+	cfg.Core.Approval.Actions = []string{"pve.*.stop", "unraid.force_update"}
+	applyDefaults(&cfg)
+	if cfg.Core.Approval.Timeout.ToDuration() != 10*time.Minute {
+		t.Fatalf("Core.Approval.Timeout = %s, want 10m", cfg.Core.Approval.Timeout.ToDuration())
+	}
+	if err := validate(cfg); err != nil {
+		t.Fatalf("validate() error: %v", err)
+	}
+
+	cfg.Core.Approval.Actions = []string{"PVE.stop"}
+	if err := validate(cfg); err == nil {
+		t.Fatalf("validate(bad action) error = nil, want not nil")
+	}
+}
+
+func TestValidate_WeComMenu(t *testing.T) {
+	t.Parallel()
+
+	base := func() Config {
+		return Config{
+			WeCom: WeComConfig{
+				CorpID:         "ww",
+				AgentID:        1,
+				Secret:         "s",
+				Token:          "t",
+				EncodingAESKey: "k",
+			},
+			Auth:   AuthConfig{AllowedUserIDs: []string{"u"}},
+			Unraid: UnraidConfig{Endpoint: "http://x/graphql", APIKey: "k"},
+		}
+	}
+
+	cfg := base()
+	cfg.WeCom.Menu = WeComMenuConfig{
+		Services: []string{" PVE "},
+		Buttons: []MenuButtonConfig{
+			{Name: "常用", SubButtons: []MenuButtonConfig{{Name: "操作菜单", Key: "core.menu"}, {Name: "面板", URL: "https://nas.example.com"}}},
+		},
+	}
+	applyDefaults(&cfg)
+	if got := cfg.WeCom.Menu.Services[0]; got != "pve" {
+		t.Fatalf("Menu.Services[0] = %q, want pve", got)
+	}
+	if sub := cfg.WeCom.Menu.Buttons[0].SubButtons; sub[0].Type != "click" || sub[1].Type != "view" || cfg.WeCom.Menu.Buttons[0].Type != "" {
+		t.Fatalf("Menu.Buttons types = %+v, want click/view inferred", cfg.WeCom.Menu.Buttons)
+	}
+	if err := validate(cfg); err != nil {
+		t.Fatalf("validate() error: %v", err)
+	}
+
+	leaf := MenuButtonConfig{Type: "click", Name: "x", Key: "k"}
+	cases := map[string]WeComMenuConfig{
+		"dup service":      {Services: []string{"pve", "pve"}},
+		"too many service": {Services: []string{"pve", "unraid", "qinglong"}},
+		"too many top":     {Buttons: []MenuButtonConfig{leaf, leaf, leaf, leaf}},
+		"too many sub":     {Buttons: []MenuButtonConfig{{Name: "a", SubButtons: []MenuButtonConfig{leaf, leaf, leaf, leaf, leaf, leaf}}}},
+		"click no key":     {Buttons: []MenuButtonConfig{{Type: "click", Name: "a"}}},
+		"view no url":      {Buttons: []MenuButtonConfig{{Type: "view", Name: "a"}}},
+		"no name":          {Buttons: []MenuButtonConfig{{Type: "click", Key: "k"}}},
+		"third level":      {Buttons: []MenuButtonConfig{{Name: "a", SubButtons: []MenuButtonConfig{{Name: "b", SubButtons: []MenuButtonConfig{leaf}}}}}},
+	}
+	for name, menu := range cases {
+		cfg := base()
+		cfg.WeCom.Menu = menu
+		if err := validate(cfg); err == nil {
+			t.Fatalf("validate(%s) error = nil, want not nil", name)
+		}
+	}
+}
+
+func TestValidate_CoreScheduler(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{
+		WeCom: WeComConfig{
+			CorpID:         "ww",
+			AgentID:        1,
+			Secret:         "s",
+			Token:          "t",
+			EncodingAESKey: "k",
+		},
+		Auth: AuthConfig{
+			AllowedUserIDs: []string{"u"},
+		},
+		Unraid: UnraidConfig{
+			Endpoint: "http://x/graphql",
+			APIKey:   "k",
+		},
+	}
+	cfg.Core.Scheduler.Timezone = " UTC "
+	applyDefaults(&cfg)
+	if cfg.Core.Scheduler.MaxPerUser != 20 || cfg.Core.Scheduler.Timezone != "UTC" {
+		t.Fatalf("Core.Scheduler = %+v, want UTC/20", cfg.Core.Scheduler)
+	}
+	if err := validate(cfg); err != nil {
+		t.Fatalf("validate() error: %v", err)
+	}
+
+	cfg.Core.Scheduler.Timezone = "Mars/Olympus"
+	if err := validate(cfg); err == nil {
+		t.Fatalf("validate(bad timezone) error = nil, want not nil")
+	}
+}
+
+func TestValidate_AuthRolesAndBindings(t *testing.T) {
+	t.Parallel()
+
+	base := func() Config {
+		cfg := Config{
+			WeCom: WeComConfig{
+				CorpID:         "ww",
+				AgentID:        1,
+				Secret:         "s",
+				Token:          "t",
+				EncodingAESKey: "k",
+			},
+			Unraid: UnraidConfig{
+				Endpoint: "http://x/graphql",
+				APIKey:   "k",
+			},
+		}
+		applyDefaults(&cfg)
+		return cfg
+	}
+
+	cfg := base()
+	cfg.Auth = AuthConfig{
+		Roles: map[string][]string{"family": {"unraid.view", "unraid.restart", "pve.vm.*"}},
+		Bindings: []RoleBindingConfig{
+			{Role: "family", Subjects: []string{"mom"}},
+			{Role: "viewer", Subjects: []string{"guest", "department:2", "Tag:7"}},
+			{Role: "operator", Subjects: []string{"kid"}, Scope: AuthScopeConfig{
+				Instances:  []string{"home"},
+				Containers: []string{"jellyfin", "media-*"},
+				VMIDs:      []string{"101", "200-299"},
+				Tags:       []string{"kids"},
+			}},
+		},
+	}
+	if err := validate(cfg); err != nil {
+		t.Fatalf("validate(bindings only) error: %v", err)
+	}
+
+	cases := map[string]AuthConfig{
+		"empty":          {},
+		"unknown role":   {Bindings: []RoleBindingConfig{{Role: "root", Subjects: []string{"u"}}}},
+		"no subjects":    {Bindings: []RoleBindingConfig{{Role: "admin"}}},
+		"bad perm":       {Roles: map[string][]string{"x": {"Unraid.Stop"}}, Bindings: []RoleBindingConfig{{Role: "x", Subjects: []string{"u"}}}},
+		"empty segment":  {Roles: map[string][]string{"x": {"pve..stop"}}, Bindings: []RoleBindingConfig{{Role: "x", Subjects: []string{"u"}}}},
+		"bad department": {Bindings: []RoleBindingConfig{{Role: "admin", Subjects: []string{"department:abc"}}}},
+		"bad tag":        {AllowedUserIDs: []string{"tag:0"}},
+		"bad vmid":       {Bindings: []RoleBindingConfig{{Role: "admin", Subjects: []string{"u"}, Scope: AuthScopeConfig{VMIDs: []string{"300-200"}}}}},
+		"bad container":  {Bindings: []RoleBindingConfig{{Role: "admin", Subjects: []string{"u"}, Scope: AuthScopeConfig{Containers: []string{"media-["}}}}},
+	}
+	for name, auth := range cases {
+		cfg := base()
+		cfg.Auth = auth
+		if err := validate(cfg); err == nil {
+			t.Fatalf("validate(%s) error = nil, want not nil", name)
+		}
+	}
+}
+
+func TestValidate_UnraidInstances(t *testing.T) {
+	t.Parallel()
+
+	base := func() Config {
+		cfg := Config{
+			Server: ServerConfig{
+				ListenAddr:        ":8080",
+				HTTPClientTimeout: Duration(15 * time.Second),
+				ReadHeaderTimeout: Duration(10 * time.Second),
+			},
+			Core: CoreConfig{
+				StateTTL: Duration(30 * time.Minute),
+			},
+			WeCom: WeComConfig{
+				CorpID:         "ww",
+				AgentID:        1,
+				Secret:         "s",
+				Token:          "t",
+				EncodingAESKey: "k",
+				APIBaseURL:     "https://qyapi.weixin.qq.com/cgi-bin",
+			},
+			Auth: AuthConfig{
+				AllowedUserIDs: []string{"u"},
+			},
+			Unraid: UnraidConfig{
+				Origin: "wecom-home-ops",
+				Instances: []UnraidInstance{
+					{ID: "main", Name: "主 NAS", Endpoint: "http://main/graphql", APIKey: "k"},
+					{ID: "backup", Name: "备份 NAS", Endpoint: "http://backup/graphql", APIKey: "k2", Origin: "custom"},
+				},
+			},
+		}
+		applyDefaults(&cfg)
+		return cfg
+	}
+
+	cfg := base()
+	if err := validate(cfg); err != nil {
+		t.Fatalf("validate() error: %v", err)
+	}
+	if got := cfg.Unraid.Instances[0].Origin; got != "wecom-home-ops" {
+		t.Fatalf("Instances[0].Origin = %q, want inherited unraid.origin", got)
+	}
+	if got := cfg.Unraid.Instances[1].Origin; got != "custom" {
+		t.Fatalf("Instances[1].Origin = %q, want custom", got)
+	}
+
+	cfg = base()
+	cfg.Unraid.Endpoint = "http://legacy/graphql"
+	cfg.Unraid.APIKey = "k"
+	if err := validate(cfg); err == nil || !strings.Contains(err.Error(), "不能同时配置") {
+		t.Fatalf("validate() error = %v, want legacy/instances conflict", err)
+	}
+
+	cfg = base()
+	cfg.Unraid.Instances[1].ID = "main"
+	if err := validate(cfg); err == nil {
+		t.Fatalf("validate() error = nil, want duplicate id error")
+	}
+
+	cfg = base()
+	cfg.Unraid.Instances[0].APIKey = ""
+	if err := validate(cfg); err == nil || !strings.Contains(err.Error(), "unraid.instances[0].api_key") {
+		t.Fatalf("validate() error = %v, want instance api_key error", err)
+	}
+
+	cfg = base()
+	if !*cfg.Unraid.Alert.Enabled || cfg.Unraid.Alert.DiskTempThreshold != 50 || cfg.Unraid.Alert.Interval != Duration(5*time.Minute) {
+		t.Fatalf("Unraid.Alert defaults = %+v", cfg.Unraid.Alert)
+	}
+	cfg.Unraid.Alert.DiskTempThreshold = 120
+	if err := validate(cfg); err == nil || !strings.Contains(err.Error(), "unraid.alert.disk_temp_threshold") {
+		t.Fatalf("validate() error = %v, want disk_temp_threshold error", err)
+	}
+
+	cfg = base()
+	if got := strings.Join(cfg.Unraid.Notify.Importance, ","); !*cfg.Unraid.Notify.Enabled || got != "alert,warning" {
+		t.Fatalf("Unraid.Notify defaults = %+v", cfg.Unraid.Notify)
+	}
+	cfg.Unraid.Notify.Importance = []string{"alert", "critical"}
+	if err := validate(cfg); err == nil || !strings.Contains(err.Error(), "unraid.notify.importance[1]") {
+		t.Fatalf("validate() error = %v, want notify importance error", err)
+	}
+
+	cfg = base()
+	cfg.Unraid.Instances[0].SubscriptionURL = "wss://main/graphql"
+	cfg.Unraid.Instances[0].SubscriptionProtocol = "graphql-ws"
+	if err := validate(cfg); err != nil {
+		t.Fatalf("validate() error: %v", err)
+	}
+	cfg.Unraid.Instances[0].SubscriptionURL = "http://main/graphql"
+	cfg.Unraid.Instances[1].SubscriptionProtocol = "subscriptions-transport-ws"
+	if err := validate(cfg); err == nil || !strings.Contains(err.Error(), "unraid.instances[0].subscription_url") ||
+		!strings.Contains(err.Error(), "unraid.instances[1].subscription_protocol") {
+		t.Fatalf("validate() error = %v, want subscription_url/subscription_protocol errors", err)
+	}
+
+	legacy := UnraidConfig{Endpoint: "http://x/graphql", APIKey: "k", Origin: "o", WebGUICookie: "c", SubscriptionURL: "ws://x/api/graphql"}
+	got := legacy.EffectiveInstances()
+	if len(got) != 1 || got[0].ID != "" || got[0].Endpoint != "http://x/graphql" || got[0].Origin != "o" || got[0].WebGUICookie != "c" ||
+		got[0].SubscriptionURL != "ws://x/api/graphql" {
+		t.Fatalf("EffectiveInstances() = %+v, want single legacy instance", got)
+	}
+	if got := (UnraidConfig{}).EffectiveInstances(); got != nil {
+		t.Fatalf("EffectiveInstances() = %+v, want nil", got)
+	}
+}
+
 func TestValidate_QinglongInstanceID(t *testing.T) {
 	t.Parallel()
 
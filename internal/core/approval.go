@@ -0,0 +1,266 @@
+package core
+
+// approval.go 实现危险操作的双人审批：命中策略的确认动作先挂起为审批单，由另一名具备同等权限的账号在时限内批准后才执行。
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// ApprovalPolicy 声明需要双人审批的动作。
+type ApprovalPolicy struct {
+	// Actions 为权限模式（如 pve.vm.stop、pve.*.stop、unraid.force_update）；为空表示不启用审批。
+	Actions []string
+	// Timeout 为审批时限（默认 10m），超时自动作废并通知发起人。
+	Timeout time.Duration
+}
+
+// Requires 判断执行权限 perm 是否需要审批。
+func (p ApprovalPolicy) Requires(perm string) bool {
+	for _, pattern := range p.Actions {
+		if MatchPermission(pattern, perm) {
+			return true
+		}
+	}
+	return false
+}
+
+type ApprovalStatus string
+
+const (
+	ApprovalStatusPending  ApprovalStatus = "pending"
+	ApprovalStatusApproved ApprovalStatus = "approved"
+	ApprovalStatusRejected ApprovalStatus = "rejected"
+	ApprovalStatusExpired  ApprovalStatus = "expired"
+)
+
+func (s ApprovalStatus) DisplayName() string {
+	switch s {
+	case ApprovalStatusPending:
+		return "待审批"
+	case ApprovalStatusApproved:
+		return "已批准"
+	case ApprovalStatusRejected:
+		return "已驳回"
+	case ApprovalStatusExpired:
+		return "已超时"
+	default:
+		return "未知"
+	}
+}
+
+// approval 为一张审批单；仅保存在内存中（动作闭包无法持久化），服务重启后未决审批单失效。
+type approval struct {
+	id        string
+	requester string
+	approvers []string
+	action    ConfirmedAction
+	// history 为获批执行后写入发起人操作记录的条目（定时任务发起时为 nil）。
+	history *HistoryEntry
+
+	status    ApprovalStatus
+	decidedBy string
+	createdAt time.Time
+	deadline  time.Time
+	timer     *time.Timer
+}
+
+// approvalBook 管理未决与已决审批单；已决/超时的审批单保留一个审批时限（用于提示重复点击的审批人）后删除。
+type approvalBook struct {
+	policy ApprovalPolicy
+
+	mu    sync.Mutex
+	seq   int64
+	items map[string]*approval
+}
+
+func newApprovalBook(policy ApprovalPolicy) *approvalBook {
+	if policy.Timeout <= 0 {
+		policy.Timeout = 10 * time.Minute
+	}
+	return &approvalBook{policy: policy, items: make(map[string]*approval)}
+}
+
+func (b *approvalBook) requires(perm string) bool {
+	return b != nil && b.policy.Requires(perm)
+}
+
+// requestApproval 为确认动作创建审批单，并向其他具备同等权限（含作用范围）的账号推送审批卡片。
+func (r *Router) requestApproval(ctx context.Context, userID string, action ConfirmedAction, history *HistoryEntry) error {
+	perm := action.RequiredPermission()
+	var approvers []string
+	for _, id := range r.auth.UsersWith(perm) {
+		if id != userID && r.deniedPermission(id, action) == "" {
+			approvers = append(approvers, id)
+		}
+	}
+	if len(approvers) == 0 {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: fmt.Sprintf("无法执行：%s 需要双人审批，但没有其他具备权限 %s 的账号可审批。", action.Title(), perm),
+		})
+	}
+
+	b := r.approvals
+	now := time.Now()
+	b.mu.Lock()
+	b.seq++
+	a := &approval{
+		id:        strconv.FormatInt(b.seq, 10),
+		requester: userID,
+		approvers: approvers,
+		action:    action,
+		history:   history,
+		status:    ApprovalStatusPending,
+		createdAt: now,
+		deadline:  now.Add(b.policy.Timeout),
+	}
+	b.items[a.id] = a
+	a.timer = time.AfterFunc(b.policy.Timeout, func() { r.expireApproval(a.id) })
+	b.mu.Unlock()
+
+	slog.Info("审批单已创建",
+		"approval_id", a.id,
+		"user_id", userID,
+		"service", action.ServiceKey,
+		"action", string(action.Action),
+		"target", action.Target,
+		"approvers", len(approvers),
+	)
+	r.auditApproval(ctx, a, AuditResultApprovalPending, "")
+
+	deadline := a.deadline.Local().Format("15:04:05")
+	for _, id := range approvers {
+		if err := r.WeCom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+			ToUser: id,
+			Card:   wecom.NewApprovalCard(a.id, userID, action.Title(), deadline),
+		}); err != nil {
+			slog.Error("审批卡片发送失败", "error", err, "approval_id", a.id, "approver", id)
+		}
+	}
+	return r.WeCom.SendText(ctx, wecom.TextMessage{
+		ToUser: userID,
+		Content: fmt.Sprintf("已提交审批（#%s）：%s\n该操作需另一名管理员批准，请在 %s 前联系审批人（%d 人）处理。",
+			a.id, action.Title(), deadline, len(approvers)),
+	})
+}
+
+// handleApprovalEvent 处理审批卡片上的批准/驳回。
+func (r *Router) handleApprovalEvent(ctx context.Context, userID, key string) error {
+	approve := strings.HasPrefix(key, wecom.EventKeyApprovalApprovePrefix)
+	id := strings.TrimPrefix(strings.TrimPrefix(key, wecom.EventKeyApprovalApprovePrefix), wecom.EventKeyApprovalRejectPrefix)
+	if r.approvals == nil {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未启用双人审批。"})
+	}
+
+	b := r.approvals
+	b.mu.Lock()
+	a, ok := b.items[id]
+	if !ok {
+		b.mu.Unlock()
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "审批单不存在或已失效（服务重启后未决审批单会作废）。"})
+	}
+	if a.status != ApprovalStatusPending {
+		status, by := a.status, a.decidedBy
+		b.mu.Unlock()
+		msg := fmt.Sprintf("审批单 #%s %s。", id, status.DisplayName())
+		if by != "" {
+			msg = fmt.Sprintf("审批单 #%s 已由 %s 处理（%s）。", id, by, status.DisplayName())
+		}
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: msg})
+	}
+	if a.requester == userID {
+		b.mu.Unlock()
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "不能审批自己发起的操作，请由其他管理员处理。"})
+	}
+	if perm := r.deniedPermission(userID, a.action); perm != "" {
+		b.mu.Unlock()
+		return r.sendForbidden(ctx, userID, perm)
+	}
+	a.status = ApprovalStatusRejected
+	if approve {
+		a.status = ApprovalStatusApproved
+	}
+	a.decidedBy = userID
+	// 计时器保留：到原定时限时由 expireApproval 删除已决审批单。
+	b.mu.Unlock()
+
+	slog.Info("审批单已处理", "approval_id", a.id, "approver", userID, "status", string(a.status))
+	title := a.action.Title()
+	for _, id := range a.approvers {
+		if id == userID {
+			continue
+		}
+		_ = r.WeCom.SendText(ctx, wecom.TextMessage{
+			ToUser:  id,
+			Content: fmt.Sprintf("审批单 #%s（%s）已由 %s %s，无需处理。", a.id, title, userID, a.status.DisplayName()),
+		})
+	}
+
+	if !approve {
+		r.auditApproval(ctx, a, AuditResultRejected, userID)
+		_ = r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: fmt.Sprintf("已驳回审批单 #%s：%s", a.id, title)})
+		return r.WeCom.SendText(ctx, wecom.TextMessage{
+			ToUser:  a.requester,
+			Content: fmt.Sprintf("审批驳回（#%s）：%s 已被 %s 驳回，未执行。", a.id, title, userID),
+		})
+	}
+
+	r.auditApproval(ctx, a, AuditResultApproved, userID)
+	_ = r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: fmt.Sprintf("已批准审批单 #%s：%s", a.id, title)})
+	_ = r.WeCom.SendText(ctx, wecom.TextMessage{
+		ToUser:  a.requester,
+		Content: fmt.Sprintf("审批通过（#%s）：%s 已由 %s 批准，开始执行。", a.id, title, userID),
+	})
+
+	action := a.action
+	action.ApprovedBy = userID
+	return r.executeConfirmed(ctx, a.requester, action, a.history)
+}
+
+// expireApproval 在审批时限到达后作废未决审批单并通知发起人，超时的审批单再保留一个时限后删除；
+// 已决审批单直接删除。
+func (r *Router) expireApproval(id string) {
+	b := r.approvals
+	b.mu.Lock()
+	a, ok := b.items[id]
+	if !ok {
+		b.mu.Unlock()
+		return
+	}
+	if a.status != ApprovalStatusPending {
+		delete(b.items, id)
+		b.mu.Unlock()
+		return
+	}
+	a.status = ApprovalStatusExpired
+	a.timer = time.AfterFunc(b.policy.Timeout, func() { r.expireApproval(id) })
+	b.mu.Unlock()
+
+	ctx := context.Background()
+	slog.Info("审批单已超时", "approval_id", a.id, "user_id", a.requester)
+	r.auditApproval(ctx, a, AuditResultExpired, "")
+	_ = r.WeCom.SendText(ctx, wecom.TextMessage{
+		ToUser:  a.requester,
+		Content: fmt.Sprintf("审批超时（#%s）：%s 未在时限内获批，已作废，如需执行请重新发起。", a.id, a.action.Title()),
+	})
+}
+
+func (r *Router) auditApproval(ctx context.Context, a *approval, result AuditResult, approver string) {
+	recordAudit(ctx, r.audit, AuditEntry{
+		UserID:     a.requester,
+		ServiceKey: a.action.ServiceKey,
+		InstanceID: a.action.InstanceID,
+		Action:     a.action.Action,
+		Target:     a.action.Target,
+		ApprovalID: a.id,
+		Approver:   approver,
+		Result:     result,
+	})
+}
@@ -0,0 +1,263 @@
+// 双人审批单元测试。
+package core
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// approvalRecorder 按收件人记录消息（过期通知在计时器 goroutine 中发送，需并发安全）。
+type approvalRecorder struct {
+	mu    sync.Mutex
+	texts []wecom.TextMessage
+	cards []wecom.TemplateCardMessage
+}
+
+func (r *approvalRecorder) SendText(_ context.Context, msg wecom.TextMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.texts = append(r.texts, msg)
+	return nil
+}
+
+func (r *approvalRecorder) SendTemplateCard(_ context.Context, msg wecom.TemplateCardMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cards = append(r.cards, msg)
+	return nil
+}
+
+func (r *approvalRecorder) cardsTo(userID string) []wecom.TemplateCardMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []wecom.TemplateCardMessage
+	for _, c := range r.cards {
+		if c.ToUser == userID {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (r *approvalRecorder) waitText(t *testing.T, userID, substr string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		for _, m := range r.texts {
+			if m.ToUser == userID && strings.Contains(m.Content, substr) {
+				r.mu.Unlock()
+				return
+			}
+		}
+		r.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t.Fatalf("%s 未收到包含 %q 的消息，实际=%v", userID, substr, r.texts)
+}
+
+func newApprovalTestRouter(t *testing.T, timeout time.Duration) (*Router, *approvalRecorder, *memAuditSink, StateStore, *int) {
+	t.Helper()
+	auth, err := NewAuthorizer(AuthorizerConfig{
+		Bindings: []RoleBinding{
+			{Role: RoleOperator, Subjects: []string{"alice", "bob"}},
+			{Role: RoleViewer, Subjects: []string{"guest"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+
+	var runs int
+	p := &fakeSyncAsyncProvider{fakeProvider: fakeProvider{key: "unraid", name: "Unraid 容器"}}
+	rec := &approvalRecorder{}
+	sink := &memAuditSink{}
+	state := NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+
+	r := NewRouter(RouterDeps{
+		WeCom:     rec,
+		Auth:      auth,
+		Providers: []ServiceProvider{&countingProvider{fakeSyncAsyncProvider: p, runs: &runs}},
+		State:     state,
+		Audit:     sink,
+		Approval:  ApprovalPolicy{Actions: []string{"unraid.stop"}, Timeout: timeout},
+	})
+	return r, rec, sink, state, &runs
+}
+
+// countingProvider 统计动作实际执行次数。
+type countingProvider struct {
+	*fakeSyncAsyncProvider
+	runs *int
+}
+
+func (p *countingProvider) PrepareConfirm(ctx context.Context, userID string) (ConfirmedAction, bool, error) {
+	action, handled, err := p.fakeSyncAsyncProvider.PrepareConfirm(ctx, userID)
+	run := action.Run
+	action.Run = func(ctx context.Context, progress ProgressFunc) (string, error) {
+		*p.runs++
+		return run(ctx, progress)
+	}
+	return action, handled, err
+}
+
+func requestStop(t *testing.T, r *Router, state StateStore, userID string) {
+	t.Helper()
+	state.Set(userID, ConversationState{ServiceKey: "unraid", Step: StepAwaitingConfirm, Action: ActionUnraidStop})
+	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{FromUserName: userID, MsgType: "text", Content: "确认"}); err != nil {
+		t.Fatalf("HandleMessage(confirm) error: %v", err)
+	}
+}
+
+func clickApproval(t *testing.T, r *Router, userID, key string) {
+	t.Helper()
+	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{FromUserName: userID, MsgType: "event", Event: "template_card_event", EventKey: key}); err != nil {
+		t.Fatalf("HandleMessage(%s) error: %v", key, err)
+	}
+}
+
+// size 返回审批簿中的审批单数量（含已决）。
+func (b *approvalBook) size() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.items)
+}
+
+func TestRouter_Approval_ApproveRunsAsRequester(t *testing.T) {
+	t.Parallel()
+
+	r, rec, sink, state, runs := newApprovalTestRouter(t, time.Minute)
+	requestStop(t, r, state, "alice")
+
+	if *runs != 0 {
+		t.Fatalf("runs = %d, want 0 before approval", *runs)
+	}
+	rec.waitText(t, "alice", "已提交审批（#1）")
+	if got := len(rec.cardsTo("bob")); got != 1 {
+		t.Fatalf("approval cards to bob = %d, want 1", got)
+	}
+	if got := len(rec.cardsTo("guest")) + len(rec.cardsTo("alice")); got != 0 {
+		t.Fatalf("approval cards to guest/alice = %d, want 0", got)
+	}
+
+	// 发起人不能自批。
+	clickApproval(t, r, "alice", wecom.EventKeyApprovalApprovePrefix+"1")
+	rec.waitText(t, "alice", "不能审批自己发起的操作")
+	// 无权限账号不能审批。
+	clickApproval(t, r, "guest", wecom.EventKeyApprovalApprovePrefix+"1")
+	rec.waitText(t, "guest", "unraid.stop")
+	if *runs != 0 {
+		t.Fatalf("runs = %d, want 0", *runs)
+	}
+
+	clickApproval(t, r, "bob", wecom.EventKeyApprovalApprovePrefix+"1")
+	rec.waitText(t, "alice", "审批通过（#1）")
+	rec.waitText(t, "alice", "执行成功")
+	if *runs != 1 {
+		t.Fatalf("runs = %d, want 1", *runs)
+	}
+
+	// 重复点击不会再次执行。
+	clickApproval(t, r, "bob", wecom.EventKeyApprovalApprovePrefix+"1")
+	rec.waitText(t, "bob", "已由 bob 处理")
+	if *runs != 1 {
+		t.Fatalf("runs = %d, want 1 after duplicate click", *runs)
+	}
+
+	var results []AuditResult
+	for _, e := range sink.snapshot() {
+		results = append(results, e.Result)
+		if e.Result == AuditResultSuccess && (e.UserID != "alice" || e.Approver != "bob") {
+			t.Fatalf("execution audit = %+v, want user alice approver bob", e)
+		}
+	}
+	want := []AuditResult{AuditResultApprovalPending, AuditResultApproved, AuditResultSuccess}
+	if len(results) != len(want) {
+		t.Fatalf("audit results = %v, want %v", results, want)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Fatalf("audit results = %v, want %v", results, want)
+		}
+	}
+}
+
+func TestRouter_Approval_RejectAndExpire(t *testing.T) {
+	t.Parallel()
+
+	r, rec, sink, state, runs := newApprovalTestRouter(t, 50*time.Millisecond)
+
+	requestStop(t, r, state, "alice")
+	clickApproval(t, r, "bob", wecom.EventKeyApprovalRejectPrefix+"1")
+	rec.waitText(t, "alice", "审批驳回（#1）")
+
+	requestStop(t, r, state, "bob")
+	rec.waitText(t, "bob", "审批超时（#2）")
+	clickApproval(t, r, "alice", wecom.EventKeyApprovalApprovePrefix+"2")
+	rec.waitText(t, "alice", "审批单 #2 已超时")
+
+	if *runs != 0 {
+		t.Fatalf("runs = %d, want 0", *runs)
+	}
+	var rejected, expired int
+	for _, e := range sink.snapshot() {
+		switch e.Result {
+		case AuditResultRejected:
+			rejected++
+		case AuditResultExpired:
+			expired++
+		}
+	}
+	if rejected != 1 || expired != 1 {
+		t.Fatalf("audit rejected=%d expired=%d, want 1/1", rejected, expired)
+	}
+
+	// 已决与超时的审批单在保留期后删除。
+	deadline := time.Now().Add(2 * time.Second)
+	for r.approvals.size() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("approval book size = %d, want 0", r.approvals.size())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	clickApproval(t, r, "alice", wecom.EventKeyApprovalApprovePrefix+"2")
+	rec.waitText(t, "alice", "审批单不存在或已失效")
+}
+
+func TestRouter_Approval_NoOtherApprover(t *testing.T) {
+	t.Parallel()
+
+	r, rec, _, state, runs := newApprovalTestRouter(t, time.Minute)
+	r.auth, _ = NewAuthorizer(AuthorizerConfig{Bindings: []RoleBinding{{Role: RoleOperator, Subjects: []string{"alice"}}}})
+
+	requestStop(t, r, state, "alice")
+	rec.waitText(t, "alice", "没有其他具备权限 unraid.stop 的账号")
+	if *runs != 0 {
+		t.Fatalf("runs = %d, want 0", *runs)
+	}
+}
+
+func TestApprovalPolicy_Requires(t *testing.T) {
+	t.Parallel()
+
+	p := ApprovalPolicy{Actions: []string{"pve.*.stop", "unraid.force_update"}}
+	for perm, want := range map[string]bool{
+		"pve.vm.stop":         true,
+		"pve.lxc.stop":        true,
+		"pve.vm.start":        false,
+		"unraid.force_update": true,
+		"unraid.restart":      false,
+	} {
+		if got := p.Requires(perm); got != want {
+			t.Fatalf("Requires(%q) = %v, want %v", perm, got, want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package core
+
+// audit.go 定义确认类操作的审计记录与落地接口（谁、何时、对哪个服务/实例/目标执行了什么、耗时与结果）。
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+type AuditResult string
+
+const (
+	AuditResultSuccess  AuditResult = "success"
+	AuditResultFailed   AuditResult = "failed"
+	AuditResultTimeout  AuditResult = "timeout"
+	AuditResultCanceled AuditResult = "canceled"
+
+	// 双人审批流转记录：发起（待审批）、批准、驳回、超时作废。
+	AuditResultApprovalPending AuditResult = "approval_pending"
+	AuditResultApproved        AuditResult = "approved"
+	AuditResultRejected        AuditResult = "rejected"
+	AuditResultExpired         AuditResult = "expired"
+)
+
+func (r AuditResult) DisplayName() string {
+	switch r {
+	case AuditResultSuccess:
+		return "成功"
+	case AuditResultFailed:
+		return "失败"
+	case AuditResultTimeout:
+		return "超时"
+	case AuditResultCanceled:
+		return "已取消"
+	case AuditResultApprovalPending:
+		return "待审批"
+	case AuditResultApproved:
+		return "已批准"
+	case AuditResultRejected:
+		return "已驳回"
+	case AuditResultExpired:
+		return "审批超时"
+	default:
+		return "未知"
+	}
+}
+
+// AuditEntry 为一次已确认操作的审计记录。
+type AuditEntry struct {
+	Time       time.Time   `json:"time"`
+	UserID     string      `json:"user_id"`
+	ServiceKey string      `json:"service"`
+	InstanceID string      `json:"instance_id,omitempty"`
+	Action     Action      `json:"action"`
+	Target     string      `json:"target,omitempty"`
+	JobID      string      `json:"job_id,omitempty"`
+	ApprovalID string      `json:"approval_id,omitempty"`
+	Approver   string      `json:"approver,omitempty"`
+	Result     AuditResult `json:"result"`
+	Error      string      `json:"error,omitempty"`
+	DurationMS int64       `json:"duration_ms"`
+}
+
+// AuditQuery 为审计查询条件；空字段表示不过滤，结果按时间倒序返回。
+type AuditQuery struct {
+	UserID     string
+	ServiceKey string
+	Limit      int
+}
+
+// AuditSink 为审计落地抽象（JSONL 文件 / SQLite 等），实现需并发安全。
+type AuditSink interface {
+	Record(ctx context.Context, entry AuditEntry) error
+	Query(ctx context.Context, q AuditQuery) ([]AuditEntry, error)
+	Close() error
+}
+
+// recordAudit 写入审计记录；sink 为空时跳过，写入失败仅记录日志，不影响业务回显。
+func recordAudit(ctx context.Context, sink AuditSink, entry AuditEntry) {
+	if sink == nil {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if err := sink.Record(ctx, entry); err != nil {
+		slog.Error("审计记录写入失败",
+			"error", err,
+			"user_id", entry.UserID,
+			"service", entry.ServiceKey,
+			"action", string(entry.Action),
+			"target", entry.Target,
+		)
+	}
+}
+
+// FormatAuditEntries 将审计记录渲染为文本（供“审计”命令使用）。
+func FormatAuditEntries(entries []AuditEntry) string {
+	if len(entries) == 0 {
+		return "暂无审计记录。"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "最近 %d 条操作审计：", len(entries))
+	for _, e := range entries {
+		service := e.ServiceKey
+		if ins := strings.TrimSpace(e.InstanceID); ins != "" {
+			service = service + "/" + ins
+		}
+		fmt.Fprintf(&b, "\n- %s %s [%s] %s %s",
+			e.Time.Local().Format("01-02 15:04:05"),
+			e.UserID,
+			service,
+			e.Action.DisplayName(),
+			strings.TrimSpace(e.Target),
+		)
+		fmt.Fprintf(&b, "：%s（%dms）", e.Result.DisplayName(), e.DurationMS)
+		if e.Approver != "" {
+			b.WriteString("，审批人 ")
+			b.WriteString(e.Approver)
+		}
+		if e.Error != "" {
+			b.WriteString("\n  错误：")
+			b.WriteString(e.Error)
+		}
+	}
+	return b.String()
+}
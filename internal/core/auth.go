@@ -0,0 +1,448 @@
+package core
+
+// auth.go 提供基于角色的授权：角色由权限串（如 unraid.stop、pve.vm.stop）组成，用户通过绑定获得角色。
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// 内置角色名。
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// core 自身的权限串；各服务权限由 Provider 约定（<service>.view / <service>.<action>）。
+const (
+	PermCoreMenuSync = "core.menu_sync"
+	PermCoreAudit    = "core.audit"
+)
+
+// BuiltinRoles 返回内置角色定义（可被配置中同名角色覆盖）。
+// 权限模式按“.”分段匹配：* 匹配单段，末段为 * 时匹配剩余任意段（例如 pve.* 覆盖 pve.vm.stop）。
+// operator 以 *.* 覆盖所有已登记服务（新增后端无需修改此处）；core 管理权限须显式授予。
+func BuiltinRoles() map[string][]string {
+	return map[string][]string{
+		RoleViewer:   {"*.view"},
+		RoleOperator: {"*.*"},
+		RoleAdmin:    {"*"},
+	}
+}
+
+// ServicePermission 拼接服务级权限串，例如 ServicePermission("pve", "vm", "stop") = "pve.vm.stop"。
+func ServicePermission(serviceKey string, parts ...string) string {
+	return strings.Join(append([]string{serviceKey}, parts...), ".")
+}
+
+// ViewPermission 返回服务的只读权限（进入菜单、查看状态/日志等）。
+func ViewPermission(serviceKey string) string {
+	return ServicePermission(serviceKey, "view")
+}
+
+// EventPermissionProvider 为 ServiceProvider 的可选扩展：声明 EventKey 所需权限。
+// 返回空串表示沿用默认的 <service>.view；Router 据此拦截事件并过滤卡片按钮。
+type EventPermissionProvider interface {
+	EventPermission(eventKey string) string
+}
+
+// ResourceAuthorizer 为 Provider 侧的对象级授权抽象（列表过滤与动作校验）。
+type ResourceAuthorizer interface {
+	CanAccess(userID, perm string, res Resource) bool
+}
+
+// Resource 描述授权判定的操作对象；零值字段表示该维度不参与判定（例如进入菜单时尚未选定目标）。
+type Resource struct {
+	InstanceID string
+	// Container 为 Unraid 容器名。
+	Container string
+	// VM 为 Unraid 虚拟机名。
+	VM string
+	// VMID 与 Tags 为 PVE 虚拟机/容器的标识与标签。
+	VMID int
+	Tags []string
+	// WholeInstance 表示作用于整台实例的操作（如 Unraid 阵列校验），仅由未限定目标维度的绑定覆盖。
+	WholeInstance bool
+}
+
+// Scope 限定一次角色绑定的作用范围；Instances 为空表示不限实例。
+// 目标维度（Containers/VMs/VMIDs/Tags）全部为空时不限目标；限定了任一目标维度时仅覆盖所列目标，
+// 其他类型的目标（如只列容器时的虚拟机）均不覆盖。
+type Scope struct {
+	// Instances 限定实例 ID（青龙/PVE/Unraid 等多实例服务）。
+	Instances []string
+	// Containers 为 Unraid 容器名通配模式（path.Match 语法，如 jellyfin、media-*），忽略大小写。
+	Containers []string
+	// VMs 为 Unraid 虚拟机名通配模式（语法同 Containers）。
+	VMs []string
+	// VMIDs 与 Tags 限定 PVE 目标：命中任一 VMID 区间或任一标签即可。
+	VMIDs []VMIDRange
+	Tags  []string
+}
+
+// VMIDRange 为闭区间 [Min, Max]。
+type VMIDRange struct {
+	Min int
+	Max int
+}
+
+// ParseVMIDRange 解析 "101" 或 "100-199" 形式的 VMID 区间。
+func ParseVMIDRange(s string) (VMIDRange, error) {
+	s = strings.TrimSpace(s)
+	lo, hi, isRange := strings.Cut(s, "-")
+	minID, err := strconv.Atoi(strings.TrimSpace(lo))
+	if err != nil || minID <= 0 {
+		return VMIDRange{}, fmt.Errorf("VMID 区间 %q 不合法（示例：101 或 100-199）", s)
+	}
+	maxID := minID
+	if isRange {
+		maxID, err = strconv.Atoi(strings.TrimSpace(hi))
+		if err != nil || maxID < minID {
+			return VMIDRange{}, fmt.Errorf("VMID 区间 %q 不合法（示例：101 或 100-199）", s)
+		}
+	}
+	return VMIDRange{Min: minID, Max: maxID}, nil
+}
+
+func (s *Scope) allows(res Resource) bool {
+	if s == nil {
+		return true
+	}
+	if len(s.Instances) > 0 && res.InstanceID != "" && !containsFold(s.Instances, res.InstanceID) {
+		return false
+	}
+	if !s.restrictsTargets() {
+		return true
+	}
+	if res.WholeInstance {
+		return false
+	}
+	if res.Container != "" && !matchFold(s.Containers, res.Container) {
+		return false
+	}
+	if res.VM != "" && !matchFold(s.VMs, res.VM) {
+		return false
+	}
+	if res.VMID > 0 {
+		matched := false
+		for _, r := range s.VMIDs {
+			if res.VMID >= r.Min && res.VMID <= r.Max {
+				matched = true
+				break
+			}
+		}
+		for i := 0; !matched && i < len(res.Tags); i++ {
+			matched = containsFold(s.Tags, res.Tags[i])
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// restrictsTargets 报告作用范围是否限定了任一目标维度（容器/虚拟机/VMID/标签）。
+func (s *Scope) restrictsTargets() bool {
+	return len(s.Containers) > 0 || len(s.VMs) > 0 || len(s.VMIDs) > 0 || len(s.Tags) > 0
+}
+
+// matchFold 判断 name 是否命中任一通配模式（path.Match 语法，忽略大小写）。
+func matchFold(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(strings.TrimSpace(pattern)), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, v string) bool {
+	v = strings.TrimSpace(v)
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), v) {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleBinding 将一组主体绑定到角色，可选 Scope 限定作用范围。
+// 主体为企业微信 UserID，或 department:<部门ID> / tag:<标签ID>（需配置 SubjectResolver）。
+type RoleBinding struct {
+	Subjects []string
+	Role     string
+	Scope    *Scope
+}
+
+// SubjectResolver 将通讯录主体（department:<id> / tag:<id>）解析为成员 UserID，由 wecom.Directory 实现。
+type SubjectResolver interface {
+	Members(ctx context.Context, subject string) ([]string, error)
+}
+
+type AuthorizerConfig struct {
+	// AdminUserIDs 为兼容旧配置的白名单账号，统一视为 admin。
+	AdminUserIDs []string
+	// Roles 为自定义角色（与内置角色同名时覆盖）。
+	Roles    map[string][]string
+	Bindings []RoleBinding
+	// Resolver 用于解析部门/标签主体；未引用此类主体时可为空。
+	Resolver SubjectResolver
+}
+
+// Authorizer 判定用户是否已授权及是否具备某项权限；构造后只读，可并发使用。
+// 部门/标签主体的成员在判定时经 Resolver 实时解析（缓存由 Resolver 负责），通讯录变动无需重启。
+type Authorizer struct {
+	grants map[string][]grant
+
+	resolver    SubjectResolver
+	groups      []string
+	groupGrants map[string][]grant
+}
+
+// subjectResolveTimeout 为判定时单次解析部门/标签成员的超时。
+const subjectResolveTimeout = 5 * time.Second
+
+// grant 为一条权限模式及其作用范围（scope 为空表示不限）。
+type grant struct {
+	pattern string
+	scope   *Scope
+}
+
+func NewAuthorizer(cfg AuthorizerConfig) (*Authorizer, error) {
+	roles := BuiltinRoles()
+	for name, perms := range cfg.Roles {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, errors.New("auth: 角色名不能为空")
+		}
+		for _, p := range perms {
+			if err := ValidatePermissionPattern(p); err != nil {
+				return nil, fmt.Errorf("auth: 角色 %s: %w", name, err)
+			}
+		}
+		roles[name] = perms
+	}
+
+	a := &Authorizer{
+		grants:      make(map[string][]grant),
+		resolver:    cfg.Resolver,
+		groupGrants: make(map[string][]grant),
+	}
+	for _, id := range cfg.AdminUserIDs {
+		if err := a.grant(id, roles[RoleAdmin], nil); err != nil {
+			return nil, fmt.Errorf("auth: allowed_userids: %w", err)
+		}
+	}
+	for i, b := range cfg.Bindings {
+		perms, ok := roles[strings.TrimSpace(b.Role)]
+		if !ok {
+			return nil, fmt.Errorf("auth: bindings[%d] 引用了未知角色 %q", i, b.Role)
+		}
+		for _, s := range b.Subjects {
+			if err := a.grant(s, perms, b.Scope); err != nil {
+				return nil, fmt.Errorf("auth: bindings[%d]: %w", i, err)
+			}
+		}
+	}
+	sort.Strings(a.groups)
+	return a, nil
+}
+
+// NewAllowAllAuthorizer 将给定账号全部视为 admin（兼容旧的白名单模式）。
+func NewAllowAllAuthorizer(userIDs []string) *Authorizer {
+	a := &Authorizer{grants: make(map[string][]grant)}
+	for _, id := range userIDs {
+		a.grant(id, []string{"*"}, nil)
+	}
+	return a
+}
+
+func (a *Authorizer) grant(subject string, perms []string, scope *Scope) error {
+	subject = strings.TrimSpace(subject)
+	if subject == "" {
+		return nil
+	}
+	target := a.grants
+	if kind, id, ok := wecom.ParseContactSubject(subject); ok {
+		if a.resolver == nil {
+			return fmt.Errorf("主体 %s 需要企业微信通讯录解析，但未配置 Resolver", subject)
+		}
+		subject = kind + ":" + strconv.Itoa(id)
+		if _, exists := a.groupGrants[subject]; !exists {
+			a.groups = append(a.groups, subject)
+		}
+		target = a.groupGrants
+	}
+	for _, p := range perms {
+		target[subject] = append(target[subject], grant{pattern: p, scope: scope})
+	}
+	if _, ok := target[subject]; !ok {
+		target[subject] = nil
+	}
+	return nil
+}
+
+// grantsFor 汇总用户的直接授权与所属部门/标签的授权；ok=false 表示用户未被任何绑定覆盖。
+func (a *Authorizer) grantsFor(userID string) (grants []grant, ok bool) {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return nil, false
+	}
+	grants, ok = a.grants[userID]
+	for _, subject := range a.groups {
+		if a.isMember(subject, userID) {
+			grants = append(grants[:len(grants):len(grants)], a.groupGrants[subject]...)
+			ok = true
+		}
+	}
+	return grants, ok
+}
+
+func (a *Authorizer) isMember(subject, userID string) bool {
+	for _, id := range a.members(subject) {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// members 解析部门/标签成员；解析失败时按“非成员”处理（拒绝优先）。
+func (a *Authorizer) members(subject string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), subjectResolveTimeout)
+	defer cancel()
+	ids, err := a.resolver.Members(ctx, subject)
+	if err != nil {
+		slog.Warn("auth 解析通讯录主体失败", "subject", subject, "error", err)
+		return nil
+	}
+	return ids
+}
+
+// Allowed 表示用户至少被授予了一个角色（未授权用户直接拒绝访问）。
+func (a *Authorizer) Allowed(userID string) bool {
+	if a == nil {
+		return false
+	}
+	_, ok := a.grantsFor(userID)
+	return ok
+}
+
+// Can 判断用户是否具备权限 perm（任一作用范围内即可）；perm 为空视为无需权限。
+// 用于菜单/按钮级判定，具体对象需再经 CanAccess 校验作用范围。
+func (a *Authorizer) Can(userID, perm string) bool {
+	return a.CanAccess(userID, perm, Resource{})
+}
+
+// CanAccess 判断用户能否对对象 res 行使权限 perm：需存在一条权限匹配且作用范围覆盖 res 的授权。
+func (a *Authorizer) CanAccess(userID, perm string, res Resource) bool {
+	if a == nil {
+		return false
+	}
+	grants, ok := a.grantsFor(userID)
+	if !ok {
+		return false
+	}
+	if strings.TrimSpace(perm) == "" {
+		return true
+	}
+	for _, g := range grants {
+		if MatchPermission(g.pattern, perm) && g.scope.allows(res) {
+			return true
+		}
+	}
+	return false
+}
+
+// Users 返回所有已授权用户（按字典序，含部门/标签成员），用于广播类通知。
+func (a *Authorizer) Users() []string {
+	return a.UsersWith("")
+}
+
+// UsersWith 返回具备权限 perm 的用户（按字典序）。
+func (a *Authorizer) UsersWith(perm string) []string {
+	return a.UsersWithAccess(perm, Resource{})
+}
+
+// UsersWithAccess 返回能对对象 res 行使权限 perm 的用户（按字典序），用于按实例/目标推送的通知。
+func (a *Authorizer) UsersWithAccess(perm string, res Resource) []string {
+	if a == nil {
+		return nil
+	}
+	candidates := make(map[string]struct{}, len(a.grants))
+	for id := range a.grants {
+		candidates[id] = struct{}{}
+	}
+	for _, subject := range a.groups {
+		for _, id := range a.members(subject) {
+			candidates[id] = struct{}{}
+		}
+	}
+	var out []string
+	for id := range candidates {
+		if a.CanAccess(id, perm, res) {
+			out = append(out, id)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// MatchPermission 判断权限模式 pattern 是否覆盖权限串 perm。
+// 首段为 * 的多段模式（如 *.view、*.*）仅匹配服务权限，不匹配 core.*；单独的 * 匹配全部。
+func MatchPermission(pattern, perm string) bool {
+	pattern = strings.TrimSpace(pattern)
+	perm = strings.TrimSpace(perm)
+	if pattern == "" || perm == "" {
+		return false
+	}
+	ps := strings.Split(pattern, ".")
+	ts := strings.Split(perm, ".")
+	if len(ps) > 1 && ps[0] == "*" && ts[0] == "core" {
+		return false
+	}
+	for i, seg := range ps {
+		if i >= len(ts) {
+			return false
+		}
+		if seg == "*" && i == len(ps)-1 {
+			return true
+		}
+		if seg != "*" && seg != ts[i] {
+			return false
+		}
+	}
+	return len(ps) == len(ts)
+}
+
+// ValidatePermissionPattern 校验权限模式：由“.”分隔的非空段组成，段内仅允许小写字母、数字、_ 或单独的 *。
+func ValidatePermissionPattern(pattern string) error {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return errors.New("权限不能为空")
+	}
+	for _, seg := range strings.Split(pattern, ".") {
+		if seg == "*" {
+			continue
+		}
+		if seg == "" {
+			return fmt.Errorf("权限 %q 不合法（存在空段）", pattern)
+		}
+		for _, ch := range seg {
+			if !(ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9')) {
+				return fmt.Errorf("权限 %q 不合法（仅允许小写字母、数字、_ 与 *）", pattern)
+			}
+		}
+	}
+	return nil
+}
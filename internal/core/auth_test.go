@@ -0,0 +1,232 @@
+// Authorizer 角色授权单元测试。
+package core
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMatchPermission(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		pattern, perm string
+		want          bool
+	}{
+		{"*", "pve.vm.stop", true},
+		{"*.view", "unraid.view", true},
+		{"*.view", "unraid.stop", false},
+		{"*.view", "pve.vm.view", false},
+		{"pve.*", "pve.vm.stop", true},
+		{"pve.vm.*", "pve.vm.stop", true},
+		{"pve.vm.*", "pve.lxc.stop", false},
+		{"pve.*.stop", "pve.lxc.stop", true},
+		{"unraid.stop", "unraid.stop", true},
+		{"unraid.stop", "unraid.restart", false},
+		{"unraid", "unraid.view", false},
+		{"", "unraid.view", false},
+		{"*.*", "nas.share.view", true},
+		{"*.*", "core.audit", false},
+		{"*", "core.audit", true},
+	}
+	for _, tc := range cases {
+		if got := MatchPermission(tc.pattern, tc.perm); got != tc.want {
+			t.Fatalf("MatchPermission(%q, %q) = %v, want %v", tc.pattern, tc.perm, got, tc.want)
+		}
+	}
+}
+
+func TestAuthorizer_RolesAndBindings(t *testing.T) {
+	t.Parallel()
+
+	a, err := NewAuthorizer(AuthorizerConfig{
+		AdminUserIDs: []string{"root"},
+		Roles:        map[string][]string{"family": {"unraid.view", "unraid.restart"}},
+		Bindings: []RoleBinding{
+			{Role: RoleViewer, Subjects: []string{"guest"}},
+			{Role: RoleOperator, Subjects: []string{"ops"}},
+			{Role: "family", Subjects: []string{"mom"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+
+	checks := []struct {
+		user, perm string
+		want       bool
+	}{
+		{"root", PermCoreMenuSync, true},
+		{"guest", "pve.view", true},
+		{"guest", "pve.vm.stop", false},
+		{"ops", "pve.vm.stop", true},
+		{"ops", PermCoreMenuSync, false},
+		{"ops", PermCoreAudit, false},
+		{"ops", "nas.share.stop", true},
+		{"mom", "unraid.restart", true},
+		{"mom", "unraid.stop", false},
+		{"mom", "pve.view", false},
+		{"stranger", "unraid.view", false},
+	}
+	for _, c := range checks {
+		if got := a.Can(c.user, c.perm); got != c.want {
+			t.Fatalf("Can(%q, %q) = %v, want %v", c.user, c.perm, got, c.want)
+		}
+	}
+	if a.Allowed("stranger") || !a.Allowed("mom") {
+		t.Fatalf("Allowed() mismatch")
+	}
+	if got := a.UsersWith("pve.view"); !reflect.DeepEqual(got, []string{"guest", "ops", "root"}) {
+		t.Fatalf("UsersWith(pve.view) = %v", got)
+	}
+}
+
+func TestNewAuthorizer_UnknownRole(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewAuthorizer(AuthorizerConfig{Bindings: []RoleBinding{{Role: "root", Subjects: []string{"u"}}}}); err == nil {
+		t.Fatalf("NewAuthorizer() error = nil, want not nil")
+	}
+	if _, err := NewAuthorizer(AuthorizerConfig{Roles: map[string][]string{"x": {"Bad.Perm"}}}); err == nil {
+		t.Fatalf("NewAuthorizer(bad perm) error = nil, want not nil")
+	}
+}
+
+func TestAuthorizer_ScopedBindings(t *testing.T) {
+	t.Parallel()
+
+	a, err := NewAuthorizer(AuthorizerConfig{
+		Bindings: []RoleBinding{
+			{Subjects: []string{"mom"}, Role: RoleOperator, Scope: &Scope{Containers: []string{"jellyfin", "media-*"}}},
+			{Subjects: []string{"kid"}, Role: RoleOperator, Scope: &Scope{
+				Instances: []string{"home"},
+				VMIDs:     []VMIDRange{{Min: 100, Max: 199}},
+				Tags:      []string{"media"},
+			}},
+			{Subjects: []string{"dad"}, Role: RoleOperator, Scope: &Scope{VMs: []string{"win*"}}},
+			{Subjects: []string{"ops"}, Role: RoleOperator, Scope: &Scope{Instances: []string{"home"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+
+	checks := []struct {
+		user, perm string
+		res        Resource
+		want       bool
+	}{
+		{"mom", "unraid.restart", Resource{}, true},
+		{"mom", "unraid.restart", Resource{Container: "Jellyfin"}, true},
+		{"mom", "unraid.restart", Resource{Container: "media-sonarr"}, true},
+		{"mom", "unraid.restart", Resource{Container: "homeassistant"}, false},
+		{"kid", "pve.vm.start", Resource{InstanceID: "home", VMID: 150}, true},
+		{"kid", "pve.vm.start", Resource{InstanceID: "home", VMID: 250}, false},
+		{"kid", "pve.vm.start", Resource{InstanceID: "home", VMID: 250, Tags: []string{"Media"}}, true},
+		{"kid", "pve.vm.start", Resource{InstanceID: "lab", VMID: 150}, false},
+		{"kid", "pve.view", Resource{InstanceID: "home"}, true},
+		{"mom", "unraid.vm.stop", Resource{VM: "win11"}, false},
+		{"kid", "unraid.vm.stop", Resource{InstanceID: "home", VM: "win11"}, false},
+		{"dad", "unraid.vm.stop", Resource{VM: "Win11"}, true},
+		{"dad", "unraid.vm.stop", Resource{VM: "ubuntu"}, false},
+		{"dad", "unraid.view", Resource{InstanceID: "home"}, true},
+		{"dad", "unraid.restart", Resource{Container: "plex"}, false},
+		{"mom", "pve.vm.stop", Resource{VMID: 150}, false},
+		{"mom", "unraid.parity.start", Resource{InstanceID: "home", WholeInstance: true}, false},
+		{"kid", "unraid.parity.start", Resource{InstanceID: "home", WholeInstance: true}, false},
+		{"ops", "unraid.parity.start", Resource{InstanceID: "home", WholeInstance: true}, true},
+		{"ops", "unraid.parity.start", Resource{InstanceID: "lab", WholeInstance: true}, false},
+	}
+	for _, c := range checks {
+		if got := a.CanAccess(c.user, c.perm, c.res); got != c.want {
+			t.Fatalf("CanAccess(%q, %q, %+v) = %v, want %v", c.user, c.perm, c.res, got, c.want)
+		}
+	}
+
+	// 按实例推送的整机通知仅发给作用范围覆盖该实例且未限定目标的用户。
+	if got := a.UsersWithAccess("unraid.notify.alert", Resource{InstanceID: "home", WholeInstance: true}); !reflect.DeepEqual(got, []string{"ops"}) {
+		t.Fatalf("UsersWithAccess(home) = %v, want [ops]", got)
+	}
+	if got := a.UsersWithAccess("unraid.notify.alert", Resource{InstanceID: "lab", WholeInstance: true}); len(got) != 0 {
+		t.Fatalf("UsersWithAccess(lab) = %v, want none", got)
+	}
+}
+
+func TestParseVMIDRange(t *testing.T) {
+	t.Parallel()
+
+	if got, err := ParseVMIDRange("101"); err != nil || got != (VMIDRange{Min: 101, Max: 101}) {
+		t.Fatalf("ParseVMIDRange(101) = %+v, %v", got, err)
+	}
+	if got, err := ParseVMIDRange(" 100 - 199 "); err != nil || got != (VMIDRange{Min: 100, Max: 199}) {
+		t.Fatalf("ParseVMIDRange(100-199) = %+v, %v", got, err)
+	}
+	for _, bad := range []string{"", "abc", "0", "200-100", "100-"} {
+		if _, err := ParseVMIDRange(bad); err == nil {
+			t.Fatalf("ParseVMIDRange(%q) error = nil, want not nil", bad)
+		}
+	}
+}
+
+type fakeSubjectResolver struct {
+	members map[string][]string
+}
+
+func (f fakeSubjectResolver) Members(_ context.Context, subject string) ([]string, error) {
+	ids, ok := f.members[subject]
+	if !ok {
+		return nil, errors.New("unknown subject")
+	}
+	return ids, nil
+}
+
+func TestAuthorizer_DepartmentAndTagSubjects(t *testing.T) {
+	t.Parallel()
+
+	resolver := fakeSubjectResolver{members: map[string][]string{
+		"department:2": {"alice", "bob"},
+		"tag:1":        {"bob", "carol"},
+	}}
+	a, err := NewAuthorizer(AuthorizerConfig{
+		AdminUserIDs: []string{"root"},
+		Bindings: []RoleBinding{
+			{Subjects: []string{"Department:2"}, Role: RoleViewer},
+			{Subjects: []string{"tag:1"}, Role: RoleOperator, Scope: &Scope{Containers: []string{"jellyfin"}}},
+			{Subjects: []string{"tag:9"}, Role: RoleAdmin},
+		},
+		Resolver: resolver,
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+
+	checks := []struct {
+		user, perm string
+		res        Resource
+		want       bool
+	}{
+		{"alice", "unraid.view", Resource{}, true},
+		{"alice", "unraid.restart", Resource{}, false},
+		{"bob", "unraid.restart", Resource{Container: "jellyfin"}, true},
+		{"bob", "unraid.restart", Resource{Container: "plex"}, false},
+		{"carol", "pve.view", Resource{}, true},
+		{"mallory", "unraid.view", Resource{}, false},
+	}
+	for _, c := range checks {
+		if got := a.CanAccess(c.user, c.perm, c.res); got != c.want {
+			t.Fatalf("CanAccess(%q, %q, %+v) = %v, want %v", c.user, c.perm, c.res, got, c.want)
+		}
+	}
+	if a.Allowed("mallory") || !a.Allowed("carol") {
+		t.Fatalf("Allowed() mismatch")
+	}
+	if got := a.Users(); !reflect.DeepEqual(got, []string{"alice", "bob", "carol", "root"}) {
+		t.Fatalf("Users() = %v", got)
+	}
+
+	if _, err := NewAuthorizer(AuthorizerConfig{Bindings: []RoleBinding{{Subjects: []string{"tag:1"}, Role: RoleViewer}}}); err == nil {
+		t.Fatalf("NewAuthorizer(no resolver) error = nil, want not nil")
+	}
+}
@@ -0,0 +1,168 @@
+package core
+
+// batch.go 实现批量操作：多个同类目标合并为一次确认，执行时逐个目标复核授权并汇总各目标结果。
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	// MaxBatchTargets 为单次批量操作的目标上限。
+	MaxBatchTargets = 20
+	// batchConcurrency 为批量执行时同时进行的目标数。
+	batchConcurrency = 4
+	// batchTargetPreview 为批量动作摘要中最多列出的目标数。
+	batchTargetPreview = 5
+)
+
+// NewBatchAction 将同一服务的多个确认动作合并为一个：Run 以有限并发逐个执行，
+// 返回按目标列出的结果报告；任一目标失败时整体返回错误（错误信息中同样包含完整报告）。
+func NewBatchAction(action Action, items []ConfirmedAction) ConfirmedAction {
+	if len(items) == 1 {
+		return items[0]
+	}
+
+	targets := make([]string, 0, len(items))
+	instanceID := ""
+	for i, it := range items {
+		targets = append(targets, it.Target)
+		if i == 0 {
+			instanceID = it.InstanceID
+		} else if it.InstanceID != instanceID {
+			instanceID = ""
+		}
+	}
+
+	var serviceKey, perm string
+	if len(items) > 0 {
+		serviceKey = items[0].ServiceKey
+		perm = items[0].RequiredPermission()
+	}
+
+	return ConfirmedAction{
+		ServiceKey: serviceKey,
+		InstanceID: instanceID,
+		Action:     action,
+		Target:     summarizeBatchTargets(targets),
+		Permission: perm,
+		Items:      items,
+		Run: func(ctx context.Context, progress ProgressFunc) (string, error) {
+			return runBatch(ctx, action, items, progress)
+		},
+	}
+}
+
+func runBatch(ctx context.Context, action Action, items []ConfirmedAction, progress ProgressFunc) (string, error) {
+	errs := make([]error, len(items))
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i, it := range items {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = fmt.Errorf("未执行：%w", ctx.Err())
+				return
+			}
+			defer func() { <-sem }()
+			if it.Run == nil {
+				errs[i] = errJobActionMissing
+				return
+			}
+			_, errs[i] = safeRunAction(ctx, it, progress)
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	var b strings.Builder
+	for i, it := range items {
+		if errs[i] != nil {
+			failed++
+			fmt.Fprintf(&b, "\n- [失败] %s：%s", it.Target, errs[i].Error())
+			continue
+		}
+		fmt.Fprintf(&b, "\n- [成功] %s", it.Target)
+	}
+	report := fmt.Sprintf("批量%s 共 %d 项：成功 %d，失败 %d%s", action.DisplayName(), len(items), len(items)-failed, failed, b.String())
+	if failed > 0 {
+		return "", errors.New(report)
+	}
+	return report, nil
+}
+
+// summarizeBatchTargets 生成批量目标摘要（用于标题与审计）：目标较多时仅列出前几项。
+func summarizeBatchTargets(targets []string) string {
+	if len(targets) <= batchTargetPreview {
+		return strings.Join(targets, "、")
+	}
+	return fmt.Sprintf("%s 等 %d 项", strings.Join(targets[:batchTargetPreview], "、"), len(targets))
+}
+
+// FormatBatchConfirm 渲染批量确认提示文本（逐行列出全部目标），与确认卡片一同下发作为文本兜底。
+func FormatBatchConfirm(action Action, targets []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "确认批量执行：%s（共 %d 项）", action.DisplayName(), len(targets))
+	for i, t := range targets {
+		fmt.Fprintf(&b, "\n%d. %s", i+1, t)
+	}
+	b.WriteString("\n回复“确认”继续，回复“取消”终止。")
+	return b.String()
+}
+
+// deniedPermission 复核 userID 能否执行 action（批量动作逐个目标按作用范围判定），返回缺失的权限串；允许时返回空。
+func (r *Router) deniedPermission(userID string, action ConfirmedAction) string {
+	if len(action.Items) == 0 {
+		perm := action.RequiredPermission()
+		if !r.auth.CanAccess(userID, perm, action.Resource) {
+			return perm
+		}
+		return ""
+	}
+	for _, it := range action.Items {
+		if perm := r.deniedPermission(userID, it); perm != "" {
+			return perm
+		}
+	}
+	return ""
+}
+
+// requiresApproval 判断动作是否命中审批策略；批量动作任一目标命中即整体审批。
+func (r *Router) requiresApproval(action ConfirmedAction) bool {
+	if len(action.Items) == 0 {
+		return r.approvals.requires(action.RequiredPermission())
+	}
+	for _, it := range action.Items {
+		if r.requiresApproval(it) {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitTargets 将“sonarr radarr”“12,15，18”“a、b”等输入拆分为目标列表（去重并保持顺序）。
+func SplitTargets(input string) []string {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		switch r {
+		case ',', '，', '、', ';', '；':
+			return true
+		}
+		return unicode.IsSpace(r)
+	})
+	seen := make(map[string]struct{}, len(fields))
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if _, ok := seen[f]; ok {
+			continue
+		}
+		seen[f] = struct{}{}
+		out = append(out, f)
+	}
+	return out
+}
@@ -0,0 +1,143 @@
+// 批量操作单元测试。
+package core
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+func TestSplitTargets(t *testing.T) {
+	t.Parallel()
+
+	got := SplitTargets(" sonarr radarr,lidarr，sonarr、 12；15 ")
+	want := []string{"sonarr", "radarr", "lidarr", "12", "15"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("SplitTargets() = %v, want %v", got, want)
+	}
+}
+
+func TestNewBatchAction_AggregatesResults(t *testing.T) {
+	t.Parallel()
+
+	var runs int32
+	item := func(target string, err error) ConfirmedAction {
+		return ConfirmedAction{
+			ServiceKey: "unraid",
+			Action:     ActionUnraidRestart,
+			Target:     target,
+			Resource:   Resource{Container: target},
+			Run: func(context.Context, ProgressFunc) (string, error) {
+				atomic.AddInt32(&runs, 1)
+				return "", err
+			},
+		}
+	}
+
+	single := NewBatchAction(ActionUnraidRestart, []ConfirmedAction{item("a", nil)})
+	if len(single.Items) != 0 || single.Target != "a" {
+		t.Fatalf("NewBatchAction(1 item) = %+v, want the item itself", single)
+	}
+
+	items := []ConfirmedAction{item("a", nil), item("b", errors.New("boom")), item("c", nil)}
+	for i := 0; i < 5; i++ {
+		items = append(items, item(string(rune('d'+i)), nil))
+	}
+	batch := NewBatchAction(ActionUnraidRestart, items)
+	if batch.ServiceKey != "unraid" || batch.RequiredPermission() != "unraid.restart" || len(batch.Items) != 8 {
+		t.Fatalf("NewBatchAction() = %+v", batch)
+	}
+	if batch.Target != "a、b、c、d、e 等 8 项" {
+		t.Fatalf("Target = %q", batch.Target)
+	}
+
+	_, err := batch.Run(context.Background(), func(string) {})
+	if err == nil {
+		t.Fatalf("Run() error = nil, want partial failure")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "共 8 项：成功 7，失败 1") || !strings.Contains(msg, "[失败] b：boom") {
+		t.Fatalf("Run() error = %q", msg)
+	}
+	if strings.Index(msg, "[成功] a") > strings.Index(msg, "[失败] b") || strings.Index(msg, "[失败] b") > strings.Index(msg, "[成功] c") {
+		t.Fatalf("report not in target order: %q", msg)
+	}
+	if got := atomic.LoadInt32(&runs); got != 8 {
+		t.Fatalf("runs = %d, want 8", got)
+	}
+}
+
+// batchProvider 返回两个容器的批量重启动作。
+type batchProvider struct {
+	fakeProvider
+	runs *int32
+}
+
+func (p *batchProvider) PrepareConfirm(context.Context, string) (ConfirmedAction, bool, error) {
+	var items []ConfirmedAction
+	for _, name := range []string{"media-a", "sonarr"} {
+		items = append(items, ConfirmedAction{
+			ServiceKey: "unraid",
+			Action:     ActionUnraidRestart,
+			Target:     name,
+			Resource:   Resource{Container: name},
+			Run: func(context.Context, ProgressFunc) (string, error) {
+				atomic.AddInt32(p.runs, 1)
+				return "", nil
+			},
+		})
+	}
+	return NewBatchAction(ActionUnraidRestart, items), true, nil
+}
+
+func TestRouter_BatchConfirm_RechecksEveryTarget(t *testing.T) {
+	t.Parallel()
+
+	auth, err := NewAuthorizer(AuthorizerConfig{
+		Bindings: []RoleBinding{
+			{Role: RoleOperator, Subjects: []string{"mom"}, Scope: &Scope{Containers: []string{"media-*"}}},
+			{Role: RoleOperator, Subjects: []string{"alice"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+
+	var runs int32
+	rec := &approvalRecorder{}
+	state := NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	r := NewRouter(RouterDeps{
+		WeCom:     rec,
+		Auth:      auth,
+		Providers: []ServiceProvider{&batchProvider{fakeProvider: fakeProvider{key: "unraid", name: "Unraid 容器"}, runs: &runs}},
+		State:     state,
+	})
+
+	confirm := func(userID string) {
+		t.Helper()
+		state.Set(userID, ConversationState{ServiceKey: "unraid", Step: StepAwaitingConfirm, Action: ActionUnraidRestart})
+		if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{FromUserName: userID, MsgType: "text", Content: "确认"}); err != nil {
+			t.Fatalf("HandleMessage(confirm) error: %v", err)
+		}
+	}
+
+	// mom 仅能操作 media-*：批量中包含 sonarr，整体拒绝。
+	confirm("mom")
+	rec.waitText(t, "mom", "无权限")
+	if got := atomic.LoadInt32(&runs); got != 0 {
+		t.Fatalf("runs = %d, want 0", got)
+	}
+
+	confirm("alice")
+	rec.waitText(t, "alice", "批量重启 共 2 项：成功 2，失败 0")
+	if got := atomic.LoadInt32(&runs); got != 2 {
+		t.Fatalf("runs = %d, want 2", got)
+	}
+}
@@ -0,0 +1,437 @@
+package core
+
+// command.go 提供声明式命令注册表：Provider 以“/unraid restart <container...>”形式声明一次性命令及其参数类型，
+// Router 统一完成匹配、参数校验、权限拦截与帮助生成，命令处理器再沿用各服务原有的确认流程。
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// ArgType 为命令参数类型。
+type ArgType int
+
+const (
+	// ArgString 为任意非空字符串。
+	ArgString ArgType = iota
+	// ArgInt 为正整数（如 VMID、任务 ID、日志行数）。
+	ArgInt
+	// ArgChoice 为限定取值（大小写不敏感），解析后统一为 Choices 中的写法。
+	ArgChoice
+)
+
+// CommandArg 描述一个命令参数。
+type CommandArg struct {
+	Name string
+	Type ArgType
+	// Choices 为 ArgChoice 的可选值。
+	Choices []string
+	// Optional 表示可省略；可选位置参数仅在取值符合类型时才消费输入。
+	Optional bool
+	// Variadic 仅用于最后一个位置参数：接收剩余全部取值（空格/逗号分隔）。
+	Variadic bool
+	// Verbatim 与 Variadic 同用：按原样保留剩余各词（不按逗号拆分、不去重），用于关键词、正则等自由文本。
+	Verbatim bool
+	// Prefix 非空时为命名参数（如 "@" 对应“@home”），可出现在任意位置且不占用位置参数。
+	Prefix string
+}
+
+// Command 为 Provider 声明的一次性命令。
+type Command struct {
+	// Path 为命令路径（不含前导“/”），如 "unraid restart"；首段为服务命名空间。
+	Path string
+	// Aliases 为等价路径，如 "ql run" 与 "qinglong run"。
+	Aliases []string
+	Summary string
+	Args    []CommandArg
+	// Permission 为 Router 预检的权限（仅判定是否具备该权限，作用范围由处理器按目标复核）；为空时要求 <service>.view。
+	Permission string
+	// Handler 在参数校验通过后调用；会话状态已清空，操作类命令应沿用服务自身的确认流程。
+	Handler func(ctx context.Context, userID string, args CommandArgs) error
+}
+
+// CommandProvider 为 ServiceProvider 的可选扩展：声明可直接输入的一次性命令。
+type CommandProvider interface {
+	Commands() []Command
+}
+
+// Usage 生成命令用法，例如“/pve start [vm|lxc] <vmid...> [@instance]”。
+func (c Command) Usage() string {
+	var b strings.Builder
+	b.WriteString("/")
+	b.WriteString(c.Path)
+	for _, a := range c.Args {
+		name := a.Name
+		if a.Type == ArgChoice {
+			name = strings.Join(a.Choices, "|")
+		}
+		if a.Variadic {
+			name += "..."
+		}
+		name = a.Prefix + name
+		if a.Optional {
+			fmt.Fprintf(&b, " [%s]", name)
+		} else {
+			fmt.Fprintf(&b, " <%s>", name)
+		}
+	}
+	return b.String()
+}
+
+// CommandArgs 为解析后的命令参数。
+type CommandArgs struct {
+	values map[string][]string
+}
+
+// Has 表示参数是否出现。
+func (a CommandArgs) Has(name string) bool {
+	return len(a.values[name]) > 0
+}
+
+// String 返回参数的首个取值；未出现时返回空串。
+func (a CommandArgs) String(name string) string {
+	if v := a.values[name]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Strings 返回参数的全部取值（可变参数）。
+func (a CommandArgs) Strings(name string) []string {
+	return append([]string(nil), a.values[name]...)
+}
+
+// Int 返回整数参数的首个取值；未出现时返回 0。
+func (a CommandArgs) Int(name string) int {
+	n, _ := strconv.Atoi(a.String(name))
+	return n
+}
+
+// Ints 返回整数参数的全部取值。
+func (a CommandArgs) Ints(name string) []int {
+	out := make([]int, 0, len(a.values[name]))
+	for _, v := range a.values[name] {
+		n, _ := strconv.Atoi(v)
+		out = append(out, n)
+	}
+	return out
+}
+
+// parseArgs 按声明解析参数：先提取带前缀的命名参数，其余按位置依次匹配。
+func (c Command) parseArgs(tokens []string) (CommandArgs, error) {
+	args := CommandArgs{values: make(map[string][]string)}
+
+	var positional []string
+	for _, tok := range tokens {
+		named := false
+		for _, a := range c.Args {
+			if a.Prefix == "" || !strings.HasPrefix(tok, a.Prefix) {
+				continue
+			}
+			v, err := a.convert(strings.TrimPrefix(tok, a.Prefix))
+			if err != nil {
+				return CommandArgs{}, err
+			}
+			args.values[a.Name] = []string{v}
+			named = true
+			break
+		}
+		if !named {
+			positional = append(positional, tok)
+		}
+	}
+
+	for _, a := range c.Args {
+		if a.Prefix != "" {
+			if !a.Optional && !args.Has(a.Name) {
+				return CommandArgs{}, fmt.Errorf("缺少参数 %s%s", a.Prefix, a.Name)
+			}
+			continue
+		}
+		if a.Variadic {
+			var vals []string
+			for _, tok := range positional {
+				parts := []string{tok}
+				if !a.Verbatim {
+					parts = SplitTargets(tok)
+				}
+				for _, part := range parts {
+					v, err := a.convert(part)
+					if err != nil {
+						return CommandArgs{}, err
+					}
+					vals = append(vals, v)
+				}
+			}
+			positional = nil
+			if len(vals) == 0 && !a.Optional {
+				return CommandArgs{}, fmt.Errorf("缺少参数 <%s>", a.Name)
+			}
+			args.values[a.Name] = vals
+			continue
+		}
+		if len(positional) == 0 {
+			if !a.Optional {
+				return CommandArgs{}, fmt.Errorf("缺少参数 <%s>", a.Name)
+			}
+			continue
+		}
+		v, err := a.convert(positional[0])
+		if err != nil {
+			if a.Optional {
+				continue
+			}
+			return CommandArgs{}, err
+		}
+		args.values[a.Name] = []string{v}
+		positional = positional[1:]
+	}
+	if len(positional) > 0 {
+		return CommandArgs{}, fmt.Errorf("多余的参数：%s", strings.Join(positional, " "))
+	}
+	return args, nil
+}
+
+// convert 按类型校验单个取值并返回规范写法。
+func (a CommandArg) convert(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("参数 %s 不能为空", a.Name)
+	}
+	switch a.Type {
+	case ArgInt:
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("参数 %s 需为正整数：%s", a.Name, raw)
+		}
+		return strconv.Itoa(n), nil
+	case ArgChoice:
+		for _, c := range a.Choices {
+			if strings.EqualFold(c, raw) {
+				return c, nil
+			}
+		}
+		return "", fmt.Errorf("参数 %s 仅支持 %s：%s", a.Name, strings.Join(a.Choices, "|"), raw)
+	default:
+		return raw, nil
+	}
+}
+
+type registeredCommand struct {
+	Command
+	serviceKey string
+	words      []string
+	// alias 表示由 Aliases 登记的等价路径（帮助中不重复列出）。
+	alias bool
+}
+
+// CommandRegistry 保存各服务声明的命令，按最长路径匹配输入。
+type CommandRegistry struct {
+	commands []*registeredCommand
+	// namespaces 为命令首段到服务 Key 的映射，用于“/unraid xxx”未匹配时回显该服务的命令列表。
+	namespaces map[string]string
+}
+
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{namespaces: make(map[string]string)}
+}
+
+// Register 登记服务的命令；路径冲突、参数声明不合法时返回错误。
+func (r *CommandRegistry) Register(serviceKey string, cmds ...Command) error {
+	for _, cmd := range cmds {
+		if cmd.Handler == nil {
+			return fmt.Errorf("命令 /%s 缺少处理器", cmd.Path)
+		}
+		if err := validateCommandArgs(cmd.Args); err != nil {
+			return fmt.Errorf("命令 /%s：%w", cmd.Path, err)
+		}
+		for i, p := range append([]string{cmd.Path}, cmd.Aliases...) {
+			words := strings.Fields(strings.ToLower(p))
+			if len(words) < 2 {
+				return fmt.Errorf("命令路径需包含命名空间与动作：/%s", p)
+			}
+			for _, c := range r.commands {
+				if strings.Join(c.words, " ") == strings.Join(words, " ") {
+					return fmt.Errorf("命令 /%s 重复注册", p)
+				}
+			}
+			if owner, ok := r.namespaces[words[0]]; ok && owner != serviceKey {
+				return fmt.Errorf("命令命名空间 %s 已被服务 %s 使用", words[0], owner)
+			}
+			r.namespaces[words[0]] = serviceKey
+			r.commands = append(r.commands, &registeredCommand{Command: cmd, serviceKey: serviceKey, words: words, alias: i > 0})
+		}
+	}
+	return nil
+}
+
+func validateCommandArgs(args []CommandArg) error {
+	variadic := ""
+	for _, a := range args {
+		if strings.TrimSpace(a.Name) == "" {
+			return errors.New("参数缺少名称")
+		}
+		if a.Type == ArgChoice && len(a.Choices) == 0 {
+			return fmt.Errorf("参数 %s 未声明可选值", a.Name)
+		}
+		if a.Verbatim && !a.Variadic {
+			return fmt.Errorf("参数 %s 声明 Verbatim 时须为可变参数", a.Name)
+		}
+		if a.Prefix != "" {
+			if a.Variadic {
+				return fmt.Errorf("命名参数 %s 不能为可变参数", a.Name)
+			}
+			continue
+		}
+		if variadic != "" {
+			return fmt.Errorf("可变参数 %s 必须是最后一个位置参数", variadic)
+		}
+		if a.Variadic {
+			variadic = a.Name
+		}
+	}
+	return nil
+}
+
+// lookup 解析“/unraid restart jellyfin”：返回匹配的命令与剩余参数。
+// namespace 非空表示首段属于某个服务（即使未匹配到具体命令），用于回显命令列表。
+func (r *CommandRegistry) lookup(text string) (cmd *registeredCommand, rest []string, namespace string) {
+	text = strings.TrimSpace(text)
+	if r == nil || !strings.HasPrefix(text, "/") {
+		return nil, nil, ""
+	}
+	fields := strings.Fields(strings.TrimPrefix(text, "/"))
+	if len(fields) == 0 {
+		return nil, nil, ""
+	}
+	lower := make([]string, len(fields))
+	for i, f := range fields {
+		lower[i] = strings.ToLower(f)
+	}
+	namespace = r.namespaces[lower[0]]
+	if namespace == "" {
+		return nil, nil, ""
+	}
+	cmd, ok := r.match(lower)
+	if !ok {
+		return nil, nil, namespace
+	}
+	return cmd, fields[len(cmd.words):], namespace
+}
+
+// match 返回与输入前缀匹配的最长命令路径。
+func (r *CommandRegistry) match(words []string) (*registeredCommand, bool) {
+	var best *registeredCommand
+	for _, c := range r.commands {
+		if len(c.words) > len(words) || (best != nil && len(c.words) <= len(best.words)) {
+			continue
+		}
+		matched := true
+		for i, w := range c.words {
+			if words[i] != w {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			best = c
+		}
+	}
+	return best, best != nil
+}
+
+// visible 返回用户有权限的命令（别名不重复列出），可按服务过滤。
+func (r *CommandRegistry) visible(can func(perm string) bool, serviceKey string) []*registeredCommand {
+	if r == nil {
+		return nil
+	}
+	var out []*registeredCommand
+	for _, c := range r.commands {
+		if c.alias {
+			continue
+		}
+		if serviceKey != "" && c.serviceKey != serviceKey {
+			continue
+		}
+		if can(c.permission()) {
+			out = append(out, c)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].serviceKey < out[j].serviceKey })
+	return out
+}
+
+func (c *registeredCommand) permission() string {
+	if strings.TrimSpace(c.Permission) != "" {
+		return c.Permission
+	}
+	return ViewPermission(c.serviceKey)
+}
+
+// formatCommandHelp 渲染命令列表（用法 + 说明）。
+func formatCommandHelp(cmds []*registeredCommand) string {
+	var b strings.Builder
+	for i, c := range cmds {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("- ")
+		b.WriteString(c.Usage())
+		if s := strings.TrimSpace(c.Summary); s != "" {
+			b.WriteString("：")
+			b.WriteString(s)
+		}
+		if len(c.Aliases) > 0 {
+			b.WriteString("（亦可 /")
+			b.WriteString(strings.Join(c.Aliases, "、/"))
+			b.WriteString("）")
+		}
+	}
+	return b.String()
+}
+
+// handleCommand 处理“/<服务> <动作> 参数...”形式的一次性命令；首段不是已注册命名空间时返回 handled=false。
+func (r *Router) handleCommand(ctx context.Context, userID, content string) (bool, error) {
+	cmd, rest, namespace := r.commands.lookup(content)
+	if namespace == "" {
+		return false, nil
+	}
+	if cmd == nil {
+		if len(strings.Fields(content)) == 1 {
+			// 仅输入“/unraid”：交由服务入口关键词进入菜单。
+			return false, nil
+		}
+		return true, r.sendCommandHelp(ctx, userID, namespace, "未知命令："+strings.TrimSpace(content))
+	}
+
+	if perm := cmd.permission(); !r.auth.Can(userID, perm) {
+		return true, r.sendForbidden(ctx, userID, perm)
+	}
+	args, err := cmd.parseArgs(rest)
+	if err != nil {
+		return true, r.WeCom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: fmt.Sprintf("参数错误：%s\n用法：%s", err.Error(), cmd.Usage()),
+		})
+	}
+
+	r.state.Clear(userID)
+	return true, cmd.Handler(ctx, userID, args)
+}
+
+// sendCommandHelp 回显服务下当前账号可用的命令。
+func (r *Router) sendCommandHelp(ctx context.Context, userID, serviceKey, title string) error {
+	cmds := r.commands.visible(func(perm string) bool { return r.auth.Can(userID, perm) }, serviceKey)
+	if len(cmds) == 0 {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: title + "\n当前账号没有可用的命令。"})
+	}
+	return r.WeCom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: title + "\n可用命令：\n" + formatCommandHelp(cmds),
+	})
+}
@@ -0,0 +1,163 @@
+// 命令注册表单元测试。
+package core
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+func TestCommand_ParseArgs(t *testing.T) {
+	t.Parallel()
+
+	cmd := Command{
+		Path: "pve start",
+		Args: []CommandArg{
+			{Name: "type", Type: ArgChoice, Choices: []string{"vm", "lxc"}, Optional: true},
+			{Name: "vmid", Type: ArgInt, Variadic: true},
+			{Name: "instance", Prefix: "@", Optional: true},
+		},
+	}
+	if got, want := cmd.Usage(), "/pve start [vm|lxc] <vmid...> [@instance]"; got != want {
+		t.Fatalf("Usage() = %q, want %q", got, want)
+	}
+
+	args, err := cmd.parseArgs([]string{"VM", "@home", "101,102", "103"})
+	if err != nil {
+		t.Fatalf("parseArgs() error: %v", err)
+	}
+	if args.String("type") != "vm" || args.String("instance") != "home" || !reflect.DeepEqual(args.Ints("vmid"), []int{101, 102, 103}) {
+		t.Fatalf("parseArgs() = %+v", args)
+	}
+
+	args, err = cmd.parseArgs([]string{"101"})
+	if err != nil || args.Has("type") || args.Has("instance") || args.Int("vmid") != 101 {
+		t.Fatalf("parseArgs(optional omitted) = %+v, %v", args, err)
+	}
+
+	for _, tokens := range [][]string{nil, {"vm"}, {"vm", "abc"}, {"0"}} {
+		if _, err := cmd.parseArgs(tokens); err == nil {
+			t.Fatalf("parseArgs(%v) error = nil, want not nil", tokens)
+		}
+	}
+
+	logs := Command{Path: "unraid logs", Args: []CommandArg{{Name: "container"}, {Name: "lines", Type: ArgInt, Optional: true}}}
+	if _, err := logs.parseArgs([]string{"plex", "x"}); err == nil || !strings.Contains(err.Error(), "多余的参数") {
+		t.Fatalf("parseArgs(extra) error = %v, want extra-arg error", err)
+	}
+
+	grep := Command{Path: "unraid logs", Args: []CommandArg{{Name: "container"}, {Name: "query", Variadic: true, Verbatim: true, Optional: true}}}
+	args, err = grep.parseArgs([]string{"nginx", "5\\d{2},", "5\\d{2},"})
+	if err != nil || !reflect.DeepEqual(args.Strings("query"), []string{"5\\d{2},", "5\\d{2},"}) {
+		t.Fatalf("parseArgs(verbatim) = %+v, %v", args, err)
+	}
+}
+
+func TestCommandRegistry_Register(t *testing.T) {
+	t.Parallel()
+
+	noop := func(context.Context, string, CommandArgs) error { return nil }
+	reg := NewCommandRegistry()
+	if err := reg.Register("unraid", Command{Path: "unraid restart", Aliases: []string{"docker restart"}, Handler: noop}); err != nil {
+		t.Fatalf("Register() error: %v", err)
+	}
+	cases := []Command{
+		{Path: "unraid restart", Handler: noop},
+		{Path: "restart", Handler: noop},
+		{Path: "unraid stop"},
+		{Path: "unraid stop", Args: []CommandArg{{Name: "a", Variadic: true}, {Name: "b"}}, Handler: noop},
+	}
+	for _, c := range cases {
+		if err := reg.Register("unraid", c); err == nil {
+			t.Fatalf("Register(%+v) error = nil, want not nil", c)
+		}
+	}
+	if err := reg.Register("pve", Command{Path: "docker stop", Handler: noop}); err == nil {
+		t.Fatalf("Register(foreign namespace) error = nil, want not nil")
+	}
+
+	cmd, rest, ns := reg.lookup("/Docker RESTART plex")
+	if cmd == nil || ns != "unraid" || !reflect.DeepEqual(rest, []string{"plex"}) {
+		t.Fatalf("lookup() = %v, %v, %q", cmd, rest, ns)
+	}
+	if cmd, _, ns := reg.lookup("/unraid nope"); cmd != nil || ns != "unraid" {
+		t.Fatalf("lookup(unknown verb) = %v, %q", cmd, ns)
+	}
+	if _, _, ns := reg.lookup("unraid restart plex"); ns != "" {
+		t.Fatalf("lookup(without slash) namespace = %q, want empty", ns)
+	}
+}
+
+// commandProvider 声明一条需要 unraid.restart 的命令并记录收到的参数。
+type commandProvider struct {
+	fakeProvider
+	got []string
+}
+
+func (p *commandProvider) Commands() []Command {
+	return []Command{{
+		Path:       "unraid restart",
+		Summary:    "重启容器",
+		Args:       []CommandArg{{Name: "container", Variadic: true}},
+		Permission: "unraid.restart",
+		Handler: func(_ context.Context, _ string, args CommandArgs) error {
+			p.got = args.Strings("container")
+			return nil
+		},
+	}}
+}
+
+func TestRouter_Command(t *testing.T) {
+	t.Parallel()
+
+	auth, err := NewAuthorizer(AuthorizerConfig{
+		Bindings: []RoleBinding{
+			{Role: RoleViewer, Subjects: []string{"bob"}},
+			{Role: RoleOperator, Subjects: []string{"alice"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+	rec := &approvalRecorder{}
+	state := NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := &commandProvider{fakeProvider: fakeProvider{key: "unraid", name: "Unraid 容器"}}
+	r := NewRouter(RouterDeps{WeCom: rec, Auth: auth, Providers: []ServiceProvider{p}, State: state})
+
+	send := func(userID, content string) {
+		t.Helper()
+		if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{FromUserName: userID, MsgType: "text", Content: content}); err != nil {
+			t.Fatalf("HandleMessage(%q) error: %v", content, err)
+		}
+	}
+
+	send("bob", "/unraid restart plex")
+	rec.waitText(t, "bob", "无权限")
+	if p.got != nil {
+		t.Fatalf("handler called for viewer: %v", p.got)
+	}
+
+	send("alice", "/unraid restart")
+	rec.waitText(t, "alice", "用法：/unraid restart <container...>")
+
+	state.Set("alice", ConversationState{ServiceKey: "unraid", Step: StepAwaitingContainerName})
+	send("alice", "/unraid restart plex sonarr")
+	if !reflect.DeepEqual(p.got, []string{"plex", "sonarr"}) {
+		t.Fatalf("handler args = %v", p.got)
+	}
+	if st, ok := state.Get("alice"); ok && st.Step != "" {
+		t.Fatalf("state = %+v, want cleared before handler", st)
+	}
+
+	send("alice", "帮助")
+	rec.waitText(t, "alice", "- /unraid：restart")
+	send("alice", "帮助 unraid")
+	rec.waitText(t, "alice", "/unraid restart <container...>：重启容器")
+	send("bob", "帮助 unraid")
+	rec.waitText(t, "bob", "没有可用的命令")
+}
@@ -0,0 +1,247 @@
+package core
+
+// favorite.go 实现用户收藏（常用目标）：在操作卡片上点击“收藏”固定容器/虚拟机/青龙任务，
+// 服务选择卡片与“我的常用”卡片顶部展示直达按钮，点击后由对应 Provider 打开该目标的操作卡片。
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/store"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// maxFavorites 为每个用户可收藏的目标数（与卡片按钮上限一致）。
+const maxFavorites = 5
+
+// Favorite 为一个收藏目标。
+type Favorite struct {
+	ID         string `json:"id"`
+	ServiceKey string `json:"service"`
+	InstanceID string `json:"instance_id,omitempty"`
+	// Target 为服务内的目标标识：Unraid 容器名、PVE “<qemu|lxc>:<vmid>”、青龙任务 ID。
+	Target string `json:"target"`
+	// Name 为展示名称（由 Provider 在收藏时补全）。
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// sameTarget 判断两个收藏是否指向同一目标。
+func (f Favorite) sameTarget(o Favorite) bool {
+	return f.ServiceKey == o.ServiceKey && f.InstanceID == o.InstanceID && f.Target == o.Target
+}
+
+// FavoriteProvider 为 ServiceProvider 的可选扩展：支持收藏其目标并从“常用”直达目标操作卡片。
+type FavoriteProvider interface {
+	// ResolveFavorite 校验收藏目标仍然存在且在用户作用范围内，并补全展示名称。
+	ResolveFavorite(ctx context.Context, userID string, fav Favorite) (Favorite, error)
+	// OpenFavorite 下发目标的操作卡片（目标不可用时由 Provider 自行回复）。
+	OpenFavorite(ctx context.Context, userID string, fav Favorite) error
+}
+
+// FavoriteStore 为收藏持久化抽象；List 按收藏先后顺序返回，实现需并发安全。
+type FavoriteStore interface {
+	List(userID string) ([]Favorite, error)
+	Save(userID string, favorites []Favorite) error
+}
+
+// MemoryFavoriteStore 将收藏保存在内存中，服务重启后丢失。
+type MemoryFavoriteStore struct {
+	mu    sync.Mutex
+	items map[string][]Favorite
+}
+
+func NewMemoryFavoriteStore() *MemoryFavoriteStore {
+	return &MemoryFavoriteStore{items: make(map[string][]Favorite)}
+}
+
+func (s *MemoryFavoriteStore) List(userID string) ([]Favorite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Favorite(nil), s.items[userID]...), nil
+}
+
+func (s *MemoryFavoriteStore) Save(userID string, favorites []Favorite) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[userID] = append([]Favorite(nil), favorites...)
+	return nil
+}
+
+const favoriteBucket = "favorite"
+
+// FileFavoriteStore 将收藏写入共享的持久化存储（bucket=favorite，key 为用户 ID），不设过期时间。
+type FileFavoriteStore struct {
+	kv *store.FileStore
+}
+
+func NewFileFavoriteStore(kv *store.FileStore) *FileFavoriteStore {
+	return &FileFavoriteStore{kv: kv}
+}
+
+func (s *FileFavoriteStore) List(userID string) ([]Favorite, error) {
+	raw, ok := s.kv.Get(favoriteBucket, userID)
+	if !ok {
+		return nil, nil
+	}
+	var out []Favorite
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("收藏解析失败: %w", err)
+	}
+	return out, nil
+}
+
+func (s *FileFavoriteStore) Save(userID string, favorites []Favorite) error {
+	raw, err := json.Marshal(favorites)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(favoriteBucket, userID, raw, time.Time{})
+}
+
+// visibleFavorites 返回用户收藏中服务仍启用且具备查看权限的条目。
+func (r *Router) visibleFavorites(userID string) []Favorite {
+	favs, err := r.favorites.List(userID)
+	if err != nil {
+		slog.Error("读取收藏失败", "user_id", userID, "error", err)
+		return nil
+	}
+	var out []Favorite
+	for _, f := range favs {
+		if _, ok := r.providers[f.ServiceKey].(FavoriteProvider); !ok {
+			continue
+		}
+		if !r.auth.Can(userID, ViewPermission(f.ServiceKey)) {
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+func favoriteOptions(favs []Favorite) []wecom.FavoriteOption {
+	opts := make([]wecom.FavoriteOption, 0, len(favs))
+	for _, f := range favs {
+		opts = append(opts, wecom.FavoriteOption{ID: f.ID, Name: f.Name})
+	}
+	return opts
+}
+
+// addFavorite 处理“收藏”按钮：由 Provider 校验目标并补全名称后追加到用户收藏（已收藏时仅提示）。
+func (r *Router) addFavorite(ctx context.Context, userID, key string) error {
+	serviceKey, instanceID, target, ok := wecom.ParseFavoriteAddKey(key)
+	if !ok {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "收藏无效，请重新打开卡片后重试。"})
+	}
+	fp, ok := r.providers[serviceKey].(FavoriteProvider)
+	if !ok {
+		return r.sendServiceUnavailable(ctx, userID, serviceKey)
+	}
+	if perm := ViewPermission(serviceKey); !r.auth.Can(userID, perm) {
+		return r.sendForbidden(ctx, userID, perm)
+	}
+
+	fav, err := fp.ResolveFavorite(ctx, userID, Favorite{ServiceKey: serviceKey, InstanceID: instanceID, Target: target})
+	if err != nil {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "收藏失败：" + err.Error()})
+	}
+	if strings.TrimSpace(fav.Name) == "" {
+		fav.Name = target
+	}
+
+	r.favoritesMu.Lock()
+	defer r.favoritesMu.Unlock()
+	favs, err := r.favorites.List(userID)
+	if err != nil {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "读取收藏失败：" + err.Error()})
+	}
+	for _, f := range favs {
+		if f.sameTarget(fav) {
+			return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "“" + f.Name + "”已在常用中。"})
+		}
+	}
+	if len(favs) >= maxFavorites {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: fmt.Sprintf("常用最多 %d 项，请先在“我的常用”中打开目标并取消收藏。", maxFavorites),
+		})
+	}
+	fav.CreatedAt = time.Now()
+	fav.ID = strconv.FormatInt(fav.CreatedAt.UnixNano(), 36)
+	if err := r.favorites.Save(userID, append(favs, fav)); err != nil {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "保存收藏失败：" + err.Error()})
+	}
+	return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "已收藏“" + fav.Name + "”，可在操作菜单顶部或“我的常用”中直达。"})
+}
+
+// removeFavorite 处理“取消收藏”按钮。
+func (r *Router) removeFavorite(ctx context.Context, userID, id string) error {
+	r.favoritesMu.Lock()
+	defer r.favoritesMu.Unlock()
+	favs, err := r.favorites.List(userID)
+	if err != nil {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "读取收藏失败：" + err.Error()})
+	}
+	for i, f := range favs {
+		if f.ID != id {
+			continue
+		}
+		if err := r.favorites.Save(userID, append(favs[:i:i], favs[i+1:]...)); err != nil {
+			return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "保存收藏失败：" + err.Error()})
+		}
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "已取消收藏“" + f.Name + "”。"})
+	}
+	return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "该收藏已不存在。"})
+}
+
+// openFavorite 处理“常用”直达按钮：复核服务查看权限后交由 Provider 打开目标操作卡片。
+func (r *Router) openFavorite(ctx context.Context, userID, id string) error {
+	favs, err := r.favorites.List(userID)
+	if err != nil {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "读取收藏失败：" + err.Error()})
+	}
+	for _, f := range favs {
+		if f.ID != id {
+			continue
+		}
+		fp, ok := r.providers[f.ServiceKey].(FavoriteProvider)
+		if !ok {
+			return r.sendServiceUnavailable(ctx, userID, f.ServiceKey)
+		}
+		if perm := ViewPermission(f.ServiceKey); !r.auth.Can(userID, perm) {
+			return r.sendForbidden(ctx, userID, perm)
+		}
+		r.state.Clear(userID)
+		return fp.OpenFavorite(ctx, userID, f)
+	}
+	return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "该收藏已不存在，请发送“常用”重新查看。"})
+}
+
+// sendFavorites 下发“我的常用”卡片。
+func (r *Router) sendFavorites(ctx context.Context, userID string) error {
+	favs := r.visibleFavorites(userID)
+	if len(favs) == 0 {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: "暂无常用目标。可在容器/虚拟机确认卡片或青龙任务卡片上点击“收藏”添加。",
+		})
+	}
+	return r.WeCom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card:   wecom.NewFavoritesCard(favoriteOptions(favs)),
+	})
+}
+
+func isFavoritesKeyword(normalized string) bool {
+	switch normalized {
+	case "常用", "我的常用", "收藏", "favorites":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,136 @@
+// 收藏（常用目标）单元测试。
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// favoriteProvider 仅接受 known 中的目标，并记录被打开的收藏。
+type favoriteProvider struct {
+	fakeProvider
+	known map[string]string
+
+	mu     sync.Mutex
+	opened []string
+}
+
+func (p *favoriteProvider) ResolveFavorite(_ context.Context, _ string, fav Favorite) (Favorite, error) {
+	name, ok := p.known[fav.Target]
+	if !ok {
+		return fav, fmt.Errorf("未找到目标：%s", fav.Target)
+	}
+	fav.Name = name
+	return fav, nil
+}
+
+func (p *favoriteProvider) OpenFavorite(_ context.Context, _ string, fav Favorite) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.opened = append(p.opened, fav.Target)
+	return nil
+}
+
+func TestRouter_Favorites_AddOpenRemove(t *testing.T) {
+	t.Parallel()
+
+	auth, err := NewAuthorizer(AuthorizerConfig{
+		Bindings: []RoleBinding{{Role: RoleViewer, Subjects: []string{"alice"}}},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+	rec := &approvalRecorder{}
+	state := NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := &favoriteProvider{
+		fakeProvider: fakeProvider{key: "unraid", name: "Unraid 容器"},
+		known:        map[string]string{"plex": "plex", "my.app": "my.app"},
+	}
+	favorites := NewMemoryFavoriteStore()
+	r := NewRouter(RouterDeps{WeCom: rec, Auth: auth, Providers: []ServiceProvider{p}, State: state, Favorites: favorites})
+
+	ctx := context.Background()
+	click := func(userID, key string) {
+		t.Helper()
+		if err := r.HandleMessage(ctx, wecom.IncomingMessage{FromUserName: userID, MsgType: "event", Event: "click", EventKey: key}); err != nil {
+			t.Fatalf("HandleMessage(%q) error: %v", key, err)
+		}
+	}
+
+	click("alice", wecom.EventKeyCoreFavorites)
+	rec.waitText(t, "alice", "暂无常用目标")
+
+	click("alice", wecom.FavoriteAddButton("unraid", "", "plex").Key)
+	rec.waitText(t, "alice", "已收藏“plex”")
+	click("alice", wecom.FavoriteAddButton("unraid", "", "plex").Key)
+	rec.waitText(t, "alice", "已在常用中")
+	click("alice", wecom.FavoriteAddButton("unraid", "", "my.app").Key)
+	click("alice", wecom.FavoriteAddButton("unraid", "", "ghost").Key)
+	rec.waitText(t, "alice", "收藏失败：未找到目标")
+
+	favs, _ := favorites.List("alice")
+	if len(favs) != 2 || favs[0].Target != "plex" || favs[1].Target != "my.app" {
+		t.Fatalf("favorites = %+v, want plex, my.app", favs)
+	}
+
+	// 服务选择卡片顶部展示常用目标直达按钮。
+	click("alice", wecom.EventKeyCoreMenu)
+	cards := rec.cardsTo("alice")
+	_, buttons, ok := wecom.RenderButtonInteractionTextMenu(cards[len(cards)-1].Card)
+	if !ok || len(buttons) != 3 || buttons[0].Key != wecom.EventKeyFavoriteOpenPrefix+favs[0].ID || !strings.HasPrefix(buttons[2].Key, wecom.EventKeyServiceSelectPrefix) {
+		t.Fatalf("service menu buttons = %+v", buttons)
+	}
+
+	click("alice", buttons[1].Key)
+	p.mu.Lock()
+	opened := append([]string(nil), p.opened...)
+	p.mu.Unlock()
+	if len(opened) != 1 || opened[0] != "my.app" {
+		t.Fatalf("opened = %v, want my.app", opened)
+	}
+
+	click("alice", wecom.FavoriteRemoveButton(favs[0].ID).Key)
+	rec.waitText(t, "alice", "已取消收藏“plex”")
+	if favs, _ = favorites.List("alice"); len(favs) != 1 || favs[0].Target != "my.app" {
+		t.Fatalf("favorites after remove = %+v, want my.app", favs)
+	}
+}
+
+func TestRouter_Favorites_Limit(t *testing.T) {
+	t.Parallel()
+
+	auth, err := NewAuthorizer(AuthorizerConfig{
+		Bindings: []RoleBinding{{Role: RoleViewer, Subjects: []string{"u"}}},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+	rec := &approvalRecorder{}
+	state := NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := &favoriteProvider{fakeProvider: fakeProvider{key: "unraid", name: "Unraid 容器"}, known: map[string]string{}}
+	for i := 0; i <= maxFavorites; i++ {
+		name := fmt.Sprintf("c%d", i)
+		p.known[name] = name
+	}
+	favorites := NewMemoryFavoriteStore()
+	r := NewRouter(RouterDeps{WeCom: rec, Auth: auth, Providers: []ServiceProvider{p}, State: state, Favorites: favorites})
+
+	for i := 0; i <= maxFavorites; i++ {
+		key := wecom.FavoriteAddButton("unraid", "", fmt.Sprintf("c%d", i)).Key
+		if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{FromUserName: "u", MsgType: "event", Event: "click", EventKey: key}); err != nil {
+			t.Fatalf("HandleMessage(%q) error: %v", key, err)
+		}
+	}
+	rec.waitText(t, "u", fmt.Sprintf("常用最多 %d 项", maxFavorites))
+	if favs, _ := favorites.List("u"); len(favs) != maxFavorites {
+		t.Fatalf("favorites = %d, want %d", len(favs), maxFavorites)
+	}
+}
@@ -0,0 +1,223 @@
+package core
+
+// fuzzy.go 提供目标名称的模糊匹配：按精确、前缀、拼音首字母、子串、编辑距离分级打分并排序，
+// 唯一最高分时自动选定，否则由 Provider 发送候选选择卡片。
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// FuzzyCandidate 为参与模糊匹配的目标。
+type FuzzyCandidate struct {
+	// Key 为选定后使用的标识（容器名、VMID、任务 ID 等）。
+	Key string
+	// Name 为用于匹配与展示的名称。
+	Name string
+}
+
+// FuzzyMatch 为命中的候选及其得分（越高越相近）。
+type FuzzyMatch struct {
+	FuzzyCandidate
+	Score int
+}
+
+// 匹配等级得分：同一等级内得分相同时视为同样相近。
+const (
+	fuzzyScoreExact        = 100
+	fuzzyScoreLooseExact   = 95
+	fuzzyScorePrefix       = 80
+	fuzzyScorePinyin       = 75
+	fuzzyScorePinyinPrefix = 70
+	fuzzyScoreSubstring    = 60
+	// fuzzyScoreEdit 为编辑距离 1 的得分，每多一处差异减 10。
+	fuzzyScoreEdit = 50
+)
+
+// MaxFuzzyChoices 为候选选择卡片中最多列出的目标数。
+const MaxFuzzyChoices = 4
+
+// RankFuzzy 返回与 query 相近的候选（按得分降序，同分时名称较短者优先）；不相近的候选被过滤。
+func RankFuzzy(query string, candidates []FuzzyCandidate) []FuzzyMatch {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+	var out []FuzzyMatch
+	for _, c := range candidates {
+		if score := fuzzyScore(query, c.Name); score > 0 {
+			out = append(out, FuzzyMatch{FuzzyCandidate: c, Score: score})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		if li, lj := len([]rune(out[i].Name)), len([]rune(out[j].Name)); li != lj {
+			return li < lj
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// ResolveFuzzy 排序候选并判断能否自动选定：仅一个候选，或最高分严格高于次高分时 unique=true（选定 matches[0]）。
+func ResolveFuzzy(query string, candidates []FuzzyCandidate) (matches []FuzzyMatch, unique bool) {
+	matches = RankFuzzy(query, candidates)
+	switch {
+	case len(matches) == 0:
+		return nil, false
+	case len(matches) == 1:
+		return matches, true
+	default:
+		return matches, matches[0].Score > matches[1].Score
+	}
+}
+
+func fuzzyScore(query, name string) int {
+	q := strings.ToLower(strings.TrimSpace(query))
+	n := strings.ToLower(strings.TrimSpace(name))
+	if q == "" || n == "" {
+		return 0
+	}
+	if q == n {
+		return fuzzyScoreExact
+	}
+	lq, ln := looseName(q), looseName(n)
+	if lq == "" || ln == "" {
+		return 0
+	}
+	if lq == ln {
+		return fuzzyScoreLooseExact
+	}
+	if strings.HasPrefix(ln, lq) {
+		return fuzzyScorePrefix
+	}
+	if hasHan(n) && isASCIIAlnum(lq) {
+		initials := PinyinInitials(n)
+		switch {
+		case initials == lq:
+			return fuzzyScorePinyin
+		case strings.HasPrefix(initials, lq):
+			return fuzzyScorePinyinPrefix
+		}
+	}
+	if strings.Contains(ln, lq) {
+		return fuzzyScoreSubstring
+	}
+	if d := editDistance(lq, ln); d > 0 && d <= maxEditDistance(lq) {
+		return fuzzyScoreEdit - (d-1)*10
+	}
+	return 0
+}
+
+// maxEditDistance 按输入长度放宽可容忍的拼写错误：3 个字符以内不容错，其后依次为 1/2/3 处
+// （避免 lidarr→radarr 这类相差两处的短名称被误判为同一目标）。
+func maxEditDistance(q string) int {
+	switch n := len([]rune(q)); {
+	case n <= 3:
+		return 0
+	case n <= 7:
+		return 1
+	case n <= 11:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// looseName 去掉名称中的分隔符（空格、-、_、.），使“home-assistant”与“homeassistant”等价。
+func looseName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_', '.':
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func hasHan(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func isASCIIAlnum(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('a' <= c && c <= 'z' || '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+var (
+	pinyinOnce     sync.Once
+	pinyinInitials map[rune]byte
+)
+
+// PinyinInitials 返回名称的拼音首字母（如“家庭影院”→“jtyy”）：ASCII 字母数字原样保留（转小写），
+// 表内汉字取首字母，其余字符忽略。
+func PinyinInitials(s string) string {
+	pinyinOnce.Do(func() {
+		pinyinInitials = make(map[rune]byte, 4096)
+		for _, g := range pinyinInitialGroups {
+			for _, r := range g.chars {
+				pinyinInitials[r] = g.initial
+			}
+		}
+	})
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+		default:
+			if c, ok := pinyinInitials[r]; ok {
+				b.WriteByte(c)
+			}
+		}
+	}
+	return b.String()
+}
+
+// editDistance 计算两个字符串（按 rune）的 Levenshtein 距离。
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// FormatFuzzyChoices 渲染候选列表（用于批量输入中存在歧义时的文本提示），最多列出 MaxFuzzyChoices 项。
+func FormatFuzzyChoices(matches []FuzzyMatch) string {
+	names := make([]string, 0, MaxFuzzyChoices)
+	for i, m := range matches {
+		if i >= MaxFuzzyChoices {
+			break
+		}
+		names = append(names, m.Name)
+	}
+	return strings.Join(names, "、")
+}
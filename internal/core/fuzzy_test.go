@@ -0,0 +1,59 @@
+// 模糊匹配单元测试。
+package core
+
+import "testing"
+
+func TestPinyinInitials(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"家庭影院":      "jtyy",
+		"京东签到-Bean": "jdqdbean",
+		"Plex":      "plex",
+		"下载·助手":     "xzzs",
+	}
+	for in, want := range cases {
+		if got := PinyinInitials(in); got != want {
+			t.Fatalf("PinyinInitials(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestResolveFuzzy(t *testing.T) {
+	t.Parallel()
+
+	cands := func(names ...string) []FuzzyCandidate {
+		out := make([]FuzzyCandidate, 0, len(names))
+		for _, n := range names {
+			out = append(out, FuzzyCandidate{Key: n, Name: n})
+		}
+		return out
+	}
+	containers := cands("sonarr", "sonarr-4k", "radarr", "home-assistant", "jellyfin", "家庭影院")
+
+	cases := []struct {
+		query  string
+		want   string
+		unique bool
+		n      int
+	}{
+		{query: "Sonarr", want: "sonarr", unique: true, n: 2},
+		{query: "son", want: "sonarr", unique: false, n: 2},
+		{query: "homeassistant", want: "home-assistant", unique: true, n: 1},
+		{query: "jelyfin", want: "jellyfin", unique: true, n: 1},
+		{query: "assist", want: "home-assistant", unique: true, n: 1},
+		{query: "jtyy", want: "家庭影院", unique: true, n: 1},
+		{query: "影院", want: "家庭影院", unique: true, n: 1},
+		{query: "nextcloud", n: 0},
+		{query: "lidarr", n: 0},
+	}
+	for _, tc := range cases {
+		matches, unique := ResolveFuzzy(tc.query, containers)
+		if len(matches) != tc.n || unique != tc.unique {
+			t.Fatalf("ResolveFuzzy(%q) = %+v unique=%v, want %d matches unique=%v", tc.query, matches, unique, tc.n, tc.unique)
+		}
+		if tc.n > 0 && matches[0].Key != tc.want {
+			t.Fatalf("ResolveFuzzy(%q) best = %q, want %q", tc.query, matches[0].Key, tc.want)
+		}
+	}
+}
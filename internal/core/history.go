@@ -0,0 +1,251 @@
+package core
+
+// history.go 记录每个用户已确认的操作，支持“重复”/“/again”与“最近操作”卡片一键重新发起；
+// 重新发起时恢复确认前的会话状态并重新下发确认卡片，执行前仍走确认、授权复核与审批。
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/store"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+const (
+	// historyLimit 为每个用户保留的操作记录条数。
+	historyLimit = 10
+	// historyCardLimit 为“最近操作”卡片中可一键重新发起的条数。
+	historyCardLimit = 5
+)
+
+// HistoryEntry 为一次已确认的操作。
+type HistoryEntry struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	ServiceKey string    `json:"service"`
+	InstanceID string    `json:"instance_id,omitempty"`
+	Action     Action    `json:"action"`
+	// Target 为目标摘要；Targets 为批量操作的各目标（单目标时为空）。
+	Target  string   `json:"target"`
+	Targets []string `json:"targets,omitempty"`
+	// Permission 为执行所需权限，重新发起前预检。
+	Permission string `json:"permission,omitempty"`
+	// State 为确认前的会话状态，重新发起时原样恢复后交由 Provider 的确认流程处理。
+	State ConversationState `json:"state"`
+}
+
+// Title 返回用于展示的动作摘要（动作 + 目标）。
+func (e HistoryEntry) Title() string {
+	return ConfirmedAction{Action: e.Action, Target: e.Target}.Title()
+}
+
+// sameAction 判断两条记录是否为同一操作（用于去重：重复执行只保留最新一条）。
+func (e HistoryEntry) sameAction(o HistoryEntry) bool {
+	return e.ServiceKey == o.ServiceKey && e.InstanceID == o.InstanceID && e.Action == o.Action &&
+		e.Target == o.Target && strings.Join(e.Targets, "\n") == strings.Join(o.Targets, "\n")
+}
+
+// HistoryStore 为操作记录持久化抽象；List 按时间倒序返回，实现需并发安全。
+type HistoryStore interface {
+	List(userID string) ([]HistoryEntry, error)
+	Save(userID string, entries []HistoryEntry) error
+}
+
+// MemoryHistoryStore 将操作记录保存在内存中，服务重启后丢失。
+type MemoryHistoryStore struct {
+	mu    sync.Mutex
+	items map[string][]HistoryEntry
+}
+
+func NewMemoryHistoryStore() *MemoryHistoryStore {
+	return &MemoryHistoryStore{items: make(map[string][]HistoryEntry)}
+}
+
+func (s *MemoryHistoryStore) List(userID string) ([]HistoryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]HistoryEntry(nil), s.items[userID]...), nil
+}
+
+func (s *MemoryHistoryStore) Save(userID string, entries []HistoryEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[userID] = append([]HistoryEntry(nil), entries...)
+	return nil
+}
+
+const historyBucket = "history"
+
+// FileHistoryStore 将操作记录写入共享的持久化存储（bucket=history，key 为用户 ID），不设过期时间。
+type FileHistoryStore struct {
+	kv *store.FileStore
+}
+
+func NewFileHistoryStore(kv *store.FileStore) *FileHistoryStore {
+	return &FileHistoryStore{kv: kv}
+}
+
+func (s *FileHistoryStore) List(userID string) ([]HistoryEntry, error) {
+	raw, ok := s.kv.Get(historyBucket, userID)
+	if !ok {
+		return nil, nil
+	}
+	var out []HistoryEntry
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("操作记录解析失败: %w", err)
+	}
+	return out, nil
+}
+
+func (s *FileHistoryStore) Save(userID string, entries []HistoryEntry) error {
+	raw, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(historyBucket, userID, raw, time.Time{})
+}
+
+// newHistoryEntry 由确认前的会话状态与 Provider 生成的动作构造记录。
+func newHistoryEntry(state ConversationState, action ConfirmedAction) HistoryEntry {
+	state.Step = StepAwaitingConfirm
+	state.PendingButtons = nil
+	state.ExpiresAt = time.Time{}
+
+	e := HistoryEntry{
+		Time:       time.Now(),
+		ServiceKey: action.ServiceKey,
+		InstanceID: action.InstanceID,
+		Action:     action.Action,
+		Target:     action.Target,
+		Permission: action.RequiredPermission(),
+		State:      state,
+	}
+	for _, it := range action.Items {
+		e.Targets = append(e.Targets, it.Target)
+	}
+	e.ID = strconv.FormatInt(e.Time.UnixNano(), 36)
+	return e
+}
+
+// recordHistory 将已确认的操作加入用户记录（同一操作仅保留最新一条）；写入失败仅记录日志，不影响执行。
+func (r *Router) recordHistory(userID string, entry HistoryEntry) {
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+
+	old, err := r.history.List(userID)
+	if err != nil {
+		slog.Error("读取操作记录失败", "user_id", userID, "error", err)
+	}
+	entries := []HistoryEntry{entry}
+	for _, e := range old {
+		if len(entries) >= historyLimit {
+			break
+		}
+		if !e.sameAction(entry) {
+			entries = append(entries, e)
+		}
+	}
+	if err := r.history.Save(userID, entries); err != nil {
+		slog.Error("保存操作记录失败", "user_id", userID, "error", err)
+	}
+}
+
+// rerunHistory 重新发起一条历史操作：预检权限后恢复确认前的会话状态并下发确认卡片。
+// id 为空时取最近一条。
+func (r *Router) rerunHistory(ctx context.Context, userID, id string) error {
+	entries, err := r.history.List(userID)
+	if err != nil {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "读取操作记录失败：" + err.Error()})
+	}
+	var entry HistoryEntry
+	found := false
+	for _, e := range entries {
+		if id == "" || e.ID == id {
+			entry, found = e, true
+			break
+		}
+	}
+	if !found {
+		msg := "暂无可重复的操作。"
+		if id != "" {
+			msg = "该操作记录已不存在，请发送“最近操作”重新查看。"
+		}
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: msg})
+	}
+
+	if _, ok := r.providers[entry.ServiceKey]; !ok {
+		return r.sendServiceUnavailable(ctx, userID, entry.ServiceKey)
+	}
+	if perm := entry.Permission; perm != "" && !r.auth.Can(userID, perm) {
+		return r.sendForbidden(ctx, userID, perm)
+	}
+
+	state := entry.State
+	state.ServiceKey = entry.ServiceKey
+	state.Step = StepAwaitingConfirm
+	r.state.Set(userID, state)
+
+	if len(entry.Targets) > 1 {
+		if err := r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: FormatBatchConfirm(entry.Action, entry.Targets)}); err != nil {
+			return err
+		}
+		return r.WeCom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+			ToUser: userID,
+			Card:   wecom.NewBatchConfirmCard(entry.Action.DisplayName(), entry.Targets),
+		})
+	}
+	return r.WeCom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card:   wecom.NewConfirmCard(entry.Action.DisplayName(), entry.Target),
+	})
+}
+
+// sendHistory 下发“最近操作”卡片：列出最近的操作（时间与动作）并为每条提供一键重新发起按钮。
+func (r *Router) sendHistory(ctx context.Context, userID string) error {
+	entries, err := r.history.List(userID)
+	if err != nil {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "读取操作记录失败：" + err.Error()})
+	}
+	if len(entries) == 0 {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "暂无最近操作。"})
+	}
+
+	var opts []wecom.HistoryOption
+	for i, e := range entries {
+		if i >= historyCardLimit {
+			break
+		}
+		opts = append(opts, wecom.HistoryOption{
+			ID:   e.ID,
+			Text: e.Title(),
+			Time: e.Time.Format("01-02 15:04"),
+		})
+	}
+	return r.WeCom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card:   wecom.NewHistoryCard(opts),
+	})
+}
+
+func isHistoryKeyword(normalized string) bool {
+	switch normalized {
+	case "最近操作", "历史", "history":
+		return true
+	default:
+		return false
+	}
+}
+
+func isRepeatKeyword(normalized string) bool {
+	switch normalized {
+	case "重复", "再来一次", "again":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,210 @@
+// 操作记录与“重复”单元测试。
+package core
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/store"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// historyProvider 按会话状态中的容器名生成动作并记录实际执行的目标。
+type historyProvider struct {
+	fakeProvider
+	state StateStore
+
+	mu  sync.Mutex
+	ran []string
+}
+
+func (p *historyProvider) PrepareConfirm(_ context.Context, userID string) (ConfirmedAction, bool, error) {
+	st, _ := p.state.Get(userID)
+	p.state.Clear(userID)
+	name := st.ContainerName
+	return ConfirmedAction{
+		Action: st.Action,
+		Target: name,
+		Run: func(context.Context, ProgressFunc) (string, error) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.ran = append(p.ran, name)
+			return "", nil
+		},
+	}, true, nil
+}
+
+func (p *historyProvider) runs() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.ran...)
+}
+
+func TestRouter_History_RepeatAndRerun(t *testing.T) {
+	t.Parallel()
+
+	auth, err := NewAuthorizer(AuthorizerConfig{
+		Bindings: []RoleBinding{
+			{Role: RoleOperator, Subjects: []string{"alice"}},
+			{Role: RoleViewer, Subjects: []string{"bob"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+	rec := &approvalRecorder{}
+	state := NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := &historyProvider{fakeProvider: fakeProvider{key: "unraid", name: "Unraid 容器"}, state: state}
+	history := NewMemoryHistoryStore()
+	r := NewRouter(RouterDeps{WeCom: rec, Auth: auth, Providers: []ServiceProvider{p}, State: state, History: history})
+
+	ctx := context.Background()
+	send := func(userID, content string) {
+		t.Helper()
+		if err := r.HandleMessage(ctx, wecom.IncomingMessage{FromUserName: userID, MsgType: "text", Content: content}); err != nil {
+			t.Fatalf("HandleMessage(%q) error: %v", content, err)
+		}
+	}
+	confirm := func(name string) {
+		t.Helper()
+		state.Set("alice", ConversationState{ServiceKey: "unraid", Step: StepAwaitingConfirm, Action: ActionUnraidRestart, ContainerName: name})
+		send("alice", "确认")
+	}
+
+	send("alice", "/again")
+	rec.waitText(t, "alice", "暂无可重复的操作")
+
+	confirm("plex")
+	send("alice", "重复")
+	if st, ok := state.Get("alice"); !ok || st.Step != StepAwaitingConfirm || st.ContainerName != "plex" {
+		t.Fatalf("state after 重复 = %+v, want awaiting confirm for plex", st)
+	}
+	cards := rec.cardsTo("alice")
+	if desc := cards[len(cards)-1].Card["main_title"].(map[string]interface{})["desc"]; desc != "重启：plex" {
+		t.Fatalf("confirm card desc = %v, want 重启：plex", desc)
+	}
+	send("alice", "确认")
+	confirm("sonarr")
+	if got := p.runs(); !reflect.DeepEqual(got, []string{"plex", "plex", "sonarr"}) {
+		t.Fatalf("runs = %v", got)
+	}
+
+	// 同一操作仅保留最新一条，最近的排在前面。
+	entries, _ := history.List("alice")
+	if len(entries) != 2 || entries[0].Target != "sonarr" || entries[1].Target != "plex" {
+		t.Fatalf("history = %+v, want sonarr, plex", entries)
+	}
+
+	send("alice", "最近操作")
+	cards = rec.cardsTo("alice")
+	_, buttons, ok := wecom.RenderButtonInteractionTextMenu(cards[len(cards)-1].Card)
+	if !ok || len(buttons) != 2 || buttons[1].Key != wecom.EventKeyHistoryRerunPrefix+entries[1].ID {
+		t.Fatalf("history buttons = %+v", buttons)
+	}
+	if err := r.HandleMessage(ctx, wecom.IncomingMessage{FromUserName: "alice", MsgType: "event", Event: "click", EventKey: buttons[1].Key}); err != nil {
+		t.Fatalf("HandleMessage(rerun) error: %v", err)
+	}
+	if st, _ := state.Get("alice"); st.ContainerName != "plex" || st.Step != StepAwaitingConfirm {
+		t.Fatalf("state after rerun = %+v, want plex awaiting confirm", st)
+	}
+
+	// 重新发起前按当前授权预检：权限被收回后不再恢复确认。
+	_ = history.Save("bob", entries)
+	send("bob", "重复")
+	rec.waitText(t, "bob", "无权限")
+	if st, ok := state.Get("bob"); ok && st.Step == StepAwaitingConfirm {
+		t.Fatalf("bob state = %+v, want no pending confirm", st)
+	}
+	if got := p.runs(); len(got) != 3 {
+		t.Fatalf("runs = %v, want no extra runs", got)
+	}
+}
+
+func TestRouter_History_RecordsOnlyApprovedActions(t *testing.T) {
+	t.Parallel()
+
+	auth, err := NewAuthorizer(AuthorizerConfig{
+		Bindings: []RoleBinding{{Role: RoleOperator, Subjects: []string{"alice", "bob"}}},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+	rec := &approvalRecorder{}
+	state := NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := &historyProvider{fakeProvider: fakeProvider{key: "unraid", name: "Unraid 容器"}, state: state}
+	history := NewMemoryHistoryStore()
+	r := NewRouter(RouterDeps{
+		WeCom:     rec,
+		Auth:      auth,
+		Providers: []ServiceProvider{p},
+		State:     state,
+		History:   history,
+		Approval:  ApprovalPolicy{Actions: []string{"unraid.restart"}, Timeout: time.Minute},
+	})
+	confirm := func(name string) {
+		t.Helper()
+		state.Set("alice", ConversationState{ServiceKey: "unraid", Step: StepAwaitingConfirm, Action: ActionUnraidRestart, ContainerName: name})
+		sendText(t, r, "alice", "确认")
+	}
+
+	// 待审批与被驳回的操作不记入操作记录。
+	confirm("plex")
+	rec.waitText(t, "alice", "已提交审批（#1）")
+	if entries, _ := history.List("alice"); len(entries) != 0 {
+		t.Fatalf("history after request = %+v, want empty", entries)
+	}
+	clickApproval(t, r, "bob", wecom.EventKeyApprovalRejectPrefix+"1")
+	rec.waitText(t, "alice", "审批驳回（#1）")
+	if entries, _ := history.List("alice"); len(entries) != 0 {
+		t.Fatalf("history after reject = %+v, want empty", entries)
+	}
+
+	confirm("sonarr")
+	clickApproval(t, r, "bob", wecom.EventKeyApprovalApprovePrefix+"2")
+	rec.waitText(t, "alice", "审批通过（#2）")
+	entries, _ := history.List("alice")
+	if len(entries) != 1 || entries[0].Target != "sonarr" || entries[0].State.ContainerName != "sonarr" {
+		t.Fatalf("history after approve = %+v, want sonarr", entries)
+	}
+	if got := p.runs(); !reflect.DeepEqual(got, []string{"sonarr"}) {
+		t.Fatalf("runs = %v, want [sonarr]", got)
+	}
+}
+
+func TestFileHistoryStore_SurvivesReload(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+	kv, err := store.Open(path)
+	if err != nil {
+		t.Fatalf("store.Open() error: %v", err)
+	}
+	entry := newHistoryEntry(
+		ConversationState{ServiceKey: "pve", Action: ActionPVEReboot, PVEGuests: []PVEGuestRef{{Type: "qemu", VMID: 101, Node: "n1"}}},
+		ConfirmedAction{ServiceKey: "pve", Action: ActionPVEReboot, Target: "VM 101", Permission: "pve.vm.reboot"},
+	)
+	if err := NewFileHistoryStore(kv).Save("u", []HistoryEntry{entry}); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	_ = kv.Close()
+
+	kv2, err := store.Open(path)
+	if err != nil {
+		t.Fatalf("store.Open(reload) error: %v", err)
+	}
+	t.Cleanup(func() { _ = kv2.Close() })
+	got, err := NewFileHistoryStore(kv2).List("u")
+	if err != nil || len(got) != 1 {
+		t.Fatalf("List() = %+v, %v", got, err)
+	}
+	if got[0].Permission != "pve.vm.reboot" || got[0].State.Step != StepAwaitingConfirm || got[0].State.PVEGuests[0].VMID != 101 || !strings.Contains(got[0].Title(), "VM 101") {
+		t.Fatalf("reloaded entry = %+v", got[0])
+	}
+}
@@ -0,0 +1,537 @@
+package core
+
+// job.go 实现确认类操作的异步执行：回调内仅做校验与受理，耗时动作交由有界 worker 池在独立 context 中执行。
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// ProgressFunc 用于在执行过程中向用户推送阶段性进度（例如 PVE 已提交任务的 UPID）。
+type ProgressFunc func(msg string)
+
+// ConfirmedAction 描述一次“已确认、待执行”的操作。
+// Provider 在回调内完成状态校验与清理后生成该结构，Run 在后台执行并返回结果描述。
+type ConfirmedAction struct {
+	ServiceKey string
+	InstanceID string
+	Action     Action
+	Target     string
+	// Permission 为执行所需权限（如 pve.vm.stop）；为空时按 <service>.<action> 推导。
+	Permission string
+	// Resource 为授权判定对象（实例/容器/VMID 等），Router 执行前按作用范围复核。
+	Resource Resource
+	// ApprovedBy 为双人审批的批准人（未经审批时为空），随执行结果写入审计。
+	ApprovedBy string
+	// Items 为批量操作的各目标动作（见 NewBatchAction）；非空时授权与审批按每个目标复核。
+	Items []ConfirmedAction
+
+	Run func(ctx context.Context, progress ProgressFunc) (string, error)
+}
+
+// Title 返回用于回显的动作摘要（动作 + 目标）。
+func (a ConfirmedAction) Title() string {
+	title := a.Action.DisplayName()
+	if t := strings.TrimSpace(a.Target); t != "" {
+		title = title + " " + t
+	}
+	return title
+}
+
+// RequiredPermission 返回执行该动作所需的权限串。
+func (a ConfirmedAction) RequiredPermission() string {
+	if p := strings.TrimSpace(a.Permission); p != "" {
+		return p
+	}
+	return ServicePermission(a.ServiceKey, string(a.Action))
+}
+
+// AsyncConfirmProvider 为 ServiceProvider 的可选扩展：实现后 Router 会将确认动作投递到 JobRunner 异步执行。
+// 返回 handled=true 且 Run 为空表示 Provider 已自行回复（例如会话过期），无需执行。
+type AsyncConfirmProvider interface {
+	PrepareConfirm(ctx context.Context, userID string) (ConfirmedAction, bool, error)
+}
+
+// RunConfirmedAction 在当前 goroutine 内同步执行确认动作并回显结果（未启用 JobRunner 时的兜底路径）。
+func RunConfirmedAction(ctx context.Context, sender WeComSender, userID string, action ConfirmedAction) error {
+	return runConfirmedAction(ctx, sender, nil, userID, action)
+}
+
+func runConfirmedAction(ctx context.Context, sender WeComSender, audit AuditSink, userID string, action ConfirmedAction) error {
+	if action.Run == nil {
+		return nil
+	}
+	progress := func(msg string) {
+		if strings.TrimSpace(msg) == "" {
+			return
+		}
+		_ = sender.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: msg})
+	}
+
+	start := time.Now()
+	result, err := safeRunAction(ctx, action, progress)
+	cost := time.Since(start).Milliseconds()
+
+	entry := AuditEntry{
+		Time:       start,
+		UserID:     userID,
+		ServiceKey: action.ServiceKey,
+		InstanceID: action.InstanceID,
+		Action:     action.Action,
+		Target:     action.Target,
+		Approver:   action.ApprovedBy,
+		Result:     AuditResultSuccess,
+		DurationMS: cost,
+	}
+	if err != nil {
+		entry.Result = AuditResultFailed
+		entry.Error = err.Error()
+	}
+	recordAudit(ctx, audit, entry)
+
+	if err != nil {
+		_ = sender.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: fmt.Sprintf("执行失败（%dms）：%s", cost, err.Error()),
+		})
+		return nil
+	}
+	if strings.TrimSpace(result) == "" {
+		result = action.Title()
+	}
+	_ = sender.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: fmt.Sprintf("执行成功（%dms）：%s", cost, result),
+	})
+	return nil
+}
+
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "queued"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusSucceeded JobStatus = "succeeded"
+	JobStatusFailed    JobStatus = "failed"
+	JobStatusTimeout   JobStatus = "timeout"
+	JobStatusCanceled  JobStatus = "canceled"
+)
+
+func (s JobStatus) DisplayName() string {
+	switch s {
+	case JobStatusQueued:
+		return "排队中"
+	case JobStatusRunning:
+		return "执行中"
+	case JobStatusSucceeded:
+		return "成功"
+	case JobStatusFailed:
+		return "失败"
+	case JobStatusTimeout:
+		return "超时"
+	case JobStatusCanceled:
+		return "已取消"
+	default:
+		return "未知"
+	}
+}
+
+func (s JobStatus) Finished() bool {
+	switch s {
+	case JobStatusSucceeded, JobStatusFailed, JobStatusTimeout, JobStatusCanceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// Job 为任务快照（只读副本）。
+type Job struct {
+	ID         string
+	UserID     string
+	ServiceKey string
+	InstanceID string
+	Action     Action
+	Target     string
+	Title      string
+
+	Status JobStatus
+	Result string
+	Error  string
+
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Duration 返回任务执行耗时（未开始返回 0；执行中返回已运行时长）。
+func (j Job) Duration() time.Duration {
+	if j.StartedAt.IsZero() {
+		return 0
+	}
+	if j.FinishedAt.IsZero() {
+		return time.Since(j.StartedAt)
+	}
+	return j.FinishedAt.Sub(j.StartedAt)
+}
+
+var (
+	ErrJobQueueFull     = errors.New("任务队列已满")
+	ErrJobRunnerClosed  = errors.New("任务执行器已关闭")
+	errJobActionMissing = errors.New("缺少可执行动作")
+)
+
+type JobRunnerDeps struct {
+	WeCom WeComSender
+	// Audit 可选：任务结束后写入审计记录。
+	Audit AuditSink
+
+	// Workers 为并发执行的 worker 数（默认 4）。
+	Workers int
+	// QueueSize 为排队上限（默认 32），超过时拒绝受理。
+	QueueSize int
+	// Timeout 为单个任务的执行超时（默认 5m）。
+	Timeout time.Duration
+	// MaxHistory 为内存中保留的已结束任务数量（默认 200）。
+	MaxHistory int
+}
+
+// JobRunner 为确认类操作提供有界并发执行、状态跟踪与结果推送。
+type JobRunner struct {
+	wecom      WeComSender
+	audit      AuditSink
+	timeout    time.Duration
+	maxHistory int
+
+	baseCtx    context.Context
+	baseCancel context.CancelFunc
+
+	queue chan *jobEntry
+	wg    sync.WaitGroup
+
+	mu       sync.Mutex
+	seq      int64
+	jobs     map[string]*jobEntry
+	finished []string
+	closed   bool
+}
+
+type jobEntry struct {
+	job    Job
+	action ConfirmedAction
+}
+
+func NewJobRunner(deps JobRunnerDeps) *JobRunner {
+	workers := deps.Workers
+	if workers <= 0 {
+		workers = 4
+	}
+	queueSize := deps.QueueSize
+	if queueSize <= 0 {
+		queueSize = 32
+	}
+	timeout := deps.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	maxHistory := deps.MaxHistory
+	if maxHistory <= 0 {
+		maxHistory = 200
+	}
+
+	baseCtx, baseCancel := context.WithCancel(context.Background())
+	r := &JobRunner{
+		wecom:      deps.WeCom,
+		audit:      deps.Audit,
+		timeout:    timeout,
+		maxHistory: maxHistory,
+		baseCtx:    baseCtx,
+		baseCancel: baseCancel,
+		queue:      make(chan *jobEntry, queueSize),
+		jobs:       make(map[string]*jobEntry),
+	}
+	for i := 0; i < workers; i++ {
+		r.wg.Add(1)
+		go r.worker()
+	}
+	return r
+}
+
+// Submit 受理一个确认动作：立即回复受理信息并入队，返回任务快照。
+func (r *JobRunner) Submit(ctx context.Context, userID string, action ConfirmedAction) (Job, error) {
+	if action.Run == nil {
+		return Job{}, errJobActionMissing
+	}
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return Job{}, ErrJobRunnerClosed
+	}
+	r.seq++
+	entry := &jobEntry{
+		job: Job{
+			ID:         strconv.FormatInt(r.seq, 10),
+			UserID:     userID,
+			ServiceKey: action.ServiceKey,
+			InstanceID: action.InstanceID,
+			Action:     action.Action,
+			Target:     action.Target,
+			Title:      action.Title(),
+			Status:     JobStatusQueued,
+			CreatedAt:  time.Now(),
+		},
+		action: action,
+	}
+	select {
+	case r.queue <- entry:
+	default:
+		r.seq--
+		r.mu.Unlock()
+		return Job{}, ErrJobQueueFull
+	}
+	r.jobs[entry.job.ID] = entry
+	snapshot := entry.job
+	r.mu.Unlock()
+
+	slog.Info("任务已受理",
+		"job_id", snapshot.ID,
+		"user_id", userID,
+		"service", snapshot.ServiceKey,
+		"action", string(snapshot.Action),
+		"target", snapshot.Target,
+	)
+	_ = r.wecom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: fmt.Sprintf("已受理（任务 #%s）：%s\n执行中，完成后将推送结果。", snapshot.ID, snapshot.Title),
+	})
+	return snapshot, nil
+}
+
+// Get 返回任务快照。
+func (r *JobRunner) Get(id string) (Job, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.jobs[strings.TrimSpace(id)]
+	if !ok {
+		return Job{}, false
+	}
+	return e.job, true
+}
+
+// ListByUser 返回指定用户最近的任务（按创建时间倒序）。
+func (r *JobRunner) ListByUser(userID string, limit int) []Job {
+	r.mu.Lock()
+	var out []Job
+	for _, e := range r.jobs {
+		if e.job.UserID == userID {
+			out = append(out, e.job)
+		}
+	}
+	r.mu.Unlock()
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// Shutdown 停止受理新任务并等待已受理任务结束；ctx 到期后取消仍在执行的任务。
+func (r *JobRunner) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		r.baseCancel()
+		return nil
+	case <-ctx.Done():
+		r.baseCancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (r *JobRunner) worker() {
+	defer r.wg.Done()
+	for entry := range r.queue {
+		r.run(entry)
+	}
+}
+
+func (r *JobRunner) run(entry *jobEntry) {
+	ctx, cancel := context.WithTimeout(r.baseCtx, r.timeout)
+	defer cancel()
+
+	r.update(entry, func(j *Job) {
+		j.Status = JobStatusRunning
+		j.StartedAt = time.Now()
+	})
+	job, _ := r.Get(entry.job.ID)
+
+	progress := func(msg string) {
+		if strings.TrimSpace(msg) == "" {
+			return
+		}
+		_ = r.wecom.SendText(ctx, wecom.TextMessage{
+			ToUser:  job.UserID,
+			Content: fmt.Sprintf("任务 #%s 进度：%s", job.ID, msg),
+		})
+	}
+
+	result, err := safeRunAction(ctx, entry.action, progress)
+
+	status := JobStatusSucceeded
+	switch {
+	case err == nil:
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		status = JobStatusTimeout
+	case errors.Is(ctx.Err(), context.Canceled):
+		status = JobStatusCanceled
+	default:
+		status = JobStatusFailed
+	}
+	if strings.TrimSpace(result) == "" {
+		result = job.Title
+	}
+
+	r.update(entry, func(j *Job) {
+		j.Status = status
+		j.FinishedAt = time.Now()
+		if err != nil {
+			j.Error = err.Error()
+		} else {
+			j.Result = result
+		}
+	})
+	job, _ = r.Get(entry.job.ID)
+	r.markFinished(job.ID)
+
+	cost := job.Duration().Milliseconds()
+
+	// 审计写入使用独立 context：任务超时/取消后仍需落地记录。
+	auditCtx, auditCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer auditCancel()
+	recordAudit(auditCtx, r.audit, AuditEntry{
+		Time:       job.StartedAt,
+		UserID:     job.UserID,
+		ServiceKey: job.ServiceKey,
+		InstanceID: job.InstanceID,
+		Action:     job.Action,
+		Target:     job.Target,
+		JobID:      job.ID,
+		Approver:   entry.action.ApprovedBy,
+		Result:     auditResultFromJobStatus(job.Status),
+		Error:      job.Error,
+		DurationMS: cost,
+	})
+
+	attrs := []any{
+		"job_id", job.ID,
+		"user_id", job.UserID,
+		"service", job.ServiceKey,
+		"action", string(job.Action),
+		"target", job.Target,
+		"status", string(job.Status),
+		"duration_ms", cost,
+	}
+	if err != nil {
+		slog.Warn("任务执行失败", append(attrs, "error", err)...)
+	} else {
+		slog.Info("任务执行完成", attrs...)
+	}
+
+	var content string
+	switch status {
+	case JobStatusSucceeded:
+		content = fmt.Sprintf("执行成功（任务 #%s，%dms）：%s", job.ID, cost, result)
+	case JobStatusTimeout:
+		content = fmt.Sprintf("执行超时（任务 #%s，超过 %s）：%s\n%s", job.ID, r.timeout, job.Title, err.Error())
+	case JobStatusCanceled:
+		content = fmt.Sprintf("任务已取消（任务 #%s）：%s", job.ID, job.Title)
+	default:
+		content = fmt.Sprintf("执行失败（任务 #%s，%dms）：%s", job.ID, cost, err.Error())
+	}
+
+	// 结果推送使用独立 context，避免任务超时后无法回显。
+	sendCtx, sendCancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer sendCancel()
+	_ = r.wecom.SendText(sendCtx, wecom.TextMessage{ToUser: job.UserID, Content: content})
+}
+
+func auditResultFromJobStatus(s JobStatus) AuditResult {
+	switch s {
+	case JobStatusSucceeded:
+		return AuditResultSuccess
+	case JobStatusTimeout:
+		return AuditResultTimeout
+	case JobStatusCanceled:
+		return AuditResultCanceled
+	default:
+		return AuditResultFailed
+	}
+}
+
+func safeRunAction(ctx context.Context, action ConfirmedAction, progress ProgressFunc) (result string, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic: %v", rec)
+		}
+	}()
+	return action.Run(ctx, progress)
+}
+
+func (r *JobRunner) update(entry *jobEntry, fn func(j *Job)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(&entry.job)
+}
+
+func (r *JobRunner) markFinished(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished = append(r.finished, id)
+	for len(r.finished) > r.maxHistory {
+		delete(r.jobs, r.finished[0])
+		r.finished = r.finished[1:]
+	}
+}
+
+// FormatJobList 将任务列表渲染为文本（供“任务状态”命令使用）。
+func FormatJobList(jobs []Job) string {
+	if len(jobs) == 0 {
+		return "暂无任务记录。"
+	}
+	var b strings.Builder
+	b.WriteString("最近任务：")
+	for _, j := range jobs {
+		fmt.Fprintf(&b, "\n- #%s [%s] %s（%s）", j.ID, j.Status.DisplayName(), j.Title, j.CreatedAt.Format("01-02 15:04:05"))
+		if j.Status.Finished() {
+			fmt.Fprintf(&b, " %dms", j.Duration().Milliseconds())
+		}
+		if j.Error != "" {
+			b.WriteString("\n  错误：")
+			b.WriteString(j.Error)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,231 @@
+// JobRunner 异步执行单元测试。
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// syncRecordWeCom 为并发安全的发送记录器（任务在 worker goroutine 中回推结果）。
+type syncRecordWeCom struct {
+	mu    sync.Mutex
+	texts []wecom.TextMessage
+}
+
+func (r *syncRecordWeCom) SendText(_ context.Context, msg wecom.TextMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.texts = append(r.texts, msg)
+	return nil
+}
+
+func (r *syncRecordWeCom) SendTemplateCard(_ context.Context, _ wecom.TemplateCardMessage) error {
+	return nil
+}
+
+func (r *syncRecordWeCom) waitText(t *testing.T, substr string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		for _, m := range r.texts {
+			if strings.Contains(m.Content, substr) {
+				r.mu.Unlock()
+				return
+			}
+		}
+		r.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t.Fatalf("未收到包含 %q 的消息，实际=%v", substr, r.texts)
+}
+
+func TestJobRunner_Submit_RepliesAcceptedThenResult(t *testing.T) {
+	t.Parallel()
+
+	rec := &syncRecordWeCom{}
+	runner := NewJobRunner(JobRunnerDeps{WeCom: rec, Workers: 1, QueueSize: 1})
+	defer runner.Shutdown(context.Background())
+
+	job, err := runner.Submit(context.Background(), "u", ConfirmedAction{
+		ServiceKey: "unraid",
+		Action:     ActionUnraidRestart,
+		Target:     "app",
+		Run: func(_ context.Context, progress ProgressFunc) (string, error) {
+			progress("step1")
+			return "重启 app", nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+	rec.waitText(t, "已受理（任务 #"+job.ID+"）")
+	rec.waitText(t, "任务 #"+job.ID+" 进度：step1")
+	rec.waitText(t, "执行成功（任务 #"+job.ID)
+
+	got, ok := runner.Get(job.ID)
+	if !ok || got.Status != JobStatusSucceeded || got.Result != "重启 app" {
+		t.Fatalf("Get() = %+v, %v", got, ok)
+	}
+}
+
+func TestJobRunner_Submit_QueueFull(t *testing.T) {
+	t.Parallel()
+
+	rec := &syncRecordWeCom{}
+	runner := NewJobRunner(JobRunnerDeps{WeCom: rec, Workers: 1, QueueSize: 1})
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	blocking := ConfirmedAction{
+		Action: ActionUnraidRestart,
+		Run: func(ctx context.Context, _ ProgressFunc) (string, error) {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-release
+			return "", nil
+		},
+	}
+
+	if _, err := runner.Submit(context.Background(), "u", blocking); err != nil {
+		t.Fatalf("Submit(1) error: %v", err)
+	}
+	<-started
+	if _, err := runner.Submit(context.Background(), "u", blocking); err != nil {
+		t.Fatalf("Submit(2) error: %v", err)
+	}
+	if _, err := runner.Submit(context.Background(), "u", blocking); !errors.Is(err, ErrJobQueueFull) {
+		t.Fatalf("Submit(3) error = %v, want ErrJobQueueFull", err)
+	}
+
+	close(release)
+	if err := runner.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error: %v", err)
+	}
+	if _, err := runner.Submit(context.Background(), "u", blocking); !errors.Is(err, ErrJobRunnerClosed) {
+		t.Fatalf("Submit(after shutdown) error = %v, want ErrJobRunnerClosed", err)
+	}
+}
+
+func TestJobRunner_Timeout(t *testing.T) {
+	t.Parallel()
+
+	rec := &syncRecordWeCom{}
+	runner := NewJobRunner(JobRunnerDeps{WeCom: rec, Workers: 1, Timeout: 20 * time.Millisecond})
+	defer runner.Shutdown(context.Background())
+
+	job, err := runner.Submit(context.Background(), "u", ConfirmedAction{
+		Action: ActionUnraidStop,
+		Target: "app",
+		Run: func(ctx context.Context, _ ProgressFunc) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		},
+	})
+	if err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+	rec.waitText(t, "执行超时（任务 #"+job.ID)
+
+	got, _ := runner.Get(job.ID)
+	if got.Status != JobStatusTimeout {
+		t.Fatalf("Status = %s, want %s", got.Status, JobStatusTimeout)
+	}
+}
+
+func TestJobRunner_ListByUser(t *testing.T) {
+	t.Parallel()
+
+	rec := &syncRecordWeCom{}
+	runner := NewJobRunner(JobRunnerDeps{WeCom: rec, Workers: 2})
+	defer runner.Shutdown(context.Background())
+
+	ok := ConfirmedAction{Action: ActionUnraidRestart, Run: func(context.Context, ProgressFunc) (string, error) { return "", nil }}
+	for _, u := range []string{"a", "b", "a"} {
+		if _, err := runner.Submit(context.Background(), u, ok); err != nil {
+			t.Fatalf("Submit() error: %v", err)
+		}
+	}
+
+	jobs := runner.ListByUser("a", 10)
+	if len(jobs) != 2 {
+		t.Fatalf("ListByUser(a) len = %d, want 2", len(jobs))
+	}
+	if text := FormatJobList(jobs); !strings.Contains(text, "最近任务") {
+		t.Fatalf("FormatJobList() = %q", text)
+	}
+}
+
+type memAuditSink struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+func (s *memAuditSink) Record(_ context.Context, e AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *memAuditSink) Query(_ context.Context, q AuditQuery) ([]AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []AuditEntry
+	for i := len(s.entries) - 1; i >= 0 && (q.Limit <= 0 || len(out) < q.Limit); i-- {
+		out = append(out, s.entries[i])
+	}
+	return out, nil
+}
+
+func (s *memAuditSink) Close() error { return nil }
+
+func (s *memAuditSink) snapshot() []AuditEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AuditEntry(nil), s.entries...)
+}
+
+func TestJobRunner_RecordsAudit(t *testing.T) {
+	t.Parallel()
+
+	rec := &syncRecordWeCom{}
+	sink := &memAuditSink{}
+	runner := NewJobRunner(JobRunnerDeps{WeCom: rec, Audit: sink, Workers: 1})
+
+	job, err := runner.Submit(context.Background(), "u", ConfirmedAction{
+		ServiceKey: "qinglong",
+		InstanceID: "ql",
+		Action:     ActionQinglongRun,
+		Target:     "任务ID 7",
+		Run: func(context.Context, ProgressFunc) (string, error) {
+			return "", errors.New("boom")
+		},
+	})
+	if err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+	if err := runner.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error: %v", err)
+	}
+
+	entries := sink.snapshot()
+	if len(entries) != 1 {
+		t.Fatalf("audit entries = %d, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.UserID != "u" || e.ServiceKey != "qinglong" || e.InstanceID != "ql" || e.Action != ActionQinglongRun ||
+		e.Target != "任务ID 7" || e.JobID != job.ID || e.Result != AuditResultFailed || e.Error != "boom" {
+		t.Fatalf("audit entry = %+v", e)
+	}
+}
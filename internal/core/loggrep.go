@@ -0,0 +1,253 @@
+package core
+
+// loggrep.go 提供日志检索：按关键词或正则筛选日志行（可忽略大小写、附带上下文行），
+// 并按企业微信消息长度渲染匹配结果；Unraid 容器日志与青龙任务日志共用。
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// LogGrepMaxContext 为每处匹配前后附带上下文行数的上限。
+	LogGrepMaxContext = 5
+	// LogGrepUsage 为检索参数说明，供各服务的用法提示复用。
+	LogGrepUsage = "关键词 [-i 忽略大小写] [-e 正则] [-C 上下文行数]"
+
+	logGrepMaxPattern  = 200
+	logGrepMaxLineRune = 300
+)
+
+// LogGrepOptions 为日志检索条件。
+type LogGrepOptions struct {
+	Pattern string
+	// Regex 为 true 时 Pattern 按正则（RE2 语法）匹配，否则按子串匹配。
+	Regex      bool
+	IgnoreCase bool
+	// Context 为每处匹配前后附带的行数（0～LogGrepMaxContext）。
+	Context int
+}
+
+// ParseLogGrepArgs 解析“关键词... [-i] [-e] [-C N]”：其余词以空格拼接为关键词，“--” 之后的词不再视为选项。
+func ParseLogGrepArgs(tokens []string) (LogGrepOptions, error) {
+	var opts LogGrepOptions
+	var words []string
+	literal := false
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if literal {
+			words = append(words, tok)
+			continue
+		}
+		switch {
+		case tok == "--":
+			literal = true
+		case tok == "-i":
+			opts.IgnoreCase = true
+		case tok == "-e":
+			opts.Regex = true
+		case tok == "-C":
+			if i+1 >= len(tokens) {
+				return LogGrepOptions{}, errors.New("-C 需指定上下文行数")
+			}
+			i++
+			n, err := parseLogGrepContext(tokens[i])
+			if err != nil {
+				return LogGrepOptions{}, err
+			}
+			opts.Context = n
+		case strings.HasPrefix(tok, "-C"):
+			n, err := parseLogGrepContext(strings.TrimPrefix(tok, "-C"))
+			if err != nil {
+				return LogGrepOptions{}, err
+			}
+			opts.Context = n
+		default:
+			words = append(words, tok)
+		}
+	}
+
+	opts.Pattern = strings.Join(words, " ")
+	if opts.Pattern == "" {
+		return LogGrepOptions{}, errors.New("缺少检索关键词")
+	}
+	if utf8.RuneCountInString(opts.Pattern) > logGrepMaxPattern {
+		return LogGrepOptions{}, fmt.Errorf("检索关键词过长（最多 %d 字）", logGrepMaxPattern)
+	}
+	if _, err := opts.matcher(); err != nil {
+		return LogGrepOptions{}, err
+	}
+	return opts, nil
+}
+
+func parseLogGrepContext(raw string) (int, error) {
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("上下文行数不合法：%s", raw)
+	}
+	return min(n, LogGrepMaxContext), nil
+}
+
+func (o LogGrepOptions) matcher() (func(string) bool, error) {
+	if o.Regex {
+		expr := o.Pattern
+		if o.IgnoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("正则表达式无效：%s", o.Pattern)
+		}
+		return re.MatchString, nil
+	}
+	if o.IgnoreCase {
+		needle := strings.ToLower(o.Pattern)
+		return func(s string) bool { return strings.Contains(strings.ToLower(s), needle) }, nil
+	}
+	return func(s string) bool { return strings.Contains(s, o.Pattern) }, nil
+}
+
+// Describe 返回检索条件说明，如““error”（正则，忽略大小写，上下文 2 行）”。
+func (o LogGrepOptions) Describe() string {
+	var flags []string
+	if o.Regex {
+		flags = append(flags, "正则")
+	}
+	if o.IgnoreCase {
+		flags = append(flags, "忽略大小写")
+	}
+	if o.Context > 0 {
+		flags = append(flags, fmt.Sprintf("上下文 %d 行", o.Context))
+	}
+	s := "“" + o.Pattern + "”"
+	if len(flags) > 0 {
+		s += "（" + strings.Join(flags, "，") + "）"
+	}
+	return s
+}
+
+// LogGrepLine 为输出中的一行：No 为在检索窗口内的行号（从 1 开始），Match 区分匹配行与上下文行。
+type LogGrepLine struct {
+	No    int
+	Text  string
+	Match bool
+}
+
+// LogGrepResult 为检索结果：Blocks 为互不相邻的片段（匹配行及其上下文，重叠时合并）。
+type LogGrepResult struct {
+	Scanned int
+	Matches int
+	Blocks  [][]LogGrepLine
+}
+
+// GrepLog 在 content 中按 opts 检索匹配行。
+func GrepLog(content string, opts LogGrepOptions) (LogGrepResult, error) {
+	match, err := opts.matcher()
+	if err != nil {
+		return LogGrepResult{}, err
+	}
+	content = strings.TrimRight(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if content == "" {
+		return LogGrepResult{}, nil
+	}
+	lines := strings.Split(content, "\n")
+	ctxLines := max(0, min(opts.Context, LogGrepMaxContext))
+
+	res := LogGrepResult{Scanned: len(lines)}
+	end := -1
+	for i, line := range lines {
+		if !match(line) {
+			continue
+		}
+		res.Matches++
+		from := max(0, i-ctxLines)
+		if len(res.Blocks) == 0 || from > end+1 {
+			res.Blocks = append(res.Blocks, nil)
+		} else {
+			from = end + 1
+		}
+		to := min(len(lines)-1, i+ctxLines)
+		cur := &res.Blocks[len(res.Blocks)-1]
+		for j := from; j <= to; j++ {
+			*cur = append(*cur, LogGrepLine{No: j + 1, Text: lines[j]})
+		}
+		// 匹配行可能已作为上一处匹配的下文写入，按行号回填标记。
+		for k := range *cur {
+			if (*cur)[k].No == i+1 {
+				(*cur)[k].Match = true
+			}
+		}
+		end = max(end, to)
+	}
+	return res, nil
+}
+
+// FormatLogGrep 渲染检索结果：首行为标题，随后为检索条件与匹配计数；匹配行以“行号:”、上下文行以“行号-”开头，
+// 片段之间以“--”分隔。超出 maxBytes 时保留最近的匹配，并注明省略数量。
+func FormatLogGrep(title string, opts LogGrepOptions, res LogGrepResult, maxBytes int) string {
+	head := fmt.Sprintf("%s\n检索：%s\n匹配 %d 行（共扫描 %d 行）", title, opts.Describe(), res.Matches, res.Scanned)
+	if res.Matches == 0 {
+		return head + "\n未找到匹配行"
+	}
+
+	const omittedReserve = 64
+	budget := maxBytes - len(head) - omittedReserve
+	var out []string
+	shown := 0
+	used := 0
+	full := false
+	for b := len(res.Blocks) - 1; b >= 0 && !full; b-- {
+		block := res.Blocks[b]
+		if len(out) > 0 {
+			if used+len("\n--") > budget {
+				break
+			}
+			out = append(out, "--")
+			used += len("\n--")
+		}
+		for l := len(block) - 1; l >= 0; l-- {
+			line := formatLogGrepLine(block[l])
+			if used+len(line)+1 > budget {
+				full = true
+				break
+			}
+			out = append(out, line)
+			used += len(line) + 1
+			if block[l].Match {
+				shown++
+			}
+		}
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	if len(out) > 0 && out[0] == "--" {
+		out = out[1:]
+	}
+
+	var sb strings.Builder
+	sb.WriteString(head)
+	if shown < res.Matches {
+		fmt.Fprintf(&sb, "\n…（仅显示最近 %d 处匹配，已省略 %d 处）", shown, res.Matches-shown)
+	}
+	for _, line := range out {
+		sb.WriteString("\n")
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+func formatLogGrepLine(l LogGrepLine) string {
+	sep := "-"
+	if l.Match {
+		sep = ":"
+	}
+	text := l.Text
+	if utf8.RuneCountInString(text) > logGrepMaxLineRune {
+		text = string([]rune(text)[:logGrepMaxLineRune]) + "…"
+	}
+	return strconv.Itoa(l.No) + sep + text
+}
@@ -0,0 +1,78 @@
+// 日志检索单元测试。
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLogGrepArgs(t *testing.T) {
+	t.Parallel()
+
+	opts, err := ParseLogGrepArgs([]string{"upstream", "-i", "timed", "-C2", "out"})
+	if err != nil {
+		t.Fatalf("ParseLogGrepArgs() error: %v", err)
+	}
+	if opts.Pattern != "upstream timed out" || !opts.IgnoreCase || opts.Regex || opts.Context != 2 {
+		t.Fatalf("ParseLogGrepArgs() = %+v", opts)
+	}
+
+	opts, err = ParseLogGrepArgs([]string{"-e", "-C", "9", "5\\d{2},", "--", "-i"})
+	if err != nil {
+		t.Fatalf("ParseLogGrepArgs(regex) error: %v", err)
+	}
+	if opts.Pattern != "5\\d{2}, -i" || !opts.Regex || opts.IgnoreCase || opts.Context != LogGrepMaxContext {
+		t.Fatalf("ParseLogGrepArgs(regex) = %+v", opts)
+	}
+
+	for _, tokens := range [][]string{nil, {"-i"}, {"a", "-C"}, {"a", "-Cx"}, {"-e", "(a"}} {
+		if _, err := ParseLogGrepArgs(tokens); err == nil {
+			t.Fatalf("ParseLogGrepArgs(%v) error = nil, want not nil", tokens)
+		}
+	}
+}
+
+func TestGrepLog(t *testing.T) {
+	t.Parallel()
+
+	content := strings.Join([]string{
+		"start",
+		"GET / 200",
+		"ERROR db timeout",
+		"retry",
+		"error again",
+		"ok",
+		"ok",
+		"ok",
+		"ERROR disk full",
+	}, "\n") + "\n"
+
+	res, err := GrepLog(content, LogGrepOptions{Pattern: "error", IgnoreCase: true, Context: 1})
+	if err != nil {
+		t.Fatalf("GrepLog() error: %v", err)
+	}
+	if res.Scanned != 9 || res.Matches != 3 || len(res.Blocks) != 2 {
+		t.Fatalf("GrepLog() = %+v", res)
+	}
+	got := FormatLogGrep("【检索】app", LogGrepOptions{Pattern: "error", IgnoreCase: true, Context: 1}, res, 1800)
+	want := "【检索】app\n检索：“error”（忽略大小写，上下文 1 行）\n匹配 3 行（共扫描 9 行）\n" +
+		"2-GET / 200\n3:ERROR db timeout\n4-retry\n5:error again\n6-ok\n--\n8-ok\n9:ERROR disk full"
+	if got != want {
+		t.Fatalf("FormatLogGrep() =\n%s\nwant\n%s", got, want)
+	}
+
+	res, _ = GrepLog(content, LogGrepOptions{Pattern: `^ERROR \w+`, Regex: true})
+	if res.Matches != 2 {
+		t.Fatalf("GrepLog(regex) matches = %d, want 2", res.Matches)
+	}
+	// 空间不足时保留最近的匹配并注明省略数量。
+	got = FormatLogGrep("【检索】app", LogGrepOptions{Pattern: `^ERROR \w+`, Regex: true}, res, 180)
+	if !strings.Contains(got, "仅显示最近 1 处匹配，已省略 1 处") || !strings.HasSuffix(got, "9:ERROR disk full") || strings.Contains(got, "db timeout") {
+		t.Fatalf("FormatLogGrep(truncated) = %q", got)
+	}
+
+	res, _ = GrepLog(content, LogGrepOptions{Pattern: "panic"})
+	if got := FormatLogGrep("【检索】app", LogGrepOptions{Pattern: "panic"}, res, 1800); !strings.HasSuffix(got, "匹配 0 行（共扫描 9 行）\n未找到匹配行") {
+		t.Fatalf("FormatLogGrep(no match) = %q", got)
+	}
+}
@@ -0,0 +1,93 @@
+package core
+
+// menu.go 由已启用的 Provider 生成企业微信应用自定义菜单：“常用”固定在首位，
+// 其余一级菜单按 Provider 注册顺序（或配置顺序）分配，放不下的服务以入口按钮并入“常用”。
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// MenuProvider 为 ServiceProvider 的可选扩展：声明该服务在应用自定义菜单中的一级按钮（含二级按钮）。
+// 未实现时以 DisplayName 生成一个直达服务菜单的按钮。
+type MenuProvider interface {
+	MenuButton() wecom.MenuButton
+}
+
+// MenuOptions 为菜单生成的配置覆盖。
+type MenuOptions struct {
+	// Services 指定占用一级菜单的服务 Key 及顺序；为空时按 Provider 注册顺序取前 2 个。
+	Services []string
+	// Buttons 非空时完全替换自动生成的菜单（仍按官方限制校验）。
+	Buttons []wecom.MenuButton
+}
+
+// BuildMenu 生成应用自定义菜单，并按企业微信 3×5 限制校验。
+func BuildMenu(providers []ServiceProvider, opts MenuOptions) (wecom.Menu, error) {
+	if len(opts.Buttons) > 0 {
+		menu := wecom.Menu{Buttons: opts.Buttons}
+		return menu, menu.Validate()
+	}
+
+	ordered := providers
+	topSlots := wecom.MaxMenuButtons - 1
+	if len(opts.Services) > 0 {
+		ordered = nil
+		for _, key := range opts.Services {
+			if i := slices.IndexFunc(providers, func(p ServiceProvider) bool { return p.Key() == key }); i >= 0 {
+				ordered = append(ordered, providers[i])
+			}
+		}
+		topSlots = min(topSlots, len(ordered))
+		for _, p := range providers {
+			if !slices.Contains(ordered, p) {
+				ordered = append(ordered, p)
+			}
+		}
+	}
+
+	common := wecom.MenuButton{
+		Name: "常用",
+		SubButtons: []wecom.MenuButton{
+			{Type: "click", Name: "操作菜单", Key: wecom.EventKeyCoreMenu},
+			{Type: "click", Name: "我的常用", Key: wecom.EventKeyCoreFavorites},
+		},
+	}
+	tail := []wecom.MenuButton{
+		{Type: "click", Name: "自检", Key: wecom.EventKeyCoreSelfTest},
+		{Type: "click", Name: "帮助", Key: wecom.EventKeyCoreHelp},
+	}
+
+	menu := wecom.Menu{Buttons: []wecom.MenuButton{common}}
+	for i, p := range ordered {
+		btn := providerMenuButton(p)
+		if i < topSlots {
+			if len(btn.SubButtons) > wecom.MaxMenuSubButtons {
+				slog.Warn("服务菜单项超出上限，已截断", "service", p.Key(), "count", len(btn.SubButtons))
+				btn.SubButtons = btn.SubButtons[:wecom.MaxMenuSubButtons]
+			}
+			menu.Buttons = append(menu.Buttons, btn)
+			continue
+		}
+		// 一级菜单已满：并入“常用”的服务入口（仍可通过“操作菜单”进入全部服务）。
+		if len(menu.Buttons[0].SubButtons)+len(tail) >= wecom.MaxMenuSubButtons {
+			slog.Warn("应用菜单已满，服务仅可通过操作菜单进入", "service", p.Key())
+			continue
+		}
+		menu.Buttons[0].SubButtons = append(menu.Buttons[0].SubButtons, wecom.MenuButton{
+			Type: "click",
+			Name: btn.Name,
+			Key:  wecom.EventKeyServiceSelectPrefix + p.Key(),
+		})
+	}
+	menu.Buttons[0].SubButtons = append(menu.Buttons[0].SubButtons, tail...)
+	return menu, menu.Validate()
+}
+
+func providerMenuButton(p ServiceProvider) wecom.MenuButton {
+	if mp, ok := p.(MenuProvider); ok {
+		return mp.MenuButton()
+	}
+	return wecom.MenuButton{Type: "click", Name: p.DisplayName(), Key: wecom.EventKeyServiceSelectPrefix + p.Key()}
+}
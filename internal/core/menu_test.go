@@ -0,0 +1,92 @@
+// 应用自定义菜单生成单元测试。
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// menuProvider 声明带二级菜单的一级按钮。
+type menuProvider struct {
+	fakeProvider
+	subs int
+}
+
+func (p *menuProvider) MenuButton() wecom.MenuButton {
+	btn := wecom.MenuButton{Name: p.name}
+	for i := 0; i < p.subs; i++ {
+		btn.SubButtons = append(btn.SubButtons, wecom.MenuButton{Type: "click", Name: "项" + string(rune('A'+i)), Key: p.key + ".menu"})
+	}
+	return btn
+}
+
+func menuNames(m wecom.Menu) [][]string {
+	var out [][]string
+	for _, b := range m.Buttons {
+		row := []string{b.Name}
+		for _, sb := range b.SubButtons {
+			row = append(row, sb.Name)
+		}
+		out = append(out, row)
+	}
+	return out
+}
+
+func TestBuildMenu(t *testing.T) {
+	t.Parallel()
+
+	unraid := &menuProvider{fakeProvider: fakeProvider{key: "unraid", name: "Unraid"}, subs: 6}
+	ql := &fakeProvider{key: "qinglong", name: "青龙"}
+	pve := &menuProvider{fakeProvider: fakeProvider{key: "pve", name: "PVE"}, subs: 2}
+
+	// 默认：前 2 个服务占一级菜单（超出 5 个二级菜单截断），其余并入“常用”。
+	menu, err := BuildMenu([]ServiceProvider{unraid, ql, pve}, MenuOptions{})
+	if err != nil {
+		t.Fatalf("BuildMenu() error: %v", err)
+	}
+	want := [][]string{
+		{"常用", "操作菜单", "我的常用", "PVE", "自检", "帮助"},
+		{"Unraid", "项A", "项B", "项C", "项D", "项E"},
+		{"青龙"},
+	}
+	if got := menuNames(menu); !reflect.DeepEqual(got, want) {
+		t.Fatalf("menu = %v, want %v", got, want)
+	}
+	if got := menu.Buttons[0].SubButtons[2].Key; got != wecom.EventKeyServiceSelectPrefix+"pve" {
+		t.Fatalf("PVE entry key = %q", got)
+	}
+	if b := menu.Buttons[2]; b.Type != "click" || b.Key != wecom.EventKeyServiceSelectPrefix+"qinglong" {
+		t.Fatalf("qinglong button = %+v, want click to service menu", b)
+	}
+
+	// 未启用的服务不出现在菜单中。
+	menu, err = BuildMenu([]ServiceProvider{pve}, MenuOptions{})
+	if err != nil {
+		t.Fatalf("BuildMenu(pve only) error: %v", err)
+	}
+	want = [][]string{{"常用", "操作菜单", "我的常用", "自检", "帮助"}, {"PVE", "项A", "项B"}}
+	if got := menuNames(menu); !reflect.DeepEqual(got, want) {
+		t.Fatalf("menu(pve only) = %v, want %v", got, want)
+	}
+
+	// 配置指定一级菜单服务：未列出的服务并入“常用”，放不下时仅可通过操作菜单进入。
+	menu, err = BuildMenu([]ServiceProvider{unraid, ql, pve}, MenuOptions{Services: []string{"pve", "nas"}})
+	if err != nil {
+		t.Fatalf("BuildMenu(services) error: %v", err)
+	}
+	want = [][]string{{"常用", "操作菜单", "我的常用", "Unraid", "自检", "帮助"}, {"PVE", "项A", "项B"}}
+	if got := menuNames(menu); !reflect.DeepEqual(got, want) {
+		t.Fatalf("menu(services) = %v, want %v", got, want)
+	}
+
+	// 完全覆盖：原样使用并校验。
+	override := []wecom.MenuButton{{Type: "view", Name: "面板", URL: "https://nas.example.com"}}
+	if menu, err = BuildMenu([]ServiceProvider{unraid}, MenuOptions{Buttons: override}); err != nil || !reflect.DeepEqual(menu.Buttons, override) {
+		t.Fatalf("BuildMenu(override) = %+v, %v", menu, err)
+	}
+	if _, err := BuildMenu(nil, MenuOptions{Buttons: []wecom.MenuButton{{Type: "click", Name: "a"}}}); err == nil {
+		t.Fatalf("BuildMenu(invalid override) error = nil, want not nil")
+	}
+}
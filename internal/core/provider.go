@@ -25,3 +25,22 @@ type ServiceProvider interface {
 	HandleEvent(ctx context.Context, userID string, msg wecom.IncomingMessage) (bool, error)
 	HandleConfirm(ctx context.Context, userID string) (bool, error)
 }
+
+// DisabledService 描述一个已注册但未启用的服务（由服务注册表按配置生成）。
+type DisabledService struct {
+	Key         string
+	DisplayName string
+	// Hint 为启用所需的配置项说明，如“unraid.endpoint 与 unraid.api_key”。
+	Hint string
+}
+
+func (d DisabledService) describe() string {
+	name := d.DisplayName
+	if name == "" {
+		name = d.Key
+	}
+	if d.Hint == "" {
+		return name + " 服务未启用：请检查 config.yaml 配置后重启服务。"
+	}
+	return name + " 服务未启用：请在 config.yaml 配置 " + d.Hint + " 后重启服务。"
+}
@@ -4,30 +4,63 @@ package core
 // router.go 负责企业微信消息入口的鉴权、会话管理与多服务 Provider 分发。
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/zcw199604/wecom-home-ops/internal/wecom"
 )
 
 type RouterDeps struct {
-	WeCom         WeComSender
+	WeCom WeComSender
+	// Auth 为角色授权；为空时回退到 AllowedUserID 白名单（白名单内账号视为 admin）。
+	Auth          *Authorizer
 	AllowedUserID map[string]struct{}
 	Providers     []ServiceProvider
-	State         *StateStore
+	State         StateStore
+	// Jobs 可选：配置后确认类动作将异步执行（回调立即返回），否则在回调内同步执行。
+	Jobs *JobRunner
+	// Audit 可选：同步执行路径的审计落地（异步路径由 JobRunner 负责），并用于“审计”命令查询。
+	Audit AuditSink
+	// Approval 可选：命中策略的确认动作需另一名具备同等权限的账号批准后执行。
+	Approval ApprovalPolicy
+	// Scheduler 可选：启用“重启 jellyfin 凌晨3点”等定时/延时指令；到点后由 Router 重建并执行动作。
+	Scheduler *Scheduler
+	// History 可选：已确认操作的记录存储（“重复”/“最近操作”）；为空时使用内存存储。
+	History HistoryStore
+	// Favorites 可选：用户收藏（“常用”）存储；为空时使用内存存储。
+	Favorites FavoriteStore
+	// Menu 为应用自定义菜单的配置覆盖（“同步菜单”时按已启用的 Provider 生成）。
+	Menu MenuOptions
+	// Disabled 为已注册但未启用的服务，点击其菜单/按钮时回复启用提示。
+	Disabled []DisabledService
 }
 
 type Router struct {
-	WeCom         WeComSender
-	AllowedUserID map[string]struct{}
-
-	state        *StateStore
+	WeCom WeComSender
+
+	auth         *Authorizer
+	state        StateStore
+	jobs         *JobRunner
+	audit        AuditSink
+	approvals    *approvalBook
+	scheduler    *Scheduler
 	providerList []ServiceProvider
 	providers    map[string]ServiceProvider
 	keywordIndex map[string]string
+	commands     *CommandRegistry
+	menu         MenuOptions
+	disabled     map[string]DisabledService
+
+	history   HistoryStore
+	historyMu sync.Mutex
+
+	favorites   FavoriteStore
+	favoritesMu sync.Mutex
 }
 
 type templateCardUpdater interface {
@@ -41,7 +74,7 @@ type menuCreator interface {
 func NewRouter(deps RouterDeps) *Router {
 	state := deps.State
 	if state == nil {
-		state = NewStateStore(30 * time.Minute)
+		state = NewMemoryStateStore(30 * time.Minute)
 	}
 
 	providers := make(map[string]ServiceProvider)
@@ -57,6 +90,13 @@ func NewRouter(deps RouterDeps) *Router {
 		list = append(list, p)
 	}
 
+	disabled := make(map[string]DisabledService)
+	for _, d := range deps.Disabled {
+		if _, enabled := providers[d.Key]; d.Key != "" && !enabled {
+			disabled[d.Key] = d
+		}
+	}
+
 	keywordIndex := make(map[string]string)
 	for _, p := range list {
 		for _, k := range p.EntryKeywords() {
@@ -68,14 +108,64 @@ func NewRouter(deps RouterDeps) *Router {
 		}
 	}
 
-	return &Router{
-		WeCom:         deps.WeCom,
-		AllowedUserID: deps.AllowedUserID,
-		state:         state,
-		providerList:  list,
-		providers:     providers,
-		keywordIndex:  keywordIndex,
+	commands := NewCommandRegistry()
+	for _, p := range list {
+		cp, ok := p.(CommandProvider)
+		if !ok {
+			continue
+		}
+		if err := commands.Register(p.Key(), cp.Commands()...); err != nil {
+			slog.Error("注册服务命令失败", "service", p.Key(), "error", err)
+		}
+	}
+
+	auth := deps.Auth
+	if auth == nil {
+		ids := make([]string, 0, len(deps.AllowedUserID))
+		for id := range deps.AllowedUserID {
+			ids = append(ids, id)
+		}
+		auth = NewAllowAllAuthorizer(ids)
+	}
+
+	history := deps.History
+	if history == nil {
+		history = NewMemoryHistoryStore()
+	}
+
+	favorites := deps.Favorites
+	if favorites == nil {
+		favorites = NewMemoryFavoriteStore()
+	}
+
+	var approvals *approvalBook
+	if len(deps.Approval.Actions) > 0 {
+		approvals = newApprovalBook(deps.Approval)
+	}
+
+	r := &Router{
+		WeCom:        deps.WeCom,
+		approvals:    approvals,
+		scheduler:    deps.Scheduler,
+		auth:         auth,
+		state:        state,
+		jobs:         deps.Jobs,
+		audit:        deps.Audit,
+		providerList: list,
+		providers:    providers,
+		keywordIndex: keywordIndex,
+		commands:     commands,
+		menu:         deps.Menu,
+		disabled:     disabled,
+		history:      history,
+		favorites:    favorites,
 	}
+	if deps.Scheduler != nil {
+		deps.Scheduler.mu.Lock()
+		deps.Scheduler.router = r
+		deps.Scheduler.mu.Unlock()
+	}
+	return r
 }
 
 func (r *Router) HandleMessage(ctx context.Context, msg wecom.IncomingMessage) error {
@@ -83,7 +173,7 @@ func (r *Router) HandleMessage(ctx context.Context, msg wecom.IncomingMessage) e
 	if userID == "" {
 		return nil
 	}
-	if _, ok := r.AllowedUserID[userID]; !ok {
+	if !r.auth.Allowed(userID) {
 		if err := r.WeCom.SendText(ctx, wecom.TextMessage{
 			ToUser:  userID,
 			Content: "无权限：该账号未加入白名单。",
@@ -155,7 +245,7 @@ func (r *Router) handleText(ctx context.Context, userID string, content string)
 					Content: "服务不可用，请输入“菜单”重新开始。",
 				})
 			}
-			handled, err := p.HandleConfirm(ctx, userID)
+			handled, err := r.dispatchConfirm(ctx, userID, p)
 			if err != nil {
 				return err
 			}
@@ -172,12 +262,50 @@ func (r *Router) handleText(ctx context.Context, userID string, content string)
 		}
 	}
 
+	if handled, err := r.handleCommand(ctx, userID, content); handled {
+		return err
+	}
 	if isHelpKeyword(keyword) {
+		if fields := strings.Fields(content); len(fields) > 1 {
+			if serviceKey, ok := r.commands.namespaces[strings.ToLower(fields[1])]; ok {
+				return r.sendCommandHelp(ctx, userID, serviceKey, r.providers[serviceKey].DisplayName()+" 命令")
+			}
+		}
 		return r.sendHelp(ctx, userID)
 	}
 	if isMenuSyncKeyword(keyword) {
+		if !r.auth.Can(userID, PermCoreMenuSync) {
+			return r.sendForbidden(ctx, userID, PermCoreMenuSync)
+		}
 		return r.syncWeComMenu(ctx, userID)
 	}
+	if isJobStatusKeyword(keyword) {
+		return r.sendJobStatus(ctx, userID)
+	}
+	if isAuditKeyword(keyword) {
+		if !r.auth.Can(userID, PermCoreAudit) {
+			return r.sendForbidden(ctx, userID, PermCoreAudit)
+		}
+		return r.sendAudit(ctx, userID, content)
+	}
+	if isRepeatKeyword(keyword) {
+		return r.rerunHistory(ctx, userID, "")
+	}
+	if isHistoryKeyword(keyword) {
+		return r.sendHistory(ctx, userID)
+	}
+	if isFavoritesKeyword(keyword) {
+		return r.sendFavorites(ctx, userID)
+	}
+	if isScheduleListKeyword(keyword) {
+		return r.sendSchedules(ctx, userID)
+	}
+	if isScheduleCancelKeyword(keyword) {
+		return r.cancelSchedule(ctx, userID, content)
+	}
+	if handled, err := r.handleScheduleText(ctx, userID, content); handled {
+		return err
+	}
 
 	if isMenuKeyword(keyword) {
 		r.state.Clear(userID)
@@ -186,15 +314,11 @@ func (r *Router) handleText(ctx context.Context, userID string, content string)
 
 	normalized := normalizeKeyword(content)
 	if providerKey, ok := r.keywordIndex[normalized]; ok {
-		r.state.Clear(userID)
-		r.state.Set(userID, ConversationState{ServiceKey: providerKey})
-		return r.enterProvider(ctx, userID, providerKey)
+		return r.selectProvider(ctx, userID, providerKey)
 	}
 	if strings.HasPrefix(strings.TrimSpace(content), "/") {
 		if providerKey, ok := r.keywordIndex[keyword]; ok {
-			r.state.Clear(userID)
-			r.state.Set(userID, ConversationState{ServiceKey: providerKey})
-			return r.enterProvider(ctx, userID, providerKey)
+			return r.selectProvider(ctx, userID, providerKey)
 		}
 	}
 
@@ -256,6 +380,8 @@ func (r *Router) handleEvent(ctx context.Context, userID string, msg wecom.Incom
 		return r.sendServiceMenu(ctx, userID)
 	case wecom.EventKeyCoreHelp:
 		return r.sendHelp(ctx, userID)
+	case wecom.EventKeyCoreFavorites:
+		return r.sendFavorites(ctx, userID)
 	case wecom.EventKeyCoreSelfTest:
 		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: buildSelfTestReply(msg)})
 	}
@@ -279,7 +405,7 @@ func (r *Router) handleEvent(ctx context.Context, userID string, msg wecom.Incom
 			})
 		}
 
-		handled, err := p.HandleConfirm(ctx, userID)
+		handled, err := r.dispatchConfirm(ctx, userID, p)
 		if err != nil {
 			return err
 		}
@@ -299,29 +425,38 @@ func (r *Router) handleEvent(ctx context.Context, userID string, msg wecom.Incom
 		})
 	}
 
-	if strings.HasPrefix(key, wecom.EventKeyServiceSelectPrefix) {
-		serviceKey := strings.TrimPrefix(key, wecom.EventKeyServiceSelectPrefix)
-		r.state.Clear(userID)
-		r.state.Set(userID, ConversationState{ServiceKey: serviceKey})
-		return r.enterProvider(ctx, userID, serviceKey)
+	if strings.HasPrefix(key, wecom.EventKeyApprovalApprovePrefix) || strings.HasPrefix(key, wecom.EventKeyApprovalRejectPrefix) {
+		return r.handleApprovalEvent(ctx, userID, key)
 	}
 
-	if strings.HasPrefix(key, "unraid.") {
-		if _, ok := r.providers["unraid"]; !ok {
-			return r.sendServiceUnavailable(ctx, userID, "unraid")
-		}
+	if strings.HasPrefix(key, wecom.EventKeyHistoryRerunPrefix) {
+		return r.rerunHistory(ctx, userID, strings.TrimPrefix(key, wecom.EventKeyHistoryRerunPrefix))
 	}
-	if strings.HasPrefix(key, "qinglong.") {
-		if _, ok := r.providers["qinglong"]; !ok {
-			return r.sendServiceUnavailable(ctx, userID, "qinglong")
-		}
+	if strings.HasPrefix(key, wecom.EventKeyFavoriteAddPrefix) {
+		return r.addFavorite(ctx, userID, key)
+	}
+	if strings.HasPrefix(key, wecom.EventKeyFavoriteOpenPrefix) {
+		return r.openFavorite(ctx, userID, strings.TrimPrefix(key, wecom.EventKeyFavoriteOpenPrefix))
+	}
+	if strings.HasPrefix(key, wecom.EventKeyFavoriteRemovePrefix) {
+		return r.removeFavorite(ctx, userID, strings.TrimPrefix(key, wecom.EventKeyFavoriteRemovePrefix))
 	}
-	if strings.HasPrefix(key, "pve.") {
-		if _, ok := r.providers["pve"]; !ok {
-			return r.sendServiceUnavailable(ctx, userID, "pve")
+
+	if strings.HasPrefix(key, wecom.EventKeyServiceSelectPrefix) {
+		return r.selectProvider(ctx, userID, strings.TrimPrefix(key, wecom.EventKeyServiceSelectPrefix))
+	}
+
+	// 已注册但未启用的服务（如旧菜单中的按钮）：提示启用方法。
+	if namespace, _, ok := strings.Cut(key, "."); ok {
+		if _, disabled := r.disabled[namespace]; disabled {
+			return r.sendServiceUnavailable(ctx, userID, namespace)
 		}
 	}
 
+	if perm := r.eventPermission(key); perm != "" && !r.auth.Can(userID, perm) {
+		return r.sendForbidden(ctx, userID, perm)
+	}
+
 	if serviceKey := r.providerKeyFromEventKey(key); serviceKey != "" {
 		p := r.providers[serviceKey]
 		handled, err := p.HandleEvent(ctx, userID, msg)
@@ -360,11 +495,222 @@ func (r *Router) templateCardReplaceName(eventKey string) string {
 		return "已确认"
 	case wecom.EventKeyCancel:
 		return "已取消"
+	}
+	switch {
+	case strings.HasPrefix(eventKey, wecom.EventKeyApprovalApprovePrefix):
+		return "已批准"
+	case strings.HasPrefix(eventKey, wecom.EventKeyApprovalRejectPrefix):
+		return "已驳回"
+	case strings.HasPrefix(eventKey, wecom.EventKeyHistoryRerunPrefix):
+		return "已重新发起"
+	case strings.HasPrefix(eventKey, wecom.EventKeyFavoriteAddPrefix):
+		return "已收藏"
+	case strings.HasPrefix(eventKey, wecom.EventKeyFavoriteRemovePrefix):
+		return "已取消收藏"
 	default:
 		return "已处理"
 	}
 }
 
+// dispatchConfirm 将确认动作交给 Provider：支持异步执行时投递到 JobRunner，否则同步执行；两条路径均写入审计。
+// 执行前按动作权限复核（卡片按钮可能在授权变更前下发，或经文本“确认”绕过按钮过滤）；命中审批策略时转为审批单。
+// 实际执行（或受理）的操作连同确认前的会话状态记入用户操作记录，供“重复”/“最近操作”重新发起；
+// 转为审批单的操作在获批执行后才记录，驳回或超时的不记录。
+func (r *Router) dispatchConfirm(ctx context.Context, userID string, p ServiceProvider) (bool, error) {
+	state, _ := r.state.Get(userID)
+	ap, ok := p.(AsyncConfirmProvider)
+	if !ok {
+		if state.Action != "" {
+			if perm := ServicePermission(p.Key(), string(state.Action)); !r.auth.Can(userID, perm) {
+				r.state.Clear(userID)
+				return true, r.sendForbidden(ctx, userID, perm)
+			}
+		}
+		handled, err := r.handleConfirmAudited(ctx, userID, p)
+		if handled && err == nil && state.Action != "" {
+			r.recordHistory(userID, newHistoryEntry(state, ConfirmedAction{
+				ServiceKey: p.Key(),
+				InstanceID: state.InstanceID,
+				Action:     state.Action,
+				Target:     state.ContainerName,
+			}))
+		}
+		return handled, err
+	}
+
+	action, handled, err := ap.PrepareConfirm(ctx, userID)
+	if err != nil || !handled {
+		return handled, err
+	}
+	if action.Run == nil {
+		return true, nil
+	}
+	if strings.TrimSpace(action.ServiceKey) == "" {
+		action.ServiceKey = p.Key()
+	}
+	if perm := r.deniedPermission(userID, action); perm != "" {
+		return true, r.sendForbidden(ctx, userID, perm)
+	}
+	history := newHistoryEntry(state, action)
+	if r.requiresApproval(action) {
+		return true, r.requestApproval(ctx, userID, action, &history)
+	}
+	return true, r.executeConfirmed(ctx, userID, action, &history)
+}
+
+// executeConfirmed 以 userID 身份执行已确认（或已获批）的动作：有 JobRunner 时异步投递，否则同步执行。
+// history 非空时在执行完成或任务受理后写入用户操作记录（定时任务触发时为 nil）。
+func (r *Router) executeConfirmed(ctx context.Context, userID string, action ConfirmedAction, history *HistoryEntry) error {
+	if r.jobs == nil {
+		err := runConfirmedAction(ctx, r.WeCom, r.audit, userID, action)
+		if history != nil {
+			r.recordHistory(userID, *history)
+		}
+		return err
+	}
+
+	if _, err := r.jobs.Submit(ctx, userID, action); err != nil {
+		slog.Error("任务受理失败",
+			"error", err,
+			"user_id", userID,
+			"service", action.ServiceKey,
+			"action", string(action.Action),
+		)
+		return r.WeCom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: "任务受理失败：" + err.Error() + "，请稍后重试。",
+		})
+	}
+	if history != nil {
+		r.recordHistory(userID, *history)
+	}
+	return nil
+}
+
+// handleConfirmAudited 兼容未实现 AsyncConfirmProvider 的 Provider：以确认前的会话状态作为审计目标信息。
+func (r *Router) handleConfirmAudited(ctx context.Context, userID string, p ServiceProvider) (bool, error) {
+	state, _ := r.state.Get(userID)
+	start := time.Now()
+	handled, err := p.HandleConfirm(ctx, userID)
+	if !handled || r.audit == nil {
+		return handled, err
+	}
+
+	entry := AuditEntry{
+		Time:       start,
+		UserID:     userID,
+		ServiceKey: p.Key(),
+		InstanceID: state.InstanceID,
+		Action:     state.Action,
+		Target:     state.ContainerName,
+		Result:     AuditResultSuccess,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		entry.Result = AuditResultFailed
+		entry.Error = err.Error()
+	}
+	recordAudit(ctx, r.audit, entry)
+	return handled, err
+}
+
+func (r *Router) sendJobStatus(ctx context.Context, userID string) error {
+	if r.jobs == nil {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: "未启用异步任务（操作在确认后同步执行）。",
+		})
+	}
+	return r.WeCom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: FormatJobList(r.jobs.ListByUser(userID, 10)),
+	})
+}
+
+const (
+	defaultAuditLimit = 10
+	maxAuditLimit     = 50
+)
+
+func (r *Router) sendAudit(ctx context.Context, userID string, content string) error {
+	if r.audit == nil {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: "未启用操作审计，请在 config.yaml 配置 core.audit.sink（jsonl/sqlite）后重启服务。",
+		})
+	}
+
+	limit := defaultAuditLimit
+	if fields := strings.Fields(content); len(fields) > 1 {
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n <= 0 {
+			return r.WeCom.SendText(ctx, wecom.TextMessage{
+				ToUser:  userID,
+				Content: "用法：审计 [条数]（默认" + strconv.Itoa(defaultAuditLimit) + "，最大" + strconv.Itoa(maxAuditLimit) + "）",
+			})
+		}
+		limit = min(n, maxAuditLimit)
+	}
+
+	entries, err := r.audit.Query(ctx, AuditQuery{Limit: limit})
+	if err != nil {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: "查询审计记录失败：" + err.Error(),
+		})
+	}
+	return r.WeCom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: FormatAuditEntries(entries),
+	})
+}
+
+// selectProvider 切换到指定服务并进入其菜单（需具备 <service>.view 权限）。
+func (r *Router) selectProvider(ctx context.Context, userID, serviceKey string) error {
+	if _, disabled := r.disabled[serviceKey]; disabled {
+		return r.sendServiceUnavailable(ctx, userID, serviceKey)
+	}
+	if _, ok := r.providers[serviceKey]; ok {
+		if perm := ViewPermission(serviceKey); !r.auth.Can(userID, perm) {
+			return r.sendForbidden(ctx, userID, perm)
+		}
+	}
+	r.state.Clear(userID)
+	r.state.Set(userID, ConversationState{ServiceKey: serviceKey})
+	return r.enterProvider(ctx, userID, serviceKey)
+}
+
+// eventPermission 返回 EventKey 所需权限：core 通用事件无需权限；服务事件默认 <service>.view，
+// Provider 可通过 EventPermissionProvider 为具体动作声明更细粒度的权限。
+func (r *Router) eventPermission(key string) string {
+	if strings.HasPrefix(key, wecom.EventKeyServiceSelectPrefix) {
+		return ViewPermission(strings.TrimPrefix(key, wecom.EventKeyServiceSelectPrefix))
+	}
+	serviceKey := r.providerKeyFromEventKey(key)
+	if serviceKey == "" {
+		return ""
+	}
+	if ep, ok := r.providers[serviceKey].(EventPermissionProvider); ok {
+		if perm := strings.TrimSpace(ep.EventPermission(key)); perm != "" {
+			return perm
+		}
+	}
+	return ViewPermission(serviceKey)
+}
+
+// CanEvent 判断用户能否触发 EventKey，供 TemplateCardSender 过滤卡片按钮。
+func (r *Router) CanEvent(userID, eventKey string) bool {
+	return r.auth.Can(userID, r.eventPermission(strings.TrimSpace(eventKey)))
+}
+
+func (r *Router) sendForbidden(ctx context.Context, userID, perm string) error {
+	slog.Warn("操作被拒绝：缺少权限", "user_id", userID, "permission", perm)
+	return r.WeCom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: "无权限：当前账号缺少权限 " + perm + "，请联系管理员授权。",
+	})
+}
+
 func (r *Router) enterProvider(ctx context.Context, userID, key string) error {
 	p, ok := r.providers[key]
 	if !ok {
@@ -396,13 +742,16 @@ func (r *Router) sendServiceMenu(ctx context.Context, userID string) error {
 		if strings.TrimSpace(p.Key()) == "" || strings.TrimSpace(p.DisplayName()) == "" {
 			continue
 		}
+		if !r.auth.Can(userID, ViewPermission(p.Key())) {
+			continue
+		}
 		opts = append(opts, wecom.ServiceOption{Key: p.Key(), Name: p.DisplayName()})
 	}
 
 	if len(opts) == 0 {
 		return r.WeCom.SendText(ctx, wecom.TextMessage{
 			ToUser:  userID,
-			Content: "未配置可用服务。",
+			Content: "未配置可用服务（或当前账号无任何服务的查看权限）。",
 		})
 	}
 
@@ -410,7 +759,7 @@ func (r *Router) sendServiceMenu(ctx context.Context, userID string) error {
 
 	return r.WeCom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
 		ToUser: userID,
-		Card:   wecom.NewServiceSelectCard(opts),
+		Card:   wecom.NewServiceSelectCard(favoriteOptions(r.visibleFavorites(userID)), opts),
 	})
 }
 
@@ -460,6 +809,24 @@ func isMenuSyncKeyword(normalized string) bool {
 	}
 }
 
+func isJobStatusKeyword(normalized string) bool {
+	switch normalized {
+	case "任务状态", "jobs":
+		return true
+	default:
+		return false
+	}
+}
+
+func isAuditKeyword(normalized string) bool {
+	switch normalized {
+	case "审计", "audit":
+		return true
+	default:
+		return false
+	}
+}
+
 func isSelfTestKeyword(normalized string) bool {
 	switch normalized {
 	case "ping", "自检":
@@ -489,13 +856,8 @@ func isCancelKeyword(normalized string) bool {
 
 func (r *Router) sendServiceUnavailable(ctx context.Context, userID string, serviceKey string) error {
 	msg := "服务未启用：" + serviceKey + "。请检查 config.yaml 配置后重启服务。"
-	switch serviceKey {
-	case "unraid":
-		msg = "Unraid 服务未启用：请在 config.yaml 配置 unraid.endpoint 与 unraid.api_key 后重启服务。"
-	case "qinglong":
-		msg = "青龙(QL) 服务未启用：请在 config.yaml 配置 qinglong.instances 后重启服务。"
-	case "pve":
-		msg = "PVE 服务未启用：请在 config.yaml 配置 pve.instances 后重启服务。"
+	if d, ok := r.disabled[serviceKey]; ok {
+		msg = d.describe()
 	}
 	return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: msg})
 }
@@ -520,6 +882,25 @@ func (r *Router) sendHelp(ctx context.Context, userID string) error {
 	b.WriteString("\n- 帮助 /help：查看帮助")
 	b.WriteString("\n- 自检 /ping：收发自检（pong）")
 	b.WriteString("\n- 同步菜单：创建/覆盖企业微信应用自定义菜单（管理员功能）")
+	b.WriteString("\n- 任务状态 /jobs：查看最近提交的操作任务")
+	b.WriteString("\n- 审计 /audit [条数]：查看最近的操作审计记录")
+	b.WriteString("\n- 最近操作 /history：查看最近确认的操作并一键重新发起；重复 /again：重新发起上一次操作")
+	b.WriteString("\n- 常用 /favorites：打开收藏的容器/虚拟机/任务（在确认卡片或任务卡片上点击“收藏”添加）")
+	b.WriteString("\n- 定时任务 /schedules：查看定时任务；取消定时 <编号>：取消")
+	b.WriteString("\n- 定时执行：在操作后附上时间，如“重启 jellyfin 凌晨3点”“30分钟后关机 VM 101”“每天 8:30 运行青龙任务 12”")
+	if cmds := r.commands.visible(func(perm string) bool { return r.auth.Can(userID, perm) }, ""); len(cmds) > 0 {
+		b.WriteString("\n\n快捷命令（无需打开菜单，发送“帮助 <命名空间>”查看参数）：")
+		var lastNS, lastVerb string
+		for _, c := range cmds {
+			switch ns, verb := c.words[0], c.words[1]; {
+			case ns != lastNS:
+				fmt.Fprintf(&b, "\n- /%s：%s", ns, verb)
+			case verb != lastVerb:
+				b.WriteString("、" + verb)
+			}
+			lastNS, lastVerb = c.words[0], c.words[1]
+		}
+	}
 	if len(services) > 0 {
 		b.WriteString("\n\n已启用服务：")
 		for _, s := range services {
@@ -527,11 +908,31 @@ func (r *Router) sendHelp(ctx context.Context, userID string) error {
 			b.WriteString(s)
 		}
 	}
+	if len(r.disabled) > 0 {
+		keys := make([]string, 0, len(r.disabled))
+		for k := range r.disabled {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteString("\n\n未启用服务：")
+		for _, k := range keys {
+			d := r.disabled[k]
+			b.WriteString("\n- " + d.DisplayName)
+			if d.Hint != "" {
+				b.WriteString("（配置 " + d.Hint + "）")
+			}
+		}
+	}
 	b.WriteString("\n\n提示：也可以直接点击应用底部自定义菜单触发常用操作。")
 
 	return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: b.String()})
 }
 
+// Menu 返回按已启用 Provider 与配置覆盖生成的应用自定义菜单。
+func (r *Router) Menu() (wecom.Menu, error) {
+	return BuildMenu(r.providerList, r.menu)
+}
+
 func (r *Router) syncWeComMenu(ctx context.Context, userID string) error {
 	c, ok := r.WeCom.(menuCreator)
 	if !ok {
@@ -540,7 +941,14 @@ func (r *Router) syncWeComMenu(ctx context.Context, userID string) error {
 			Content: "当前发送端不支持同步菜单。",
 		})
 	}
-	if err := c.CreateMenu(ctx, wecom.DefaultMenu()); err != nil {
+	menu, err := r.Menu()
+	if err != nil {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: "生成菜单失败：" + err.Error(),
+		})
+	}
+	if err := c.CreateMenu(ctx, menu); err != nil {
 		return r.WeCom.SendText(ctx, wecom.TextMessage{
 			ToUser:  userID,
 			Content: "同步菜单失败：" + err.Error(),
@@ -104,7 +104,7 @@ func TestRouter_Menu_SendsServiceSelectCard(t *testing.T) {
 			&fakeProvider{key: "unraid", name: "Unraid 容器"},
 			&fakeProvider{key: "qinglong", name: "青龙(QL)"},
 		},
-		State: NewStateStore(1 * time.Minute),
+		State: NewMemoryStateStore(1 * time.Minute),
 	})
 
 	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{
@@ -144,7 +144,7 @@ func TestRouter_ClickCoreMenu_SendsServiceSelectCard(t *testing.T) {
 			&fakeProvider{key: "unraid", name: "Unraid 容器"},
 			&fakeProvider{key: "qinglong", name: "青龙(QL)"},
 		},
-		State: NewStateStore(1 * time.Minute),
+		State: NewMemoryStateStore(1 * time.Minute),
 	})
 
 	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{
@@ -168,7 +168,7 @@ func TestRouter_PendingButtons_Number_TriggersEventKey(t *testing.T) {
 	userID := "u"
 
 	unraid := &fakeProvider{key: "unraid", name: "Unraid 容器", eventHandled: true}
-	state := NewStateStore(1 * time.Minute)
+	state := NewMemoryStateStore(1 * time.Minute)
 
 	r := NewRouter(RouterDeps{
 		WeCom: rec,
@@ -207,7 +207,7 @@ func TestRouter_PendingButtons_Number_TriggersConfirm(t *testing.T) {
 	userID := "u"
 
 	unraid := &fakeProvider{key: "unraid", name: "Unraid 容器", confirmHandled: true}
-	state := NewStateStore(1 * time.Minute)
+	state := NewMemoryStateStore(1 * time.Minute)
 
 	r := NewRouter(RouterDeps{
 		WeCom: rec,
@@ -258,7 +258,7 @@ func TestRouter_DirectKeyword_EntersProvider(t *testing.T) {
 		Providers: []ServiceProvider{
 			unraid,
 		},
-		State: NewStateStore(1 * time.Minute),
+		State: NewMemoryStateStore(1 * time.Minute),
 	})
 
 	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{
@@ -285,7 +285,7 @@ func TestRouter_Help_SendsTextHelp(t *testing.T) {
 		AllowedUserID: map[string]struct{}{
 			userID: {},
 		},
-		State: NewStateStore(1 * time.Minute),
+		State: NewMemoryStateStore(1 * time.Minute),
 	})
 
 	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{
@@ -318,7 +318,7 @@ func TestRouter_SyncMenu_CallsCreateMenu(t *testing.T) {
 		AllowedUserID: map[string]struct{}{
 			userID: {},
 		},
-		State: NewStateStore(1 * time.Minute),
+		State: NewMemoryStateStore(1 * time.Minute),
 	})
 
 	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{
@@ -348,7 +348,8 @@ func TestRouter_ClickUnraidWhenProviderMissing_RepliesServiceUnavailable(t *test
 		AllowedUserID: map[string]struct{}{
 			userID: {},
 		},
-		State: NewStateStore(1 * time.Minute),
+		State:    NewMemoryStateStore(1 * time.Minute),
+		Disabled: []DisabledService{{Key: "unraid", DisplayName: "Unraid", Hint: "unraid.endpoint 与 unraid.api_key"}},
 	})
 
 	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{
@@ -369,6 +370,18 @@ func TestRouter_ClickUnraidWhenProviderMissing_RepliesServiceUnavailable(t *test
 	if !strings.Contains(rec.texts[0].Content, "Unraid 服务未启用") {
 		t.Fatalf("reply = %q, want contains %q", rec.texts[0].Content, "Unraid 服务未启用")
 	}
+	// 未注册的命名空间不视为服务。
+	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{
+		FromUserName: userID,
+		MsgType:      "event",
+		Event:        "CLICK",
+		EventKey:     "nas.menu",
+	}); err != nil {
+		t.Fatalf("HandleMessage() error: %v", err)
+	}
+	if got := rec.texts[len(rec.texts)-1].Content; !strings.Contains(got, "未识别的菜单操作") {
+		t.Fatalf("reply = %q, want 未识别的菜单操作", got)
+	}
 }
 
 func TestRouter_EventPrefix_DispatchesToProvider(t *testing.T) {
@@ -387,7 +400,7 @@ func TestRouter_EventPrefix_DispatchesToProvider(t *testing.T) {
 		Providers: []ServiceProvider{
 			ql,
 		},
-		State: NewStateStore(1 * time.Minute),
+		State: NewMemoryStateStore(1 * time.Minute),
 	})
 
 	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{
@@ -408,7 +421,7 @@ func TestRouter_Confirm_DispatchesByStateServiceKey(t *testing.T) {
 
 	rec := &recordWeCom{}
 	userID := "u"
-	state := NewStateStore(1 * time.Minute)
+	state := NewMemoryStateStore(1 * time.Minute)
 
 	unraid := &fakeProvider{key: "unraid", name: "Unraid 容器", confirmHandled: true}
 	r := NewRouter(RouterDeps{
@@ -445,7 +458,7 @@ func TestRouter_TextConfirm_DispatchesByStateServiceKey(t *testing.T) {
 
 	rec := &recordWeCom{}
 	userID := "u"
-	state := NewStateStore(1 * time.Minute)
+	state := NewMemoryStateStore(1 * time.Minute)
 
 	unraid := &fakeProvider{key: "unraid", name: "Unraid 容器", confirmHandled: true}
 	r := NewRouter(RouterDeps{
@@ -488,7 +501,7 @@ func TestRouter_TemplateCardEvent_UpdatesButtonByResponseCode(t *testing.T) {
 			userID: {},
 		},
 		Providers: []ServiceProvider{},
-		State:     NewStateStore(1 * time.Minute),
+		State:     NewMemoryStateStore(1 * time.Minute),
 	})
 
 	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{
@@ -523,7 +536,7 @@ func TestRouter_SelfTestPing_AutoReplies(t *testing.T) {
 		AllowedUserID: map[string]struct{}{
 			userID: {},
 		},
-		State: NewStateStore(1 * time.Minute),
+		State: NewMemoryStateStore(1 * time.Minute),
 	})
 
 	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{
@@ -555,3 +568,207 @@ func TestRouter_SelfTestPing_AutoReplies(t *testing.T) {
 		t.Fatalf("template card count = %d, want 0", got)
 	}
 }
+
+type fakeAsyncProvider struct {
+	fakeProvider
+	ran chan struct{}
+}
+
+func (p *fakeAsyncProvider) PrepareConfirm(_ context.Context, _ string) (ConfirmedAction, bool, error) {
+	return ConfirmedAction{
+		Action: ActionUnraidRestart,
+		Target: "app",
+		Run: func(context.Context, ProgressFunc) (string, error) {
+			close(p.ran)
+			return "", nil
+		},
+	}, true, nil
+}
+
+func TestRouter_Confirm_WithJobs_SubmitsAsync(t *testing.T) {
+	t.Parallel()
+
+	rec := &syncRecordWeCom{}
+	userID := "u"
+	state := NewMemoryStateStore(1 * time.Minute)
+	jobs := NewJobRunner(JobRunnerDeps{WeCom: rec, Workers: 1})
+	defer jobs.Shutdown(context.Background())
+
+	p := &fakeAsyncProvider{fakeProvider: fakeProvider{key: "unraid", name: "Unraid 容器"}, ran: make(chan struct{})}
+	r := NewRouter(RouterDeps{
+		WeCom:         rec,
+		AllowedUserID: map[string]struct{}{userID: {}},
+		Providers:     []ServiceProvider{p},
+		State:         state,
+		Jobs:          jobs,
+	})
+
+	state.Set(userID, ConversationState{ServiceKey: "unraid", Step: StepAwaitingConfirm})
+	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{
+		FromUserName: userID,
+		MsgType:      "event",
+		Event:        "template_card_event",
+		EventKey:     wecom.EventKeyConfirm,
+	}); err != nil {
+		t.Fatalf("HandleMessage() error: %v", err)
+	}
+	if p.onConfirm != 0 {
+		t.Fatalf("HandleConfirm hits = %d, want 0（应走异步路径）", p.onConfirm)
+	}
+
+	select {
+	case <-p.ran:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("任务未执行")
+	}
+	rec.waitText(t, "已受理（任务 #1）")
+	rec.waitText(t, "执行成功（任务 #1")
+}
+
+type fakeSyncAsyncProvider struct {
+	fakeProvider
+}
+
+func (p *fakeSyncAsyncProvider) PrepareConfirm(_ context.Context, _ string) (ConfirmedAction, bool, error) {
+	return ConfirmedAction{
+		InstanceID: "ins",
+		Action:     ActionUnraidStop,
+		Target:     "app",
+		Run: func(context.Context, ProgressFunc) (string, error) {
+			return "停止 app", nil
+		},
+	}, true, nil
+}
+
+func (p *fakeSyncAsyncProvider) EventPermission(eventKey string) string {
+	if eventKey == wecom.EventKeyUnraidStop {
+		return "unraid.stop"
+	}
+	return ""
+}
+
+func TestRouter_Confirm_WithoutJobs_RecordsAuditAndAuditCommand(t *testing.T) {
+	t.Parallel()
+
+	rec := &recordWeCom{}
+	userID := "u"
+	state := NewMemoryStateStore(1 * time.Minute)
+	sink := &memAuditSink{}
+
+	p := &fakeSyncAsyncProvider{fakeProvider: fakeProvider{key: "unraid", name: "Unraid 容器"}}
+	r := NewRouter(RouterDeps{
+		WeCom:         rec,
+		AllowedUserID: map[string]struct{}{userID: {}},
+		Providers:     []ServiceProvider{p},
+		State:         state,
+		Audit:         sink,
+	})
+
+	state.Set(userID, ConversationState{ServiceKey: "unraid", Step: StepAwaitingConfirm})
+	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{FromUserName: userID, MsgType: "text", Content: "确认"}); err != nil {
+		t.Fatalf("HandleMessage() error: %v", err)
+	}
+	if len(rec.texts) == 0 || !strings.Contains(rec.texts[len(rec.texts)-1].Content, "执行成功") {
+		t.Fatalf("texts = %+v, want 执行成功", rec.texts)
+	}
+
+	entries := sink.snapshot()
+	if len(entries) != 1 || entries[0].ServiceKey != "unraid" || entries[0].InstanceID != "ins" || entries[0].Result != AuditResultSuccess {
+		t.Fatalf("audit entries = %+v", entries)
+	}
+
+	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{FromUserName: userID, MsgType: "text", Content: "/audit 5"}); err != nil {
+		t.Fatalf("HandleMessage(/audit) error: %v", err)
+	}
+	last := rec.texts[len(rec.texts)-1].Content
+	if !strings.Contains(last, "最近 1 条操作审计") || !strings.Contains(last, "停止 app") {
+		t.Fatalf("audit reply = %q", last)
+	}
+}
+
+func TestRouter_Viewer_DeniedConfirmAndMenuSync(t *testing.T) {
+	t.Parallel()
+
+	rec := &recordWeComMenu{}
+	state := NewMemoryStateStore(time.Minute)
+	auth, err := NewAuthorizer(AuthorizerConfig{
+		Bindings: []RoleBinding{{Role: RoleViewer, Subjects: []string{"v"}}},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+	p := &fakeSyncAsyncProvider{fakeProvider: fakeProvider{key: "unraid", name: "Unraid 容器", eventHandled: true}}
+	r := NewRouter(RouterDeps{
+		WeCom:     rec,
+		Auth:      auth,
+		Providers: []ServiceProvider{p, &fakeProvider{key: "pve", name: "PVE"}},
+		State:     state,
+	})
+
+	// 查看类事件放行。
+	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{FromUserName: "v", MsgType: "event", Event: "click", EventKey: "unraid.view.status"}); err != nil {
+		t.Fatalf("HandleMessage(view) error: %v", err)
+	}
+	if p.onEvent != 1 {
+		t.Fatalf("onEvent = %d, want 1", p.onEvent)
+	}
+
+	state.Set("v", ConversationState{ServiceKey: "unraid", Step: StepAwaitingConfirm})
+	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{FromUserName: "v", MsgType: "text", Content: "确认"}); err != nil {
+		t.Fatalf("HandleMessage(confirm) error: %v", err)
+	}
+	if got := rec.texts[len(rec.texts)-1].Content; !strings.Contains(got, "unraid.stop") {
+		t.Fatalf("confirm reply = %q, want permission denial", got)
+	}
+
+	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{FromUserName: "v", MsgType: "text", Content: "同步菜单"}); err != nil {
+		t.Fatalf("HandleMessage(sync) error: %v", err)
+	}
+	if len(rec.menus) != 0 {
+		t.Fatalf("menus = %d, want 0", len(rec.menus))
+	}
+	if got := rec.texts[len(rec.texts)-1].Content; !strings.Contains(got, PermCoreMenuSync) {
+		t.Fatalf("sync reply = %q, want permission denial", got)
+	}
+
+	if r.CanEvent("v", wecom.EventKeyUnraidStop) || !r.CanEvent("v", wecom.EventKeyConfirm) || !r.CanEvent("v", wecom.EventKeyServiceSelectPrefix+"pve") {
+		t.Fatalf("CanEvent() mismatch for viewer")
+	}
+}
+
+func TestRouter_ServiceMenu_HidesServicesWithoutView(t *testing.T) {
+	t.Parallel()
+
+	rec := &recordWeCom{}
+	auth, err := NewAuthorizer(AuthorizerConfig{
+		Roles:    map[string][]string{"family": {"unraid.view", "unraid.restart"}},
+		Bindings: []RoleBinding{{Role: "family", Subjects: []string{"mom"}}},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+	pveProvider := &fakeProvider{key: "pve", name: "PVE", keywords: []string{"pve"}}
+	r := NewRouter(RouterDeps{
+		WeCom:     rec,
+		Auth:      auth,
+		Providers: []ServiceProvider{&fakeProvider{key: "unraid", name: "Unraid 容器"}, pveProvider},
+	})
+
+	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{FromUserName: "mom", MsgType: "text", Content: "菜单"}); err != nil {
+		t.Fatalf("HandleMessage(menu) error: %v", err)
+	}
+	if len(rec.cards) != 1 {
+		t.Fatalf("cards = %d, want 1", len(rec.cards))
+	}
+	_, buttons, _ := wecom.RenderButtonInteractionTextMenu(rec.cards[0].Card)
+	if len(buttons) != 1 || buttons[0].Key != wecom.EventKeyServiceSelectPrefix+"unraid" {
+		t.Fatalf("service buttons = %+v, want unraid only", buttons)
+	}
+
+	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{FromUserName: "mom", MsgType: "text", Content: "pve"}); err != nil {
+		t.Fatalf("HandleMessage(pve) error: %v", err)
+	}
+	if pveProvider.onEnter != 0 {
+		t.Fatalf("pve onEnter = %d, want 0", pveProvider.onEnter)
+	}
+}
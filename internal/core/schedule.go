@@ -0,0 +1,536 @@
+package core
+
+// schedule.go 实现定时/延时执行：将 Provider 动作描述（ActionSpec）保存为定时任务，到点后以创建人身份重建动作、复核权限，
+// 再按与手动确认相同的路径执行（命中审批策略时转为审批单），并通过企业微信通知触发与结果。
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// ActionSpec 为可持久化的动作描述，定时任务到点后据此重建 ConfirmedAction。
+type ActionSpec struct {
+	ServiceKey string `json:"service"`
+	InstanceID string `json:"instance_id,omitempty"`
+	Action     Action `json:"action"`
+	// Target 为动作目标：Unraid 容器名、PVE VMID、青龙任务 ID。
+	Target string `json:"target"`
+	// GuestType 为 PVE 目标类型（qemu/lxc）。
+	GuestType string `json:"guest_type,omitempty"`
+}
+
+// ActionSpecProvider 为 ServiceProvider 的可选扩展：解析动作短语并按描述重建可执行动作，供定时任务使用。
+type ActionSpecProvider interface {
+	// ParseActionSpec 解析动作短语（如“重启 jellyfin”“关机 VM 101”）；ok=false 表示不属于本服务。
+	ParseActionSpec(text string) (spec ActionSpec, ok bool, err error)
+	// BuildAction 校验目标仍然存在并构造动作（创建定时任务与到点执行时各调用一次）。
+	BuildAction(ctx context.Context, spec ActionSpec) (ConfirmedAction, error)
+}
+
+// Schedule 为一条定时任务。
+type Schedule struct {
+	ID     string     `json:"id"`
+	UserID string     `json:"user_id"`
+	Spec   ActionSpec `json:"spec"`
+	// Title 为创建时的动作摘要（如“重启 jellyfin”）。
+	Title string `json:"title"`
+	// Cron 非空表示周期任务，Repeat 为其可读描述；为空表示一次性任务，执行后删除。
+	Cron      string    `json:"cron,omitempty"`
+	Repeat    string    `json:"repeat,omitempty"`
+	NextRun   time.Time `json:"next_run"`
+	LastRun   time.Time `json:"last_run"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// scheduleMissedGrace 为允许补执行的延迟：服务停机等原因错过执行时间超过该值时跳过本次。
+const scheduleMissedGrace = 10 * time.Minute
+
+type SchedulerDeps struct {
+	Store ScheduleStore
+	// Location 为解释“凌晨3点”等时间表达式与计算 cron 的时区（默认 time.Local）。
+	Location *time.Location
+	// MaxPerUser 为单个账号的定时任务上限（默认 20）。
+	MaxPerUser int
+	// Tick 为到期检查间隔（默认 1s）。
+	Tick time.Duration
+}
+
+// Scheduler 管理定时任务的存储与到期调度；由 Router 负责动作的重建与执行。
+type Scheduler struct {
+	store      ScheduleStore
+	loc        *time.Location
+	maxPerUser int
+	tick       time.Duration
+
+	mu     sync.Mutex
+	seq    int64
+	items  map[string]Schedule
+	router *Router
+
+	stopCh    chan struct{}
+	stopOnce  sync.Once
+	startOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewScheduler 创建调度器并加载已持久化的定时任务（调用 Start 后开始调度）。
+func NewScheduler(deps SchedulerDeps) (*Scheduler, error) {
+	s := &Scheduler{
+		store:      deps.Store,
+		loc:        deps.Location,
+		maxPerUser: deps.MaxPerUser,
+		tick:       deps.Tick,
+		items:      make(map[string]Schedule),
+		stopCh:     make(chan struct{}),
+	}
+	if s.store == nil {
+		s.store = NewMemoryScheduleStore()
+	}
+	if s.loc == nil {
+		s.loc = time.Local
+	}
+	if s.maxPerUser <= 0 {
+		s.maxPerUser = 20
+	}
+	if s.tick <= 0 {
+		s.tick = time.Second
+	}
+
+	list, err := s.store.List()
+	if err != nil {
+		return nil, fmt.Errorf("加载定时任务失败: %w", err)
+	}
+	for _, sc := range list {
+		s.items[sc.ID] = sc
+		if n, err := strconv.ParseInt(sc.ID, 10, 64); err == nil && n > s.seq {
+			s.seq = n
+		}
+	}
+	if len(list) > 0 {
+		slog.Info("已加载定时任务", "count", len(list))
+	}
+	return s, nil
+}
+
+// Location 返回调度使用的时区。
+func (s *Scheduler) Location() *time.Location { return s.loc }
+
+// Start 启动到期检查循环；重复调用安全。
+func (s *Scheduler) Start() {
+	s.startOnce.Do(func() {
+		s.wg.Add(1)
+		go s.loop()
+	})
+}
+
+// Close 停止调度循环（已触发的动作由 JobRunner 或各自 goroutine 继续执行）。
+func (s *Scheduler) Close() {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+	s.wg.Wait()
+}
+
+// Add 保存一条新的定时任务并分配编号。
+func (s *Scheduler) Add(sc Schedule) (Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, it := range s.items {
+		if it.UserID == sc.UserID {
+			count++
+		}
+	}
+	if count >= s.maxPerUser {
+		return Schedule{}, fmt.Errorf("定时任务已达上限（%d 条），请先取消不需要的任务", s.maxPerUser)
+	}
+
+	s.seq++
+	sc.ID = strconv.FormatInt(s.seq, 10)
+	if sc.CreatedAt.IsZero() {
+		sc.CreatedAt = time.Now()
+	}
+	if err := s.store.Save(sc); err != nil {
+		s.seq--
+		return Schedule{}, fmt.Errorf("保存定时任务失败: %w", err)
+	}
+	s.items[sc.ID] = sc
+	return sc, nil
+}
+
+// ListByUser 返回用户的定时任务（按下次执行时间排序）。
+func (s *Scheduler) ListByUser(userID string) []Schedule {
+	s.mu.Lock()
+	var out []Schedule
+	for _, sc := range s.items {
+		if sc.UserID == userID {
+			out = append(out, sc)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].NextRun.Equal(out[j].NextRun) {
+			return out[i].NextRun.Before(out[j].NextRun)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Cancel 取消用户自己的定时任务。
+func (s *Scheduler) Cancel(userID, id string) (Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, ok := s.items[id]
+	if !ok || sc.UserID != userID {
+		return Schedule{}, fmt.Errorf("定时任务 #%s 不存在", id)
+	}
+	if err := s.store.Delete(id); err != nil {
+		return Schedule{}, fmt.Errorf("删除定时任务失败: %w", err)
+	}
+	delete(s.items, id)
+	return sc, nil
+}
+
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.dispatchDue(time.Now())
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// dispatchDue 取出到期任务：一次性任务先删除、周期任务先推进到下次执行时间并落盘，再异步触发，避免重启后重复执行。
+func (s *Scheduler) dispatchDue(now time.Time) {
+	var due, missed []Schedule
+
+	s.mu.Lock()
+	for id, sc := range s.items {
+		if sc.NextRun.After(now) {
+			continue
+		}
+		run := sc
+		if sc.Cron == "" {
+			delete(s.items, id)
+			if err := s.store.Delete(id); err != nil {
+				slog.Error("定时任务删除失败", "schedule_id", id, "error", err)
+			}
+		} else {
+			var next time.Time
+			if cs, err := ParseCron(sc.Cron); err == nil {
+				next = cs.Next(now.In(s.loc))
+			}
+			if next.IsZero() {
+				slog.Warn("周期任务无后续执行时间，已删除", "schedule_id", id, "cron", sc.Cron)
+				delete(s.items, id)
+				_ = s.store.Delete(id)
+			} else {
+				sc.NextRun = next
+				sc.LastRun = now
+				s.items[id] = sc
+				if err := s.store.Save(sc); err != nil {
+					slog.Error("定时任务保存失败", "schedule_id", id, "error", err)
+				}
+			}
+		}
+
+		if now.Sub(run.NextRun) > scheduleMissedGrace {
+			missed = append(missed, run)
+		} else {
+			due = append(due, run)
+		}
+	}
+	router := s.router
+	s.mu.Unlock()
+
+	if router == nil {
+		return
+	}
+	for _, sc := range missed {
+		router.notifyMissedSchedule(sc)
+	}
+	for _, sc := range due {
+		go router.runSchedule(sc)
+	}
+}
+
+// runSchedule 在到点时重建动作并以创建人身份执行：目标不存在或权限已收回时不执行，仅通知。
+func (r *Router) runSchedule(sc Schedule) {
+	ctx := context.Background()
+	slog.Info("定时任务触发",
+		"schedule_id", sc.ID,
+		"user_id", sc.UserID,
+		"service", sc.Spec.ServiceKey,
+		"action", string(sc.Spec.Action),
+		"target", sc.Spec.Target,
+	)
+
+	fail := func(reason string) {
+		slog.Warn("定时任务未执行", "schedule_id", sc.ID, "user_id", sc.UserID, "reason", reason)
+		recordAudit(ctx, r.audit, AuditEntry{
+			UserID:     sc.UserID,
+			ServiceKey: sc.Spec.ServiceKey,
+			InstanceID: sc.Spec.InstanceID,
+			Action:     sc.Spec.Action,
+			Target:     sc.Spec.Target,
+			Result:     AuditResultFailed,
+			Error:      reason,
+		})
+		_ = r.WeCom.SendText(ctx, wecom.TextMessage{
+			ToUser:  sc.UserID,
+			Content: fmt.Sprintf("定时任务 #%s（%s）未执行：%s", sc.ID, sc.Title, reason),
+		})
+	}
+
+	if !r.auth.Allowed(sc.UserID) {
+		fail("账号已不在授权名单内")
+		return
+	}
+	p, ok := r.providers[sc.Spec.ServiceKey].(ActionSpecProvider)
+	if !ok {
+		fail("服务 " + sc.Spec.ServiceKey + " 未启用或不支持定时执行")
+		return
+	}
+	buildCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	action, err := p.BuildAction(buildCtx, sc.Spec)
+	cancel()
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+	if perm := r.deniedPermission(sc.UserID, action); perm != "" {
+		fail("当前账号已无权限 " + perm)
+		return
+	}
+
+	_ = r.WeCom.SendText(ctx, wecom.TextMessage{
+		ToUser:  sc.UserID,
+		Content: fmt.Sprintf("定时任务 #%s 触发：%s", sc.ID, action.Title()),
+	})
+	if r.requiresApproval(action) {
+		_ = r.requestApproval(ctx, sc.UserID, action, nil)
+		return
+	}
+	_ = r.executeConfirmed(ctx, sc.UserID, action, nil)
+}
+
+func (r *Router) notifyMissedSchedule(sc Schedule) {
+	slog.Warn("定时任务错过执行时间，已跳过", "schedule_id", sc.ID, "user_id", sc.UserID, "next_run", sc.NextRun)
+	msg := fmt.Sprintf("定时任务 #%s（%s）错过执行时间 %s（服务未运行），已跳过。", sc.ID, sc.Title, sc.NextRun.Format("01-02 15:04"))
+	if sc.Cron != "" {
+		msg = fmt.Sprintf("定时任务 #%s（%s）错过执行时间 %s（服务未运行），本次已跳过，后续按周期执行。", sc.ID, sc.Title, sc.NextRun.Format("01-02 15:04"))
+	}
+	_ = r.WeCom.SendText(context.Background(), wecom.TextMessage{ToUser: sc.UserID, Content: msg})
+}
+
+// handleScheduleText 识别“重启 jellyfin 凌晨3点”“30分钟后关机 VM 101”等定时指令并创建定时任务。
+// 仅当文本含时间表达式且动作短语能被某个 Provider 解析时视为定时指令（以“定时”开头时总是处理），否则交由后续流程；
+// 用户处于 Provider 的输入步骤时不做隐式识别，文本按步骤输入处理。
+func (r *Router) handleScheduleText(ctx context.Context, userID, content string) (bool, error) {
+	explicit := false
+	if kw := normalizeCommandKeyword(content); kw == "定时" || kw == "schedule" {
+		explicit = true
+		content = strings.TrimSpace(strings.TrimPrefix(content, strings.Fields(content)[0]))
+	}
+	if !explicit {
+		if st, ok := r.state.Get(userID); ok && st.Step != "" {
+			return false, nil
+		}
+	}
+	if r.scheduler == nil {
+		if explicit {
+			return true, r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未启用定时任务。"})
+		}
+		return false, nil
+	}
+
+	now := time.Now().In(r.scheduler.Location())
+	when, rest, ok, parseErr := ParseScheduleText(content, now)
+	if !ok {
+		if explicit {
+			return true, r.sendScheduleUsage(ctx, userID, "未识别执行时间。")
+		}
+		return false, nil
+	}
+
+	spec, p, err := r.resolveActionSpec(rest)
+	if err != nil {
+		return true, r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "无法创建定时任务：" + err.Error()})
+	}
+	if p == nil {
+		if explicit {
+			return true, r.sendScheduleUsage(ctx, userID, "未识别要执行的操作："+rest)
+		}
+		return false, nil
+	}
+	if parseErr != nil {
+		return true, r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "无法创建定时任务：" + parseErr.Error()})
+	}
+
+	action, err := p.BuildAction(ctx, spec)
+	if err != nil {
+		return true, r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "无法创建定时任务：" + err.Error()})
+	}
+	if strings.TrimSpace(action.ServiceKey) == "" {
+		action.ServiceKey = spec.ServiceKey
+	}
+	if perm := r.deniedPermission(userID, action); perm != "" {
+		return true, r.sendForbidden(ctx, userID, perm)
+	}
+	// 固化实例：单实例时解析结果可能未指明实例，避免日后新增实例导致目标漂移。
+	spec.InstanceID = action.InstanceID
+
+	sc, err := r.scheduler.Add(Schedule{
+		UserID:  userID,
+		Spec:    spec,
+		Title:   action.Title(),
+		Cron:    when.Cron,
+		Repeat:  when.Repeat,
+		NextRun: when.At,
+	})
+	if err != nil {
+		return true, r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "无法创建定时任务：" + err.Error()})
+	}
+	slog.Info("定时任务已创建",
+		"schedule_id", sc.ID,
+		"user_id", userID,
+		"service", spec.ServiceKey,
+		"action", string(spec.Action),
+		"target", spec.Target,
+		"next_run", sc.NextRun,
+		"cron", sc.Cron,
+	)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "已创建定时任务 #%s：%s", sc.ID, sc.Title)
+	fmt.Fprintf(&b, "\n执行时间：%s", r.formatScheduleTime(sc.NextRun))
+	if sc.Repeat != "" {
+		fmt.Fprintf(&b, "（%s）", sc.Repeat)
+	}
+	if r.requiresApproval(action) {
+		b.WriteString("\n提示：该操作需双人审批，到点后将发起审批。")
+	}
+	fmt.Fprintf(&b, "\n发送“取消定时 %s”可取消。", sc.ID)
+	return true, r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: b.String()})
+}
+
+// resolveActionSpec 依次交由各 Provider 解析动作短语；多个 Provider 同时认领时视为歧义。
+func (r *Router) resolveActionSpec(text string) (ActionSpec, ActionSpecProvider, error) {
+	var (
+		found    ActionSpec
+		provider ActionSpecProvider
+		names    []string
+	)
+	for _, sp := range r.providerList {
+		p, ok := sp.(ActionSpecProvider)
+		if !ok {
+			continue
+		}
+		spec, ok, err := p.ParseActionSpec(text)
+		if err != nil {
+			return ActionSpec{}, nil, err
+		}
+		if !ok {
+			continue
+		}
+		if strings.TrimSpace(spec.ServiceKey) == "" {
+			spec.ServiceKey = sp.Key()
+		}
+		names = append(names, sp.DisplayName())
+		if provider == nil {
+			found, provider = spec, p
+		}
+	}
+	if len(names) > 1 {
+		return ActionSpec{}, nil, fmt.Errorf("“%s”可匹配多个服务（%s），请写明目标类型，例如“重启 容器 jellyfin”或“重启 VM 101”", text, strings.Join(names, "、"))
+	}
+	return found, provider, nil
+}
+
+func (r *Router) sendSchedules(ctx context.Context, userID string) error {
+	if r.scheduler == nil {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未启用定时任务。"})
+	}
+	list := r.scheduler.ListByUser(userID)
+	if len(list) == 0 {
+		return r.sendScheduleUsage(ctx, userID, "暂无定时任务。")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "定时任务（%d）：", len(list))
+	for _, sc := range list {
+		fmt.Fprintf(&b, "\n#%s %s", sc.ID, sc.Title)
+		fmt.Fprintf(&b, "\n  下次：%s", r.formatScheduleTime(sc.NextRun))
+		if sc.Repeat != "" {
+			fmt.Fprintf(&b, " | %s", sc.Repeat)
+		} else {
+			b.WriteString(" | 一次性")
+		}
+	}
+	b.WriteString("\n\n发送“取消定时 <编号>”可取消。")
+	return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: b.String()})
+}
+
+func (r *Router) cancelSchedule(ctx context.Context, userID, content string) error {
+	if r.scheduler == nil {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未启用定时任务。"})
+	}
+	fields := strings.Fields(content)
+	if len(fields) < 2 {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "用法：取消定时 <编号>（发送“定时任务”查看编号）"})
+	}
+	id := strings.TrimPrefix(fields[1], "#")
+	sc, err := r.scheduler.Cancel(userID, id)
+	if err != nil {
+		return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error() + "，发送“定时任务”查看列表。"})
+	}
+	slog.Info("定时任务已取消", "schedule_id", sc.ID, "user_id", userID)
+	return r.WeCom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: fmt.Sprintf("已取消定时任务 #%s：%s", sc.ID, sc.Title),
+	})
+}
+
+func (r *Router) sendScheduleUsage(ctx context.Context, userID, prefix string) error {
+	msg := prefix + "\n用法：在操作后附上时间即可创建定时任务，例如：" +
+		"\n- 重启 jellyfin 凌晨3点" +
+		"\n- 30分钟后关机 VM 101" +
+		"\n- 每天 8:30 运行青龙任务 12" +
+		"\n- cron 0 3 * * 1 重启 容器 jellyfin"
+	return r.WeCom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: msg})
+}
+
+func (r *Router) formatScheduleTime(t time.Time) string {
+	return t.In(r.scheduler.Location()).Format("2006-01-02 15:04:05")
+}
+
+func isScheduleListKeyword(normalized string) bool {
+	switch normalized {
+	case "定时任务", "定时列表", "schedules":
+		return true
+	default:
+		return false
+	}
+}
+
+func isScheduleCancelKeyword(normalized string) bool {
+	switch normalized {
+	case "取消定时", "unschedule":
+		return true
+	default:
+		return false
+	}
+}
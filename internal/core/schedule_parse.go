@@ -0,0 +1,224 @@
+package core
+
+// schedule_parse.go 从聊天文本中识别定时/延时表达式（“凌晨3点”“30分钟后”“每天 8:00”“cron 0 3 * * *”），并剥离出动作短语。
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScheduleWhen 为解析出的执行时间：Cron 为空表示一次性任务；周期任务的 At 为首次执行时间。
+type ScheduleWhen struct {
+	At   time.Time
+	Cron string
+	// Repeat 为周期的可读描述（如“每天 03:00”）。
+	Repeat string
+}
+
+const scheduleTimeOfDay = `(凌晨|早上|早晨|上午|中午|下午|傍晚|晚上|今晚|夜里)?\s*(\d{1,2})\s*(?:[点时]\s*(?:(半)|(\d{1,2})\s*分?)?|[:：]\s*(\d{2}))`
+
+var (
+	scheduleCronPattern    = regexp.MustCompile(`(?i)^cron\s+(\S+\s+\S+\s+\S+\s+\S+\s+\S+)\s+`)
+	scheduleDelayPattern   = regexp.MustCompile(`(\d+)\s*(秒钟?|分钟|分|个?小时|个?钟头|天)\s*(?:之后|以后|后)`)
+	scheduleDelayENPattern = regexp.MustCompile(`(?i)\bin\s+(\d+)\s*(s|secs?|seconds?|m|mins?|minutes?|h|hrs?|hours?|d|days?)\b`)
+	scheduleRepeatPattern  = regexp.MustCompile(`(每天|每日|每个?工作日|每周[一二三四五六日天1-7])\s*` + scheduleTimeOfDay)
+	scheduleHourlyPattern  = regexp.MustCompile(`每个?小时`)
+	scheduleAtPattern      = regexp.MustCompile(`(今天|明天|后天)?\s*` + scheduleTimeOfDay)
+)
+
+// maxScheduleDelay 为延时/一次性任务允许的最远时间。
+const maxScheduleDelay = 366 * 24 * time.Hour
+
+var errScheduleNoAction = errors.New("缺少要执行的操作（示例：重启 jellyfin 凌晨3点、30分钟后关机 VM 101）")
+
+// ParseScheduleText 识别 text 中的时间表达式（时间按 now 所在时区解释）。
+// ok=false 表示不含时间表达式；ok=true 时 rest 为剥离时间后的动作短语，err 非空表示时间不合法或缺少动作。
+func ParseScheduleText(text string, now time.Time) (when ScheduleWhen, rest string, ok bool, err error) {
+	text = strings.TrimSpace(text)
+
+	if m := scheduleCronPattern.FindStringSubmatchIndex(text); m != nil {
+		expr := text[m[2]:m[3]]
+		rest = cleanScheduleRest(text[m[1]:])
+		cs, err := ParseCron(expr)
+		if err != nil {
+			return ScheduleWhen{}, rest, true, err
+		}
+		when = ScheduleWhen{Cron: cs.String(), Repeat: "cron " + cs.String()}
+		return finishRepeat(when, cs, rest, now)
+	}
+
+	for _, p := range []*regexp.Regexp{scheduleDelayPattern, scheduleDelayENPattern} {
+		m := p.FindStringSubmatchIndex(text)
+		if m == nil {
+			continue
+		}
+		rest = cleanScheduleRest(text[:m[0]] + " " + text[m[1]:])
+		n, _ := strconv.Atoi(text[m[2]:m[3]])
+		d := time.Duration(n) * scheduleDelayUnit(strings.ToLower(text[m[4]:m[5]]))
+		switch {
+		case n <= 0:
+			return ScheduleWhen{}, rest, true, errors.New("延时需大于 0")
+		case d > maxScheduleDelay:
+			return ScheduleWhen{}, rest, true, errors.New("延时过长（最长 366 天）")
+		case rest == "":
+			return ScheduleWhen{}, rest, true, errScheduleNoAction
+		}
+		return ScheduleWhen{At: now.Add(d)}, rest, true, nil
+	}
+
+	if m := scheduleRepeatPattern.FindStringSubmatchIndex(text); m != nil {
+		rest = cleanScheduleRest(text[:m[0]] + " " + text[m[1]:])
+		hour, minute, err := scheduleClock(submatch(text, m, 2), submatch(text, m, 3), submatch(text, m, 4), submatch(text, m, 5), submatch(text, m, 6))
+		if err != nil {
+			return ScheduleWhen{}, rest, true, err
+		}
+		kind := text[m[2]:m[3]]
+		dow, label := "*", "每天"
+		switch {
+		case strings.Contains(kind, "工作日"):
+			dow, label = "1-5", "工作日"
+		case strings.HasPrefix(kind, "每周"):
+			d := scheduleWeekday(strings.TrimPrefix(kind, "每周"))
+			dow, label = strconv.Itoa(d), kind
+		}
+		expr := fmt.Sprintf("%d %d * * %s", minute, hour, dow)
+		cs, err := ParseCron(expr)
+		if err != nil {
+			return ScheduleWhen{}, rest, true, err
+		}
+		when = ScheduleWhen{Cron: expr, Repeat: fmt.Sprintf("%s %02d:%02d", label, hour, minute)}
+		return finishRepeat(when, cs, rest, now)
+	}
+
+	if m := scheduleHourlyPattern.FindStringIndex(text); m != nil {
+		rest = cleanScheduleRest(text[:m[0]] + " " + text[m[1]:])
+		cs, _ := ParseCron("0 * * * *")
+		return finishRepeat(ScheduleWhen{Cron: cs.String(), Repeat: "每小时整点"}, cs, rest, now)
+	}
+
+	if m := scheduleAtPattern.FindStringSubmatchIndex(text); m != nil {
+		rest = cleanScheduleRest(text[:m[0]] + " " + text[m[1]:])
+		day := submatch(text, m, 1)
+		period := submatch(text, m, 2)
+		hour, minute, err := scheduleClock(period, submatch(text, m, 3), submatch(text, m, 4), submatch(text, m, 5), submatch(text, m, 6))
+		if err != nil {
+			return ScheduleWhen{}, rest, true, err
+		}
+		at := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+		switch day {
+		case "明天":
+			at = at.AddDate(0, 0, 1)
+		case "后天":
+			at = at.AddDate(0, 0, 2)
+		case "今天":
+			if !at.After(now) {
+				return ScheduleWhen{}, rest, true, fmt.Errorf("时间 %02d:%02d 已过", hour, minute)
+			}
+		default:
+			if period == "今晚" && !at.After(now) {
+				return ScheduleWhen{}, rest, true, fmt.Errorf("时间 %02d:%02d 已过", hour, minute)
+			}
+			// 未指明日期且今天已过：顺延到明天。
+			if !at.After(now) {
+				at = at.AddDate(0, 0, 1)
+			}
+		}
+		if rest == "" {
+			return ScheduleWhen{}, rest, true, errScheduleNoAction
+		}
+		return ScheduleWhen{At: at}, rest, true, nil
+	}
+
+	return ScheduleWhen{}, text, false, nil
+}
+
+func finishRepeat(when ScheduleWhen, cs *CronSchedule, rest string, now time.Time) (ScheduleWhen, string, bool, error) {
+	if rest == "" {
+		return ScheduleWhen{}, rest, true, errScheduleNoAction
+	}
+	when.At = cs.Next(now)
+	if when.At.IsZero() {
+		return ScheduleWhen{}, rest, true, fmt.Errorf("cron 表达式 %q 没有可执行的时间", when.Cron)
+	}
+	return when, rest, true, nil
+}
+
+// scheduleClock 将“下午3点半”“晚上10点”“15:30”等换算为 24 小时制。
+func scheduleClock(period, hourStr, half, minStr, colonMin string) (int, int, error) {
+	hour, _ := strconv.Atoi(hourStr)
+	minute := 0
+	switch {
+	case half != "":
+		minute = 30
+	case minStr != "":
+		minute, _ = strconv.Atoi(minStr)
+	case colonMin != "":
+		minute, _ = strconv.Atoi(colonMin)
+	}
+
+	switch period {
+	case "下午", "傍晚", "晚上", "今晚", "夜里":
+		if hour < 12 {
+			hour += 12
+		}
+	case "中午":
+		if hour < 6 {
+			hour += 12
+		}
+	case "凌晨":
+		if hour == 12 {
+			hour = 0
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, 0, fmt.Errorf("时间不合法：%s%s点%d分", period, hourStr, minute)
+	}
+	return hour, minute, nil
+}
+
+func scheduleDelayUnit(unit string) time.Duration {
+	switch {
+	case strings.HasPrefix(unit, "秒"), strings.HasPrefix(unit, "s"):
+		return time.Second
+	case strings.HasPrefix(unit, "分"), strings.HasPrefix(unit, "m"):
+		return time.Minute
+	case strings.HasSuffix(unit, "小时"), strings.HasSuffix(unit, "钟头"), strings.HasPrefix(unit, "h"):
+		return time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+func scheduleWeekday(s string) int {
+	switch s {
+	case "一", "1":
+		return 1
+	case "二", "2":
+		return 2
+	case "三", "3":
+		return 3
+	case "四", "4":
+		return 4
+	case "五", "5":
+		return 5
+	case "六", "6":
+		return 6
+	default:
+		return 0
+	}
+}
+
+func submatch(s string, m []int, i int) string {
+	if 2*i+1 >= len(m) || m[2*i] < 0 {
+		return ""
+	}
+	return s[m[2*i]:m[2*i+1]]
+}
+
+func cleanScheduleRest(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.Trim(s, " ，,。")
+}
@@ -0,0 +1,88 @@
+package core
+
+// schedule_store.go 提供定时任务的存储实现：内存（重启丢失）与基于 store.FileStore 的文件持久化。
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/store"
+)
+
+// ScheduleStore 为定时任务持久化抽象；实现需并发安全。
+type ScheduleStore interface {
+	List() ([]Schedule, error)
+	Save(s Schedule) error
+	Delete(id string) error
+}
+
+// MemoryScheduleStore 将定时任务保存在内存中，服务重启后丢失。
+type MemoryScheduleStore struct {
+	mu    sync.Mutex
+	items map[string]Schedule
+}
+
+func NewMemoryScheduleStore() *MemoryScheduleStore {
+	return &MemoryScheduleStore{items: make(map[string]Schedule)}
+}
+
+func (s *MemoryScheduleStore) List() ([]Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Schedule, 0, len(s.items))
+	for _, sc := range s.items {
+		out = append(out, sc)
+	}
+	return out, nil
+}
+
+func (s *MemoryScheduleStore) Save(sc Schedule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[sc.ID] = sc
+	return nil
+}
+
+func (s *MemoryScheduleStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, id)
+	return nil
+}
+
+const scheduleBucket = "schedule"
+
+// FileScheduleStore 将定时任务写入共享的持久化存储（bucket=schedule），不设过期时间。
+type FileScheduleStore struct {
+	kv *store.FileStore
+}
+
+func NewFileScheduleStore(kv *store.FileStore) *FileScheduleStore {
+	return &FileScheduleStore{kv: kv}
+}
+
+func (s *FileScheduleStore) List() ([]Schedule, error) {
+	raw := s.kv.List(scheduleBucket)
+	out := make([]Schedule, 0, len(raw))
+	for id, v := range raw {
+		var sc Schedule
+		if err := json.Unmarshal(v, &sc); err != nil {
+			return nil, fmt.Errorf("定时任务 %s 解析失败: %w", id, err)
+		}
+		out = append(out, sc)
+	}
+	return out, nil
+}
+
+func (s *FileScheduleStore) Save(sc Schedule) error {
+	raw, err := json.Marshal(sc)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(scheduleBucket, sc.ID, raw, time.Time{})
+}
+
+func (s *FileScheduleStore) Delete(id string) error {
+	return s.kv.Delete(scheduleBucket, id)
+}
@@ -0,0 +1,301 @@
+// 定时任务单元测试。
+package core
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/store"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+func TestParseScheduleText(t *testing.T) {
+	t.Parallel()
+
+	loc := time.FixedZone("CST", 8*3600)
+	now := time.Date(2026, 10, 16, 14, 20, 0, 0, loc) // 周五
+
+	tests := []struct {
+		in     string
+		at     time.Time
+		cron   string
+		rest   string
+		ok     bool
+		hasErr bool
+	}{
+		{in: "重启 jellyfin 凌晨3点", at: time.Date(2026, 10, 17, 3, 0, 0, 0, loc), rest: "重启 jellyfin", ok: true},
+		{in: "30分钟后关机 VM 101", at: now.Add(30 * time.Minute), rest: "关机 VM 101", ok: true},
+		{in: "关机 VM 101 in 2h", at: now.Add(2 * time.Hour), rest: "关机 VM 101", ok: true},
+		{in: "下午3点半 重启 jellyfin", at: time.Date(2026, 10, 16, 15, 30, 0, 0, loc), rest: "重启 jellyfin", ok: true},
+		{in: "明天 08:05 运行青龙任务 12", at: time.Date(2026, 10, 17, 8, 5, 0, 0, loc), rest: "运行青龙任务 12", ok: true},
+		{in: "每天 8:30 运行青龙任务 12", at: time.Date(2026, 10, 17, 8, 30, 0, 0, loc), cron: "30 8 * * *", rest: "运行青龙任务 12", ok: true},
+		{in: "每周一凌晨4点 重启 plex", at: time.Date(2026, 10, 19, 4, 0, 0, 0, loc), cron: "0 4 * * 1", rest: "重启 plex", ok: true},
+		{in: "cron */15 * * * * 重启 plex", at: time.Date(2026, 10, 16, 14, 30, 0, 0, loc), cron: "*/15 * * * *", rest: "重启 plex", ok: true},
+		{in: "今天 9点 重启 plex", ok: true, hasErr: true},
+		{in: "25点 重启 plex", ok: true, hasErr: true},
+		{in: "10分钟后", ok: true, hasErr: true},
+		{in: "重启 VM 101", rest: "重启 VM 101"},
+	}
+	for _, tc := range tests {
+		when, rest, ok, err := ParseScheduleText(tc.in, now)
+		if ok != tc.ok || (err != nil) != tc.hasErr {
+			t.Fatalf("ParseScheduleText(%q) ok=%v err=%v; want ok=%v hasErr=%v", tc.in, ok, err, tc.ok, tc.hasErr)
+		}
+		if tc.hasErr {
+			continue
+		}
+		if rest != tc.rest || !when.At.Equal(tc.at) || when.Cron != tc.cron {
+			t.Fatalf("ParseScheduleText(%q) = %v %q rest=%q; want %v %q rest=%q", tc.in, when.At, when.Cron, rest, tc.at, tc.cron, tc.rest)
+		}
+	}
+}
+
+func TestCronSchedule_Next(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2026, 10, 16, 14, 20, 30, 0, time.UTC) // 周五
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 3 * * *", time.Date(2026, 10, 17, 3, 0, 0, 0, time.UTC)},
+		{"*/15 9-18 * * 1-5", time.Date(2026, 10, 16, 14, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)},
+		{"0 9 13 * 1", time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		cs, err := ParseCron(tc.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) error: %v", tc.expr, err)
+		}
+		if got := cs.Next(from); !got.Equal(tc.want) {
+			t.Fatalf("Next(%q) = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := ParseCron(bad); err == nil {
+			t.Fatalf("ParseCron(%q) error = nil, want not nil", bad)
+		}
+	}
+}
+
+// specProvider 为支持定时执行的测试 Provider：解析“重启 <容器>”，目标 gone 视为已不存在。
+type specProvider struct {
+	fakeProvider
+	runs atomic.Int32
+}
+
+func (p *specProvider) ParseActionSpec(text string) (ActionSpec, bool, error) {
+	name, ok := strings.CutPrefix(text, "重启 ")
+	if !ok {
+		return ActionSpec{}, false, nil
+	}
+	return ActionSpec{ServiceKey: p.key, Action: ActionUnraidRestart, Target: strings.TrimSpace(name)}, true, nil
+}
+
+func (p *specProvider) BuildAction(_ context.Context, spec ActionSpec) (ConfirmedAction, error) {
+	if spec.Target == "gone" {
+		return ConfirmedAction{}, errors.New("未找到容器：gone")
+	}
+	return ConfirmedAction{
+		ServiceKey: p.key,
+		Action:     spec.Action,
+		Target:     spec.Target,
+		Resource:   Resource{Container: spec.Target},
+		Run: func(context.Context, ProgressFunc) (string, error) {
+			p.runs.Add(1)
+			return "重启 " + spec.Target, nil
+		},
+	}, nil
+}
+
+func newScheduleTestRouter(t *testing.T, schedStore ScheduleStore, approval ApprovalPolicy) (*Router, *Scheduler, *specProvider, *approvalRecorder) {
+	t.Helper()
+	auth, err := NewAuthorizer(AuthorizerConfig{
+		Bindings: []RoleBinding{
+			{Role: RoleOperator, Subjects: []string{"alice", "bob"}},
+			{Role: RoleViewer, Subjects: []string{"guest"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+	sched, err := NewScheduler(SchedulerDeps{Store: schedStore, Tick: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewScheduler() error: %v", err)
+	}
+	t.Cleanup(sched.Close)
+
+	p := &specProvider{fakeProvider: fakeProvider{key: "unraid", name: "Unraid 容器"}}
+	rec := &approvalRecorder{}
+	state := NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	r := NewRouter(RouterDeps{
+		WeCom:     rec,
+		Auth:      auth,
+		Providers: []ServiceProvider{p},
+		State:     state,
+		Approval:  approval,
+		Scheduler: sched,
+	})
+	return r, sched, p, rec
+}
+
+func sendText(t *testing.T, r *Router, userID, content string) {
+	t.Helper()
+	if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{FromUserName: userID, MsgType: "text", Content: content}); err != nil {
+		t.Fatalf("HandleMessage(%q) error: %v", content, err)
+	}
+}
+
+func TestRouter_Schedule_CreateListCancel(t *testing.T) {
+	t.Parallel()
+
+	r, sched, p, rec := newScheduleTestRouter(t, nil, ApprovalPolicy{})
+
+	sendText(t, r, "alice", "30分钟后重启 jellyfin")
+	rec.waitText(t, "alice", "已创建定时任务 #1：重启 jellyfin")
+	sendText(t, r, "alice", "每天 8:30 重启 plex")
+	rec.waitText(t, "alice", "已创建定时任务 #2：重启 plex")
+
+	sendText(t, r, "alice", "定时任务")
+	rec.waitText(t, "alice", "定时任务（2）")
+	rec.waitText(t, "alice", "每天 08:30")
+
+	// 他人不可见、不可取消。
+	sendText(t, r, "bob", "取消定时 1")
+	rec.waitText(t, "bob", "定时任务 #1 不存在")
+	sendText(t, r, "alice", "取消定时 #1")
+	rec.waitText(t, "alice", "已取消定时任务 #1：重启 jellyfin")
+	if got := len(sched.ListByUser("alice")); got != 1 {
+		t.Fatalf("ListByUser(alice) = %d, want 1", got)
+	}
+
+	// 查看者无执行权限，不能创建。
+	sendText(t, r, "guest", "凌晨3点 重启 jellyfin")
+	rec.waitText(t, "guest", "unraid.restart")
+	// 目标不存在时拒绝创建。
+	sendText(t, r, "alice", "10分钟后 重启 gone")
+	rec.waitText(t, "alice", "无法创建定时任务：未找到容器：gone")
+	// 无法识别动作的文本交由后续流程处理。
+	sendText(t, r, "alice", "明天 9点 开会")
+	rec.waitText(t, "alice", "请输入“菜单”")
+
+	// 处于输入步骤时含时间的文本交给 Provider，显式“定时”前缀仍创建任务。
+	p.textHandled = true
+	r.state.Set("alice", ConversationState{ServiceKey: "unraid", Step: StepAwaitingContainerName})
+	sendText(t, r, "alice", "30分钟后重启 sonarr")
+	if p.onText != 1 || len(sched.ListByUser("alice")) != 1 {
+		t.Fatalf("step input: onText = %d, schedules = %d, want 1, 1", p.onText, len(sched.ListByUser("alice")))
+	}
+	sendText(t, r, "alice", "定时 30分钟后重启 sonarr")
+	rec.waitText(t, "alice", "已创建定时任务 #3：重启 sonarr")
+
+	if got := p.runs.Load(); got != 0 {
+		t.Fatalf("runs = %d, want 0", got)
+	}
+}
+
+func TestScheduler_FiresAsOwnerAndRechecksPermission(t *testing.T) {
+	t.Parallel()
+
+	r, sched, p, rec := newScheduleTestRouter(t, nil, ApprovalPolicy{})
+	sched.Start()
+
+	spec := ActionSpec{ServiceKey: "unraid", Action: ActionUnraidRestart, Target: "jellyfin"}
+	if _, err := sched.Add(Schedule{UserID: "alice", Spec: spec, Title: "重启 jellyfin", NextRun: time.Now()}); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+	rec.waitText(t, "alice", "定时任务 #1 触发：重启 jellyfin")
+	rec.waitText(t, "alice", "执行成功")
+	if got := p.runs.Load(); got != 1 {
+		t.Fatalf("runs = %d, want 1", got)
+	}
+	if got := len(sched.ListByUser("alice")); got != 0 {
+		t.Fatalf("one-shot schedule still listed: %d", got)
+	}
+
+	// 权限被收回后到点不执行。
+	r.auth, _ = NewAuthorizer(AuthorizerConfig{Bindings: []RoleBinding{{Role: RoleViewer, Subjects: []string{"alice"}}}})
+	if _, err := sched.Add(Schedule{UserID: "alice", Spec: spec, Title: "重启 jellyfin", NextRun: time.Now()}); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+	rec.waitText(t, "alice", "定时任务 #2（重启 jellyfin）未执行：当前账号已无权限 unraid.restart")
+	if got := p.runs.Load(); got != 1 {
+		t.Fatalf("runs = %d, want 1", got)
+	}
+}
+
+func TestScheduler_FireRequestsApproval(t *testing.T) {
+	t.Parallel()
+
+	_, sched, p, rec := newScheduleTestRouter(t, nil, ApprovalPolicy{Actions: []string{"unraid.restart"}})
+	sched.Start()
+
+	spec := ActionSpec{ServiceKey: "unraid", Action: ActionUnraidRestart, Target: "jellyfin"}
+	if _, err := sched.Add(Schedule{UserID: "alice", Spec: spec, Title: "重启 jellyfin", NextRun: time.Now()}); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+	rec.waitText(t, "alice", "已提交审批（#1）")
+	if got := p.runs.Load(); got != 0 {
+		t.Fatalf("runs = %d, want 0 before approval", got)
+	}
+}
+
+func TestScheduler_PersistsAndSkipsMissed(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+	kv, err := store.Open(path)
+	if err != nil {
+		t.Fatalf("store.Open() error: %v", err)
+	}
+	first, err := NewScheduler(SchedulerDeps{Store: NewFileScheduleStore(kv)})
+	if err != nil {
+		t.Fatalf("NewScheduler() error: %v", err)
+	}
+	spec := ActionSpec{ServiceKey: "unraid", Action: ActionUnraidRestart, Target: "jellyfin"}
+	missed, _ := first.Add(Schedule{UserID: "alice", Spec: spec, Title: "重启 jellyfin", NextRun: time.Now().Add(-time.Hour)})
+	daily, _ := first.Add(Schedule{UserID: "alice", Spec: spec, Title: "重启 jellyfin", Cron: "0 3 * * *", Repeat: "每天 03:00", NextRun: time.Now().Add(-time.Hour)})
+	if err := kv.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	kv, err = store.Open(path)
+	if err != nil {
+		t.Fatalf("store.Open(reopen) error: %v", err)
+	}
+	t.Cleanup(func() { _ = kv.Close() })
+	_, sched, p, rec := newScheduleTestRouter(t, NewFileScheduleStore(kv), ApprovalPolicy{})
+
+	if got := len(sched.ListByUser("alice")); got != 2 {
+		t.Fatalf("reloaded schedules = %d, want 2", got)
+	}
+	sched.dispatchDue(time.Now())
+	rec.waitText(t, "alice", "定时任务 #"+missed.ID+"（重启 jellyfin）错过执行时间")
+	rec.waitText(t, "alice", "本次已跳过，后续按周期执行")
+	if got := p.runs.Load(); got != 0 {
+		t.Fatalf("runs = %d, want 0 for missed schedules", got)
+	}
+
+	list := sched.ListByUser("alice")
+	if len(list) != 1 || list[0].ID != daily.ID || !list[0].NextRun.After(time.Now()) {
+		t.Fatalf("after dispatch = %+v, want only daily with future next run", list)
+	}
+	if persisted, err := NewFileScheduleStore(kv).List(); err != nil || len(persisted) != 1 {
+		t.Fatalf("persisted schedules = %d err=%v, want 1", len(persisted), err)
+	}
+
+	next, err := sched.Add(Schedule{UserID: "alice", Spec: spec, Title: "重启 jellyfin", NextRun: time.Now().Add(time.Hour)})
+	if err != nil || next.ID != "3" {
+		t.Fatalf("Add() after reload = %q err=%v, want id 3", next.ID, err)
+	}
+}
@@ -23,6 +23,8 @@ const (
 	StepAwaitingUnraidViewAction Step = "awaiting_unraid_view_action"
 	// StepAwaitingUnraidSystemAction 表示处于 Unraid “系统监控”菜单选择阶段（文本模式）。
 	StepAwaitingUnraidSystemAction Step = "awaiting_unraid_system_action"
+	// StepAwaitingUnraidLogKeyword 表示等待输入 Unraid 系统日志的检索条件（目标为会话中的当前日志文件）。
+	StepAwaitingUnraidLogKeyword Step = "awaiting_unraid_log_keyword"
 )
 
 type Action string
@@ -36,6 +38,26 @@ const (
 	ActionUnraidViewSystemStats       Action = "view_system_stats"
 	ActionUnraidViewSystemStatsDetail Action = "view_system_stats_detail"
 	ActionUnraidViewLogs              Action = "view_logs"
+	ActionUnraidViewArray             Action = "view_array"
+	// ActionUnraidFollowLogs 为“跟踪日志”：在选定时长内持续推送容器日志新增行（只读，沿用 unraid.view）。
+	ActionUnraidFollowLogs Action = "follow_logs"
+
+	// Unraid 虚拟机电源操作（VmMutations），权限为 unraid.vm.<start|stop|...>。
+	ActionUnraidVMStart     Action = "vm_start"
+	ActionUnraidVMStop      Action = "vm_stop"
+	ActionUnraidVMPause     Action = "vm_pause"
+	ActionUnraidVMResume    Action = "vm_resume"
+	ActionUnraidVMForceStop Action = "vm_force_stop"
+	ActionUnraidVMReboot    Action = "vm_reboot"
+	ActionUnraidVMReset     Action = "vm_reset"
+
+	// Unraid 校验检查（ParityCheckMutations），权限为 unraid.parity.<start|start_correct|pause|resume|cancel>。
+	ActionUnraidViewParity         Action = "view_parity"
+	ActionUnraidParityStart        Action = "parity_start"
+	ActionUnraidParityStartCorrect Action = "parity_start_correct"
+	ActionUnraidParityPause        Action = "parity_pause"
+	ActionUnraidParityResume       Action = "parity_resume"
+	ActionUnraidParityCancel       Action = "parity_cancel"
 
 	ActionQinglongRun     Action = "run"
 	ActionQinglongEnable  Action = "enable"
@@ -63,6 +85,10 @@ func ActionFromEventKey(key string) Action {
 		return ActionUnraidViewSystemStatsDetail
 	case wecom.EventKeyUnraidViewLogs:
 		return ActionUnraidViewLogs
+	case wecom.EventKeyUnraidViewArray:
+		return ActionUnraidViewArray
+	case wecom.EventKeyUnraidFollowLogs:
+		return ActionUnraidFollowLogs
 	default:
 		return ""
 	}
@@ -84,6 +110,36 @@ func (a Action) DisplayName() string {
 		return "系统资源详情"
 	case ActionUnraidViewLogs:
 		return "查看日志"
+	case ActionUnraidViewArray:
+		return "阵列状态"
+	case ActionUnraidFollowLogs:
+		return "跟踪日志"
+	case ActionUnraidVMStart:
+		return "启动虚拟机"
+	case ActionUnraidVMStop:
+		return "关闭虚拟机"
+	case ActionUnraidVMPause:
+		return "暂停虚拟机"
+	case ActionUnraidVMResume:
+		return "恢复虚拟机"
+	case ActionUnraidVMForceStop:
+		return "强制关闭虚拟机"
+	case ActionUnraidVMReboot:
+		return "重启虚拟机"
+	case ActionUnraidVMReset:
+		return "重置虚拟机"
+	case ActionUnraidViewParity:
+		return "校验检查"
+	case ActionUnraidParityStart:
+		return "开始校验检查"
+	case ActionUnraidParityStartCorrect:
+		return "开始校验检查（修正错误）"
+	case ActionUnraidParityPause:
+		return "暂停校验检查"
+	case ActionUnraidParityResume:
+		return "恢复校验检查"
+	case ActionUnraidParityCancel:
+		return "取消校验检查"
 	case ActionQinglongRun:
 		return "运行"
 	case ActionQinglongEnable:
@@ -106,6 +162,9 @@ func (a Action) DisplayName() string {
 func (a Action) RequiresConfirm() bool {
 	switch a {
 	case ActionUnraidRestart, ActionUnraidStop, ActionUnraidForceUpdate,
+		ActionUnraidVMStart, ActionUnraidVMStop, ActionUnraidVMPause, ActionUnraidVMResume,
+		ActionUnraidVMForceStop, ActionUnraidVMReboot, ActionUnraidVMReset,
+		ActionUnraidParityStart, ActionUnraidParityStartCorrect, ActionUnraidParityPause, ActionUnraidParityResume, ActionUnraidParityCancel,
 		ActionQinglongRun, ActionQinglongEnable, ActionQinglongDisable,
 		ActionPVEStart, ActionPVEShutdown, ActionPVEReboot, ActionPVEStop:
 		return true
@@ -126,6 +185,19 @@ type ConversationState struct {
 	PVEGuestID    int
 	PVEGuestName  string
 	PVENode       string
+	// PVEGuestTags 为目标的 PVE 标签，供确认时按标签作用范围复核授权。
+	PVEGuestTags []string
+	// UnraidVMID 与 UnraidVMName 为 Unraid 虚拟机操作的目标（VmDomain 的 id/name）。
+	UnraidVMID   string
+	UnraidVMName string
+	// UnraidLogPath 与 UnraidLogStart 为“系统日志”浏览中的当前文件与当前页首行（从 1 开始）。
+	UnraidLogPath  string
+	UnraidLogStart int
+
+	// ContainerNames、CronIDs 与 PVEGuests 为批量操作的目标（一次确认多个目标），非空时优先于对应的单目标字段。
+	ContainerNames []string
+	CronIDs        []int
+	PVEGuests      []PVEGuestRef
 
 	// PendingButtons 用于模板卡片(button_interaction)的文本兜底：当用户回复“序号”时，映射到对应的 EventKey。
 	PendingButtons []wecom.TemplateCardButton
@@ -133,7 +205,26 @@ type ConversationState struct {
 	ExpiresAt time.Time
 }
 
-type StateStore struct {
+// PVEGuestRef 为批量操作中的一个 PVE 目标。
+type PVEGuestRef struct {
+	Type string
+	VMID int
+	Node string
+	Name string
+	Tags []string
+}
+
+// StateStore 为会话状态存储抽象：Set 会刷新 TTL，Get 对已过期状态返回 false。
+// 实现需并发安全；Close 仅停止后台清理，不负责释放共享的底层存储。
+type StateStore interface {
+	Get(userID string) (ConversationState, bool)
+	Set(userID string, state ConversationState)
+	Clear(userID string)
+	Close()
+}
+
+// MemoryStateStore 为内存实现（默认后端），服务重启后状态丢失。
+type MemoryStateStore struct {
 	ttl  time.Duration
 	mu   sync.Mutex
 	data map[string]ConversationState
@@ -142,12 +233,12 @@ type StateStore struct {
 	stopOnce sync.Once
 }
 
-func NewStateStore(ttl time.Duration) *StateStore {
+func NewMemoryStateStore(ttl time.Duration) *MemoryStateStore {
 	if ttl <= 0 {
 		ttl = 30 * time.Minute
 	}
 
-	s := &StateStore{
+	s := &MemoryStateStore{
 		ttl:    ttl,
 		data:   make(map[string]ConversationState),
 		stopCh: make(chan struct{}),
@@ -156,7 +247,7 @@ func NewStateStore(ttl time.Duration) *StateStore {
 	return s
 }
 
-func (s *StateStore) Get(userID string) (ConversationState, bool) {
+func (s *MemoryStateStore) Get(userID string) (ConversationState, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	state, ok := s.data[userID]
@@ -170,24 +261,24 @@ func (s *StateStore) Get(userID string) (ConversationState, bool) {
 	return state, true
 }
 
-func (s *StateStore) Set(userID string, state ConversationState) {
+func (s *MemoryStateStore) Set(userID string, state ConversationState) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	state.ExpiresAt = time.Now().Add(s.ttl)
 	s.data[userID] = state
 }
 
-func (s *StateStore) Clear(userID string) {
+func (s *MemoryStateStore) Clear(userID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	delete(s.data, userID)
 }
 
-func (s *StateStore) Close() {
+func (s *MemoryStateStore) Close() {
 	s.stopOnce.Do(func() { close(s.stopCh) })
 }
 
-func (s *StateStore) startJanitor(interval time.Duration) {
+func (s *MemoryStateStore) startJanitor(interval time.Duration) {
 	if interval <= 0 {
 		return
 	}
@@ -205,7 +296,7 @@ func (s *StateStore) startJanitor(interval time.Duration) {
 	}()
 }
 
-func (s *StateStore) pruneExpired() {
+func (s *MemoryStateStore) pruneExpired() {
 	now := time.Now()
 	s.mu.Lock()
 	defer s.mu.Unlock()
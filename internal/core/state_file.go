@@ -0,0 +1,95 @@
+package core
+
+// state_file.go 提供基于 store.FileStore 的持久化会话状态实现，服务重启后可继续未完成的确认流程与文本兜底按钮。
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/store"
+)
+
+const stateBucket = "state"
+
+// FileStateStore 将会话状态写入共享的持久化存储（bucket=state），TTL 语义与内存实现一致。
+type FileStateStore struct {
+	ttl time.Duration
+	kv  *store.FileStore
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+func NewFileStateStore(kv *store.FileStore, ttl time.Duration) *FileStateStore {
+	if ttl <= 0 {
+		ttl = 30 * time.Minute
+	}
+	s := &FileStateStore{
+		ttl:    ttl,
+		kv:     kv,
+		stopCh: make(chan struct{}),
+	}
+	s.startJanitor(minDuration(ttl, time.Minute))
+	return s
+}
+
+func (s *FileStateStore) Get(userID string) (ConversationState, bool) {
+	raw, ok := s.kv.Get(stateBucket, userID)
+	if !ok {
+		return ConversationState{}, false
+	}
+	var state ConversationState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		slog.Warn("会话状态解析失败，已丢弃", "user_id", userID, "error", err)
+		_ = s.kv.Delete(stateBucket, userID)
+		return ConversationState{}, false
+	}
+	if time.Now().After(state.ExpiresAt) {
+		return ConversationState{}, false
+	}
+	return state, true
+}
+
+func (s *FileStateStore) Set(userID string, state ConversationState) {
+	state.ExpiresAt = time.Now().Add(s.ttl)
+	raw, err := json.Marshal(state)
+	if err != nil {
+		slog.Error("会话状态序列化失败", "user_id", userID, "error", err)
+		return
+	}
+	if err := s.kv.Put(stateBucket, userID, raw, state.ExpiresAt); err != nil {
+		slog.Error("会话状态持久化失败", "user_id", userID, "error", err)
+	}
+}
+
+func (s *FileStateStore) Clear(userID string) {
+	if err := s.kv.Delete(stateBucket, userID); err != nil {
+		slog.Error("会话状态删除失败", "user_id", userID, "error", err)
+	}
+}
+
+// Close 停止后台清理；底层 FileStore 由创建方关闭（可能与其他组件共享）。
+func (s *FileStateStore) Close() {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+}
+
+func (s *FileStateStore) startJanitor(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.kv.Prune(); err != nil {
+					slog.Warn("持久化存储清理失败", "error", err)
+				}
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,75 @@
+// FileStateStore 持久化会话状态单元测试。
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/store"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+func TestFileStateStore_SurvivesReload(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+	kv, err := store.Open(path)
+	if err != nil {
+		t.Fatalf("store.Open() error: %v", err)
+	}
+	s := NewFileStateStore(kv, time.Minute)
+	s.Set("u", ConversationState{
+		ServiceKey:    "unraid",
+		Step:          StepAwaitingConfirm,
+		Action:        ActionUnraidRestart,
+		ContainerName: "app",
+		PendingButtons: []wecom.TemplateCardButton{
+			{Text: "确认", Key: wecom.EventKeyConfirm},
+		},
+	})
+	s.Set("gone", ConversationState{ServiceKey: "unraid"})
+	s.Clear("gone")
+	// 模拟崩溃：不关闭底层存储直接重新打开。
+	s.Close()
+
+	kv2, err := store.Open(path)
+	if err != nil {
+		t.Fatalf("store.Open(reload) error: %v", err)
+	}
+	t.Cleanup(func() { _ = kv2.Close(); _ = kv.Close() })
+	s2 := NewFileStateStore(kv2, time.Minute)
+	t.Cleanup(s2.Close)
+
+	got, ok := s2.Get("u")
+	if !ok {
+		t.Fatalf("Get(u) ok = false, want true")
+	}
+	if got.Step != StepAwaitingConfirm || got.ContainerName != "app" || len(got.PendingButtons) != 1 || got.PendingButtons[0].Key != wecom.EventKeyConfirm {
+		t.Fatalf("Get(u) = %+v", got)
+	}
+	if _, ok := s2.Get("gone"); ok {
+		t.Fatalf("Get(gone) ok = true, want false")
+	}
+}
+
+func TestFileStateStore_TTL(t *testing.T) {
+	t.Parallel()
+
+	kv, err := store.Open(filepath.Join(t.TempDir(), "state.jsonl"))
+	if err != nil {
+		t.Fatalf("store.Open() error: %v", err)
+	}
+	t.Cleanup(func() { _ = kv.Close() })
+	s := NewFileStateStore(kv, 20*time.Millisecond)
+	t.Cleanup(s.Close)
+
+	s.Set("u", ConversationState{Step: StepAwaitingContainerName})
+	if _, ok := s.Get("u"); !ok {
+		t.Fatalf("Get() ok = false, want true")
+	}
+	time.Sleep(25 * time.Millisecond)
+	if _, ok := s.Get("u"); ok {
+		t.Fatalf("Get() ok = true, want false")
+	}
+}
@@ -9,7 +9,7 @@ import (
 func TestStateStore_TTL(t *testing.T) {
 	t.Parallel()
 
-	store := NewStateStore(20 * time.Millisecond)
+	store := NewMemoryStateStore(20 * time.Millisecond)
 	t.Cleanup(store.Close)
 	store.Set("u", ConversationState{
 		Step:   StepAwaitingContainerName,
@@ -29,7 +29,7 @@ func TestStateStore_TTL(t *testing.T) {
 func TestStateStore_JanitorPrunesWithoutGet(t *testing.T) {
 	t.Parallel()
 
-	store := NewStateStore(20 * time.Millisecond)
+	store := NewMemoryStateStore(20 * time.Millisecond)
 	t.Cleanup(store.Close)
 
 	store.Set("u", ConversationState{
@@ -53,6 +53,9 @@ func TestAction_RequiresConfirm(t *testing.T) {
 	if !ActionUnraidRestart.RequiresConfirm() {
 		t.Fatalf("ActionUnraidRestart RequiresConfirm() = false, want true")
 	}
+	if !ActionUnraidVMForceStop.RequiresConfirm() {
+		t.Fatalf("ActionUnraidVMForceStop RequiresConfirm() = false, want true")
+	}
 	if ActionUnraidViewStatus.RequiresConfirm() {
 		t.Fatalf("ActionUnraidViewStatus RequiresConfirm() = true, want false")
 	}
@@ -18,23 +18,27 @@ const (
 
 type TemplateCardSenderDeps struct {
 	Base  WeComSender
-	State *StateStore
+	State StateStore
 	Mode  TemplateCardMode
+	// ButtonFilter 可选：返回 false 的按钮（按 EventKey 判定）在下发前移除，用于按权限隐藏操作。
+	ButtonFilter func(userID, eventKey string) bool
 }
 
 // TemplateCardSender 为模板卡片提供“文本兜底 + 序号选择”能力。
 // 典型用途：企业微信客户端不支持展示模板卡片时（官方注明微工作台不支持，且存在客户端版本门槛），仍可通过文本完成交互。
 type TemplateCardSender struct {
-	base  WeComSender
-	state *StateStore
-	mode  TemplateCardMode
+	base   WeComSender
+	state  StateStore
+	mode   TemplateCardMode
+	filter func(userID, eventKey string) bool
 }
 
 func NewTemplateCardSender(deps TemplateCardSenderDeps) *TemplateCardSender {
 	return &TemplateCardSender{
-		base:  deps.Base,
-		state: deps.State,
-		mode:  deps.Mode,
+		base:   deps.Base,
+		state:  deps.State,
+		mode:   deps.Mode,
+		filter: deps.ButtonFilter,
 	}
 }
 
@@ -58,6 +62,16 @@ func (s *TemplateCardSender) SendTemplateCard(ctx context.Context, msg wecom.Tem
 
 	s.clearPendingButtons(msg.ToUser)
 
+	if s.filter != nil {
+		card, remaining := wecom.FilterTemplateCardButtons(msg.Card, func(key string) bool {
+			return s.filter(msg.ToUser, key)
+		})
+		if remaining == 0 {
+			return s.base.SendText(ctx, wecom.TextMessage{ToUser: msg.ToUser, Content: "当前账号无该菜单下任何操作的权限。"})
+		}
+		msg.Card = card
+	}
+
 	if mode == TemplateCardModeTemplateCard {
 		return s.base.SendTemplateCard(ctx, msg)
 	}
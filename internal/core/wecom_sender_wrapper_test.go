@@ -12,7 +12,7 @@ func TestTemplateCardSender_ModeText_SendsTextAndStoresPendingButtons(t *testing
 	t.Parallel()
 
 	base := &recordWeCom{}
-	state := NewStateStore(1 * time.Minute)
+	state := NewMemoryStateStore(1 * time.Minute)
 	sender := NewTemplateCardSender(TemplateCardSenderDeps{
 		Base:  base,
 		State: state,
@@ -43,7 +43,7 @@ func TestTemplateCardSender_ModeBoth_SendsCardAndText(t *testing.T) {
 	t.Parallel()
 
 	base := &recordWeCom{}
-	state := NewStateStore(1 * time.Minute)
+	state := NewMemoryStateStore(1 * time.Minute)
 	sender := NewTemplateCardSender(TemplateCardSenderDeps{
 		Base:  base,
 		State: state,
@@ -70,7 +70,7 @@ func TestTemplateCardSender_SendText_ClearsPendingButtons(t *testing.T) {
 	t.Parallel()
 
 	base := &recordWeCom{}
-	state := NewStateStore(1 * time.Minute)
+	state := NewMemoryStateStore(1 * time.Minute)
 	sender := NewTemplateCardSender(TemplateCardSenderDeps{
 		Base:  base,
 		State: state,
@@ -94,3 +94,39 @@ func TestTemplateCardSender_SendText_ClearsPendingButtons(t *testing.T) {
 		t.Fatalf("pending buttons not cleared")
 	}
 }
+
+func TestTemplateCardSender_ButtonFilter_RemovesForbiddenButtons(t *testing.T) {
+	t.Parallel()
+
+	base := &recordWeCom{}
+	state := NewMemoryStateStore(1 * time.Minute)
+	sender := NewTemplateCardSender(TemplateCardSenderDeps{
+		Base:  base,
+		State: state,
+		Mode:  TemplateCardModeBoth,
+		ButtonFilter: func(_ string, key string) bool {
+			return key != wecom.EventKeyUnraidStop && key != wecom.EventKeyUnraidForceUpdate
+		},
+	})
+
+	card := wecom.NewUnraidOpsCard()
+	if err := sender.SendTemplateCard(context.Background(), wecom.TemplateCardMessage{ToUser: "u", Card: card}); err != nil {
+		t.Fatalf("SendTemplateCard() error: %v", err)
+	}
+	if len(base.cards) != 1 {
+		t.Fatalf("template card count = %d, want 1", len(base.cards))
+	}
+	_, sent, _ := wecom.RenderButtonInteractionTextMenu(base.cards[0].Card)
+	for _, b := range sent {
+		if b.Key == wecom.EventKeyUnraidStop || b.Key == wecom.EventKeyUnraidForceUpdate {
+			t.Fatalf("forbidden button %q still present", b.Key)
+		}
+	}
+	st, _ := state.Get("u")
+	if len(st.PendingButtons) != len(sent) {
+		t.Fatalf("pending buttons = %d, want %d", len(st.PendingButtons), len(sent))
+	}
+	if _, orig, _ := wecom.RenderButtonInteractionTextMenu(card); len(orig) <= len(sent) {
+		t.Fatalf("original card was modified or nothing filtered: orig=%d sent=%d", len(orig), len(sent))
+	}
+}
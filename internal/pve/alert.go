@@ -26,15 +26,18 @@ type AlertConfig struct {
 }
 
 type AlertManagerDeps struct {
-	WeCom     core.WeComSender
-	UserIDs   []string
-	Instances []Instance
-	Config    AlertConfig
+	WeCom   core.WeComSender
+	UserIDs []string
+	// Recipients 可选：每次推送前动态解析收件人（优先于 UserIDs），使部门/标签成员变动无需重启即可生效。
+	Recipients func() []string
+	Instances  []Instance
+	Config     AlertConfig
 }
 
 type AlertManager struct {
-	wecom   core.WeComSender
-	userIDs []string
+	wecom      core.WeComSender
+	userIDs    []string
+	recipients func() []string
 
 	cfg       AlertConfig
 	instances map[string]Instance
@@ -48,6 +51,7 @@ type AlertManager struct {
 	stopOnce sync.Once
 
 	startOnce sync.Once
+	wg        sync.WaitGroup
 }
 
 func NewAlertManager(deps AlertManagerDeps) *AlertManager {
@@ -69,6 +73,7 @@ func NewAlertManager(deps AlertManagerDeps) *AlertManager {
 	return &AlertManager{
 		wecom:      deps.WeCom,
 		userIDs:    userIDs,
+		recipients: deps.Recipients,
 		cfg:        deps.Config,
 		instances:  instances,
 		order:      order,
@@ -88,7 +93,7 @@ func (m *AlertManager) Config() AlertConfig {
 }
 
 func (m *AlertManager) Start() {
-	if m == nil || !m.cfg.Enabled || m.wecom == nil || len(m.userIDs) == 0 || len(m.order) == 0 {
+	if m == nil || !m.cfg.Enabled || m.wecom == nil || (len(m.userIDs) == 0 && m.recipients == nil) || len(m.order) == 0 {
 		return
 	}
 	m.startOnce.Do(func() {
@@ -96,6 +101,7 @@ func (m *AlertManager) Start() {
 		if interval <= 0 {
 			interval = 2 * time.Minute
 		}
+		m.wg.Add(1)
 		go m.loop(interval)
 	})
 }
@@ -105,6 +111,7 @@ func (m *AlertManager) Close() {
 		return
 	}
 	m.stopOnce.Do(func() { close(m.stopCh) })
+	m.wg.Wait()
 }
 
 func (m *AlertManager) Mute(instanceID string, until time.Time) bool {
@@ -150,6 +157,7 @@ func (m *AlertManager) MuteUntil(instanceID string) (time.Time, bool) {
 }
 
 func (m *AlertManager) loop(interval time.Duration) {
+	defer m.wg.Done()
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -355,7 +363,11 @@ func (m *AlertManager) sendIfNotInCooldown(ctx context.Context, ins Instance, ki
 	m.lastSent[key] = now
 	m.mu.Unlock()
 
-	for _, userID := range m.userIDs {
+	userIDs := m.userIDs
+	if m.recipients != nil {
+		userIDs = uniqueNonEmpty(m.recipients())
+	}
+	for _, userID := range userIDs {
 		_ = m.wecom.SendText(ctx, wecom.TextMessage{
 			ToUser:  userID,
 			Content: content,
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -22,8 +23,10 @@ type Instance struct {
 
 type ProviderDeps struct {
 	WeCom     core.WeComSender
-	State     *core.StateStore
+	State     core.StateStore
 	Instances []Instance
+	// Auth 可选：按实例/VMID/标签作用范围过滤实例与目标；为空时不限制。
+	Auth core.ResourceAuthorizer
 
 	AlertConfig AlertConfig
 	Alerts      *AlertManager
@@ -31,7 +34,8 @@ type ProviderDeps struct {
 
 type Provider struct {
 	wecom  core.WeComSender
-	state  *core.StateStore
+	state  core.StateStore
+	auth   core.ResourceAuthorizer
 	alerts *AlertManager
 
 	alertCfg AlertConfig
@@ -59,6 +63,7 @@ func NewProvider(deps ProviderDeps) *Provider {
 	return &Provider{
 		wecom:     deps.WeCom,
 		state:     deps.State,
+		auth:      deps.Auth,
 		alerts:    deps.Alerts,
 		alertCfg:  deps.AlertConfig,
 		instances: instances,
@@ -70,6 +75,20 @@ func (p *Provider) Key() string { return "pve" }
 
 func (p *Provider) DisplayName() string { return "PVE" }
 
+// MenuButton 声明应用自定义菜单中的“PVE”一级菜单（并入“常用”时仅保留入口）。
+func (p *Provider) MenuButton() wecom.MenuButton {
+	return wecom.MenuButton{
+		Name: "PVE",
+		SubButtons: []wecom.MenuButton{
+			{Type: "click", Name: "进入PVE", Key: wecom.EventKeyServiceSelectPrefix + p.Key()},
+			{Type: "click", Name: "资源概览", Key: wecom.EventKeyPVEActionOverview},
+			{Type: "click", Name: "虚拟机", Key: wecom.EventKeyPVEActionVMMenu},
+			{Type: "click", Name: "LXC 容器", Key: wecom.EventKeyPVEActionLXCMenu},
+			{Type: "click", Name: "告警状态", Key: wecom.EventKeyPVEActionAlertStatus},
+		},
+	}
+}
+
 func (p *Provider) EntryKeywords() []string {
 	return []string{"pve", "proxmox"}
 }
@@ -82,10 +101,18 @@ func (p *Provider) OnEnter(ctx context.Context, userID string) error {
 		})
 	}
 
+	visible := p.visibleInstances(userID)
+	if len(visible) == 0 {
+		return p.wecom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: "无权限：当前账号未被授权访问任何 PVE 实例。",
+		})
+	}
+
 	p.state.Set(userID, core.ConversationState{ServiceKey: p.Key()})
 
-	if len(p.order) == 1 {
-		ins := p.order[0]
+	if len(visible) == 1 {
+		ins := visible[0]
 		p.state.Set(userID, core.ConversationState{
 			ServiceKey:  p.Key(),
 			InstanceID:  ins.ID,
@@ -98,7 +125,7 @@ func (p *Provider) OnEnter(ctx context.Context, userID string) error {
 	}
 
 	var opts []wecom.PVEInstanceOption
-	for _, ins := range p.order {
+	for _, ins := range visible {
 		opts = append(opts, wecom.PVEInstanceOption{ID: ins.ID, Name: ins.Name})
 	}
 	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
@@ -113,9 +140,21 @@ func (p *Provider) HandleText(ctx context.Context, userID, content string) (bool
 		return false, nil
 	}
 
+	cmd, isCmd, err := parseGuestCommand(content)
+	if err != nil {
+		return true, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+	}
+	if isCmd {
+		ins, ok := p.instanceFromState(userID, state)
+		if !ok {
+			return true, p.OnEnter(ctx, userID)
+		}
+		return true, p.handleGuestCommand(ctx, userID, ins, state, cmd)
+	}
+
 	switch state.Step {
 	case core.StepAwaitingPVEGuestQuery:
-		ins, ok := p.instanceFromState(state)
+		ins, ok := p.instanceFromState(userID, state)
 		if !ok {
 			p.state.Clear(userID)
 			return true, p.OnEnter(ctx, userID)
@@ -124,11 +163,43 @@ func (p *Provider) HandleText(ctx context.Context, userID, content string) (bool
 	default:
 		return true, p.wecom.SendText(ctx, wecom.TextMessage{
 			ToUser:  userID,
-			Content: "请输入“菜单”打开操作菜单，或点击卡片按钮继续。",
+			Content: "请输入“菜单”打开操作菜单，或点击卡片按钮继续。\n也可直接发送电源指令，例如“关机 101 102”“shutdown all running lxc on node pve2”。",
 		})
 	}
 }
 
+// EventPermission 声明电源操作与告警静默按钮所需权限（pve.vm.*、pve.lxc.*、pve.alert），其余事件沿用 pve.view。
+func (p *Provider) EventPermission(eventKey string) string {
+	if suffix, ok := strings.CutPrefix(eventKey, wecom.EventKeyPVEGuestActionPrefix); ok {
+		if guestType, guestAction, ok := parseGuestActionKey(suffix); ok {
+			return guestActionPermission(guestType, guestAction)
+		}
+		return ""
+	}
+	switch eventKey {
+	case wecom.EventKeyPVEVMStart:
+		return guestActionPermission(GuestTypeQEMU, GuestActionStart)
+	case wecom.EventKeyPVEVMShutdown:
+		return guestActionPermission(GuestTypeQEMU, GuestActionShutdown)
+	case wecom.EventKeyPVEVMReboot:
+		return guestActionPermission(GuestTypeQEMU, GuestActionReboot)
+	case wecom.EventKeyPVEVMStop:
+		return guestActionPermission(GuestTypeQEMU, GuestActionStop)
+	case wecom.EventKeyPVELXCStart:
+		return guestActionPermission(GuestTypeLXC, GuestActionStart)
+	case wecom.EventKeyPVELXCShutdown:
+		return guestActionPermission(GuestTypeLXC, GuestActionShutdown)
+	case wecom.EventKeyPVELXCReboot:
+		return guestActionPermission(GuestTypeLXC, GuestActionReboot)
+	case wecom.EventKeyPVELXCStop:
+		return guestActionPermission(GuestTypeLXC, GuestActionStop)
+	case wecom.EventKeyPVEActionAlertMute, wecom.EventKeyPVEActionAlertUnmute:
+		return core.ServicePermission(p.Key(), "alert")
+	default:
+		return ""
+	}
+}
+
 func (p *Provider) HandleEvent(ctx context.Context, userID string, msg wecom.IncomingMessage) (bool, error) {
 	key := strings.TrimSpace(msg.EventKey)
 	if key == "" {
@@ -138,7 +209,7 @@ func (p *Provider) HandleEvent(ctx context.Context, userID string, msg wecom.Inc
 	if strings.HasPrefix(key, wecom.EventKeyPVEInstanceSelectPrefix) {
 		id := strings.TrimPrefix(key, wecom.EventKeyPVEInstanceSelectPrefix)
 		ins, ok := p.instances[id]
-		if !ok {
+		if !ok || !p.allowed(userID, core.ViewPermission(p.Key()), core.Resource{InstanceID: ins.ID}) {
 			return true, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "实例不可用，请重新选择。"})
 		}
 		p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
@@ -152,7 +223,7 @@ func (p *Provider) HandleEvent(ctx context.Context, userID string, msg wecom.Inc
 
 	switch key {
 	case wecom.EventKeyPVEMenu:
-		ins, ok := p.instanceFromState(state)
+		ins, ok := p.instanceFromState(userID, state)
 		if !ok {
 			return true, p.OnEnter(ctx, userID)
 		}
@@ -165,7 +236,7 @@ func (p *Provider) HandleEvent(ctx context.Context, userID string, msg wecom.Inc
 		return true, p.OnEnter(ctx, userID)
 
 	case wecom.EventKeyPVEActionOverview:
-		ins, ok := p.instanceFromState(state)
+		ins, ok := p.instanceFromState(userID, state)
 		if !ok {
 			return true, p.OnEnter(ctx, userID)
 		}
@@ -174,7 +245,7 @@ func (p *Provider) HandleEvent(ctx context.Context, userID string, msg wecom.Inc
 		return true, p.sendOverview(ctx, userID, ins)
 
 	case wecom.EventKeyPVEActionVMMenu:
-		ins, ok := p.instanceFromState(state)
+		ins, ok := p.instanceFromState(userID, state)
 		if !ok {
 			return true, p.OnEnter(ctx, userID)
 		}
@@ -186,7 +257,7 @@ func (p *Provider) HandleEvent(ctx context.Context, userID string, msg wecom.Inc
 		})
 
 	case wecom.EventKeyPVEActionLXCMenu:
-		ins, ok := p.instanceFromState(state)
+		ins, ok := p.instanceFromState(userID, state)
 		if !ok {
 			return true, p.OnEnter(ctx, userID)
 		}
@@ -198,7 +269,7 @@ func (p *Provider) HandleEvent(ctx context.Context, userID string, msg wecom.Inc
 		})
 
 	case wecom.EventKeyPVEActionAlertStatus:
-		ins, ok := p.instanceFromState(state)
+		ins, ok := p.instanceFromState(userID, state)
 		if !ok {
 			return true, p.OnEnter(ctx, userID)
 		}
@@ -207,7 +278,7 @@ func (p *Provider) HandleEvent(ctx context.Context, userID string, msg wecom.Inc
 		return true, p.sendAlertStatus(ctx, userID, ins)
 
 	case wecom.EventKeyPVEActionAlertMute:
-		ins, ok := p.instanceFromState(state)
+		ins, ok := p.instanceFromState(userID, state)
 		if !ok {
 			return true, p.OnEnter(ctx, userID)
 		}
@@ -222,7 +293,7 @@ func (p *Provider) HandleEvent(ctx context.Context, userID string, msg wecom.Inc
 		})
 
 	case wecom.EventKeyPVEActionAlertUnmute:
-		ins, ok := p.instanceFromState(state)
+		ins, ok := p.instanceFromState(userID, state)
 		if !ok {
 			return true, p.OnEnter(ctx, userID)
 		}
@@ -252,83 +323,185 @@ func (p *Provider) HandleEvent(ctx context.Context, userID string, msg wecom.Inc
 	}
 
 	if strings.HasPrefix(key, wecom.EventKeyPVEGuestSelectPrefix) {
-		ins, ok := p.instanceFromState(state)
+		ins, ok := p.instanceFromState(userID, state)
 		if !ok {
 			return true, p.OnEnter(ctx, userID)
 		}
 		return true, p.handleGuestSelect(ctx, userID, ins, state, key)
 	}
+	if strings.HasPrefix(key, wecom.EventKeyPVEGuestActionPrefix) {
+		return true, p.handleGuestAction(ctx, userID, state, strings.TrimPrefix(key, wecom.EventKeyPVEGuestActionPrefix))
+	}
 
 	return false, nil
 }
 
 func (p *Provider) HandleConfirm(ctx context.Context, userID string) (bool, error) {
+	action, ok, err := p.PrepareConfirm(ctx, userID)
+	if err != nil || !ok {
+		return ok, err
+	}
+	return true, core.RunConfirmedAction(ctx, p.wecom, userID, action)
+}
+
+// PrepareConfirm 校验并清理待确认状态，返回可在后台执行的 VM/LXC 电源操作（含任务状态等待）。
+func (p *Provider) PrepareConfirm(ctx context.Context, userID string) (core.ConfirmedAction, bool, error) {
 	state, ok := p.state.Get(userID)
 	if !ok || state.ServiceKey != p.Key() || state.Step != core.StepAwaitingConfirm {
-		return false, nil
+		return core.ConfirmedAction{}, false, nil
 	}
 
-	ins, ok := p.instanceFromState(state)
+	ins, ok := p.instanceFromState(userID, state)
 	if !ok {
 		p.state.Clear(userID)
-		return true, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "会话已过期，请重新进入 PVE 菜单。"})
+		return core.ConfirmedAction{}, true, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "会话已过期，请重新进入 PVE 菜单。"})
+	}
+
+	if len(state.PVEGuests) > 0 {
+		p.state.Clear(userID)
+		if _, ok := coreActionToGuestAction(state.Action); !ok {
+			return core.ConfirmedAction{}, true, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未知动作，请重新选择。"})
+		}
+		items := make([]core.ConfirmedAction, 0, len(state.PVEGuests))
+		for _, g := range state.PVEGuests {
+			items = append(items, p.guestAction(ins, GuestType(g.Type), state.Action, ClusterResource{
+				VMID: g.VMID,
+				Node: g.Node,
+				Name: g.Name,
+			}, g.Tags))
+		}
+		return core.NewBatchAction(state.Action, items), true, nil
 	}
 
 	guestType := GuestType(strings.TrimSpace(state.PVEGuestType))
 	if !guestType.IsValid() || state.PVEGuestID <= 0 || strings.TrimSpace(state.PVENode) == "" {
 		p.state.Clear(userID)
-		return true, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "缺少目标信息，请重新选择。"})
+		return core.ConfirmedAction{}, true, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "缺少目标信息，请重新选择。"})
 	}
 
-	action, ok := coreActionToGuestAction(state.Action)
-	if !ok {
+	if _, ok := coreActionToGuestAction(state.Action); !ok {
 		p.state.Clear(userID)
-		return true, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未知动作，请重新选择。"})
+		return core.ConfirmedAction{}, true, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未知动作，请重新选择。"})
 	}
 
-	target := fmt.Sprintf("%s %d（%s）", strings.ToUpper(guestType.String()), state.PVEGuestID, state.PVENode)
-	if n := strings.TrimSpace(state.PVEGuestName); n != "" {
-		target = fmt.Sprintf("%s %d（%s | %s）", strings.ToUpper(guestType.String()), state.PVEGuestID, state.PVENode, n)
+	p.state.Clear(userID)
+	return p.guestAction(ins, guestType, state.Action, ClusterResource{
+		VMID: state.PVEGuestID,
+		Node: state.PVENode,
+		Name: state.PVEGuestName,
+	}, state.PVEGuestTags), true, nil
+}
+
+// guestAction 构造 VM/LXC 电源操作：提交后等待 PVE 任务结束并回报 UPID。
+func (p *Provider) guestAction(ins Instance, guestType GuestType, action core.Action, res ClusterResource, tags []string) core.ConfirmedAction {
+	guestAction, _ := coreActionToGuestAction(action)
+	target := formatGuestTarget(guestType, res.VMID, res.Node, res.Name)
+	node := res.Node
+	vmid := res.VMID
+	return core.ConfirmedAction{
+		ServiceKey: p.Key(),
+		InstanceID: ins.ID,
+		Action:     action,
+		Target:     target,
+		Permission: guestActionPermission(guestType, guestAction),
+		Resource:   core.Resource{InstanceID: ins.ID, VMID: vmid, Tags: tags},
+		Run: func(ctx context.Context, progress core.ProgressFunc) (string, error) {
+			upid, err := ins.Client.GuestAction(ctx, node, guestType, vmid, guestAction)
+			if err != nil {
+				return "", fmt.Errorf("%s失败：%w", action.DisplayName(), err)
+			}
+			progress(fmt.Sprintf("已提交：%s %s\nUPID: %s", action.DisplayName(), target, upid))
+
+			final, err := waitTask(ctx, ins.Client, node, upid, 90*time.Second)
+			if err != nil {
+				return "", fmt.Errorf("任务状态获取失败（UPID: %s）：%w", upid, err)
+			}
+			if exit := strings.TrimSpace(final.ExitStatus); exit != "" && strings.ToUpper(exit) != "OK" {
+				return "", fmt.Errorf("执行完成但状态异常：%s\n目标：%s\nUPID: %s", final.ExitStatus, target, upid)
+			}
+			return fmt.Sprintf("%s %s\nUPID: %s", action.DisplayName(), target, upid), nil
+		},
 	}
+}
 
-	p.state.Clear(userID)
+// actionSpecPattern 匹配“关机 VM 101”“启动 lxc 200 @home”等电源操作短语。
+var actionSpecPattern = regexp.MustCompile(`(?i)^(启动|开机|start|关机|shutdown|重启|reboot|强制停止|强制关机|stop)\s*(vm|虚拟机|qemu|lxc|ct)\s*(\d+)(?:\s+@?(\S+))?$`)
 
-	upid, err := ins.Client.GuestAction(ctx, state.PVENode, guestType, state.PVEGuestID, action)
-	if err != nil {
-		return true, p.wecom.SendText(ctx, wecom.TextMessage{
-			ToUser:  userID,
-			Content: fmt.Sprintf("%s失败：%s", state.Action.DisplayName(), err.Error()),
-		})
+// ParseActionSpec 解析 VM/LXC 电源操作短语；多实例时可在末尾以 @<实例ID> 指定实例。
+func (p *Provider) ParseActionSpec(text string) (core.ActionSpec, bool, error) {
+	m := actionSpecPattern.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return core.ActionSpec{}, false, nil
 	}
 
-	_ = p.wecom.SendText(ctx, wecom.TextMessage{
-		ToUser:  userID,
-		Content: fmt.Sprintf("已提交：%s %s\nUPID: %s", state.Action.DisplayName(), target, upid),
-	})
+	var action core.Action
+	switch strings.ToLower(m[1]) {
+	case "启动", "开机", "start":
+		action = core.ActionPVEStart
+	case "关机", "shutdown":
+		action = core.ActionPVEShutdown
+	case "重启", "reboot":
+		action = core.ActionPVEReboot
+	default:
+		action = core.ActionPVEStop
+	}
+	guestType := GuestTypeQEMU
+	switch strings.ToLower(m[2]) {
+	case "lxc", "ct":
+		guestType = GuestTypeLXC
+	}
+	if id := m[4]; id != "" {
+		if _, ok := p.instances[id]; !ok {
+			return core.ActionSpec{}, false, fmt.Errorf("未知 PVE 实例：%s", id)
+		}
+	}
+	return core.ActionSpec{
+		ServiceKey: p.Key(),
+		InstanceID: m[4],
+		Action:     action,
+		Target:     m[3],
+		GuestType:  guestType.String(),
+	}, true, nil
+}
 
-	final, waitErr := waitTask(ctx, ins.Client, state.PVENode, upid, 90*time.Second)
-	if waitErr != nil {
-		return true, p.wecom.SendText(ctx, wecom.TextMessage{
-			ToUser:  userID,
-			Content: fmt.Sprintf("任务状态获取失败（UPID: %s）：%s", upid, waitErr.Error()),
-		})
+// BuildAction 在实例中查找目标 VM/LXC（需仍然存在）并构造电源操作。
+func (p *Provider) BuildAction(ctx context.Context, spec core.ActionSpec) (core.ConfirmedAction, error) {
+	var ins Instance
+	switch {
+	case spec.InstanceID != "":
+		var ok bool
+		if ins, ok = p.instances[spec.InstanceID]; !ok {
+			return core.ConfirmedAction{}, fmt.Errorf("PVE 实例 %s 不存在", spec.InstanceID)
+		}
+	case len(p.order) == 1:
+		ins = p.order[0]
+	default:
+		return core.ConfirmedAction{}, errors.New("存在多个 PVE 实例，请在末尾指定实例，例如“关机 VM 101 @home”")
 	}
 
-	if strings.TrimSpace(final.ExitStatus) != "" && strings.ToUpper(strings.TrimSpace(final.ExitStatus)) != "OK" {
-		return true, p.wecom.SendText(ctx, wecom.TextMessage{
-			ToUser:  userID,
-			Content: fmt.Sprintf("执行完成但状态异常：%s\n目标：%s\nUPID: %s", final.ExitStatus, target, upid),
-		})
+	guestType := GuestType(spec.GuestType)
+	vmid, err := strconv.Atoi(spec.Target)
+	if !guestType.IsValid() || err != nil || vmid <= 0 {
+		return core.ConfirmedAction{}, fmt.Errorf("目标不合法：%s %s", spec.GuestType, spec.Target)
+	}
+	if _, ok := coreActionToGuestAction(spec.Action); !ok {
+		return core.ConfirmedAction{}, fmt.Errorf("不支持定时执行的动作：%s", spec.Action)
 	}
 
-	return true, p.wecom.SendText(ctx, wecom.TextMessage{
-		ToUser:  userID,
-		Content: fmt.Sprintf("执行成功：%s %s\nUPID: %s", state.Action.DisplayName(), target, upid),
-	})
+	list, err := ins.Client.ListClusterResources(ctx, "vm")
+	if err != nil {
+		return core.ConfirmedAction{}, fmt.Errorf("获取 %s 资源列表失败：%w", ins.Name, err)
+	}
+	for _, res := range list {
+		if GuestType(strings.TrimSpace(res.Type)) == guestType && res.VMID == vmid {
+			return p.guestAction(ins, guestType, spec.Action, res, res.TagList()), nil
+		}
+	}
+	return core.ConfirmedAction{}, fmt.Errorf("%s 中未找到 %s %d", ins.Name, strings.ToUpper(guestType.String()), vmid)
 }
 
 func (p *Provider) prepareGuestQuery(ctx context.Context, userID string, state core.ConversationState, guestType GuestType, action core.Action) error {
-	_, ok := p.instanceFromState(state)
+	_, ok := p.instanceFromState(userID, state)
 	if !ok {
 		return p.OnEnter(ctx, userID)
 	}
@@ -365,11 +538,26 @@ func (p *Provider) handleGuestQuery(ctx context.Context, userID string, ins Inst
 		return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{ToUser: userID, Card: wecom.NewPVEActionCard(p.actionCardOptions(ins))})
 	}
 
+	if targets := core.SplitTargets(content); len(targets) > 1 {
+		cmd := guestCommand{action: state.Action, guestType: guestType}
+		for _, t := range targets {
+			vmid, err := strconv.Atoi(t)
+			if err != nil || vmid <= 0 {
+				return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "批量操作请输入多个 VMID（空格或逗号分隔）。"})
+			}
+			cmd.vmids = append(cmd.vmids, vmid)
+		}
+		return p.handleGuestCommand(ctx, userID, ins, state, cmd)
+	}
+
 	if vmid, err := strconv.Atoi(content); err == nil && vmid > 0 {
 		res, ok := findGuestByVMID(ctx, ins.Client, guestType, vmid)
 		if !ok {
 			return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未找到目标，请确认 VMID 或改用名称关键词。"})
 		}
+		if !p.allowedGuest(userID, ins, guestType, state.Action, res) {
+			return p.sendGuestForbidden(ctx, userID, guestType, res)
+		}
 		return p.prepareConfirm(ctx, userID, state, ins, guestType, res)
 	}
 
@@ -378,32 +566,31 @@ func (p *Provider) handleGuestQuery(ctx context.Context, userID string, ins Inst
 		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "查询失败：" + err.Error()})
 	}
 
-	kw := strings.ToLower(strings.TrimSpace(content))
-	var hits []ClusterResource
+	// 名称按模糊匹配解析：唯一最相近者直接进入确认，否则按相近程度列出候选。
+	byKey := make(map[string]ClusterResource)
+	var cands []core.FuzzyCandidate
 	for _, r := range list {
-		if GuestType(strings.TrimSpace(r.Type)) != guestType {
+		if GuestType(strings.TrimSpace(r.Type)) != guestType || !p.allowedGuest(userID, ins, guestType, state.Action, r) {
 			continue
 		}
-		name := strings.ToLower(strings.TrimSpace(r.Name))
-		if kw != "" && strings.Contains(name, kw) {
-			hits = append(hits, r)
-		}
+		key := strconv.Itoa(r.VMID)
+		byKey[key] = r
+		cands = append(cands, core.FuzzyCandidate{Key: key, Name: strings.TrimSpace(r.Name)})
 	}
-	if len(hits) == 0 {
+	matches, unique := core.ResolveFuzzy(content, cands)
+	if len(matches) == 0 {
 		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未找到目标，请更换关键词重试。"})
 	}
-	if len(hits) == 1 {
-		return p.prepareConfirm(ctx, userID, state, ins, guestType, hits[0])
+	if unique {
+		return p.prepareConfirm(ctx, userID, state, ins, guestType, byKey[matches[0].Key])
 	}
 
-	sort.SliceStable(hits, func(i, j int) bool { return hits[i].VMID < hits[j].VMID })
-
-	const maxButtons = 4
 	var opts []wecom.PVEGuestOption
-	for i, r := range hits {
-		if i >= maxButtons {
+	for i, m := range matches {
+		if i >= core.MaxFuzzyChoices {
 			break
 		}
+		r := byKey[m.Key]
 		text := fmt.Sprintf("%d: %s", r.VMID, strings.TrimSpace(r.Name))
 		opts = append(opts, wecom.PVEGuestOption{
 			Text:      truncateRunes(text, 32),
@@ -440,6 +627,9 @@ func (p *Provider) handleGuestSelect(ctx context.Context, userID string, ins Ins
 		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未找到目标，请重新搜索。"})
 	}
 	res.Node = node
+	if !p.allowedGuest(userID, ins, guestType, state.Action, res) {
+		return p.sendGuestForbidden(ctx, userID, guestType, res)
+	}
 	return p.prepareConfirm(ctx, userID, state, ins, guestType, res)
 }
 
@@ -450,18 +640,380 @@ func (p *Provider) prepareConfirm(ctx context.Context, userID string, state core
 	state.PVEGuestID = res.VMID
 	state.PVENode = strings.TrimSpace(res.Node)
 	state.PVEGuestName = strings.TrimSpace(res.Name)
+	state.PVEGuestTags = res.TagList()
+	state.PVEGuests = nil
 	p.state.Set(userID, state)
 
-	target := fmt.Sprintf("%s %d（%s）", strings.ToUpper(guestType.String()), res.VMID, res.Node)
-	if name := strings.TrimSpace(res.Name); name != "" {
-		target = fmt.Sprintf("%s %d（%s | %s）", strings.ToUpper(guestType.String()), res.VMID, res.Node, name)
+	target := formatGuestTarget(guestType, res.VMID, res.Node, res.Name)
+	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card:   wecom.NewConfirmCardWithFavorite(state.Action.DisplayName(), target, wecom.FavoriteAddButton(p.Key(), ins.ID, favoriteTarget(guestType, res.VMID))),
+	})
+}
+
+// handleGuestAction 处理目标操作卡片（由“常用”打开）上的电源按钮：目标为会话中的当前虚拟机/容器。
+func (p *Provider) handleGuestAction(ctx context.Context, userID string, state core.ConversationState, suffix string) error {
+	guestType, guestAction, ok := parseGuestActionKey(suffix)
+	if !ok {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "选择无效，请重新选择。"})
+	}
+	ins, ok := p.instanceFromState(userID, state)
+	if !ok || state.PVEGuestID <= 0 || GuestType(state.PVEGuestType) != guestType {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "会话已过期，请发送“常用”重新打开目标。"})
+	}
+	res, ok := findGuestByVMID(ctx, ins.Client, guestType, state.PVEGuestID)
+	if !ok {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未找到目标，可能已被删除或迁移。"})
+	}
+	state.Action = guestActionToCoreAction(guestAction)
+	if !p.allowedGuest(userID, ins, guestType, state.Action, res) {
+		return p.sendGuestForbidden(ctx, userID, guestType, res)
+	}
+	return p.prepareConfirm(ctx, userID, state, ins, guestType, res)
+}
+
+// ResolveFavorite 校验收藏的虚拟机/容器（目标格式 <qemu|lxc>:<vmid>）存在且在用户作用范围内，并补全名称。
+func (p *Provider) ResolveFavorite(ctx context.Context, userID string, fav core.Favorite) (core.Favorite, error) {
+	ins, guestType, res, err := p.lookupFavorite(ctx, userID, fav)
+	if err != nil {
+		return fav, err
+	}
+	fav.Name = strings.TrimSpace(fmt.Sprintf("%s %d %s", strings.ToUpper(guestType.String()), res.VMID, strings.TrimSpace(res.Name)))
+	if len(p.order) > 1 {
+		fav.Name = ins.Name + " " + fav.Name
+	}
+	return fav, nil
+}
+
+// OpenFavorite 以收藏的虚拟机/容器为当前目标下发电源操作卡片。
+func (p *Provider) OpenFavorite(ctx context.Context, userID string, fav core.Favorite) error {
+	ins, guestType, res, err := p.lookupFavorite(ctx, userID, fav)
+	if err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+	}
+	p.state.Set(userID, core.ConversationState{
+		ServiceKey:   p.Key(),
+		InstanceID:   ins.ID,
+		PVEGuestType: guestType.String(),
+		PVEGuestID:   res.VMID,
+		PVENode:      strings.TrimSpace(res.Node),
+		PVEGuestName: strings.TrimSpace(res.Name),
+		PVEGuestTags: res.TagList(),
+	})
+	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card: wecom.NewPVEGuestActionCard(wecom.PVEGuestActionCardOptions{
+			InstanceName: ins.Name,
+			GuestType:    guestType.String(),
+			VMID:         res.VMID,
+			Name:         strings.TrimSpace(res.Name),
+			Fav:          wecom.FavoriteRemoveButton(fav.ID),
+		}),
+	})
+}
+
+func (p *Provider) lookupFavorite(ctx context.Context, userID string, fav core.Favorite) (Instance, GuestType, ClusterResource, error) {
+	ins, ok := p.instances[fav.InstanceID]
+	if !ok || !p.allowed(userID, core.ViewPermission(p.Key()), core.Resource{InstanceID: ins.ID}) {
+		return Instance{}, "", ClusterResource{}, fmt.Errorf("实例不可用：%s", fav.InstanceID)
+	}
+	typ, id, _ := strings.Cut(fav.Target, ":")
+	guestType := GuestType(typ)
+	vmid, err := strconv.Atoi(id)
+	if err != nil || vmid <= 0 || !guestType.IsValid() {
+		return Instance{}, "", ClusterResource{}, fmt.Errorf("目标不合法：%s", fav.Target)
+	}
+	res, ok := findGuestByVMID(ctx, ins.Client, guestType, vmid)
+	if !ok {
+		return Instance{}, "", ClusterResource{}, fmt.Errorf("未找到目标：%s %d", strings.ToUpper(guestType.String()), vmid)
+	}
+	if !p.allowedGuest(userID, ins, guestType, "", res) {
+		return Instance{}, "", ClusterResource{}, fmt.Errorf("无权限：%s %d 不在当前账号的授权范围内", strings.ToUpper(guestType.String()), vmid)
+	}
+	return ins, guestType, res, nil
+}
+
+// favoriteTarget 返回收藏目标标识：<qemu|lxc>:<vmid>。
+func favoriteTarget(guestType GuestType, vmid int) string {
+	return guestType.String() + ":" + strconv.Itoa(vmid)
+}
+
+// parseGuestActionKey 解析目标操作卡片按钮后缀：<qemu|lxc>.<start|shutdown|reboot|stop>。
+func parseGuestActionKey(suffix string) (GuestType, GuestAction, bool) {
+	typ, act, ok := strings.Cut(suffix, ".")
+	guestType, guestAction := GuestType(typ), GuestAction(act)
+	if !ok || !guestType.IsValid() || !guestAction.IsValid() {
+		return "", "", false
+	}
+	return guestType, guestAction, true
+}
+
+// guestCommand 为解析后的电源指令：vmids 非空时按 VMID 指定目标，all=true 时按类型/状态/节点/标签筛选目标。
+type guestCommand struct {
+	action    core.Action
+	guestType GuestType
+	vmids     []int
+
+	all    bool
+	status string
+	node   string
+	tag    string
+}
+
+var guestCommandVerbs = map[string]core.Action{
+	"启动": core.ActionPVEStart, "开机": core.ActionPVEStart, "start": core.ActionPVEStart,
+	"关机": core.ActionPVEShutdown, "shutdown": core.ActionPVEShutdown,
+	"重启": core.ActionPVEReboot, "reboot": core.ActionPVEReboot,
+	"强制停止": core.ActionPVEStop, "强制关机": core.ActionPVEStop, "stop": core.ActionPVEStop,
+}
+
+const guestCommandUsage = "用法：<动作> [vm|lxc] <VMID...>，或 <动作> all [running|stopped] [vm|lxc] [on node <节点>] [tag <标签>]"
+
+// parseGuestCommand 解析“关机 101 102”“shutdown all running LXC on node pve2”等电源指令。
+// 首词不是电源动作时返回 ok=false；err 非空表示指令格式不正确。
+func parseGuestCommand(text string) (guestCommand, bool, error) {
+	fields := core.SplitTargets(strings.ToLower(text))
+	if len(fields) < 2 {
+		return guestCommand{}, false, nil
+	}
+	action, ok := guestCommandVerbs[fields[0]]
+	if !ok {
+		return guestCommand{}, false, nil
+	}
+
+	cmd := guestCommand{action: action}
+	for i := 1; i < len(fields); i++ {
+		f := fields[i]
+		switch f {
+		case "all", "全部", "所有":
+			cmd.all = true
+		case "running", "运行中":
+			cmd.status = "running"
+		case "stopped", "已停止", "已关机":
+			cmd.status = "stopped"
+		case "vm", "vms", "qemu", "虚拟机":
+			cmd.guestType = GuestTypeQEMU
+		case "lxc", "ct", "容器":
+			cmd.guestType = GuestTypeLXC
+		case "on", "in", "的":
+		case "node", "节点", "tag", "标签":
+			if i+1 >= len(fields) {
+				return guestCommand{}, true, errors.New(guestCommandUsage)
+			}
+			i++
+			if f == "node" || f == "节点" {
+				cmd.node = fields[i]
+			} else {
+				cmd.tag = fields[i]
+			}
+		default:
+			vmid, err := strconv.Atoi(f)
+			if err != nil || vmid <= 0 {
+				return guestCommand{}, true, fmt.Errorf("无法识别“%s”。\n%s", f, guestCommandUsage)
+			}
+			cmd.vmids = append(cmd.vmids, vmid)
+		}
+	}
+	switch {
+	case cmd.all && len(cmd.vmids) > 0:
+		return guestCommand{}, true, errors.New("all 与 VMID 不能同时使用。\n" + guestCommandUsage)
+	case !cmd.all && len(cmd.vmids) == 0:
+		return guestCommand{}, true, errors.New(guestCommandUsage)
+	case !cmd.all && (cmd.status != "" || cmd.node != "" || cmd.tag != ""):
+		return guestCommand{}, true, errors.New("状态/节点/标签筛选需配合 all 使用。\n" + guestCommandUsage)
+	}
+	return cmd, true, nil
+}
+
+// matches 判断目标是否满足筛选条件；未指定状态时按动作取有意义的目标（启动选已停止，其余选运行中）。
+func (c guestCommand) matches(res ClusterResource) bool {
+	if c.guestType != "" && GuestType(strings.TrimSpace(res.Type)) != c.guestType {
+		return false
+	}
+	status := c.status
+	if status == "" {
+		status = "running"
+		if c.action == core.ActionPVEStart {
+			status = "stopped"
+		}
+	}
+	if !strings.EqualFold(strings.TrimSpace(res.Status), status) {
+		return false
+	}
+	if c.node != "" && !strings.EqualFold(strings.TrimSpace(res.Node), c.node) {
+		return false
+	}
+	if c.tag != "" {
+		found := false
+		for _, t := range res.TagList() {
+			if strings.EqualFold(t, c.tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// handleGuestCommand 解析指令目标并进入确认：显式 VMID 需全部存在且有权限；筛选模式仅保留有权限的目标。
+func (p *Provider) handleGuestCommand(ctx context.Context, userID string, ins Instance, state core.ConversationState, cmd guestCommand) error {
+	list, err := ins.Client.ListClusterResources(ctx, "vm")
+	if err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "查询失败：" + err.Error()})
+	}
+
+	var targets []ClusterResource
+	if cmd.all {
+		for _, res := range list {
+			guestType := GuestType(strings.TrimSpace(res.Type))
+			if !guestType.IsValid() || !cmd.matches(res) {
+				continue
+			}
+			if p.allowedGuest(userID, ins, guestType, cmd.action, res) {
+				targets = append(targets, res)
+			}
+		}
+		if len(targets) == 0 {
+			return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "没有符合条件且有权限的目标。"})
+		}
+	} else {
+		for _, vmid := range cmd.vmids {
+			res, ok := findGuestInList(list, cmd.guestType, vmid)
+			if !ok {
+				return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: fmt.Sprintf("未找到 VMID %d，请确认后重试。", vmid)})
+			}
+			guestType := GuestType(strings.TrimSpace(res.Type))
+			if !p.allowedGuest(userID, ins, guestType, cmd.action, res) {
+				return p.sendGuestForbidden(ctx, userID, guestType, res)
+			}
+			targets = append(targets, res)
+		}
+	}
+	if len(targets) > core.MaxBatchTargets {
+		return p.wecom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: fmt.Sprintf("命中 %d 个目标，超过单次上限 %d，请缩小范围（如指定节点/标签）。", len(targets), core.MaxBatchTargets),
+		})
+	}
+	sort.SliceStable(targets, func(i, j int) bool { return targets[i].VMID < targets[j].VMID })
+
+	state.Action = cmd.action
+	if len(targets) == 1 {
+		return p.prepareConfirm(ctx, userID, state, ins, GuestType(strings.TrimSpace(targets[0].Type)), targets[0])
+	}
+	return p.prepareBatchConfirm(ctx, userID, state, targets)
+}
+
+func (p *Provider) prepareBatchConfirm(ctx context.Context, userID string, state core.ConversationState, targets []ClusterResource) error {
+	state.ServiceKey = p.Key()
+	state.Step = core.StepAwaitingConfirm
+	state.PVEGuestType = ""
+	state.PVEGuestID = 0
+	state.PVENode = ""
+	state.PVEGuestName = ""
+	state.PVEGuestTags = nil
+	state.PVEGuests = make([]core.PVEGuestRef, 0, len(targets))
+	names := make([]string, 0, len(targets))
+	for _, res := range targets {
+		guestType := GuestType(strings.TrimSpace(res.Type))
+		state.PVEGuests = append(state.PVEGuests, core.PVEGuestRef{
+			Type: guestType.String(),
+			VMID: res.VMID,
+			Node: strings.TrimSpace(res.Node),
+			Name: strings.TrimSpace(res.Name),
+			Tags: res.TagList(),
+		})
+		names = append(names, formatGuestTarget(guestType, res.VMID, res.Node, res.Name))
 	}
+	p.state.Set(userID, state)
+
+	_ = p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: core.FormatBatchConfirm(state.Action, names)})
 	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
 		ToUser: userID,
-		Card:   wecom.NewConfirmCard(state.Action.DisplayName(), target),
+		Card:   wecom.NewBatchConfirmCard(state.Action.DisplayName(), names),
 	})
 }
 
+// Commands 声明电源与概览一次性命令；多实例时以 @<实例ID> 指定实例。
+// 电源命令的具体权限（pve.vm.*/pve.lxc.*）按目标类型与作用范围在 handleGuestCommand 中复核。
+func (p *Provider) Commands() []core.Command {
+	instanceArg := core.CommandArg{Name: "instance", Prefix: "@", Optional: true}
+	power := func(verb string, action core.Action) core.Command {
+		return core.Command{
+			Path:    "pve " + verb,
+			Summary: action.DisplayName() + " VM/LXC（可多个VMID）",
+			Args: []core.CommandArg{
+				{Name: "type", Type: core.ArgChoice, Choices: []string{"vm", "lxc"}, Optional: true},
+				{Name: "vmid", Type: core.ArgInt, Variadic: true},
+				instanceArg,
+			},
+			Handler: func(ctx context.Context, userID string, args core.CommandArgs) error {
+				ins, err := p.commandInstance(userID, args.String("instance"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				cmd := guestCommand{action: action, vmids: args.Ints("vmid")}
+				switch args.String("type") {
+				case "vm":
+					cmd.guestType = GuestTypeQEMU
+				case "lxc":
+					cmd.guestType = GuestTypeLXC
+				}
+				state := core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID}
+				return p.handleGuestCommand(ctx, userID, ins, state, cmd)
+			},
+		}
+	}
+	return []core.Command{
+		power("start", core.ActionPVEStart),
+		power("shutdown", core.ActionPVEShutdown),
+		power("reboot", core.ActionPVEReboot),
+		power("stop", core.ActionPVEStop),
+		{
+			Path:    "pve overview",
+			Summary: "查看节点与 VM/LXC 概览",
+			Args:    []core.CommandArg{instanceArg},
+			Handler: func(ctx context.Context, userID string, args core.CommandArgs) error {
+				ins, err := p.commandInstance(userID, args.String("instance"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
+				return p.sendOverview(ctx, userID, ins)
+			},
+		},
+	}
+}
+
+// commandInstance 解析命令中的 @实例；省略时要求当前账号仅能访问一个实例。
+func (p *Provider) commandInstance(userID, id string) (Instance, error) {
+	if id != "" {
+		ins, ok := p.instances[id]
+		if !ok {
+			return Instance{}, fmt.Errorf("未知 PVE 实例：%s", id)
+		}
+		if !p.allowed(userID, core.ViewPermission(p.Key()), core.Resource{InstanceID: ins.ID}) {
+			return Instance{}, fmt.Errorf("无权限：当前账号未被授权访问 PVE 实例 %s。", id)
+		}
+		return ins, nil
+	}
+	visible := p.visibleInstances(userID)
+	switch len(visible) {
+	case 0:
+		return Instance{}, errors.New("无权限：当前账号未被授权访问任何 PVE 实例。")
+	case 1:
+		return visible[0], nil
+	}
+	ids := make([]string, 0, len(visible))
+	for _, ins := range visible {
+		ids = append(ids, ins.ID)
+	}
+	return Instance{}, fmt.Errorf("存在多个 PVE 实例，请在命令末尾以 @<实例ID> 指定：%s", strings.Join(ids, "、"))
+}
+
 func (p *Provider) sendActionMenu(ctx context.Context, userID string, ins Instance) error {
 	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
 		ToUser: userID,
@@ -640,15 +1192,87 @@ func (p *Provider) sendAlertStatus(ctx context.Context, userID string, ins Insta
 	return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: b.String()})
 }
 
-func (p *Provider) instanceFromState(state core.ConversationState) (Instance, bool) {
+// instanceFromState 返回会话中的实例（单实例时可省略），并要求用户对该实例具备查看权限。
+func (p *Provider) instanceFromState(userID string, state core.ConversationState) (Instance, bool) {
+	var ins Instance
 	if strings.TrimSpace(state.InstanceID) == "" {
-		if len(p.order) == 1 {
-			return p.order[0], true
+		if len(p.order) != 1 {
+			return Instance{}, false
+		}
+		ins = p.order[0]
+	} else {
+		var ok bool
+		if ins, ok = p.instances[state.InstanceID]; !ok {
+			return Instance{}, false
 		}
+	}
+	if !p.allowed(userID, core.ViewPermission(p.Key()), core.Resource{InstanceID: ins.ID}) {
 		return Instance{}, false
 	}
-	ins, ok := p.instances[state.InstanceID]
-	return ins, ok
+	return ins, true
+}
+
+// visibleInstances 返回用户有查看权限的实例（按 ID 排序）。
+func (p *Provider) visibleInstances(userID string) []Instance {
+	var out []Instance
+	for _, ins := range p.order {
+		if p.allowed(userID, core.ViewPermission(p.Key()), core.Resource{InstanceID: ins.ID}) {
+			out = append(out, ins)
+		}
+	}
+	return out
+}
+
+// allowed 校验用户对对象的权限；未注入授权器时不限制。
+func (p *Provider) allowed(userID, perm string, res core.Resource) bool {
+	return p.auth == nil || p.auth.CanAccess(userID, perm, res)
+}
+
+// allowedGuest 校验用户能否对 VM/LXC 执行当前动作（按实例、VMID 区间与标签作用范围）。
+func (p *Provider) allowedGuest(userID string, ins Instance, guestType GuestType, action core.Action, res ClusterResource) bool {
+	perm := core.ViewPermission(p.Key())
+	if guestAction, ok := coreActionToGuestAction(action); ok {
+		perm = guestActionPermission(guestType, guestAction)
+	}
+	return p.allowed(userID, perm, core.Resource{InstanceID: ins.ID, VMID: res.VMID, Tags: res.TagList()})
+}
+
+func (p *Provider) sendGuestForbidden(ctx context.Context, userID string, guestType GuestType, res ClusterResource) error {
+	return p.wecom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: fmt.Sprintf("无权限：%s %d 不在当前账号的授权范围内。", strings.ToUpper(guestType.String()), res.VMID),
+	})
+}
+
+func formatGuestTarget(guestType GuestType, vmid int, node string, name string) string {
+	if n := strings.TrimSpace(name); n != "" {
+		return fmt.Sprintf("%s %d（%s | %s）", strings.ToUpper(guestType.String()), vmid, node, n)
+	}
+	return fmt.Sprintf("%s %d（%s）", strings.ToUpper(guestType.String()), vmid, node)
+}
+
+// guestActionPermission 返回电源操作权限串：QEMU 为 pve.vm.<action>，LXC 为 pve.lxc.<action>。
+func guestActionPermission(guestType GuestType, action GuestAction) string {
+	kind := "vm"
+	if guestType == GuestTypeLXC {
+		kind = "lxc"
+	}
+	return core.ServicePermission("pve", kind, string(action))
+}
+
+func guestActionToCoreAction(a GuestAction) core.Action {
+	switch a {
+	case GuestActionStart:
+		return core.ActionPVEStart
+	case GuestActionShutdown:
+		return core.ActionPVEShutdown
+	case GuestActionReboot:
+		return core.ActionPVEReboot
+	case GuestActionStop:
+		return core.ActionPVEStop
+	default:
+		return ""
+	}
 }
 
 func coreActionToGuestAction(a core.Action) (GuestAction, bool) {
@@ -674,8 +1298,14 @@ func findGuestByVMID(ctx context.Context, c *Client, guestType GuestType, vmid i
 	if err != nil {
 		return ClusterResource{}, false
 	}
+	return findGuestInList(list, guestType, vmid)
+}
+
+// findGuestInList 在资源列表中查找 VMID；guestType 为空时不限类型（PVE 集群内 VMID 唯一）。
+func findGuestInList(list []ClusterResource, guestType GuestType, vmid int) (ClusterResource, bool) {
 	for _, r := range list {
-		if GuestType(strings.TrimSpace(r.Type)) != guestType {
+		t := GuestType(strings.TrimSpace(r.Type))
+		if !t.IsValid() || (guestType != "" && t != guestType) {
 			continue
 		}
 		if r.VMID == vmid {
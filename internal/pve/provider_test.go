@@ -112,7 +112,7 @@ func TestProvider_VMRebootFlowByVMID(t *testing.T) {
 	}
 
 	wc := &recordWeCom{}
-	store := core.NewStateStore(5 * time.Minute)
+	store := core.NewMemoryStateStore(5 * time.Minute)
 	t.Cleanup(store.Close)
 
 	p := NewProvider(ProviderDeps{
@@ -167,3 +167,463 @@ func TestProvider_VMRebootFlowByVMID(t *testing.T) {
 	}
 }
 
+func TestProvider_ScopedGuestAccess(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var rebootPaths []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api2/json/cluster/resources":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []map[string]interface{}{
+					{"type": "qemu", "vmid": 100, "name": "media-vm", "node": "node1"},
+					{"type": "qemu", "vmid": 101, "name": "media-db", "node": "node1"},
+					{"type": "qemu", "vmid": 205, "name": "media-box", "node": "node1", "tags": "kids;media"},
+				},
+			})
+			return
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/status/reboot"):
+			mu.Lock()
+			rebootPaths = append(rebootPaths, r.URL.Path)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": "UPID:node1:00000000:00000000:00000000:qmreboot:100:root@pam:"})
+			return
+		case r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/tasks/"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"status": "stopped", "exitstatus": "OK"},
+			})
+			return
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(ClientConfig{BaseURL: srv.URL, APIToken: "PVEAPIToken=x"}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	auth, err := core.NewAuthorizer(core.AuthorizerConfig{
+		Bindings: []core.RoleBinding{{
+			Subjects: []string{"kid"},
+			Role:     core.RoleOperator,
+			Scope:    &core.Scope{Instances: []string{"home"}, VMIDs: []core.VMIDRange{{Min: 100, Max: 100}}, Tags: []string{"kids"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+
+	wc := &recordWeCom{}
+	store := core.NewMemoryStateStore(5 * time.Minute)
+	t.Cleanup(store.Close)
+
+	p := NewProvider(ProviderDeps{
+		WeCom: wc,
+		State: store,
+		Auth:  auth,
+		Instances: []Instance{
+			{ID: "home", Name: "Home", Client: client},
+			{ID: "lab", Name: "Lab", Client: client},
+		},
+	})
+
+	ctx := context.Background()
+	userID := "kid"
+
+	// 仅 home 可见：直接进入动作菜单，无需选择实例。
+	if err := p.OnEnter(ctx, userID); err != nil {
+		t.Fatalf("OnEnter() error: %v", err)
+	}
+	st, ok := store.Get(userID)
+	if !ok || st.InstanceID != "home" {
+		t.Fatalf("state instance = %q, want %q", st.InstanceID, "home")
+	}
+
+	if handled, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: wecom.EventKeyPVEVMReboot}); err != nil || !handled {
+		t.Fatalf("HandleEvent(Reboot) handled=%v err=%v", handled, err)
+	}
+
+	// 101 不在授权范围内。
+	if _, err := p.HandleText(ctx, userID, "101"); err != nil {
+		t.Fatalf("HandleText(101) error: %v", err)
+	}
+	texts := wc.Texts()
+	if len(texts) == 0 || !strings.Contains(texts[len(texts)-1].Content, "无权限") {
+		t.Fatalf("last text = %v, want forbidden", texts)
+	}
+
+	// 关键词仅命中授权范围内的目标（100 按 VMID，205 按标签）。
+	if _, err := p.HandleText(ctx, userID, "media"); err != nil {
+		t.Fatalf("HandleText(media) error: %v", err)
+	}
+	cards := wc.Cards()
+	_, buttons, ok := wecom.RenderButtonInteractionTextMenu(cards[len(cards)-1].Card)
+	if !ok || len(buttons) != 3 || !strings.HasSuffix(buttons[0].Key, ".100.node1") || !strings.HasSuffix(buttons[1].Key, ".205.node1") {
+		t.Fatalf("keyword hits buttons = %v, want 100/205 + 返回菜单", buttons)
+	}
+	if handled, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: buttons[1].Key}); err != nil || !handled {
+		t.Fatalf("HandleEvent(select) handled=%v err=%v", handled, err)
+	}
+	if handled, err := p.HandleConfirm(ctx, userID); err != nil || !handled {
+		t.Fatalf("HandleConfirm() handled=%v err=%v", handled, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(rebootPaths) != 1 || !strings.Contains(rebootPaths[0], "/qemu/205/") {
+		t.Fatalf("reboot paths = %v, want only 205", rebootPaths)
+	}
+}
+
+func TestProvider_ActionSpec(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var shutdownPaths []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api2/json/cluster/resources":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []map[string]interface{}{
+					{"type": "qemu", "vmid": 101, "name": "media-db", "node": "node1", "tags": "media"},
+					{"type": "lxc", "vmid": 200, "name": "dns", "node": "node2"},
+				},
+			})
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/status/shutdown"):
+			mu.Lock()
+			shutdownPaths = append(shutdownPaths, r.URL.Path)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": "UPID:node1:00000000:00000000:00000000:qmshutdown:101:root@pam:"})
+		case r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/tasks/"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"status": "stopped", "exitstatus": "OK"},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(ClientConfig{BaseURL: srv.URL, APIToken: "PVEAPIToken=x"}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	p := NewProvider(ProviderDeps{
+		WeCom: &recordWeCom{},
+		State: core.NewMemoryStateStore(time.Minute),
+		Instances: []Instance{
+			{ID: "home", Name: "Home", Client: client},
+			{ID: "lab", Name: "Lab", Client: client},
+		},
+	})
+
+	tests := []struct {
+		in   string
+		ok   bool
+		want core.ActionSpec
+	}{
+		{"关机 VM 101", true, core.ActionSpec{ServiceKey: "pve", Action: core.ActionPVEShutdown, Target: "101", GuestType: "qemu"}},
+		{"启动 lxc200 @lab", true, core.ActionSpec{ServiceKey: "pve", InstanceID: "lab", Action: core.ActionPVEStart, Target: "200", GuestType: "lxc"}},
+		{"reboot vm 101 home", true, core.ActionSpec{ServiceKey: "pve", InstanceID: "home", Action: core.ActionPVEReboot, Target: "101", GuestType: "qemu"}},
+		{"重启 jellyfin", false, core.ActionSpec{}},
+		{"关机 VM abc", false, core.ActionSpec{}},
+	}
+	for _, tc := range tests {
+		spec, ok, err := p.ParseActionSpec(tc.in)
+		if err != nil || ok != tc.ok || spec != tc.want {
+			t.Fatalf("ParseActionSpec(%q) = %+v ok=%v err=%v; want %+v ok=%v", tc.in, spec, ok, err, tc.want, tc.ok)
+		}
+	}
+	if _, _, err := p.ParseActionSpec("关机 VM 101 @nas"); err == nil {
+		t.Fatalf("ParseActionSpec(unknown instance) error = nil, want not nil")
+	}
+
+	ctx := context.Background()
+	spec := core.ActionSpec{ServiceKey: "pve", Action: core.ActionPVEShutdown, Target: "101", GuestType: "qemu"}
+	if _, err := p.BuildAction(ctx, spec); err == nil || !strings.Contains(err.Error(), "多个 PVE 实例") {
+		t.Fatalf("BuildAction(no instance) error = %v, want multi-instance error", err)
+	}
+	spec.InstanceID = "home"
+	action, err := p.BuildAction(ctx, spec)
+	if err != nil {
+		t.Fatalf("BuildAction() error: %v", err)
+	}
+	if action.Permission != "pve.vm.shutdown" || action.Resource.VMID != 101 || action.Resource.InstanceID != "home" || len(action.Resource.Tags) != 1 {
+		t.Fatalf("BuildAction() = %+v, want pve.vm.shutdown on home/101 tagged media", action)
+	}
+	if _, err := action.Run(ctx, func(string) {}); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(shutdownPaths) != 1 || shutdownPaths[0] != "/api2/json/nodes/node1/qemu/101/status/shutdown" {
+		t.Fatalf("shutdown paths = %v", shutdownPaths)
+	}
+
+	spec.Target = "999"
+	if _, err := p.BuildAction(ctx, spec); err == nil {
+		t.Fatalf("BuildAction(missing guest) error = nil, want not nil")
+	}
+}
+
+func TestParseGuestCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		isCmd   bool
+		wantErr bool
+		want    guestCommand
+	}{
+		{"关机 101 102", true, false, guestCommand{action: core.ActionPVEShutdown, vmids: []int{101, 102}}},
+		{"reboot lxc 200,201", true, false, guestCommand{action: core.ActionPVEReboot, guestType: GuestTypeLXC, vmids: []int{200, 201}}},
+		{"shutdown all running LXC on node pve2", true, false, guestCommand{action: core.ActionPVEShutdown, guestType: GuestTypeLXC, all: true, status: "running", node: "pve2"}},
+		{"启动 全部 虚拟机 标签 media", true, false, guestCommand{action: core.ActionPVEStart, guestType: GuestTypeQEMU, all: true, tag: "media"}},
+		{"关机 all 101", true, true, guestCommand{}},
+		{"关机 running lxc", true, true, guestCommand{}},
+		{"关机 jellyfin", true, true, guestCommand{}},
+		{"关机", false, false, guestCommand{}},
+		{"概览 101", false, false, guestCommand{}},
+	}
+	for _, tc := range tests {
+		got, isCmd, err := parseGuestCommand(tc.in)
+		if isCmd != tc.isCmd || (err != nil) != tc.wantErr {
+			t.Fatalf("parseGuestCommand(%q) isCmd=%v err=%v; want isCmd=%v wantErr=%v", tc.in, isCmd, err, tc.isCmd, tc.wantErr)
+		}
+		if err == nil && isCmd {
+			if got.action != tc.want.action || got.guestType != tc.want.guestType || got.all != tc.want.all ||
+				got.status != tc.want.status || got.node != tc.want.node || got.tag != tc.want.tag ||
+				len(got.vmids) != len(tc.want.vmids) {
+				t.Fatalf("parseGuestCommand(%q) = %+v, want %+v", tc.in, got, tc.want)
+			}
+			for i := range got.vmids {
+				if got.vmids[i] != tc.want.vmids[i] {
+					t.Fatalf("parseGuestCommand(%q) vmids = %v, want %v", tc.in, got.vmids, tc.want.vmids)
+				}
+			}
+		}
+	}
+}
+
+func TestProvider_BatchShutdownBySelector(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var shutdownPaths []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api2/json/cluster/resources":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []map[string]interface{}{
+					{"type": "lxc", "vmid": 201, "name": "dns", "node": "pve2", "status": "running"},
+					{"type": "lxc", "vmid": 200, "name": "proxy", "node": "pve2", "status": "running"},
+					{"type": "lxc", "vmid": 202, "name": "old", "node": "pve2", "status": "stopped"},
+					{"type": "lxc", "vmid": 203, "name": "other", "node": "pve1", "status": "running"},
+					{"type": "qemu", "vmid": 101, "name": "win", "node": "pve2", "status": "running"},
+				},
+			})
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/status/shutdown"):
+			mu.Lock()
+			shutdownPaths = append(shutdownPaths, r.URL.Path)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": "UPID:pve2:00000000:00000000:00000000:vzshutdown:200:root@pam:"})
+		case r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/tasks/"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"status": "stopped", "exitstatus": "OK"},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(ClientConfig{BaseURL: srv.URL, APIToken: "PVEAPIToken=x"}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{
+		WeCom:     wc,
+		State:     state,
+		Instances: []Instance{{ID: "home", Name: "Home", Client: client}},
+	})
+
+	ctx := context.Background()
+	const userID = "u1"
+	state.Set(userID, core.ConversationState{ServiceKey: "pve", InstanceID: "home"})
+
+	if handled, err := p.HandleText(ctx, userID, "shutdown all running LXC on node pve2"); err != nil || !handled {
+		t.Fatalf("HandleText() handled=%v err=%v", handled, err)
+	}
+	texts := wc.Texts()
+	if len(texts) != 1 || !strings.Contains(texts[0].Content, "共 2 项") ||
+		!strings.Contains(texts[0].Content, "1. LXC 200") || !strings.Contains(texts[0].Content, "2. LXC 201") {
+		t.Fatalf("batch confirm text = %+v, want LXC 200 and 201", texts)
+	}
+	if cards := wc.Cards(); len(cards) != 1 {
+		t.Fatalf("cards = %d, want 1", len(cards))
+	}
+
+	action, handled, err := p.PrepareConfirm(ctx, userID)
+	if err != nil || !handled {
+		t.Fatalf("PrepareConfirm() handled=%v err=%v", handled, err)
+	}
+	if len(action.Items) != 2 || action.Permission != "pve.lxc.shutdown" || action.Items[1].Resource.VMID != 201 {
+		t.Fatalf("PrepareConfirm() = %+v, want 2-item pve.lxc.shutdown batch", action)
+	}
+	result, err := action.Run(ctx, func(string) {})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if !strings.Contains(result, "成功 2，失败 0") {
+		t.Fatalf("Run() = %q, want aggregated report", result)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(shutdownPaths) != 2 {
+		t.Fatalf("shutdown paths = %v, want 2", shutdownPaths)
+	}
+}
+
+func TestProvider_GuestQueryFuzzy(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/api2/json/cluster/resources" {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []map[string]interface{}{
+					{"type": "qemu", "vmid": 100, "name": "家庭影院", "node": "node1"},
+					{"type": "qemu", "vmid": 101, "name": "home-assistant", "node": "node1"},
+					{"type": "qemu", "vmid": 102, "name": "homebridge", "node": "node1"},
+					{"type": "lxc", "vmid": 200, "name": "home-proxy", "node": "node1"},
+				},
+			})
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(ClientConfig{BaseURL: srv.URL, APIToken: "PVEAPIToken=x"}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{
+		WeCom:     wc,
+		State:     state,
+		Instances: []Instance{{ID: "home", Name: "Home", Client: client}},
+	})
+
+	ctx := context.Background()
+	const userID = "u1"
+	query := func(content string) core.ConversationState {
+		t.Helper()
+		state.Set(userID, core.ConversationState{
+			ServiceKey:   "pve",
+			InstanceID:   "home",
+			Step:         core.StepAwaitingPVEGuestQuery,
+			Action:       core.Action(GuestActionReboot),
+			PVEGuestType: GuestTypeQEMU.String(),
+		})
+		if handled, err := p.HandleText(ctx, userID, content); err != nil || !handled {
+			t.Fatalf("HandleText(%q) handled=%v err=%v", content, handled, err)
+		}
+		st, _ := state.Get(userID)
+		return st
+	}
+
+	// 拼音首字母与拼写错误均可唯一命中，直接进入确认。
+	if st := query("jtyy"); st.PVEGuestID != 100 {
+		t.Fatalf("jtyy -> vmid %d, want 100", st.PVEGuestID)
+	}
+	if st := query("homeasistant"); st.PVEGuestID != 101 {
+		t.Fatalf("homeasistant -> vmid %d, want 101", st.PVEGuestID)
+	}
+
+	// 多个同样相近的目标：按相近程度列出候选（LXC 不参与 VM 匹配）。
+	if st := query("home"); st.PVEGuestID != 0 {
+		t.Fatalf("home -> vmid %d, want selection card", st.PVEGuestID)
+	}
+	cards := wc.Cards()
+	_, buttons, ok := wecom.RenderButtonInteractionTextMenu(cards[len(cards)-1].Card)
+	if !ok || len(buttons) != 3 || !strings.HasSuffix(buttons[0].Key, ".102.node1") || !strings.HasSuffix(buttons[1].Key, ".101.node1") {
+		t.Fatalf("home buttons = %v, want 102/101 + 返回菜单", buttons)
+	}
+
+	query("nextcloud")
+	texts := wc.Texts()
+	if len(texts) == 0 || !strings.Contains(texts[len(texts)-1].Content, "未找到目标") {
+		t.Fatalf("last text = %v, want not found", texts)
+	}
+}
+
+func TestProvider_Favorite(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/api2/json/cluster/resources" {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []map[string]interface{}{
+					{"type": "qemu", "vmid": 101, "name": "home-assistant", "node": "node1"},
+				},
+			})
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(ClientConfig{BaseURL: srv.URL, APIToken: "PVEAPIToken=x"}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{
+		WeCom:     wc,
+		State:     state,
+		Instances: []Instance{{ID: "home", Name: "Home", Client: client}},
+	})
+
+	ctx := context.Background()
+	const userID = "u1"
+	fav, err := p.ResolveFavorite(ctx, userID, core.Favorite{ID: "f1", ServiceKey: "pve", InstanceID: "home", Target: "qemu:101"})
+	if err != nil || fav.Name != "QEMU 101 home-assistant" {
+		t.Fatalf("ResolveFavorite() = %+v, %v", fav, err)
+	}
+	if _, err := p.ResolveFavorite(ctx, userID, core.Favorite{ServiceKey: "pve", InstanceID: "home", Target: "lxc:101"}); err == nil {
+		t.Fatalf("ResolveFavorite(lxc:101) error = nil, want not found")
+	}
+
+	// 从常用打开目标操作卡片，再点击电源按钮进入确认。
+	if err := p.OpenFavorite(ctx, userID, fav); err != nil {
+		t.Fatalf("OpenFavorite() error: %v", err)
+	}
+	cards := wc.Cards()
+	_, buttons, ok := wecom.RenderButtonInteractionTextMenu(cards[len(cards)-1].Card)
+	if !ok || buttons[len(buttons)-1].Key != wecom.EventKeyFavoriteRemovePrefix+"f1" {
+		t.Fatalf("guest action buttons = %+v, want 取消收藏 last", buttons)
+	}
+	key := wecom.EventKeyPVEGuestActionPrefix + "qemu.reboot"
+	if perm := p.EventPermission(key); perm != "pve.vm.reboot" {
+		t.Fatalf("EventPermission(%q) = %q", key, perm)
+	}
+	if handled, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: key}); err != nil || !handled {
+		t.Fatalf("HandleEvent(%q) handled=%v err=%v", key, handled, err)
+	}
+	st, _ := state.Get(userID)
+	if st.Step != core.StepAwaitingConfirm || st.Action != core.ActionPVEReboot || st.PVEGuestID != 101 {
+		t.Fatalf("state = %+v, want reboot 101 awaiting confirm", st)
+	}
+}
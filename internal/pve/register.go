@@ -0,0 +1,88 @@
+package pve
+
+// register.go 向服务注册表登记 PVE 后端（配置段 pve），并托管告警轮询的启停。
+import (
+	"context"
+	"fmt"
+
+	"github.com/zcw199604/wecom-home-ops/internal/config"
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+	"github.com/zcw199604/wecom-home-ops/internal/registry"
+)
+
+func init() {
+	registry.Register(registry.Factory{
+		Key:         "pve",
+		DisplayName: "PVE",
+		Hint:        "pve.instances",
+		Order:       30,
+		Enabled: func(cfg config.Config) bool {
+			return len(cfg.PVE.Instances) > 0
+		},
+		New: newService,
+	})
+}
+
+func newService(deps registry.Deps) (registry.Service, error) {
+	cfg := deps.Config.PVE
+	var instances []Instance
+	for _, ins := range cfg.Instances {
+		client, err := NewClient(ClientConfig{
+			BaseURL:            ins.BaseURL,
+			APIToken:           ins.APIToken,
+			InsecureSkipVerify: ins.InsecureSkipVerify,
+		}, deps.HTTPClient)
+		if err != nil {
+			return registry.Service{}, err
+		}
+		instances = append(instances, Instance{
+			ID:     ins.ID,
+			Name:   ins.Name,
+			Client: client,
+		})
+	}
+
+	alertCfg := AlertConfig{
+		Enabled: cfg.Alert.Enabled != nil && *cfg.Alert.Enabled,
+
+		Interval: cfg.Alert.Interval.ToDuration(),
+		Cooldown: cfg.Alert.Cooldown.ToDuration(),
+		MuteFor:  cfg.Alert.MuteFor.ToDuration(),
+
+		CPUUsageThreshold:     cfg.Alert.CPUUsageThreshold,
+		MemUsageThreshold:     cfg.Alert.MemUsageThreshold,
+		StorageUsageThreshold: cfg.Alert.StorageUsageThreshold,
+	}
+	alerts := NewAlertManager(AlertManagerDeps{
+		WeCom: deps.Notifier,
+		Recipients: func() []string {
+			if deps.Auth == nil {
+				return nil
+			}
+			return deps.Auth.UsersWith(core.ServicePermission("pve", "alert"))
+		},
+		Instances: instances,
+		Config:    alertCfg,
+	})
+
+	return registry.Service{
+		Provider: NewProvider(ProviderDeps{
+			WeCom:       deps.WeCom,
+			State:       deps.State,
+			Instances:   instances,
+			Auth:        deps.ResourceAuthorizer(),
+			AlertConfig: alertCfg,
+			Alerts:      alerts,
+		}),
+		Health: func(ctx context.Context) error {
+			for _, ins := range instances {
+				if _, err := ins.Client.GetVersion(ctx); err != nil {
+					return fmt.Errorf("%s: %w", ins.ID, err)
+				}
+			}
+			return nil
+		},
+		Start: alerts.Start,
+		Close: alerts.Close,
+	}, nil
+}
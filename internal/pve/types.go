@@ -2,6 +2,7 @@
 package pve
 
 // types.go 定义 PVE API 常用数据结构（按实际使用字段裁剪）。
+import "strings"
 
 type VersionInfo struct {
 	Release string `json:"release"`
@@ -34,6 +35,16 @@ type ClusterResource struct {
 
 	// Storage 标识符（type=storage 时可用）
 	Storage string `json:"storage"`
+
+	// Tags 为 VM/LXC 标签（PVE 7.3+，以 ; 分隔）。
+	Tags string `json:"tags"`
+}
+
+// TagList 将 Tags 拆分为标签列表（兼容 ; , 空格 分隔）。
+func (r ClusterResource) TagList() []string {
+	return strings.FieldsFunc(r.Tags, func(c rune) bool {
+		return c == ';' || c == ',' || c == ' '
+	})
 }
 
 type TaskStatus struct {
@@ -5,9 +5,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/zcw199604/wecom-home-ops/internal/core"
 	"github.com/zcw199604/wecom-home-ops/internal/wecom"
@@ -21,13 +21,16 @@ type Instance struct {
 
 type ProviderDeps struct {
 	WeCom     core.WeComSender
-	State     *core.StateStore
+	State     core.StateStore
 	Instances []Instance
+	// Auth 可选：按实例作用范围过滤实例列表与校验动作；为空时不限制。
+	Auth core.ResourceAuthorizer
 }
 
 type Provider struct {
 	wecom     core.WeComSender
-	state     *core.StateStore
+	state     core.StateStore
+	auth      core.ResourceAuthorizer
 	instances map[string]Instance
 	order     []Instance
 }
@@ -49,6 +52,7 @@ func NewProvider(deps ProviderDeps) *Provider {
 	return &Provider{
 		wecom:     deps.WeCom,
 		state:     deps.State,
+		auth:      deps.Auth,
 		instances: instances,
 		order:     order,
 	}
@@ -58,6 +62,17 @@ func (p *Provider) Key() string { return "qinglong" }
 
 func (p *Provider) DisplayName() string { return "青龙(QL)" }
 
+// MenuButton 声明应用自定义菜单中的“青龙”一级菜单。
+func (p *Provider) MenuButton() wecom.MenuButton {
+	return wecom.MenuButton{
+		Name: "青龙",
+		SubButtons: []wecom.MenuButton{
+			{Type: "click", Name: "进入青龙", Key: wecom.EventKeyServiceSelectPrefix + p.Key()},
+			{Type: "click", Name: "动作菜单", Key: wecom.EventKeyQinglongMenu},
+		},
+	}
+}
+
 func (p *Provider) EntryKeywords() []string {
 	return []string{"青龙", "ql", "qinglong"}
 }
@@ -70,10 +85,18 @@ func (p *Provider) OnEnter(ctx context.Context, userID string) error {
 		})
 	}
 
+	visible := p.visibleInstances(userID)
+	if len(visible) == 0 {
+		return p.wecom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: "无权限：当前账号未被授权访问任何青龙实例。",
+		})
+	}
+
 	p.state.Set(userID, core.ConversationState{ServiceKey: p.Key()})
 
-	if len(p.order) == 1 {
-		ins := p.order[0]
+	if len(visible) == 1 {
+		ins := visible[0]
 		p.state.Set(userID, core.ConversationState{
 			ServiceKey: p.Key(),
 			InstanceID: ins.ID,
@@ -85,7 +108,7 @@ func (p *Provider) OnEnter(ctx context.Context, userID string) error {
 	}
 
 	var opts []wecom.QinglongInstanceOption
-	for _, ins := range p.order {
+	for _, ins := range visible {
 		opts = append(opts, wecom.QinglongInstanceOption{ID: ins.ID, Name: ins.Name})
 	}
 	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
@@ -114,6 +137,11 @@ func (p *Provider) HandleText(ctx context.Context, userID, content string) (bool
 		return true, p.OnEnter(ctx, userID)
 	}
 
+	// 直接输入“运行 12 15 18”等指令：一次确认多个任务。
+	if action, ids, ok := parseCronCommand(content); ok {
+		return true, p.confirmCrons(ctx, userID, state, ins, action, ids)
+	}
+
 	switch state.Step {
 	case core.StepAwaitingQinglongSearchKeyword:
 		kw := strings.TrimSpace(content)
@@ -126,6 +154,12 @@ func (p *Provider) HandleText(ctx context.Context, userID, content string) (bool
 		return true, p.sendCronListBySearch(ctx, userID, ins, kw)
 
 	case core.StepAwaitingQinglongCronID:
+		if len(core.SplitTargets(content)) > 1 {
+			return true, p.wecom.SendText(ctx, wecom.TextMessage{
+				ToUser:  userID,
+				Content: "批量操作请发送：运行/启用/禁用 + 多个任务ID，例如“运行 12 15 18”。",
+			})
+		}
 		id, err := strconv.Atoi(strings.TrimSpace(content))
 		if err != nil || id <= 0 {
 			return true, p.wecom.SendText(ctx, wecom.TextMessage{
@@ -145,13 +179,27 @@ func (p *Provider) HandleText(ctx context.Context, userID, content string) (bool
 		p.state.Set(userID, state)
 		return true, p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
 			ToUser: userID,
-			Card:   wecom.NewQinglongCronActionCard(ins.Name, cron.ID, cron.Name),
+			Card:   wecom.NewQinglongCronActionCard(ins.Name, cron.ID, cron.Name, wecom.FavoriteAddButton(p.Key(), ins.ID, strconv.Itoa(cron.ID))),
 		})
 	default:
 		return false, nil
 	}
 }
 
+// EventPermission 声明任务操作按钮所需权限（qinglong.run/enable/disable），其余事件沿用 qinglong.view。
+func (p *Provider) EventPermission(eventKey string) string {
+	switch eventKey {
+	case wecom.EventKeyQinglongCronRun:
+		return core.ServicePermission(p.Key(), string(core.ActionQinglongRun))
+	case wecom.EventKeyQinglongCronEnable:
+		return core.ServicePermission(p.Key(), string(core.ActionQinglongEnable))
+	case wecom.EventKeyQinglongCronDisable:
+		return core.ServicePermission(p.Key(), string(core.ActionQinglongDisable))
+	default:
+		return ""
+	}
+}
+
 func (p *Provider) HandleEvent(ctx context.Context, userID string, msg wecom.IncomingMessage) (bool, error) {
 	key := strings.TrimSpace(msg.EventKey)
 	if key == "" {
@@ -166,7 +214,7 @@ func (p *Provider) HandleEvent(ctx context.Context, userID string, msg wecom.Inc
 		insID := strings.TrimPrefix(key, wecom.EventKeyQinglongInstanceSelectPrefix)
 		insID = strings.TrimSpace(insID)
 		ins, ok := p.instances[insID]
-		if !ok {
+		if !ok || !p.allowed(userID, core.ViewPermission(p.Key()), ins.ID) {
 			return true, p.wecom.SendText(ctx, wecom.TextMessage{
 				ToUser:  userID,
 				Content: "实例不存在或不可用，请重新选择。",
@@ -237,7 +285,7 @@ func (p *Provider) HandleEvent(ctx context.Context, userID string, msg wecom.Inc
 		p.state.Set(userID, state)
 		return true, p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
 			ToUser: userID,
-			Card:   wecom.NewQinglongCronActionCard(ins.Name, cron.ID, cron.Name),
+			Card:   wecom.NewQinglongCronActionCard(ins.Name, cron.ID, cron.Name, wecom.FavoriteAddButton(p.Key(), ins.ID, strconv.Itoa(cron.ID))),
 		})
 	}
 
@@ -273,50 +321,280 @@ func (p *Provider) HandleEvent(ctx context.Context, userID string, msg wecom.Inc
 }
 
 func (p *Provider) HandleConfirm(ctx context.Context, userID string) (bool, error) {
+	action, ok, err := p.PrepareConfirm(ctx, userID)
+	if err != nil || !ok {
+		return ok, err
+	}
+	return true, core.RunConfirmedAction(ctx, p.wecom, userID, action)
+}
+
+// PrepareConfirm 校验并清理待确认状态，返回可在后台执行的任务操作。
+func (p *Provider) PrepareConfirm(ctx context.Context, userID string) (core.ConfirmedAction, bool, error) {
 	state, ok := p.state.Get(userID)
 	if !ok || state.ServiceKey != p.Key() || state.Step != core.StepAwaitingConfirm {
-		return false, nil
+		return core.ConfirmedAction{}, false, nil
 	}
 	ins, ok := p.instances[state.InstanceID]
 	if !ok {
-		return true, p.OnEnter(ctx, userID)
+		return core.ConfirmedAction{}, true, p.OnEnter(ctx, userID)
 	}
-	if state.CronID <= 0 {
-		return true, errors.New("缺少任务ID")
+	if state.CronID <= 0 && len(state.CronIDs) == 0 {
+		return core.ConfirmedAction{}, true, errors.New("缺少任务ID")
 	}
 
 	// 清除“待确认动作”避免重复确认，但保留实例/任务选择提升可用性。
 	state.Step = ""
 	action := state.Action
 	state.Action = ""
+	batch := state.CronIDs
+	state.CronIDs = nil
 	p.state.Set(userID, state)
 
-	start := time.Now()
-	var err error
+	var run func(ctx context.Context, ids []int) error
 	switch action {
 	case core.ActionQinglongRun:
-		err = ins.Client.RunCrons(ctx, []int{state.CronID})
+		run = ins.Client.RunCrons
 	case core.ActionQinglongEnable:
-		err = ins.Client.EnableCrons(ctx, []int{state.CronID})
+		run = ins.Client.EnableCrons
 	case core.ActionQinglongDisable:
-		err = ins.Client.DisableCrons(ctx, []int{state.CronID})
+		run = ins.Client.DisableCrons
 	default:
-		return false, nil
+		return core.ConfirmedAction{}, false, nil
+	}
+
+	if len(batch) > 0 {
+		items := make([]core.ConfirmedAction, 0, len(batch))
+		for _, id := range batch {
+			items = append(items, cronAction(ins, action, id, run))
+		}
+		return core.NewBatchAction(action, items), true, nil
+	}
+	return cronAction(ins, action, state.CronID, run), true, nil
+}
+
+func cronAction(ins Instance, action core.Action, cronID int, run func(ctx context.Context, ids []int) error) core.ConfirmedAction {
+	return core.ConfirmedAction{
+		ServiceKey: "qinglong",
+		InstanceID: ins.ID,
+		Action:     action,
+		Target:     fmt.Sprintf("任务ID %d", cronID),
+		Resource:   core.Resource{InstanceID: ins.ID},
+		Run: func(ctx context.Context, _ core.ProgressFunc) (string, error) {
+			if err := run(ctx, []int{cronID}); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s 任务ID %d", action.DisplayName(), cronID), nil
+		},
+	}
+}
+
+// actionSpecPattern 匹配“运行青龙任务 12”“run ql 12 @home”等短语。
+var actionSpecPattern = regexp.MustCompile(`(?i)^(?:运行|执行|run)\s*(?:青龙|ql|qinglong)\s*(?:任务)?\s*(\d+)(?:\s+@?(\S+))?$`)
+
+// ParseActionSpec 解析运行青龙任务的短语；多实例时可在末尾以 @<实例ID> 指定实例。
+func (p *Provider) ParseActionSpec(text string) (core.ActionSpec, bool, error) {
+	m := actionSpecPattern.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return core.ActionSpec{}, false, nil
+	}
+	if id := m[2]; id != "" {
+		if _, ok := p.instances[id]; !ok {
+			return core.ActionSpec{}, false, fmt.Errorf("未知青龙实例：%s", id)
+		}
+	}
+	return core.ActionSpec{ServiceKey: p.Key(), InstanceID: m[2], Action: core.ActionQinglongRun, Target: m[1]}, true, nil
+}
+
+// BuildAction 校验任务仍然存在后构造运行操作。
+func (p *Provider) BuildAction(ctx context.Context, spec core.ActionSpec) (core.ConfirmedAction, error) {
+	var ins Instance
+	switch {
+	case spec.InstanceID != "":
+		var ok bool
+		if ins, ok = p.instances[spec.InstanceID]; !ok {
+			return core.ConfirmedAction{}, fmt.Errorf("青龙实例 %s 不存在", spec.InstanceID)
+		}
+	case len(p.order) == 1:
+		ins = p.order[0]
+	default:
+		return core.ConfirmedAction{}, errors.New("存在多个青龙实例，请在末尾指定实例，例如“运行青龙任务 12 @home”")
+	}
+	if spec.Action != core.ActionQinglongRun {
+		return core.ConfirmedAction{}, fmt.Errorf("不支持定时执行的动作：%s", spec.Action)
+	}
+	cronID, err := strconv.Atoi(spec.Target)
+	if err != nil || cronID <= 0 {
+		return core.ConfirmedAction{}, fmt.Errorf("任务ID 不合法：%s", spec.Target)
+	}
+	if _, err := ins.Client.GetCron(ctx, cronID); err != nil {
+		return core.ConfirmedAction{}, fmt.Errorf("%s 中任务ID %d 不可用：%w", ins.Name, cronID, err)
+	}
+	return cronAction(ins, spec.Action, cronID, ins.Client.RunCrons), nil
+}
+
+// Commands 声明任务一次性命令；多实例时以 @<实例ID> 指定实例。
+func (p *Provider) Commands() []core.Command {
+	instanceArg := core.CommandArg{Name: "instance", Prefix: "@", Optional: true}
+	op := func(verb string, action core.Action) core.Command {
+		return core.Command{
+			Path:       "ql " + verb,
+			Aliases:    []string{"qinglong " + verb},
+			Summary:    action.DisplayName() + "任务（可多个ID）",
+			Args:       []core.CommandArg{{Name: "id", Type: core.ArgInt, Variadic: true}, instanceArg},
+			Permission: core.ServicePermission(p.Key(), string(action)),
+			Handler: func(ctx context.Context, userID string, args core.CommandArgs) error {
+				ins, err := p.commandInstance(userID, args.String("instance"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				state := core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID}
+				return p.confirmCrons(ctx, userID, state, ins, action, args.Ints("id"))
+			},
+		}
+	}
+	return []core.Command{
+		op("run", core.ActionQinglongRun),
+		op("enable", core.ActionQinglongEnable),
+		op("disable", core.ActionQinglongDisable),
+		{
+			Path:    "ql search",
+			Aliases: []string{"qinglong search"},
+			Summary: "按关键词搜索任务",
+			Args:    []core.CommandArg{{Name: "keyword", Variadic: true}, instanceArg},
+			Handler: func(ctx context.Context, userID string, args core.CommandArgs) error {
+				ins, err := p.commandInstance(userID, args.String("instance"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
+				return p.sendCronListBySearch(ctx, userID, ins, strings.Join(args.Strings("keyword"), " "))
+			},
+		},
+		{
+			Path:    "ql log",
+			Aliases: []string{"qinglong log"},
+			Summary: "查看任务最近日志；附关键词时在完整日志中检索，仅返回匹配行（" + core.LogGrepUsage + "）",
+			Args: []core.CommandArg{
+				{Name: "id", Type: core.ArgInt},
+				{Name: "keyword", Optional: true, Variadic: true, Verbatim: true},
+				instanceArg,
+			},
+			Handler: func(ctx context.Context, userID string, args core.CommandArgs) error {
+				ins, err := p.commandInstance(userID, args.String("instance"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				var grep *core.LogGrepOptions
+				if args.Has("keyword") {
+					opts, err := core.ParseLogGrepArgs(args.Strings("keyword"))
+					if err != nil {
+						return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "检索条件不合法：" + err.Error()})
+					}
+					grep = &opts
+				}
+				id := args.Int("id")
+				p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID, CronID: id})
+				logText, err := ins.Client.GetCronLog(ctx, id)
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{
+						ToUser:  userID,
+						Content: fmt.Sprintf("获取日志失败：%s", err.Error()),
+					})
+				}
+				if grep != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: formatLogGrepForWeCom(id, logText, *grep)})
+				}
+				return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: formatLogForWeCom(id, logText)})
+			},
+		},
 	}
-	cost := time.Since(start).Milliseconds()
+}
 
+// commandInstance 解析命令中的 @实例；省略时要求当前账号仅能访问一个实例。
+func (p *Provider) commandInstance(userID, id string) (Instance, error) {
+	if id != "" {
+		ins, ok := p.instances[id]
+		if !ok {
+			return Instance{}, fmt.Errorf("未知青龙实例：%s", id)
+		}
+		if !p.allowed(userID, core.ViewPermission(p.Key()), ins.ID) {
+			return Instance{}, fmt.Errorf("无权限：当前账号未被授权访问青龙实例 %s。", id)
+		}
+		return ins, nil
+	}
+	visible := p.visibleInstances(userID)
+	switch len(visible) {
+	case 0:
+		return Instance{}, errors.New("无权限：当前账号未被授权访问任何青龙实例。")
+	case 1:
+		return visible[0], nil
+	}
+	ids := make([]string, 0, len(visible))
+	for _, ins := range visible {
+		ids = append(ids, ins.ID)
+	}
+	return Instance{}, fmt.Errorf("存在多个青龙实例，请在命令末尾以 @<实例ID> 指定：%s", strings.Join(ids, "、"))
+}
+
+// visibleInstances 返回用户有查看权限的实例（保持配置顺序）。
+func (p *Provider) visibleInstances(userID string) []Instance {
+	var out []Instance
+	for _, ins := range p.order {
+		if p.allowed(userID, core.ViewPermission(p.Key()), ins.ID) {
+			out = append(out, ins)
+		}
+	}
+	return out
+}
+
+// ResolveFavorite 校验收藏的任务（目标为任务 ID）在实例中存在且实例在用户作用范围内，并补全名称。
+func (p *Provider) ResolveFavorite(ctx context.Context, userID string, fav core.Favorite) (core.Favorite, error) {
+	ins, cron, err := p.lookupFavorite(ctx, userID, fav)
 	if err != nil {
-		_ = p.wecom.SendText(ctx, wecom.TextMessage{
-			ToUser:  userID,
-			Content: fmt.Sprintf("执行失败（%dms）：%s", cost, err.Error()),
-		})
-		return true, nil
+		return fav, err
 	}
-	_ = p.wecom.SendText(ctx, wecom.TextMessage{
-		ToUser:  userID,
-		Content: fmt.Sprintf("执行成功（%dms）：%s 任务ID %d", cost, action.DisplayName(), state.CronID),
+	fav.Name = strings.TrimSpace(cron.Name)
+	if fav.Name == "" {
+		fav.Name = fmt.Sprintf("任务 %d", cron.ID)
+	}
+	if len(p.order) > 1 {
+		fav.Name = ins.Name + " " + fav.Name
+	}
+	return fav, nil
+}
+
+// OpenFavorite 以收藏的任务为当前任务下发任务操作卡片。
+func (p *Provider) OpenFavorite(ctx context.Context, userID string, fav core.Favorite) error {
+	ins, cron, err := p.lookupFavorite(ctx, userID, fav)
+	if err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+	}
+	p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID, CronID: cron.ID})
+	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card:   wecom.NewQinglongCronActionCard(ins.Name, cron.ID, cron.Name, wecom.FavoriteRemoveButton(fav.ID)),
 	})
-	return true, nil
+}
+
+func (p *Provider) lookupFavorite(ctx context.Context, userID string, fav core.Favorite) (Instance, Cron, error) {
+	ins, ok := p.instances[fav.InstanceID]
+	if !ok || !p.allowed(userID, core.ViewPermission(p.Key()), ins.ID) {
+		return Instance{}, Cron{}, fmt.Errorf("实例不可用：%s", fav.InstanceID)
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(fav.Target))
+	if err != nil || id <= 0 {
+		return Instance{}, Cron{}, fmt.Errorf("任务ID不合法：%s", fav.Target)
+	}
+	cron, err := ins.Client.GetCron(ctx, id)
+	if err != nil {
+		return Instance{}, Cron{}, fmt.Errorf("获取任务失败：%s", err.Error())
+	}
+	return ins, cron, nil
+}
+
+// allowed 校验用户对实例的权限；未注入授权器时不限制。
+func (p *Provider) allowed(userID, perm, instanceID string) bool {
+	return p.auth == nil || p.auth.CanAccess(userID, perm, core.Resource{InstanceID: instanceID})
 }
 
 func (p *Provider) sendActionMenu(ctx context.Context, userID string) error {
@@ -343,7 +621,63 @@ func (p *Provider) sendCronListBySearch(ctx context.Context, userID string, ins
 		state.Step = ""
 		p.state.Set(userID, state)
 	}
-	return p.sendCronList(ctx, userID, ins, keyword, "搜索结果")
+
+	// 先用服务端搜索（同时匹配名称与命令），再按名称模糊排序；服务端无结果时退回本地匹配全部任务，
+	// 以容忍拼写错误与拼音首字母输入。
+	page, err := ins.Client.ListCrons(ctx, ListCronsParams{SearchValue: keyword, Page: 1, Size: 20})
+	if err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: fmt.Sprintf("获取任务列表失败：%s", err.Error()),
+		})
+	}
+	crons := page.Data
+	if len(crons) == 0 {
+		all, err := ins.Client.ListCrons(ctx, ListCronsParams{Page: 1, Size: 200})
+		if err != nil {
+			return p.wecom.SendText(ctx, wecom.TextMessage{
+				ToUser:  userID,
+				Content: fmt.Sprintf("获取任务列表失败：%s", err.Error()),
+			})
+		}
+		crons = all.Data
+	}
+
+	byKey := make(map[string]Cron, len(crons))
+	cands := make([]core.FuzzyCandidate, 0, len(crons))
+	for _, cron := range crons {
+		key := strconv.Itoa(cron.ID)
+		byKey[key] = cron
+		cands = append(cands, core.FuzzyCandidate{Key: key, Name: cron.Name})
+	}
+	matches, unique := core.ResolveFuzzy(keyword, cands)
+	if len(matches) == 0 {
+		if len(page.Data) == 0 {
+			return p.wecom.SendText(ctx, wecom.TextMessage{
+				ToUser:  userID,
+				Content: "未找到任务，请更换关键词重试。",
+			})
+		}
+		// 仅命中命令等非名称字段：沿用服务端顺序。
+		return p.sendCronOptions(ctx, userID, ins, page.Data, "搜索结果")
+	}
+	if unique {
+		cron := byKey[matches[0].Key]
+		if ok && state.ServiceKey == p.Key() {
+			state.CronID = cron.ID
+			p.state.Set(userID, state)
+		}
+		return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+			ToUser: userID,
+			Card:   wecom.NewQinglongCronActionCard(ins.Name, cron.ID, cron.Name, wecom.FavoriteAddButton(p.Key(), ins.ID, strconv.Itoa(cron.ID))),
+		})
+	}
+
+	ranked := make([]Cron, 0, len(matches))
+	for _, m := range matches {
+		ranked = append(ranked, byKey[m.Key])
+	}
+	return p.sendCronOptions(ctx, userID, ins, ranked, "搜索结果")
 }
 
 func (p *Provider) sendCronList(ctx context.Context, userID string, ins Instance, keyword string, title string) error {
@@ -369,10 +703,13 @@ func (p *Provider) sendCronList(ctx context.Context, userID string, ins Instance
 		})
 	}
 
-	const maxButtons = 4
+	return p.sendCronOptions(ctx, userID, ins, page.Data, title)
+}
+
+func (p *Provider) sendCronOptions(ctx context.Context, userID string, ins Instance, crons []Cron, title string) error {
 	var opts []wecom.QinglongCronOption
-	for i, cron := range page.Data {
-		if i >= maxButtons {
+	for i, cron := range crons {
+		if i >= core.MaxFuzzyChoices {
 			break
 		}
 		opts = append(opts, wecom.QinglongCronOption{
@@ -394,8 +731,15 @@ func (p *Provider) prepareConfirm(ctx context.Context, userID string, state core
 			Content: "请先选择任务。",
 		})
 	}
+	if !p.allowed(userID, core.ServicePermission(p.Key(), string(action)), state.InstanceID) {
+		return true, p.wecom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: fmt.Sprintf("无权限：当前账号不能在该实例上%s任务。", action.DisplayName()),
+		})
+	}
 	state.Step = core.StepAwaitingConfirm
 	state.Action = action
+	state.CronIDs = nil
 	p.state.Set(userID, state)
 
 	target := fmt.Sprintf("任务ID %d", state.CronID)
@@ -405,6 +749,85 @@ func (p *Provider) prepareConfirm(ctx context.Context, userID string, state core
 	})
 }
 
+// cronCommandVerbs 为任务指令中可识别的动作。
+var cronCommandVerbs = map[string]core.Action{
+	"运行": core.ActionQinglongRun, "执行": core.ActionQinglongRun, "run": core.ActionQinglongRun,
+	"启用": core.ActionQinglongEnable, "enable": core.ActionQinglongEnable,
+	"禁用": core.ActionQinglongDisable, "disable": core.ActionQinglongDisable,
+}
+
+// parseCronCommand 解析“运行 12 15 18”“禁用 3,4”等指令（动作 + 至少一个任务ID）。
+func parseCronCommand(text string) (core.Action, []int, bool) {
+	fields := core.SplitTargets(text)
+	if len(fields) < 2 {
+		return "", nil, false
+	}
+	action, ok := cronCommandVerbs[strings.ToLower(fields[0])]
+	if !ok {
+		return "", nil, false
+	}
+	ids := make([]int, 0, len(fields)-1)
+	for _, f := range fields[1:] {
+		id, err := strconv.Atoi(f)
+		if err != nil || id <= 0 {
+			return "", nil, false
+		}
+		ids = append(ids, id)
+	}
+	return action, ids, true
+}
+
+// confirmCrons 校验任务均存在且有权限后进入待确认状态；多个任务合并为一次批量确认。
+func (p *Provider) confirmCrons(ctx context.Context, userID string, state core.ConversationState, ins Instance, action core.Action, ids []int) error {
+	if len(ids) > core.MaxBatchTargets {
+		return p.wecom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: fmt.Sprintf("一次最多操作 %d 个任务，当前 %d 个，请分批执行。", core.MaxBatchTargets, len(ids)),
+		})
+	}
+	if !p.allowed(userID, core.ServicePermission(p.Key(), string(action)), ins.ID) {
+		return p.wecom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: fmt.Sprintf("无权限：当前账号不能在该实例上%s任务。", action.DisplayName()),
+		})
+	}
+
+	targets := make([]string, 0, len(ids))
+	for _, id := range ids {
+		cron, err := ins.Client.GetCron(ctx, id)
+		if err != nil {
+			return p.wecom.SendText(ctx, wecom.TextMessage{
+				ToUser:  userID,
+				Content: fmt.Sprintf("获取任务 %d 失败：%s", id, err.Error()),
+			})
+		}
+		targets = append(targets, formatCronButtonText(cron.ID, cron.Name))
+	}
+
+	state.Step = core.StepAwaitingConfirm
+	state.Action = action
+	if len(ids) == 1 {
+		state.CronID = ids[0]
+		state.CronIDs = nil
+		p.state.Set(userID, state)
+		return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+			ToUser: userID,
+			Card:   wecom.NewConfirmCard(action.DisplayName(), fmt.Sprintf("任务ID %d", ids[0])),
+		})
+	}
+
+	state.CronIDs = ids
+	p.state.Set(userID, state)
+	_ = p.wecom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: core.FormatBatchConfirm(action, targets),
+	})
+	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card:   wecom.NewBatchConfirmCard(action.DisplayName(), targets),
+	})
+}
+
 func formatCronButtonText(id int, name string) string {
 	text := strings.TrimSpace(name)
 	if text == "" {
@@ -423,6 +846,21 @@ func formatLogForWeCom(cronID int, logText string) string {
 	return fmt.Sprintf("任务ID %d 最近日志：\n%s", cronID, content)
 }
 
+// logGrepMaxBytes 为日志检索结果的消息长度上限（企业微信文本消息上限 2048 字节）。
+const logGrepMaxBytes = 1800
+
+// formatLogGrepForWeCom 在任务完整日志中检索，仅返回匹配行与匹配计数。
+func formatLogGrepForWeCom(cronID int, logText string, opts core.LogGrepOptions) string {
+	if strings.TrimSpace(logText) == "" {
+		return fmt.Sprintf("任务ID %d 日志为空。", cronID)
+	}
+	res, err := core.GrepLog(logText, opts)
+	if err != nil {
+		return "检索条件不合法：" + err.Error()
+	}
+	return core.FormatLogGrep(fmt.Sprintf("任务ID %d 日志检索：", cronID), opts, res, logGrepMaxBytes)
+}
+
 func truncateRunes(s string, max int) string {
 	if max <= 0 {
 		return ""
@@ -58,7 +58,7 @@ func TestProvider_OnEnter_NoInstances(t *testing.T) {
 	t.Parallel()
 
 	rec := &recordWeCom{}
-	store := core.NewStateStore(1 * time.Minute)
+	store := core.NewMemoryStateStore(1 * time.Minute)
 	t.Cleanup(store.Close)
 
 	p := NewProvider(ProviderDeps{
@@ -184,7 +184,7 @@ func TestProvider_OnEnter_MultiInstance_SelectAndMenu(t *testing.T) {
 	}
 
 	rec := &recordWeCom{}
-	store := core.NewStateStore(1 * time.Minute)
+	store := core.NewMemoryStateStore(1 * time.Minute)
 	t.Cleanup(store.Close)
 
 	p := NewProvider(ProviderDeps{
@@ -343,7 +343,7 @@ func TestProvider_SearchAndByIDInputs(t *testing.T) {
 	}
 
 	rec := &recordWeCom{}
-	store := core.NewStateStore(1 * time.Minute)
+	store := core.NewMemoryStateStore(1 * time.Minute)
 	t.Cleanup(store.Close)
 
 	p := NewProvider(ProviderDeps{
@@ -440,7 +440,7 @@ func TestProvider_ListEmpty_ShowsText(t *testing.T) {
 	}
 
 	rec := &recordWeCom{}
-	store := core.NewStateStore(1 * time.Minute)
+	store := core.NewMemoryStateStore(1 * time.Minute)
 	t.Cleanup(store.Close)
 
 	p := NewProvider(ProviderDeps{
@@ -507,7 +507,7 @@ func TestProvider_SearchNoResult_ShowsText(t *testing.T) {
 	}
 
 	rec := &recordWeCom{}
-	store := core.NewStateStore(1 * time.Minute)
+	store := core.NewMemoryStateStore(1 * time.Minute)
 	t.Cleanup(store.Close)
 
 	p := NewProvider(ProviderDeps{
@@ -596,7 +596,7 @@ func TestProvider_LogEmptyAndTruncated(t *testing.T) {
 	}
 
 	rec := &recordWeCom{}
-	store := core.NewStateStore(1 * time.Minute)
+	store := core.NewMemoryStateStore(1 * time.Minute)
 	t.Cleanup(store.Close)
 
 	p := NewProvider(ProviderDeps{
@@ -691,7 +691,7 @@ func TestProvider_EnableDisable_ConfirmFlow(t *testing.T) {
 	}
 
 	rec := &recordWeCom{}
-	store := core.NewStateStore(1 * time.Minute)
+	store := core.NewMemoryStateStore(1 * time.Minute)
 	t.Cleanup(store.Close)
 
 	p := NewProvider(ProviderDeps{
@@ -768,7 +768,7 @@ func TestProvider_MenuAndSwitchInstance_Navigation(t *testing.T) {
 	}
 
 	rec := &recordWeCom{}
-	store := core.NewStateStore(1 * time.Minute)
+	store := core.NewMemoryStateStore(1 * time.Minute)
 	t.Cleanup(store.Close)
 
 	p := NewProvider(ProviderDeps{
@@ -835,3 +835,410 @@ func TestProvider_MenuAndSwitchInstance_Navigation(t *testing.T) {
 		t.Fatalf("card title = %q, want %q", title, "青龙(QL)")
 	}
 }
+
+func TestProvider_ActionSpec(t *testing.T) {
+	t.Parallel()
+
+	var runHits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/open/auth/token":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"code": 200,
+				"data": map[string]interface{}{"token": "AT", "token_type": "Bearer", "expiration": time.Now().Add(time.Hour).Unix()},
+			})
+		case r.URL.Path == "/open/crons/run" && r.Method == http.MethodPut:
+			atomic.AddInt32(&runHits, 1)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 200})
+		case r.URL.Path == "/open/crons/12" && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "data": map[string]interface{}{"id": 12, "name": "sign"}})
+		case strings.HasPrefix(r.URL.Path, "/open/crons/"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 400, "message": "not found"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(ClientConfig{BaseURL: srv.URL, ClientID: "id", ClientSecret: "sec"}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	p := NewProvider(ProviderDeps{
+		WeCom:     &recordWeCom{},
+		State:     core.NewMemoryStateStore(time.Minute),
+		Instances: []Instance{{ID: "home", Name: "Home", Client: client}},
+	})
+
+	for in, want := range map[string]bool{
+		"运行青龙任务 12":       true,
+		"run ql 12 @home": true,
+		"执行 青龙 12":        true,
+		"运行 jellyfin":     false,
+	} {
+		spec, ok, err := p.ParseActionSpec(in)
+		if err != nil || ok != want || (ok && (spec.Target != "12" || spec.Action != core.ActionQinglongRun)) {
+			t.Fatalf("ParseActionSpec(%q) = %+v ok=%v err=%v; want ok=%v", in, spec, ok, err, want)
+		}
+	}
+	if _, _, err := p.ParseActionSpec("运行青龙任务 12 @lab"); err == nil {
+		t.Fatalf("ParseActionSpec(unknown instance) error = nil, want not nil")
+	}
+
+	ctx := context.Background()
+	action, err := p.BuildAction(ctx, core.ActionSpec{Action: core.ActionQinglongRun, Target: "12"})
+	if err != nil {
+		t.Fatalf("BuildAction() error: %v", err)
+	}
+	if action.InstanceID != "home" || action.Target != "任务ID 12" {
+		t.Fatalf("BuildAction() = %+v, want home/任务ID 12", action)
+	}
+	if _, err := action.Run(ctx, func(string) {}); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if got := atomic.LoadInt32(&runHits); got != 1 {
+		t.Fatalf("run hits = %d, want 1", got)
+	}
+	if _, err := p.BuildAction(ctx, core.ActionSpec{Action: core.ActionQinglongRun, Target: "99"}); err == nil {
+		t.Fatalf("BuildAction(missing cron) error = nil, want not nil")
+	}
+}
+
+func TestProvider_BatchRun(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var runIDs []int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/open/auth/token":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"code": 200,
+				"data": map[string]interface{}{"token": "AT", "token_type": "Bearer", "expiration": time.Now().Add(time.Hour).Unix()},
+			})
+		case strings.HasPrefix(r.URL.Path, "/open/crons/") && r.Method == http.MethodGet:
+			id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/open/crons/"))
+			if id == 99 {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 400, "message": "not found"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "data": map[string]interface{}{"id": id, "name": "job" + strconv.Itoa(id)}})
+		case r.URL.Path == "/open/crons/run" && r.Method == http.MethodPut:
+			var ids []int
+			_ = json.NewDecoder(r.Body).Decode(&ids)
+			mu.Lock()
+			runIDs = append(runIDs, ids...)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "data": true})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(ClientConfig{BaseURL: srv.URL, ClientID: "id", ClientSecret: "sec"}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	rec := &recordWeCom{}
+	store := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(store.Close)
+	p := NewProvider(ProviderDeps{
+		WeCom:     rec,
+		State:     store,
+		Instances: []Instance{{ID: "home", Name: "Home", Client: client}},
+	})
+
+	ctx := context.Background()
+	userID := "u"
+	if err := p.OnEnter(ctx, userID); err != nil {
+		t.Fatalf("OnEnter() error: %v", err)
+	}
+
+	if ok, err := p.HandleText(ctx, userID, "运行 12 99"); err != nil || !ok {
+		t.Fatalf("HandleText(missing id) ok=%v err=%v", ok, err)
+	}
+	if msg, _ := rec.LastText(); !strings.Contains(msg.Content, "获取任务 99 失败") {
+		t.Fatalf("LastText() = %q, want missing-task error", msg.Content)
+	}
+
+	if ok, err := p.HandleText(ctx, userID, "运行 12 15，18"); err != nil || !ok {
+		t.Fatalf("HandleText() ok=%v err=%v", ok, err)
+	}
+	if msg, _ := rec.LastText(); !strings.Contains(msg.Content, "共 3 项") || !strings.Contains(msg.Content, "15: job15") {
+		t.Fatalf("LastText() = %q, want batch confirm listing", msg.Content)
+	}
+
+	action, ok, err := p.PrepareConfirm(ctx, userID)
+	if err != nil || !ok || len(action.Items) != 3 || action.InstanceID != "home" {
+		t.Fatalf("PrepareConfirm() = %+v ok=%v err=%v", action, ok, err)
+	}
+	result, err := action.Run(ctx, func(string) {})
+	if err != nil || !strings.Contains(result, "成功 3，失败 0") {
+		t.Fatalf("Run() = %q, %v", result, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(runIDs) != 3 {
+		t.Fatalf("run ids = %v, want 3", runIDs)
+	}
+	if st, _ := store.Get(userID); len(st.CronIDs) != 0 || st.Step != "" {
+		t.Fatalf("state after confirm = %+v, want batch cleared", st)
+	}
+}
+
+func TestProvider_Commands(t *testing.T) {
+	t.Parallel()
+
+	var runs int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/open/auth/token":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"code": 200,
+				"data": map[string]interface{}{"token": "AT", "token_type": "Bearer", "expiration": time.Now().Add(time.Hour).Unix()},
+			})
+		case strings.HasSuffix(r.URL.Path, "/log") && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "data": "start\nlogin ok\nCheckin Error: 403\nretry\ncheckin error: timeout\ndone\n"})
+		case strings.HasPrefix(r.URL.Path, "/open/crons/") && r.Method == http.MethodGet:
+			id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/open/crons/"))
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "data": map[string]interface{}{"id": id, "name": "job" + strconv.Itoa(id)}})
+		case r.URL.Path == "/open/crons/run" && r.Method == http.MethodPut:
+			atomic.AddInt32(&runs, 1)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "data": true})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(ClientConfig{BaseURL: srv.URL, ClientID: "id", ClientSecret: "sec"}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	rec := &recordWeCom{}
+	store := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(store.Close)
+	p := NewProvider(ProviderDeps{
+		WeCom: rec,
+		State: store,
+		Instances: []Instance{
+			{ID: "home", Name: "Home", Client: client},
+			{ID: "office", Name: "Office", Client: client},
+		},
+	})
+	r := core.NewRouter(core.RouterDeps{
+		WeCom:         rec,
+		AllowedUserID: map[string]struct{}{"u": {}},
+		Providers:     []core.ServiceProvider{p},
+		State:         store,
+	})
+
+	ctx := context.Background()
+	send := func(content string) string {
+		t.Helper()
+		if err := r.HandleMessage(ctx, wecom.IncomingMessage{FromUserName: "u", MsgType: "text", Content: content}); err != nil {
+			t.Fatalf("HandleMessage(%q) error: %v", content, err)
+		}
+		msg, _ := rec.LastText()
+		return msg.Content
+	}
+
+	if got := send("/ql run 12 15"); !strings.Contains(got, "@<实例ID>") {
+		t.Fatalf("reply = %q, want instance hint", got)
+	}
+	if got := send("/ql run 12 abc @home"); !strings.Contains(got, "参数错误") || !strings.Contains(got, "/ql run <id...> [@instance]") {
+		t.Fatalf("reply = %q, want usage", got)
+	}
+	if got := send("/qinglong run 12,15 @home"); !strings.Contains(got, "共 2 项") {
+		t.Fatalf("reply = %q, want batch confirm", got)
+	}
+	send("确认")
+	if got := atomic.LoadInt32(&runs); got != 2 {
+		t.Fatalf("run calls = %d, want 2", got)
+	}
+	if got := send("/ql log 12 checkin error -i @home"); !strings.Contains(got, "匹配 2 行（共扫描 6 行）") ||
+		!strings.Contains(got, "3:Checkin Error: 403") || !strings.HasSuffix(got, "5:checkin error: timeout") || strings.Contains(got, "login ok") {
+		t.Fatalf("reply = %q, want grep result", got)
+	}
+	if got := send("/ql log 12 -e Error:\\s(\\d+ @home"); !strings.Contains(got, "正则表达式无效") {
+		t.Fatalf("reply = %q, want invalid regex", got)
+	}
+	if got := send("/ql foo"); !strings.Contains(got, "未知命令") || !strings.Contains(got, "/ql search <keyword...> [@instance]") {
+		t.Fatalf("reply = %q, want command list", got)
+	}
+}
+
+func TestProvider_SearchFuzzy(t *testing.T) {
+	t.Parallel()
+
+	crons := []map[string]interface{}{
+		{"id": 11, "name": "京东签到"},
+		{"id": 12, "name": "京东农场"},
+		{"id": 13, "name": "backup-nas"},
+		{"id": 14, "name": "backup-db"},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/open/auth/token":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"code": 200,
+				"data": map[string]interface{}{
+					"token":      "AT",
+					"token_type": "Bearer",
+					"expiration": time.Now().Add(1 * time.Hour).Unix(),
+				},
+			})
+		case r.URL.Path == "/open/crons" && r.Method == http.MethodGet:
+			// 模拟服务端子串搜索。
+			kw := r.URL.Query().Get("searchValue")
+			var hits []map[string]interface{}
+			for _, c := range crons {
+				if strings.Contains(c["name"].(string), kw) {
+					hits = append(hits, c)
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"code": 200,
+				"data": map[string]interface{}{"data": hits, "total": len(hits)},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(ClientConfig{BaseURL: srv.URL, ClientID: "id", ClientSecret: "sec"}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	rec := &recordWeCom{}
+	store := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(store.Close)
+	p := NewProvider(ProviderDeps{
+		WeCom:     rec,
+		State:     store,
+		Instances: []Instance{{ID: "home", Name: "Home", Client: client}},
+	})
+
+	ctx := context.Background()
+	const userID = "u"
+	search := func(kw string) {
+		t.Helper()
+		store.Set(userID, core.ConversationState{ServiceKey: "qinglong", InstanceID: "home", Step: core.StepAwaitingQinglongSearchKeyword})
+		if ok, err := p.HandleText(ctx, userID, kw); err != nil || !ok {
+			t.Fatalf("HandleText(%q) ok=%v err=%v", kw, ok, err)
+		}
+	}
+
+	// 服务端无结果：本地按拼音首字母唯一命中，直接进入任务操作并记录任务。
+	search("jdqd")
+	if st, _ := store.Get(userID); st.CronID != 11 {
+		t.Fatalf("jdqd -> cron %d, want 11", st.CronID)
+	}
+
+	// 拼写错误同样可唯一命中。
+	search("backup-naz")
+	if st, _ := store.Get(userID); st.CronID != 13 {
+		t.Fatalf("backup-naz -> cron %d, want 13", st.CronID)
+	}
+
+	// 多个同样相近：列出候选，不自动选定。
+	search("backup")
+	if st, _ := store.Get(userID); st.CronID != 0 {
+		t.Fatalf("backup -> cron %d, want list", st.CronID)
+	}
+	card, ok := rec.LastCard()
+	if !ok {
+		t.Fatalf("want search result card")
+	}
+	_, buttons, ok := wecom.RenderButtonInteractionTextMenu(card.Card)
+	if !ok || len(buttons) < 2 || !strings.HasSuffix(buttons[0].Key, "14") || !strings.HasSuffix(buttons[1].Key, "13") {
+		t.Fatalf("backup buttons = %v, want 14 then 13", buttons)
+	}
+}
+
+func TestProvider_Favorite(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/open/auth/token":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"code": 200,
+				"data": map[string]interface{}{"token": "AT", "token_type": "Bearer", "expiration": time.Now().Add(time.Hour).Unix()},
+			})
+		case r.URL.Path == "/open/crons/12" && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "data": map[string]interface{}{"id": 12, "name": "签到"}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(ClientConfig{BaseURL: srv.URL, ClientID: "id", ClientSecret: "sec"}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	auth, err := core.NewAuthorizer(core.AuthorizerConfig{
+		Bindings: []core.RoleBinding{{Subjects: []string{"kid"}, Role: core.RoleOperator, Scope: &core.Scope{Instances: []string{"home"}}}},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+	rec := &recordWeCom{}
+	store := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(store.Close)
+	p := NewProvider(ProviderDeps{
+		WeCom: rec,
+		State: store,
+		Auth:  auth,
+		Instances: []Instance{
+			{ID: "home", Name: "Home", Client: client},
+			{ID: "office", Name: "Office", Client: client},
+		},
+	})
+
+	ctx := context.Background()
+	const userID = "kid"
+	fav, err := p.ResolveFavorite(ctx, userID, core.Favorite{ID: "f1", ServiceKey: "qinglong", InstanceID: "home", Target: "12"})
+	if err != nil || fav.Name != "Home 签到" {
+		t.Fatalf("ResolveFavorite() = %+v, %v", fav, err)
+	}
+	if _, err := p.ResolveFavorite(ctx, userID, core.Favorite{ServiceKey: "qinglong", InstanceID: "home", Target: "99"}); err == nil {
+		t.Fatalf("ResolveFavorite(99) error = nil, want not found")
+	}
+	if _, err := p.ResolveFavorite(ctx, userID, core.Favorite{ServiceKey: "qinglong", InstanceID: "office", Target: "12"}); err == nil ||
+		!strings.Contains(err.Error(), "实例不可用") {
+		t.Fatalf("ResolveFavorite(office) error = %v, want out of scope", err)
+	}
+
+	if err := p.OpenFavorite(ctx, userID, fav); err != nil {
+		t.Fatalf("OpenFavorite() error: %v", err)
+	}
+	card, _ := rec.LastCard()
+	_, buttons, ok := wecom.RenderButtonInteractionTextMenu(card.Card)
+	if !ok || buttons[len(buttons)-1] != wecom.FavoriteRemoveButton("f1") {
+		t.Fatalf("cron action buttons = %+v, want 取消收藏 last", buttons)
+	}
+	if st, _ := store.Get(userID); st.InstanceID != "home" || st.CronID != 12 {
+		t.Fatalf("state = %+v, want home cron 12", st)
+	}
+
+	// 越权的收藏不下发操作卡片。
+	rec.mu.Lock()
+	cardCount := len(rec.cards)
+	rec.mu.Unlock()
+	if err := p.OpenFavorite(ctx, userID, core.Favorite{ID: "f2", ServiceKey: "qinglong", InstanceID: "office", Target: "12"}); err != nil {
+		t.Fatalf("OpenFavorite(office) error: %v", err)
+	}
+	if msg, _ := rec.LastText(); !strings.Contains(msg.Content, "实例不可用") {
+		t.Fatalf("reply = %q, want out of scope", msg.Content)
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.cards) != cardCount {
+		t.Fatalf("cards = %d, want %d (no card for out-of-scope favorite)", len(rec.cards), cardCount)
+	}
+}
@@ -0,0 +1,58 @@
+package qinglong
+
+// register.go 向服务注册表登记青龙(QL)后端（配置段 qinglong）。
+import (
+	"context"
+	"fmt"
+
+	"github.com/zcw199604/wecom-home-ops/internal/config"
+	"github.com/zcw199604/wecom-home-ops/internal/registry"
+)
+
+func init() {
+	registry.Register(registry.Factory{
+		Key:         "qinglong",
+		DisplayName: "青龙(QL)",
+		Hint:        "qinglong.instances",
+		Order:       20,
+		Enabled: func(cfg config.Config) bool {
+			return len(cfg.Qinglong.Instances) > 0
+		},
+		New: newService,
+	})
+}
+
+func newService(deps registry.Deps) (registry.Service, error) {
+	var instances []Instance
+	for _, ins := range deps.Config.Qinglong.Instances {
+		client, err := NewClient(ClientConfig{
+			BaseURL:      ins.BaseURL,
+			ClientID:     ins.ClientID,
+			ClientSecret: ins.ClientSecret,
+		}, deps.HTTPClient)
+		if err != nil {
+			return registry.Service{}, err
+		}
+		instances = append(instances, Instance{
+			ID:     ins.ID,
+			Name:   ins.Name,
+			Client: client,
+		})
+	}
+	return registry.Service{
+		Provider: NewProvider(ProviderDeps{
+			WeCom:     deps.WeCom,
+			State:     deps.State,
+			Instances: instances,
+			Auth:      deps.ResourceAuthorizer(),
+		}),
+		Health: func(ctx context.Context) error {
+			for _, ins := range instances {
+				if _, err := ins.Client.ListCrons(ctx, ListCronsParams{Page: 1, Size: 1}); err != nil {
+					return fmt.Errorf("%s: %w", ins.ID, err)
+				}
+			}
+			return nil
+		},
+	}, nil
+}
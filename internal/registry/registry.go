@@ -0,0 +1,139 @@
+package registry
+
+// registry.go 为服务后端注册表：各后端包在 init 中登记启用条件、构造函数、健康检查与 EventKey 命名空间，
+// app 按配置统一装配已启用的服务，未启用的服务自动生成“未启用”提示，新增后端无需修改 core 与 app。
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/zcw199604/wecom-home-ops/internal/config"
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+	"github.com/zcw199604/wecom-home-ops/internal/store"
+)
+
+// Factory 描述一个可注册的服务后端。
+type Factory struct {
+	// Key 为服务 Key，同时是 EventKey 命名空间（“<key>.”）与权限前缀，须与 Provider.Key() 一致。
+	Key string
+	// DisplayName 用于“未启用”提示与启动日志。
+	DisplayName string
+	// Hint 为启用所需的配置项说明。
+	Hint string
+	// Order 决定服务选择卡片与应用菜单中的顺序（越小越靠前）。
+	Order int
+
+	// Enabled 判断配置是否启用该服务。
+	Enabled func(cfg config.Config) bool
+	// New 构造服务；不得启动后台任务（由 Service.Start 负责），以便命令行生成菜单时复用。
+	New func(deps Deps) (Service, error)
+}
+
+// Deps 为构造服务时可用的共享依赖。
+type Deps struct {
+	Config config.Config
+	// WeCom 为带按钮权限过滤的发送端，供会话交互使用。
+	WeCom core.WeComSender
+	// Notifier 为企业微信原始客户端，供告警等主动推送使用。
+	Notifier   core.WeComSender
+	State      core.StateStore
+	Auth       *core.Authorizer
+	HTTPClient *http.Client
+	// Store 可选：core.state_backend=file 时的共享持久化存储，服务可使用独立 bucket 落地自身数据；nil 表示仅内存。
+	Store *store.FileStore
+}
+
+// ResourceAuthorizer 返回供 Provider 使用的授权器；未注入时返回 nil（Provider 视为不限制）。
+func (d Deps) ResourceAuthorizer() core.ResourceAuthorizer {
+	if d.Auth == nil {
+		return nil
+	}
+	return d.Auth
+}
+
+// Service 为构造完成的服务。
+type Service struct {
+	Provider core.ServiceProvider
+	// Health 可选：检查后端是否可达（/readyz?services=1 调用）。
+	Health func(ctx context.Context) error
+	// Start / Close 可选：后台任务（如告警轮询）的启动与停止。
+	Start func()
+	Close func()
+}
+
+var (
+	mu        sync.Mutex
+	factories = make(map[string]Factory)
+)
+
+// Register 登记服务后端，通常在后端包的 init 中调用；Key 重复或缺少构造函数时 panic。
+func Register(f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if f.Key == "" || f.Enabled == nil || f.New == nil {
+		panic(fmt.Sprintf("registry: 服务后端 %q 缺少 Key/Enabled/New", f.Key))
+	}
+	if _, exists := factories[f.Key]; exists {
+		panic(fmt.Sprintf("registry: 服务后端 %q 重复注册", f.Key))
+	}
+	factories[f.Key] = f
+}
+
+// Factories 返回已登记的服务后端（按 Order、Key 排序）。
+func Factories() []Factory {
+	mu.Lock()
+	defer mu.Unlock()
+	out := make([]Factory, 0, len(factories))
+	for _, f := range factories {
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Order != out[j].Order {
+			return out[i].Order < out[j].Order
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+// Lookup 返回指定 Key 的服务后端。
+func Lookup(key string) (Factory, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	f, ok := factories[key]
+	return f, ok
+}
+
+// Build 按配置构造已启用的服务，并返回未启用服务的描述（供 Router 回复“未启用”提示）。
+func Build(deps Deps) ([]Service, []core.DisabledService, error) {
+	var (
+		services []Service
+		disabled []core.DisabledService
+	)
+	for _, f := range Factories() {
+		if !f.Enabled(deps.Config) {
+			disabled = append(disabled, core.DisabledService{Key: f.Key, DisplayName: f.DisplayName, Hint: f.Hint})
+			continue
+		}
+		svc, err := f.New(deps)
+		if err != nil {
+			return nil, nil, fmt.Errorf("初始化 %s 失败: %w", f.DisplayName, err)
+		}
+		if svc.Provider == nil || svc.Provider.Key() != f.Key {
+			return nil, nil, fmt.Errorf("初始化 %s 失败: Provider Key 与注册 Key %q 不一致", f.DisplayName, f.Key)
+		}
+		services = append(services, svc)
+	}
+	return services, disabled, nil
+}
+
+// Providers 返回服务对应的 Provider 列表。
+func Providers(services []Service) []core.ServiceProvider {
+	out := make([]core.ServiceProvider, 0, len(services))
+	for _, svc := range services {
+		out = append(out, svc.Provider)
+	}
+	return out
+}
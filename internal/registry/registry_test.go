@@ -0,0 +1,117 @@
+package registry
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/zcw199604/wecom-home-ops/internal/config"
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+type stubProvider struct {
+	key string
+}
+
+func (p stubProvider) Key() string             { return p.key }
+func (p stubProvider) DisplayName() string     { return p.key }
+func (p stubProvider) EntryKeywords() []string { return nil }
+
+func (p stubProvider) OnEnter(ctx context.Context, userID string) error { return nil }
+
+func (p stubProvider) HandleText(ctx context.Context, userID, content string) (bool, error) {
+	return false, nil
+}
+
+func (p stubProvider) HandleEvent(ctx context.Context, userID string, msg wecom.IncomingMessage) (bool, error) {
+	return false, nil
+}
+
+func (p stubProvider) HandleConfirm(ctx context.Context, userID string) (bool, error) {
+	return false, nil
+}
+
+// withFactories 在测试期间替换全局注册表。
+func withFactories(t *testing.T) {
+	t.Helper()
+	mu.Lock()
+	saved := factories
+	factories = make(map[string]Factory)
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		factories = saved
+		mu.Unlock()
+	})
+}
+
+func stubFactory(key string, order int, enabled bool, providerKey string) Factory {
+	return Factory{
+		Key:         key,
+		DisplayName: strings.ToUpper(key),
+		Hint:        key + ".endpoint",
+		Order:       order,
+		Enabled:     func(config.Config) bool { return enabled },
+		New: func(Deps) (Service, error) {
+			return Service{Provider: stubProvider{key: providerKey}}, nil
+		},
+	}
+}
+
+func TestBuild_OrderAndDisabled(t *testing.T) {
+	withFactories(t)
+
+	Register(stubFactory("zeta", 10, true, "zeta"))
+	Register(stubFactory("beta", 20, false, "beta"))
+	Register(stubFactory("alpha", 10, true, "alpha"))
+
+	services, disabled, err := Build(Deps{})
+	if err != nil {
+		t.Fatalf("Build() error: %v", err)
+	}
+	var keys []string
+	for _, p := range Providers(services) {
+		keys = append(keys, p.Key())
+	}
+	if got := strings.Join(keys, ","); got != "alpha,zeta" {
+		t.Fatalf("Build() providers = %q, want %q", got, "alpha,zeta")
+	}
+	want := []core.DisabledService{{Key: "beta", DisplayName: "BETA", Hint: "beta.endpoint"}}
+	if len(disabled) != 1 || disabled[0] != want[0] {
+		t.Fatalf("Build() disabled = %+v, want %+v", disabled, want)
+	}
+}
+
+func TestBuild_ProviderKeyMismatch(t *testing.T) {
+	withFactories(t)
+
+	Register(stubFactory("nas", 10, true, "unraid"))
+	if _, _, err := Build(Deps{}); err == nil || !strings.Contains(err.Error(), "不一致") {
+		t.Fatalf("Build() error = %v, want key mismatch", err)
+	}
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	withFactories(t)
+
+	Register(stubFactory("nas", 10, true, "nas"))
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Register(duplicate) did not panic")
+		}
+	}()
+	Register(stubFactory("nas", 20, true, "nas"))
+}
+
+func TestLookup(t *testing.T) {
+	withFactories(t)
+
+	Register(stubFactory("nas", 10, true, "nas"))
+	if f, ok := Lookup("nas"); !ok || f.DisplayName != "NAS" {
+		t.Fatalf("Lookup(nas) = %+v, %v", f, ok)
+	}
+	if _, ok := Lookup("pve"); ok {
+		t.Fatalf("Lookup(pve) ok = true, want false")
+	}
+}
@@ -0,0 +1,333 @@
+// Package store 提供基于本地文件（JSON Lines 追加日志）的轻量持久化 KV，用于会话状态/回调去重等需跨重启保留的数据。
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	opPut    = "put"
+	opDelete = "del"
+
+	// compactMinRecords 为触发压缩的最小日志条数，避免小文件频繁重写。
+	compactMinRecords = 256
+	// maxLineBytes 为单条记录的最大长度（超过视为损坏）。
+	maxLineBytes = 4 << 20
+)
+
+// ErrClosed 表示存储已关闭。
+var ErrClosed = errors.New("store: 已关闭")
+
+// record 为日志中的一行：put 写入/覆盖，del 删除。
+type record struct {
+	Op        string          `json:"op"`
+	Bucket    string          `json:"b"`
+	Key       string          `json:"k"`
+	Value     json.RawMessage `json:"v,omitempty"`
+	ExpiresAt time.Time       `json:"exp,omitempty"`
+}
+
+type entry struct {
+	value     json.RawMessage
+	expiresAt time.Time
+}
+
+func (e entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// FileStore 为按 bucket 分区的 KV 存储：内存中保存最新视图，所有变更先追加写入日志并 fsync。
+// 启动时回放日志（容忍末尾半行等崩溃残留），过期条目在回放/压缩时丢弃。
+type FileStore struct {
+	path string
+
+	mu      sync.Mutex
+	f       *os.File
+	data    map[string]map[string]entry
+	records int
+	closed  bool
+}
+
+// Open 打开（或创建）指定路径的存储文件并回放历史记录。
+func Open(path string) (*FileStore, error) {
+	if path == "" {
+		return nil, errors.New("store: path 不能为空")
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("store: 创建目录失败: %w", err)
+		}
+	}
+
+	s := &FileStore{
+		path: path,
+		data: make(map[string]map[string]entry),
+	}
+	if err := s.replay(); err != nil {
+		return nil, err
+	}
+	// 启动即压缩：清理过期条目与损坏的尾部记录。
+	if err := s.rewriteLocked(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Path 返回存储文件路径。
+func (s *FileStore) Path() string { return s.path }
+
+// Get 返回未过期的值。
+func (s *FileStore) Get(bucket, key string) (json.RawMessage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.data[bucket][key]
+	if !ok || e.expired(time.Now()) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// List 返回 bucket 内所有未过期的值（key -> value）。
+func (s *FileStore) List(bucket string) map[string]json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	out := make(map[string]json.RawMessage, len(s.data[bucket]))
+	for k, e := range s.data[bucket] {
+		if !e.expired(now) {
+			out[k] = e.value
+		}
+	}
+	return out
+}
+
+// Put 写入/覆盖一个值；expiresAt 为零值表示不过期。
+func (s *FileStore) Put(bucket, key string, value json.RawMessage, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.putLocked(bucket, key, value, expiresAt)
+}
+
+// PutIfAbsent 在 key 不存在（或已过期）时写入并返回 loaded=false；否则返回已有值与 loaded=true。
+func (s *FileStore) PutIfAbsent(bucket, key string, value json.RawMessage, expiresAt time.Time) (json.RawMessage, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.data[bucket][key]; ok && !e.expired(time.Now()) {
+		return e.value, true, nil
+	}
+	return nil, false, s.putLocked(bucket, key, value, expiresAt)
+}
+
+// Delete 删除一个值（不存在时为 no-op）。
+func (s *FileStore) Delete(bucket, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if _, ok := s.data[bucket][key]; !ok {
+		return nil
+	}
+	delete(s.data[bucket], key)
+	return s.appendLocked(record{Op: opDelete, Bucket: bucket, Key: key})
+}
+
+// Prune 丢弃所有过期条目；当日志中的冗余记录明显多于存活条目时顺带压缩文件。
+func (s *FileStore) Prune() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	now := time.Now()
+	for _, b := range s.data {
+		for k, e := range b {
+			if e.expired(now) {
+				delete(b, k)
+			}
+		}
+	}
+	if s.records >= compactMinRecords && s.records > 2*s.liveLocked() {
+		return s.rewriteLocked()
+	}
+	return nil
+}
+
+// Close 关闭底层文件；重复调用安全。
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.f == nil {
+		return nil
+	}
+	return s.f.Close()
+}
+
+func (s *FileStore) putLocked(bucket, key string, value json.RawMessage, expiresAt time.Time) error {
+	if s.closed {
+		return ErrClosed
+	}
+	b, ok := s.data[bucket]
+	if !ok {
+		b = make(map[string]entry)
+		s.data[bucket] = b
+	}
+	b[key] = entry{value: value, expiresAt: expiresAt}
+	if err := s.appendLocked(record{Op: opPut, Bucket: bucket, Key: key, Value: value, ExpiresAt: expiresAt}); err != nil {
+		return err
+	}
+	if s.records >= compactMinRecords && s.records > 4*s.liveLocked() {
+		return s.rewriteLocked()
+	}
+	return nil
+}
+
+func (s *FileStore) appendLocked(r record) error {
+	if s.f == nil {
+		return errors.New("store: 文件未打开")
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("store: 序列化失败: %w", err)
+	}
+	line = append(line, '\n')
+	if _, err := s.f.Write(line); err != nil {
+		return fmt.Errorf("store: 写入失败: %w", err)
+	}
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("store: fsync 失败: %w", err)
+	}
+	s.records++
+	return nil
+}
+
+func (s *FileStore) liveLocked() int {
+	n := 0
+	for _, b := range s.data {
+		n += len(b)
+	}
+	return n
+}
+
+func (s *FileStore) replay() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("store: 打开文件失败: %w", err)
+	}
+	defer f.Close()
+
+	now := time.Now()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	lineNo := 0
+	skipped := 0
+	for sc.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var r record
+		if err := json.Unmarshal(line, &r); err != nil || r.Bucket == "" || r.Key == "" {
+			// 崩溃时可能残留半行记录：跳过并在压缩时清除。
+			skipped++
+			continue
+		}
+		switch r.Op {
+		case opPut:
+			if !r.ExpiresAt.IsZero() && now.After(r.ExpiresAt) {
+				delete(s.data[r.Bucket], r.Key)
+				continue
+			}
+			b, ok := s.data[r.Bucket]
+			if !ok {
+				b = make(map[string]entry)
+				s.data[r.Bucket] = b
+			}
+			b[r.Key] = entry{value: append(json.RawMessage(nil), r.Value...), expiresAt: r.ExpiresAt}
+		case opDelete:
+			delete(s.data[r.Bucket], r.Key)
+		default:
+			skipped++
+		}
+	}
+	if err := sc.Err(); err != nil {
+		// 超长行等读取错误：保留已回放内容，剩余部分视为损坏。
+		slog.Warn("持久化存储回放中断，剩余记录已忽略", "path", s.path, "line", lineNo, "error", err)
+	}
+	if skipped > 0 {
+		slog.Warn("持久化存储存在损坏记录，已跳过", "path", s.path, "skipped", skipped)
+	}
+	return nil
+}
+
+// rewriteLocked 将当前存活条目写入临时文件后原子替换，并重新打开追加句柄。
+func (s *FileStore) rewriteLocked() error {
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("store: 创建临时文件失败: %w", err)
+	}
+
+	now := time.Now()
+	w := bufio.NewWriter(f)
+	count := 0
+	for bucket, b := range s.data {
+		for key, e := range b {
+			if e.expired(now) {
+				delete(b, key)
+				continue
+			}
+			line, err := json.Marshal(record{Op: opPut, Bucket: bucket, Key: key, Value: e.value, ExpiresAt: e.expiresAt})
+			if err != nil {
+				_ = f.Close()
+				return fmt.Errorf("store: 序列化失败: %w", err)
+			}
+			_, _ = w.Write(line)
+			_ = w.WriteByte('\n')
+			count++
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("store: 写入临时文件失败: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("store: fsync 失败: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("store: 关闭临时文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("store: 替换文件失败: %w", err)
+	}
+
+	if s.f != nil {
+		_ = s.f.Close()
+	}
+	af, err := os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		s.f = nil
+		return fmt.Errorf("store: 打开文件失败: %w", err)
+	}
+	s.f = af
+	s.records = count
+	return nil
+}
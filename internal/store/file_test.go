@@ -0,0 +1,180 @@
+// FileStore 持久化/回放/崩溃恢复单元测试。
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStore_ReloadAfterCrash(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	exp := time.Now().Add(time.Hour)
+	if err := s.Put("state", "a", json.RawMessage(`{"n":1}`), exp); err != nil {
+		t.Fatalf("Put(a) error: %v", err)
+	}
+	if err := s.Put("state", "b", json.RawMessage(`{"n":2}`), exp); err != nil {
+		t.Fatalf("Put(b) error: %v", err)
+	}
+	if err := s.Put("state", "a", json.RawMessage(`{"n":3}`), exp); err != nil {
+		t.Fatalf("Put(a) error: %v", err)
+	}
+	if err := s.Delete("state", "b"); err != nil {
+		t.Fatalf("Delete(b) error: %v", err)
+	}
+	// 模拟进程崩溃：不调用 Close，直接重新打开。
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open(reload) error: %v", err)
+	}
+	t.Cleanup(func() { _ = reopened.Close() })
+
+	got, ok := reopened.Get("state", "a")
+	if !ok || string(got) != `{"n":3}` {
+		t.Fatalf("Get(a) = %s, %v; want {\"n\":3}, true", got, ok)
+	}
+	if _, ok := reopened.Get("state", "b"); ok {
+		t.Fatalf("Get(b) ok = true, want false（已删除）")
+	}
+	_ = s.Close()
+}
+
+func TestFileStore_ReloadSkipsTornTailAndExpired(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if err := s.Put("state", "live", json.RawMessage(`1`), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Put(live) error: %v", err)
+	}
+	if err := s.Put("state", "old", json.RawMessage(`2`), time.Now().Add(10*time.Millisecond)); err != nil {
+		t.Fatalf("Put(old) error: %v", err)
+	}
+	if err := s.Put("dedupe", "k", json.RawMessage(`3`), time.Time{}); err != nil {
+		t.Fatalf("Put(k) error: %v", err)
+	}
+	_ = s.Close()
+
+	// 崩溃时写了一半的记录。
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatalf("OpenFile() error: %v", err)
+	}
+	_, _ = f.WriteString(`{"op":"put","b":"state","k":"torn","v":{"x"`)
+	_ = f.Close()
+
+	time.Sleep(20 * time.Millisecond)
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open(reload) error: %v", err)
+	}
+	t.Cleanup(func() { _ = reopened.Close() })
+
+	if _, ok := reopened.Get("state", "live"); !ok {
+		t.Fatalf("Get(live) ok = false, want true")
+	}
+	if _, ok := reopened.Get("state", "old"); ok {
+		t.Fatalf("Get(old) ok = true, want false（已过期）")
+	}
+	if _, ok := reopened.Get("state", "torn"); ok {
+		t.Fatalf("Get(torn) ok = true, want false（损坏记录）")
+	}
+	if _, ok := reopened.Get("dedupe", "k"); !ok {
+		t.Fatalf("Get(dedupe/k) ok = false, want true（零值 expiresAt 不过期）")
+	}
+
+	// 追加写入在压缩后仍可回放。
+	if err := reopened.Put("state", "after", json.RawMessage(`4`), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Put(after) error: %v", err)
+	}
+	_ = reopened.Close()
+	again, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open(again) error: %v", err)
+	}
+	t.Cleanup(func() { _ = again.Close() })
+	if _, ok := again.Get("state", "after"); !ok {
+		t.Fatalf("Get(after) ok = false, want true")
+	}
+}
+
+func TestFileStore_PutIfAbsent(t *testing.T) {
+	t.Parallel()
+
+	s, err := Open(filepath.Join(t.TempDir(), "kv.jsonl"))
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	if _, loaded, err := s.PutIfAbsent("b", "k", json.RawMessage(`1`), time.Now().Add(time.Hour)); err != nil || loaded {
+		t.Fatalf("PutIfAbsent(1) loaded=%v err=%v, want false nil", loaded, err)
+	}
+	v, loaded, err := s.PutIfAbsent("b", "k", json.RawMessage(`2`), time.Now().Add(time.Hour))
+	if err != nil || !loaded || string(v) != "1" {
+		t.Fatalf("PutIfAbsent(2) = %s loaded=%v err=%v, want 1 true nil", v, loaded, err)
+	}
+}
+
+func TestFileStore_List(t *testing.T) {
+	t.Parallel()
+
+	s, err := Open(filepath.Join(t.TempDir(), "kv.jsonl"))
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	_ = s.Put("b", "a", json.RawMessage(`1`), time.Time{})
+	_ = s.Put("b", "gone", json.RawMessage(`2`), time.Now().Add(-time.Second))
+	_ = s.Put("other", "c", json.RawMessage(`3`), time.Time{})
+
+	got := s.List("b")
+	if len(got) != 1 || string(got["a"]) != "1" {
+		t.Fatalf("List(b) = %v, want only a=1", got)
+	}
+	if got := s.List("missing"); len(got) != 0 {
+		t.Fatalf("List(missing) = %v, want empty", got)
+	}
+}
+
+func TestFileStore_PruneCompacts(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "kv.jsonl")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	exp := time.Now().Add(time.Hour)
+	for i := 0; i < compactMinRecords; i++ {
+		if err := s.Put("b", "k", json.RawMessage(`1`), exp); err != nil {
+			t.Fatalf("Put() error: %v", err)
+		}
+	}
+	if err := s.Prune(); err != nil {
+		t.Fatalf("Prune() error: %v", err)
+	}
+
+	s.mu.Lock()
+	records := s.records
+	s.mu.Unlock()
+	if records != 1 {
+		t.Fatalf("records = %d, want 1（压缩后仅保留存活条目）", records)
+	}
+}
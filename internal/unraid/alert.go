@@ -0,0 +1,299 @@
+package unraid
+
+// alert.go 实现 Unraid 阵列/磁盘健康轮询告警（磁盘过热、磁盘被禁用、阵列降级），按目标冷却避免重复推送；
+// 同时跟踪校验检查，结束时推送完成通知（含错误数）。
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+type AlertConfig struct {
+	Enabled bool
+
+	Interval time.Duration
+	Cooldown time.Duration
+
+	// DiskTempThreshold 为磁盘温度阈值（℃），≤0 表示不检查温度。
+	DiskTempThreshold int
+}
+
+type AlertManagerDeps struct {
+	WeCom core.WeComSender
+	// Recipients 每次推送前解析收件人（通常为具备 unraid.alert 权限的用户）。
+	Recipients func() []string
+	Instances  []Instance
+	Config     AlertConfig
+}
+
+type AlertManager struct {
+	wecom      core.WeComSender
+	recipients func() []string
+
+	cfg       AlertConfig
+	instances []Instance
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+	// parity 为各实例上次轮询到的校验检查状态；parityWatchers 为通过本服务发起校验检查的用户（结束时通知）。
+	parity         map[string]ParityCheck
+	parityWatchers map[string]map[string]struct{}
+
+	stopCh    chan struct{}
+	stopOnce  sync.Once
+	startOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func NewAlertManager(deps AlertManagerDeps) *AlertManager {
+	var instances []Instance
+	for _, ins := range deps.Instances {
+		if ins.Client != nil {
+			instances = append(instances, ins)
+		}
+	}
+	return &AlertManager{
+		wecom:      deps.WeCom,
+		recipients: deps.Recipients,
+		cfg:        deps.Config,
+		instances:  instances,
+		lastSent:   make(map[string]time.Time),
+		stopCh:     make(chan struct{}),
+
+		parity:         make(map[string]ParityCheck),
+		parityWatchers: make(map[string]map[string]struct{}),
+	}
+}
+
+// Start 启动轮询；告警关闭时仍会运行，仅用于向发起人推送校验检查完成通知。
+func (m *AlertManager) Start() {
+	if m == nil || m.wecom == nil || len(m.instances) == 0 {
+		return
+	}
+	m.startOnce.Do(func() {
+		interval := m.cfg.Interval
+		if interval <= 0 {
+			interval = 5 * time.Minute
+		}
+		m.wg.Add(1)
+		go m.loop(interval)
+	})
+}
+
+func (m *AlertManager) Close() {
+	if m == nil {
+		return
+	}
+	m.stopOnce.Do(func() { close(m.stopCh) })
+	m.wg.Wait()
+}
+
+func (m *AlertManager) loop(interval time.Duration) {
+	defer m.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// 启动后先做一次检查，避免需要等待一个 interval。
+	m.checkOnce()
+
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case <-ticker.C:
+			m.checkOnce()
+		}
+	}
+}
+
+func (m *AlertManager) checkOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, ins := range m.instances {
+		if m.cfg.Enabled && m.recipients != nil {
+			m.checkInstance(ctx, ins)
+		}
+		m.checkParity(ctx, ins)
+	}
+}
+
+// WatchParity 记录发起校验检查的用户，检查结束时向其推送完成通知（告警关闭时同样生效）。
+func (m *AlertManager) WatchParity(instanceID, userID string) {
+	if m == nil || strings.TrimSpace(userID) == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	users := m.parityWatchers[instanceID]
+	if users == nil {
+		users = make(map[string]struct{})
+		m.parityWatchers[instanceID] = users
+	}
+	users[userID] = struct{}{}
+}
+
+// checkParity 轮询校验检查状态：由运行中/已暂停变为结束时推送完成通知（含错误数），收件人为发起人与告警收件人。
+func (m *AlertManager) checkParity(ctx context.Context, ins Instance) {
+	m.mu.Lock()
+	_, watched := m.parityWatchers[ins.ID]
+	m.mu.Unlock()
+	if !m.cfg.Enabled && !watched {
+		return
+	}
+
+	cur, err := ins.Client.GetParityStatus(ctx)
+	if err != nil {
+		if watched {
+			slog.Warn("Unraid 校验检查状态查询失败", "instance_id", ins.ID, "error", err)
+		}
+		return
+	}
+
+	m.mu.Lock()
+	prev, seen := m.parity[ins.ID]
+	m.parity[ins.ID] = cur
+	// 发起后首次轮询前检查可能已结束（或尚未开始），发起人仍需收到结果。
+	done := !cur.Active() && ((seen && prev.Active()) || (watched && !seen))
+	var users []string
+	if done {
+		for u := range m.parityWatchers[ins.ID] {
+			users = append(users, u)
+		}
+		delete(m.parityWatchers, ins.ID)
+	}
+	m.mu.Unlock()
+	if !done {
+		return
+	}
+
+	if m.cfg.Enabled && m.recipients != nil {
+		users = append(users, m.recipients()...)
+	}
+	content := formatParityDone(ins.Name, cur)
+	for _, userID := range uniqueNonEmpty(users) {
+		_ = m.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: content})
+	}
+}
+
+func formatParityDone(instanceName string, p ParityCheck) string {
+	icon := "✅"
+	if p.Errors > 0 || p.Status == ParityStatusFailed {
+		icon = "⚠️"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s Unraid 校验检查结束\n实例：%s\n结果：%s\n错误数：%d", icon, instanceName, parityStatusText(p.Status), p.Errors)
+	if p.Duration > 0 {
+		fmt.Fprintf(&b, "\n耗时：%s", formatParityDuration(p.Duration))
+	}
+	return b.String()
+}
+
+// alertFinding 为一条告警：key 用于冷却（实例 + 类型 + 目标），line 为推送文本中的一行。
+type alertFinding struct {
+	key  string
+	line string
+}
+
+// arrayFindings 根据阵列状态生成告警项：阵列未启动、磁盘被禁用/缺失（校验盘/数据盘标注阵列降级）、磁盘过热。
+func arrayFindings(s ArrayStatus, tempThreshold int) []alertFinding {
+	var out []alertFinding
+	if s.State != "" && s.State != "STARTED" {
+		out = append(out, alertFinding{key: "state|" + s.State, line: "阵列降级：阵列状态 " + arrayStateText(s.State)})
+	}
+	for _, group := range []struct {
+		disks []ArrayDisk
+		note  string
+	}{
+		{s.Parities, "（阵列降级，无校验保护）"},
+		{s.Disks, "（阵列降级，数据由校验模拟）"},
+		{s.Caches, ""},
+	} {
+		for _, d := range group.disks {
+			if d.Disabled() {
+				out = append(out, alertFinding{
+					key:  "disabled|" + d.Name,
+					line: fmt.Sprintf("磁盘%s：%s%s", diskStatusText(d.Status), d.Name, group.note),
+				})
+			}
+		}
+	}
+	var hot []ArrayDisk
+	for _, d := range s.AllDisks() {
+		if tempThreshold > 0 && d.HasTemp && d.Temp >= tempThreshold {
+			hot = append(hot, d)
+		}
+	}
+	sort.SliceStable(hot, func(i, j int) bool { return hot[i].Temp > hot[j].Temp })
+	for _, d := range hot {
+		out = append(out, alertFinding{
+			key:  "hot|" + d.Name,
+			line: fmt.Sprintf("磁盘过热：%s %d℃（≥ %d℃）", d.Name, d.Temp, tempThreshold),
+		})
+	}
+	return out
+}
+
+func (m *AlertManager) checkInstance(ctx context.Context, ins Instance) {
+	status, err := ins.Client.GetArrayStatus(ctx)
+	if err != nil {
+		slog.Warn("Unraid 阵列状态查询失败", "instance_id", ins.ID, "error", err)
+		return
+	}
+
+	findings := arrayFindings(status, m.cfg.DiskTempThreshold)
+	if len(findings) == 0 {
+		return
+	}
+
+	cooldown := m.cfg.Cooldown
+	if cooldown <= 0 {
+		cooldown = 30 * time.Minute
+	}
+	now := time.Now()
+
+	var lines []string
+	m.mu.Lock()
+	for _, f := range findings {
+		key := ins.ID + "|" + f.key
+		if last := m.lastSent[key]; !last.IsZero() && now.Sub(last) < cooldown {
+			continue
+		}
+		m.lastSent[key] = now
+		lines = append(lines, "- "+f.line)
+	}
+	m.mu.Unlock()
+	if len(lines) == 0 {
+		return
+	}
+
+	content := fmt.Sprintf("⚠️ Unraid 告警\n实例：%s\n\n%s\n\n提示：发送“/unraid array”查看阵列状态。", ins.Name, strings.Join(lines, "\n"))
+	for _, userID := range uniqueNonEmpty(m.recipients()) {
+		_ = m.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: content})
+	}
+}
+
+func uniqueNonEmpty(ss []string) []string {
+	seen := make(map[string]struct{})
+	var out []string
+	for _, s := range ss {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
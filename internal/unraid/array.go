@@ -0,0 +1,314 @@
+package unraid
+
+// array.go 封装 Unraid 阵列与磁盘健康查询（Query.array / Query.disks），并提供降级/禁用/过热判定。
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ArrayStatus 为阵列状态与各磁盘健康信息。
+type ArrayStatus struct {
+	// State 为 ArrayState（STARTED/STOPPED/...）。
+	State string
+	// 容量单位为字节（GraphQL 以 KiB 字符串返回）。
+	CapacityTotal int64
+	CapacityUsed  int64
+	CapacityFree  int64
+
+	Parities []ArrayDisk
+	Disks    []ArrayDisk
+	Caches   []ArrayDisk
+
+	// SMARTAvailable 表示 Query.disks 可用（SMART 状态已合并到各磁盘）。
+	SMARTAvailable bool
+}
+
+// ArrayDisk 为阵列中的一块磁盘（校验盘/数据盘/缓存盘）。
+type ArrayDisk struct {
+	Name   string
+	Device string
+	// Status 为 ArrayDiskStatus（DISK_OK/DISK_DSBL/DISK_NP_MISSING/...）。
+	Status string
+	// Temp 为温度（℃）；HasTemp=false 表示未上报（如已休眠）。
+	Temp    int
+	HasTemp bool
+	// Errors 为读写错误计数。
+	Errors   int64
+	Size     int64
+	FSUsed   int64
+	FSSize   int64
+	Spinning *bool
+	// SMART 为 Query.disks 的 smartStatus（OK/UNKNOWN/...），未查询到时为空。
+	SMART string
+}
+
+type arrayDiskResp struct {
+	Name       string       `json:"name"`
+	Device     string       `json:"device"`
+	Status     string       `json:"status"`
+	Temp       *float64     `json:"temp"`
+	NumErrors  bigIntString `json:"numErrors"`
+	Size       bigIntString `json:"size"`
+	FSSize     bigIntString `json:"fsSize"`
+	FSUsed     bigIntString `json:"fsUsed"`
+	IsSpinning *bool        `json:"isSpinning"`
+}
+
+const arrayDiskFields = `name device status temp numErrors size fsSize fsUsed isSpinning`
+
+const arrayFields = `state capacity { kilobytes { free used total } } ` +
+	`parities { ` + arrayDiskFields + ` } disks { ` + arrayDiskFields + ` } caches { ` + arrayDiskFields + ` }`
+
+// arrayResp 为 UnraidArray 的查询/订阅结果（Query.array 与 Subscription.arraySubscription 共用）。
+type arrayResp struct {
+	State    string `json:"state"`
+	Capacity struct {
+		Kilobytes struct {
+			Free  bigIntString `json:"free"`
+			Used  bigIntString `json:"used"`
+			Total bigIntString `json:"total"`
+		} `json:"kilobytes"`
+	} `json:"capacity"`
+	Parities []arrayDiskResp `json:"parities"`
+	Disks    []arrayDiskResp `json:"disks"`
+	Caches   []arrayDiskResp `json:"caches"`
+}
+
+func (r arrayResp) convert() ArrayStatus {
+	return ArrayStatus{
+		State:         strings.ToUpper(strings.TrimSpace(r.State)),
+		CapacityTotal: parseInt64FromBigIntString(r.Capacity.Kilobytes.Total) * 1024,
+		CapacityUsed:  parseInt64FromBigIntString(r.Capacity.Kilobytes.Used) * 1024,
+		CapacityFree:  parseInt64FromBigIntString(r.Capacity.Kilobytes.Free) * 1024,
+		Parities:      convertArrayDisks(r.Parities),
+		Disks:         convertArrayDisks(r.Disks),
+		Caches:        convertArrayDisks(r.Caches),
+	}
+}
+
+// GetArrayStatus 查询阵列状态、容量与各磁盘健康；Query.disks（SMART）不可用时忽略，仅展示阵列字段。
+func (c *Client) GetArrayStatus(ctx context.Context) (ArrayStatus, error) {
+	q := `query { array { ` + arrayFields + ` } }`
+	var resp struct {
+		Array arrayResp `json:"array"`
+	}
+	if err := c.do(ctx, q, nil, &resp); err != nil {
+		return ArrayStatus{}, err
+	}
+
+	out := resp.Array.convert()
+	if smart, err := c.getDiskSMART(ctx); err == nil {
+		out.SMARTAvailable = true
+		for _, list := range [][]ArrayDisk{out.Parities, out.Disks, out.Caches} {
+			for i := range list {
+				list[i].SMART = smart[deviceKey(list[i].Device)]
+			}
+		}
+	}
+	return out, nil
+}
+
+// getDiskSMART 返回设备名（不含 /dev/）到 smartStatus 的映射。
+func (c *Client) getDiskSMART(ctx context.Context) (map[string]string, error) {
+	const q = `query { disks { device smartStatus } }`
+	var resp struct {
+		Disks []struct {
+			Device      string `json:"device"`
+			SmartStatus string `json:"smartStatus"`
+		} `json:"disks"`
+	}
+	if err := c.do(ctx, q, nil, &resp); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(resp.Disks))
+	for _, d := range resp.Disks {
+		if key := deviceKey(d.Device); key != "" {
+			out[key] = strings.ToUpper(strings.TrimSpace(d.SmartStatus))
+		}
+	}
+	return out, nil
+}
+
+func deviceKey(device string) string {
+	return strings.TrimPrefix(strings.TrimSpace(device), "/dev/")
+}
+
+func convertArrayDisks(in []arrayDiskResp) []ArrayDisk {
+	out := make([]ArrayDisk, 0, len(in))
+	for _, d := range in {
+		status := strings.ToUpper(strings.TrimSpace(d.Status))
+		// DISK_NP 为空槽位（未分配磁盘），不参与展示与告警。
+		if status == "DISK_NP" {
+			continue
+		}
+		disk := ArrayDisk{
+			Name:     strings.TrimSpace(d.Name),
+			Device:   strings.TrimSpace(d.Device),
+			Status:   status,
+			Errors:   parseInt64FromBigIntString(d.NumErrors),
+			Size:     parseInt64FromBigIntString(d.Size) * 1024,
+			FSSize:   parseInt64FromBigIntString(d.FSSize) * 1024,
+			FSUsed:   parseInt64FromBigIntString(d.FSUsed) * 1024,
+			Spinning: d.IsSpinning,
+		}
+		if d.Temp != nil {
+			disk.Temp = int(*d.Temp)
+			disk.HasTemp = true
+		}
+		out = append(out, disk)
+	}
+	return out
+}
+
+// AllDisks 返回校验盘、数据盘与缓存盘。
+func (s ArrayStatus) AllDisks() []ArrayDisk {
+	out := make([]ArrayDisk, 0, len(s.Parities)+len(s.Disks)+len(s.Caches))
+	out = append(out, s.Parities...)
+	out = append(out, s.Disks...)
+	return append(out, s.Caches...)
+}
+
+// Disabled 表示磁盘被禁用/缺失/无效（需人工处理）。
+func (d ArrayDisk) Disabled() bool {
+	switch d.Status {
+	case "DISK_DSBL", "DISK_DSBL_NEW", "DISK_NP_DSBL", "DISK_NP_MISSING", "DISK_INVALID", "DISK_WRONG":
+		return true
+	default:
+		return false
+	}
+}
+
+// SMARTFailed 表示 SMART 状态已上报且不为 OK/UNKNOWN。
+func (d ArrayDisk) SMARTFailed() bool {
+	return d.SMART != "" && d.SMART != "OK" && d.SMART != "UNKNOWN"
+}
+
+// Degraded 返回阵列降级原因：阵列未处于 STARTED，或校验盘/数据盘存在禁用/缺失；正常时返回空串。
+func (s ArrayStatus) Degraded() string {
+	if s.State != "" && s.State != "STARTED" {
+		return "阵列状态 " + arrayStateText(s.State)
+	}
+	var names []string
+	for _, d := range append(append([]ArrayDisk(nil), s.Parities...), s.Disks...) {
+		if d.Disabled() {
+			names = append(names, d.Name)
+		}
+	}
+	if len(names) > 0 {
+		return "磁盘禁用/缺失：" + strings.Join(names, "、")
+	}
+	return ""
+}
+
+func arrayStateText(state string) string {
+	switch state {
+	case "STARTED":
+		return "已启动"
+	case "STOPPED":
+		return "已停止"
+	case "NEW_ARRAY":
+		return "新阵列"
+	case "RECON_DISK":
+		return "重建磁盘"
+	case "DISABLE_DISK":
+		return "磁盘被禁用"
+	case "SWAP_DSBL":
+		return "替换禁用磁盘"
+	case "INVALID_EXPANSION":
+		return "扩容无效"
+	case "TOO_MANY_MISSING_DISKS":
+		return "缺失磁盘过多"
+	default:
+		return state
+	}
+}
+
+func diskStatusText(status string) string {
+	switch status {
+	case "DISK_OK":
+		return "正常"
+	case "DISK_DSBL", "DISK_NP_DSBL":
+		return "已禁用"
+	case "DISK_DSBL_NEW":
+		return "已禁用（新盘）"
+	case "DISK_NP_MISSING":
+		return "缺失"
+	case "DISK_INVALID":
+		return "无效"
+	case "DISK_WRONG":
+		return "错误磁盘"
+	case "DISK_NEW":
+		return "新盘"
+	case "":
+		return "未知"
+	default:
+		return status
+	}
+}
+
+// formatArrayStatus 渲染“阵列状态”：阵列状态与容量，以及各磁盘状态/温度/错误/SMART。
+func formatArrayStatus(s ArrayStatus) string {
+	var b strings.Builder
+	b.WriteString("阵列状态\n")
+	fmt.Fprintf(&b, "状态：%s", arrayStateText(s.State))
+	if reason := s.Degraded(); reason != "" {
+		fmt.Fprintf(&b, "（⚠️ %s）", reason)
+	}
+	b.WriteString("\n")
+	if s.CapacityTotal > 0 {
+		fmt.Fprintf(&b, "容量：%s / %s（%s），可用 %s\n",
+			formatBytesIEC(s.CapacityUsed), formatBytesIEC(s.CapacityTotal),
+			formatPercent(float64(s.CapacityUsed)/float64(s.CapacityTotal)*100), formatBytesIEC(s.CapacityFree))
+	}
+
+	groups := []struct {
+		title string
+		disks []ArrayDisk
+	}{
+		{"校验盘", s.Parities},
+		{"数据盘", s.Disks},
+		{"缓存盘", s.Caches},
+	}
+	for _, g := range groups {
+		if len(g.disks) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s（%d）\n", g.title, len(g.disks))
+		for _, d := range g.disks {
+			b.WriteString("- " + formatArrayDiskLine(d) + "\n")
+		}
+	}
+	if !s.SMARTAvailable {
+		b.WriteString("\n提示：SMART 状态不可用（Query.disks 查询失败）。")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func formatArrayDiskLine(d ArrayDisk) string {
+	name := d.Name
+	if d.Device != "" {
+		name += "（" + d.Device + "）"
+	}
+	parts := []string{name, diskStatusText(d.Status)}
+	switch {
+	case d.HasTemp:
+		parts = append(parts, fmt.Sprintf("%d℃", d.Temp))
+	case d.Spinning != nil && !*d.Spinning:
+		parts = append(parts, "休眠")
+	}
+	if d.Errors > 0 {
+		parts = append(parts, fmt.Sprintf("错误 %d", d.Errors))
+	}
+	if d.SMART != "" {
+		flag := "SMART " + d.SMART
+		if d.SMARTFailed() {
+			flag = "⚠️ " + flag
+		}
+		parts = append(parts, flag)
+	}
+	if d.FSSize > 0 {
+		parts = append(parts, "已用 "+formatPercent(float64(d.FSUsed)/float64(d.FSSize)*100))
+	}
+	return strings.Join(parts, " | ")
+}
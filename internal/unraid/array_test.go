@@ -0,0 +1,179 @@
+package unraid
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newArrayServer 模拟 array 查询；smartOK=false 时 disks 查询返回 GraphQL 错误。
+func newArrayServer(t *testing.T, hotTemp int, smartOK bool) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query string `json:"query"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if strings.Contains(req.Query, "smartStatus") {
+			if !smartOK {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"errors": []map[string]interface{}{{"message": "Cannot query field \"disks\""}},
+				})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"disks": []map[string]interface{}{
+						{"device": "/dev/sdb", "smartStatus": "OK"},
+						{"device": "/dev/sdc", "smartStatus": "FAILED"},
+					},
+				},
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"array": map[string]interface{}{
+					"state": "STARTED",
+					"capacity": map[string]interface{}{
+						"kilobytes": map[string]interface{}{"free": "1048576", "used": "3145728", "total": "4194304"},
+					},
+					"parities": []map[string]interface{}{
+						{"name": "parity", "device": "sdb", "status": "DISK_OK", "temp": 35, "numErrors": "0", "isSpinning": true},
+					},
+					"disks": []map[string]interface{}{
+						{"name": "disk1", "device": "sdc", "status": "DISK_OK", "temp": hotTemp, "numErrors": "3", "fsSize": "100", "fsUsed": "50", "isSpinning": true},
+						{"name": "disk2", "device": "sdd", "status": "DISK_DSBL", "temp": nil, "numErrors": "0", "isSpinning": false},
+						{"name": "disk3", "device": "", "status": "DISK_NP", "temp": nil, "numErrors": "0"},
+					},
+					"caches": []map[string]interface{}{},
+				},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient_GetArrayStatus(t *testing.T) {
+	t.Parallel()
+
+	srv := newArrayServer(t, 55, true)
+	c := NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client())
+
+	s, err := c.GetArrayStatus(context.Background())
+	if err != nil {
+		t.Fatalf("GetArrayStatus() error: %v", err)
+	}
+	if s.State != "STARTED" || s.CapacityTotal != 4*1024*1024*1024 || !s.SMARTAvailable {
+		t.Fatalf("GetArrayStatus() = %+v", s)
+	}
+	if len(s.Disks) != 2 {
+		t.Fatalf("Disks = %d, want 2（DISK_NP 应被忽略）", len(s.Disks))
+	}
+	if d := s.Disks[0]; !d.HasTemp || d.Temp != 55 || d.Errors != 3 || d.SMART != "FAILED" || !d.SMARTFailed() {
+		t.Fatalf("disk1 = %+v", d)
+	}
+	if d := s.Disks[1]; d.HasTemp || !d.Disabled() {
+		t.Fatalf("disk2 = %+v", d)
+	}
+	if got := s.Degraded(); !strings.Contains(got, "disk2") {
+		t.Fatalf("Degraded() = %q, want contains disk2", got)
+	}
+
+	text := formatArrayStatus(s)
+	for _, want := range []string{"状态：已启动", "disk2", "已禁用", "休眠", "55℃", "错误 3", "⚠️ SMART FAILED", "校验盘（1）", "数据盘（2）"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("formatArrayStatus() missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "缓存盘") {
+		t.Fatalf("formatArrayStatus() should omit empty cache group:\n%s", text)
+	}
+}
+
+func TestClient_GetArrayStatus_SMARTUnavailable(t *testing.T) {
+	t.Parallel()
+
+	srv := newArrayServer(t, 40, false)
+	c := NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client())
+
+	s, err := c.GetArrayStatus(context.Background())
+	if err != nil {
+		t.Fatalf("GetArrayStatus() error: %v", err)
+	}
+	if s.SMARTAvailable || s.Disks[0].SMART != "" {
+		t.Fatalf("SMART should be unavailable: %+v", s)
+	}
+	if text := formatArrayStatus(s); !strings.Contains(text, "SMART 状态不可用") {
+		t.Fatalf("formatArrayStatus() missing SMART hint:\n%s", text)
+	}
+}
+
+func TestArrayFindings(t *testing.T) {
+	t.Parallel()
+
+	s := ArrayStatus{
+		State:    "STARTED",
+		Parities: []ArrayDisk{{Name: "parity", Status: "DISK_OK", Temp: 60, HasTemp: true}},
+		Disks: []ArrayDisk{
+			{Name: "disk1", Status: "DISK_OK", Temp: 49, HasTemp: true},
+			{Name: "disk2", Status: "DISK_NP_MISSING"},
+		},
+		Caches: []ArrayDisk{{Name: "cache", Status: "DISK_OK", Temp: 52, HasTemp: true}},
+	}
+	got := arrayFindings(s, 50)
+	var keys []string
+	for _, f := range got {
+		keys = append(keys, f.key)
+	}
+	want := []string{"disabled|disk2", "hot|parity", "hot|cache"}
+	if strings.Join(keys, ",") != strings.Join(want, ",") {
+		t.Fatalf("arrayFindings() keys = %v, want %v", keys, want)
+	}
+	if !strings.Contains(got[0].line, "阵列降级") {
+		t.Fatalf("disabled data disk line = %q, want 阵列降级", got[0].line)
+	}
+
+	if got := arrayFindings(ArrayStatus{State: "STOPPED"}, 50); len(got) != 1 || got[0].key != "state|STOPPED" {
+		t.Fatalf("arrayFindings(STOPPED) = %+v", got)
+	}
+	if got := arrayFindings(s, 0); len(got) != 1 {
+		t.Fatalf("arrayFindings(threshold=0) = %+v, want only disabled", got)
+	}
+}
+
+func TestAlertManager_CheckInstanceCooldown(t *testing.T) {
+	t.Parallel()
+
+	srv := newArrayServer(t, 55, true)
+	rec := &recordWeCom{}
+	m := NewAlertManager(AlertManagerDeps{
+		WeCom:      rec,
+		Recipients: func() []string { return []string{"bob", "alice", "bob", " "} },
+		Instances:  []Instance{{ID: "tower", Name: "Tower", Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client())}},
+		Config:     AlertConfig{Enabled: true, Interval: time.Minute, Cooldown: time.Hour, DiskTempThreshold: 50},
+	})
+
+	m.checkOnce()
+	texts := rec.Texts()
+	if len(texts) != 2 || texts[0].ToUser != "alice" || texts[1].ToUser != "bob" {
+		t.Fatalf("texts = %+v, want alice and bob", texts)
+	}
+	for _, want := range []string{"实例：Tower", "disk1 55℃", "disk2"} {
+		if !strings.Contains(texts[0].Content, want) {
+			t.Fatalf("alert content missing %q:\n%s", want, texts[0].Content)
+		}
+	}
+
+	// 冷却期内相同告警不重复推送。
+	m.checkOnce()
+	if got := len(rec.Texts()); got != 2 {
+		t.Fatalf("texts after cooldown check = %d, want 2", got)
+	}
+}
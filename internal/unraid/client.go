@@ -13,6 +13,9 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
 )
 
 type ClientConfig struct {
@@ -45,11 +48,22 @@ type ClientConfig struct {
 	ForceUpdateArgName      string
 	ForceUpdateArgType      string
 	ForceUpdateReturnFields []string
+
+	// GraphQL 订阅（WebSocket）配置。
+	// - SubscriptionURL: 例如 ws://<ip>/graphql（默认由 Endpoint 推导，http→ws、https→wss）
+	// - SubscriptionProtocol: graphql-transport-ws | graphql-ws（旧版 subscriptions-transport-ws）；为空时握手协商，优先新协议
+	SubscriptionURL      string
+	SubscriptionProtocol string
 }
 
 type Client struct {
 	cfg        ClientConfig
 	httpClient *http.Client
+
+	// 订阅断线重连退避区间与 WebSocket 保活 ping 间隔。
+	subBackoffMin   time.Duration
+	subBackoffMax   time.Duration
+	subPingInterval time.Duration
 }
 
 func NewClient(cfg ClientConfig, httpClient *http.Client) *Client {
@@ -57,6 +71,10 @@ func NewClient(cfg ClientConfig, httpClient *http.Client) *Client {
 	return &Client{
 		cfg:        cfg,
 		httpClient: httpClient,
+
+		subBackoffMin:   time.Second,
+		subBackoffMax:   30 * time.Second,
+		subPingInterval: 30 * time.Second,
 	}
 }
 
@@ -104,6 +122,9 @@ func applyClientDefaults(cfg *ClientConfig) {
 	if strings.TrimSpace(cfg.WebGUIEventsURL) == "" {
 		cfg.WebGUIEventsURL = deriveWebGUIEventsURL(cfg.Endpoint)
 	}
+	if strings.TrimSpace(cfg.SubscriptionURL) == "" {
+		cfg.SubscriptionURL = deriveSubscriptionURL(cfg.Endpoint)
+	}
 }
 
 type graphQLRequest struct {
@@ -111,11 +132,27 @@ type graphQLRequest struct {
 	Variables map[string]interface{} `json:"variables,omitempty"`
 }
 
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
 type graphQLResponse struct {
 	Data   json.RawMessage `json:"data"`
-	Errors []struct {
-		Message string `json:"message"`
-	} `json:"errors"`
+	Errors []graphQLError  `json:"errors"`
+}
+
+// graphQLErrorsErr 将 GraphQL errors 合并为单个错误。
+func graphQLErrorsErr(errs []graphQLError) error {
+	var msgs []string
+	for _, e := range errs {
+		if e.Message != "" {
+			msgs = append(msgs, e.Message)
+		}
+	}
+	if len(msgs) == 0 {
+		return errors.New("graphql error")
+	}
+	return fmt.Errorf("graphql error: %s", strings.Join(msgs, "; "))
 }
 
 func (c *Client) RestartContainerByName(ctx context.Context, name string) error {
@@ -308,17 +345,8 @@ func (b *bigIntString) UnmarshalJSON(data []byte) error {
 type systemMetricsResp struct {
 	Metrics struct {
 		CPU struct {
-			PercentTotal float64 `json:"percentTotal"`
-			CPUs         []struct {
-				PercentTotal  float64 `json:"percentTotal"`
-				PercentUser   float64 `json:"percentUser"`
-				PercentSystem float64 `json:"percentSystem"`
-				PercentNice   float64 `json:"percentNice"`
-				PercentIdle   float64 `json:"percentIdle"`
-				PercentIrq    float64 `json:"percentIrq"`
-				PercentGuest  float64 `json:"percentGuest"`
-				PercentSteal  float64 `json:"percentSteal"`
-			} `json:"cpus"`
+			PercentTotal float64       `json:"percentTotal"`
+			CPUs         []cpuLoadResp `json:"cpus"`
 		} `json:"cpu"`
 		Memory struct {
 			Total        bigIntString `json:"total"`
@@ -330,6 +358,39 @@ type systemMetricsResp struct {
 	} `json:"metrics"`
 }
 
+type cpuLoadResp struct {
+	PercentTotal  float64 `json:"percentTotal"`
+	PercentUser   float64 `json:"percentUser"`
+	PercentSystem float64 `json:"percentSystem"`
+	PercentNice   float64 `json:"percentNice"`
+	PercentIdle   float64 `json:"percentIdle"`
+	PercentIrq    float64 `json:"percentIrq"`
+	PercentGuest  float64 `json:"percentGuest"`
+	PercentSteal  float64 `json:"percentSteal"`
+}
+
+const cpuLoadFields = `percentTotal percentUser percentSystem percentNice percentIdle percentIrq percentGuest percentSteal`
+
+func convertCPUCores(in []cpuLoadResp) []CPUCoreLoad {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]CPUCoreLoad, 0, len(in))
+	for _, c := range in {
+		out = append(out, CPUCoreLoad{
+			PercentTotal:  c.PercentTotal,
+			PercentUser:   c.PercentUser,
+			PercentSystem: c.PercentSystem,
+			PercentNice:   c.PercentNice,
+			PercentIdle:   c.PercentIdle,
+			PercentIrq:    c.PercentIrq,
+			PercentGuest:  c.PercentGuest,
+			PercentSteal:  c.PercentSteal,
+		})
+	}
+	return out
+}
+
 func parseInt64FromBigIntString(s bigIntString) int64 {
 	v := strings.TrimSpace(string(s))
 	if v == "" {
@@ -343,7 +404,7 @@ func parseInt64FromBigIntString(s bigIntString) int64 {
 }
 
 func (c *Client) GetSystemMetrics(ctx context.Context) (SystemMetrics, error) {
-	const q = `query { metrics { cpu { percentTotal cpus { percentTotal percentUser percentSystem percentNice percentIdle percentIrq percentGuest percentSteal } } memory { total used free available percentTotal } } }`
+	const q = `query { metrics { cpu { percentTotal cpus { ` + cpuLoadFields + ` } } memory { total used free available percentTotal } } }`
 
 	var resp systemMetricsResp
 	if err := c.do(ctx, q, nil, &resp); err != nil {
@@ -364,21 +425,7 @@ func (c *Client) GetSystemMetrics(ctx context.Context) (SystemMetrics, error) {
 		out.HasMemoryEffective = true
 	}
 
-	if len(resp.Metrics.CPU.CPUs) > 0 {
-		out.PerCPU = make([]CPUCoreLoad, 0, len(resp.Metrics.CPU.CPUs))
-		for _, c := range resp.Metrics.CPU.CPUs {
-			out.PerCPU = append(out.PerCPU, CPUCoreLoad{
-				PercentTotal:  c.PercentTotal,
-				PercentUser:   c.PercentUser,
-				PercentSystem: c.PercentSystem,
-				PercentNice:   c.PercentNice,
-				PercentIdle:   c.PercentIdle,
-				PercentIrq:    c.PercentIrq,
-				PercentGuest:  c.PercentGuest,
-				PercentSteal:  c.PercentSteal,
-			})
-		}
-	}
+	out.PerCPU = convertCPUCores(resp.Metrics.CPU.CPUs)
 
 	if rx, tx, ok, err := c.getDockerNetworkIOTotals(ctx); err == nil && ok {
 		out.NetworkRxBytesTotal = rx
@@ -410,6 +457,12 @@ func (c *Client) GetSystemMetrics(ctx context.Context) (SystemMetrics, error) {
 	return out, nil
 }
 
+// Ping 以最轻量的查询（系统运行时长）检查 GraphQL 接口可达且 API Key 有效。
+func (c *Client) Ping(ctx context.Context) error {
+	_, _, err := c.getUnraidUptimeSeconds(ctx)
+	return err
+}
+
 func (c *Client) getUnraidUptimeSeconds(ctx context.Context) (seconds int64, ok bool, err error) {
 	const q = `query { info { os { uptime } } }`
 	var resp struct {
@@ -611,8 +664,11 @@ func parseHumanBytes(s string) (int64, bool) {
 	return int64(bytes + 0.5), true
 }
 
+// maxContainerLogLines 为单次拉取容器日志的行数上限（日志检索使用较大的拉取窗口）。
+const maxContainerLogLines = 5000
+
 func (c *Client) GetContainerLogsByName(ctx context.Context, name string, tail int) (ContainerLogs, error) {
-	tail = clampInt(tail, 1, 200)
+	tail = clampInt(tail, 1, maxContainerLogLines)
 	fieldName, fieldExpr, extractPath, err := c.buildLogsFieldExpr(tail)
 	if err != nil {
 		return ContainerLogs{}, err
@@ -709,6 +765,14 @@ func (c *Client) findContainerByName(ctx context.Context, name string) (containe
 		}
 	}
 
+	fuzzy := make([]core.FuzzyCandidate, 0, len(candidates))
+	for _, n := range candidates {
+		fuzzy = append(fuzzy, core.FuzzyCandidate{Key: n, Name: n})
+	}
+	if matches := core.RankFuzzy(want, fuzzy); len(matches) > 0 {
+		return containerInfo{}, fmt.Errorf("未找到容器：%s（相近容器：%s）", name, core.FormatFuzzyChoices(matches))
+	}
+
 	sort.Strings(candidates)
 	if len(candidates) > 0 {
 		const max = 10
@@ -820,16 +884,7 @@ func (c *Client) do(ctx context.Context, query string, variables map[string]inte
 		return err
 	}
 	if len(raw.Errors) > 0 {
-		var msgs []string
-		for _, e := range raw.Errors {
-			if e.Message != "" {
-				msgs = append(msgs, e.Message)
-			}
-		}
-		if len(msgs) == 0 {
-			return errors.New("graphql error")
-		}
-		return fmt.Errorf("graphql error: %s", strings.Join(msgs, "; "))
+		return graphQLErrorsErr(raw.Errors)
 	}
 	if out == nil {
 		return nil
@@ -1064,6 +1119,14 @@ func pickContainerObjectByName(containers []interface{}, name string) (container
 		}
 	}
 
+	fuzzy := make([]core.FuzzyCandidate, 0, len(candidates))
+	for _, n := range candidates {
+		fuzzy = append(fuzzy, core.FuzzyCandidate{Key: n, Name: n})
+	}
+	if matches := core.RankFuzzy(want, fuzzy); len(matches) > 0 {
+		return containerInfo{}, nil, fmt.Errorf("未找到容器：%s（相近容器：%s）", name, core.FormatFuzzyChoices(matches))
+	}
+
 	sort.Strings(candidates)
 	if len(candidates) > 0 {
 		const max = 10
@@ -0,0 +1,422 @@
+package unraid
+
+// follow.go 实现“跟踪日志”：在用户选定的时长内推送容器日志（定时拉取尾部并差分）或 Unraid 日志文件（logFile 订阅）的新增行，
+// 新增行按固定节奏合并为一条消息发送（限速），支持“停止跟踪”，会话超时或离开 Unraid 时自动停止。
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+const (
+	followDefaultMinutes = 5
+	followMaxMinutes     = 30
+	// followMaxPollErrors 为容器日志连续拉取失败的上限，超过后停止跟踪。
+	followMaxPollErrors = 3
+	// followMaxPending 为两次发送之间最多缓存的行数，超出时丢弃较早的行。
+	followMaxPending = 500
+)
+
+// followMinuteChoices 为时长选择卡片提供的选项（分钟）。
+var followMinuteChoices = []int{1, 5, 10, 30}
+
+// syslogPermission 为读取 Unraid 日志文件（logFile）的权限：容器作用范围无法约束系统日志，故单独授权。
+const syslogPermission = "unraid.syslog"
+
+// 跟踪结束原因（context cause）。
+var (
+	errFollowTimeUp         = errors.New("已到设定时长")
+	errFollowStopped        = errors.New("已手动停止")
+	errFollowReplaced       = errors.New("已开始新的跟踪")
+	errFollowSessionExpired = errors.New("会话已超时或已退出 Unraid")
+	errFollowShutdown       = errors.New("服务关闭")
+	errFollowSourceEnded    = errors.New("日志源已结束")
+)
+
+// followTarget 为跟踪目标：容器名或日志文件路径（二选一）。
+type followTarget struct {
+	Container string
+	Path      string
+}
+
+func (t followTarget) label() string {
+	if t.Path != "" {
+		return "日志文件 " + t.Path
+	}
+	return "容器 " + t.Container
+}
+
+type logFollow struct {
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+}
+
+// logFollows 管理各用户进行中的日志跟踪（每个用户同时仅一个，开始新的跟踪会替换旧的）。
+type logFollows struct {
+	mu     sync.Mutex
+	byUser map[string]*logFollow
+	closed bool
+
+	// pollInterval 为容器日志拉取间隔；flushInterval 为合并发送间隔（即单个跟踪的最高发送频率）。
+	pollInterval  time.Duration
+	flushInterval time.Duration
+}
+
+func newLogFollows() *logFollows {
+	return &logFollows{
+		byUser:        make(map[string]*logFollow),
+		pollInterval:  5 * time.Second,
+		flushInterval: 10 * time.Second,
+	}
+}
+
+// stop 结束用户进行中的跟踪并等待其发出结束通知；返回是否存在进行中的跟踪。
+func (f *logFollows) stop(userID string, cause error) bool {
+	f.mu.Lock()
+	lf := f.byUser[userID]
+	f.mu.Unlock()
+	if lf == nil {
+		return false
+	}
+	lf.cancel(cause)
+	<-lf.done
+	return true
+}
+
+func (f *logFollows) closeAll() {
+	f.mu.Lock()
+	f.closed = true
+	list := make([]*logFollow, 0, len(f.byUser))
+	for _, lf := range f.byUser {
+		list = append(list, lf)
+	}
+	f.mu.Unlock()
+
+	for _, lf := range list {
+		lf.cancel(errFollowShutdown)
+		<-lf.done
+	}
+}
+
+// StopFollows 结束所有进行中的日志跟踪（服务关闭时调用）。
+func (p *Provider) StopFollows() {
+	p.follows.closeAll()
+}
+
+// parseFollowTarget 解析跟踪目标：以 / 开头视为 /var/log 下的日志文件，否则为容器名（不做解析与授权）。
+func parseFollowTarget(raw string) (followTarget, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.HasPrefix(raw, "/") {
+		return followTarget{Container: raw}, nil
+	}
+	clean, err := cleanLogFilePath(raw)
+	if err != nil {
+		return followTarget{}, err
+	}
+	return followTarget{Path: clean}, nil
+}
+
+// followLogFile 校验日志文件权限后开始跟踪。
+func (p *Provider) followLogFile(ctx context.Context, userID string, ins Instance, target followTarget, minutes int) error {
+	if !p.syslogAllowed(userID, ins) {
+		return p.sendSyslogForbidden(ctx, userID)
+	}
+	return p.startFollow(ctx, userID, ins, target, minutes)
+}
+
+// startFollow 开始（或替换）用户的日志跟踪，并发送带“停止跟踪”按钮的卡片；调用方负责授权校验。
+func (p *Provider) startFollow(ctx context.Context, userID string, ins Instance, target followTarget, minutes int) error {
+	if ins.Client == nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "查询失败：unraid client 未配置"})
+	}
+	minutes = clampInt(minutes, 1, followMaxMinutes)
+	p.follows.stop(userID, errFollowReplaced)
+
+	base, cancel := context.WithCancelCause(context.Background())
+	runCtx, cancelTimeout := context.WithTimeoutCause(base, time.Duration(minutes)*time.Minute, errFollowTimeUp)
+	lf := &logFollow{cancel: cancel, done: make(chan struct{})}
+
+	p.follows.mu.Lock()
+	if p.follows.closed {
+		p.follows.mu.Unlock()
+		cancelTimeout()
+		cancel(errFollowShutdown)
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "服务正在关闭，无法跟踪日志。"})
+	}
+	p.follows.byUser[userID] = lf
+	p.follows.mu.Unlock()
+
+	label := p.targetLabel(ins, target.label())
+	_ = p.wecom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: fmt.Sprintf("开始跟踪%s，时长 %d 分钟。\n新增日志每 %s 合并推送一次；会话超时或退出 Unraid 时自动停止。", label, minutes, p.follows.flushInterval),
+	})
+	err := p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card:   wecom.NewUnraidFollowCard(label, minutes),
+	})
+
+	go func() {
+		defer cancelTimeout()
+		p.runFollow(runCtx, lf, userID, ins, target, label)
+	}()
+	return err
+}
+
+// runFollow 运行一次跟踪：日志源写入缓冲，按 flushInterval 合并发送；结束时发送剩余内容与结束原因。
+func (p *Provider) runFollow(ctx context.Context, lf *logFollow, userID string, ins Instance, target followTarget, label string) {
+	defer close(lf.done)
+	defer func() {
+		p.follows.mu.Lock()
+		if p.follows.byUser[userID] == lf {
+			delete(p.follows.byUser, userID)
+		}
+		p.follows.mu.Unlock()
+	}()
+
+	var mu sync.Mutex
+	var pending []string
+	dropped := 0
+	emit := func(lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		pending = append(pending, lines...)
+		if over := len(pending) - followMaxPending; over > 0 {
+			pending = pending[over:]
+			dropped += over
+		}
+	}
+	flush := func() {
+		mu.Lock()
+		lines, skipped := pending, dropped
+		pending, dropped = nil, 0
+		mu.Unlock()
+		if len(lines) == 0 {
+			return
+		}
+		sendCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := p.wecom.SendText(sendCtx, wecom.TextMessage{ToUser: userID, Content: formatFollowBatch(label, lines, skipped)}); err != nil {
+			slog.Warn("Unraid 日志跟踪推送失败", "userid", userID, "target", label, "error", err)
+		}
+	}
+
+	srcDone := make(chan error, 1)
+	go func() { srcDone <- p.followSource(ctx, ins, target, emit) }()
+
+	t := time.NewTicker(p.follows.flushInterval)
+	defer t.Stop()
+	sourceExited := false
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case err := <-srcDone:
+			sourceExited = true
+			if err != nil && ctx.Err() == nil {
+				lf.cancel(fmt.Errorf("日志读取失败：%w", err))
+			} else {
+				lf.cancel(errFollowSourceEnded)
+			}
+			break loop
+		case <-t.C:
+			if st, ok := p.state.Get(userID); !ok || st.ServiceKey != p.Key() {
+				lf.cancel(errFollowSessionExpired)
+				break loop
+			}
+			flush()
+		}
+	}
+	if !sourceExited {
+		<-srcDone
+	}
+	flush()
+
+	cause := context.Cause(ctx)
+	if errors.Is(cause, errFollowShutdown) {
+		return
+	}
+	sendCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = p.wecom.SendText(sendCtx, wecom.TextMessage{ToUser: userID, Content: fmt.Sprintf("已结束跟踪%s：%s。", label, cause)})
+}
+
+// followSource 持续读取新增日志行直到 ctx 结束：日志文件走 logFile 订阅，容器日志按 pollInterval 拉取尾部并与上次结果差分。
+func (p *Provider) followSource(ctx context.Context, ins Instance, target followTarget, emit func([]string)) error {
+	if target.Path != "" {
+		return ins.Client.SubscribeLogFile(ctx, target.Path, func(ch LogFileChunk) error {
+			emit(splitLogLines(ch.Content))
+			return nil
+		})
+	}
+
+	t := time.NewTicker(p.follows.pollInterval)
+	defer t.Stop()
+	var prev []string
+	baseline := false
+	failures := 0
+	for {
+		logs, err := ins.Client.GetContainerLogsByName(ctx, target.Container, maxLogTail)
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil:
+			failures++
+			if failures >= followMaxPollErrors {
+				return err
+			}
+		default:
+			failures = 0
+			cur := splitLogLines(logs.Logs)
+			// 首次拉取仅建立基线，之后只推送新增行。
+			if baseline {
+				emit(newLogLines(prev, cur))
+			}
+			prev, baseline = cur, true
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func splitLogLines(s string) []string {
+	s = strings.TrimRight(s, "\r\n")
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(s, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r")
+	}
+	return lines
+}
+
+// newLogLines 返回 cur 相对 prev 的新增行：两次均为日志尾部窗口，取 prev 中与 cur 开头重叠的最长后缀，其后为新增；
+// 无重叠（两次拉取间新增超过窗口）时视为全部新增。
+func newLogLines(prev, cur []string) []string {
+	for s := 0; s < len(prev); s++ {
+		tail := prev[s:]
+		if len(tail) <= len(cur) && slices.Equal(tail, cur[:len(tail)]) {
+			return cur[len(tail):]
+		}
+	}
+	return cur
+}
+
+// formatFollowBatch 将一批新增行渲染为一条消息；超出长度上限时保留最新的行。
+func formatFollowBatch(label string, lines []string, dropped int) string {
+	header := fmt.Sprintf("【跟踪】%s 新增 %d 行", label, len(lines)+dropped)
+	budget := maxWecomTextBytes - len(header) - 64
+	start := len(lines)
+	size := 0
+	for start > 0 && size+len(lines[start-1])+1 <= budget {
+		start--
+		size += len(lines[start]) + 1
+	}
+	if start == len(lines) {
+		// 单行超长：仅保留最后一行（由 truncateForWecom 截断）。
+		start = len(lines) - 1
+	}
+	if omitted := dropped + start; omitted > 0 {
+		header += fmt.Sprintf("（省略较早的 %d 行）", omitted)
+	}
+	return truncateForWecom(header + "\n" + strings.Join(lines[start:], "\n"))
+}
+
+// sendFollowDurationCard 记录目标容器并发送跟踪时长选择卡片。
+func (p *Provider) sendFollowDurationCard(ctx context.Context, userID string, ins Instance, containerName string) error {
+	p.state.Set(userID, core.ConversationState{
+		ServiceKey:    p.Key(),
+		InstanceID:    ins.ID,
+		Action:        core.ActionUnraidFollowLogs,
+		ContainerName: containerName,
+	})
+	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card:   wecom.NewUnraidFollowDurationCard(p.targetLabel(ins, containerName), followMinuteChoices),
+	})
+}
+
+// handleFollowStart 处理时长选择按钮：目标为会话中记录的实例与容器。
+func (p *Provider) handleFollowStart(ctx context.Context, userID string, minutesRaw string) error {
+	state, ok := p.state.Get(userID)
+	if !ok || state.ServiceKey != p.Key() || state.Action != core.ActionUnraidFollowLogs || strings.TrimSpace(state.ContainerName) == "" {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "会话已过期，请重新选择“跟踪日志”。"})
+	}
+	ins, ok := p.instanceFromState(userID, state)
+	if !ok {
+		return p.sendInstanceRequired(ctx, userID)
+	}
+	minutes, err := strconv.Atoi(strings.TrimSpace(minutesRaw))
+	if err != nil || minutes <= 0 {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "时长不合法，请重新选择。"})
+	}
+	name := state.ContainerName
+	if !p.allowed(userID, ins, core.ActionUnraidFollowLogs, name) {
+		return p.sendContainerForbidden(ctx, userID, core.ActionUnraidFollowLogs, name)
+	}
+	p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
+	return p.startFollow(ctx, userID, ins, followTarget{Container: name}, minutes)
+}
+
+// handleFollowStop 处理“停止跟踪”按钮。
+func (p *Provider) handleFollowStop(ctx context.Context, userID string) error {
+	if p.follows.stop(userID, errFollowStopped) {
+		return nil
+	}
+	return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "当前没有进行中的日志跟踪。"})
+}
+
+// followCommands 声明跟踪日志命令：/unraid follow <容器|/var/log/文件> [分钟]，/unraid unfollow 停止。
+func (p *Provider) followCommands(instanceArg core.CommandArg) []core.Command {
+	return []core.Command{
+		{
+			Path:    "unraid follow",
+			Summary: fmt.Sprintf("跟踪日志新增行（容器名或 /var/log 下的文件；默认%d分钟，最长%d）", followDefaultMinutes, followMaxMinutes),
+			Args:    []core.CommandArg{{Name: "target"}, {Name: "minutes", Type: core.ArgInt, Optional: true}, instanceArg},
+			Handler: func(ctx context.Context, userID string, args core.CommandArgs) error {
+				ins, err := p.commandInstance(userID, args.String("instance"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				minutes := followDefaultMinutes
+				if args.Has("minutes") {
+					minutes = args.Int("minutes")
+				}
+				target, err := parseFollowTarget(args.String("target"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				if target.Path != "" {
+					p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
+					return p.followLogFile(ctx, userID, ins, target, minutes)
+				}
+				return p.viewContainer(ctx, userID, ins, core.ActionUnraidFollowLogs, target.Container, minutes, nil)
+			},
+		},
+		{
+			Path:    "unraid unfollow",
+			Summary: "停止跟踪日志",
+			Handler: func(ctx context.Context, userID string, _ core.CommandArgs) error {
+				return p.handleFollowStop(ctx, userID)
+			},
+		},
+	}
+}
@@ -0,0 +1,133 @@
+package unraid
+
+// logfile.go 封装 Unraid 系统日志文件（Query.logFiles / Query.logFile），路径校验与返回结构同 logFile 订阅共用。
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// logFileRoot 为允许读取的日志目录（系统日志不受容器作用范围约束，仅开放该目录）。
+const logFileRoot = "/var/log/"
+
+// LogFile 为日志目录下的一个文件（Query.logFiles）。
+type LogFile struct {
+	Name string
+	Path string
+	// Size 为文件大小（字节）。
+	Size       int64
+	ModifiedAt time.Time
+}
+
+type logFileContentResp struct {
+	Path       string   `json:"path"`
+	Content    string   `json:"content"`
+	TotalLines *float64 `json:"totalLines"`
+	StartLine  *float64 `json:"startLine"`
+}
+
+const logFileContentFields = `path content totalLines startLine`
+
+func (r logFileContentResp) convert() LogFileChunk {
+	out := LogFileChunk{Path: r.Path, Content: r.Content}
+	if v := r.TotalLines; v != nil {
+		out.TotalLines = int(*v)
+	}
+	if v := r.StartLine; v != nil {
+		out.StartLine = int(*v)
+	}
+	return out
+}
+
+// cleanLogFilePath 规范化日志文件路径：相对路径视为 /var/log 下的文件（如“nginx/error.log”），
+// 规范化后不在 /var/log 下时返回错误。
+func cleanLogFilePath(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", errors.New("请指定日志文件")
+	}
+	p := raw
+	if !strings.HasPrefix(p, "/") {
+		p = logFileRoot + p
+	}
+	clean := path.Clean(p)
+	if !strings.HasPrefix(clean, logFileRoot) {
+		return "", fmt.Errorf("日志文件路径不合法：%s（仅支持 /var/log 下的文件）", raw)
+	}
+	return clean, nil
+}
+
+// ListLogFiles 返回 /var/log 下可读取的日志文件（按路径排序）。
+func (c *Client) ListLogFiles(ctx context.Context) ([]LogFile, error) {
+	const q = `query { logFiles { name path size modifiedAt } }`
+	var resp struct {
+		LogFiles []struct {
+			Name       string       `json:"name"`
+			Path       string       `json:"path"`
+			Size       bigIntString `json:"size"`
+			ModifiedAt string       `json:"modifiedAt"`
+		} `json:"logFiles"`
+	}
+	if err := c.do(ctx, q, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	files := make([]LogFile, 0, len(resp.LogFiles))
+	for _, f := range resp.LogFiles {
+		if !strings.HasPrefix(strings.TrimSpace(f.Path), "/") {
+			continue
+		}
+		p, err := cleanLogFilePath(f.Path)
+		if err != nil {
+			continue
+		}
+		name := strings.TrimSpace(f.Name)
+		if name == "" {
+			name = path.Base(p)
+		}
+		lf := LogFile{Name: name, Path: p, Size: parseInt64FromBigIntString(f.Size)}
+		if t, err := time.Parse(time.RFC3339, strings.TrimSpace(f.ModifiedAt)); err == nil {
+			lf.ModifiedAt = t
+		}
+		files = append(files, lf)
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// GetLogFile 读取日志文件的一段：startLine>0 时从该行（从 1 开始）起读取 lines 行，否则读取末尾 lines 行。
+// 服务端未返回 startLine 时按总行数与本段行数推算。
+func (c *Client) GetLogFile(ctx context.Context, filePath string, lines, startLine int) (LogFileChunk, error) {
+	lines = clampInt(lines, 1, maxContainerLogLines)
+	const q = `query LogFile($path: String!, $lines: Int, $startLine: Int) { logFile(path: $path, lines: $lines, startLine: $startLine) { ` + logFileContentFields + ` } }`
+	vars := map[string]interface{}{"path": filePath, "lines": lines}
+	if startLine > 0 {
+		vars["startLine"] = startLine
+	}
+	var resp struct {
+		LogFile logFileContentResp `json:"logFile"`
+	}
+	if err := c.do(ctx, q, vars, &resp); err != nil {
+		return LogFileChunk{}, err
+	}
+
+	out := resp.LogFile.convert()
+	if out.Path == "" {
+		out.Path = filePath
+	}
+	if out.StartLine <= 0 {
+		switch n := len(splitLogLines(out.Content)); {
+		case startLine > 0:
+			out.StartLine = startLine
+		case out.TotalLines > 0:
+			out.StartLine = max(1, out.TotalLines-n+1)
+		default:
+			out.StartLine = 1
+		}
+	}
+	return out, nil
+}
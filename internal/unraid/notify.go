@@ -0,0 +1,411 @@
+package unraid
+
+// notify.go 实现 Unraid 通知转发：轮询未读通知（Query.notifications.list），按重要程度推送给具备
+// unraid.notify.<importance> 权限的用户，卡片提供归档/按重要程度全部归档；已转发的通知按实例持久化去重。
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+	"github.com/zcw199604/wecom-home-ops/internal/store"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// 通知重要程度（NotificationImportance 枚举）。
+const (
+	NotifyImportanceAlert   = "ALERT"
+	NotifyImportanceWarning = "WARNING"
+	NotifyImportanceInfo    = "INFO"
+)
+
+// Notification 为一条 Unraid 通知。
+type Notification struct {
+	ID          string
+	Title       string
+	Subject     string
+	Description string
+	Importance  string
+	Link        string
+	Timestamp   time.Time
+}
+
+const notifyListLimit = 50
+
+// ListUnreadNotifications 返回未读通知（按时间从旧到新）。
+func (c *Client) ListUnreadNotifications(ctx context.Context) ([]Notification, error) {
+	const q = `query Notifications($filter: NotificationFilter!) { notifications { list(filter: $filter) { id title subject description importance link timestamp } } }`
+	vars := map[string]interface{}{
+		"filter": map[string]interface{}{"type": "UNREAD", "offset": 0, "limit": notifyListLimit},
+	}
+	var resp struct {
+		Notifications struct {
+			List []struct {
+				ID          string `json:"id"`
+				Title       string `json:"title"`
+				Subject     string `json:"subject"`
+				Description string `json:"description"`
+				Importance  string `json:"importance"`
+				Link        string `json:"link"`
+				Timestamp   string `json:"timestamp"`
+			} `json:"list"`
+		} `json:"notifications"`
+	}
+	if err := c.do(ctx, q, vars, &resp); err != nil {
+		return nil, err
+	}
+
+	out := make([]Notification, 0, len(resp.Notifications.List))
+	for _, n := range resp.Notifications.List {
+		id := strings.TrimSpace(n.ID)
+		if id == "" {
+			continue
+		}
+		item := Notification{
+			ID:          id,
+			Title:       strings.TrimSpace(n.Title),
+			Subject:     strings.TrimSpace(n.Subject),
+			Description: strings.TrimSpace(n.Description),
+			Importance:  strings.ToUpper(strings.TrimSpace(n.Importance)),
+			Link:        strings.TrimSpace(n.Link),
+		}
+		if t, err := time.Parse(time.RFC3339, strings.TrimSpace(n.Timestamp)); err == nil {
+			item.Timestamp = t
+		}
+		out = append(out, item)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Timestamp.Before(out[j].Timestamp) })
+	return out, nil
+}
+
+// ArchiveNotification 归档单条通知。
+func (c *Client) ArchiveNotification(ctx context.Context, id string) error {
+	const q = `mutation ArchiveNotification($id: PrefixedID!) { archiveNotification(id: $id) { id } }`
+	var resp struct {
+		ArchiveNotification struct {
+			ID string `json:"id"`
+		} `json:"archiveNotification"`
+	}
+	return c.do(ctx, q, map[string]interface{}{"id": id}, &resp)
+}
+
+// ArchiveAllNotifications 归档指定重要程度（为空表示全部）的未读通知，返回剩余未读数。
+func (c *Client) ArchiveAllNotifications(ctx context.Context, importance string) (int64, error) {
+	const q = `mutation ArchiveAll($importance: NotificationImportance) { archiveAll(importance: $importance) { unread { total } } }`
+	vars := map[string]interface{}{}
+	if importance != "" {
+		vars["importance"] = importance
+	}
+	var resp struct {
+		ArchiveAll struct {
+			Unread struct {
+				Total bigIntString `json:"total"`
+			} `json:"unread"`
+		} `json:"archiveAll"`
+	}
+	if err := c.do(ctx, q, vars, &resp); err != nil {
+		return 0, err
+	}
+	return parseInt64FromBigIntString(resp.ArchiveAll.Unread.Total), nil
+}
+
+// notifyPermission 返回接收某重要程度通知所需的权限：unraid.notify.<alert|warning|info>。
+func notifyPermission(importance string) string {
+	return core.ServicePermission("unraid", "notify", strings.ToLower(importance))
+}
+
+// notifyArchivePermission 为通知卡片上归档按钮所需的权限。
+var notifyArchivePermission = core.ServicePermission("unraid", "notify", "archive")
+
+func notifyImportanceText(importance string) string {
+	switch importance {
+	case NotifyImportanceAlert:
+		return "告警"
+	case NotifyImportanceWarning:
+		return "警告"
+	case NotifyImportanceInfo:
+		return "信息"
+	default:
+		return importance
+	}
+}
+
+func notifyImportanceIcon(importance string) string {
+	switch importance {
+	case NotifyImportanceAlert:
+		return "🔴"
+	case NotifyImportanceWarning:
+		return "🟠"
+	default:
+		return "🔵"
+	}
+}
+
+// notifyKey 为通知卡片按钮 key 的后缀：<实例ID>:<通知ID或重要程度>（实例 ID 不含“:”，旧单台写法为空）。
+func notifyKey(instanceID, rest string) string {
+	return instanceID + ":" + rest
+}
+
+func splitNotifyKey(suffix string) (instanceID, rest string, ok bool) {
+	instanceID, rest, ok = strings.Cut(suffix, ":")
+	return instanceID, rest, ok && rest != ""
+}
+
+// NotifyConfig 为通知转发配置。
+type NotifyConfig struct {
+	Enabled  bool
+	Interval time.Duration
+	// Importance 为需要转发的重要程度（ALERT/WARNING/INFO）。
+	Importance []string
+}
+
+type NotifyBridgeDeps struct {
+	WeCom core.WeComSender
+	// Recipients 每次推送前按实例与权限解析收件人（通常为 Authorizer.UsersWithAccess，通知覆盖整台实例）。
+	Recipients func(instanceID, permission string) []string
+	Instances  []Instance
+	Config     NotifyConfig
+	// Store 可选：持久化已转发的通知（bucket=unraid_notify），服务重启后不重复推送；为空时仅内存去重。
+	Store *store.FileStore
+}
+
+// NotifyBridge 轮询各实例的未读通知并转发到企业微信。
+type NotifyBridge struct {
+	wecom      core.WeComSender
+	recipients func(instanceID, permission string) []string
+	cfg        NotifyConfig
+	instances  []Instance
+	importance map[string]struct{}
+	seen       *notifySeen
+
+	stopCh    chan struct{}
+	stopOnce  sync.Once
+	startOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func NewNotifyBridge(deps NotifyBridgeDeps) *NotifyBridge {
+	var instances []Instance
+	for _, ins := range deps.Instances {
+		if ins.Client != nil {
+			instances = append(instances, ins)
+		}
+	}
+	importance := make(map[string]struct{})
+	for _, v := range deps.Config.Importance {
+		if v = strings.ToUpper(strings.TrimSpace(v)); v != "" {
+			importance[v] = struct{}{}
+		}
+	}
+	return &NotifyBridge{
+		wecom:      deps.WeCom,
+		recipients: deps.Recipients,
+		cfg:        deps.Config,
+		instances:  instances,
+		importance: importance,
+		seen:       newNotifySeen(deps.Store),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+func (b *NotifyBridge) Start() {
+	if b == nil || !b.cfg.Enabled || b.wecom == nil || b.recipients == nil || len(b.instances) == 0 {
+		return
+	}
+	b.startOnce.Do(func() {
+		interval := b.cfg.Interval
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		b.wg.Add(1)
+		go b.loop(interval)
+	})
+}
+
+func (b *NotifyBridge) Close() {
+	if b == nil {
+		return
+	}
+	b.stopOnce.Do(func() { close(b.stopCh) })
+	b.wg.Wait()
+}
+
+func (b *NotifyBridge) loop(interval time.Duration) {
+	defer b.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	b.pollOnce()
+	for {
+		select {
+		case <-b.stopCh:
+			return
+		case <-ticker.C:
+			b.pollOnce()
+		}
+	}
+}
+
+func (b *NotifyBridge) pollOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, ins := range b.instances {
+		b.pollInstance(ctx, ins)
+	}
+}
+
+// pollInstance 转发实例中尚未转发过的未读通知；首次运行（无基线）时仅记录现有通知，避免历史通知刷屏。
+func (b *NotifyBridge) pollInstance(ctx context.Context, ins Instance) {
+	list, err := ins.Client.ListUnreadNotifications(ctx)
+	if err != nil {
+		slog.Warn("Unraid 通知查询失败", "instance_id", ins.ID, "error", err)
+		return
+	}
+
+	baseline := !b.seen.hasBaseline(ins.ID)
+	cutoff := time.Now().Add(-notifySeenTTL)
+	for _, n := range list {
+		if b.seen.has(ins.ID, n.ID) {
+			continue
+		}
+		b.seen.mark(ins.ID, n.ID)
+		if baseline {
+			continue
+		}
+		if _, ok := b.importance[n.Importance]; !ok {
+			continue
+		}
+		// 超过去重保留期的旧通知不再转发（其去重记录可能已过期）。
+		if !n.Timestamp.IsZero() && n.Timestamp.Before(cutoff) {
+			continue
+		}
+		b.relay(ctx, ins, n)
+	}
+	if baseline {
+		b.seen.markBaseline(ins.ID)
+		slog.Info("Unraid 通知转发已建立基线", "instance_id", ins.ID, "unread", len(list))
+	}
+}
+
+func (b *NotifyBridge) relay(ctx context.Context, ins Instance, n Notification) {
+	users := uniqueNonEmpty(b.recipients(ins.ID, notifyPermission(n.Importance)))
+	if len(users) == 0 {
+		return
+	}
+	card := wecom.NewUnraidNotificationCard(wecom.UnraidNotificationCardOptions{
+		Title:          notifyImportanceIcon(n.Importance) + " " + firstNonEmpty(n.Title, n.Subject, "Unraid 通知"),
+		Desc:           formatNotifyDesc(ins, n, len(b.instances) > 1),
+		Detail:         formatNotifyDetail(n),
+		ArchiveKey:     notifyKey(ins.ID, n.ID),
+		ArchiveAllKey:  notifyKey(ins.ID, n.Importance),
+		ImportanceText: notifyImportanceText(n.Importance),
+	})
+	for _, userID := range users {
+		if err := b.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{ToUser: userID, Card: card}); err != nil {
+			slog.Warn("Unraid 通知推送失败", "instance_id", ins.ID, "notification_id", n.ID, "userid", userID, "error", err)
+		}
+	}
+}
+
+func formatNotifyDesc(ins Instance, n Notification, multi bool) string {
+	parts := []string{notifyImportanceText(n.Importance)}
+	if multi {
+		parts = append(parts, ins.Name)
+	}
+	if !n.Timestamp.IsZero() {
+		parts = append(parts, n.Timestamp.Local().Format("01-02 15:04"))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func formatNotifyDetail(n Notification) string {
+	var lines []string
+	if n.Subject != "" && n.Subject != n.Title {
+		lines = append(lines, n.Subject)
+	}
+	if n.Description != "" {
+		lines = append(lines, n.Description)
+	}
+	detail := strings.Join(lines, "\n")
+	if r := []rune(detail); len(r) > 200 {
+		detail = string(r[:199]) + "…"
+	}
+	return detail
+}
+
+func firstNonEmpty(ss ...string) string {
+	for _, s := range ss {
+		if strings.TrimSpace(s) != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+const (
+	notifySeenBucket = "unraid_notify"
+	// notifySeenTTL 为已转发记录的保留期；更旧的未读通知不再转发。
+	notifySeenTTL = 30 * 24 * time.Hour
+)
+
+// notifySeen 记录已转发（或基线时已存在）的通知；kv 非空时写入持久化存储，重启后仍可去重。
+type notifySeen struct {
+	kv *store.FileStore
+
+	mu  sync.Mutex
+	mem map[string]time.Time
+}
+
+func newNotifySeen(kv *store.FileStore) *notifySeen {
+	return &notifySeen{kv: kv, mem: make(map[string]time.Time)}
+}
+
+func (s *notifySeen) has(instanceID, id string) bool {
+	return s.get(instanceID + "|" + id)
+}
+
+func (s *notifySeen) mark(instanceID, id string) {
+	s.put(instanceID+"|"+id, time.Now().Add(notifySeenTTL))
+}
+
+// 基线标记不过期：仅首次运行时跳过已有通知。
+func (s *notifySeen) hasBaseline(instanceID string) bool {
+	return s.get(instanceID + "|#baseline")
+}
+
+func (s *notifySeen) markBaseline(instanceID string) {
+	s.put(instanceID+"|#baseline", time.Time{})
+}
+
+func (s *notifySeen) get(key string) bool {
+	if s.kv != nil {
+		_, ok := s.kv.Get(notifySeenBucket, key)
+		return ok
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exp, ok := s.mem[key]
+	if ok && !exp.IsZero() && time.Now().After(exp) {
+		delete(s.mem, key)
+		return false
+	}
+	return ok
+}
+
+func (s *notifySeen) put(key string, expiresAt time.Time) {
+	if s.kv != nil {
+		raw, _ := json.Marshal(time.Now())
+		if err := s.kv.Put(notifySeenBucket, key, raw, expiresAt); err != nil {
+			slog.Warn("Unraid 通知去重记录持久化失败", "key", key, "error", err)
+		}
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mem[key] = expiresAt
+}
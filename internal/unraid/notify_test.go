@@ -0,0 +1,286 @@
+package unraid
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+	"github.com/zcw199604/wecom-home-ops/internal/store"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// notifyServer 模拟 notifications.list 与 archiveNotification/archiveAll，通知列表可在测试中追加。
+type notifyServer struct {
+	mu       sync.Mutex
+	list     []map[string]interface{}
+	archived []string
+}
+
+func (s *notifyServer) add(id, importance, title string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = append(s.list, map[string]interface{}{
+		"id": id, "title": title, "subject": "subject " + id, "description": "desc " + id,
+		"importance": importance, "timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+func (s *notifyServer) Archived() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.archived...)
+}
+
+func newNotifyServer(t *testing.T) (*httptest.Server, *notifyServer) {
+	t.Helper()
+
+	ns := &notifyServer{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		ns.mu.Lock()
+		defer ns.mu.Unlock()
+		switch {
+		case strings.Contains(req.Query, "archiveNotification("):
+			ns.archived = append(ns.archived, req.Variables["id"].(string))
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"archiveNotification": map[string]interface{}{"id": req.Variables["id"]}}})
+		case strings.Contains(req.Query, "archiveAll("):
+			ns.archived = append(ns.archived, "all:"+req.Variables["importance"].(string))
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"archiveAll": map[string]interface{}{"unread": map[string]interface{}{"total": 1}}}})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"notifications": map[string]interface{}{"list": ns.list}},
+			})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, ns
+}
+
+func TestNotifyBridge_RelayByImportanceAndDedupeAcrossRestart(t *testing.T) {
+	t.Parallel()
+
+	srv, ns := newNotifyServer(t)
+	ns.add("n-old", "ALERT", "旧通知")
+
+	kv, err := store.Open(filepath.Join(t.TempDir(), "state.log"))
+	if err != nil {
+		t.Fatalf("store.Open() error: %v", err)
+	}
+	t.Cleanup(func() { _ = kv.Close() })
+
+	recipients := func(instanceID, permission string) []string {
+		if instanceID != "tower" {
+			return nil
+		}
+		switch permission {
+		case "unraid.notify.alert":
+			return []string{"admin", "ops"}
+		case "unraid.notify.warning":
+			return []string{"admin"}
+		default:
+			return []string{"admin"}
+		}
+	}
+	wc := &recordWeCom{}
+	newBridge := func() *NotifyBridge {
+		return NewNotifyBridge(NotifyBridgeDeps{
+			WeCom:      wc,
+			Recipients: recipients,
+			Instances:  []Instance{{ID: "tower", Name: "Tower", Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client())}},
+			Config:     NotifyConfig{Enabled: true, Interval: time.Minute, Importance: []string{"alert", "warning"}},
+			Store:      kv,
+		})
+	}
+
+	b := newBridge()
+	// 首次运行仅建立基线，不转发已有通知。
+	b.pollOnce()
+	if got := len(wc.Cards()); got != 0 {
+		t.Fatalf("cards after baseline = %d, want 0", got)
+	}
+
+	ns.add("n-1", "ALERT", "Array 磁盘过热")
+	ns.add("n-2", "WARNING", "Docker 更新可用")
+	ns.add("n-3", "INFO", "备份完成")
+	b.pollOnce()
+
+	var got []string
+	for _, c := range wc.Cards() {
+		got = append(got, c.ToUser+"="+mustJSON(t, c.Card))
+	}
+	if len(got) != 3 {
+		t.Fatalf("cards = %d, want 3 (alert → admin/ops, warning → admin)", len(got))
+	}
+	joined := strings.Join(got, "\n")
+	for _, want := range []string{"ops=", "Array 磁盘过热", "Docker 更新可用", wecom.EventKeyUnraidNotifyArchivePrefix + "tower:n-1", wecom.EventKeyUnraidNotifyArchiveAllPrefix + "tower:WARNING", "desc n-1"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("cards missing %q:\n%s", want, joined)
+		}
+	}
+	if strings.Contains(joined, "备份完成") || strings.Contains(joined, "旧通知") {
+		t.Fatalf("cards should skip info and baseline notifications:\n%s", joined)
+	}
+
+	// 重启（新建 bridge，复用持久化存储）后不重复推送。
+	b2 := newBridge()
+	b2.pollOnce()
+	if got := len(wc.Cards()); got != 3 {
+		t.Fatalf("cards after restart = %d, want 3", got)
+	}
+	ns.add("n-4", "ALERT", "UPS 断电")
+	b2.pollOnce()
+	cards := wc.Cards()
+	if len(cards) != 5 || !strings.Contains(mustJSON(t, cards[4].Card), "UPS 断电") {
+		t.Fatalf("cards after new alert = %d, want 5", len(cards))
+	}
+}
+
+func TestProvider_NotifyArchiveButtons(t *testing.T) {
+	t.Parallel()
+
+	srv, ns := newNotifyServer(t)
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{
+		WeCom: wc,
+		Instances: []Instance{
+			{ID: "tower", Name: "Tower", Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client())},
+			{ID: "backup", Name: "Backup", Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client())},
+		},
+		State: state,
+	})
+	ctx := context.Background()
+
+	if got := p.EventPermission(wecom.EventKeyUnraidNotifyArchivePrefix + "tower:n-1"); got != "unraid.notify.archive" {
+		t.Fatalf("EventPermission(archive) = %q", got)
+	}
+	if ok, err := p.HandleEvent(ctx, "u", wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidNotifyArchivePrefix + "tower:srv:n-1.notify"}); err != nil || !ok {
+		t.Fatalf("HandleEvent(archive) ok=%v err=%v", ok, err)
+	}
+	if _, err := p.HandleEvent(ctx, "u", wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidNotifyArchiveAllPrefix + "backup:WARNING"}); err != nil {
+		t.Fatalf("HandleEvent(archive all) error: %v", err)
+	}
+	if got := strings.Join(ns.Archived(), ","); got != "srv:n-1.notify,all:WARNING" {
+		t.Fatalf("archived = %q", got)
+	}
+	texts := wc.Texts()
+	if !strings.Contains(texts[0].Content, "已归档") || !strings.Contains(texts[1].Content, "剩余未读 1 条") {
+		t.Fatalf("replies = %+v", texts)
+	}
+
+	if _, err := p.HandleEvent(ctx, "u", wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidNotifyArchivePrefix + "nope:n-1"}); err != nil {
+		t.Fatalf("HandleEvent(unknown instance) error: %v", err)
+	}
+	if texts := wc.Texts(); !strings.Contains(texts[len(texts)-1].Content, "不可用") {
+		t.Fatalf("unknown instance reply = %q", texts[len(texts)-1].Content)
+	}
+}
+
+func TestProvider_NotifyArchiveScopedBinding(t *testing.T) {
+	t.Parallel()
+
+	srv, ns := newNotifyServer(t)
+	auth, err := core.NewAuthorizer(core.AuthorizerConfig{
+		Bindings: []core.RoleBinding{
+			{Subjects: []string{"mom"}, Role: core.RoleOperator, Scope: &core.Scope{Containers: []string{"plex"}}},
+			{Subjects: []string{"ops"}, Role: core.RoleOperator, Scope: &core.Scope{Instances: []string{"tower"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{
+		WeCom: wc,
+		Instances: []Instance{
+			{ID: "tower", Name: "Tower", Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client())},
+			{ID: "backup", Name: "Backup", Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client())},
+		},
+		State: state,
+		Auth:  auth,
+	})
+	r := core.NewRouter(core.RouterDeps{WeCom: wc, Auth: auth, Providers: []core.ServiceProvider{p}, State: state})
+	click := func(userID, key string) string {
+		t.Helper()
+		if err := r.HandleMessage(context.Background(), wecom.IncomingMessage{FromUserName: userID, MsgType: "event", Event: "template_card_event", EventKey: key}); err != nil {
+			t.Fatalf("HandleMessage(%s) error: %v", key, err)
+		}
+		texts := wc.Texts()
+		return texts[len(texts)-1].Content
+	}
+
+	// 仅限定容器的运维账号不能归档整台实例的通知。
+	if got := click("mom", wecom.EventKeyUnraidNotifyArchivePrefix+"tower:n-1"); !strings.Contains(got, "无权限") {
+		t.Fatalf("container-scoped archive reply = %q, want forbidden", got)
+	}
+	if got := click("mom", wecom.EventKeyUnraidNotifyArchiveAllPrefix+"tower:ALERT"); !strings.Contains(got, "无权限") {
+		t.Fatalf("container-scoped archive all reply = %q, want forbidden", got)
+	}
+	if got := click("ops", wecom.EventKeyUnraidNotifyArchiveAllPrefix+"backup:ALERT"); !strings.Contains(got, "无权限") {
+		t.Fatalf("other instance archive all reply = %q, want forbidden", got)
+	}
+	if got := click("ops", wecom.EventKeyUnraidNotifyArchivePrefix+"tower:n-1"); !strings.Contains(got, "已归档") {
+		t.Fatalf("instance-scoped archive reply = %q", got)
+	}
+	if got := strings.Join(ns.Archived(), ","); got != "n-1" {
+		t.Fatalf("archived = %q, want n-1", got)
+	}
+}
+
+func TestNotifyBridge_CloseWaitsForPoll(t *testing.T) {
+	t.Parallel()
+
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case entered <- struct{}{}:
+		default:
+		}
+		<-release
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"notifications": map[string]interface{}{"list": []interface{}{}}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	b := NewNotifyBridge(NotifyBridgeDeps{
+		WeCom:      &recordWeCom{},
+		Recipients: func(string, string) []string { return []string{"admin"} },
+		Instances:  []Instance{{ID: "tower", Name: "Tower", Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client())}},
+		Config:     NotifyConfig{Enabled: true, Interval: time.Minute},
+	})
+	b.Start()
+	<-entered
+
+	closed := make(chan struct{})
+	go func() {
+		b.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatalf("Close() returned while poll is still running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Close() did not return after poll finished")
+	}
+}
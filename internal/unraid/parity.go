@@ -0,0 +1,324 @@
+package unraid
+
+// parity.go 封装 Unraid 校验检查（Query.array.parityCheckStatus，缺失时回退 Query.vars / Query.parityHistory / Mutation.parityCheck）。
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+)
+
+// 校验检查状态（ParityCheckStatus 枚举）。
+const (
+	ParityStatusNeverRun  = "NEVER_RUN"
+	ParityStatusRunning   = "RUNNING"
+	ParityStatusPaused    = "PAUSED"
+	ParityStatusCompleted = "COMPLETED"
+	ParityStatusCancelled = "CANCELLED"
+	ParityStatusFailed    = "FAILED"
+)
+
+// ParityCheck 为一次校验检查（当前进度或历史记录）。
+type ParityCheck struct {
+	Status string
+	// Date 为开始/完成时间（历史记录为完成时间），未上报时为零值。
+	Date time.Time
+	// Duration 为已用/总耗时。
+	Duration time.Duration
+	Speed    string
+	Errors   int64
+	// Progress 为进度百分比（0~100），仅当前状态有效。
+	Progress   int
+	Correcting bool
+}
+
+// Active 表示校验检查正在运行或已暂停。
+func (p ParityCheck) Active() bool {
+	return p.Status == ParityStatusRunning || p.Status == ParityStatusPaused
+}
+
+// ETA 按已用时长与进度估算剩余时间；无法估算时返回 0。
+func (p ParityCheck) ETA() time.Duration {
+	if p.Status != ParityStatusRunning || p.Progress <= 0 || p.Progress >= 100 || p.Duration <= 0 {
+		return 0
+	}
+	return p.Duration * time.Duration(100-p.Progress) / time.Duration(p.Progress)
+}
+
+type parityCheckResp struct {
+	Status     string       `json:"status"`
+	Date       string       `json:"date"`
+	Duration   *float64     `json:"duration"`
+	Speed      string       `json:"speed"`
+	Errors     bigIntString `json:"errors"`
+	Progress   *float64     `json:"progress"`
+	Correcting *bool        `json:"correcting"`
+	Paused     *bool        `json:"paused"`
+	Running    *bool        `json:"running"`
+}
+
+func (r parityCheckResp) convert() ParityCheck {
+	out := ParityCheck{
+		Status: strings.ToUpper(strings.TrimSpace(r.Status)),
+		Speed:  strings.TrimSpace(r.Speed),
+		Errors: parseInt64FromBigIntString(r.Errors),
+	}
+	// 兼容旧版本仅返回 running/paused 布尔值的情况。
+	if out.Status == "" {
+		switch {
+		case r.Paused != nil && *r.Paused:
+			out.Status = ParityStatusPaused
+		case r.Running != nil && *r.Running:
+			out.Status = ParityStatusRunning
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, strings.TrimSpace(r.Date)); err == nil {
+		out.Date = t
+	}
+	if r.Duration != nil && *r.Duration > 0 {
+		out.Duration = time.Duration(*r.Duration) * time.Second
+	}
+	if r.Progress != nil {
+		out.Progress = clampInt(int(*r.Progress), 0, 100)
+	}
+	if r.Correcting != nil {
+		out.Correcting = *r.Correcting
+	}
+	return out
+}
+
+// GetParityStatus 查询当前校验检查状态与进度。
+// 优先使用 array.parityCheckStatus；Unraid API 未提供该字段时回退到 vars（mdResync*）推算进度，
+// 空闲时以最近一次历史记录作为结果。
+func (c *Client) GetParityStatus(ctx context.Context) (ParityCheck, error) {
+	const q = `query { array { parityCheckStatus { status date duration speed errors progress correcting paused running } } }`
+	var resp struct {
+		Array struct {
+			ParityCheckStatus parityCheckResp `json:"parityCheckStatus"`
+		} `json:"array"`
+	}
+	if err := c.do(ctx, q, nil, &resp); err != nil {
+		if !isMaybeUnsupportedGraphQL(err) {
+			return ParityCheck{}, err
+		}
+		cur, errVars := c.getParityStatusFromVars(ctx)
+		if errVars != nil {
+			return ParityCheck{}, fmt.Errorf("%w；vars 回退查询失败：%v", err, errVars)
+		}
+		return cur, nil
+	}
+	return resp.Array.ParityCheckStatus.convert(), nil
+}
+
+// parityVarsResp 为 vars 中与校验检查相关的字段（emhttp 原始变量，数值可能以字符串返回）。
+type parityVarsResp struct {
+	MdResync     bigIntString `json:"mdResync"`
+	MdResyncPos  bigIntString `json:"mdResyncPos"`
+	MdResyncSize bigIntString `json:"mdResyncSize"`
+	MdResyncDt   bigIntString `json:"mdResyncDt"`
+	MdResyncDb   bigIntString `json:"mdResyncDb"`
+	MdResyncCorr bigIntString `json:"mdResyncCorr"`
+	SbSyncErrs   bigIntString `json:"sbSyncErrs"`
+}
+
+// convert 按 emhttp 约定推算状态：mdResyncPos > 0 表示检查进行中，此时 mdResync 为 0 表示已暂停；
+// 速度由最近采样区间（mdResyncDb KiB / mdResyncDt 秒）计算。
+func (r parityVarsResp) convert() ParityCheck {
+	pos := parseInt64FromBigIntString(r.MdResyncPos)
+	out := ParityCheck{
+		Errors:     parseInt64FromBigIntString(r.SbSyncErrs),
+		Correcting: parseInt64FromBigIntString(r.MdResyncCorr) != 0,
+	}
+	if pos <= 0 {
+		return out
+	}
+	out.Status = ParityStatusRunning
+	if parseInt64FromBigIntString(r.MdResync) == 0 {
+		out.Status = ParityStatusPaused
+	}
+	if size := parseInt64FromBigIntString(r.MdResyncSize); size > 0 {
+		out.Progress = clampInt(int(pos*100/size), 0, 100)
+	}
+	if dt, db := parseInt64FromBigIntString(r.MdResyncDt), parseInt64FromBigIntString(r.MdResyncDb); dt > 0 && db > 0 && out.Status == ParityStatusRunning {
+		out.Speed = fmt.Sprintf("%.1f MB/s", float64(db)/float64(dt)/1024)
+	}
+	return out
+}
+
+// getParityStatusFromVars 由 vars 推算当前校验检查状态；未在检查时以最近一次历史记录补全状态、时间与错误数。
+func (c *Client) getParityStatusFromVars(ctx context.Context) (ParityCheck, error) {
+	const q = `query { vars { mdResync mdResyncPos mdResyncSize mdResyncDt mdResyncDb mdResyncCorr sbSyncErrs } }`
+	var resp struct {
+		Vars parityVarsResp `json:"vars"`
+	}
+	if err := c.do(ctx, q, nil, &resp); err != nil {
+		return ParityCheck{}, err
+	}
+	cur := resp.Vars.convert()
+	if cur.Active() {
+		return cur, nil
+	}
+	list, err := c.ListParityHistory(ctx)
+	if err != nil {
+		return cur, nil
+	}
+	if len(list) == 0 {
+		cur.Status = ParityStatusNeverRun
+		return cur, nil
+	}
+	return list[0], nil
+}
+
+// ListParityHistory 返回校验检查历史（按时间倒序）。
+func (c *Client) ListParityHistory(ctx context.Context) ([]ParityCheck, error) {
+	const q = `query { parityHistory { date duration speed status errors } }`
+	var resp struct {
+		ParityHistory []parityCheckResp `json:"parityHistory"`
+	}
+	if err := c.do(ctx, q, nil, &resp); err != nil {
+		return nil, err
+	}
+	out := make([]ParityCheck, 0, len(resp.ParityHistory))
+	for _, r := range resp.ParityHistory {
+		out = append(out, r.convert())
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Date.After(out[j].Date) })
+	return out, nil
+}
+
+// ParityAction 执行校验检查动作（start/pause/resume/cancel）；ParityCheckMutations 返回 JSON，不做结果判定。
+func (c *Client) ParityAction(ctx context.Context, action core.Action) error {
+	var field string
+	var vars map[string]interface{}
+	switch action {
+	case core.ActionUnraidParityStart, core.ActionUnraidParityStartCorrect:
+		field = "start(correct: $correct)"
+		vars = map[string]interface{}{"correct": action == core.ActionUnraidParityStartCorrect}
+	case core.ActionUnraidParityPause:
+		field = "pause"
+	case core.ActionUnraidParityResume:
+		field = "resume"
+	case core.ActionUnraidParityCancel:
+		field = "cancel"
+	default:
+		return fmt.Errorf("未知校验检查动作: %s", action)
+	}
+
+	q := fmt.Sprintf(`mutation ParityAction { parityCheck { %s } }`, field)
+	if vars != nil {
+		q = fmt.Sprintf(`mutation ParityAction($correct: Boolean!) { parityCheck { %s } }`, field)
+	}
+	var resp struct {
+		ParityCheck map[string]interface{} `json:"parityCheck"`
+	}
+	return c.do(ctx, q, vars, &resp)
+}
+
+func isParityAction(action core.Action) bool {
+	switch action {
+	case core.ActionUnraidParityStart, core.ActionUnraidParityStartCorrect,
+		core.ActionUnraidParityPause, core.ActionUnraidParityResume, core.ActionUnraidParityCancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// parityActionsForStatus 返回当前状态下可执行的校验检查动作（用于卡片按钮）。
+func parityActionsForStatus(status string) []core.Action {
+	switch status {
+	case ParityStatusRunning:
+		return []core.Action{core.ActionUnraidParityPause, core.ActionUnraidParityCancel}
+	case ParityStatusPaused:
+		return []core.Action{core.ActionUnraidParityResume, core.ActionUnraidParityCancel}
+	default:
+		return []core.Action{core.ActionUnraidParityStart, core.ActionUnraidParityStartCorrect}
+	}
+}
+
+func parityStatusText(status string) string {
+	switch status {
+	case ParityStatusNeverRun:
+		return "从未运行"
+	case ParityStatusRunning:
+		return "运行中"
+	case ParityStatusPaused:
+		return "已暂停"
+	case ParityStatusCompleted:
+		return "已完成"
+	case ParityStatusCancelled:
+		return "已取消"
+	case ParityStatusFailed:
+		return "失败"
+	case "":
+		return "未知"
+	default:
+		return status
+	}
+}
+
+func formatParityDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	h := int(d / time.Hour)
+	m := int((d % time.Hour) / time.Minute)
+	if h > 0 {
+		return fmt.Sprintf("%d小时%d分", h, m)
+	}
+	return fmt.Sprintf("%d分", m)
+}
+
+// formatParityStatus 渲染当前校验检查状态：进行中时含进度/速度/已用时长/预计剩余。
+func formatParityStatus(p ParityCheck) string {
+	var b strings.Builder
+	b.WriteString("校验检查\n")
+	fmt.Fprintf(&b, "状态：%s", parityStatusText(p.Status))
+	if p.Active() {
+		mode := "只读检查"
+		if p.Correcting {
+			mode = "修正错误"
+		}
+		fmt.Fprintf(&b, "（%s）\n进度：%d%%", mode, p.Progress)
+		if p.Speed != "" {
+			fmt.Fprintf(&b, "\n速度：%s", p.Speed)
+		}
+		if p.Duration > 0 {
+			fmt.Fprintf(&b, "\n已用：%s", formatParityDuration(p.Duration))
+		}
+		if eta := p.ETA(); eta > 0 {
+			fmt.Fprintf(&b, "\n预计剩余：%s", formatParityDuration(eta))
+		}
+	} else if p.Status != ParityStatusNeverRun && !p.Date.IsZero() {
+		fmt.Fprintf(&b, "\n上次：%s", p.Date.Local().Format("2006-01-02 15:04"))
+	}
+	fmt.Fprintf(&b, "\n错误数：%d", p.Errors)
+	return b.String()
+}
+
+// formatParityHistory 渲染最近 limit 条校验检查历史。
+func formatParityHistory(list []ParityCheck, limit int) string {
+	if len(list) == 0 {
+		return "校验检查历史：无"
+	}
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "校验检查历史（最近 %d 次）", len(list))
+	for _, p := range list {
+		date := "-"
+		if !p.Date.IsZero() {
+			date = p.Date.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(&b, "\n- %s %s | 错误 %d", date, parityStatusText(p.Status), p.Errors)
+		if p.Duration > 0 {
+			fmt.Fprintf(&b, " | 耗时 %s", formatParityDuration(p.Duration))
+		}
+		if p.Speed != "" {
+			fmt.Fprintf(&b, " | %s", p.Speed)
+		}
+	}
+	return b.String()
+}
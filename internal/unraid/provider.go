@@ -1,10 +1,11 @@
 package unraid
 
-// provider.go 将 Unraid 容器管理与查看能力适配为可插拔的企业微信交互 Provider。
+// provider.go 将 Unraid 容器管理与查看能力适配为可插拔的企业微信交互 Provider（支持多实例）。
 import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -15,23 +16,67 @@ import (
 	"github.com/zcw199604/wecom-home-ops/internal/wecom"
 )
 
-type ProviderDeps struct {
-	WeCom  core.WeComSender
+// Instance 为一台 Unraid；ID 为空表示旧的单台配置（授权与操作记录不带实例）。
+type Instance struct {
+	ID     string
+	Name   string
 	Client *Client
-	State  *core.StateStore
+}
+
+type ProviderDeps struct {
+	WeCom core.WeComSender
+	// Instances 为多台 Unraid；Client 兼容单台写法（等价于 ID 为空的唯一实例），二者同时提供时忽略 Client。
+	Instances []Instance
+	Client    *Client
+	State     core.StateStore
+	// Auth 可选：按实例与容器名作用范围过滤列表与校验动作；为空时不限制。
+	Auth core.ResourceAuthorizer
+	// Alerts 可选：发起校验检查后登记发起人，检查结束时推送完成通知。
+	Alerts *AlertManager
 }
 
 type Provider struct {
 	wecom  core.WeComSender
-	client *Client
-	state  *core.StateStore
+	state  core.StateStore
+	auth   core.ResourceAuthorizer
+	alerts *AlertManager
+
+	instances map[string]Instance
+	order     []Instance
+
+	follows *logFollows
 }
 
 func NewProvider(deps ProviderDeps) *Provider {
+	candidates := deps.Instances
+	if len(candidates) == 0 && deps.Client != nil {
+		candidates = []Instance{{Name: "Unraid", Client: deps.Client}}
+	}
+
+	instances := make(map[string]Instance)
+	var order []Instance
+	for _, ins := range candidates {
+		// ID 为空仅允许作为唯一实例（旧的单台配置）。
+		if ins.Client == nil || strings.TrimSpace(ins.Name) == "" || (ins.ID == "" && len(candidates) > 1) {
+			continue
+		}
+		if _, exists := instances[ins.ID]; exists {
+			continue
+		}
+		instances[ins.ID] = ins
+		order = append(order, ins)
+	}
+
+	sort.SliceStable(order, func(i, j int) bool { return order[i].ID < order[j].ID })
+
 	return &Provider{
-		wecom:  deps.WeCom,
-		client: deps.Client,
-		state:  deps.State,
+		wecom:     deps.WeCom,
+		state:     deps.State,
+		auth:      deps.Auth,
+		alerts:    deps.Alerts,
+		instances: instances,
+		order:     order,
+		follows:   newLogFollows(),
 	}
 }
 
@@ -39,14 +84,62 @@ func (p *Provider) Key() string { return "unraid" }
 
 func (p *Provider) DisplayName() string { return "Unraid 容器" }
 
+// MenuButton 声明应用自定义菜单中的“Unraid”一级菜单。
+func (p *Provider) MenuButton() wecom.MenuButton {
+	return wecom.MenuButton{
+		Name: "Unraid",
+		SubButtons: []wecom.MenuButton{
+			{Type: "click", Name: "进入菜单", Key: wecom.EventKeyServiceSelectPrefix + p.Key()},
+			{Type: "click", Name: "容器操作", Key: wecom.EventKeyUnraidMenuOps},
+			{Type: "click", Name: "容器查看", Key: wecom.EventKeyUnraidMenuView},
+			{Type: "click", Name: "系统监控", Key: wecom.EventKeyUnraidMenuSystem},
+			{Type: "click", Name: "虚拟机", Key: wecom.EventKeyUnraidMenuVM},
+		},
+	}
+}
+
 func (p *Provider) EntryKeywords() []string {
 	return []string{"容器", "docker", "unraid"}
 }
 
 func (p *Provider) OnEnter(ctx context.Context, userID string) error {
+	if len(p.order) <= 1 {
+		// 单台（或未配置）：直接进入菜单，与旧版本一致。
+		return p.sendEntryCard(ctx, userID, p.soleInstance())
+	}
+
+	visible := p.visibleInstances(userID)
+	switch len(visible) {
+	case 0:
+		return p.wecom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: "无权限：当前账号未被授权访问任何 Unraid 实例。",
+		})
+	case 1:
+		p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: visible[0].ID})
+		return p.sendEntryCard(ctx, userID, visible[0])
+	}
+
+	p.state.Set(userID, core.ConversationState{ServiceKey: p.Key()})
+	var opts []wecom.UnraidInstanceOption
+	for _, ins := range visible {
+		opts = append(opts, wecom.UnraidInstanceOption{ID: ins.ID, Name: ins.Name})
+	}
 	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
 		ToUser: userID,
-		Card:   wecom.NewUnraidEntryCard(),
+		Card:   wecom.NewUnraidInstanceSelectCard(opts),
+	})
+}
+
+func (p *Provider) sendEntryCard(ctx context.Context, userID string, ins Instance) error {
+	var opts wecom.UnraidEntryCardOptions
+	if len(p.order) > 1 {
+		opts.InstanceName = ins.Name
+		opts.ShowSwitchInstance = len(p.visibleInstances(userID)) > 1
+	}
+	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card:   wecom.NewUnraidEntryCard(opts),
 	})
 }
 
@@ -56,6 +149,20 @@ func (p *Provider) HandleText(ctx context.Context, userID, content string) (bool
 		return false, nil
 	}
 
+	// 直接输入“重启 sonarr radarr [@实例]”等操作指令：无需先选择动作，支持一次确认多个容器。
+	if action, names, ok := parseOpCommand(content); ok {
+		names, instanceID := cutInstanceArg(names)
+		if instanceID != "" {
+			state.InstanceID = instanceID
+		}
+		ins, ok := p.instanceFromState(userID, state)
+		if !ok {
+			return true, p.sendInstanceRequired(ctx, userID)
+		}
+		state.Action = action
+		return true, p.confirmContainers(ctx, userID, ins, state, names)
+	}
+
 	switch state.Step {
 	case core.StepAwaitingUnraidViewAction:
 		action, ok := parseUnraidViewAction(content)
@@ -70,11 +177,7 @@ func (p *Provider) HandleText(ctx context.Context, userID, content string) (bool
 		state.Step = core.StepAwaitingContainerName
 		p.state.Set(userID, state)
 
-		prompt := fmt.Sprintf("已选择动作：%s\n请输入容器名：", action.DisplayName())
-		if action == core.ActionUnraidViewLogs {
-			prompt = fmt.Sprintf("已选择动作：%s\n请输入：容器名 [行数]（默认%d，最大%d）：", action.DisplayName(), defaultLogTail, maxLogTail)
-		}
-		return true, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: prompt})
+		return true, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: containerInputPrompt(action)})
 
 	case core.StepAwaitingUnraidOpsAction:
 		action, ok := parseUnraidOpsAction(content)
@@ -92,6 +195,13 @@ func (p *Provider) HandleText(ctx context.Context, userID, content string) (bool
 		return true, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: prompt})
 
 	case core.StepAwaitingUnraidSystemAction:
+		if isLogFilesMenuChoice(content) {
+			ins, ok := p.instanceFromState(userID, state)
+			if !ok {
+				return true, p.sendInstanceRequired(ctx, userID)
+			}
+			return true, p.sendLogFileSelectCard(ctx, userID, ins, 1)
+		}
 		action, ok := parseUnraidSystemAction(content)
 		if !ok {
 			return true, p.wecom.SendText(ctx, wecom.TextMessage{
@@ -99,21 +209,32 @@ func (p *Provider) HandleText(ctx context.Context, userID, content string) (bool
 				Content: unraidSystemTextMenu(),
 			})
 		}
+		ins, ok := p.instanceFromState(userID, state)
+		if !ok {
+			return true, p.sendInstanceRequired(ctx, userID)
+		}
 
 		state.Step = ""
 		state.Action = ""
 		state.ContainerName = ""
 		p.state.Set(userID, state)
-		return true, p.execViewAndReply(ctx, userID, action, "", 0)
+		return true, p.execViewAndReply(ctx, userID, ins, action, "", 0)
+
+	case core.StepAwaitingUnraidLogKeyword:
+		return true, p.handleLogFileGrepInput(ctx, userID, state, content)
 
 	case core.StepAwaitingContainerName:
 		if state.Action == core.ActionUnraidViewSystemStats || state.Action == core.ActionUnraidViewSystemStatsDetail {
+			ins, ok := p.instanceFromState(userID, state)
+			if !ok {
+				return true, p.sendInstanceRequired(ctx, userID)
+			}
 			action := state.Action
 			state.Step = ""
 			state.Action = ""
 			state.ContainerName = ""
 			p.state.Set(userID, state)
-			return true, p.execViewAndReply(ctx, userID, action, "", 0)
+			return true, p.execViewAndReply(ctx, userID, ins, action, "", 0)
 		}
 
 	default:
@@ -124,47 +245,258 @@ func (p *Provider) HandleText(ctx context.Context, userID, content string) (bool
 		return false, nil
 	}
 
-	containerNameRaw, logTail, err := parseContainerAndOptionalTail(content, state.Action)
+	ins, ok := p.instanceFromState(userID, state)
+	if !ok {
+		return true, p.sendInstanceRequired(ctx, userID)
+	}
+
+	if state.Action.RequiresConfirm() {
+		// 操作类动作支持一次输入多个容器（空格/逗号分隔）。
+		return true, p.confirmContainers(ctx, userID, ins, state, core.SplitTargets(content))
+	}
+
+	containerNameRaw, logTail, grep, err := parseContainerAndOptionalTail(content, state.Action)
 	if err != nil {
 		return true, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
 	}
+	resolved, ok, err := p.resolveContainers(ctx, userID, ins, state, []string{containerNameRaw})
+	if !ok {
+		return true, err
+	}
 
-	containerName, err := core.ValidateContainerName(containerNameRaw)
+	containerName, err := core.ValidateContainerName(resolved[0])
 	if err != nil {
 		return true, p.wecom.SendText(ctx, wecom.TextMessage{
 			ToUser:  userID,
 			Content: fmt.Sprintf("容器名不合法：%s", err.Error()),
 		})
 	}
+	if !p.allowed(userID, ins, state.Action, containerName) {
+		return true, p.sendContainerForbidden(ctx, userID, state.Action, containerName)
+	}
 
-	if state.Action.RequiresConfirm() {
-		state.ContainerName = containerName
-		state.Step = core.StepAwaitingConfirm
+	// 查看类动作：执行并回显，清除“待输入”状态但保留 ServiceKey 与实例。
+	action := state.Action
+	state.Step = ""
+	state.Action = ""
+	state.ContainerName = ""
+	p.state.Set(userID, state)
+
+	if action == core.ActionUnraidFollowLogs {
+		return true, p.startFollow(ctx, userID, ins, followTarget{Container: containerName}, logTail)
+	}
+	if grep != nil {
+		return true, p.grepLogsAndReply(ctx, userID, ins, containerName, logTail, *grep)
+	}
+	return true, p.execViewAndReply(ctx, userID, ins, action, containerName, logTail)
+}
+
+// confirmContainers 校验容器名与授权后进入待确认状态：单个容器沿用原确认卡片，多个容器合并为一次批量确认。
+func (p *Provider) confirmContainers(ctx context.Context, userID string, ins Instance, state core.ConversationState, rawNames []string) error {
+	if len(rawNames) == 0 {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "请输入容器名。"})
+	}
+	if len(rawNames) > core.MaxBatchTargets {
+		return p.wecom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: fmt.Sprintf("一次最多操作 %d 个容器，当前 %d 个，请分批执行。", core.MaxBatchTargets, len(rawNames)),
+		})
+	}
+	resolved, ok, err := p.resolveContainers(ctx, userID, ins, state, rawNames)
+	if !ok {
+		return err
+	}
+	names := make([]string, 0, len(resolved))
+	for _, raw := range resolved {
+		name, err := core.ValidateContainerName(raw)
+		if err != nil {
+			return p.wecom.SendText(ctx, wecom.TextMessage{
+				ToUser:  userID,
+				Content: fmt.Sprintf("容器名不合法（%s）：%s", raw, err.Error()),
+			})
+		}
+		if !p.allowed(userID, ins, state.Action, name) {
+			return p.sendContainerForbidden(ctx, userID, state.Action, name)
+		}
+		names = append(names, name)
+	}
+
+	state.ServiceKey = p.Key()
+	state.InstanceID = ins.ID
+	state.Step = core.StepAwaitingConfirm
+	if len(names) == 1 {
+		state.ContainerName = names[0]
+		state.ContainerNames = nil
 		p.state.Set(userID, state)
 
+		target := p.targetLabel(ins, state.ContainerName)
 		_ = p.wecom.SendText(ctx, wecom.TextMessage{
 			ToUser:  userID,
-			Content: fmt.Sprintf("确认执行：%s %s\n回复“确认”继续，回复“取消”终止。", state.Action.DisplayName(), state.ContainerName),
+			Content: fmt.Sprintf("确认执行：%s %s\n回复“确认”继续，回复“取消”终止。", state.Action.DisplayName(), target),
 		})
-		_ = p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
 			ToUser: userID,
-			Card:   wecom.NewConfirmCard(state.Action.DisplayName(), state.ContainerName),
+			Card:   wecom.NewConfirmCardWithFavorite(state.Action.DisplayName(), target, wecom.FavoriteAddButton(p.Key(), ins.ID, state.ContainerName)),
 		})
-		return true, nil
 	}
 
-	// 查看类动作：执行并回显，清除“待输入”状态但保留 ServiceKey。
-	action := state.Action
+	state.ContainerName = ""
+	state.ContainerNames = names
+	p.state.Set(userID, state)
+	labels := make([]string, 0, len(names))
+	for _, name := range names {
+		labels = append(labels, p.targetLabel(ins, name))
+	}
+	_ = p.wecom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: core.FormatBatchConfirm(state.Action, labels),
+	})
+	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card:   wecom.NewBatchConfirmCard(state.Action.DisplayName(), labels),
+	})
+}
+
+// resolveContainers 将输入解析为实际容器名：与现有容器完全一致时直接采用，否则在有权限的容器中模糊匹配
+// （前缀/子串/拼音首字母/拼写容错）。单个输入命中多个候选时发送选择卡片，批量输入存在歧义或未命中时提示；
+// 两种情况均返回 ok=false（err 为发送结果）。
+func (p *Provider) resolveContainers(ctx context.Context, userID string, ins Instance, state core.ConversationState, raws []string) ([]string, bool, error) {
+	all, err := p.listContainerNames(ctx, ins)
+	if err != nil {
+		return nil, false, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "获取容器列表失败：" + err.Error()})
+	}
+	existing := make(map[string]struct{}, len(all))
+	var candidates []core.FuzzyCandidate
+	for _, name := range all {
+		existing[name] = struct{}{}
+		if p.allowed(userID, ins, state.Action, name) {
+			candidates = append(candidates, core.FuzzyCandidate{Key: name, Name: name})
+		}
+	}
+
+	out := make([]string, 0, len(raws))
+	seen := make(map[string]struct{}, len(raws))
+	for _, raw := range raws {
+		name := normalizeName(raw)
+		if _, ok := existing[name]; !ok {
+			matches, unique := core.ResolveFuzzy(name, candidates)
+			switch {
+			case len(matches) == 0:
+				return nil, false, p.wecom.SendText(ctx, wecom.TextMessage{
+					ToUser:  userID,
+					Content: fmt.Sprintf("未找到容器：%s，请确认名称后重试。", raw),
+				})
+			case !unique && len(raws) == 1:
+				return nil, false, p.sendContainerChoices(ctx, userID, ins, state, raw, matches)
+			case !unique:
+				return nil, false, p.wecom.SendText(ctx, wecom.TextMessage{
+					ToUser:  userID,
+					Content: fmt.Sprintf("“%s”匹配多个容器：%s，请输入完整名称。", raw, core.FormatFuzzyChoices(matches)),
+				})
+			}
+			name = matches[0].Key
+		}
+		if _, dup := seen[name]; dup {
+			continue
+		}
+		seen[name] = struct{}{}
+		out = append(out, name)
+	}
+	return out, true, nil
+}
+
+// sendContainerChoices 发送模糊匹配候选的容器选择卡片，选定后沿用 handleContainerSelect 流程。
+func (p *Provider) sendContainerChoices(ctx context.Context, userID string, ins Instance, state core.ConversationState, raw string, matches []core.FuzzyMatch) error {
+	state.ServiceKey = p.Key()
+	state.InstanceID = ins.ID
 	state.Step = ""
-	state.Action = ""
 	state.ContainerName = ""
+	state.ContainerNames = nil
 	p.state.Set(userID, state)
 
-	return true, p.execViewAndReply(ctx, userID, action, containerName, logTail)
+	var opts []wecom.UnraidContainerOption
+	for i, m := range matches {
+		if i >= core.MaxFuzzyChoices {
+			break
+		}
+		opts = append(opts, wecom.UnraidContainerOption{Name: m.Key, Text: truncateRunes(m.Name, 32)})
+	}
+	_ = p.wecom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: fmt.Sprintf("“%s”匹配多个容器：%s，请选择。", raw, core.FormatFuzzyChoices(matches)),
+	})
+	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card:   wecom.NewUnraidContainerSelectCard(state.Action.DisplayName(), 0, 0, opts, 0, 0),
+	})
+}
+
+// EventPermission 声明容器操作按钮所需权限（unraid.restart/stop/force_update）与虚拟机操作权限（unraid.vm.<action>），
+// 其余事件沿用 unraid.view。
+func (p *Provider) EventPermission(eventKey string) string {
+	if action, ok := strings.CutPrefix(eventKey, wecom.EventKeyUnraidVMActionPrefix); ok {
+		if isVMAction(core.Action(action)) {
+			return vmActionPermission(core.Action(action))
+		}
+		return ""
+	}
+	if strings.HasPrefix(eventKey, wecom.EventKeyUnraidNotifyArchivePrefix) || strings.HasPrefix(eventKey, wecom.EventKeyUnraidNotifyArchiveAllPrefix) {
+		return notifyArchivePermission
+	}
+	if action, ok := strings.CutPrefix(eventKey, wecom.EventKeyUnraidParityActionPrefix); ok {
+		if isParityAction(core.Action(action)) {
+			return parityActionPermission(core.Action(action))
+		}
+		return ""
+	}
+	if action, ok := strings.CutPrefix(eventKey, wecom.EventKeyUnraidContainerActionPrefix); ok {
+		if core.Action(action).RequiresConfirm() {
+			return core.ServicePermission(p.Key(), action)
+		}
+		return ""
+	}
+	switch eventKey {
+	case wecom.EventKeyUnraidRestart, wecom.EventKeyUnraidStop, wecom.EventKeyUnraidForceUpdate:
+		return core.ServicePermission(p.Key(), string(core.ActionFromEventKey(eventKey)))
+	default:
+		return ""
+	}
 }
 
 func (p *Provider) HandleEvent(ctx context.Context, userID string, msg wecom.IncomingMessage) (bool, error) {
 	key := strings.TrimSpace(msg.EventKey)
+	if id, ok := strings.CutPrefix(key, wecom.EventKeyUnraidInstanceSelectPrefix); ok {
+		ins, ok := p.instances[id]
+		if !ok || id == "" || !p.instanceAllowed(userID, ins) {
+			return true, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "实例不可用，请重新选择。"})
+		}
+		p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
+		return true, p.sendEntryCard(ctx, userID, ins)
+	}
+	// 通知卡片按钮携带实例 ID（主动推送，不依赖会话）。
+	if suffix, ok := strings.CutPrefix(key, wecom.EventKeyUnraidNotifyArchivePrefix); ok {
+		return true, p.archiveNotification(ctx, userID, suffix)
+	}
+	if suffix, ok := strings.CutPrefix(key, wecom.EventKeyUnraidNotifyArchiveAllPrefix); ok {
+		return true, p.archiveAllNotifications(ctx, userID, suffix)
+	}
+	if key == wecom.EventKeyUnraidFollowStop {
+		return true, p.handleFollowStop(ctx, userID)
+	}
+	if suffix, ok := strings.CutPrefix(key, wecom.EventKeyUnraidFollowStartPrefix); ok {
+		return true, p.handleFollowStart(ctx, userID, suffix)
+	}
+	if key == wecom.EventKeyUnraidSwitchInstance {
+		p.state.Clear(userID)
+		return true, p.OnEnter(ctx, userID)
+	}
+
+	state, _ := p.state.Get(userID)
+	if state.ServiceKey != p.Key() {
+		state = core.ConversationState{ServiceKey: p.Key()}
+	}
+	ins, ok := p.instanceFromState(userID, state)
+
 	if strings.HasPrefix(key, wecom.EventKeyUnraidContainerSelectPrefix) {
 		suffix := strings.TrimPrefix(key, wecom.EventKeyUnraidContainerSelectPrefix)
 		return true, p.handleContainerSelect(ctx, userID, suffix)
@@ -173,58 +505,195 @@ func (p *Provider) HandleEvent(ctx context.Context, userID string, msg wecom.Inc
 		suffix := strings.TrimPrefix(key, wecom.EventKeyUnraidContainerPagePrefix)
 		return true, p.handleContainerPage(ctx, userID, suffix)
 	}
+	if strings.HasPrefix(key, wecom.EventKeyUnraidContainerActionPrefix) {
+		suffix := strings.TrimPrefix(key, wecom.EventKeyUnraidContainerActionPrefix)
+		return true, p.handleContainerAction(ctx, userID, core.Action(suffix))
+	}
+
+	if suffix, parityOK := strings.CutPrefix(key, wecom.EventKeyUnraidParityActionPrefix); parityOK {
+		if !ok {
+			return true, p.OnEnter(ctx, userID)
+		}
+		return true, p.confirmParity(ctx, userID, ins, core.Action(suffix))
+	}
+	if suffix, ok := strings.CutPrefix(key, wecom.EventKeyUnraidVMActionPrefix); ok {
+		return true, p.handleVMAction(ctx, userID, core.Action(suffix))
+	}
+	if suffix, vmOK := strings.CutPrefix(key, wecom.EventKeyUnraidVMSelectPrefix); vmOK {
+		if !ok {
+			return true, p.OnEnter(ctx, userID)
+		}
+		return true, p.handleVMSelect(ctx, userID, ins, suffix)
+	}
+	if suffix, pageOK := strings.CutPrefix(key, wecom.EventKeyUnraidVMPagePrefix); pageOK {
+		if !ok {
+			return true, p.OnEnter(ctx, userID)
+		}
+		return true, p.handleVMPage(ctx, userID, ins, suffix)
+	}
+	if suffix, logOK := strings.CutPrefix(key, wecom.EventKeyUnraidLogFileSelectPrefix); logOK {
+		if !ok {
+			return true, p.OnEnter(ctx, userID)
+		}
+		return true, p.handleLogFileSelect(ctx, userID, ins, suffix)
+	}
+	if suffix, pageOK := strings.CutPrefix(key, wecom.EventKeyUnraidLogFilePagePrefix); pageOK {
+		if !ok {
+			return true, p.OnEnter(ctx, userID)
+		}
+		return true, p.handleLogFilePage(ctx, userID, ins, suffix)
+	}
+	if suffix, viewOK := strings.CutPrefix(key, wecom.EventKeyUnraidLogFileViewPrefix); viewOK {
+		return true, p.handleLogFileView(ctx, userID, suffix)
+	}
+	if key == wecom.EventKeyUnraidLogFileGrep {
+		return true, p.handleLogFileGrep(ctx, userID)
+	}
+
+	switch key {
+	case wecom.EventKeyUnraidMenuOps, wecom.EventKeyUnraidMenuView, wecom.EventKeyUnraidMenuSystem, wecom.EventKeyUnraidBackToMenu, wecom.EventKeyUnraidMenuVM,
+		wecom.EventKeyUnraidMenuParity, wecom.EventKeyUnraidParityHistory, wecom.EventKeyUnraidMenuLogFiles,
+		wecom.EventKeyUnraidRestart, wecom.EventKeyUnraidStop, wecom.EventKeyUnraidForceUpdate,
+		wecom.EventKeyUnraidViewStatus, wecom.EventKeyUnraidViewSystemStats, wecom.EventKeyUnraidViewSystemStatsDetail, wecom.EventKeyUnraidViewLogs,
+		wecom.EventKeyUnraidViewArray, wecom.EventKeyUnraidFollowLogs:
+		if !ok {
+			// 多台 Unraid 且尚未选择实例（如从应用菜单直接点击）：先选择实例。
+			return true, p.OnEnter(ctx, userID)
+		}
+	default:
+		return false, nil
+	}
 
 	switch key {
 	case wecom.EventKeyUnraidMenuOps:
-		p.state.Set(userID, core.ConversationState{ServiceKey: p.Key()})
+		p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
 		return true, p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{ToUser: userID, Card: wecom.NewUnraidOpsCard()})
 	case wecom.EventKeyUnraidMenuView:
-		p.state.Set(userID, core.ConversationState{ServiceKey: p.Key()})
+		p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
 		return true, p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{ToUser: userID, Card: wecom.NewUnraidViewCard()})
 	case wecom.EventKeyUnraidMenuSystem:
-		p.state.Set(userID, core.ConversationState{ServiceKey: p.Key()})
+		p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
 		return true, p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{ToUser: userID, Card: wecom.NewUnraidSystemCard()})
 	case wecom.EventKeyUnraidBackToMenu:
-		p.state.Set(userID, core.ConversationState{ServiceKey: p.Key()})
-		return true, p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{ToUser: userID, Card: wecom.NewUnraidEntryCard()})
-
-	case wecom.EventKeyUnraidRestart, wecom.EventKeyUnraidStop, wecom.EventKeyUnraidForceUpdate,
-		wecom.EventKeyUnraidViewStatus, wecom.EventKeyUnraidViewSystemStats, wecom.EventKeyUnraidViewSystemStatsDetail, wecom.EventKeyUnraidViewLogs:
-		action := core.ActionFromEventKey(key)
-
-		switch action {
-		case core.ActionUnraidViewSystemStats, core.ActionUnraidViewSystemStatsDetail:
-			return true, p.execViewAndReply(ctx, userID, action, "", 0)
-		default:
-			state := core.ConversationState{
-				ServiceKey: p.Key(),
-				Action:     action,
-			}
-			p.state.Set(userID, state)
+		p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
+		return true, p.sendEntryCard(ctx, userID, ins)
+	case wecom.EventKeyUnraidMenuVM:
+		p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
+		return true, p.sendVMSelectCard(ctx, userID, ins, 1)
+	case wecom.EventKeyUnraidMenuParity:
+		p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
+		return true, p.sendParityCard(ctx, userID, ins)
+	case wecom.EventKeyUnraidParityHistory:
+		return true, p.execViewAndReply(ctx, userID, ins, core.ActionUnraidViewParity, "", 0)
+	case wecom.EventKeyUnraidMenuLogFiles:
+		p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
+		return true, p.sendLogFileSelectCard(ctx, userID, ins, 1)
+	}
+
+	action := core.ActionFromEventKey(key)
+	switch action {
+	case core.ActionUnraidViewSystemStats, core.ActionUnraidViewSystemStatsDetail, core.ActionUnraidViewArray:
+		return true, p.execViewAndReply(ctx, userID, ins, action, "", 0)
+	}
 
-			if err := p.sendContainerSelectCard(ctx, userID, action, 1); err != nil {
-				state.Step = core.StepAwaitingContainerName
-				p.state.Set(userID, state)
+	state = core.ConversationState{
+		ServiceKey: p.Key(),
+		InstanceID: ins.ID,
+		Action:     action,
+	}
+	p.state.Set(userID, state)
 
-				prompt := fmt.Sprintf("已选择动作：%s\n请输入容器名：", action.DisplayName())
-				if action == core.ActionUnraidViewLogs {
-					prompt = fmt.Sprintf("已选择动作：%s\n请输入：容器名 [行数]（默认%d，最大%d）：", action.DisplayName(), defaultLogTail, maxLogTail)
-				}
-				return true, p.wecom.SendText(ctx, wecom.TextMessage{
-					ToUser:  userID,
-					Content: "获取容器列表失败，已切换为文本输入。\n" + prompt,
-				})
-			}
-			return true, nil
+	if err := p.sendContainerSelectCard(ctx, userID, ins, action, 1); err != nil {
+		state.Step = core.StepAwaitingContainerName
+		p.state.Set(userID, state)
+
+		return true, p.wecom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: "获取容器列表失败，已切换为文本输入。\n" + containerInputPrompt(action),
+		})
+	}
+	return true, nil
+}
+
+// handleContainerAction 处理容器操作卡片（由“常用”打开）上的按钮：目标为会话中的当前实例与容器。
+func (p *Provider) handleContainerAction(ctx context.Context, userID string, action core.Action) error {
+	state, ok := p.state.Get(userID)
+	if !ok || state.ServiceKey != p.Key() || strings.TrimSpace(state.ContainerName) == "" {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "会话已过期，请发送“常用”重新打开目标。"})
+	}
+	ins, ok := p.instanceFromState(userID, state)
+	if !ok {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "实例不可用，请发送“常用”重新打开目标。"})
+	}
+	name := state.ContainerName
+
+	switch action {
+	case core.ActionUnraidRestart, core.ActionUnraidStop, core.ActionUnraidForceUpdate:
+		state.Action = action
+		return p.confirmContainers(ctx, userID, ins, state, []string{name})
+	case core.ActionUnraidViewStatus, core.ActionUnraidViewLogs:
+		if !p.allowed(userID, ins, action, name) {
+			return p.sendContainerForbidden(ctx, userID, action, name)
+		}
+		tail := 0
+		if action == core.ActionUnraidViewLogs {
+			tail = defaultLogTail
 		}
+		return p.execViewAndReply(ctx, userID, ins, action, name, tail)
 	default:
-		return false, nil
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未知动作，请返回后重试。"})
 	}
 }
 
+// ResolveFavorite 校验收藏的实例与容器存在且在用户作用范围内。
+func (p *Provider) ResolveFavorite(ctx context.Context, userID string, fav core.Favorite) (core.Favorite, error) {
+	ins, ok := p.instanceByID(fav.InstanceID)
+	if !ok || !p.instanceAllowed(userID, ins) {
+		return fav, fmt.Errorf("实例不可用：%s", fav.InstanceID)
+	}
+	name, err := core.ValidateContainerName(fav.Target)
+	if err != nil {
+		return fav, fmt.Errorf("容器名不合法：%w", err)
+	}
+	all, err := p.listContainerNames(ctx, ins)
+	if err != nil {
+		return fav, fmt.Errorf("获取容器列表失败：%w", err)
+	}
+	if !slices.Contains(all, name) {
+		return fav, fmt.Errorf("未找到容器：%s", name)
+	}
+	if !p.allowed(userID, ins, core.ActionUnraidViewStatus, name) {
+		return fav, fmt.Errorf("无权限：当前账号的授权范围不包含容器 %s", name)
+	}
+	fav.InstanceID = ins.ID
+	fav.Target = name
+	fav.Name = name
+	if len(p.order) > 1 {
+		fav.Name = ins.Name + " " + name
+	}
+	return fav, nil
+}
+
+// OpenFavorite 以收藏的实例与容器为当前目标下发容器操作卡片。
+func (p *Provider) OpenFavorite(ctx context.Context, userID string, fav core.Favorite) error {
+	ins, ok := p.instanceByID(fav.InstanceID)
+	if !ok || !p.instanceAllowed(userID, ins) {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "实例不可用：" + fav.InstanceID})
+	}
+	if !p.allowed(userID, ins, core.ActionUnraidViewStatus, fav.Target) {
+		return p.sendContainerForbidden(ctx, userID, core.ActionUnraidViewStatus, fav.Target)
+	}
+	p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID, ContainerName: fav.Target})
+	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card:   wecom.NewUnraidContainerActionCard(p.targetLabel(ins, fav.Target), wecom.FavoriteRemoveButton(fav.ID)),
+	})
+}
+
 func (p *Provider) handleContainerPage(ctx context.Context, userID string, pageStr string) error {
 	state, ok := p.state.Get(userID)
-	if !ok || state.ServiceKey != p.Key() || !unraidActionNeedsContainer(state.Action) {
+	ins, insOK := p.instanceFromState(userID, state)
+	if !ok || state.ServiceKey != p.Key() || !unraidActionNeedsContainer(state.Action) || !insOK {
 		_ = p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "会话已过期，请重新选择动作。"})
 		_ = p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{ToUser: userID, Card: wecom.NewUnraidOpsCard()})
 		return nil
@@ -234,7 +703,7 @@ func (p *Provider) handleContainerPage(ctx context.Context, userID string, pageS
 	if err != nil || page <= 0 {
 		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "页码不合法，请重新选择。"})
 	}
-	return p.sendContainerSelectCard(ctx, userID, state.Action, page)
+	return p.sendContainerSelectCard(ctx, userID, ins, state.Action, page)
 }
 
 func (p *Provider) handleContainerSelect(ctx context.Context, userID string, containerNameRaw string) error {
@@ -247,20 +716,26 @@ func (p *Provider) handleContainerSelect(ctx context.Context, userID string, con
 	}
 
 	state, ok := p.state.Get(userID)
-	if !ok || state.ServiceKey != p.Key() || !unraidActionNeedsContainer(state.Action) {
+	ins, insOK := p.instanceFromState(userID, state)
+	if !ok || state.ServiceKey != p.Key() || !unraidActionNeedsContainer(state.Action) || !insOK {
 		_ = p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "会话已过期，请重新选择动作。"})
 		_ = p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{ToUser: userID, Card: wecom.NewUnraidOpsCard()})
 		return nil
 	}
+	if !p.allowed(userID, ins, state.Action, containerName) {
+		return p.sendContainerForbidden(ctx, userID, state.Action, containerName)
+	}
 
 	switch state.Action {
 	case core.ActionUnraidRestart, core.ActionUnraidStop, core.ActionUnraidForceUpdate:
+		state.InstanceID = ins.ID
 		state.Step = core.StepAwaitingConfirm
 		state.ContainerName = containerName
+		state.ContainerNames = nil
 		p.state.Set(userID, state)
 		return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
 			ToUser: userID,
-			Card:   wecom.NewConfirmCard(state.Action.DisplayName(), containerName),
+			Card:   wecom.NewConfirmCardWithFavorite(state.Action.DisplayName(), p.targetLabel(ins, containerName), wecom.FavoriteAddButton(p.Key(), ins.ID, containerName)),
 		})
 
 	case core.ActionUnraidViewStatus:
@@ -269,7 +744,7 @@ func (p *Provider) handleContainerSelect(ctx context.Context, userID string, con
 		state.Action = ""
 		state.ContainerName = ""
 		p.state.Set(userID, state)
-		return p.execViewAndReply(ctx, userID, action, containerName, 0)
+		return p.execViewAndReply(ctx, userID, ins, action, containerName, 0)
 
 	case core.ActionUnraidViewLogs:
 		action := state.Action
@@ -277,7 +752,10 @@ func (p *Provider) handleContainerSelect(ctx context.Context, userID string, con
 		state.Action = ""
 		state.ContainerName = ""
 		p.state.Set(userID, state)
-		return p.execViewAndReply(ctx, userID, action, containerName, defaultLogTail)
+		return p.execViewAndReply(ctx, userID, ins, action, containerName, defaultLogTail)
+
+	case core.ActionUnraidFollowLogs:
+		return p.sendFollowDurationCard(ctx, userID, ins, containerName)
 
 	default:
 		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未知动作，请返回后重试。"})
@@ -286,16 +764,28 @@ func (p *Provider) handleContainerSelect(ctx context.Context, userID string, con
 
 const unraidContainerSelectPageSize = 3
 
-func (p *Provider) sendContainerSelectCard(ctx context.Context, userID string, action core.Action, page int) error {
-	if p.client == nil {
+func (p *Provider) sendContainerSelectCard(ctx context.Context, userID string, ins Instance, action core.Action, page int) error {
+	if ins.Client == nil {
 		return errors.New("unraid client 未配置")
 	}
 
-	names, err := p.listContainerNames(ctx)
+	all, err := p.listContainerNames(ctx, ins)
 	if err != nil {
 		return err
 	}
+	names := make([]string, 0, len(all))
+	for _, name := range all {
+		if p.allowed(userID, ins, action, name) {
+			names = append(names, name)
+		}
+	}
 	if len(names) == 0 {
+		if len(all) > 0 {
+			return p.wecom.SendText(ctx, wecom.TextMessage{
+				ToUser:  userID,
+				Content: fmt.Sprintf("没有可%s的容器：当前账号的授权范围内无匹配容器。", action.DisplayName()),
+			})
+		}
 		return errors.New("未找到任何容器")
 	}
 
@@ -343,14 +833,18 @@ func (p *Provider) sendContainerSelectCard(ctx context.Context, userID string, a
 		nextPage = page + 1
 	}
 
+	title := action.DisplayName()
+	if len(p.order) > 1 {
+		title = title + "（" + ins.Name + "）"
+	}
 	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
 		ToUser: userID,
-		Card:   wecom.NewUnraidContainerSelectCard(action.DisplayName(), page, totalPages, opts, prevPage, nextPage),
+		Card:   wecom.NewUnraidContainerSelectCard(title, page, totalPages, opts, prevPage, nextPage),
 	})
 }
 
-func (p *Provider) listContainerNames(ctx context.Context) ([]string, error) {
-	if p.client == nil {
+func (p *Provider) listContainerNames(ctx context.Context, ins Instance) ([]string, error) {
+	if ins.Client == nil {
 		return nil, errors.New("unraid client 未配置")
 	}
 
@@ -362,7 +856,7 @@ func (p *Provider) listContainerNames(ctx context.Context) ([]string, error) {
 			} `json:"containers"`
 		} `json:"docker"`
 	}
-	if err := p.client.do(ctx, q, nil, &resp); err != nil {
+	if err := ins.Client.do(ctx, q, nil, &resp); err != nil {
 		return nil, err
 	}
 
@@ -385,10 +879,105 @@ func (p *Provider) listContainerNames(ctx context.Context) ([]string, error) {
 	return names, nil
 }
 
+// soleInstance 返回唯一的实例；未配置时返回空实例（执行时提示 client 未配置）。
+func (p *Provider) soleInstance() Instance {
+	if len(p.order) == 1 {
+		return p.order[0]
+	}
+	return Instance{}
+}
+
+// instanceByID 按 ID 查找实例；ID 为空（旧的单台配置/旧收藏与记录）时仅在只有一个实例时命中。
+func (p *Provider) instanceByID(id string) (Instance, bool) {
+	if id == "" {
+		if len(p.order) > 1 {
+			return Instance{}, false
+		}
+		return p.soleInstance(), true
+	}
+	ins, ok := p.instances[id]
+	return ins, ok
+}
+
+// instanceFromState 返回会话中的实例：会话未指定时，仅当用户只能访问一个实例时直接使用；多实例且未选择时 ok=false。
+func (p *Provider) instanceFromState(userID string, state core.ConversationState) (Instance, bool) {
+	if strings.TrimSpace(state.InstanceID) != "" || len(p.order) <= 1 {
+		ins, ok := p.instanceByID(strings.TrimSpace(state.InstanceID))
+		if !ok || !p.instanceAllowed(userID, ins) {
+			return Instance{}, false
+		}
+		return ins, true
+	}
+	visible := p.visibleInstances(userID)
+	if len(visible) != 1 {
+		return Instance{}, false
+	}
+	return visible[0], true
+}
+
+// visibleInstances 返回用户有查看权限的实例（按 ID 排序）。
+func (p *Provider) visibleInstances(userID string) []Instance {
+	var out []Instance
+	for _, ins := range p.order {
+		if p.instanceAllowed(userID, ins) {
+			out = append(out, ins)
+		}
+	}
+	return out
+}
+
+// sendInstanceRequired 提示先选择实例（多台 Unraid 且会话未指定实例时）。
+func (p *Provider) sendInstanceRequired(ctx context.Context, userID string) error {
+	_ = p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "存在多个 Unraid 实例，请先选择实例（或在指令末尾以 @<实例ID> 指定）。"})
+	return p.OnEnter(ctx, userID)
+}
+
+// targetLabel 返回用于回显的容器名：多台 Unraid 时附带实例名。
+func (p *Provider) targetLabel(ins Instance, containerName string) string {
+	if len(p.order) > 1 && ins.Name != "" {
+		return containerName + "（" + ins.Name + "）"
+	}
+	return containerName
+}
+
+// actionPermission 返回动作所需权限：容器操作为 unraid.<action>，虚拟机操作为 unraid.vm.<action>，查看类为 unraid.view。
+func (p *Provider) actionPermission(action core.Action) string {
+	if isVMAction(action) {
+		return vmActionPermission(action)
+	}
+	if isParityAction(action) {
+		return parityActionPermission(action)
+	}
+	if action.RequiresConfirm() {
+		return core.ServicePermission(p.Key(), string(action))
+	}
+	return core.ViewPermission(p.Key())
+}
+
+// instanceAllowed 校验用户能否访问实例（unraid.view + 实例作用范围）；未注入授权器时不限制。
+func (p *Provider) instanceAllowed(userID string, ins Instance) bool {
+	return p.auth == nil || p.auth.CanAccess(userID, core.ViewPermission(p.Key()), core.Resource{InstanceID: ins.ID})
+}
+
+// allowed 校验用户能否对实例中的容器执行动作；未注入授权器时不限制。
+func (p *Provider) allowed(userID string, ins Instance, action core.Action, containerName string) bool {
+	if p.auth == nil {
+		return true
+	}
+	return p.auth.CanAccess(userID, p.actionPermission(action), core.Resource{InstanceID: ins.ID, Container: containerName})
+}
+
+func (p *Provider) sendContainerForbidden(ctx context.Context, userID string, action core.Action, containerName string) error {
+	return p.wecom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: fmt.Sprintf("无权限：容器 %s 不在当前账号的%s授权范围内。", containerName, action.DisplayName()),
+	})
+}
+
 func unraidActionNeedsContainer(action core.Action) bool {
 	switch action {
 	case core.ActionUnraidRestart, core.ActionUnraidStop, core.ActionUnraidForceUpdate,
-		core.ActionUnraidViewStatus, core.ActionUnraidViewLogs:
+		core.ActionUnraidViewStatus, core.ActionUnraidViewLogs, core.ActionUnraidFollowLogs:
 		return true
 	default:
 		return false
@@ -414,6 +1003,7 @@ func unraidViewTextMenu() string {
 	return "Unraid 容器查看（文本模式）\n" +
 		"1. 查看状态\n" +
 		"2. 查看日志\n" +
+		"3. 跟踪日志\n" +
 		"\n提示：系统资源请从“系统监控”进入。\n" +
 		"\n回复序号选择。"
 }
@@ -422,6 +1012,8 @@ func unraidSystemTextMenu() string {
 	return "Unraid 系统监控（文本模式）\n" +
 		"1. 系统资源概览\n" +
 		"2. 系统资源详情\n" +
+		"3. 阵列状态\n" +
+		"4. 系统日志\n" +
 		"\n回复序号选择。"
 }
 
@@ -440,6 +1032,8 @@ func parseUnraidViewAction(input string) (core.Action, bool) {
 		return core.ActionUnraidViewStatus, true
 	case "2", "日志", "查看日志", "logs":
 		return core.ActionUnraidViewLogs, true
+	case "3", "跟踪", "跟踪日志", "follow":
+		return core.ActionUnraidFollowLogs, true
 	default:
 		return "", false
 	}
@@ -452,11 +1046,23 @@ func parseUnraidSystemAction(input string) (core.Action, bool) {
 		return core.ActionUnraidViewSystemStats, true
 	case "2", "详情", "系统资源详情", "系统详情", "detail":
 		return core.ActionUnraidViewSystemStatsDetail, true
+	case "3", "阵列", "阵列状态", "array":
+		return core.ActionUnraidViewArray, true
 	default:
 		return "", false
 	}
 }
 
+// isLogFilesMenuChoice 判断系统监控文本菜单中的“系统日志”选项。
+func isLogFilesMenuChoice(input string) bool {
+	switch strings.ToLower(strings.TrimSpace(input)) {
+	case "4", "系统日志", "日志文件", "syslog":
+		return true
+	default:
+		return false
+	}
+}
+
 func parseUnraidOpsAction(input string) (core.Action, bool) {
 	s := strings.ToLower(strings.TrimSpace(input))
 	switch s {
@@ -472,46 +1078,341 @@ func parseUnraidOpsAction(input string) (core.Action, bool) {
 }
 
 func (p *Provider) HandleConfirm(ctx context.Context, userID string) (bool, error) {
+	action, ok, err := p.PrepareConfirm(ctx, userID)
+	if err != nil || !ok {
+		return ok, err
+	}
+	return true, core.RunConfirmedAction(ctx, p.wecom, userID, action)
+}
+
+// PrepareConfirm 校验并清理待确认状态，返回可在后台执行的容器操作。
+func (p *Provider) PrepareConfirm(_ context.Context, userID string) (core.ConfirmedAction, bool, error) {
 	state, ok := p.state.Get(userID)
 	if !ok || state.ServiceKey != p.Key() || state.Step != core.StepAwaitingConfirm {
-		return false, nil
+		return core.ConfirmedAction{}, false, nil
 	}
 	p.state.Clear(userID)
 
-	start := time.Now()
-	err := p.execOperationAction(ctx, state.Action, state.ContainerName)
-	cost := time.Since(start).Milliseconds()
+	ins, ok := p.instanceByID(state.InstanceID)
+	if !ok {
+		return core.ConfirmedAction{}, true, fmt.Errorf("Unraid 实例 %s 不存在", state.InstanceID)
+	}
+	if isVMAction(state.Action) {
+		return p.vmAction(ins, state.Action, VMDomain{ID: state.UnraidVMID, Name: state.UnraidVMName}), true, nil
+	}
+	if isParityAction(state.Action) {
+		return p.parityAction(ins, state.Action, userID), true, nil
+	}
+	if len(state.ContainerNames) > 0 {
+		items := make([]core.ConfirmedAction, 0, len(state.ContainerNames))
+		for _, name := range state.ContainerNames {
+			items = append(items, p.containerAction(ins, state.Action, name))
+		}
+		return core.NewBatchAction(state.Action, items), true, nil
+	}
+	return p.containerAction(ins, state.Action, state.ContainerName), true, nil
+}
+
+func (p *Provider) containerAction(ins Instance, action core.Action, containerName string) core.ConfirmedAction {
+	target := p.targetLabel(ins, containerName)
+	return core.ConfirmedAction{
+		ServiceKey: p.Key(),
+		InstanceID: ins.ID,
+		Action:     action,
+		Target:     containerName,
+		Resource:   core.Resource{InstanceID: ins.ID, Container: containerName},
+		Run: func(ctx context.Context, _ core.ProgressFunc) (string, error) {
+			if err := p.execOperationAction(ctx, ins, action, containerName); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s %s", action.DisplayName(), target), nil
+		},
+	}
+}
+
+// opVerbs 为文本指令中可识别的容器动作（长词优先匹配）。
+var opVerbs = []struct {
+	word   string
+	action core.Action
+}{
+	{"强制更新", core.ActionUnraidForceUpdate},
+	{"force_update", core.ActionUnraidForceUpdate},
+	{"restart", core.ActionUnraidRestart},
+	{"update", core.ActionUnraidForceUpdate},
+	{"重启", core.ActionUnraidRestart},
+	{"停止", core.ActionUnraidStop},
+	{"更新", core.ActionUnraidForceUpdate},
+	{"stop", core.ActionUnraidStop},
+}
+
+// parseOpCommand 解析“重启 jellyfin”“停止 容器 plex sonarr”等操作指令，返回动作与容器名列表（至少一个）。
+func parseOpCommand(text string) (core.Action, []string, bool) {
+	text = strings.TrimSpace(text)
+	lower := strings.ToLower(text)
+	for _, v := range opVerbs {
+		if !strings.HasPrefix(lower, v.word) {
+			continue
+		}
+		rest := text[len(v.word):]
+		if rest != "" && rest[0] < utf8.RuneSelf && rest[0] != ' ' {
+			// 英文动词需与目标以空格分隔（避免 stopped 等误判）。
+			continue
+		}
+		names := core.SplitTargets(rest)
+		if len(names) > 0 {
+			switch strings.ToLower(names[0]) {
+			case "容器", "container", "docker":
+				names = names[1:]
+			}
+		}
+		if len(names) == 0 {
+			return "", nil, false
+		}
+		return v.action, names, true
+	}
+	return "", nil, false
+}
+
+// cutInstanceArg 取出目标列表中的“@实例ID”，返回其余目标与实例 ID（未指定时为空）。
+func cutInstanceArg(names []string) ([]string, string) {
+	var (
+		out []string
+		id  string
+	)
+	for _, n := range names {
+		if v, ok := strings.CutPrefix(n, "@"); ok && v != "" {
+			id = v
+			continue
+		}
+		out = append(out, n)
+	}
+	return out, id
+}
+
+// ParseActionSpec 解析“重启 jellyfin [@实例]”“停止 容器 plex”等动作短语（定时任务仅支持单个容器）。
+func (p *Provider) ParseActionSpec(text string) (core.ActionSpec, bool, error) {
+	action, names, ok := parseOpCommand(text)
+	if !ok {
+		return core.ActionSpec{}, false, nil
+	}
+	names, instanceID := cutInstanceArg(names)
+	if len(names) != 1 {
+		return core.ActionSpec{}, false, nil
+	}
+	if instanceID != "" {
+		if _, ok := p.instances[instanceID]; !ok {
+			return core.ActionSpec{}, false, fmt.Errorf("未知 Unraid 实例：%s", instanceID)
+		}
+	}
+	return core.ActionSpec{ServiceKey: p.Key(), InstanceID: instanceID, Action: action, Target: names[0]}, true, nil
+}
+
+// BuildAction 校验实例与容器仍然存在后构造容器操作。
+func (p *Provider) BuildAction(ctx context.Context, spec core.ActionSpec) (core.ConfirmedAction, error) {
+	switch spec.Action {
+	case core.ActionUnraidRestart, core.ActionUnraidStop, core.ActionUnraidForceUpdate:
+	default:
+		return core.ConfirmedAction{}, fmt.Errorf("不支持定时执行的动作：%s", spec.Action)
+	}
+	ins, ok := p.instanceByID(spec.InstanceID)
+	switch {
+	case !ok && spec.InstanceID == "":
+		return core.ConfirmedAction{}, fmt.Errorf("存在多个 Unraid 实例，请以 @<实例ID> 指定：%s", strings.Join(p.instanceIDs(), "、"))
+	case !ok:
+		return core.ConfirmedAction{}, fmt.Errorf("Unraid 实例 %s 不存在", spec.InstanceID)
+	case ins.Client == nil:
+		return core.ConfirmedAction{}, errors.New("unraid client 未配置")
+	}
+	st, err := ins.Client.GetContainerStatusByName(ctx, spec.Target)
 	if err != nil {
-		_ = p.wecom.SendText(ctx, wecom.TextMessage{
+		return core.ConfirmedAction{}, err
+	}
+	name := spec.Target
+	if n := strings.TrimSpace(st.Name); n != "" {
+		name = n
+	}
+	return p.containerAction(ins, spec.Action, name), nil
+}
+
+func (p *Provider) instanceIDs() []string {
+	ids := make([]string, 0, len(p.order))
+	for _, ins := range p.order {
+		ids = append(ids, ins.ID)
+	}
+	return ids
+}
+
+// Commands 声明容器与虚拟机一次性命令：操作类沿用确认流程（容器支持多个），查看类直接回显；多实例时以 @<实例ID> 指定实例。
+func (p *Provider) Commands() []core.Command {
+	instanceArg := core.CommandArg{Name: "instance", Prefix: "@", Optional: true}
+	op := func(verb string, action core.Action, aliases ...string) core.Command {
+		return core.Command{
+			Path:       "unraid " + verb,
+			Aliases:    aliases,
+			Summary:    action.DisplayName() + "（可多个容器）",
+			Args:       []core.CommandArg{{Name: "container", Variadic: true}, instanceArg},
+			Permission: p.actionPermission(action),
+			Handler: func(ctx context.Context, userID string, args core.CommandArgs) error {
+				ins, err := p.commandInstance(userID, args.String("instance"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				state := core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID, Action: action}
+				return p.confirmContainers(ctx, userID, ins, state, args.Strings("container"))
+			},
+		}
+	}
+	return append([]core.Command{
+		op("restart", core.ActionUnraidRestart),
+		op("stop", core.ActionUnraidStop),
+		op("update", core.ActionUnraidForceUpdate, "unraid force_update"),
+		{
+			Path:    "unraid status",
+			Summary: "查看容器状态",
+			Args:    []core.CommandArg{{Name: "container"}, instanceArg},
+			Handler: func(ctx context.Context, userID string, args core.CommandArgs) error {
+				ins, err := p.commandInstance(userID, args.String("instance"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				return p.viewContainer(ctx, userID, ins, core.ActionUnraidViewStatus, args.String("container"), 0, nil)
+			},
+		},
+		{
+			Path: "unraid logs",
+			Summary: fmt.Sprintf("查看容器日志（默认%d行，最大%d）；附关键词时仅返回匹配行（%s，默认检索最近%d行，最大%d）",
+				defaultLogTail, maxLogTail, core.LogGrepUsage, defaultLogGrepLines, maxContainerLogLines),
+			Args: []core.CommandArg{
+				{Name: "container"},
+				{Name: "lines", Type: core.ArgInt, Optional: true},
+				{Name: "keyword", Optional: true, Variadic: true, Verbatim: true},
+				instanceArg,
+			},
+			Handler: func(ctx context.Context, userID string, args core.CommandArgs) error {
+				ins, err := p.commandInstance(userID, args.String("instance"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				var grep *core.LogGrepOptions
+				if args.Has("keyword") {
+					opts, err := core.ParseLogGrepArgs(args.Strings("keyword"))
+					if err != nil {
+						return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "检索条件不合法：" + err.Error()})
+					}
+					grep = &opts
+				}
+				tail := logLines(args.Int("lines"), grep != nil)
+				return p.viewContainer(ctx, userID, ins, core.ActionUnraidViewLogs, args.String("container"), tail, grep)
+			},
+		},
+		{
+			Path:    "unraid sys",
+			Summary: "系统资源概览（加 detail 查看详情）",
+			Args:    []core.CommandArg{{Name: "detail", Type: core.ArgChoice, Choices: []string{"detail"}, Optional: true}, instanceArg},
+			Handler: func(ctx context.Context, userID string, args core.CommandArgs) error {
+				ins, err := p.commandInstance(userID, args.String("instance"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
+				action := core.ActionUnraidViewSystemStats
+				if args.Has("detail") {
+					action = core.ActionUnraidViewSystemStatsDetail
+				}
+				return p.execViewAndReply(ctx, userID, ins, action, "", 0)
+			},
+		},
+		{
+			Path:    "unraid array",
+			Summary: "阵列状态与磁盘健康（温度/错误/SMART）",
+			Args:    []core.CommandArg{instanceArg},
+			Handler: func(ctx context.Context, userID string, args core.CommandArgs) error {
+				ins, err := p.commandInstance(userID, args.String("instance"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
+				return p.execViewAndReply(ctx, userID, ins, core.ActionUnraidViewArray, "", 0)
+			},
+		},
+	}, slices.Concat(p.vmCommands(instanceArg), p.parityCommands(instanceArg), p.followCommands(instanceArg), p.logFileCommands(instanceArg))...)
+}
+
+// commandInstance 解析命令中的 @实例；省略时要求当前账号仅能访问一个实例。
+func (p *Provider) commandInstance(userID, id string) (Instance, error) {
+	if id != "" {
+		ins, ok := p.instances[id]
+		if !ok {
+			return Instance{}, fmt.Errorf("未知 Unraid 实例：%s", id)
+		}
+		if !p.instanceAllowed(userID, ins) {
+			return Instance{}, fmt.Errorf("无权限：当前账号未被授权访问 Unraid 实例 %s。", id)
+		}
+		return ins, nil
+	}
+	if len(p.order) <= 1 {
+		return p.soleInstance(), nil
+	}
+	visible := p.visibleInstances(userID)
+	switch len(visible) {
+	case 0:
+		return Instance{}, errors.New("无权限：当前账号未被授权访问任何 Unraid 实例。")
+	case 1:
+		return visible[0], nil
+	}
+	ids := make([]string, 0, len(visible))
+	for _, ins := range visible {
+		ids = append(ids, ins.ID)
+	}
+	return Instance{}, fmt.Errorf("存在多个 Unraid 实例，请在命令末尾以 @<实例ID> 指定：%s", strings.Join(ids, "、"))
+}
+
+// viewContainer 解析并校验容器名与作用范围后执行查看类动作，并保留 Unraid 会话便于继续输入；
+// logTail 为日志行数，跟踪日志时为跟踪分钟数；grep 非空时仅回复日志中的匹配行。
+func (p *Provider) viewContainer(ctx context.Context, userID string, ins Instance, action core.Action, raw string, logTail int, grep *core.LogGrepOptions) error {
+	resolved, ok, err := p.resolveContainers(ctx, userID, ins, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID, Action: action}, []string{raw})
+	if !ok {
+		return err
+	}
+	name, err := core.ValidateContainerName(resolved[0])
+	if err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{
 			ToUser:  userID,
-			Content: fmt.Sprintf("执行失败（%dms）：%s", cost, err.Error()),
+			Content: fmt.Sprintf("容器名不合法：%s", err.Error()),
 		})
-		return true, nil
 	}
-
-	_ = p.wecom.SendText(ctx, wecom.TextMessage{
-		ToUser:  userID,
-		Content: fmt.Sprintf("执行成功（%dms）：%s %s", cost, state.Action.DisplayName(), state.ContainerName),
-	})
-	return true, nil
+	if !p.allowed(userID, ins, action, name) {
+		return p.sendContainerForbidden(ctx, userID, action, name)
+	}
+	p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
+	if action == core.ActionUnraidFollowLogs {
+		return p.startFollow(ctx, userID, ins, followTarget{Container: name}, logTail)
+	}
+	if grep != nil {
+		return p.grepLogsAndReply(ctx, userID, ins, name, logTail, *grep)
+	}
+	return p.execViewAndReply(ctx, userID, ins, action, name, logTail)
 }
 
-func (p *Provider) execOperationAction(ctx context.Context, action core.Action, containerName string) error {
+func (p *Provider) execOperationAction(ctx context.Context, ins Instance, action core.Action, containerName string) error {
+	if ins.Client == nil {
+		return errors.New("unraid client 未配置")
+	}
 	switch action {
 	case core.ActionUnraidRestart:
-		return p.client.RestartContainerByName(ctx, containerName)
+		return ins.Client.RestartContainerByName(ctx, containerName)
 	case core.ActionUnraidStop:
-		return p.client.StopContainerByName(ctx, containerName)
+		return ins.Client.StopContainerByName(ctx, containerName)
 	case core.ActionUnraidForceUpdate:
-		return p.client.ForceUpdateContainerByName(ctx, containerName)
+		return ins.Client.ForceUpdateContainerByName(ctx, containerName)
 	default:
 		return fmt.Errorf("未知动作: %s", action)
 	}
 }
 
-func (p *Provider) execViewAndReply(ctx context.Context, userID string, action core.Action, containerName string, logTail int) error {
+func (p *Provider) execViewAndReply(ctx context.Context, userID string, ins Instance, action core.Action, containerName string, logTail int) error {
 	start := time.Now()
-	content, err := p.execViewAction(ctx, action, containerName, logTail)
+	content, err := p.execViewAction(ctx, ins, action, containerName, logTail)
 	cost := time.Since(start).Milliseconds()
 	if err != nil {
 		return p.wecom.SendText(ctx, wecom.TextMessage{
@@ -519,34 +1420,83 @@ func (p *Provider) execViewAndReply(ctx context.Context, userID string, action c
 			Content: fmt.Sprintf("查询失败（%dms）：%s", cost, err.Error()),
 		})
 	}
+	if len(p.order) > 1 {
+		content = "[" + ins.Name + "] " + content
+	}
 	return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: truncateForWecom(content)})
 }
 
-func (p *Provider) execViewAction(ctx context.Context, action core.Action, containerName string, logTail int) (string, error) {
+// grepLogsAndReply 拉取容器最近 lines 行日志，仅回复匹配检索条件的行（附匹配计数）。
+func (p *Provider) grepLogsAndReply(ctx context.Context, userID string, ins Instance, containerName string, lines int, opts core.LogGrepOptions) error {
+	if ins.Client == nil {
+		return errors.New("unraid client 未配置")
+	}
+	start := time.Now()
+	logs, err := ins.Client.GetContainerLogsByName(ctx, containerName, lines)
+	cost := time.Since(start).Milliseconds()
+	if err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: fmt.Sprintf("查询失败（%dms）：%s", cost, err.Error()),
+		})
+	}
+	res, err := core.GrepLog(logs.Logs, opts)
+	if err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "检索条件不合法：" + err.Error()})
+	}
+
+	title := fmt.Sprintf("【日志检索】%s（最近 %d 行）", logs.Name, logs.Tail)
+	if len(p.order) > 1 {
+		title = "[" + ins.Name + "] " + title
+	}
+	return p.wecom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: truncateForWecom(core.FormatLogGrep(title, opts, res, maxWecomTextBytes)),
+	})
+}
+
+func (p *Provider) execViewAction(ctx context.Context, ins Instance, action core.Action, containerName string, logTail int) (string, error) {
+	if ins.Client == nil {
+		return "", errors.New("unraid client 未配置")
+	}
 	switch action {
 	case core.ActionUnraidViewStatus:
-		st, err := p.client.GetContainerStatusByName(ctx, containerName)
+		st, err := ins.Client.GetContainerStatusByName(ctx, containerName)
 		if err != nil {
 			return "", err
 		}
 		return formatContainerStatus(st), nil
 
 	case core.ActionUnraidViewSystemStats:
-		m, err := p.client.GetSystemMetrics(ctx)
+		m, err := ins.Client.GetSystemMetrics(ctx)
 		if err != nil {
 			return "", err
 		}
 		return formatSystemMetricsOverview(m), nil
 
 	case core.ActionUnraidViewSystemStatsDetail:
-		m, err := p.client.GetSystemMetrics(ctx)
+		m, err := ins.Client.GetSystemMetrics(ctx)
 		if err != nil {
 			return "", err
 		}
 		return formatSystemMetricsDetail(m), nil
 
+	case core.ActionUnraidViewArray:
+		s, err := ins.Client.GetArrayStatus(ctx)
+		if err != nil {
+			return "", err
+		}
+		return formatArrayStatus(s), nil
+
+	case core.ActionUnraidViewParity:
+		list, err := ins.Client.ListParityHistory(ctx)
+		if err != nil {
+			return "", err
+		}
+		return formatParityHistory(list, parityHistoryLimit), nil
+
 	case core.ActionUnraidViewLogs:
-		logs, err := p.client.GetContainerLogsByName(ctx, containerName, logTail)
+		logs, err := ins.Client.GetContainerLogsByName(ctx, containerName, logTail)
 		if err != nil {
 			return "", err
 		}
@@ -558,33 +1508,91 @@ func (p *Provider) execViewAction(ctx context.Context, action core.Action, conta
 }
 
 const (
-	defaultLogTail     = 50
-	maxLogTail         = 200
-	maxWecomTextBytes  = 1800
-	wecomTruncSuffix   = "\n…（已截断）"
-	wecomTruncMinBytes = 64
+	defaultLogTail      = 50
+	maxLogTail          = 200
+	defaultLogGrepLines = 1000
+	maxWecomTextBytes   = 1800
+	wecomTruncSuffix    = "\n…（已截断）"
+	wecomTruncMinBytes  = 64
 )
 
-func parseContainerAndOptionalTail(input string, action core.Action) (container string, tail int, err error) {
+// parseContainerAndOptionalTail 解析“容器名 [数字]”：查看日志时数字为行数，跟踪日志时为分钟数。
+// 查看日志时数字后还可跟检索条件（如“nginx 2000 ERROR -i”），此时返回 grep，行数默认与上限按检索窗口计算。
+func parseContainerAndOptionalTail(input string, action core.Action) (container string, tail int, grep *core.LogGrepOptions, err error) {
 	fields := strings.Fields(strings.TrimSpace(input))
 	if len(fields) == 0 {
-		return "", 0, errors.New("请输入容器名。")
+		return "", 0, nil, errors.New("请输入容器名。")
 	}
 
 	container = fields[0]
+	if action == core.ActionUnraidFollowLogs {
+		minutes := followDefaultMinutes
+		if len(fields) >= 2 {
+			n, err2 := strconv.Atoi(fields[1])
+			if err2 != nil {
+				return "", 0, nil, fmt.Errorf("跟踪分钟数不合法：%s", fields[1])
+			}
+			minutes = clampInt(n, 1, followMaxMinutes)
+		}
+		return container, minutes, nil, nil
+	}
 	if action != core.ActionUnraidViewLogs {
-		return container, 0, nil
+		return container, 0, nil, nil
 	}
 
-	tail = defaultLogTail
-	if len(fields) >= 2 {
-		n, err2 := strconv.Atoi(fields[1])
-		if err2 != nil {
-			return "", 0, fmt.Errorf("日志行数不合法：%s", fields[1])
+	lines, grep, err := parseLogQuery(fields[1:])
+	if err != nil {
+		return "", 0, nil, err
+	}
+	return container, logLines(lines, grep != nil), grep, nil
+}
+
+// parseLogQuery 解析“[行数] [检索条件...]”：首个词为数字时作为行数（未指定为 0），其余词为检索条件（无则 grep 为空）。
+func parseLogQuery(tokens []string) (lines int, grep *core.LogGrepOptions, err error) {
+	if len(tokens) > 0 {
+		if n, err2 := strconv.Atoi(tokens[0]); err2 == nil {
+			if n <= 0 {
+				return 0, nil, fmt.Errorf("日志行数不合法：%s", tokens[0])
+			}
+			lines = n
+			tokens = tokens[1:]
 		}
-		tail = clampInt(n, 1, maxLogTail)
 	}
-	return container, tail, nil
+	if len(tokens) == 0 {
+		return lines, nil, nil
+	}
+	opts, err := core.ParseLogGrepArgs(tokens)
+	if err != nil {
+		return 0, nil, fmt.Errorf("检索条件不合法：%s", err.Error())
+	}
+	return lines, &opts, nil
+}
+
+// logLines 返回查看日志的行数：未指定时取默认值；检索时使用更大的拉取窗口。
+func logLines(n int, grep bool) int {
+	switch {
+	case grep && n <= 0:
+		return defaultLogGrepLines
+	case grep:
+		return clampInt(n, 1, maxContainerLogLines)
+	case n <= 0:
+		return defaultLogTail
+	default:
+		return clampInt(n, 1, maxLogTail)
+	}
+}
+
+// containerInputPrompt 返回选择动作后提示输入容器名的文案（日志类动作附带可选参数说明）。
+func containerInputPrompt(action core.Action) string {
+	switch action {
+	case core.ActionUnraidViewLogs:
+		return fmt.Sprintf("已选择动作：%s\n请输入：容器名 [行数]（默认%d，最大%d）\n检索：容器名 [行数] %s（默认检索最近%d行，最大%d）：",
+			action.DisplayName(), defaultLogTail, maxLogTail, core.LogGrepUsage, defaultLogGrepLines, maxContainerLogLines)
+	case core.ActionUnraidFollowLogs:
+		return fmt.Sprintf("已选择动作：%s\n请输入：容器名 [分钟]（默认%d，最长%d）：", action.DisplayName(), followDefaultMinutes, followMaxMinutes)
+	default:
+		return fmt.Sprintf("已选择动作：%s\n请输入容器名：", action.DisplayName())
+	}
 }
 
 func formatContainerStatus(st ContainerStatus) string {
@@ -0,0 +1,233 @@
+package unraid
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// logsServer 模拟 docker containers 查询（含 logs 字段），日志行可在测试中追加。
+type logsServer struct {
+	mu        sync.Mutex
+	lines     []string
+	fetches   int
+	lastQuery string
+}
+
+func (s *logsServer) LastQuery() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastQuery
+}
+
+func (s *logsServer) append(lines ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines = append(s.lines, lines...)
+}
+
+func (s *logsServer) Fetches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+func newLogsServer(t *testing.T, lines ...string) (*httptest.Server, *logsServer) {
+	t.Helper()
+
+	ls := &logsServer{lines: lines}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		ls.mu.Lock()
+		defer ls.mu.Unlock()
+		container := map[string]interface{}{"id": "docker:app", "names": []string{"/app"}, "state": "running", "status": "Up 1 hour"}
+		if strings.Contains(req.Query, "logs") {
+			container["logs"] = strings.Join(ls.lines, "\n")
+			ls.fetches++
+			ls.lastQuery = req.Query
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"docker": map[string]interface{}{"containers": []interface{}{container}}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, ls
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func lastTextContains(wc *recordWeCom, want string) func() bool {
+	return func() bool {
+		texts := wc.Texts()
+		return len(texts) > 0 && strings.Contains(texts[len(texts)-1].Content, want)
+	}
+}
+
+func TestNewLogLines(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		prev, cur []string
+		want      string
+	}{
+		{[]string{"a", "b"}, []string{"a", "b"}, ""},
+		{[]string{"a", "b"}, []string{"a", "b", "c"}, "c"},
+		{[]string{"a", "b", "c"}, []string{"c", "d", "e"}, "d,e"},
+		{[]string{"a", "b"}, []string{"x", "y"}, "x,y"},
+		{nil, []string{"x"}, "x"},
+	}
+	for _, tc := range cases {
+		if got := strings.Join(newLogLines(tc.prev, tc.cur), ","); got != tc.want {
+			t.Fatalf("newLogLines(%v, %v) = %q, want %q", tc.prev, tc.cur, got, tc.want)
+		}
+	}
+}
+
+func TestProvider_FollowContainerLogs(t *testing.T) {
+	t.Parallel()
+
+	srv, ls := newLogsServer(t, "boot 1", "boot 2")
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{WeCom: wc, Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client()), State: state})
+	p.follows.pollInterval = 10 * time.Millisecond
+	p.follows.flushInterval = 30 * time.Millisecond
+	t.Cleanup(p.StopFollows)
+
+	ctx := context.Background()
+	const userID = "u1"
+
+	if _, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidFollowLogs}); err != nil {
+		t.Fatalf("HandleEvent(follow logs) error: %v", err)
+	}
+	if _, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidContainerSelectPrefix + "app"}); err != nil {
+		t.Fatalf("HandleEvent(select) error: %v", err)
+	}
+	cards := wc.Cards()
+	keys := strings.Join(cardButtonKeys(t, cards[len(cards)-1].Card), ",")
+	if !strings.Contains(keys, wecom.EventKeyUnraidFollowStartPrefix+"5") || !strings.Contains(keys, wecom.EventKeyUnraidFollowStartPrefix+"30") {
+		t.Fatalf("duration card keys = %s", keys)
+	}
+
+	if _, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidFollowStartPrefix + "1"}); err != nil {
+		t.Fatalf("HandleEvent(start) error: %v", err)
+	}
+	cards = wc.Cards()
+	if keys := strings.Join(cardButtonKeys(t, cards[len(cards)-1].Card), ","); keys != wecom.EventKeyUnraidFollowStop {
+		t.Fatalf("follow card keys = %s", keys)
+	}
+	waitFor(t, "baseline fetch", func() bool { return ls.Fetches() >= 1 })
+
+	ls.append("req 1", "req 2")
+	waitFor(t, "batched lines", lastTextContains(wc, "新增 2 行"))
+	if got := wc.Texts()[len(wc.Texts())-1].Content; !strings.HasSuffix(got, "req 1\nreq 2") || strings.Contains(got, "boot") {
+		t.Fatalf("batch = %q", got)
+	}
+
+	if _, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidFollowStop}); err != nil {
+		t.Fatalf("HandleEvent(stop) error: %v", err)
+	}
+	if texts := wc.Texts(); !strings.Contains(texts[len(texts)-1].Content, "已结束跟踪容器 app：已手动停止") {
+		t.Fatalf("stop reply = %q", texts[len(texts)-1].Content)
+	}
+	if _, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidFollowStop}); err != nil {
+		t.Fatalf("HandleEvent(stop again) error: %v", err)
+	}
+	if texts := wc.Texts(); !strings.Contains(texts[len(texts)-1].Content, "没有进行中") {
+		t.Fatalf("second stop reply = %q", texts[len(texts)-1].Content)
+	}
+
+	// 文本输入“容器名 分钟”开始跟踪；会话结束（如超时）后自动停止。
+	state.Set(userID, core.ConversationState{ServiceKey: "unraid", Step: core.StepAwaitingUnraidViewAction})
+	if _, err := p.HandleText(ctx, userID, "3"); err != nil {
+		t.Fatalf("HandleText(3) error: %v", err)
+	}
+	if _, err := p.HandleText(ctx, userID, "app 10"); err != nil {
+		t.Fatalf("HandleText(app 10) error: %v", err)
+	}
+	cards = wc.Cards()
+	if got := mustJSON(t, cards[len(cards)-1].Card); !strings.Contains(got, "时长 10 分钟") {
+		t.Fatalf("follow card = %s", got)
+	}
+	state.Clear(userID)
+	waitFor(t, "session stop", lastTextContains(wc, "会话已超时"))
+}
+
+func TestProvider_FollowLogFilePermission(t *testing.T) {
+	t.Parallel()
+
+	srv := newWSServer(t, []string{SubprotocolGraphQLTransportWS}, func(c *wsTestConn) {
+		sub := c.ack()
+		c.send("next", sub.ID, map[string]interface{}{
+			"data": map[string]interface{}{"logFile": map[string]interface{}{"path": "/var/log/syslog", "content": "kernel: eth0 up\n"}},
+		})
+		c.read()
+	})
+	auth, err := core.NewAuthorizer(core.AuthorizerConfig{
+		Bindings: []core.RoleBinding{
+			{Subjects: []string{"viewer"}, Role: core.RoleViewer},
+			{Subjects: []string{"ops"}, Role: core.RoleOperator},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{
+		WeCom:  wc,
+		Client: newSubscriptionClient(srv),
+		State:  state,
+		Auth:   auth,
+	})
+	p.follows.flushInterval = 20 * time.Millisecond
+	t.Cleanup(p.StopFollows)
+	r := core.NewRouter(core.RouterDeps{WeCom: wc, Auth: auth, Providers: []core.ServiceProvider{p}, State: state})
+	ctx := context.Background()
+	send := func(userID, content string) {
+		t.Helper()
+		if err := r.HandleMessage(ctx, wecom.IncomingMessage{FromUserName: userID, MsgType: "text", Content: content}); err != nil {
+			t.Fatalf("HandleMessage(%q) error: %v", content, err)
+		}
+	}
+
+	send("viewer", "/unraid follow /var/log/syslog")
+	if !lastTextContains(wc, "unraid.syslog")() {
+		t.Fatalf("viewer reply = %+v, want forbidden", wc.Texts())
+	}
+	send("ops", "/unraid follow /etc/shadow")
+	if !lastTextContains(wc, "仅支持 /var/log")() {
+		t.Fatalf("invalid path reply = %+v", wc.Texts())
+	}
+
+	send("ops", "/unraid follow /var/log/../log/syslog 2")
+	waitFor(t, "log file lines", lastTextContains(wc, "kernel: eth0 up"))
+	if got := wc.Texts()[len(wc.Texts())-1]; got.ToUser != "ops" || !strings.Contains(got.Content, "日志文件 /var/log/syslog 新增 1 行") {
+		t.Fatalf("batch = %+v", got)
+	}
+	send("ops", "/unraid unfollow")
+	if !lastTextContains(wc, "已手动停止")() {
+		t.Fatalf("unfollow reply = %q", wc.Texts()[len(wc.Texts())-1].Content)
+	}
+}
@@ -0,0 +1,317 @@
+package unraid
+
+// provider_logfile.go 实现“系统日志”：以选择卡片列出 /var/log 下的日志文件，按页浏览（开头/末尾/更早/更新，基于 logFile 的 startLine），
+// 并支持在较大窗口内检索关键词；读取日志文件需 unraid.syslog 权限。
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+const (
+	unraidLogFileSelectPageSize = 3
+	// logFilePageLines 为浏览日志文件时每页的行数（卡片翻页固定使用该值）。
+	logFilePageLines = 20
+)
+
+// syslogAllowed 校验用户能否读取实例的日志文件；系统日志覆盖整台实例，限定了容器/虚拟机等目标的绑定不覆盖。
+// 未注入授权器时不限制。
+func (p *Provider) syslogAllowed(userID string, ins Instance) bool {
+	return p.auth == nil || p.auth.CanAccess(userID, syslogPermission, core.Resource{InstanceID: ins.ID, WholeInstance: true})
+}
+
+func (p *Provider) sendSyslogForbidden(ctx context.Context, userID string) error {
+	return p.wecom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: fmt.Sprintf("无权限：当前账号未被授权读取 Unraid 日志文件（%s）。", syslogPermission),
+	})
+}
+
+// sendLogFileSelectCard 发送日志文件选择卡片（按钮含文件大小）。
+func (p *Provider) sendLogFileSelectCard(ctx context.Context, userID string, ins Instance, page int) error {
+	if !p.syslogAllowed(userID, ins) {
+		return p.sendSyslogForbidden(ctx, userID)
+	}
+	if ins.Client == nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "获取日志文件列表失败：unraid client 未配置"})
+	}
+	files, err := ins.Client.ListLogFiles(ctx)
+	if err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "获取日志文件列表失败：" + err.Error()})
+	}
+	if len(files) == 0 {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未找到任何日志文件。"})
+	}
+	p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
+
+	totalPages := (len(files) + unraidLogFileSelectPageSize - 1) / unraidLogFileSelectPageSize
+	page = clampInt(page, 1, totalPages)
+	start := (page - 1) * unraidLogFileSelectPageSize
+	end := min(start+unraidLogFileSelectPageSize, len(files))
+
+	var opts []wecom.UnraidLogFileOption
+	for _, f := range files[start:end] {
+		text := strings.TrimPrefix(f.Path, logFileRoot)
+		if f.Size > 0 {
+			text += "（" + formatBytesIEC(f.Size) + "）"
+		}
+		opts = append(opts, wecom.UnraidLogFileOption{Path: f.Path, Text: text})
+	}
+	prevPage, nextPage := 0, 0
+	if page > 1 {
+		prevPage = page - 1
+	}
+	if page < totalPages {
+		nextPage = page + 1
+	}
+
+	instanceName := ""
+	if len(p.order) > 1 {
+		instanceName = ins.Name
+	}
+	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card:   wecom.NewUnraidLogFileSelectCard(instanceName, page, totalPages, opts, prevPage, nextPage),
+	})
+}
+
+// handleLogFilePage 处理日志文件选择卡片的翻页。
+func (p *Provider) handleLogFilePage(ctx context.Context, userID string, ins Instance, pageStr string) error {
+	page, err := strconv.Atoi(strings.TrimSpace(pageStr))
+	if err != nil || page <= 0 {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "页码不合法，请重新选择。"})
+	}
+	return p.sendLogFileSelectCard(ctx, userID, ins, page)
+}
+
+// handleLogFileSelect 校验路径后显示日志文件末尾一页。
+func (p *Provider) handleLogFileSelect(ctx context.Context, userID string, ins Instance, raw string) error {
+	filePath, err := cleanLogFilePath(raw)
+	if err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+	}
+	return p.showLogFile(ctx, userID, ins, filePath, 0, logFilePageLines)
+}
+
+// currentLogFile 返回会话中正在浏览的实例与日志文件。
+func (p *Provider) currentLogFile(ctx context.Context, userID string) (core.ConversationState, Instance, bool, error) {
+	state, ok := p.state.Get(userID)
+	if !ok || state.ServiceKey != p.Key() || strings.TrimSpace(state.UnraidLogPath) == "" {
+		return state, Instance{}, false, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "会话已过期，请重新选择日志文件。"})
+	}
+	ins, ok := p.instanceFromState(userID, state)
+	if !ok {
+		return state, Instance{}, false, p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "实例不可用，请重新选择日志文件。"})
+	}
+	return state, ins, true, nil
+}
+
+// handleLogFileView 处理浏览卡片的翻页：head/tail 跳转到开头/末尾，older/newer 以当前页首行为基准前后翻页。
+func (p *Provider) handleLogFileView(ctx context.Context, userID, mode string) error {
+	state, ins, ok, err := p.currentLogFile(ctx, userID)
+	if !ok {
+		return err
+	}
+	var startLine int
+	switch mode {
+	case "head":
+		startLine = 1
+	case "tail":
+		startLine = 0
+	case "older":
+		startLine = max(1, state.UnraidLogStart-logFilePageLines)
+	case "newer":
+		startLine = max(1, state.UnraidLogStart+logFilePageLines)
+	default:
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未知翻页方式，请重新选择。"})
+	}
+	return p.showLogFile(ctx, userID, ins, state.UnraidLogPath, startLine, logFilePageLines)
+}
+
+// handleLogFileGrep 提示输入检索条件（目标为会话中的当前日志文件）。
+func (p *Provider) handleLogFileGrep(ctx context.Context, userID string) error {
+	state, _, ok, err := p.currentLogFile(ctx, userID)
+	if !ok {
+		return err
+	}
+	state.Step = core.StepAwaitingUnraidLogKeyword
+	p.state.Set(userID, state)
+	return p.wecom.SendText(ctx, wecom.TextMessage{
+		ToUser: userID,
+		Content: fmt.Sprintf("检索 %s\n请输入：[行数] %s（默认检索末尾%d行，最大%d）：",
+			state.UnraidLogPath, core.LogGrepUsage, defaultLogGrepLines, maxContainerLogLines),
+	})
+}
+
+// handleLogFileGrepInput 处理检索条件的文本输入；完成后保留当前日志文件，可继续翻页或检索。
+func (p *Provider) handleLogFileGrepInput(ctx context.Context, userID string, state core.ConversationState, content string) error {
+	lines, grep, err := parseLogQuery(strings.Fields(content))
+	if err == nil && grep == nil {
+		err = errors.New("请输入检索关键词。")
+	}
+	if err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+	}
+	ins, ok := p.instanceFromState(userID, state)
+	if !ok {
+		return p.sendInstanceRequired(ctx, userID)
+	}
+	state.Step = ""
+	p.state.Set(userID, state)
+	return p.grepLogFileAndReply(ctx, userID, ins, state.UnraidLogPath, 0, logLines(lines, true), *grep)
+}
+
+// showLogFile 读取日志文件的一页（startLine<=0 表示末尾）并回复，随后发送浏览卡片并记录当前页首行。
+func (p *Provider) showLogFile(ctx context.Context, userID string, ins Instance, filePath string, startLine, lines int) error {
+	if !p.syslogAllowed(userID, ins) {
+		return p.sendSyslogForbidden(ctx, userID)
+	}
+	if ins.Client == nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "查询失败：unraid client 未配置"})
+	}
+	chunk, err := ins.Client.GetLogFile(ctx, filePath, lines, startLine)
+	if err == nil && startLine > 1 && chunk.TotalLines > 0 && startLine > chunk.TotalLines {
+		// 已翻过文件末尾（如文件被轮转截短）：回到末尾一页。
+		chunk, err = ins.Client.GetLogFile(ctx, filePath, lines, 0)
+	}
+	if err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "查询失败：" + err.Error()})
+	}
+
+	rows := splitLogLines(chunk.Content)
+	first := chunk.StartLine
+	last := first + len(rows) - 1
+	total := max(chunk.TotalLines, last)
+	p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID, UnraidLogPath: filePath, UnraidLogStart: first})
+
+	content := formatLogFilePage(filePath, first, rows, total)
+	if len(p.order) > 1 {
+		content = "[" + ins.Name + "] " + content
+	}
+	_ = p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: truncateForWecom(content)})
+
+	desc := fmt.Sprintf("共 %d 行", total)
+	if len(rows) > 0 {
+		desc = fmt.Sprintf("第 %d-%d 行 / 共 %d 行", first, last, total)
+	}
+	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card: wecom.NewUnraidLogFileCard(wecom.UnraidLogFileCardOptions{
+			Path:     p.targetLabel(ins, filePath),
+			Desc:     desc,
+			HasOlder: len(rows) > 0 && first > 1,
+			HasNewer: len(rows) > 0 && last < total,
+		}),
+	})
+}
+
+// grepLogFileAndReply 读取日志文件的检索窗口（startLine<=0 表示末尾 lines 行），仅回复匹配行；行号为文件中的行号。
+func (p *Provider) grepLogFileAndReply(ctx context.Context, userID string, ins Instance, filePath string, startLine, lines int, opts core.LogGrepOptions) error {
+	if !p.syslogAllowed(userID, ins) {
+		return p.sendSyslogForbidden(ctx, userID)
+	}
+	if ins.Client == nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "查询失败：unraid client 未配置"})
+	}
+	chunk, err := ins.Client.GetLogFile(ctx, filePath, lines, startLine)
+	if err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "查询失败：" + err.Error()})
+	}
+	res, err := core.GrepLog(chunk.Content, opts)
+	if err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "检索条件不合法：" + err.Error()})
+	}
+	for _, block := range res.Blocks {
+		for i := range block {
+			block[i].No += chunk.StartLine - 1
+		}
+	}
+
+	title := "【系统日志检索】" + filePath
+	if res.Scanned > 0 {
+		title += fmt.Sprintf("（第 %d-%d 行）", chunk.StartLine, chunk.StartLine+res.Scanned-1)
+	}
+	if len(p.order) > 1 {
+		title = "[" + ins.Name + "] " + title
+	}
+	return p.wecom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: truncateForWecom(core.FormatLogGrep(title, opts, res, maxWecomTextBytes)),
+	})
+}
+
+func formatLogFilePage(filePath string, first int, rows []string, total int) string {
+	if len(rows) == 0 {
+		return fmt.Sprintf("【系统日志】%s（共 %d 行）\n（无内容）", filePath, total)
+	}
+	header := fmt.Sprintf("【系统日志】%s 第 %d-%d 行（共 %d 行）", filePath, first, first+len(rows)-1, total)
+	return header + "\n" + strings.Join(rows, "\n")
+}
+
+// logFileCommands 声明系统日志命令：/unraid logfiles 打开文件列表，/unraid logfile 浏览或检索指定文件。
+func (p *Provider) logFileCommands(instanceArg core.CommandArg) []core.Command {
+	return []core.Command{
+		{
+			Path:       "unraid logfiles",
+			Summary:    "系统日志文件列表（选择后按页浏览）",
+			Args:       []core.CommandArg{instanceArg},
+			Permission: syslogPermission,
+			Handler: func(ctx context.Context, userID string, args core.CommandArgs) error {
+				ins, err := p.commandInstance(userID, args.String("instance"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				return p.sendLogFileSelectCard(ctx, userID, ins, 1)
+			},
+		},
+		{
+			Path: "unraid logfile",
+			Summary: fmt.Sprintf("查看 /var/log 下的日志文件（默认末尾%d行，head 从开头，+N 从第 N 行起，最多%d行）；附关键词时仅返回匹配行（%s，默认检索%d行，最大%d）",
+				logFilePageLines, maxLogTail, core.LogGrepUsage, defaultLogGrepLines, maxContainerLogLines),
+			Args: []core.CommandArg{
+				{Name: "file"},
+				{Name: "mode", Type: core.ArgChoice, Choices: []string{"head", "tail"}, Optional: true},
+				{Name: "lines", Type: core.ArgInt, Optional: true},
+				{Name: "keyword", Optional: true, Variadic: true, Verbatim: true},
+				{Name: "start", Type: core.ArgInt, Prefix: "+", Optional: true},
+				instanceArg,
+			},
+			Permission: syslogPermission,
+			Handler: func(ctx context.Context, userID string, args core.CommandArgs) error {
+				ins, err := p.commandInstance(userID, args.String("instance"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				filePath, err := cleanLogFilePath(args.String("file"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				startLine := 0
+				switch {
+				case args.Has("start"):
+					startLine = args.Int("start")
+				case args.String("mode") == "head":
+					startLine = 1
+				}
+				if args.Has("keyword") {
+					opts, err := core.ParseLogGrepArgs(args.Strings("keyword"))
+					if err != nil {
+						return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "检索条件不合法：" + err.Error()})
+					}
+					p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID, UnraidLogPath: filePath})
+					return p.grepLogFileAndReply(ctx, userID, ins, filePath, startLine, logLines(args.Int("lines"), true), opts)
+				}
+				lines := logFilePageLines
+				if args.Has("lines") {
+					lines = clampInt(args.Int("lines"), 1, maxLogTail)
+				}
+				return p.showLogFile(ctx, userID, ins, filePath, startLine, lines)
+			},
+		},
+	}
+}
@@ -0,0 +1,184 @@
+package unraid
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// newLogFileServer 模拟 logFiles/logFile 查询：/var/log/syslog 共 100 行，第 42、95 行为 ERROR。
+func newLogFileServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var lines []string
+	for i := 1; i <= 100; i++ {
+		switch i {
+		case 42:
+			lines = append(lines, "kernel: ERROR boom")
+		case 95:
+			lines = append(lines, "nginx: error again")
+		default:
+			lines = append(lines, "line "+strconv.Itoa(i))
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		var data map[string]interface{}
+		switch {
+		case strings.Contains(req.Query, "logFiles"):
+			data = map[string]interface{}{"logFiles": []map[string]interface{}{
+				{"name": "syslog", "path": "/var/log/syslog", "size": 2048, "modifiedAt": "2026-10-17T08:00:00Z"},
+				{"name": "passwd", "path": "/etc/passwd", "size": 10},
+				{"name": "error.log", "path": "/var/log/nginx/error.log", "size": 0},
+			}}
+		case strings.Contains(req.Query, "logFile("):
+			n, _ := req.Variables["lines"].(float64)
+			start := len(lines) - int(n) + 1
+			if v, ok := req.Variables["startLine"].(float64); ok {
+				start = int(v)
+			}
+			start = max(start, 1)
+			end := min(start+int(n)-1, len(lines))
+			content := ""
+			if start <= end {
+				content = strings.Join(lines[start-1:end], "\n") + "\n"
+			}
+			data = map[string]interface{}{"logFile": map[string]interface{}{
+				"path": req.Variables["path"], "content": content, "totalLines": len(lines), "startLine": start,
+			}}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestProvider_LogFileBrowser(t *testing.T) {
+	t.Parallel()
+
+	srv := newLogFileServer(t)
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{WeCom: wc, Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client()), State: state})
+	ctx := context.Background()
+	const userID = "u1"
+	event := func(key string) {
+		t.Helper()
+		if _, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: key}); err != nil {
+			t.Fatalf("HandleEvent(%s) error: %v", key, err)
+		}
+	}
+	lastText := func() string {
+		texts := wc.Texts()
+		return texts[len(texts)-1].Content
+	}
+	lastCardKeys := func() string {
+		cards := wc.Cards()
+		return strings.Join(cardButtonKeys(t, cards[len(cards)-1].Card), ",")
+	}
+
+	event(wecom.EventKeyUnraidMenuLogFiles)
+	keys := lastCardKeys()
+	if !strings.Contains(keys, wecom.EventKeyUnraidLogFileSelectPrefix+"/var/log/syslog") ||
+		!strings.Contains(keys, wecom.EventKeyUnraidLogFileSelectPrefix+"/var/log/nginx/error.log") || strings.Contains(keys, "passwd") {
+		t.Fatalf("select card keys = %s", keys)
+	}
+	if got := mustJSON(t, wc.Cards()[len(wc.Cards())-1].Card); !strings.Contains(got, "syslog（2.00KiB）") {
+		t.Fatalf("select card = %s", got)
+	}
+
+	// 选择文件后显示末尾一页，可向前翻页。
+	event(wecom.EventKeyUnraidLogFileSelectPrefix + "/var/log/syslog")
+	if got := lastText(); !strings.HasPrefix(got, "【系统日志】/var/log/syslog 第 81-100 行（共 100 行）\nline 81") || !strings.HasSuffix(got, "line 100") {
+		t.Fatalf("tail page = %q", got)
+	}
+	if keys := lastCardKeys(); !strings.Contains(keys, wecom.EventKeyUnraidLogFileViewPrefix+"older") || strings.Contains(keys, "newer") {
+		t.Fatalf("tail card keys = %s", keys)
+	}
+	event(wecom.EventKeyUnraidLogFileViewPrefix + "older")
+	if got := lastText(); !strings.Contains(got, "第 61-80 行") {
+		t.Fatalf("older page = %q", got)
+	}
+	event(wecom.EventKeyUnraidLogFileViewPrefix + "head")
+	if got := lastText(); !strings.Contains(got, "第 1-20 行") {
+		t.Fatalf("head page = %q", got)
+	}
+	if keys := lastCardKeys(); strings.Contains(keys, "older") || !strings.Contains(keys, "newer") {
+		t.Fatalf("head card keys = %s", keys)
+	}
+	event(wecom.EventKeyUnraidLogFileViewPrefix + "newer")
+	if got := lastText(); !strings.Contains(got, "第 21-40 行") {
+		t.Fatalf("newer page = %q", got)
+	}
+
+	// 检索：行号为文件中的行号。
+	event(wecom.EventKeyUnraidLogFileGrep)
+	if _, err := p.HandleText(ctx, userID, "error -i"); err != nil {
+		t.Fatalf("HandleText(grep) error: %v", err)
+	}
+	want := "【系统日志检索】/var/log/syslog（第 1-100 行）\n检索：“error”（忽略大小写）\n匹配 2 行（共扫描 100 行）\n42:kernel: ERROR boom\n--\n95:nginx: error again"
+	if got := lastText(); got != want {
+		t.Fatalf("grep reply =\n%s\nwant\n%s", got, want)
+	}
+	event(wecom.EventKeyUnraidLogFileViewPrefix + "tail")
+	if got := lastText(); !strings.Contains(got, "第 81-100 行") {
+		t.Fatalf("tail after grep = %q", got)
+	}
+}
+
+func TestProvider_LogFileCommands(t *testing.T) {
+	t.Parallel()
+
+	srv := newLogFileServer(t)
+	auth, err := core.NewAuthorizer(core.AuthorizerConfig{
+		Bindings: []core.RoleBinding{
+			{Subjects: []string{"viewer"}, Role: core.RoleViewer},
+			{Subjects: []string{"ops"}, Role: core.RoleOperator},
+			{Subjects: []string{"mom"}, Role: core.RoleOperator, Scope: &core.Scope{Containers: []string{"jellyfin"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{WeCom: wc, Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client()), State: state, Auth: auth})
+	r := core.NewRouter(core.RouterDeps{WeCom: wc, Auth: auth, Providers: []core.ServiceProvider{p}, State: state})
+	ctx := context.Background()
+	send := func(userID, content string) string {
+		t.Helper()
+		if err := r.HandleMessage(ctx, wecom.IncomingMessage{FromUserName: userID, MsgType: "text", Content: content}); err != nil {
+			t.Fatalf("HandleMessage(%q) error: %v", content, err)
+		}
+		texts := wc.Texts()
+		return texts[len(texts)-1].Content
+	}
+
+	if got := send("viewer", "/unraid logfile syslog"); !strings.Contains(got, syslogPermission) {
+		t.Fatalf("viewer reply = %q, want forbidden", got)
+	}
+	if got := send("mom", "/unraid logfile syslog"); !strings.Contains(got, "无权限") {
+		t.Fatalf("container-scoped reply = %q, want forbidden", got)
+	}
+	if got := send("ops", "/unraid logfile ../../etc/passwd"); !strings.Contains(got, "仅支持 /var/log") {
+		t.Fatalf("invalid path reply = %q", got)
+	}
+	if got := send("ops", "/unraid logfile syslog 5 +50"); !strings.HasPrefix(got, "【系统日志】/var/log/syslog 第 50-54 行") {
+		t.Fatalf("start page reply = %q", got)
+	}
+	if got := send("ops", "/unraid logfile /var/log/syslog head 50 -e ^kernel -C1"); !strings.Contains(got, "（第 1-50 行）") ||
+		!strings.Contains(got, "41-line 41\n42:kernel: ERROR boom\n43-line 43") {
+		t.Fatalf("head grep reply = %q", got)
+	}
+}
@@ -0,0 +1,64 @@
+package unraid
+
+// provider_notify.go 处理 Unraid 通知卡片上的归档按钮（单条归档 / 按重要程度全部归档）。
+import (
+	"context"
+	"fmt"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// notifyArchiveAllowed 校验用户能否归档实例的通知；通知覆盖整台实例，限定了容器/虚拟机等目标的绑定不覆盖。
+// 未注入授权器时不限制。
+func (p *Provider) notifyArchiveAllowed(userID string, ins Instance) bool {
+	return p.auth == nil || p.auth.CanAccess(userID, notifyArchivePermission, core.Resource{InstanceID: ins.ID, WholeInstance: true})
+}
+
+func (p *Provider) sendNotifyArchiveForbidden(ctx context.Context, userID string) error {
+	return p.wecom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: fmt.Sprintf("无权限：当前账号未被授权归档该实例的通知（%s）。", notifyArchivePermission),
+	})
+}
+
+// archiveNotification 处理通知卡片“归档”按钮：suffix 为 <实例ID>:<通知ID>。
+func (p *Provider) archiveNotification(ctx context.Context, userID, suffix string) error {
+	insID, id, ok := splitNotifyKey(suffix)
+	ins, found := p.instances[insID]
+	if !ok || !found {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "通知不可用或实例已移除。"})
+	}
+	if !p.notifyArchiveAllowed(userID, ins) {
+		return p.sendNotifyArchiveForbidden(ctx, userID)
+	}
+	if err := ins.Client.ArchiveNotification(ctx, id); err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "归档失败：" + err.Error()})
+	}
+	return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "已归档该通知。"})
+}
+
+// archiveAllNotifications 处理“全部归档”按钮：suffix 为 <实例ID>:<重要程度>。
+func (p *Provider) archiveAllNotifications(ctx context.Context, userID, suffix string) error {
+	insID, importance, ok := splitNotifyKey(suffix)
+	ins, found := p.instances[insID]
+	if !ok || !found {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "通知不可用或实例已移除。"})
+	}
+	if !p.notifyArchiveAllowed(userID, ins) {
+		return p.sendNotifyArchiveForbidden(ctx, userID)
+	}
+	switch importance {
+	case NotifyImportanceAlert, NotifyImportanceWarning, NotifyImportanceInfo:
+	default:
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未知重要程度：" + importance})
+	}
+	remain, err := ins.Client.ArchiveAllNotifications(ctx, importance)
+	if err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "归档失败：" + err.Error()})
+	}
+	return p.wecom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: fmt.Sprintf("已归档全部“%s”通知，剩余未读 %d 条。", notifyImportanceText(importance), remain),
+	})
+}
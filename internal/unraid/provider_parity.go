@@ -0,0 +1,165 @@
+package unraid
+
+// provider_parity.go 实现 Unraid 校验检查卡片（进度/预计剩余/历史）与开始/暂停/恢复/取消的确认流程。
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+const parityHistoryLimit = 10
+
+// parityActionPermission 返回校验检查动作权限：unraid.parity.<start|start_correct|pause|resume|cancel>。
+func parityActionPermission(action core.Action) string {
+	return core.ServicePermission("unraid", "parity", strings.TrimPrefix(string(action), "parity_"))
+}
+
+// parityAllowed 校验用户能否对实例执行校验检查动作；校验作用于整个阵列，限定了容器/虚拟机等目标的绑定不覆盖。
+// 未注入授权器时不限制。
+func (p *Provider) parityAllowed(userID string, ins Instance, action core.Action) bool {
+	return p.auth == nil || p.auth.CanAccess(userID, parityActionPermission(action), parityResource(ins))
+}
+
+func parityResource(ins Instance) core.Resource {
+	return core.Resource{InstanceID: ins.ID, WholeInstance: true}
+}
+
+// sendParityCard 发送当前校验检查进度与操作卡片（按钮按状态与权限过滤）。
+func (p *Provider) sendParityCard(ctx context.Context, userID string, ins Instance) error {
+	if ins.Client == nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "查询失败：unraid client 未配置"})
+	}
+	// 状态查询失败时仍发送卡片（按空闲状态提供开始按钮）并附历史记录，避免无法发起或查看校验检查。
+	var content string
+	cur, err := ins.Client.GetParityStatus(ctx)
+	if err != nil {
+		slog.Warn("Unraid 校验检查状态查询失败", "instance_id", ins.ID, "error", err)
+		content = "校验检查\n状态：未知（查询失败：" + err.Error() + "）"
+		if list, errHistory := ins.Client.ListParityHistory(ctx); errHistory == nil {
+			content += "\n\n" + formatParityHistory(list, parityHistoryLimit)
+		}
+	} else {
+		content = formatParityStatus(cur)
+	}
+	instanceName := ""
+	if len(p.order) > 1 {
+		instanceName = ins.Name
+		content = "[" + ins.Name + "] " + content
+	}
+	_ = p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: content})
+
+	var actions []string
+	for _, action := range parityActionsForStatus(cur.Status) {
+		if p.parityAllowed(userID, ins, action) {
+			actions = append(actions, string(action))
+		}
+	}
+	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card: wecom.NewUnraidParityCard(wecom.UnraidParityCardOptions{
+			InstanceName: instanceName,
+			StatusText:   parityStatusText(cur.Status),
+			Actions:      actions,
+		}),
+	})
+}
+
+// confirmParity 校验动作与权限后进入校验检查操作的待确认状态。
+func (p *Provider) confirmParity(ctx context.Context, userID string, ins Instance, action core.Action) error {
+	if !isParityAction(action) {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未知动作，请返回后重试。"})
+	}
+	if !p.parityAllowed(userID, ins, action) {
+		return p.wecom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: fmt.Sprintf("无权限：当前账号未被授权%s（%s）。", action.DisplayName(), parityActionPermission(action)),
+		})
+	}
+
+	p.state.Set(userID, core.ConversationState{
+		ServiceKey: p.Key(),
+		InstanceID: ins.ID,
+		Step:       core.StepAwaitingConfirm,
+		Action:     action,
+	})
+
+	target := p.targetLabel(ins, "阵列")
+	hint := ""
+	if action == core.ActionUnraidParityStartCorrect {
+		hint = "\n注意：修正模式会将校验错误写回校验盘。"
+	}
+	_ = p.wecom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: fmt.Sprintf("确认执行：%s %s%s\n回复“确认”继续，回复“取消”终止。", action.DisplayName(), target, hint),
+	})
+	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card:   wecom.NewConfirmCard(action.DisplayName(), target),
+	})
+}
+
+// parityAction 构造校验检查操作；开始/恢复成功后登记发起人，检查结束时推送完成通知。
+func (p *Provider) parityAction(ins Instance, action core.Action, userID string) core.ConfirmedAction {
+	target := p.targetLabel(ins, "阵列")
+	return core.ConfirmedAction{
+		ServiceKey: p.Key(),
+		InstanceID: ins.ID,
+		Action:     action,
+		Target:     "阵列",
+		Permission: parityActionPermission(action),
+		Resource:   parityResource(ins),
+		Run: func(ctx context.Context, _ core.ProgressFunc) (string, error) {
+			if ins.Client == nil {
+				return "", errors.New("unraid client 未配置")
+			}
+			if err := ins.Client.ParityAction(ctx, action); err != nil {
+				return "", err
+			}
+			msg := fmt.Sprintf("%s %s", action.DisplayName(), target)
+			switch action {
+			case core.ActionUnraidParityStart, core.ActionUnraidParityStartCorrect, core.ActionUnraidParityResume:
+				if p.alerts != nil {
+					p.alerts.WatchParity(ins.ID, userID)
+					msg += "\n检查结束后将推送结果与错误数。"
+				}
+			}
+			return msg, nil
+		},
+	}
+}
+
+// parityCommands 声明校验检查命令：/unraid parity 查看进度，history 查看历史，其余动作走确认流程。
+func (p *Provider) parityCommands(instanceArg core.CommandArg) []core.Command {
+	return []core.Command{
+		{
+			Path:    "unraid parity",
+			Summary: "校验检查进度（history 查看历史；start/correct/pause/resume/cancel 需确认）",
+			Args: []core.CommandArg{
+				{Name: "action", Type: core.ArgChoice, Choices: []string{"history", "start", "correct", "pause", "resume", "cancel"}, Optional: true},
+				instanceArg,
+			},
+			Handler: func(ctx context.Context, userID string, args core.CommandArgs) error {
+				ins, err := p.commandInstance(userID, args.String("instance"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				p.state.Set(userID, core.ConversationState{ServiceKey: p.Key(), InstanceID: ins.ID})
+				switch verb := args.String("action"); verb {
+				case "":
+					return p.sendParityCard(ctx, userID, ins)
+				case "history":
+					return p.execViewAndReply(ctx, userID, ins, core.ActionUnraidViewParity, "", 0)
+				case "correct":
+					return p.confirmParity(ctx, userID, ins, core.ActionUnraidParityStartCorrect)
+				default:
+					return p.confirmParity(ctx, userID, ins, core.Action("parity_"+verb))
+				}
+			},
+		},
+	}
+}
@@ -0,0 +1,339 @@
+package unraid
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// parityServer 模拟 parityCheckStatus/parityHistory/vars 查询与 parityCheck mutations；status 可在测试中切换。
+// status 为 nil 时模拟未提供 parityCheckStatus 字段的 Unraid API，vars 为 nil 时 vars 查询同样失败。
+type parityServer struct {
+	mu     sync.Mutex
+	status map[string]interface{}
+	vars   map[string]interface{}
+	calls  []string
+}
+
+func (s *parityServer) setVars(v map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vars = v
+}
+
+func (s *parityServer) setStatus(v map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = v
+}
+
+func (s *parityServer) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+func newParityServer(t *testing.T) (*httptest.Server, *parityServer) {
+	t.Helper()
+
+	ps := &parityServer{status: map[string]interface{}{"status": "COMPLETED", "date": "2026-10-01T03:00:00Z", "errors": 0}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		ps.mu.Lock()
+		defer ps.mu.Unlock()
+		switch {
+		case strings.Contains(req.Query, "mutation ParityAction"):
+			call := strings.TrimSpace(strings.SplitN(strings.SplitN(req.Query, "parityCheck {", 2)[1], "}", 2)[0])
+			if v, ok := req.Variables["correct"]; ok {
+				call = strings.SplitN(call, "(", 2)[0] + map[bool]string{true: "(correct)", false: "(check)"}[v.(bool)]
+			}
+			ps.calls = append(ps.calls, call)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"parityCheck": map[string]interface{}{}}})
+		case strings.Contains(req.Query, "parityHistory"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"parityHistory": []map[string]interface{}{
+						{"date": "2026-09-01T03:00:00Z", "duration": 36000, "speed": "150 MB/s", "status": "COMPLETED", "errors": 0},
+						{"date": "2026-10-01T03:00:00Z", "duration": 39600, "speed": "140 MB/s", "status": "CANCELLED", "errors": 2},
+					},
+				},
+			})
+		case strings.Contains(req.Query, "vars {"):
+			if ps.vars == nil {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": []map[string]interface{}{{"message": `Cannot query field "mdResync" on type "Vars".`}}})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"vars": ps.vars}})
+		case ps.status == nil:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": []map[string]interface{}{{"message": `Cannot query field "parityCheckStatus" on type "UnraidArray".`}}})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"array": map[string]interface{}{"parityCheckStatus": ps.status}},
+			})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, ps
+}
+
+func TestProvider_ParityFlow(t *testing.T) {
+	t.Parallel()
+
+	srv, ps := newParityServer(t)
+	client := NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client())
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	alerts := NewAlertManager(AlertManagerDeps{
+		WeCom:     wc,
+		Instances: []Instance{{Name: "Unraid", Client: client}},
+		Config:    AlertConfig{Enabled: false},
+	})
+	p := NewProvider(ProviderDeps{WeCom: wc, Client: client, State: state, Alerts: alerts})
+
+	ctx := context.Background()
+	const userID = "u1"
+
+	if ok, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidMenuParity}); err != nil || !ok {
+		t.Fatalf("HandleEvent(menu parity) ok=%v err=%v", ok, err)
+	}
+	texts := wc.Texts()
+	if got := texts[len(texts)-1].Content; !strings.Contains(got, "状态：已完成") || !strings.Contains(got, "错误数：0") {
+		t.Fatalf("parity status text = %q", got)
+	}
+	cards := wc.Cards()
+	keys := strings.Join(cardButtonKeys(t, cards[len(cards)-1].Card), ",")
+	if !strings.Contains(keys, wecom.EventKeyUnraidParityActionPrefix+"parity_start_correct") || strings.Contains(keys, "parity_pause") {
+		t.Fatalf("idle parity buttons = %s", keys)
+	}
+
+	if _, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidParityActionPrefix + "parity_start_correct"}); err != nil {
+		t.Fatalf("HandleEvent(start correct) error: %v", err)
+	}
+	if st, _ := state.Get(userID); st.Step != core.StepAwaitingConfirm || st.Action != core.ActionUnraidParityStartCorrect {
+		t.Fatalf("state = %+v, want confirm parity_start_correct", st)
+	}
+	action, handled, err := p.PrepareConfirm(ctx, userID)
+	if err != nil || !handled || action.RequiredPermission() != "unraid.parity.start_correct" {
+		t.Fatalf("PrepareConfirm() = %+v handled=%v err=%v", action, handled, err)
+	}
+	if msg, err := action.Run(ctx, func(string) {}); err != nil || !strings.Contains(msg, "检查结束后将推送") {
+		t.Fatalf("Run() = %q, %v", msg, err)
+	}
+	if got := strings.Join(ps.Calls(), ","); got != "start(correct)" {
+		t.Fatalf("mutations = %q, want start(correct)", got)
+	}
+
+	// 运行中：展示进度与预计剩余，按钮为暂停/取消。
+	ps.setStatus(map[string]interface{}{"status": "RUNNING", "progress": 25, "duration": 3600, "speed": "150 MB/s", "correcting": true, "errors": 0})
+	alerts.checkOnce()
+	before := len(wc.Texts())
+	if _, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidMenuParity}); err != nil {
+		t.Fatalf("HandleEvent(refresh) error: %v", err)
+	}
+	texts = wc.Texts()
+	if len(texts) != before+1 {
+		t.Fatalf("unexpected notifications while running: %+v", texts[before-1:])
+	}
+	for _, want := range []string{"运行中（修正错误）", "进度：25%", "预计剩余：3小时0分"} {
+		if !strings.Contains(texts[len(texts)-1].Content, want) {
+			t.Fatalf("running status missing %q: %q", want, texts[len(texts)-1].Content)
+		}
+	}
+	cards = wc.Cards()
+	keys = strings.Join(cardButtonKeys(t, cards[len(cards)-1].Card), ",")
+	if !strings.Contains(keys, "parity_pause") || !strings.Contains(keys, "parity_cancel") || strings.Contains(keys, "parity_start") {
+		t.Fatalf("running parity buttons = %s", keys)
+	}
+
+	// 结束：向发起人推送完成通知（含错误数），之后不再重复。
+	ps.setStatus(map[string]interface{}{"status": "COMPLETED", "duration": 14400, "errors": 3})
+	alerts.checkOnce()
+	texts = wc.Texts()
+	last := texts[len(texts)-1]
+	if last.ToUser != userID || !strings.Contains(last.Content, "校验检查结束") || !strings.Contains(last.Content, "错误数：3") {
+		t.Fatalf("completion notice = %+v", last)
+	}
+	n := len(texts)
+	alerts.checkOnce()
+	if got := len(wc.Texts()); got != n {
+		t.Fatalf("texts after second check = %d, want %d", got, n)
+	}
+
+	if _, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidParityHistory}); err != nil {
+		t.Fatalf("HandleEvent(history) error: %v", err)
+	}
+	texts = wc.Texts()
+	history := texts[len(texts)-1].Content
+	if !strings.Contains(history, "最近 2 次") || strings.Index(history, "已取消") > strings.Index(history, "已完成") {
+		t.Fatalf("history = %q, want newest first", history)
+	}
+}
+
+func TestProvider_ParityStatusFallback(t *testing.T) {
+	t.Parallel()
+
+	srv, ps := newParityServer(t)
+	ps.setStatus(nil)
+	client := NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client())
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	alerts := NewAlertManager(AlertManagerDeps{
+		WeCom:     wc,
+		Instances: []Instance{{Name: "Unraid", Client: client}},
+		Config:    AlertConfig{Enabled: false},
+	})
+	p := NewProvider(ProviderDeps{WeCom: wc, Client: client, State: state, Alerts: alerts})
+	ctx := context.Background()
+	const userID = "u1"
+	openCard := func() (string, string) {
+		t.Helper()
+		if _, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidMenuParity}); err != nil {
+			t.Fatalf("HandleEvent(menu parity) error: %v", err)
+		}
+		texts, cards := wc.Texts(), wc.Cards()
+		return texts[len(texts)-1].Content, strings.Join(cardButtonKeys(t, cards[len(cards)-1].Card), ",")
+	}
+
+	// 状态与 vars 均不可用：仍发送卡片（开始按钮 + 历史记录）。
+	text, keys := openCard()
+	if !strings.Contains(text, "状态：未知（查询失败") || !strings.Contains(text, "最近 2 次") {
+		t.Fatalf("unavailable status text = %q", text)
+	}
+	if !strings.Contains(keys, "parity_start") || !strings.Contains(keys, wecom.EventKeyUnraidParityHistory) {
+		t.Fatalf("unavailable parity buttons = %s", keys)
+	}
+
+	// 回退 vars：由 mdResync* 推算进度与速度。
+	ps.setVars(map[string]interface{}{
+		"mdResync": "976762584", "mdResyncPos": "244190646", "mdResyncSize": "976762584",
+		"mdResyncDt": "10", "mdResyncDb": "1536000", "mdResyncCorr": "1", "sbSyncErrs": "0",
+	})
+	text, keys = openCard()
+	for _, want := range []string{"运行中（修正错误）", "进度：25%", "速度：150.0 MB/s"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("vars status missing %q: %q", want, text)
+		}
+	}
+	if !strings.Contains(keys, "parity_pause") || strings.Contains(keys, "parity_start") {
+		t.Fatalf("vars parity buttons = %s", keys)
+	}
+
+	// 检查结束（mdResyncPos 归零）：以最近一次历史记录推送完成通知。
+	alerts.WatchParity("", userID)
+	alerts.checkOnce()
+	ps.setVars(map[string]interface{}{"mdResync": "0", "mdResyncPos": "0", "mdResyncSize": "976762584", "sbSyncErrs": "2"})
+	alerts.checkOnce()
+	texts := wc.Texts()
+	last := texts[len(texts)-1]
+	if last.ToUser != userID || !strings.Contains(last.Content, "校验检查结束") || !strings.Contains(last.Content, "错误数：2") {
+		t.Fatalf("completion notice = %+v", last)
+	}
+}
+
+func TestProvider_ParityCommandPermission(t *testing.T) {
+	t.Parallel()
+
+	srv, ps := newParityServer(t)
+	auth, err := core.NewAuthorizer(core.AuthorizerConfig{
+		Roles: map[string][]string{"checker": {"unraid.view", "unraid.parity.start"}},
+		Bindings: []core.RoleBinding{
+			{Subjects: []string{"kid"}, Role: "checker"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{
+		WeCom:  wc,
+		Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client()),
+		State:  state,
+		Auth:   auth,
+	})
+	r := core.NewRouter(core.RouterDeps{WeCom: wc, Auth: auth, Providers: []core.ServiceProvider{p}, State: state})
+	ctx := context.Background()
+	send := func(content string) string {
+		t.Helper()
+		if err := r.HandleMessage(ctx, wecom.IncomingMessage{FromUserName: "kid", MsgType: "text", Content: content}); err != nil {
+			t.Fatalf("HandleMessage(%q) error: %v", content, err)
+		}
+		texts := wc.Texts()
+		return texts[len(texts)-1].Content
+	}
+
+	if got := send("/unraid parity correct"); !strings.Contains(got, "无权限") {
+		t.Fatalf("correct reply = %q, want forbidden", got)
+	}
+	send("/unraid parity")
+	cards := wc.Cards()
+	if keys := strings.Join(cardButtonKeys(t, cards[len(cards)-1].Card), ","); !strings.Contains(keys, "parity_start") || strings.Contains(keys, "parity_start_correct") {
+		t.Fatalf("parity buttons = %s, want no start_correct", keys)
+	}
+	if got := send("/unraid parity start"); !strings.Contains(got, "确认执行：开始校验检查 阵列") {
+		t.Fatalf("start reply = %q, want confirm", got)
+	}
+	send("确认")
+	if got := strings.Join(ps.Calls(), ","); got != "start(check)" {
+		t.Fatalf("mutations = %q, want start(check)", got)
+	}
+}
+
+func TestProvider_ParityScopedBinding(t *testing.T) {
+	t.Parallel()
+
+	srv, ps := newParityServer(t)
+	auth, err := core.NewAuthorizer(core.AuthorizerConfig{
+		Bindings: []core.RoleBinding{
+			{Subjects: []string{"mom"}, Role: core.RoleOperator, Scope: &core.Scope{Containers: []string{"jellyfin"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{WeCom: wc, Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client()), State: state, Auth: auth})
+	r := core.NewRouter(core.RouterDeps{WeCom: wc, Auth: auth, Providers: []core.ServiceProvider{p}, State: state})
+	ctx := context.Background()
+	send := func(content string) string {
+		t.Helper()
+		if err := r.HandleMessage(ctx, wecom.IncomingMessage{FromUserName: "mom", MsgType: "text", Content: content}); err != nil {
+			t.Fatalf("HandleMessage(%q) error: %v", content, err)
+		}
+		texts := wc.Texts()
+		return texts[len(texts)-1].Content
+	}
+
+	// 仅限定容器的运维账号可查看进度，但不能操作整个阵列的校验。
+	for _, cmd := range []string{"/unraid parity start", "/unraid parity correct", "/unraid parity cancel"} {
+		if got := send(cmd); !strings.Contains(got, "无权限") {
+			t.Fatalf("%s reply = %q, want forbidden", cmd, got)
+		}
+	}
+	send("/unraid parity")
+	cards := wc.Cards()
+	if keys := strings.Join(cardButtonKeys(t, cards[len(cards)-1].Card), ","); strings.Contains(keys, "parity_") {
+		t.Fatalf("parity buttons = %s, want none", keys)
+	}
+	if calls := ps.Calls(); len(calls) != 0 {
+		t.Fatalf("mutations = %v, want none", calls)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -154,7 +155,7 @@ func TestProvider_ViewFlowsAndLogTail(t *testing.T) {
 	t.Cleanup(srv.Close)
 
 	rec := &recordWeCom{}
-	store := core.NewStateStore(1 * time.Minute)
+	store := core.NewMemoryStateStore(1 * time.Minute)
 	t.Cleanup(store.Close)
 
 	client := NewClient(ClientConfig{
@@ -221,11 +222,74 @@ func TestProvider_ViewFlowsAndLogTail(t *testing.T) {
 	}
 }
 
+func TestProvider_LogsGrep(t *testing.T) {
+	t.Parallel()
+
+	lines := make([]string, 0, 3000)
+	for i := 1; i <= 3000; i++ {
+		switch i {
+		case 500:
+			lines = append(lines, "ERROR too old")
+		case 2500:
+			lines = append(lines, "[error] upstream timed out")
+		case 2990:
+			lines = append(lines, "ERROR disk full")
+		default:
+			lines = append(lines, "GET /"+strconv.Itoa(i)+" 200")
+		}
+	}
+	srv, ls := newLogsServer(t, lines...)
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{WeCom: wc, Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client()), State: state})
+	r := core.NewRouter(core.RouterDeps{WeCom: wc, AllowedUserID: map[string]struct{}{"u": {}}, Providers: []core.ServiceProvider{p}, State: state})
+	ctx := context.Background()
+	send := func(content string) string {
+		t.Helper()
+		if err := r.HandleMessage(ctx, wecom.IncomingMessage{FromUserName: "u", MsgType: "text", Content: content}); err != nil {
+			t.Fatalf("HandleMessage(%q) error: %v", content, err)
+		}
+		texts := wc.Texts()
+		return texts[len(texts)-1].Content
+	}
+
+	got := send("/unraid logs app 2000 error -i -C1")
+	if !strings.Contains(ls.LastQuery(), "2000") {
+		t.Fatalf("query = %q, want tail 2000", ls.LastQuery())
+	}
+	want := "【日志检索】app（最近 2000 行）\n检索：“error”（忽略大小写，上下文 1 行）\n匹配 2 行（共扫描 2000 行）\n" +
+		"1499-GET /2499 200\n1500:[error] upstream timed out\n1501-GET /2501 200\n--\n1989-GET /2989 200\n1990:ERROR disk full\n1991-GET /2991 200"
+	if got != want {
+		t.Fatalf("grep reply =\n%s\nwant\n%s", got, want)
+	}
+
+	// 未指定行数时按默认检索窗口；正则中的逗号不被拆分。
+	if got := send("/unraid logs app -e ^ERROR\\s(disk|db),?"); !strings.Contains(got, "（最近 1000 行）") || !strings.Contains(got, "匹配 1 行") {
+		t.Fatalf("regex reply = %q", got)
+	}
+	if got := send("/unraid logs app -e (oops"); !strings.Contains(got, "正则表达式无效") {
+		t.Fatalf("invalid regex reply = %q", got)
+	}
+
+	// 文本模式：容器名 [行数] 关键词。
+	state.Set("u", core.ConversationState{ServiceKey: "unraid", Step: core.StepAwaitingUnraidViewAction})
+	send("2")
+	if got := send("app 5000 too old"); !strings.Contains(got, "匹配 1 行（共扫描 3000 行）") || !strings.Contains(got, "500:ERROR too old") {
+		t.Fatalf("text grep reply = %q", got)
+	}
+	state.Set("u", core.ConversationState{ServiceKey: "unraid", Step: core.StepAwaitingUnraidViewAction})
+	send("2")
+	if got := send("app 3"); !strings.HasPrefix(got, "【日志】app（tail 3 行）") {
+		t.Fatalf("text tail reply = %q", got)
+	}
+}
+
 func TestProvider_MenuNavigation_Cards(t *testing.T) {
 	t.Parallel()
 
 	rec := &recordWeCom{}
-	store := core.NewStateStore(1 * time.Minute)
+	store := core.NewMemoryStateStore(1 * time.Minute)
 	t.Cleanup(store.Close)
 
 	p := NewProvider(ProviderDeps{
@@ -281,7 +345,7 @@ func TestProvider_ClickMenuView_SendsTemplateCard(t *testing.T) {
 	t.Parallel()
 
 	rec := &recordWeCom{}
-	store := core.NewStateStore(1 * time.Minute)
+	store := core.NewMemoryStateStore(1 * time.Minute)
 	t.Cleanup(store.Close)
 
 	p := NewProvider(ProviderDeps{
@@ -317,3 +381,472 @@ func TestProvider_ClickMenuView_SendsTemplateCard(t *testing.T) {
 		t.Fatalf("view title = %q, want %q", title, "Unraid 容器查看")
 	}
 }
+
+func TestProvider_ScopedContainers(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"docker": map[string]interface{}{
+					"containers": []map[string]interface{}{
+						{"id": "docker:1", "names": []string{"jellyfin"}, "state": "running"},
+						{"id": "docker:2", "names": []string{"homeassistant"}, "state": "running"},
+						{"id": "docker:3", "names": []string{"media-sonarr"}, "state": "running"},
+					},
+				},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	auth, err := core.NewAuthorizer(core.AuthorizerConfig{
+		Bindings: []core.RoleBinding{{
+			Subjects: []string{"mom"},
+			Role:     core.RoleOperator,
+			Scope:    &core.Scope{Containers: []string{"jellyfin", "media-*"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+
+	rec := &recordWeCom{}
+	store := core.NewMemoryStateStore(1 * time.Minute)
+	t.Cleanup(store.Close)
+
+	p := NewProvider(ProviderDeps{
+		WeCom:  rec,
+		Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client()),
+		State:  store,
+		Auth:   auth,
+	})
+
+	ctx := context.Background()
+	userID := "mom"
+
+	if ok, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidRestart}); err != nil || !ok {
+		t.Fatalf("HandleEvent(restart) ok=%v err=%v", ok, err)
+	}
+	cards := rec.Cards()
+	if len(cards) == 0 {
+		t.Fatalf("want container select card")
+	}
+	_, buttons, _ := wecom.RenderButtonInteractionTextMenu(cards[len(cards)-1].Card)
+	var names []string
+	for _, b := range buttons {
+		if strings.HasPrefix(b.Key, wecom.EventKeyUnraidContainerSelectPrefix) {
+			names = append(names, strings.TrimPrefix(b.Key, wecom.EventKeyUnraidContainerSelectPrefix))
+		}
+	}
+	if strings.Join(names, ",") != "jellyfin,media-sonarr" {
+		t.Fatalf("container buttons = %v, want jellyfin,media-sonarr", names)
+	}
+
+	// 手动构造越权事件应被拒绝且不进入确认。
+	if ok, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidContainerSelectPrefix + "homeassistant"}); err != nil || !ok {
+		t.Fatalf("HandleEvent(select homeassistant) ok=%v err=%v", ok, err)
+	}
+	if st, _ := store.Get(userID); st.Step == core.StepAwaitingConfirm {
+		t.Fatalf("state step = %q, want not awaiting confirm", st.Step)
+	}
+	texts := rec.Texts()
+	if len(texts) == 0 || !strings.Contains(texts[len(texts)-1].Content, "无权限") {
+		t.Fatalf("want forbidden reply, got: %#v", texts)
+	}
+}
+
+func TestProvider_ActionSpec(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"docker": map[string]interface{}{
+					"containers": []map[string]interface{}{
+						{"id": "docker:1", "names": []string{"/jellyfin"}, "state": "running"},
+					},
+				},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	p := NewProvider(ProviderDeps{
+		WeCom:  &recordWeCom{},
+		Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client()),
+		State:  core.NewMemoryStateStore(time.Minute),
+	})
+
+	tests := []struct {
+		in     string
+		ok     bool
+		action core.Action
+		target string
+	}{
+		{"重启 jellyfin", true, core.ActionUnraidRestart, "jellyfin"},
+		{"停止 容器 plex", true, core.ActionUnraidStop, "plex"},
+		{"强制更新 sonarr", true, core.ActionUnraidForceUpdate, "sonarr"},
+		{"restart jellyfin", true, core.ActionUnraidRestart, "jellyfin"},
+		{"stopped jellyfin", false, "", ""},
+		{"重启 VM 101", false, "", ""},
+		{"查看 jellyfin", false, "", ""},
+	}
+	for _, tc := range tests {
+		spec, ok, err := p.ParseActionSpec(tc.in)
+		if err != nil || ok != tc.ok || spec.Action != tc.action || spec.Target != tc.target {
+			t.Fatalf("ParseActionSpec(%q) = %+v ok=%v err=%v; want %s %s ok=%v", tc.in, spec, ok, err, tc.action, tc.target, tc.ok)
+		}
+	}
+
+	ctx := context.Background()
+	action, err := p.BuildAction(ctx, core.ActionSpec{Action: core.ActionUnraidRestart, Target: "jellyfin"})
+	if err != nil {
+		t.Fatalf("BuildAction() error: %v", err)
+	}
+	if action.Target != "jellyfin" || action.Resource.Container != "jellyfin" || action.Run == nil {
+		t.Fatalf("BuildAction() = %+v, want target jellyfin", action)
+	}
+	if _, err := p.BuildAction(ctx, core.ActionSpec{Action: core.ActionUnraidRestart, Target: "plex"}); err == nil {
+		t.Fatalf("BuildAction(missing container) error = nil, want not nil")
+	}
+	if _, err := p.BuildAction(ctx, core.ActionSpec{Action: core.ActionUnraidViewLogs, Target: "jellyfin"}); err == nil {
+		t.Fatalf("BuildAction(view) error = nil, want not nil")
+	}
+}
+
+func TestProvider_BatchStop(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var stopped []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if strings.Contains(req.Query, "mutation Stop") {
+			id := req.Variables["dockerId"].(string)
+			if id == "2" {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": []map[string]interface{}{{"message": "boom"}}})
+				return
+			}
+			mu.Lock()
+			stopped = append(stopped, id)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"docker": map[string]interface{}{"stop": map[string]interface{}{"state": "exited"}}},
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"docker": map[string]interface{}{
+					"containers": []map[string]interface{}{
+						{"id": "docker:1", "names": []string{"/sonarr"}, "state": "running"},
+						{"id": "docker:2", "names": []string{"/radarr"}, "state": "running"},
+						{"id": "docker:3", "names": []string{"/sonarr-4k"}, "state": "running"},
+					},
+				},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{
+		WeCom:  wc,
+		Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client()),
+		State:  state,
+	})
+
+	ctx := context.Background()
+	const userID = "u1"
+	state.Set(userID, core.ConversationState{ServiceKey: "unraid"})
+	if handled, err := p.HandleText(ctx, userID, "停止 sonarr, radarr lidarr"); err != nil || !handled {
+		t.Fatalf("HandleText() handled=%v err=%v", handled, err)
+	}
+	if texts := wc.Texts(); len(texts) != 1 || !strings.Contains(texts[0].Content, "未找到容器：lidarr") {
+		t.Fatalf("texts = %+v, want missing-container reply", texts)
+	}
+
+	// 批量输入中的歧义名称需写完整；“4k”经子串唯一匹配到 sonarr-4k。
+	if _, err := p.HandleText(ctx, userID, "停止 son radarr"); err != nil {
+		t.Fatalf("HandleText() error: %v", err)
+	}
+	if texts := wc.Texts(); !strings.Contains(texts[len(texts)-1].Content, "“son”匹配多个容器：sonarr、sonarr-4k") {
+		t.Fatalf("last text = %q, want ambiguity hint", texts[len(texts)-1].Content)
+	}
+	if _, err := p.HandleText(ctx, userID, "停止 Sonarr, radarr 4k"); err != nil {
+		t.Fatalf("HandleText() error: %v", err)
+	}
+	st, _ := state.Get(userID)
+	if st.Step != core.StepAwaitingConfirm || st.Action != core.ActionUnraidStop || strings.Join(st.ContainerNames, ",") != "sonarr,radarr,sonarr-4k" {
+		t.Fatalf("state = %+v, want batch confirm of 3 containers", st)
+	}
+	if texts := wc.Texts(); !strings.Contains(texts[len(texts)-1].Content, "共 3 项") {
+		t.Fatalf("confirm text = %q", texts[len(texts)-1].Content)
+	}
+
+	action, handled, err := p.PrepareConfirm(ctx, userID)
+	if err != nil || !handled || len(action.Items) != 3 || action.Items[1].Resource.Container != "radarr" {
+		t.Fatalf("PrepareConfirm() = %+v handled=%v err=%v", action, handled, err)
+	}
+	_, err = action.Run(ctx, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "成功 2，失败 1") || !strings.Contains(err.Error(), "[失败] radarr") {
+		t.Fatalf("Run() error = %v, want partial failure report", err)
+	}
+	mu.Lock()
+	if len(stopped) != 2 {
+		t.Fatalf("stopped = %v, want 2 containers", stopped)
+	}
+	mu.Unlock()
+
+	// 单个输入存在歧义时发送候选选择卡片。
+	state.Set(userID, core.ConversationState{ServiceKey: "unraid"})
+	if _, err := p.HandleText(ctx, userID, "重启 son"); err != nil {
+		t.Fatalf("HandleText() error: %v", err)
+	}
+	cards := wc.Cards()
+	if b, _ := json.Marshal(cards[len(cards)-1].Card); !strings.Contains(string(b), wecom.EventKeyUnraidContainerSelectPrefix+"sonarr-4k") {
+		t.Fatalf("last card = %s, want container choices", b)
+	}
+	if st, _ := state.Get(userID); st.Action != core.ActionUnraidRestart || st.Step != "" {
+		t.Fatalf("state = %+v, want pending restart selection", st)
+	}
+
+	// 单个容器仍走原确认流程。
+	state.Set(userID, core.ConversationState{ServiceKey: "unraid"})
+	if _, err := p.HandleText(ctx, userID, "重启 sonarr"); err != nil {
+		t.Fatalf("HandleText() error: %v", err)
+	}
+	if st, _ := state.Get(userID); st.ContainerName != "sonarr" || len(st.ContainerNames) != 0 {
+		t.Fatalf("state = %+v, want single container confirm", st)
+	}
+}
+
+func TestProvider_MultiInstance(t *testing.T) {
+	t.Parallel()
+
+	newServer := func(container string, hits *[]string, mu *sync.Mutex) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Query     string                 `json:"query"`
+				Variables map[string]interface{} `json:"variables"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if strings.Contains(req.Query, "mutation Stop") {
+				mu.Lock()
+				*hits = append(*hits, req.Variables["dockerId"].(string))
+				mu.Unlock()
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"data": map[string]interface{}{"docker": map[string]interface{}{"stop": map[string]interface{}{"state": "exited"}}},
+				})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"docker": map[string]interface{}{
+						"containers": []map[string]interface{}{
+							{"id": "docker:1", "names": []string{"/" + container}, "state": "running"},
+						},
+					},
+				},
+			})
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	var mu sync.Mutex
+	var mainHits, backupHits []string
+	mainSrv := newServer("jellyfin", &mainHits, &mu)
+	backupSrv := newServer("minio", &backupHits, &mu)
+
+	auth, err := core.NewAuthorizer(core.AuthorizerConfig{
+		Bindings: []core.RoleBinding{
+			{Subjects: []string{"admin"}, Role: core.RoleAdmin},
+			{Subjects: []string{"mom"}, Role: core.RoleOperator, Scope: &core.Scope{Instances: []string{"main"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{
+		WeCom: wc,
+		Instances: []Instance{
+			{ID: "main", Name: "主 NAS", Client: NewClient(ClientConfig{Endpoint: mainSrv.URL, APIKey: "k"}, mainSrv.Client())},
+			{ID: "backup", Name: "备份 NAS", Client: NewClient(ClientConfig{Endpoint: backupSrv.URL, APIKey: "k"}, backupSrv.Client())},
+		},
+		State: state,
+		Auth:  auth,
+	})
+
+	ctx := context.Background()
+
+	// 多个可见实例：先发送实例选择卡片。
+	if err := p.OnEnter(ctx, "admin"); err != nil {
+		t.Fatalf("OnEnter() error: %v", err)
+	}
+	cards := wc.Cards()
+	if b, _ := json.Marshal(cards[len(cards)-1].Card); !strings.Contains(string(b), wecom.EventKeyUnraidInstanceSelectPrefix+"backup") {
+		t.Fatalf("last card = %s, want instance select card", b)
+	}
+
+	// 菜单点击在未选择实例时回到实例选择。
+	before := len(wc.Cards())
+	if _, err := p.HandleEvent(ctx, "admin", wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidStop}); err != nil {
+		t.Fatalf("HandleEvent(stop) error: %v", err)
+	}
+	if cards := wc.Cards(); len(cards) != before+1 || !strings.Contains(mustJSON(t, cards[len(cards)-1].Card), wecom.EventKeyUnraidInstanceSelectPrefix) {
+		t.Fatalf("want instance select card after stop without instance")
+	}
+
+	if _, err := p.HandleEvent(ctx, "admin", wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidInstanceSelectPrefix + "backup"}); err != nil {
+		t.Fatalf("HandleEvent(select backup) error: %v", err)
+	}
+	if st, _ := state.Get("admin"); st.InstanceID != "backup" {
+		t.Fatalf("state = %+v, want instance backup", st)
+	}
+	cards = wc.Cards()
+	if b := mustJSON(t, cards[len(cards)-1].Card); !strings.Contains(b, "备份 NAS") || !strings.Contains(b, wecom.EventKeyUnraidSwitchInstance) {
+		t.Fatalf("last card = %s, want entry card of backup with switch button", b)
+	}
+
+	// 实例内的容器操作仅作用于所选实例。
+	if _, err := p.HandleText(ctx, "admin", "停止 minio"); err != nil {
+		t.Fatalf("HandleText() error: %v", err)
+	}
+	action, handled, err := p.PrepareConfirm(ctx, "admin")
+	if err != nil || !handled || action.InstanceID != "backup" || action.Resource.InstanceID != "backup" || action.Target != "minio" {
+		t.Fatalf("PrepareConfirm() = %+v handled=%v err=%v", action, handled, err)
+	}
+	if _, err := action.Run(ctx, func(string) {}); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	mu.Lock()
+	if len(backupHits) != 1 || len(mainHits) != 0 {
+		t.Fatalf("hits main=%v backup=%v, want only backup stopped", mainHits, backupHits)
+	}
+	mu.Unlock()
+
+	// 文本指令可用 @实例 指定目标实例。
+	state.Set("admin", core.ConversationState{ServiceKey: "unraid", InstanceID: "backup"})
+	if _, err := p.HandleText(ctx, "admin", "停止 jellyfin @main"); err != nil {
+		t.Fatalf("HandleText(@main) error: %v", err)
+	}
+	if st, _ := state.Get("admin"); st.InstanceID != "main" || st.ContainerName != "jellyfin" || st.Step != core.StepAwaitingConfirm {
+		t.Fatalf("state = %+v, want confirm jellyfin on main", st)
+	}
+
+	spec, ok, err := p.ParseActionSpec("重启 jellyfin @main")
+	if err != nil || !ok || spec.InstanceID != "main" || spec.Target != "jellyfin" {
+		t.Fatalf("ParseActionSpec(@main) = %+v ok=%v err=%v", spec, ok, err)
+	}
+	if _, err := p.BuildAction(ctx, core.ActionSpec{Action: core.ActionUnraidRestart, Target: "jellyfin"}); err == nil || !strings.Contains(err.Error(), "@<实例ID>") {
+		t.Fatalf("BuildAction(no instance) error = %v, want instance hint", err)
+	}
+
+	// 作用范围仅含一个实例的用户直接进入该实例。
+	state.Clear("mom")
+	if err := p.OnEnter(ctx, "mom"); err != nil {
+		t.Fatalf("OnEnter(mom) error: %v", err)
+	}
+	if st, _ := state.Get("mom"); st.InstanceID != "main" {
+		t.Fatalf("state = %+v, want instance main", st)
+	}
+	if _, err := p.HandleEvent(ctx, "mom", wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidInstanceSelectPrefix + "backup"}); err != nil {
+		t.Fatalf("HandleEvent(select backup) error: %v", err)
+	}
+	if st, _ := state.Get("mom"); st.InstanceID != "main" {
+		t.Fatalf("state = %+v, want forbidden instance rejected", st)
+	}
+}
+
+func TestProvider_Favorite(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"docker": map[string]interface{}{
+					"containers": []map[string]interface{}{
+						{"id": "docker:1", "names": []string{"jellyfin"}, "state": "running"},
+						{"id": "docker:2", "names": []string{"homeassistant"}, "state": "running"},
+					},
+				},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	auth, err := core.NewAuthorizer(core.AuthorizerConfig{
+		Bindings: []core.RoleBinding{{Subjects: []string{"mom"}, Role: core.RoleOperator, Scope: &core.Scope{Containers: []string{"jellyfin"}}}},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+	rec := &recordWeCom{}
+	store := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(store.Close)
+	p := NewProvider(ProviderDeps{
+		WeCom:  rec,
+		Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client()),
+		State:  store,
+		Auth:   auth,
+	})
+
+	ctx := context.Background()
+	const userID = "mom"
+	fav, err := p.ResolveFavorite(ctx, userID, core.Favorite{ID: "f1", ServiceKey: "unraid", Target: "jellyfin"})
+	if err != nil || fav.Name != "jellyfin" || fav.Target != "jellyfin" {
+		t.Fatalf("ResolveFavorite() = %+v, %v", fav, err)
+	}
+	if _, err := p.ResolveFavorite(ctx, userID, core.Favorite{ServiceKey: "unraid", Target: "nextcloud"}); err == nil || !strings.Contains(err.Error(), "未找到容器") {
+		t.Fatalf("ResolveFavorite(nextcloud) error = %v, want not found", err)
+	}
+	if _, err := p.ResolveFavorite(ctx, userID, core.Favorite{ServiceKey: "unraid", Target: "homeassistant"}); err == nil || !strings.Contains(err.Error(), "无权限") {
+		t.Fatalf("ResolveFavorite(homeassistant) error = %v, want out of scope", err)
+	}
+
+	// 从常用打开容器操作卡片，末尾为“取消收藏”。
+	if err := p.OpenFavorite(ctx, userID, fav); err != nil {
+		t.Fatalf("OpenFavorite() error: %v", err)
+	}
+	cards := rec.Cards()
+	_, buttons, ok := wecom.RenderButtonInteractionTextMenu(cards[len(cards)-1].Card)
+	if !ok || buttons[len(buttons)-1] != wecom.FavoriteRemoveButton("f1") {
+		t.Fatalf("container action buttons = %+v, want 取消收藏 last", buttons)
+	}
+	if st, _ := store.Get(userID); st.ServiceKey != "unraid" || st.ContainerName != "jellyfin" {
+		t.Fatalf("state = %+v, want jellyfin selected", st)
+	}
+
+	// 越权的收藏不下发操作卡片。
+	if err := p.OpenFavorite(ctx, userID, core.Favorite{ID: "f2", ServiceKey: "unraid", Target: "homeassistant"}); err != nil {
+		t.Fatalf("OpenFavorite(homeassistant) error: %v", err)
+	}
+	texts := rec.Texts()
+	if got := texts[len(texts)-1].Content; !strings.Contains(got, "无权限") {
+		t.Fatalf("reply = %q, want forbidden", got)
+	}
+	if got := len(rec.Cards()); got != len(cards) {
+		t.Fatalf("cards = %d, want %d (no card for out-of-scope favorite)", got, len(cards))
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal() error: %v", err)
+	}
+	return string(b)
+}
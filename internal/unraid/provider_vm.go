@@ -0,0 +1,255 @@
+package unraid
+
+// provider_vm.go 实现 Unraid 虚拟机列表、选择卡片与电源操作的确认流程。
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+const unraidVMSelectPageSize = 3
+
+// vmActionPermission 返回虚拟机动作权限：unraid.vm.<start|stop|pause|resume|force_stop|reboot|reset>。
+func vmActionPermission(action core.Action) string {
+	return core.ServicePermission("unraid", "vm", strings.TrimPrefix(string(action), "vm_"))
+}
+
+// vmAllowed 校验用户能否对实例中的虚拟机 vmName 执行动作（查看时 action 为空）；未注入授权器时不限制。
+func (p *Provider) vmAllowed(userID string, ins Instance, action core.Action, vmName string) bool {
+	if p.auth == nil {
+		return true
+	}
+	perm := core.ViewPermission(p.Key())
+	if action != "" {
+		perm = vmActionPermission(action)
+	}
+	return p.auth.CanAccess(userID, perm, core.Resource{InstanceID: ins.ID, VM: vmName})
+}
+
+// visibleVMs 过滤出用户有权查看的虚拟机。
+func (p *Provider) visibleVMs(userID string, ins Instance, vms []VMDomain) []VMDomain {
+	out := make([]VMDomain, 0, len(vms))
+	for _, vm := range vms {
+		if p.vmAllowed(userID, ins, "", vm.Name) {
+			out = append(out, vm)
+		}
+	}
+	return out
+}
+
+// sendVMSelectCard 发送虚拟机选择卡片（按钮含状态）。
+func (p *Provider) sendVMSelectCard(ctx context.Context, userID string, ins Instance, page int) error {
+	if ins.Client == nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "获取虚拟机列表失败：unraid client 未配置"})
+	}
+	vms, err := ins.Client.ListVMs(ctx)
+	if err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "获取虚拟机列表失败：" + err.Error()})
+	}
+	vms = p.visibleVMs(userID, ins, vms)
+	if len(vms) == 0 {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未找到任何虚拟机。"})
+	}
+
+	totalPages := (len(vms) + unraidVMSelectPageSize - 1) / unraidVMSelectPageSize
+	page = clampInt(page, 1, totalPages)
+	start := (page - 1) * unraidVMSelectPageSize
+	end := min(start+unraidVMSelectPageSize, len(vms))
+
+	var opts []wecom.UnraidVMOption
+	for _, vm := range vms[start:end] {
+		opts = append(opts, wecom.UnraidVMOption{
+			ID:   vm.ID,
+			Text: truncateRunes(vm.Name, 10) + "（" + vmStateText(vm.State) + "）",
+		})
+	}
+	prevPage, nextPage := 0, 0
+	if page > 1 {
+		prevPage = page - 1
+	}
+	if page < totalPages {
+		nextPage = page + 1
+	}
+
+	instanceName := ""
+	if len(p.order) > 1 {
+		instanceName = ins.Name
+	}
+	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card:   wecom.NewUnraidVMSelectCard(instanceName, page, totalPages, opts, prevPage, nextPage),
+	})
+}
+
+// handleVMPage 处理虚拟机选择卡片的翻页。
+func (p *Provider) handleVMPage(ctx context.Context, userID string, ins Instance, pageStr string) error {
+	page, err := strconv.Atoi(strings.TrimSpace(pageStr))
+	if err != nil || page <= 0 {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "页码不合法，请重新选择。"})
+	}
+	return p.sendVMSelectCard(ctx, userID, ins, page)
+}
+
+// handleVMSelect 记录选中的虚拟机并按当前状态下发操作卡片。
+func (p *Provider) handleVMSelect(ctx context.Context, userID string, ins Instance, id string) error {
+	if ins.Client == nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "查询失败：unraid client 未配置"})
+	}
+	vm, err := ins.Client.FindVM(ctx, id)
+	if err != nil {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "查询失败：" + err.Error()})
+	}
+
+	p.state.Set(userID, core.ConversationState{
+		ServiceKey:   p.Key(),
+		InstanceID:   ins.ID,
+		UnraidVMID:   vm.ID,
+		UnraidVMName: vm.Name,
+	})
+
+	var actions []string
+	for _, action := range vmActionsForState(vm.State) {
+		if p.vmAllowed(userID, ins, action, vm.Name) {
+			actions = append(actions, string(action))
+		}
+	}
+	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card: wecom.NewUnraidVMActionCard(wecom.UnraidVMActionCardOptions{
+			Name:      p.targetLabel(ins, vm.Name),
+			StateText: vmStateText(vm.State),
+			Actions:   actions,
+		}),
+	})
+}
+
+// handleVMAction 处理虚拟机操作卡片按钮：校验权限后进入待确认状态。
+func (p *Provider) handleVMAction(ctx context.Context, userID string, action core.Action) error {
+	state, ok := p.state.Get(userID)
+	if !ok || state.ServiceKey != p.Key() || strings.TrimSpace(state.UnraidVMID) == "" {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "会话已过期，请重新选择虚拟机。"})
+	}
+	ins, ok := p.instanceFromState(userID, state)
+	if !ok {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "实例不可用，请重新选择虚拟机。"})
+	}
+	return p.confirmVM(ctx, userID, ins, action, VMDomain{ID: state.UnraidVMID, Name: state.UnraidVMName})
+}
+
+// confirmVM 校验动作与权限后进入虚拟机操作的待确认状态。
+func (p *Provider) confirmVM(ctx context.Context, userID string, ins Instance, action core.Action, vm VMDomain) error {
+	if !isVMAction(action) {
+		return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "未知动作，请返回后重试。"})
+	}
+	if !p.vmAllowed(userID, ins, action, vm.Name) {
+		return p.wecom.SendText(ctx, wecom.TextMessage{
+			ToUser:  userID,
+			Content: fmt.Sprintf("无权限：虚拟机 %s 不在当前账号的%s授权范围内（%s）。", vm.Name, action.DisplayName(), vmActionPermission(action)),
+		})
+	}
+
+	p.state.Set(userID, core.ConversationState{
+		ServiceKey:   p.Key(),
+		InstanceID:   ins.ID,
+		Step:         core.StepAwaitingConfirm,
+		Action:       action,
+		UnraidVMID:   vm.ID,
+		UnraidVMName: vm.Name,
+	})
+
+	target := p.targetLabel(ins, vm.Name)
+	_ = p.wecom.SendText(ctx, wecom.TextMessage{
+		ToUser:  userID,
+		Content: fmt.Sprintf("确认执行：%s %s\n回复“确认”继续，回复“取消”终止。", action.DisplayName(), target),
+	})
+	return p.wecom.SendTemplateCard(ctx, wecom.TemplateCardMessage{
+		ToUser: userID,
+		Card:   wecom.NewConfirmCard(action.DisplayName(), target),
+	})
+}
+
+// vmAction 构造虚拟机电源操作。
+func (p *Provider) vmAction(ins Instance, action core.Action, vm VMDomain) core.ConfirmedAction {
+	target := p.targetLabel(ins, vm.Name)
+	return core.ConfirmedAction{
+		ServiceKey: p.Key(),
+		InstanceID: ins.ID,
+		Action:     action,
+		Target:     vm.Name,
+		Permission: vmActionPermission(action),
+		Resource:   core.Resource{InstanceID: ins.ID, VM: vm.Name},
+		Run: func(ctx context.Context, _ core.ProgressFunc) (string, error) {
+			if ins.Client == nil {
+				return "", errors.New("unraid client 未配置")
+			}
+			if err := ins.Client.VMAction(ctx, action, vm.ID); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s %s", action.DisplayName(), target), nil
+		},
+	}
+}
+
+// vmCommands 声明虚拟机一次性命令：/unraid vms 查看列表，/unraid vm <动作> <名称> 走确认流程。
+func (p *Provider) vmCommands(instanceArg core.CommandArg) []core.Command {
+	verbs := make([]string, 0, len(vmMutations))
+	for _, action := range []core.Action{
+		core.ActionUnraidVMStart, core.ActionUnraidVMStop, core.ActionUnraidVMPause, core.ActionUnraidVMResume,
+		core.ActionUnraidVMForceStop, core.ActionUnraidVMReboot, core.ActionUnraidVMReset,
+	} {
+		verbs = append(verbs, strings.TrimPrefix(string(action), "vm_"))
+	}
+	return []core.Command{
+		{
+			Path:    "unraid vms",
+			Summary: "查看虚拟机列表与状态",
+			Args:    []core.CommandArg{instanceArg},
+			Handler: func(ctx context.Context, userID string, args core.CommandArgs) error {
+				ins, err := p.commandInstance(userID, args.String("instance"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				if ins.Client == nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "查询失败：unraid client 未配置"})
+				}
+				vms, err := ins.Client.ListVMs(ctx)
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "查询失败：" + err.Error()})
+				}
+				content := formatVMList(p.visibleVMs(userID, ins, vms))
+				if len(p.order) > 1 {
+					content = "[" + ins.Name + "] " + content
+				}
+				return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: content})
+			},
+		},
+		{
+			Path:    "unraid vm",
+			Summary: "虚拟机电源操作（需确认）",
+			Args: []core.CommandArg{
+				{Name: "action", Type: core.ArgChoice, Choices: verbs},
+				{Name: "vm"},
+				instanceArg,
+			},
+			Handler: func(ctx context.Context, userID string, args core.CommandArgs) error {
+				ins, err := p.commandInstance(userID, args.String("instance"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				if ins.Client == nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: "查询失败：unraid client 未配置"})
+				}
+				vm, err := ins.Client.FindVM(ctx, args.String("vm"))
+				if err != nil {
+					return p.wecom.SendText(ctx, wecom.TextMessage{ToUser: userID, Content: err.Error()})
+				}
+				return p.confirmVM(ctx, userID, ins, core.Action("vm_"+args.String("action")), vm)
+			},
+		},
+	}
+}
@@ -0,0 +1,252 @@
+package unraid
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+	"github.com/zcw199604/wecom-home-ops/internal/wecom"
+)
+
+// newVMServer 模拟 vms.domains 查询与 vm.<mutation>，记录调用的 mutation 与虚拟机 ID。
+func newVMServer(t *testing.T, result bool) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if strings.Contains(req.Query, "mutation VMAction") {
+			field := strings.TrimSpace(strings.SplitN(strings.SplitN(req.Query, "vm {", 2)[1], "(", 2)[0])
+			mu.Lock()
+			calls = append(calls, field+" "+req.Variables["id"].(string))
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"vm": map[string]interface{}{field: result}},
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"vms": map[string]interface{}{
+					"domains": []map[string]interface{}{
+						{"id": "vm:aaaa", "name": "win11", "state": "RUNNING"},
+						{"id": "vm:bbbb", "name": "ubuntu", "state": "SHUTOFF"},
+						{"id": "vm:cccc", "name": "hass", "state": "PAUSED"},
+					},
+				},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), calls...)
+	}
+}
+
+func cardButtonKeys(t *testing.T, card wecom.TemplateCard) []string {
+	t.Helper()
+	_, buttons, _ := wecom.RenderButtonInteractionTextMenu(card)
+	var keys []string
+	for _, b := range buttons {
+		keys = append(keys, b.Key)
+	}
+	return keys
+}
+
+func TestProvider_VMFlow(t *testing.T) {
+	t.Parallel()
+
+	srv, calls := newVMServer(t, true)
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{
+		WeCom:  wc,
+		Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client()),
+		State:  state,
+	})
+
+	ctx := context.Background()
+	const userID = "u1"
+
+	if ok, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidMenuVM}); err != nil || !ok {
+		t.Fatalf("HandleEvent(menu vm) ok=%v err=%v", ok, err)
+	}
+	cards := wc.Cards()
+	b := mustJSON(t, cards[len(cards)-1].Card)
+	for _, want := range []string{"选择虚拟机", "hass（已暂停）", "ubuntu（已关闭）", "win11（运行中）", wecom.EventKeyUnraidVMSelectPrefix + "vm:aaaa"} {
+		if !strings.Contains(b, want) {
+			t.Fatalf("vm select card = %s, want %q", b, want)
+		}
+	}
+
+	if _, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidVMSelectPrefix + "vm:aaaa"}); err != nil {
+		t.Fatalf("HandleEvent(select vm) error: %v", err)
+	}
+	cards = wc.Cards()
+	keys := strings.Join(cardButtonKeys(t, cards[len(cards)-1].Card), ",")
+	for _, action := range []string{"vm_stop", "vm_pause", "vm_reboot", "vm_force_stop", "vm_reset"} {
+		if !strings.Contains(keys, wecom.EventKeyUnraidVMActionPrefix+action) {
+			t.Fatalf("running vm buttons = %s, want %s", keys, action)
+		}
+	}
+	if strings.Contains(keys, wecom.EventKeyUnraidVMActionPrefix+"vm_start") {
+		t.Fatalf("running vm buttons = %s, want no start", keys)
+	}
+
+	if _, err := p.HandleEvent(ctx, userID, wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidVMActionPrefix + "vm_force_stop"}); err != nil {
+		t.Fatalf("HandleEvent(force stop) error: %v", err)
+	}
+	st, _ := state.Get(userID)
+	if st.Step != core.StepAwaitingConfirm || st.Action != core.ActionUnraidVMForceStop || st.UnraidVMID != "vm:aaaa" {
+		t.Fatalf("state = %+v, want confirm force stop of win11", st)
+	}
+	if texts := wc.Texts(); !strings.Contains(texts[len(texts)-1].Content, "强制关闭虚拟机 win11") {
+		t.Fatalf("confirm text = %q", texts[len(texts)-1].Content)
+	}
+
+	action, handled, err := p.PrepareConfirm(ctx, userID)
+	if err != nil || !handled || action.RequiredPermission() != "unraid.vm.force_stop" || action.Target != "win11" {
+		t.Fatalf("PrepareConfirm() = %+v handled=%v err=%v", action, handled, err)
+	}
+	if _, err := action.Run(ctx, func(string) {}); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if got := strings.Join(calls(), ","); got != "forceStop vm:aaaa" {
+		t.Fatalf("mutations = %q, want forceStop vm:aaaa", got)
+	}
+
+	if got := p.EventPermission(wecom.EventKeyUnraidVMActionPrefix + "vm_start"); got != "unraid.vm.start" {
+		t.Fatalf("EventPermission(vm_start) = %q, want unraid.vm.start", got)
+	}
+}
+
+func TestProvider_VMCommandAndPermission(t *testing.T) {
+	t.Parallel()
+
+	srv, calls := newVMServer(t, false)
+	auth, err := core.NewAuthorizer(core.AuthorizerConfig{
+		Roles: map[string][]string{"vmstart": {"unraid.view", "unraid.vm.start"}},
+		Bindings: []core.RoleBinding{
+			{Subjects: []string{"kid"}, Role: "vmstart"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{
+		WeCom:  wc,
+		Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client()),
+		State:  state,
+		Auth:   auth,
+	})
+
+	r := core.NewRouter(core.RouterDeps{
+		WeCom:     wc,
+		Auth:      auth,
+		Providers: []core.ServiceProvider{p},
+		State:     state,
+	})
+	ctx := context.Background()
+	send := func(content string) string {
+		t.Helper()
+		if err := r.HandleMessage(ctx, wecom.IncomingMessage{FromUserName: "kid", MsgType: "text", Content: content}); err != nil {
+			t.Fatalf("HandleMessage(%q) error: %v", content, err)
+		}
+		texts := wc.Texts()
+		return texts[len(texts)-1].Content
+	}
+
+	if got := send("/unraid vms"); !strings.Contains(got, "win11：运行中") || !strings.Contains(got, "ubuntu：已关闭") {
+		t.Fatalf("vms reply = %q", got)
+	}
+
+	// 仅有 unraid.vm.start：停止被拒绝，操作卡片仅展示“启动”。
+	if got := send("/unraid vm stop WIN11"); !strings.Contains(got, "无权限") {
+		t.Fatalf("stop reply = %q, want forbidden", got)
+	}
+	if _, err := p.HandleEvent(ctx, "kid", wecom.IncomingMessage{EventKey: wecom.EventKeyUnraidVMSelectPrefix + "vm:aaaa"}); err != nil {
+		t.Fatalf("HandleEvent(select vm) error: %v", err)
+	}
+	cards := wc.Cards()
+	if keys := cardButtonKeys(t, cards[len(cards)-1].Card); len(keys) != 1 || keys[0] != wecom.EventKeyUnraidMenuVM {
+		t.Fatalf("running vm buttons = %v, want only back to list", keys)
+	}
+
+	if got := send("/unraid vm start ubuntu"); !strings.Contains(got, "确认执行：启动虚拟机 ubuntu") {
+		t.Fatalf("start reply = %q, want confirm", got)
+	}
+	// VmMutations 返回 false 视为未生效。
+	if got := send("确认"); !strings.Contains(got, "未生效") {
+		t.Fatalf("confirm reply = %q, want not effective", got)
+	}
+	if got := strings.Join(calls(), ","); got != "start vm:bbbb" {
+		t.Fatalf("mutations = %q, want start vm:bbbb", got)
+	}
+}
+
+func TestProvider_VMScopedBindings(t *testing.T) {
+	t.Parallel()
+
+	srv, calls := newVMServer(t, true)
+	auth, err := core.NewAuthorizer(core.AuthorizerConfig{
+		Bindings: []core.RoleBinding{
+			{Subjects: []string{"mom"}, Role: core.RoleOperator, Scope: &core.Scope{Containers: []string{"jellyfin"}}},
+			{Subjects: []string{"dad"}, Role: core.RoleOperator, Scope: &core.Scope{VMs: []string{"ubuntu"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error: %v", err)
+	}
+	wc := &recordWeCom{}
+	state := core.NewMemoryStateStore(time.Minute)
+	t.Cleanup(state.Close)
+	p := NewProvider(ProviderDeps{WeCom: wc, Client: NewClient(ClientConfig{Endpoint: srv.URL, APIKey: "k"}, srv.Client()), State: state, Auth: auth})
+	r := core.NewRouter(core.RouterDeps{WeCom: wc, Auth: auth, Providers: []core.ServiceProvider{p}, State: state})
+	ctx := context.Background()
+	send := func(userID, content string) string {
+		t.Helper()
+		if err := r.HandleMessage(ctx, wecom.IncomingMessage{FromUserName: userID, MsgType: "text", Content: content}); err != nil {
+			t.Fatalf("HandleMessage(%q) error: %v", content, err)
+		}
+		texts := wc.Texts()
+		return texts[len(texts)-1].Content
+	}
+
+	// 仅限定容器的绑定不覆盖虚拟机。
+	if got := send("mom", "/unraid vm stop win11"); !strings.Contains(got, "无权限") {
+		t.Fatalf("container-scoped stop reply = %q, want forbidden", got)
+	}
+	if got := send("mom", "/unraid vms"); got != "虚拟机：无" {
+		t.Fatalf("container-scoped vms reply = %q", got)
+	}
+
+	if got := send("dad", "/unraid vms"); !strings.Contains(got, "ubuntu") || strings.Contains(got, "win11") {
+		t.Fatalf("vm-scoped vms reply = %q", got)
+	}
+	if got := send("dad", "/unraid vm reset win11"); !strings.Contains(got, "无权限") {
+		t.Fatalf("vm-scoped reset reply = %q, want forbidden", got)
+	}
+	if got := send("dad", "/unraid vm start ubuntu"); !strings.Contains(got, "确认执行") {
+		t.Fatalf("vm-scoped start reply = %q, want confirm", got)
+	}
+	send("dad", "确认")
+	waitFor(t, "vm start", func() bool { return strings.Join(calls(), ",") == "start vm:bbbb" })
+}
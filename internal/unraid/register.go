@@ -0,0 +1,127 @@
+package unraid
+
+// register.go 向服务注册表登记 Unraid 后端（配置段 unraid，支持单台写法与 instances 多台写法），并挂载阵列健康告警与通知转发。
+import (
+	"context"
+	"fmt"
+
+	"github.com/zcw199604/wecom-home-ops/internal/config"
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+	"github.com/zcw199604/wecom-home-ops/internal/registry"
+)
+
+func init() {
+	registry.Register(registry.Factory{
+		Key:         "unraid",
+		DisplayName: "Unraid",
+		Hint:        "unraid.endpoint 与 unraid.api_key（或 unraid.instances）",
+		Order:       10,
+		Enabled: func(cfg config.Config) bool {
+			return len(cfg.Unraid.EffectiveInstances()) > 0
+		},
+		New: newService,
+	})
+}
+
+func newService(deps registry.Deps) (registry.Service, error) {
+	cfg := deps.Config.Unraid
+	var instances []Instance
+	for _, ins := range cfg.EffectiveInstances() {
+		client := NewClient(ClientConfig{
+			Endpoint: ins.Endpoint,
+			APIKey:   ins.APIKey,
+			Origin:   ins.Origin,
+
+			WebGUICommandURL: ins.WebGUICommandURL,
+			WebGUIEventsURL:  ins.WebGUIEventsURL,
+			WebGUICSRFToken:  ins.WebGUICSRFToken,
+			WebGUICookie:     ins.WebGUICookie,
+
+			SubscriptionURL:      ins.SubscriptionURL,
+			SubscriptionProtocol: ins.SubscriptionProtocol,
+
+			LogsField:        cfg.LogsField,
+			LogsTailArg:      cfg.LogsTailArg,
+			LogsPayloadField: cfg.LogsPayloadField,
+
+			StatsField:  cfg.StatsField,
+			StatsFields: cfg.StatsFields,
+
+			ForceUpdateMutation:     cfg.ForceUpdateMutation,
+			ForceUpdateArgName:      cfg.ForceUpdateArgName,
+			ForceUpdateArgType:      cfg.ForceUpdateArgType,
+			ForceUpdateReturnFields: cfg.ForceUpdateReturnFields,
+		}, deps.HTTPClient)
+		instances = append(instances, Instance{
+			ID:     ins.ID,
+			Name:   ins.Name,
+			Client: client,
+		})
+	}
+
+	alerts := NewAlertManager(AlertManagerDeps{
+		WeCom: deps.Notifier,
+		Recipients: func() []string {
+			if deps.Auth == nil {
+				return nil
+			}
+			return deps.Auth.UsersWith(core.ServicePermission("unraid", "alert"))
+		},
+		Instances: instances,
+		Config: AlertConfig{
+			Enabled:           cfg.Alert.Enabled != nil && *cfg.Alert.Enabled,
+			Interval:          cfg.Alert.Interval.ToDuration(),
+			Cooldown:          cfg.Alert.Cooldown.ToDuration(),
+			DiskTempThreshold: cfg.Alert.DiskTempThreshold,
+		},
+	})
+
+	notify := NewNotifyBridge(NotifyBridgeDeps{
+		WeCom: deps.Notifier,
+		Recipients: func(instanceID, permission string) []string {
+			if deps.Auth == nil {
+				return nil
+			}
+			return deps.Auth.UsersWithAccess(permission, core.Resource{InstanceID: instanceID, WholeInstance: true})
+		},
+		Instances: instances,
+		Config: NotifyConfig{
+			Enabled:    cfg.Notify.Enabled != nil && *cfg.Notify.Enabled,
+			Interval:   cfg.Notify.Interval.ToDuration(),
+			Importance: cfg.Notify.Importance,
+		},
+		Store: deps.Store,
+	})
+
+	provider := NewProvider(ProviderDeps{
+		WeCom:     deps.WeCom,
+		Instances: instances,
+		State:     deps.State,
+		Auth:      deps.ResourceAuthorizer(),
+		Alerts:    alerts,
+	})
+
+	return registry.Service{
+		Provider: provider,
+		Health: func(ctx context.Context) error {
+			for _, ins := range instances {
+				if err := ins.Client.Ping(ctx); err != nil {
+					if ins.ID == "" {
+						return err
+					}
+					return fmt.Errorf("%s: %w", ins.ID, err)
+				}
+			}
+			return nil
+		},
+		Start: func() {
+			alerts.Start()
+			notify.Start()
+		},
+		Close: func() {
+			provider.StopFollows()
+			alerts.Close()
+			notify.Close()
+		},
+	}, nil
+}
@@ -0,0 +1,388 @@
+package unraid
+
+// subscription.go 基于 WebSocket 实现 GraphQL 订阅（graphql-transport-ws 与旧版 subscriptions-transport-ws），
+// 断线按指数退避自动重连，并封装 logFile/systemMetricsCpu/systemMetricsMemory/upsUpdates/arraySubscription。
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GraphQL over WebSocket 子协议。
+const (
+	SubprotocolGraphQLTransportWS = "graphql-transport-ws"
+	// SubprotocolGraphQLWS 为旧版 subscriptions-transport-ws 使用的子协议名。
+	SubprotocolGraphQLWS = "graphql-ws"
+)
+
+// subscriptionID 为单连接内的订阅 ID（每个连接只承载一个订阅）。
+const subscriptionID = "1"
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// subscriptionFatalError 标记不应重连的错误（鉴权失败、GraphQL 错误、回调返回错误）。
+type subscriptionFatalError struct {
+	err error
+}
+
+func (e *subscriptionFatalError) Error() string { return e.err.Error() }
+func (e *subscriptionFatalError) Unwrap() error { return e.err }
+
+func deriveSubscriptionURL(endpoint string) string {
+	raw := strings.TrimSpace(endpoint)
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || strings.TrimSpace(u.Host) == "" {
+		return ""
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	case "ws", "wss":
+	default:
+		return ""
+	}
+	return u.String()
+}
+
+// Subscribe 建立 GraphQL 订阅并对每条 data 回调 fn，直到 ctx 取消（返回 ctx.Err()）或服务端 complete（返回 nil）。
+// 连接断开时按指数退避重连；鉴权失败、GraphQL 错误或 fn 返回错误时直接返回该错误。
+func (c *Client) Subscribe(ctx context.Context, query string, variables map[string]interface{}, fn func(data json.RawMessage) error) error {
+	if strings.TrimSpace(c.cfg.SubscriptionURL) == "" {
+		return errors.New("unraid subscription url 未配置")
+	}
+
+	backoff := c.subBackoffMin
+	for {
+		acked, err := c.subscribeOnce(ctx, query, variables, fn)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil {
+			return nil
+		}
+		var fatal *subscriptionFatalError
+		if errors.As(err, &fatal) {
+			return fatal.err
+		}
+		if acked {
+			backoff = c.subBackoffMin
+		}
+
+		slog.Warn("Unraid 订阅连接中断，稍后重连", "url", c.cfg.SubscriptionURL, "retry_in", backoff, "error", err)
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		backoff = min(backoff*2, c.subBackoffMax)
+	}
+}
+
+// subscribeOnce 在单个连接上完成 connection_init → subscribe → next… 流程；acked 表示连接已被服务端接受。
+func (c *Client) subscribeOnce(ctx context.Context, query string, variables map[string]interface{}, fn func(json.RawMessage) error) (acked bool, err error) {
+	protocols := []string{SubprotocolGraphQLTransportWS, SubprotocolGraphQLWS}
+	if p := strings.TrimSpace(c.cfg.SubscriptionProtocol); p != "" {
+		protocols = []string{p}
+	}
+	header := make(http.Header)
+	header.Set("x-api-key", c.cfg.APIKey)
+	if c.cfg.Origin != "" {
+		header.Set("Origin", c.cfg.Origin)
+	}
+
+	ws, err := dialWebSocket(ctx, c.cfg.SubscriptionURL, header, protocols, c.tlsConfig())
+	if err != nil {
+		var he *wsHandshakeError
+		if errors.As(err, &he) && (he.StatusCode == http.StatusUnauthorized || he.StatusCode == http.StatusForbidden) {
+			return false, &subscriptionFatalError{err: err}
+		}
+		return false, err
+	}
+	defer ws.close()
+
+	// 服务端未回显子协议时按首选协议处理。
+	legacy := ws.protocol == SubprotocolGraphQLWS || (ws.protocol == "" && protocols[0] == SubprotocolGraphQLWS)
+	subscribeType, stopType, dataType := "subscribe", "complete", "next"
+	if legacy {
+		subscribeType, stopType, dataType = "start", "stop", "data"
+	}
+
+	// ctx 取消时通知服务端结束订阅并断开连接，以解除阻塞的读取。
+	stop := context.AfterFunc(ctx, func() {
+		_ = ws.writeJSON(wsMessage{ID: subscriptionID, Type: stopType})
+		_ = ws.close()
+	})
+	defer stop()
+
+	if c.subPingInterval > 0 {
+		ws.readTimeout = 2 * c.subPingInterval
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			t := time.NewTicker(c.subPingInterval)
+			defer t.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-t.C:
+					if ws.writeFrame(wsOpPing, nil) != nil {
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	initPayload, _ := json.Marshal(map[string]string{"x-api-key": c.cfg.APIKey})
+	if err := ws.writeJSON(wsMessage{Type: "connection_init", Payload: initPayload}); err != nil {
+		return false, err
+	}
+
+	for !acked {
+		msg, err := readSubscriptionMessage(ws)
+		if err != nil {
+			return false, err
+		}
+		switch msg.Type {
+		case "connection_ack":
+			acked = true
+		case "ping":
+			if err := ws.writeJSON(wsMessage{Type: "pong"}); err != nil {
+				return false, err
+			}
+		case "pong", "ka":
+		case "connection_error":
+			return false, &subscriptionFatalError{err: fmt.Errorf("unraid subscription connection_error: %s", strings.TrimSpace(string(msg.Payload)))}
+		default:
+			return false, fmt.Errorf("unraid subscription: 握手期间收到意外消息 %q", msg.Type)
+		}
+	}
+
+	payload, err := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return true, &subscriptionFatalError{err: err}
+	}
+	if err := ws.writeJSON(wsMessage{ID: subscriptionID, Type: subscribeType, Payload: payload}); err != nil {
+		return true, err
+	}
+
+	for {
+		msg, err := readSubscriptionMessage(ws)
+		if err != nil {
+			return true, err
+		}
+		switch msg.Type {
+		case dataType:
+			if msg.ID != subscriptionID {
+				continue
+			}
+			var resp graphQLResponse
+			if err := json.Unmarshal(msg.Payload, &resp); err != nil {
+				return true, err
+			}
+			if len(resp.Errors) > 0 {
+				return true, &subscriptionFatalError{err: graphQLErrorsErr(resp.Errors)}
+			}
+			if err := fn(resp.Data); err != nil {
+				return true, &subscriptionFatalError{err: err}
+			}
+		case "error":
+			return true, &subscriptionFatalError{err: parseSubscriptionErrorPayload(msg.Payload)}
+		case "complete":
+			return true, nil
+		case "ping":
+			if err := ws.writeJSON(wsMessage{Type: "pong"}); err != nil {
+				return true, err
+			}
+		case "pong", "ka":
+		case "connection_error":
+			return true, &subscriptionFatalError{err: fmt.Errorf("unraid subscription connection_error: %s", strings.TrimSpace(string(msg.Payload)))}
+		}
+	}
+}
+
+// readSubscriptionMessage 读取一条协议消息；鉴权类 close 状态码（4400/4401/4403）视为不可重连。
+func readSubscriptionMessage(ws *wsConn) (wsMessage, error) {
+	b, err := ws.readMessage()
+	if err != nil {
+		var ce *wsCloseError
+		if errors.As(err, &ce) && (ce.Code == 4400 || ce.Code == 4401 || ce.Code == 4403) {
+			return wsMessage{}, &subscriptionFatalError{err: err}
+		}
+		return wsMessage{}, err
+	}
+	var msg wsMessage
+	if err := json.Unmarshal(b, &msg); err != nil {
+		return wsMessage{}, fmt.Errorf("unraid subscription: 无法解析消息: %w", err)
+	}
+	return msg, nil
+}
+
+// parseSubscriptionErrorPayload 兼容 graphql-transport-ws（错误数组）与旧协议（单个错误对象）的 error 负载。
+func parseSubscriptionErrorPayload(payload json.RawMessage) error {
+	var list []graphQLError
+	if err := json.Unmarshal(payload, &list); err == nil {
+		return graphQLErrorsErr(list)
+	}
+	var one graphQLError
+	if err := json.Unmarshal(payload, &one); err == nil {
+		return graphQLErrorsErr([]graphQLError{one})
+	}
+	return graphQLErrorsErr(nil)
+}
+
+// tlsConfig 复用 HTTP 客户端的 TLS 配置（如自签证书场景的 InsecureSkipVerify/RootCAs）。
+func (c *Client) tlsConfig() *tls.Config {
+	if c.httpClient == nil {
+		return nil
+	}
+	if t, ok := c.httpClient.Transport.(*http.Transport); ok {
+		return t.TLSClientConfig
+	}
+	return nil
+}
+
+// LogFileChunk 为 logFile 返回的日志内容：查询时为指定的一段，订阅时为文件变化后推送的新增内容。
+type LogFileChunk struct {
+	Path       string
+	Content    string
+	TotalLines int
+	StartLine  int
+}
+
+// CPUUtilization 为 systemMetricsCpu 订阅推送的 CPU 占用。
+type CPUUtilization struct {
+	PercentTotal float64
+	PerCPU       []CPUCoreLoad
+}
+
+// MemoryUtilization 为 systemMetricsMemory 订阅推送的内存占用（字节）。
+type MemoryUtilization struct {
+	Total         int64
+	Used          int64
+	Free          int64
+	Available     int64
+	PercentTotal  float64
+	SwapTotal     int64
+	SwapUsed      int64
+	PercentSwap   float64
+	UsedEffective int64
+}
+
+// SubscribeLogFile 订阅日志文件（Subscription.logFile）。
+func (c *Client) SubscribeLogFile(ctx context.Context, path string, fn func(LogFileChunk) error) error {
+	const q = `subscription LogFile($path: String!) { logFile(path: $path) { ` + logFileContentFields + ` } }`
+	return c.Subscribe(ctx, q, map[string]interface{}{"path": path}, func(data json.RawMessage) error {
+		var resp struct {
+			LogFile logFileContentResp `json:"logFile"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		return fn(resp.LogFile.convert())
+	})
+}
+
+// SubscribeCPU 订阅 CPU 占用（Subscription.systemMetricsCpu）。
+func (c *Client) SubscribeCPU(ctx context.Context, fn func(CPUUtilization) error) error {
+	const q = `subscription { systemMetricsCpu { percentTotal cpus { ` + cpuLoadFields + ` } } }`
+	return c.Subscribe(ctx, q, nil, func(data json.RawMessage) error {
+		var resp struct {
+			SystemMetricsCPU struct {
+				PercentTotal float64       `json:"percentTotal"`
+				CPUs         []cpuLoadResp `json:"cpus"`
+			} `json:"systemMetricsCpu"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		return fn(CPUUtilization{
+			PercentTotal: resp.SystemMetricsCPU.PercentTotal,
+			PerCPU:       convertCPUCores(resp.SystemMetricsCPU.CPUs),
+		})
+	})
+}
+
+// SubscribeMemory 订阅内存占用（Subscription.systemMetricsMemory）。
+func (c *Client) SubscribeMemory(ctx context.Context, fn func(MemoryUtilization) error) error {
+	const q = `subscription { systemMetricsMemory { total used free available percentTotal swapTotal swapUsed percentSwapTotal } }`
+	return c.Subscribe(ctx, q, nil, func(data json.RawMessage) error {
+		var resp struct {
+			Memory struct {
+				Total            bigIntString `json:"total"`
+				Used             bigIntString `json:"used"`
+				Free             bigIntString `json:"free"`
+				Available        bigIntString `json:"available"`
+				PercentTotal     float64      `json:"percentTotal"`
+				SwapTotal        bigIntString `json:"swapTotal"`
+				SwapUsed         bigIntString `json:"swapUsed"`
+				PercentSwapTotal float64      `json:"percentSwapTotal"`
+			} `json:"systemMetricsMemory"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		m := resp.Memory
+		out := MemoryUtilization{
+			Total:        parseInt64FromBigIntString(m.Total),
+			Used:         parseInt64FromBigIntString(m.Used),
+			Free:         parseInt64FromBigIntString(m.Free),
+			Available:    parseInt64FromBigIntString(m.Available),
+			PercentTotal: m.PercentTotal,
+			SwapTotal:    parseInt64FromBigIntString(m.SwapTotal),
+			SwapUsed:     parseInt64FromBigIntString(m.SwapUsed),
+			PercentSwap:  m.PercentSwapTotal,
+		}
+		if out.Total > 0 && out.Available >= 0 && out.Available <= out.Total {
+			out.UsedEffective = out.Total - out.Available
+		}
+		return fn(out)
+	})
+}
+
+// SubscribeUPS 订阅 UPS 状态变化（Subscription.upsUpdates）。
+func (c *Client) SubscribeUPS(ctx context.Context, fn func(UPSDeviceMetrics) error) error {
+	const q = `subscription { upsUpdates { id name model status battery { chargeLevel estimatedRuntime health } power { inputVoltage outputVoltage loadPercentage } } }`
+	return c.Subscribe(ctx, q, nil, func(data json.RawMessage) error {
+		var resp struct {
+			UPSUpdates UPSDeviceMetrics `json:"upsUpdates"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		return fn(resp.UPSUpdates)
+	})
+}
+
+// SubscribeArray 订阅阵列状态变化（Subscription.arraySubscription）；推送不含 SMART 状态。
+func (c *Client) SubscribeArray(ctx context.Context, fn func(ArrayStatus) error) error {
+	const q = `subscription { arraySubscription { ` + arrayFields + ` } }`
+	return c.Subscribe(ctx, q, nil, func(data json.RawMessage) error {
+		var resp struct {
+			ArraySubscription arrayResp `json:"arraySubscription"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		return fn(resp.ArraySubscription.convert())
+	})
+}
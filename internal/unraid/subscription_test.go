@@ -0,0 +1,254 @@
+package unraid
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// wsTestConn 为服务端一侧的 WebSocket 连接（发送不掩码帧）。
+type wsTestConn struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// read 读取下一条协议消息；连接关闭（close 帧/EOF）时返回空消息。
+func (c *wsTestConn) read() wsMessage {
+	c.t.Helper()
+	for {
+		_, op, payload, err := readWSFrame(c.br)
+		if err != nil || op == wsOpClose {
+			return wsMessage{}
+		}
+		if op != wsOpText {
+			continue
+		}
+		var msg wsMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			c.t.Errorf("server decode %q: %v", payload, err)
+		}
+		return msg
+	}
+}
+
+func (c *wsTestConn) send(msgType, id string, payload interface{}) {
+	c.t.Helper()
+	msg := wsMessage{ID: id, Type: msgType}
+	if payload != nil {
+		msg.Payload = json.RawMessage(mustJSON(c.t, payload))
+	}
+	b, _ := json.Marshal(msg)
+	if err := writeWSFrame(c.conn, wsOpText, b, false); err != nil {
+		c.t.Errorf("server write: %v", err)
+	}
+}
+
+// ack 读取 connection_init 并回复 connection_ack，返回随后的 subscribe/start 消息。
+func (c *wsTestConn) ack() wsMessage {
+	c.t.Helper()
+	init := c.read()
+	if init.Type != "connection_init" || !strings.Contains(string(init.Payload), `"x-api-key":"k"`) {
+		c.t.Errorf("connection_init = %+v", init)
+	}
+	c.send("connection_ack", "", nil)
+	return c.read()
+}
+
+// newWSServer 启动 WebSocket 测试桩：按 protocols 顺序选择客户端声明的子协议，每个连接调用一次 serve。
+func newWSServer(t *testing.T, protocols []string, serve func(c *wsTestConn)) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offered := strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",")
+		for i := range offered {
+			offered[i] = strings.TrimSpace(offered[i])
+		}
+		chosen := ""
+		for _, p := range protocols {
+			if containsString(offered, p) {
+				chosen = p
+				break
+			}
+		}
+		if chosen == "" || r.Header.Get("x-api-key") != "k" {
+			http.Error(w, "unsupported", http.StatusBadRequest)
+			return
+		}
+
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Hijack() error: %v", err)
+			return
+		}
+		defer conn.Close()
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + wsAcceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n" +
+			"Sec-WebSocket-Protocol: " + chosen + "\r\n\r\n")
+		_ = brw.Flush()
+		serve(&wsTestConn{t: t, conn: conn, br: brw.Reader})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newSubscriptionClient(srv *httptest.Server) *Client {
+	c := NewClient(ClientConfig{Endpoint: srv.URL + "/graphql", APIKey: "k"}, srv.Client())
+	c.subBackoffMin = 10 * time.Millisecond
+	c.subBackoffMax = 20 * time.Millisecond
+	return c
+}
+
+func TestSubscribeCPU_TransportWSReconnect(t *testing.T) {
+	t.Parallel()
+
+	var conns atomic.Int32
+	completed := make(chan string, 1)
+	srv := newWSServer(t, []string{SubprotocolGraphQLTransportWS}, func(c *wsTestConn) {
+		n := conns.Add(1)
+		sub := c.ack()
+		if sub.Type != "subscribe" || !strings.Contains(string(sub.Payload), "systemMetricsCpu") {
+			t.Errorf("subscribe = %+v", sub)
+		}
+		c.send("ping", "", nil)
+		if pong := c.read(); pong.Type != "pong" {
+			t.Errorf("pong = %+v", pong)
+		}
+		c.send("next", sub.ID, map[string]interface{}{
+			"data": map[string]interface{}{"systemMetricsCpu": map[string]interface{}{
+				"percentTotal": float64(n) * 10, "cpus": []map[string]interface{}{{"percentTotal": 5}},
+			}},
+		})
+		if n == 1 {
+			// 首个连接推送一条后异常断开，客户端应重连。
+			return
+		}
+		if msg := c.read(); msg.Type == "complete" {
+			completed <- msg.ID
+		}
+	})
+
+	client := newSubscriptionClient(srv)
+	if got := client.cfg.SubscriptionURL; !strings.HasPrefix(got, "ws://") || !strings.HasSuffix(got, "/graphql") {
+		t.Fatalf("SubscriptionURL = %q", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var got []CPUUtilization
+	err := client.SubscribeCPU(ctx, func(u CPUUtilization) error {
+		got = append(got, u)
+		if len(got) == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("SubscribeCPU() error = %v, want context.Canceled", err)
+	}
+	if len(got) != 2 || got[0].PercentTotal != 10 || got[1].PercentTotal != 20 || len(got[1].PerCPU) != 1 {
+		t.Fatalf("events = %+v", got)
+	}
+	if n := conns.Load(); n != 2 {
+		t.Fatalf("connections = %d, want 2", n)
+	}
+	select {
+	case id := <-completed:
+		if id != subscriptionID {
+			t.Fatalf("complete id = %q", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("server did not receive complete after cancel")
+	}
+}
+
+func TestSubscribeLogFile_LegacyProtocol(t *testing.T) {
+	t.Parallel()
+
+	srv := newWSServer(t, []string{SubprotocolGraphQLWS}, func(c *wsTestConn) {
+		start := c.ack()
+		var req graphQLRequest
+		_ = json.Unmarshal(start.Payload, &req)
+		if start.Type != "start" || req.Variables["path"] != "/var/log/syslog" {
+			t.Errorf("start = %+v", start)
+		}
+		c.send("ka", "", nil)
+		for i, line := range []string{"line 1\n", "line 2\n"} {
+			c.send("data", start.ID, map[string]interface{}{
+				"data": map[string]interface{}{"logFile": map[string]interface{}{
+					"path": "/var/log/syslog", "content": line, "totalLines": i + 1, "startLine": i + 1,
+				}},
+			})
+		}
+		c.send("complete", start.ID, nil)
+	})
+
+	var chunks []LogFileChunk
+	err := newSubscriptionClient(srv).SubscribeLogFile(context.Background(), "/var/log/syslog", func(ch LogFileChunk) error {
+		chunks = append(chunks, ch)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SubscribeLogFile() error: %v", err)
+	}
+	if len(chunks) != 2 || chunks[1].Content != "line 2\n" || chunks[1].StartLine != 2 || chunks[0].Path != "/var/log/syslog" {
+		t.Fatalf("chunks = %+v", chunks)
+	}
+}
+
+func TestSubscribe_FatalErrorsDoNotReconnect(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	conns := map[string]int{}
+	srv := newWSServer(t, []string{SubprotocolGraphQLTransportWS}, func(c *wsTestConn) {
+		sub := c.ack()
+		var req graphQLRequest
+		_ = json.Unmarshal(sub.Payload, &req)
+		mu.Lock()
+		conns[req.Query]++
+		mu.Unlock()
+		switch {
+		case strings.Contains(req.Query, "arraySubscription"):
+			c.send("error", sub.ID, []map[string]interface{}{{"message": "Cannot query field arraySubscription"}})
+		default:
+			c.send("next", sub.ID, map[string]interface{}{
+				"data": map[string]interface{}{"upsUpdates": map[string]interface{}{"id": "ups1", "status": "ONBATT"}},
+			})
+		}
+		c.read()
+	})
+	client := newSubscriptionClient(srv)
+	ctx := context.Background()
+
+	err := client.SubscribeArray(ctx, func(ArrayStatus) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "Cannot query field arraySubscription") {
+		t.Fatalf("SubscribeArray() error = %v", err)
+	}
+
+	stopErr := errors.New("stop")
+	var ups UPSDeviceMetrics
+	if err := client.SubscribeUPS(ctx, func(d UPSDeviceMetrics) error { ups = d; return stopErr }); !errors.Is(err, stopErr) {
+		t.Fatalf("SubscribeUPS() error = %v, want callback error", err)
+	}
+	if ups.ID != "ups1" || ups.Status != "ONBATT" {
+		t.Fatalf("ups = %+v", ups)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for q, n := range conns {
+		if n != 1 {
+			t.Fatalf("connections for %q = %d, want 1", q, n)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package unraid
+
+// vm.go 封装 Unraid 虚拟机（Query.vms.domains / Mutation.vm）的查询与电源操作。
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zcw199604/wecom-home-ops/internal/core"
+)
+
+// VMDomain 为 Unraid 虚拟机（libvirt domain）。
+type VMDomain struct {
+	ID    string
+	Name  string
+	State string
+}
+
+// VM 状态（VmState 枚举）。
+const (
+	VMStateRunning     = "RUNNING"
+	VMStateIdle        = "IDLE"
+	VMStatePaused      = "PAUSED"
+	VMStateShutdown    = "SHUTDOWN"
+	VMStateShutoff     = "SHUTOFF"
+	VMStateCrashed     = "CRASHED"
+	VMStatePMSuspended = "PMSUSPENDED"
+	VMStateNoState     = "NOSTATE"
+)
+
+// vmMutations 为虚拟机动作对应的 VmMutations 字段。
+var vmMutations = map[core.Action]string{
+	core.ActionUnraidVMStart:     "start",
+	core.ActionUnraidVMStop:      "stop",
+	core.ActionUnraidVMPause:     "pause",
+	core.ActionUnraidVMResume:    "resume",
+	core.ActionUnraidVMForceStop: "forceStop",
+	core.ActionUnraidVMReboot:    "reboot",
+	core.ActionUnraidVMReset:     "reset",
+}
+
+// ListVMs 返回全部虚拟机（按名称排序）。
+func (c *Client) ListVMs(ctx context.Context) ([]VMDomain, error) {
+	const q = `query { vms { domains { id name state } } }`
+	var resp struct {
+		VMs struct {
+			Domains []struct {
+				ID    string `json:"id"`
+				Name  string `json:"name"`
+				State string `json:"state"`
+			} `json:"domains"`
+		} `json:"vms"`
+	}
+	if err := c.do(ctx, q, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	vms := make([]VMDomain, 0, len(resp.VMs.Domains))
+	for _, d := range resp.VMs.Domains {
+		id := strings.TrimSpace(d.ID)
+		if id == "" {
+			continue
+		}
+		name := strings.TrimSpace(d.Name)
+		if name == "" {
+			name = id
+		}
+		vms = append(vms, VMDomain{ID: id, Name: name, State: strings.ToUpper(strings.TrimSpace(d.State))})
+	}
+	sort.SliceStable(vms, func(i, j int) bool { return vms[i].Name < vms[j].Name })
+	return vms, nil
+}
+
+// FindVM 按 ID 或名称（忽略大小写）查找虚拟机。
+func (c *Client) FindVM(ctx context.Context, idOrName string) (VMDomain, error) {
+	vms, err := c.ListVMs(ctx)
+	if err != nil {
+		return VMDomain{}, err
+	}
+	want := strings.TrimSpace(idOrName)
+	for _, vm := range vms {
+		if vm.ID == want || strings.EqualFold(vm.Name, want) {
+			return vm, nil
+		}
+	}
+	return VMDomain{}, fmt.Errorf("未找到虚拟机：%s", want)
+}
+
+// VMAction 对虚拟机执行电源操作；VmMutations 返回 false 时视为未生效。
+func (c *Client) VMAction(ctx context.Context, action core.Action, id string) error {
+	field, ok := vmMutations[action]
+	if !ok {
+		return fmt.Errorf("未知虚拟机动作: %s", action)
+	}
+	q := fmt.Sprintf(`mutation VMAction($id: PrefixedID!) { vm { %s(id: $id) } }`, field)
+	var resp struct {
+		VM map[string]bool `json:"vm"`
+	}
+	if err := c.do(ctx, q, map[string]interface{}{"id": id}, &resp); err != nil {
+		return err
+	}
+	if !resp.VM[field] {
+		return fmt.Errorf("虚拟机%s未生效（vm.%s 返回 false）", action.DisplayName(), field)
+	}
+	return nil
+}
+
+// vmActionsForState 返回当前状态下可执行的虚拟机动作（用于操作卡片按钮）。
+func vmActionsForState(state string) []core.Action {
+	switch strings.ToUpper(state) {
+	case VMStateRunning, VMStateIdle:
+		return []core.Action{core.ActionUnraidVMStop, core.ActionUnraidVMPause, core.ActionUnraidVMReboot, core.ActionUnraidVMForceStop, core.ActionUnraidVMReset}
+	case VMStatePaused, VMStatePMSuspended:
+		return []core.Action{core.ActionUnraidVMResume, core.ActionUnraidVMForceStop}
+	case VMStateCrashed:
+		return []core.Action{core.ActionUnraidVMStart, core.ActionUnraidVMForceStop}
+	default:
+		return []core.Action{core.ActionUnraidVMStart}
+	}
+}
+
+// vmStateText 返回虚拟机状态的中文描述。
+func vmStateText(state string) string {
+	switch strings.ToUpper(state) {
+	case VMStateRunning:
+		return "运行中"
+	case VMStateIdle:
+		return "空闲"
+	case VMStatePaused:
+		return "已暂停"
+	case VMStatePMSuspended:
+		return "已休眠"
+	case VMStateShutdown:
+		return "关机中"
+	case VMStateShutoff:
+		return "已关闭"
+	case VMStateCrashed:
+		return "已崩溃"
+	default:
+		return "未知"
+	}
+}
+
+func isVMAction(action core.Action) bool {
+	_, ok := vmMutations[action]
+	return ok
+}
+
+// formatVMList 渲染虚拟机列表（名称 + 状态）。
+func formatVMList(vms []VMDomain) string {
+	if len(vms) == 0 {
+		return "虚拟机：无"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "虚拟机（%d）", len(vms))
+	for _, vm := range vms {
+		fmt.Fprintf(&b, "\n- %s：%s", vm.Name, vmStateText(vm.State))
+	}
+	return b.String()
+}
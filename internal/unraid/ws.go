@@ -0,0 +1,381 @@
+package unraid
+
+// ws.go 实现 GraphQL 订阅所需的最小 WebSocket（RFC 6455）客户端：握手、掩码文本帧、分片重组与 ping/pong/close 控制帧。
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	wsOpContinuation byte = 0x0
+	wsOpText         byte = 0x1
+	wsOpBinary       byte = 0x2
+	wsOpClose        byte = 0x8
+	wsOpPing         byte = 0x9
+	wsOpPong         byte = 0xA
+)
+
+// wsMaxMessageSize 限制单条消息大小（logFile 订阅可能推送较大的日志片段）。
+const wsMaxMessageSize = 16 << 20
+
+// wsCloseError 为对端发送的 close 帧（状态码与原因）。
+type wsCloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *wsCloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket closed: %d", e.Code)
+	}
+	return fmt.Sprintf("websocket closed: %d %s", e.Code, e.Reason)
+}
+
+// wsHandshakeError 为握手阶段的非 101 响应。
+type wsHandshakeError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *wsHandshakeError) Error() string {
+	return fmt.Sprintf("websocket handshake http status %d: %s", e.StatusCode, e.Body)
+}
+
+type wsConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	// mask 为 true 时发送帧使用掩码（客户端必须掩码，服务端不得掩码）。
+	mask bool
+	// readTimeout 大于 0 时，每次读帧前刷新读超时（配合定时 ping 检测半开连接）。
+	readTimeout time.Duration
+	// protocol 为握手协商出的子协议。
+	protocol string
+
+	wmu       sync.Mutex
+	closeOnce sync.Once
+}
+
+// dialWebSocket 发起 WebSocket 握手并校验 Sec-WebSocket-Accept；protocols 按优先级声明子协议。
+func dialWebSocket(ctx context.Context, rawURL string, header http.Header, protocols []string, tlsConfig *tls.Config) (*wsConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	addr := u.Host
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			addr = net.JoinHostPort(u.Hostname(), "80")
+		}
+	case "wss":
+		if u.Port() == "" {
+			addr = net.JoinHostPort(u.Hostname(), "443")
+		}
+	default:
+		return nil, fmt.Errorf("websocket url scheme 不支持: %q", u.Scheme)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	// 握手期间 ctx 取消时立即中断阻塞的读写。
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if u.Scheme == "wss" {
+		cfg := &tls.Config{}
+		if tlsConfig != nil {
+			cfg = tlsConfig.Clone()
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName = u.Hostname()
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	ws, err := wsClientHandshake(conn, u, header, protocols)
+	if err != nil {
+		_ = conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	if !stop() {
+		_ = conn.Close()
+		return nil, ctx.Err()
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return ws, nil
+}
+
+func wsClientHandshake(conn net.Conn, u *url.URL, header http.Header, protocols []string) (*wsConn, error) {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	for k, v := range header {
+		req.Header[k] = append([]string(nil), v...)
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if len(protocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(protocols, ", "))
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 4<<10))
+		_ = res.Body.Close()
+		return nil, &wsHandshakeError{StatusCode: res.StatusCode, Body: strings.TrimSpace(string(b))}
+	}
+	if !strings.EqualFold(res.Header.Get("Upgrade"), "websocket") ||
+		!headerContainsToken(res.Header, "Connection", "upgrade") {
+		return nil, errors.New("websocket handshake: 缺少 Upgrade 响应头")
+	}
+	if res.Header.Get("Sec-WebSocket-Accept") != wsAcceptKey(key) {
+		return nil, errors.New("websocket handshake: Sec-WebSocket-Accept 不匹配")
+	}
+
+	protocol := strings.TrimSpace(res.Header.Get("Sec-WebSocket-Protocol"))
+	if protocol != "" && !containsString(protocols, protocol) {
+		return nil, fmt.Errorf("websocket handshake: 服务端选择了未声明的子协议 %q", protocol)
+	}
+	return &wsConn{conn: conn, br: br, mask: true, protocol: protocol}, nil
+}
+
+func wsAcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// readMessage 读取一条完整的数据消息：自动应答 ping、忽略 pong、重组分片；收到 close 帧时回应并返回 *wsCloseError。
+func (c *wsConn) readMessage() ([]byte, error) {
+	var msg []byte
+	fragmented := false
+	for {
+		if c.readTimeout > 0 {
+			_ = c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+		}
+		fin, op, payload, err := readWSFrame(c.br)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			ce := &wsCloseError{Code: 1005}
+			if len(payload) >= 2 {
+				ce.Code = int(binary.BigEndian.Uint16(payload[:2]))
+				ce.Reason = string(payload[2:])
+			}
+			_ = c.writeFrame(wsOpClose, payload[:min(len(payload), 2)])
+			return nil, ce
+		case wsOpText, wsOpBinary:
+			if fragmented {
+				return nil, errors.New("websocket: 分片消息未结束即收到新消息")
+			}
+			msg = payload
+		case wsOpContinuation:
+			if !fragmented {
+				return nil, errors.New("websocket: 意外的 continuation 帧")
+			}
+			msg = append(msg, payload...)
+		default:
+			return nil, fmt.Errorf("websocket: 未知 opcode %#x", op)
+		}
+
+		if len(msg) > wsMaxMessageSize {
+			return nil, errors.New("websocket: 消息超过大小上限")
+		}
+		if fin {
+			return msg, nil
+		}
+		fragmented = true
+	}
+}
+
+func (c *wsConn) writeFrame(op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return writeWSFrame(c.conn, op, payload, c.mask)
+}
+
+func (c *wsConn) writeJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(wsOpText, b)
+}
+
+// close 发送正常关闭帧后断开连接（不等待对端回应），可重复调用。
+func (c *wsConn) close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		_ = c.writeFrame(wsOpClose, []byte{0x03, 0xE8})
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// readWSFrame 读取一个帧；掩码帧自动解掩码（服务端测试桩同样复用）。
+func readWSFrame(r io.Reader) (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(r, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = head[0]&0x80 != 0
+	op = head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	n := uint64(head[1] & 0x7F)
+
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > wsMaxMessageSize {
+		return false, 0, nil, errors.New("websocket: 帧超过大小上限")
+	}
+	if op >= wsOpClose && (n > 125 || !fin) {
+		return false, 0, nil, errors.New("websocket: 非法控制帧")
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err = io.ReadFull(r, key[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= key[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+// writeWSFrame 以单帧（FIN=1）写出 payload；mask 为 true 时使用随机掩码。
+func writeWSFrame(w io.Writer, op byte, payload []byte, mask bool) error {
+	buf := make([]byte, 0, 14+len(payload))
+	buf = append(buf, 0x80|op)
+
+	var maskBit byte
+	if mask {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xFFFF:
+		buf = append(buf, maskBit|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, maskBit|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+
+	if !mask {
+		buf = append(buf, payload...)
+		_, err := w.Write(buf)
+		return err
+	}
+	var key [4]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return err
+	}
+	buf = append(buf, key[:]...)
+	start := len(buf)
+	buf = append(buf, payload...)
+	for i := range payload {
+		buf[start+i] ^= key[i%4]
+	}
+	_, err := w.Write(buf)
+	return err
+}
@@ -10,12 +10,14 @@ import (
 	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 )
 
 type CallbackDeps struct {
-	Crypto  *Crypto
-	Core    MessageHandler
-	Deduper *Deduper
+	Crypto *Crypto
+	Core   MessageHandler
+	// Deduper 可选：内存实现 *Deduper 或持久化实现 *FileDeduper。
+	Deduper CallbackDeduper
 	// MaxBodyBytes 限制回调请求体大小，避免恶意超大 body 导致内存/CPU 被占满。默认 1MiB。
 	MaxBodyBytes int64
 }
@@ -173,7 +175,12 @@ func NewCallbackHandler(deps CallbackDeps) http.Handler {
 		key := callbackDedupeKey(msg, plain)
 		if deps.Deduper != nil && key != "" {
 			if deps.Deduper.SeenOrMark(key) {
+				// 重试幂等应答：不再执行业务逻辑，直接按首次处理结果返回 success（业务回复已通过应用消息发送）。
+				first, _ := deps.Deduper.Outcome(key)
 				slog.Info("wecom callback 重复消息已忽略",
+					"first_status", string(first.Status),
+					"first_error", first.Error,
+					"first_handled_at", first.HandledAt,
 					"user_id", strings.TrimSpace(msg.FromUserName),
 					"msg_type", strings.TrimSpace(msg.MsgType),
 					"event", strings.TrimSpace(msg.Event),
@@ -188,7 +195,16 @@ func NewCallbackHandler(deps CallbackDeps) http.Handler {
 			}
 		}
 
-		if err := deps.Core.HandleMessage(r.Context(), msg); err != nil {
+		err = deps.Core.HandleMessage(r.Context(), msg)
+		if deps.Deduper != nil && key != "" {
+			outcome := DedupeOutcome{Status: DedupeStatusDone, HandledAt: time.Now()}
+			if err != nil {
+				outcome.Status = DedupeStatusFailed
+				outcome.Error = err.Error()
+			}
+			deps.Deduper.RecordOutcome(key, outcome)
+		}
+		if err != nil {
 			slog.Error("wecom callback 处理失败（不触发重试）",
 				"error", err,
 				"user_id", strings.TrimSpace(msg.FromUserName),
@@ -7,10 +7,13 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/store"
 )
 
 type testCoreHandler struct {
@@ -130,6 +133,64 @@ func TestCallbackHandler_DedupByMsgID(t *testing.T) {
 	}
 }
 
+func TestCallbackHandler_FileDeduper_RetryAfterRestartIsIdempotent(t *testing.T) {
+	t.Parallel()
+
+	token := "test-token"
+	crypto := mustTestCrypto(t, token, "ww123")
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+
+	plain := []byte("<xml>" +
+		"<ToUserName><![CDATA[to]]></ToUserName>" +
+		"<FromUserName><![CDATA[user]]></FromUserName>" +
+		"<CreateTime>1700000000</CreateTime>" +
+		"<MsgType><![CDATA[text]]></MsgType>" +
+		"<Content><![CDATA[确认]]></Content>" +
+		"<MsgId>777</MsgId>" +
+		"</xml>")
+	encrypted := mustEncrypt(t, crypto, plain)
+	timestamp := "1700000001"
+	nonce := "nonce"
+	sig := signature(token, timestamp, nonce, encrypted)
+	body := []byte("<xml>" +
+		"<ToUserName><![CDATA[to]]></ToUserName>" +
+		"<Encrypt><![CDATA[" + encrypted + "]]></Encrypt>" +
+		"</xml>")
+	send := func(h http.Handler) {
+		req := httptest.NewRequest(http.MethodPost, "/wecom/callback?msg_signature="+sig+"&timestamp="+timestamp+"&nonce="+nonce, bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Body.String() != "success" {
+			t.Fatalf("status/body = %d/%q, want 200/success", w.Code, w.Body.String())
+		}
+	}
+
+	kv, err := store.Open(path)
+	if err != nil {
+		t.Fatalf("store.Open() error: %v", err)
+	}
+	core := &testCoreHandler{err: errors.New("boom")}
+	send(NewCallbackHandler(CallbackDeps{Crypto: crypto, Core: core, Deduper: NewFileDeduper(kv, 10*time.Minute)}))
+	_ = kv.Close()
+
+	// 模拟服务重启：重新打开存储后收到企业微信重试。
+	kv2, err := store.Open(path)
+	if err != nil {
+		t.Fatalf("store.Open(reload) error: %v", err)
+	}
+	t.Cleanup(func() { _ = kv2.Close() })
+	deduper := NewFileDeduper(kv2, 10*time.Minute)
+	send(NewCallbackHandler(CallbackDeps{Crypto: crypto, Core: core, Deduper: deduper}))
+
+	if got := core.Calls(); got != 1 {
+		t.Fatalf("core calls = %d, want 1", got)
+	}
+	o, ok := deduper.Outcome(callbackDedupeKey(IncomingMessage{FromUserName: "user", MsgID: "777"}, nil))
+	if !ok || o.Status != DedupeStatusFailed || o.Error != "boom" {
+		t.Fatalf("Outcome() = %+v, %v; want failed/boom", o, ok)
+	}
+}
+
 func TestCallbackHandler_DedupByTaskID(t *testing.T) {
 	t.Parallel()
 
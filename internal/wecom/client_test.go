@@ -302,7 +302,11 @@ func TestClient_CreateMenu_RequestShape(t *testing.T) {
 		Secret:     "sec",
 	}, srv.Client())
 
-	if err := c.CreateMenu(context.Background(), DefaultMenu()); err != nil {
+	menu := Menu{Buttons: []MenuButton{{
+		Name:       "常用",
+		SubButtons: []MenuButton{{Type: "click", Name: "操作菜单", Key: EventKeyCoreMenu}},
+	}}}
+	if err := c.CreateMenu(context.Background(), menu); err != nil {
 		t.Fatalf("CreateMenu() error: %v", err)
 	}
 	select {
@@ -0,0 +1,139 @@
+package wecom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ListDepartmentUserIDs 返回部门成员的 UserID（fetchChild=true 时递归子部门）。
+// 需在企业微信后台为应用开启通讯录读取权限，且部门处于应用可见范围内。
+//
+// 官方文档（SSOT）：获取部门成员
+// https://developer.work.weixin.qq.com/document/path/90200
+func (c *Client) ListDepartmentUserIDs(ctx context.Context, departmentID int, fetchChild bool) ([]string, error) {
+	q := url.Values{}
+	q.Set("department_id", strconv.Itoa(departmentID))
+	if fetchChild {
+		q.Set("fetch_child", "1")
+	}
+
+	var out struct {
+		UserList []struct {
+			UserID string `json:"userid"`
+		} `json:"userlist"`
+	}
+	if err := c.getJSON(ctx, "user/simplelist", q, &out); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(out.UserList))
+	for _, u := range out.UserList {
+		if u.UserID != "" {
+			ids = append(ids, u.UserID)
+		}
+	}
+	return ids, nil
+}
+
+// GetTagMembers 返回标签下的成员 UserID 与部门 ID（标签可直接包含部门）。
+//
+// 官方文档（SSOT）：获取标签成员
+// https://developer.work.weixin.qq.com/document/path/90213
+func (c *Client) GetTagMembers(ctx context.Context, tagID int) (userIDs []string, departmentIDs []int, err error) {
+	q := url.Values{}
+	q.Set("tagid", strconv.Itoa(tagID))
+
+	var out struct {
+		UserList []struct {
+			UserID string `json:"userid"`
+		} `json:"userlist"`
+		PartyList []int `json:"partylist"`
+	}
+	if err := c.getJSON(ctx, "tag/get", q, &out); err != nil {
+		return nil, nil, err
+	}
+	for _, u := range out.UserList {
+		if u.UserID != "" {
+			userIDs = append(userIDs, u.UserID)
+		}
+	}
+	return userIDs, out.PartyList, nil
+}
+
+// APIError 为企业微信接口返回的业务错误（errcode 非 0）。
+type APIError struct {
+	Code int
+	Msg  string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("wecom api error: %d %s", e.Code, e.Msg)
+}
+
+// getJSON 调用读取类 GET 接口（通讯录、菜单等）并解析响应（errcode 非 0 时返回 *APIError）。
+func (c *Client) getJSON(ctx context.Context, api string, query url.Values, out interface{}) error {
+	start := time.Now()
+
+	token, err := c.getAccessToken(ctx)
+	if err != nil {
+		slog.Error("wecom "+api+" 获取 access_token 失败", "error", err)
+		return err
+	}
+	query.Set("access_token", token)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.APIBaseURL+"/"+api+"?"+query.Encode(), nil)
+	if err != nil {
+		slog.Error("wecom "+api+" 创建请求失败", "error", err)
+		return err
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		slog.Error("wecom "+api+" HTTP 请求失败",
+			"error", err,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+		return err
+	}
+	defer res.Body.Close()
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&raw); err != nil {
+		slog.Error("wecom "+api+" 解析响应失败",
+			"error", err,
+			"status_code", res.StatusCode,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+		return err
+	}
+	var status struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return err
+	}
+
+	attrs := []any{
+		"status_code", res.StatusCode,
+		"duration_ms", time.Since(start).Milliseconds(),
+		"errcode", status.ErrCode,
+		"errmsg", status.ErrMsg,
+	}
+	if status.ErrCode != 0 {
+		apiErr := &APIError{Code: status.ErrCode, Msg: status.ErrMsg}
+		slog.Error("wecom "+api+" 返回错误", append(attrs, "error", apiErr)...)
+		return apiErr
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		slog.Error("wecom "+api+" 解析响应失败", append(attrs, "error", err)...)
+		return err
+	}
+	slog.Debug("wecom "+api+" 成功", attrs...)
+	return nil
+}
@@ -5,13 +5,40 @@ import (
 	"time"
 )
 
+// DedupeStatus 为回调消息首次处理的状态。
+type DedupeStatus string
+
+const (
+	// DedupeStatusProcessing 表示首次处理尚未结束（或处理中进程退出）；重试将被忽略，保证至多执行一次。
+	DedupeStatusProcessing DedupeStatus = "processing"
+	DedupeStatusDone       DedupeStatus = "done"
+	DedupeStatusFailed     DedupeStatus = "failed"
+)
+
+// DedupeOutcome 记录回调消息首次处理的结果，供重试时幂等应答与排障。
+type DedupeOutcome struct {
+	Status    DedupeStatus `json:"status"`
+	Error     string       `json:"error,omitempty"`
+	HandledAt time.Time    `json:"handled_at"`
+}
+
+// CallbackDeduper 为回调去重抽象：SeenOrMark 标记首次处理，RecordOutcome/Outcome 记录并查询首次处理结果。
+type CallbackDeduper interface {
+	// SeenOrMark 如果 key 已存在且未过期，返回 true；否则标记为处理中并返回 false。
+	SeenOrMark(key string) bool
+	RecordOutcome(key string, outcome DedupeOutcome)
+	Outcome(key string) (DedupeOutcome, bool)
+	Close()
+}
+
 // Deduper 用于在短时间窗口内对“同一回调消息”做去重，吸收企业微信重试，避免重复执行业务逻辑。
-// 注意：这是内存去重，服务重启后会丢失。
+// 注意：这是内存去重，服务重启后会丢失；需跨重启去重请使用 FileDeduper。
 type Deduper struct {
 	ttl time.Duration
 
-	mu   sync.Mutex
-	data map[string]time.Time
+	mu       sync.Mutex
+	data     map[string]time.Time
+	outcomes map[string]DedupeOutcome
 
 	stopCh   chan struct{}
 	stopOnce sync.Once
@@ -22,9 +49,10 @@ func NewDeduper(ttl time.Duration) *Deduper {
 		ttl = 10 * time.Minute
 	}
 	d := &Deduper{
-		ttl:    ttl,
-		data:   make(map[string]time.Time),
-		stopCh: make(chan struct{}),
+		ttl:      ttl,
+		data:     make(map[string]time.Time),
+		outcomes: make(map[string]DedupeOutcome),
+		stopCh:   make(chan struct{}),
 	}
 	d.startJanitor(minDuration(ttl, time.Minute))
 	return d
@@ -45,9 +73,38 @@ func (d *Deduper) SeenOrMark(key string) bool {
 		return true
 	}
 	d.data[key] = exp
+	d.outcomes[key] = DedupeOutcome{Status: DedupeStatusProcessing, HandledAt: now}
 	return false
 }
 
+// RecordOutcome 记录首次处理结果（key 未标记或已过期时忽略）。
+func (d *Deduper) RecordOutcome(key string, outcome DedupeOutcome) {
+	if d == nil || key == "" {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.data[key]; !ok {
+		return
+	}
+	d.outcomes[key] = outcome
+}
+
+// Outcome 返回首次处理结果。
+func (d *Deduper) Outcome(key string) (DedupeOutcome, bool) {
+	if d == nil || key == "" {
+		return DedupeOutcome{}, false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t, ok := d.data[key]
+	if !ok || time.Now().After(t) {
+		return DedupeOutcome{}, false
+	}
+	o, ok := d.outcomes[key]
+	return o, ok
+}
+
 func (d *Deduper) Close() {
 	if d == nil {
 		return
@@ -80,6 +137,7 @@ func (d *Deduper) pruneExpired() {
 	for k, v := range d.data {
 		if now.After(v) {
 			delete(d.data, k)
+			delete(d.outcomes, k)
 		}
 	}
 }
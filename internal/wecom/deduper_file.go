@@ -0,0 +1,79 @@
+package wecom
+
+// deduper_file.go 提供基于 store.FileStore 的持久化回调去重，服务重启后仍可吸收企业微信重试。
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/store"
+)
+
+const dedupeBucket = "dedupe"
+
+// FileDeduper 将去重标记与首次处理结果写入共享的持久化存储（bucket=dedupe）。
+// 过期条目由存储自身的 Prune/启动压缩清理；Close 不关闭底层存储。
+type FileDeduper struct {
+	ttl time.Duration
+	kv  *store.FileStore
+}
+
+func NewFileDeduper(kv *store.FileStore, ttl time.Duration) *FileDeduper {
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	return &FileDeduper{ttl: ttl, kv: kv}
+}
+
+func (d *FileDeduper) SeenOrMark(key string) bool {
+	if d == nil || key == "" {
+		return false
+	}
+	now := time.Now()
+	raw, err := json.Marshal(DedupeOutcome{Status: DedupeStatusProcessing, HandledAt: now})
+	if err != nil {
+		return false
+	}
+	_, loaded, err := d.kv.PutIfAbsent(dedupeBucket, key, raw, now.Add(d.ttl))
+	if err != nil {
+		// 持久化失败时仍以内存视图为准（PutIfAbsent 已更新内存），仅记录告警。
+		slog.Warn("回调去重标记持久化失败", "key", key, "error", err)
+	}
+	return loaded
+}
+
+func (d *FileDeduper) RecordOutcome(key string, outcome DedupeOutcome) {
+	if d == nil || key == "" {
+		return
+	}
+	raw, err := json.Marshal(outcome)
+	if err != nil {
+		return
+	}
+	// 过期时间沿用首次标记的窗口，避免结果记录延长去重时长；未标记或已过期时忽略。
+	prev, ok := d.Outcome(key)
+	if !ok {
+		return
+	}
+	exp := prev.HandledAt.Add(d.ttl)
+	if err := d.kv.Put(dedupeBucket, key, raw, exp); err != nil {
+		slog.Warn("回调处理结果持久化失败", "key", key, "error", err)
+	}
+}
+
+func (d *FileDeduper) Outcome(key string) (DedupeOutcome, bool) {
+	if d == nil || key == "" {
+		return DedupeOutcome{}, false
+	}
+	raw, ok := d.kv.Get(dedupeBucket, key)
+	if !ok {
+		return DedupeOutcome{}, false
+	}
+	var o DedupeOutcome
+	if err := json.Unmarshal(raw, &o); err != nil {
+		return DedupeOutcome{}, false
+	}
+	return o, true
+}
+
+func (d *FileDeduper) Close() {}
@@ -0,0 +1,67 @@
+package wecom
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zcw199604/wecom-home-ops/internal/store"
+)
+
+func TestFileDeduper_SurvivesReloadWithOutcome(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+	kv, err := store.Open(path)
+	if err != nil {
+		t.Fatalf("store.Open() error: %v", err)
+	}
+	d := NewFileDeduper(kv, 10*time.Minute)
+
+	if d.SeenOrMark("k") {
+		t.Fatalf("SeenOrMark(1) = true, want false")
+	}
+	if o, ok := d.Outcome("k"); !ok || o.Status != DedupeStatusProcessing {
+		t.Fatalf("Outcome() = %+v, %v; want processing", o, ok)
+	}
+	d.RecordOutcome("k", DedupeOutcome{Status: DedupeStatusFailed, Error: "boom", HandledAt: time.Now()})
+	d.RecordOutcome("unmarked", DedupeOutcome{Status: DedupeStatusDone, HandledAt: time.Now()})
+	_ = kv.Close()
+
+	kv2, err := store.Open(path)
+	if err != nil {
+		t.Fatalf("store.Open(reload) error: %v", err)
+	}
+	t.Cleanup(func() { _ = kv2.Close() })
+	d2 := NewFileDeduper(kv2, 10*time.Minute)
+
+	if !d2.SeenOrMark("k") {
+		t.Fatalf("SeenOrMark(after reload) = false, want true")
+	}
+	o, ok := d2.Outcome("k")
+	if !ok || o.Status != DedupeStatusFailed || o.Error != "boom" {
+		t.Fatalf("Outcome(after reload) = %+v, %v; want failed/boom", o, ok)
+	}
+	if _, ok := d2.Outcome("unmarked"); ok {
+		t.Fatalf("Outcome(unmarked) ok = true, want false")
+	}
+}
+
+func TestFileDeduper_ExpiredKeyCanBeMarkedAgain(t *testing.T) {
+	t.Parallel()
+
+	kv, err := store.Open(filepath.Join(t.TempDir(), "state.jsonl"))
+	if err != nil {
+		t.Fatalf("store.Open() error: %v", err)
+	}
+	t.Cleanup(func() { _ = kv.Close() })
+	d := NewFileDeduper(kv, 10*time.Millisecond)
+
+	if d.SeenOrMark("k") {
+		t.Fatalf("SeenOrMark(1) = true, want false")
+	}
+	time.Sleep(15 * time.Millisecond)
+	if d.SeenOrMark("k") {
+		t.Fatalf("SeenOrMark(after ttl) = true, want false")
+	}
+}
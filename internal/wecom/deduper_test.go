@@ -106,3 +106,24 @@ func TestDeduper_SeenOrMark_ConcurrentSameKey(t *testing.T) {
 		t.Fatalf("falseCount = %d, want 1", falseCount)
 	}
 }
+
+func TestDeduper_RecordOutcome(t *testing.T) {
+	t.Parallel()
+
+	d := NewDeduper(10 * time.Minute)
+	t.Cleanup(d.Close)
+
+	d.RecordOutcome("k", DedupeOutcome{Status: DedupeStatusDone})
+	if _, ok := d.Outcome("k"); ok {
+		t.Fatalf("Outcome(unmarked) ok = true, want false")
+	}
+
+	_ = d.SeenOrMark("k")
+	if o, ok := d.Outcome("k"); !ok || o.Status != DedupeStatusProcessing {
+		t.Fatalf("Outcome() = %+v, %v; want processing", o, ok)
+	}
+	d.RecordOutcome("k", DedupeOutcome{Status: DedupeStatusDone})
+	if o, _ := d.Outcome("k"); o.Status != DedupeStatusDone {
+		t.Fatalf("Outcome().Status = %q, want done", o.Status)
+	}
+}
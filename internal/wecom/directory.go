@@ -0,0 +1,150 @@
+package wecom
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// 通讯录主体前缀：授权配置中可用 department:<部门ID> / tag:<标签ID> 代替具体 UserID。
+const (
+	SubjectDepartmentPrefix = "department:"
+	SubjectTagPrefix        = "tag:"
+)
+
+// ParseContactSubject 解析 department:<id> / tag:<id>；kind 为 "department" 或 "tag"。
+func ParseContactSubject(subject string) (kind string, id int, ok bool) {
+	s := strings.ToLower(strings.TrimSpace(subject))
+	for _, prefix := range []string{SubjectDepartmentPrefix, SubjectTagPrefix} {
+		if !strings.HasPrefix(s, prefix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(s, prefix)))
+		if err != nil || n <= 0 {
+			return "", 0, false
+		}
+		return strings.TrimSuffix(prefix, ":"), n, true
+	}
+	return "", 0, false
+}
+
+// ContactAPI 为 Directory 依赖的通讯录读取接口（由 Client 实现，测试可替换）。
+type ContactAPI interface {
+	ListDepartmentUserIDs(ctx context.Context, departmentID int, fetchChild bool) ([]string, error)
+	GetTagMembers(ctx context.Context, tagID int) (userIDs []string, departmentIDs []int, err error)
+}
+
+type DirectoryDeps struct {
+	Client ContactAPI
+	// TTL 为成员列表缓存时长；过期后下一次查询触发刷新，刷新失败时沿用旧数据。
+	TTL time.Duration
+}
+
+// Directory 将 department:<id> / tag:<id> 解析为成员 UserID，并按 TTL 缓存结果。
+// 部门成员包含子部门；标签成员包含标签内的部门成员。
+type Directory struct {
+	client ContactAPI
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	cache map[string]directoryEntry
+
+	sf singleflight.Group
+}
+
+type directoryEntry struct {
+	members   []string
+	fetchedAt time.Time
+}
+
+func NewDirectory(deps DirectoryDeps) *Directory {
+	ttl := deps.TTL
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	return &Directory{
+		client: deps.Client,
+		ttl:    ttl,
+		cache:  make(map[string]directoryEntry),
+	}
+}
+
+// Members 返回主体的成员 UserID（按字典序）。
+func (d *Directory) Members(ctx context.Context, subject string) ([]string, error) {
+	kind, id, ok := ParseContactSubject(subject)
+	if !ok {
+		return nil, fmt.Errorf("不支持的通讯录主体 %q（示例：department:2、tag:1）", subject)
+	}
+	key := kind + ":" + strconv.Itoa(id)
+
+	d.mu.Lock()
+	entry, cached := d.cache[key]
+	d.mu.Unlock()
+	if cached && time.Since(entry.fetchedAt) < d.ttl {
+		return entry.members, nil
+	}
+
+	v, err, _ := d.sf.Do(key, func() (interface{}, error) {
+		return d.fetch(ctx, kind, id)
+	})
+	if err != nil {
+		if cached {
+			slog.Warn("wecom 通讯录刷新失败，沿用缓存成员", "subject", key, "error", err, "members", len(entry.members))
+			return entry.members, nil
+		}
+		return nil, err
+	}
+	members, _ := v.([]string)
+
+	d.mu.Lock()
+	d.cache[key] = directoryEntry{members: members, fetchedAt: time.Now()}
+	d.mu.Unlock()
+	return members, nil
+}
+
+func (d *Directory) fetch(ctx context.Context, kind string, id int) ([]string, error) {
+	seen := make(map[string]struct{})
+	add := func(ids []string) {
+		for _, u := range ids {
+			if u = strings.TrimSpace(u); u != "" {
+				seen[u] = struct{}{}
+			}
+		}
+	}
+
+	switch kind {
+	case "department":
+		ids, err := d.client.ListDepartmentUserIDs(ctx, id, true)
+		if err != nil {
+			return nil, err
+		}
+		add(ids)
+	case "tag":
+		ids, parties, err := d.client.GetTagMembers(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		add(ids)
+		for _, party := range parties {
+			ids, err := d.client.ListDepartmentUserIDs(ctx, party, true)
+			if err != nil {
+				return nil, err
+			}
+			add(ids)
+		}
+	}
+
+	out := make([]string, 0, len(seen))
+	for u := range seen {
+		out = append(out, u)
+	}
+	sort.Strings(out)
+	return out, nil
+}
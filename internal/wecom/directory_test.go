@@ -0,0 +1,161 @@
+package wecom
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFakeContactServer 模拟企业微信通讯录接口：部门 2 含子部门 3，标签 1 含成员 carol 与部门 3。
+func newFakeContactServer(t *testing.T, failing *atomic.Bool, listHits *int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gettoken" && r.URL.Query().Get("access_token") != "AT" {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"errcode": 40014, "errmsg": "invalid access_token"})
+			return
+		}
+		switch r.URL.Path {
+		case "/gettoken":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"errcode":      0,
+				"errmsg":       "ok",
+				"access_token": "AT",
+				"expires_in":   7200,
+			})
+		case "/user/simplelist":
+			atomic.AddInt32(listHits, 1)
+			if failing.Load() {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"errcode": 60011, "errmsg": "no privilege to access/modify contact/party/agent"})
+				return
+			}
+			var users []map[string]interface{}
+			switch r.URL.Query().Get("department_id") {
+			case "2":
+				if r.URL.Query().Get("fetch_child") != "1" {
+					t.Errorf("fetch_child = %q, want 1", r.URL.Query().Get("fetch_child"))
+				}
+				users = []map[string]interface{}{{"userid": "bob"}, {"userid": "alice"}}
+			case "3":
+				users = []map[string]interface{}{{"userid": "dave"}}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"errcode": 0, "errmsg": "ok", "userlist": users})
+		case "/tag/get":
+			if r.URL.Query().Get("tagid") != "1" {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"errcode": 40068, "errmsg": "invalid tagid"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"errcode":   0,
+				"errmsg":    "ok",
+				"tagname":   "ops",
+				"userlist":  []map[string]interface{}{{"userid": "carol", "name": "Carol"}},
+				"partylist": []int{3},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDirectory_MembersAndCache(t *testing.T) {
+	t.Parallel()
+
+	var failing atomic.Bool
+	var listHits int32
+	srv := newFakeContactServer(t, &failing, &listHits)
+
+	client := NewClient(ClientConfig{APIBaseURL: srv.URL, CorpID: "ww", AgentID: 1, Secret: "sec"}, srv.Client())
+	dir := NewDirectory(DirectoryDeps{Client: client, TTL: time.Hour})
+	ctx := context.Background()
+
+	got, err := dir.Members(ctx, "department:2")
+	if err != nil {
+		t.Fatalf("Members(department:2) error: %v", err)
+	}
+	if want := []string{"alice", "bob"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Members(department:2) = %v, want %v", got, want)
+	}
+	if _, err := dir.Members(ctx, "Department:2"); err != nil {
+		t.Fatalf("Members(Department:2) error: %v", err)
+	}
+	if hits := atomic.LoadInt32(&listHits); hits != 1 {
+		t.Fatalf("simplelist hits = %d, want 1 (cached)", hits)
+	}
+
+	got, err = dir.Members(ctx, "tag:1")
+	if err != nil {
+		t.Fatalf("Members(tag:1) error: %v", err)
+	}
+	if want := []string{"carol", "dave"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Members(tag:1) = %v, want %v", got, want)
+	}
+
+	if _, err := dir.Members(ctx, "tag:9"); err == nil {
+		t.Fatalf("Members(tag:9) error = nil, want api error")
+	}
+	if _, err := dir.Members(ctx, "bob"); err == nil {
+		t.Fatalf("Members(bob) error = nil, want not nil")
+	}
+}
+
+func TestDirectory_RefreshFailureKeepsStaleMembers(t *testing.T) {
+	t.Parallel()
+
+	var failing atomic.Bool
+	var listHits int32
+	srv := newFakeContactServer(t, &failing, &listHits)
+
+	client := NewClient(ClientConfig{APIBaseURL: srv.URL, CorpID: "ww", AgentID: 1, Secret: "sec"}, srv.Client())
+	dir := NewDirectory(DirectoryDeps{Client: client, TTL: time.Millisecond})
+	ctx := context.Background()
+
+	if _, err := dir.Members(ctx, "department:2"); err != nil {
+		t.Fatalf("Members() error: %v", err)
+	}
+	failing.Store(true)
+	time.Sleep(5 * time.Millisecond)
+
+	got, err := dir.Members(ctx, "department:2")
+	if err != nil {
+		t.Fatalf("Members(stale) error: %v", err)
+	}
+	if want := []string{"alice", "bob"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Members(stale) = %v, want %v", got, want)
+	}
+	if hits := atomic.LoadInt32(&listHits); hits != 2 {
+		t.Fatalf("simplelist hits = %d, want 2 (refresh attempted)", hits)
+	}
+	if _, err := dir.Members(ctx, "department:3"); err == nil {
+		t.Fatalf("Members(uncached, failing) error = nil, want not nil")
+	}
+}
+
+func TestParseContactSubject(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		kind string
+		id   int
+		ok   bool
+	}{
+		{"department:2", "department", 2, true},
+		{" TAG:15 ", "tag", 15, true},
+		{"department:0", "", 0, false},
+		{"tag:x", "", 0, false},
+		{"zhangsan", "", 0, false},
+	}
+	for _, tc := range tests {
+		kind, id, ok := ParseContactSubject(tc.in)
+		if kind != tc.kind || id != tc.id || ok != tc.ok {
+			t.Fatalf("ParseContactSubject(%q) = %q,%d,%v; want %q,%d,%v", tc.in, kind, id, ok, tc.kind, tc.id, tc.ok)
+		}
+	}
+}
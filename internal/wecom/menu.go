@@ -0,0 +1,184 @@
+package wecom
+
+// menu.go 定义应用自定义菜单的结构、官方限制校验、读取（menu/get）与差异对比。
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// 官方限制（SSOT：https://developer.work.weixin.qq.com/document/path/90231）。
+const (
+	MaxMenuButtons    = 3
+	MaxMenuSubButtons = 5
+
+	maxMenuNameBytes    = 16
+	maxSubMenuNameBytes = 40
+	maxMenuKeyBytes     = 128
+	maxMenuURLBytes     = 1024
+)
+
+// errCodeMenuNotExist 为 menu/get 在应用尚未设置菜单时返回的错误码。
+const errCodeMenuNotExist = 46003
+
+// Menu 定义企业微信“应用自定义菜单”的请求体。
+//
+// 官方文档（SSOT）：
+// - 创建菜单：https://developer.work.weixin.qq.com/document/path/90231
+// - 获取菜单：https://developer.work.weixin.qq.com/document/path/90232
+// - 删除菜单：https://developer.work.weixin.qq.com/document/path/90233
+type Menu struct {
+	Buttons []MenuButton `json:"button"`
+}
+
+// MenuButton 定义菜单按钮（最多 3 个一级按钮，每个最多 5 个二级按钮）。
+type MenuButton struct {
+	Type string `json:"type,omitempty"`
+	Name string `json:"name"`
+	Key  string `json:"key,omitempty"`
+	URL  string `json:"url,omitempty"`
+
+	SubButtons []MenuButton `json:"sub_button,omitempty"`
+}
+
+// Validate 按官方限制校验菜单：一级 1~3 个、二级最多 5 个，名称/Key/URL 长度，
+// 以及 click 需 key、view 需 url。
+func (m Menu) Validate() error {
+	var problems []string
+	if len(m.Buttons) == 0 || len(m.Buttons) > MaxMenuButtons {
+		problems = append(problems, fmt.Sprintf("一级菜单需为 1~%d 个（当前 %d 个）", MaxMenuButtons, len(m.Buttons)))
+	}
+	for _, b := range m.Buttons {
+		problems = append(problems, b.validate(b.Name, maxMenuNameBytes)...)
+		if len(b.SubButtons) == 0 {
+			continue
+		}
+		if len(b.SubButtons) > MaxMenuSubButtons {
+			problems = append(problems, fmt.Sprintf("%s：二级菜单最多 %d 个（当前 %d 个）", b.Name, MaxMenuSubButtons, len(b.SubButtons)))
+		}
+		for _, sb := range b.SubButtons {
+			if len(sb.SubButtons) > 0 {
+				problems = append(problems, fmt.Sprintf("%s/%s：不支持三级菜单", b.Name, sb.Name))
+			}
+			problems = append(problems, sb.validate(b.Name+"/"+sb.Name, maxSubMenuNameBytes)...)
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New("菜单不合法：" + strings.Join(problems, "；"))
+	}
+	return nil
+}
+
+func (b MenuButton) validate(path string, maxNameBytes int) []string {
+	var problems []string
+	if strings.TrimSpace(b.Name) == "" {
+		problems = append(problems, path+"：名称不能为空")
+	} else if len(b.Name) > maxNameBytes {
+		problems = append(problems, fmt.Sprintf("%s：名称超过 %d 字节", path, maxNameBytes))
+	}
+	if len(b.SubButtons) > 0 {
+		return problems
+	}
+	switch b.Type {
+	case "click":
+		if b.Key == "" || len(b.Key) > maxMenuKeyBytes {
+			problems = append(problems, fmt.Sprintf("%s：click 需填写 key（不超过 %d 字节）", path, maxMenuKeyBytes))
+		}
+	case "view":
+		if b.URL == "" || len(b.URL) > maxMenuURLBytes {
+			problems = append(problems, fmt.Sprintf("%s：view 需填写 url（不超过 %d 字节）", path, maxMenuURLBytes))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("%s：type 不支持 %q（仅支持 click/view）", path, b.Type))
+	}
+	return problems
+}
+
+// GetMenu 读取应用当前的自定义菜单；应用尚未设置菜单时返回空菜单。
+//
+// 官方文档（SSOT）：获取菜单
+// https://developer.work.weixin.qq.com/document/path/90232
+func (c *Client) GetMenu(ctx context.Context) (Menu, error) {
+	q := url.Values{}
+	q.Set("agentid", strconv.Itoa(c.cfg.AgentID))
+
+	var out Menu
+	if err := c.getJSON(ctx, "menu/get", q, &out); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.Code == errCodeMenuNotExist {
+			return Menu{}, nil
+		}
+		return Menu{}, err
+	}
+	return out, nil
+}
+
+// FormatMenu 将菜单展开为逐行文本（一级/二级 + 类型与 key/url），便于展示与对比。
+func FormatMenu(m Menu) []string {
+	var lines []string
+	for _, b := range m.Buttons {
+		lines = append(lines, b.Name+formatMenuAction(b))
+		for _, sb := range b.SubButtons {
+			lines = append(lines, b.Name+" / "+sb.Name+formatMenuAction(sb))
+		}
+	}
+	return lines
+}
+
+func formatMenuAction(b MenuButton) string {
+	switch {
+	case len(b.SubButtons) > 0:
+		return ""
+	case b.Type == "view":
+		return " [view " + b.URL + "]"
+	default:
+		return " [" + b.Type + " " + b.Key + "]"
+	}
+}
+
+// DiffMenu 逐行对比两个菜单（“- ”为将删除，“+ ”为将新增，“  ”为不变）；完全一致时返回 nil。
+func DiffMenu(current, next Menu) []string {
+	a, b := FormatMenu(current), FormatMenu(next)
+
+	// 最长公共子序列：lcs[i][j] 为 a[i:] 与 b[j:] 的公共行数。
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	changed := false
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			out = append(out, "+ "+b[j])
+			j++
+			changed = true
+		default:
+			out = append(out, "- "+a[i])
+			i++
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,109 @@
+package wecom
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMenu_Validate(t *testing.T) {
+	t.Parallel()
+
+	click := MenuButton{Type: "click", Name: "帮助", Key: EventKeyCoreHelp}
+	ok := Menu{Buttons: []MenuButton{
+		{Name: "常用", SubButtons: []MenuButton{click, {Type: "view", Name: "面板", URL: "https://nas.example.com"}}},
+		click,
+	}}
+	if err := ok.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	cases := map[string]Menu{
+		"empty":        {},
+		"too many top": {Buttons: []MenuButton{click, click, click, click}},
+		"too many sub": {Buttons: []MenuButton{{Name: "常用", SubButtons: []MenuButton{click, click, click, click, click, click}}}},
+		"long name":    {Buttons: []MenuButton{{Type: "click", Name: "一二三四五六", Key: "k"}}},
+		"click no key": {Buttons: []MenuButton{{Type: "click", Name: "a"}}},
+		"bad type":     {Buttons: []MenuButton{{Type: "scancode_push", Name: "a", Key: "k"}}},
+	}
+	for name, m := range cases {
+		if err := m.Validate(); err == nil {
+			t.Fatalf("Validate(%s) error = nil, want not nil", name)
+		}
+	}
+}
+
+func TestDiffMenu(t *testing.T) {
+	t.Parallel()
+
+	current := Menu{Buttons: []MenuButton{
+		{Name: "常用", SubButtons: []MenuButton{
+			{Type: "click", Name: "操作菜单", Key: EventKeyCoreMenu},
+			{Type: "click", Name: "PVE", Key: EventKeyServiceSelectPrefix + "pve"},
+		}},
+	}}
+	next := Menu{Buttons: []MenuButton{
+		{Name: "常用", SubButtons: []MenuButton{
+			{Type: "click", Name: "操作菜单", Key: EventKeyCoreMenu},
+			{Type: "click", Name: "我的常用", Key: EventKeyCoreFavorites},
+		}},
+	}}
+
+	if got := DiffMenu(current, current); got != nil {
+		t.Fatalf("DiffMenu(same) = %v, want nil", got)
+	}
+	want := []string{
+		"  常用",
+		"  常用 / 操作菜单 [click core.menu]",
+		"+ 常用 / 我的常用 [click core.favorites]",
+		"- 常用 / PVE [click svc.select.pve]",
+	}
+	if got := DiffMenu(current, next); !reflect.DeepEqual(got, want) {
+		t.Fatalf("DiffMenu() = %q, want %q", got, want)
+	}
+}
+
+func TestClient_GetMenu(t *testing.T) {
+	t.Parallel()
+
+	var missing atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gettoken":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"errcode": 0, "access_token": "AT", "expires_in": 7200})
+		case "/menu/get":
+			if r.URL.Query().Get("agentid") != "1" || r.URL.Query().Get("access_token") != "AT" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if missing.Load() {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"errcode": 46003, "errmsg": "menu no exist"})
+				return
+			}
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","button":[{"name":"常用","sub_button":[{"type":"click","name":"帮助","key":"core.help"}]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewClient(ClientConfig{APIBaseURL: srv.URL, CorpID: "ww", AgentID: 1, Secret: "sec"}, srv.Client())
+	menu, err := c.GetMenu(context.Background())
+	if err != nil {
+		t.Fatalf("GetMenu() error: %v", err)
+	}
+	if got := strings.Join(FormatMenu(menu), "\n"); got != "常用\n常用 / 帮助 [click core.help]" {
+		t.Fatalf("FormatMenu(GetMenu()) = %q", got)
+	}
+
+	missing.Store(true)
+	menu, err = c.GetMenu(context.Background())
+	if err != nil || len(menu.Buttons) != 0 {
+		t.Fatalf("GetMenu(no menu) = %+v, %v, want empty menu", menu, err)
+	}
+}
@@ -28,6 +28,8 @@ const (
 	EventKeyCoreMenu     = "core.menu"
 	EventKeyCoreHelp     = "core.help"
 	EventKeyCoreSelfTest = "core.selftest"
+	// EventKeyCoreFavorites 打开当前用户的“我的常用”卡片。
+	EventKeyCoreFavorites = "core.favorites"
 
 	EventKeyUnraidMenuOps         = "unraid.menu.ops"
 	EventKeyUnraidMenuView        = "unraid.menu.view"
@@ -41,9 +43,42 @@ const (
 
 	EventKeyUnraidViewSystemStatsDetail = "unraid.view.system_stats_detail"
 	EventKeyUnraidViewLogs              = "unraid.view.logs"
+	// EventKeyUnraidViewArray 查看阵列状态与磁盘健康（系统监控卡片）。
+	EventKeyUnraidViewArray = "unraid.view.array"
 
 	EventKeyUnraidContainerSelectPrefix = "unraid.container.select."
 	EventKeyUnraidContainerPagePrefix   = "unraid.container.page."
+	// EventKeyUnraidContainerActionPrefix 为容器操作卡片按钮：后缀为动作（restart/stop/force_update/view_status/view_logs），
+	// 目标为会话中的当前容器。
+	EventKeyUnraidContainerActionPrefix = "unraid.container.action."
+	// EventKeyUnraidInstanceSelectPrefix 为实例选择按钮（后缀为实例 ID）；EventKeyUnraidSwitchInstance 返回实例选择（多台 Unraid 时展示）。
+	EventKeyUnraidInstanceSelectPrefix = "unraid.instance.select."
+	EventKeyUnraidSwitchInstance       = "unraid.menu.switch_instance"
+	// EventKeyUnraidMenuVM 进入虚拟机列表；EventKeyUnraidVMSelectPrefix 后缀为虚拟机 ID，
+	// EventKeyUnraidVMActionPrefix 后缀为动作（vm_start/vm_stop/...），目标为会话中的当前虚拟机。
+	EventKeyUnraidMenuVM         = "unraid.menu.vm"
+	EventKeyUnraidVMSelectPrefix = "unraid.vm.select."
+	EventKeyUnraidVMPagePrefix   = "unraid.vm.page."
+	EventKeyUnraidVMActionPrefix = "unraid.vm.action."
+	// EventKeyUnraidMenuParity 打开校验检查卡片（含当前进度）；EventKeyUnraidParityActionPrefix 后缀为动作（parity_start/parity_pause/...）。
+	EventKeyUnraidMenuParity         = "unraid.menu.parity"
+	EventKeyUnraidParityHistory      = "unraid.parity.history"
+	EventKeyUnraidParityActionPrefix = "unraid.parity.action."
+	// EventKeyUnraidNotifyArchivePrefix 后缀为 <实例ID>:<通知ID>；EventKeyUnraidNotifyArchiveAllPrefix 后缀为 <实例ID>:<重要程度>。
+	EventKeyUnraidNotifyArchivePrefix    = "unraid.notify.archive."
+	EventKeyUnraidNotifyArchiveAllPrefix = "unraid.notify.archive_all."
+	// EventKeyUnraidFollowLogs 进入“跟踪日志”容器选择；EventKeyUnraidFollowStartPrefix 后缀为跟踪时长（分钟），
+	// 目标为会话中的当前容器；EventKeyUnraidFollowStop 停止当前用户进行中的日志跟踪。
+	EventKeyUnraidFollowLogs        = "unraid.view.follow_logs"
+	EventKeyUnraidFollowStartPrefix = "unraid.follow.start."
+	EventKeyUnraidFollowStop        = "unraid.follow.stop"
+	// EventKeyUnraidMenuLogFiles 进入系统日志文件列表；EventKeyUnraidLogFileSelectPrefix 后缀为日志文件路径，
+	// EventKeyUnraidLogFileViewPrefix 后缀为翻页方式（head/tail/older/newer），目标为会话中的当前日志文件。
+	EventKeyUnraidMenuLogFiles        = "unraid.menu.logfiles"
+	EventKeyUnraidLogFileSelectPrefix = "unraid.logfile.select."
+	EventKeyUnraidLogFilePagePrefix   = "unraid.logfile.page."
+	EventKeyUnraidLogFileViewPrefix   = "unraid.logfile.view."
+	EventKeyUnraidLogFileGrep         = "unraid.logfile.grep"
 
 	EventKeyQinglongMenu                 = "qinglong.menu"
 	EventKeyQinglongInstanceSelectPrefix = "qinglong.instance.select."
@@ -60,6 +95,8 @@ const (
 	EventKeyPVEMenu                 = "pve.menu"
 	EventKeyPVEInstanceSelectPrefix = "pve.instance.select."
 	EventKeyPVEGuestSelectPrefix    = "pve.guest.select."
+	// EventKeyPVEGuestActionPrefix 为目标操作卡片按钮：后缀为 <qemu|lxc>.<start|shutdown|reboot|stop>，目标为会话中的当前虚拟机/容器。
+	EventKeyPVEGuestActionPrefix = "pve.guest.action."
 
 	EventKeyPVEActionOverview       = "pve.action.overview"
 	EventKeyPVEActionVMMenu         = "pve.action.vm_menu"
@@ -81,61 +118,19 @@ const (
 
 	EventKeyConfirm = "core.action.confirm"
 	EventKeyCancel  = "core.action.cancel"
-)
-
-// Menu 定义企业微信“应用自定义菜单”的请求体。
-//
-// 官方文档（SSOT）：
-// - 创建菜单：https://developer.work.weixin.qq.com/document/path/90231
-// - 获取菜单：https://developer.work.weixin.qq.com/document/path/90232
-// - 删除菜单：https://developer.work.weixin.qq.com/document/path/90233
-type Menu struct {
-	Buttons []MenuButton `json:"button"`
-}
 
-// MenuButton 定义菜单按钮（最多 3 个一级按钮，每个最多 5 个二级按钮）。
-type MenuButton struct {
-	Type string `json:"type,omitempty"`
-	Name string `json:"name"`
-	Key  string `json:"key,omitempty"`
-	URL  string `json:"url,omitempty"`
+	// 双人审批：后缀为审批单 ID。
+	EventKeyApprovalApprovePrefix = "core.approval.approve."
+	EventKeyApprovalRejectPrefix  = "core.approval.reject."
 
-	SubButtons []MenuButton `json:"sub_button,omitempty"`
-}
+	// 最近操作：后缀为操作记录 ID，点击后重新发起该操作（仍需确认）。
+	EventKeyHistoryRerunPrefix = "core.history.rerun."
 
-// DefaultMenu 返回本项目推荐的“应用自定义菜单”。
-// 该菜单以 CLICK 事件为主，EventKey 与回调路由约定保持一致。
-func DefaultMenu() Menu {
-	return Menu{
-		Buttons: []MenuButton{
-			{
-				Name: "常用",
-				SubButtons: []MenuButton{
-					{Type: "click", Name: "操作菜单", Key: EventKeyCoreMenu},
-					{Type: "click", Name: "PVE", Key: EventKeyServiceSelectPrefix + "pve"},
-					{Type: "click", Name: "自检", Key: EventKeyCoreSelfTest},
-					{Type: "click", Name: "帮助", Key: EventKeyCoreHelp},
-				},
-			},
-			{
-				Name: "Unraid",
-				SubButtons: []MenuButton{
-					{Type: "click", Name: "进入菜单", Key: EventKeyServiceSelectPrefix + "unraid"},
-					{Type: "click", Name: "容器操作", Key: EventKeyUnraidMenuOps},
-					{Type: "click", Name: "容器查看", Key: EventKeyUnraidMenuView},
-					{Type: "click", Name: "系统监控", Key: EventKeyUnraidMenuSystem},
-				},
-			},
-			{
-				Name: "青龙",
-				SubButtons: []MenuButton{
-					{Type: "click", Name: "进入青龙", Key: EventKeyServiceSelectPrefix + "qinglong"},
-					{Type: "click", Name: "动作菜单", Key: EventKeyQinglongMenu},
-				},
-			},
-		},
-	}
-}
+	// 收藏：添加的后缀为 <service>.<instance>.<target>（instance 可为空），打开/取消的后缀为收藏 ID。
+	EventKeyFavoriteAddPrefix    = "core.favorite.add."
+	EventKeyFavoriteOpenPrefix   = "core.favorite.open."
+	EventKeyFavoriteRemovePrefix = "core.favorite.remove."
+)
 
 type TextMessage struct {
 	ToUser  string
@@ -288,13 +283,84 @@ func extractButtonList(card TemplateCard) []TemplateCardButton {
 	return buttons
 }
 
+// FilterTemplateCardButtons 返回仅保留 keep(key)=true 按钮的卡片副本（不修改原卡片）及剩余按钮数。
+// 卡片无 button_list 时原样返回，remaining 为 -1。
+func FilterTemplateCardButtons(card TemplateCard, keep func(key string) bool) (filtered TemplateCard, remaining int) {
+	if card == nil || keep == nil {
+		return card, -1
+	}
+	raw, ok := card["button_list"]
+	if !ok || raw == nil {
+		return card, -1
+	}
+
+	var items []map[string]interface{}
+	switch v := raw.(type) {
+	case []map[string]interface{}:
+		items = v
+	case []interface{}:
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				items = append(items, m)
+			}
+		}
+	default:
+		return card, -1
+	}
+
+	kept := make([]map[string]interface{}, 0, len(items))
+	for _, m := range items {
+		key, _ := m["key"].(string)
+		if keep(strings.TrimSpace(key)) {
+			kept = append(kept, m)
+		}
+	}
+	if len(kept) == len(items) {
+		return card, len(kept)
+	}
+
+	out := make(TemplateCard, len(card))
+	for k, v := range card {
+		out[k] = v
+	}
+	out["button_list"] = kept
+	return out, len(kept)
+}
+
 type ServiceOption struct {
 	Key  string
 	Name string
 }
 
-func NewServiceSelectCard(services []ServiceOption) TemplateCard {
+// FavoriteOption 为“常用”区域中的一个收藏目标。
+type FavoriteOption struct {
+	ID   string
+	Name string
+}
+
+// maxCardButtons 为 button_interaction 卡片的按钮上限。
+const maxCardButtons = 6
+
+// NewServiceSelectCard 构建服务选择卡片；favorites 非空时在顶部展示“常用”区域（收藏目标的直达按钮，
+// 按钮数受卡片上限约束，优先保证服务按钮）。
+func NewServiceSelectCard(favorites []FavoriteOption, services []ServiceOption) TemplateCard {
+	desc := "请选择服务"
 	var buttons []map[string]interface{}
+	var names []string
+	for _, f := range favorites {
+		if strings.TrimSpace(f.ID) == "" || len(buttons)+len(services) >= maxCardButtons {
+			continue
+		}
+		names = append(names, f.Name)
+		buttons = append(buttons, map[string]interface{}{
+			"text":  "★ " + truncateButtonText(f.Name),
+			"style": 2,
+			"key":   EventKeyFavoriteOpenPrefix + strings.TrimSpace(f.ID),
+		})
+	}
+	if len(names) > 0 {
+		desc = "常用：" + strings.Join(names, "、") + "\n或选择服务"
+	}
 	for _, svc := range services {
 		if svc.Key == "" || svc.Name == "" {
 			continue
@@ -310,37 +376,170 @@ func NewServiceSelectCard(services []ServiceOption) TemplateCard {
 		"card_type": "button_interaction",
 		"main_title": map[string]interface{}{
 			"title": "操作菜单",
-			"desc":  "请选择服务",
+			"desc":  desc,
+		},
+		"button_list": buttons,
+	}
+	return applyDefaultSource(card)
+}
+
+// NewFavoritesCard 构建“我的常用”卡片：每个收藏目标一个直达按钮，点击后打开该目标的操作卡片。
+func NewFavoritesCard(favorites []FavoriteOption) TemplateCard {
+	var buttons []map[string]interface{}
+	for _, f := range favorites {
+		if strings.TrimSpace(f.ID) == "" || len(buttons) >= maxCardButtons-1 {
+			continue
+		}
+		buttons = append(buttons, map[string]interface{}{
+			"text":  truncateButtonText(f.Name),
+			"style": 1,
+			"key":   EventKeyFavoriteOpenPrefix + strings.TrimSpace(f.ID),
+		})
+	}
+	buttons = append(buttons, map[string]interface{}{
+		"text":  "操作菜单",
+		"style": 2,
+		"key":   EventKeyCoreMenu,
+	})
+
+	card := TemplateCard{
+		"card_type": "button_interaction",
+		"main_title": map[string]interface{}{
+			"title": "我的常用",
+			"desc":  "点击目标打开操作",
 		},
 		"button_list": buttons,
 	}
 	return applyDefaultSource(card)
 }
 
-func NewUnraidEntryCard() TemplateCard {
+// FavoriteAddButton 返回“收藏”按钮：instanceID 为空表示单实例服务；target 为服务内的目标标识（可含“.”）。
+func FavoriteAddButton(serviceKey, instanceID, target string) TemplateCardButton {
+	return TemplateCardButton{
+		Text: "收藏",
+		Key:  EventKeyFavoriteAddPrefix + serviceKey + "." + instanceID + "." + target,
+	}
+}
+
+// FavoriteRemoveButton 返回“取消收藏”按钮。
+func FavoriteRemoveButton(favoriteID string) TemplateCardButton {
+	return TemplateCardButton{Text: "取消收藏", Key: EventKeyFavoriteRemovePrefix + favoriteID}
+}
+
+// ParseFavoriteAddKey 解析“收藏”按钮的 EventKey。
+func ParseFavoriteAddKey(key string) (serviceKey, instanceID, target string, ok bool) {
+	suffix, ok := strings.CutPrefix(key, EventKeyFavoriteAddPrefix)
+	if !ok {
+		return "", "", "", false
+	}
+	parts := strings.SplitN(suffix, ".", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+// appendButton 在按钮列表末尾追加可选按钮（Key 为空时忽略）。
+func appendButton(buttons []map[string]interface{}, btn TemplateCardButton, style int) []map[string]interface{} {
+	if strings.TrimSpace(btn.Key) == "" {
+		return buttons
+	}
+	return append(buttons, map[string]interface{}{
+		"text":  btn.Text,
+		"style": style,
+		"key":   btn.Key,
+	})
+}
+
+func truncateButtonText(s string) string {
+	if r := []rune(s); len(r) > 16 {
+		return string(r[:15]) + "…"
+	}
+	return s
+}
+
+type UnraidInstanceOption struct {
+	ID   string
+	Name string
+}
+
+func NewUnraidInstanceSelectCard(instances []UnraidInstanceOption) TemplateCard {
+	var buttons []map[string]interface{}
+	for _, ins := range instances {
+		if ins.ID == "" || ins.Name == "" {
+			continue
+		}
+		buttons = append(buttons, map[string]interface{}{
+			"text":  ins.Name,
+			"style": 1,
+			"key":   EventKeyUnraidInstanceSelectPrefix + ins.ID,
+		})
+	}
+
 	card := TemplateCard{
 		"card_type": "button_interaction",
 		"main_title": map[string]interface{}{
 			"title": "Unraid 容器",
-			"desc":  "请选择菜单",
+			"desc":  "请选择实例",
 		},
-		"button_list": []map[string]interface{}{
-			{
-				"text":  "容器操作",
-				"style": 1,
-				"key":   EventKeyUnraidMenuOps,
-			},
-			{
-				"text":  "容器查看",
-				"style": 2,
-				"key":   EventKeyUnraidMenuView,
-			},
-			{
-				"text":  "系统监控",
-				"style": 2,
-				"key":   EventKeyUnraidMenuSystem,
-			},
+		"button_list": buttons,
+	}
+	return applyDefaultSource(card)
+}
+
+// UnraidEntryCardOptions 为 Unraid 入口卡片选项：多台 Unraid 时展示当前实例与“切换实例”。
+type UnraidEntryCardOptions struct {
+	InstanceName       string
+	ShowSwitchInstance bool
+}
+
+func NewUnraidEntryCard(opts UnraidEntryCardOptions) TemplateCard {
+	desc := "请选择菜单"
+	if name := strings.TrimSpace(opts.InstanceName); name != "" {
+		desc = "实例：" + name
+	}
+	buttons := []map[string]interface{}{
+		{
+			"text":  "容器操作",
+			"style": 1,
+			"key":   EventKeyUnraidMenuOps,
 		},
+		{
+			"text":  "容器查看",
+			"style": 2,
+			"key":   EventKeyUnraidMenuView,
+		},
+		{
+			"text":  "系统监控",
+			"style": 2,
+			"key":   EventKeyUnraidMenuSystem,
+		},
+		{
+			"text":  "虚拟机",
+			"style": 2,
+			"key":   EventKeyUnraidMenuVM,
+		},
+		{
+			"text":  "校验检查",
+			"style": 2,
+			"key":   EventKeyUnraidMenuParity,
+		},
+	}
+	if opts.ShowSwitchInstance {
+		buttons = append(buttons, map[string]interface{}{
+			"text":  "切换实例",
+			"style": 2,
+			"key":   EventKeyUnraidSwitchInstance,
+		})
+	}
+
+	card := TemplateCard{
+		"card_type": "button_interaction",
+		"main_title": map[string]interface{}{
+			"title": "Unraid 容器",
+			"desc":  desc,
+		},
+		"button_list": buttons,
 	}
 	return applyDefaultSource(card)
 }
@@ -442,6 +641,376 @@ func NewUnraidContainerSelectCard(actionDisplayName string, page int, totalPages
 	return applyDefaultSource(card)
 }
 
+// NewUnraidContainerActionCard 构建单个容器的操作卡片（由“常用”打开）；fav 为可选的“收藏/取消收藏”按钮。
+func NewUnraidContainerActionCard(containerName string, fav TemplateCardButton) TemplateCard {
+	buttons := []map[string]interface{}{
+		{"text": "重启", "style": 1, "key": EventKeyUnraidContainerActionPrefix + "restart"},
+		{"text": "停止", "style": 2, "key": EventKeyUnraidContainerActionPrefix + "stop"},
+		{"text": "强制更新", "style": 2, "key": EventKeyUnraidContainerActionPrefix + "force_update"},
+		{"text": "查看状态", "style": 1, "key": EventKeyUnraidContainerActionPrefix + "view_status"},
+		{"text": "查看日志", "style": 1, "key": EventKeyUnraidContainerActionPrefix + "view_logs"},
+	}
+	card := TemplateCard{
+		"card_type": "button_interaction",
+		"main_title": map[string]interface{}{
+			"title": "容器操作",
+			"desc":  containerName,
+		},
+		"button_list": appendButton(buttons, fav, 2),
+	}
+	return applyDefaultSource(card)
+}
+
+type UnraidVMOption struct {
+	ID   string
+	Text string
+}
+
+// NewUnraidVMSelectCard 构建虚拟机选择卡片（按钮文本含状态，分页同容器选择）。
+func NewUnraidVMSelectCard(instanceName string, page int, totalPages int, vms []UnraidVMOption, prevPage int, nextPage int) TemplateCard {
+	desc := "请选择虚拟机"
+	if name := strings.TrimSpace(instanceName); name != "" {
+		desc = "实例：" + name
+	}
+	if page > 0 && totalPages > 0 {
+		desc = fmt.Sprintf("%s | %d/%d", desc, page, totalPages)
+	}
+
+	var buttons []map[string]interface{}
+	for _, vm := range vms {
+		id := strings.TrimSpace(vm.ID)
+		if id == "" {
+			continue
+		}
+		text := strings.TrimSpace(vm.Text)
+		if text == "" {
+			text = id
+		}
+		buttons = append(buttons, map[string]interface{}{
+			"text":  text,
+			"style": 1,
+			"key":   EventKeyUnraidVMSelectPrefix + id,
+		})
+	}
+	if prevPage > 0 {
+		buttons = append(buttons, map[string]interface{}{
+			"text":  "上一页",
+			"style": 2,
+			"key":   EventKeyUnraidVMPagePrefix + intToString(prevPage),
+		})
+	}
+	if nextPage > 0 {
+		buttons = append(buttons, map[string]interface{}{
+			"text":  "下一页",
+			"style": 2,
+			"key":   EventKeyUnraidVMPagePrefix + intToString(nextPage),
+		})
+	}
+	buttons = append(buttons, map[string]interface{}{
+		"text":  "返回菜单",
+		"style": 2,
+		"key":   EventKeyUnraidBackToMenu,
+	})
+
+	card := TemplateCard{
+		"card_type": "button_interaction",
+		"main_title": map[string]interface{}{
+			"title": "选择虚拟机",
+			"desc":  desc,
+		},
+		"button_list": buttons,
+	}
+	return applyDefaultSource(card)
+}
+
+// UnraidVMActionCardOptions 为虚拟机操作卡片参数；Actions 为当前状态下可执行的动作（vm_start/vm_stop/...）。
+type UnraidVMActionCardOptions struct {
+	Name      string
+	StateText string
+	Actions   []string
+}
+
+var unraidVMActionText = map[string]string{
+	"vm_start":      "启动",
+	"vm_stop":       "关闭",
+	"vm_pause":      "暂停",
+	"vm_resume":     "恢复",
+	"vm_force_stop": "强制关闭",
+	"vm_reboot":     "重启",
+	"vm_reset":      "重置",
+}
+
+// NewUnraidVMActionCard 构建单个虚拟机的电源操作卡片（最多 5 个动作 + 返回）。
+func NewUnraidVMActionCard(opts UnraidVMActionCardOptions) TemplateCard {
+	desc := "请选择动作"
+	if st := strings.TrimSpace(opts.StateText); st != "" {
+		desc = "状态：" + st
+	}
+
+	var buttons []map[string]interface{}
+	for _, action := range opts.Actions {
+		text, ok := unraidVMActionText[action]
+		if !ok || len(buttons) >= 5 {
+			continue
+		}
+		style := 2
+		if action == "vm_start" || action == "vm_resume" {
+			style = 1
+		}
+		buttons = append(buttons, map[string]interface{}{
+			"text":  text,
+			"style": style,
+			"key":   EventKeyUnraidVMActionPrefix + action,
+		})
+	}
+	buttons = append(buttons, map[string]interface{}{
+		"text":  "虚拟机列表",
+		"style": 1,
+		"key":   EventKeyUnraidMenuVM,
+	})
+
+	card := TemplateCard{
+		"card_type": "button_interaction",
+		"main_title": map[string]interface{}{
+			"title": "虚拟机 " + strings.TrimSpace(opts.Name),
+			"desc":  desc,
+		},
+		"button_list": buttons,
+	}
+	return applyDefaultSource(card)
+}
+
+// UnraidParityCardOptions 为校验检查卡片参数；Actions 为当前状态下可执行的动作（parity_start/parity_pause/...）。
+type UnraidParityCardOptions struct {
+	InstanceName string
+	StatusText   string
+	Actions      []string
+}
+
+var unraidParityActionText = map[string]string{
+	"parity_start":         "开始检查",
+	"parity_start_correct": "检查并修正",
+	"parity_pause":         "暂停",
+	"parity_resume":        "恢复",
+	"parity_cancel":        "取消检查",
+}
+
+// NewUnraidParityCard 构建校验检查卡片：按状态展示开始/暂停/恢复/取消，并提供刷新、历史与返回。
+func NewUnraidParityCard(opts UnraidParityCardOptions) TemplateCard {
+	desc := "状态：" + strings.TrimSpace(opts.StatusText)
+	if name := strings.TrimSpace(opts.InstanceName); name != "" {
+		desc = "实例：" + name + "，" + desc
+	}
+
+	var buttons []map[string]interface{}
+	for _, action := range opts.Actions {
+		text, ok := unraidParityActionText[action]
+		if !ok || len(buttons) >= 3 {
+			continue
+		}
+		style := 2
+		if action == "parity_start" || action == "parity_resume" {
+			style = 1
+		}
+		buttons = append(buttons, map[string]interface{}{
+			"text":  text,
+			"style": style,
+			"key":   EventKeyUnraidParityActionPrefix + action,
+		})
+	}
+	buttons = append(buttons,
+		map[string]interface{}{"text": "刷新", "style": 2, "key": EventKeyUnraidMenuParity},
+		map[string]interface{}{"text": "历史记录", "style": 2, "key": EventKeyUnraidParityHistory},
+		map[string]interface{}{"text": "返回菜单", "style": 1, "key": EventKeyUnraidBackToMenu},
+	)
+
+	card := TemplateCard{
+		"card_type": "button_interaction",
+		"main_title": map[string]interface{}{
+			"title": "Unraid 校验检查",
+			"desc":  desc,
+		},
+		"button_list": buttons,
+	}
+	return applyDefaultSource(card)
+}
+
+// NewUnraidFollowDurationCard 构建“跟踪日志”时长选择卡片（按钮 key 后缀为分钟数）。
+func NewUnraidFollowDurationCard(target string, minutes []int) TemplateCard {
+	var buttons []map[string]interface{}
+	for i, m := range minutes {
+		style := 2
+		if i == 0 {
+			style = 1
+		}
+		buttons = append(buttons, map[string]interface{}{
+			"text":  intToString(m) + " 分钟",
+			"style": style,
+			"key":   EventKeyUnraidFollowStartPrefix + intToString(m),
+		})
+	}
+	buttons = append(buttons, map[string]interface{}{"text": "返回菜单", "style": 1, "key": EventKeyUnraidBackToMenu})
+
+	card := TemplateCard{
+		"card_type": "button_interaction",
+		"main_title": map[string]interface{}{
+			"title": "跟踪日志",
+			"desc":  "目标：" + strings.TrimSpace(target) + "\n请选择跟踪时长",
+		},
+		"button_list": buttons,
+	}
+	return applyDefaultSource(card)
+}
+
+// NewUnraidFollowCard 构建日志跟踪进行中的卡片（提供“停止跟踪”）。
+func NewUnraidFollowCard(target string, minutes int) TemplateCard {
+	card := TemplateCard{
+		"card_type": "button_interaction",
+		"main_title": map[string]interface{}{
+			"title": "正在跟踪日志",
+			"desc":  "目标：" + strings.TrimSpace(target) + "，时长 " + intToString(minutes) + " 分钟",
+		},
+		"button_list": []map[string]interface{}{
+			{"text": "停止跟踪", "style": 2, "key": EventKeyUnraidFollowStop},
+		},
+	}
+	return applyDefaultSource(card)
+}
+
+type UnraidLogFileOption struct {
+	Path string
+	Text string
+}
+
+// NewUnraidLogFileSelectCard 构建系统日志文件选择卡片（按钮文本含文件大小，分页同容器选择）。
+func NewUnraidLogFileSelectCard(instanceName string, page int, totalPages int, files []UnraidLogFileOption, prevPage int, nextPage int) TemplateCard {
+	desc := "请选择日志文件"
+	if name := strings.TrimSpace(instanceName); name != "" {
+		desc = "实例：" + name
+	}
+	if page > 0 && totalPages > 0 {
+		desc = fmt.Sprintf("%s | %d/%d", desc, page, totalPages)
+	}
+
+	var buttons []map[string]interface{}
+	for _, f := range files {
+		p := strings.TrimSpace(f.Path)
+		if p == "" {
+			continue
+		}
+		text := strings.TrimSpace(f.Text)
+		if text == "" {
+			text = p
+		}
+		buttons = append(buttons, map[string]interface{}{
+			"text":  truncateButtonText(text),
+			"style": 1,
+			"key":   EventKeyUnraidLogFileSelectPrefix + p,
+		})
+	}
+	if prevPage > 0 {
+		buttons = append(buttons, map[string]interface{}{
+			"text":  "上一页",
+			"style": 2,
+			"key":   EventKeyUnraidLogFilePagePrefix + intToString(prevPage),
+		})
+	}
+	if nextPage > 0 {
+		buttons = append(buttons, map[string]interface{}{
+			"text":  "下一页",
+			"style": 2,
+			"key":   EventKeyUnraidLogFilePagePrefix + intToString(nextPage),
+		})
+	}
+	buttons = append(buttons, map[string]interface{}{
+		"text":  "返回菜单",
+		"style": 2,
+		"key":   EventKeyUnraidBackToMenu,
+	})
+
+	card := TemplateCard{
+		"card_type": "button_interaction",
+		"main_title": map[string]interface{}{
+			"title": "系统日志",
+			"desc":  desc,
+		},
+		"button_list": buttons,
+	}
+	return applyDefaultSource(card)
+}
+
+// UnraidLogFileCardOptions 为日志文件浏览卡片参数：HasOlder/HasNewer 控制“更早”“更新”翻页按钮。
+type UnraidLogFileCardOptions struct {
+	Path     string
+	Desc     string
+	HasOlder bool
+	HasNewer bool
+}
+
+// NewUnraidLogFileCard 构建日志文件浏览卡片：翻页（更早/更新）、跳转（开头/末尾）、检索与返回文件列表。
+func NewUnraidLogFileCard(opts UnraidLogFileCardOptions) TemplateCard {
+	var buttons []map[string]interface{}
+	if opts.HasOlder {
+		buttons = append(buttons, map[string]interface{}{"text": "更早", "style": 1, "key": EventKeyUnraidLogFileViewPrefix + "older"})
+	}
+	if opts.HasNewer {
+		buttons = append(buttons, map[string]interface{}{"text": "更新", "style": 1, "key": EventKeyUnraidLogFileViewPrefix + "newer"})
+	}
+	buttons = append(buttons,
+		map[string]interface{}{"text": "开头", "style": 2, "key": EventKeyUnraidLogFileViewPrefix + "head"},
+		map[string]interface{}{"text": "末尾", "style": 2, "key": EventKeyUnraidLogFileViewPrefix + "tail"},
+		map[string]interface{}{"text": "检索", "style": 2, "key": EventKeyUnraidLogFileGrep},
+		map[string]interface{}{"text": "文件列表", "style": 2, "key": EventKeyUnraidMenuLogFiles},
+	)
+
+	card := TemplateCard{
+		"card_type": "button_interaction",
+		"main_title": map[string]interface{}{
+			"title": strings.TrimSpace(opts.Path),
+			"desc":  strings.TrimSpace(opts.Desc),
+		},
+		"button_list": buttons,
+	}
+	return applyDefaultSource(card)
+}
+
+// UnraidNotificationCardOptions 为 Unraid 通知卡片参数；ArchiveKey/ArchiveAllKey 为按钮 key 后缀。
+type UnraidNotificationCardOptions struct {
+	Title          string
+	Desc           string
+	Detail         string
+	ImportanceText string
+	ArchiveKey     string
+	ArchiveAllKey  string
+}
+
+// NewUnraidNotificationCard 构建 Unraid 通知卡片：副标题为通知内容，按钮为“归档”与“全部归档<重要程度>”。
+func NewUnraidNotificationCard(opts UnraidNotificationCardOptions) TemplateCard {
+	card := TemplateCard{
+		"card_type": "button_interaction",
+		"main_title": map[string]interface{}{
+			"title": opts.Title,
+			"desc":  opts.Desc,
+		},
+		"button_list": []map[string]interface{}{
+			{
+				"text":  "归档",
+				"style": 1,
+				"key":   EventKeyUnraidNotifyArchivePrefix + opts.ArchiveKey,
+			},
+			{
+				"text":  truncateButtonText("全部归档" + opts.ImportanceText),
+				"style": 2,
+				"key":   EventKeyUnraidNotifyArchiveAllPrefix + opts.ArchiveAllKey,
+			},
+		},
+	}
+	if detail := strings.TrimSpace(opts.Detail); detail != "" {
+		card["sub_title_text"] = detail
+	}
+	return applyDefaultSource(card)
+}
+
 func NewUnraidViewCard() TemplateCard {
 	card := TemplateCard{
 		"card_type": "button_interaction",
@@ -460,6 +1029,11 @@ func NewUnraidViewCard() TemplateCard {
 				"style": 2,
 				"key":   EventKeyUnraidViewLogs,
 			},
+			{
+				"text":  "跟踪日志",
+				"style": 2,
+				"key":   EventKeyUnraidFollowLogs,
+			},
 			{
 				"text":  "系统监控",
 				"style": 1,
@@ -493,6 +1067,16 @@ func NewUnraidSystemCard() TemplateCard {
 				"style": 2,
 				"key":   EventKeyUnraidViewSystemStatsDetail,
 			},
+			{
+				"text":  "阵列状态",
+				"style": 2,
+				"key":   EventKeyUnraidViewArray,
+			},
+			{
+				"text":  "系统日志",
+				"style": 2,
+				"key":   EventKeyUnraidMenuLogFiles,
+			},
 			{
 				"text":  "返回菜单",
 				"style": 1,
@@ -612,7 +1196,8 @@ func NewQinglongCronListCard(title, instanceName string, crons []QinglongCronOpt
 	return applyDefaultSource(card)
 }
 
-func NewQinglongCronActionCard(instanceName string, cronID int, cronName string) TemplateCard {
+// NewQinglongCronActionCard 构建任务操作卡片；fav 为可选的“收藏/取消收藏”按钮（Key 为空时不展示）。
+func NewQinglongCronActionCard(instanceName string, cronID int, cronName string, fav TemplateCardButton) TemplateCard {
 	desc := "请选择动作"
 	if cronName != "" {
 		desc = cronName
@@ -660,6 +1245,7 @@ func NewQinglongCronActionCard(instanceName string, cronID int, cronName string)
 			},
 		},
 	}
+	card["button_list"] = appendButton(card["button_list"].([]map[string]interface{}), fav, 2)
 	return applyDefaultSource(card)
 }
 
@@ -823,6 +1409,49 @@ type PVEGuestOption struct {
 	Node      string
 }
 
+// PVEGuestActionCardOptions 为目标操作卡片参数；Fav 为可选的“收藏/取消收藏”按钮。
+type PVEGuestActionCardOptions struct {
+	InstanceName string
+	GuestType    string
+	VMID         int
+	Name         string
+	Fav          TemplateCardButton
+}
+
+// NewPVEGuestActionCard 构建单个虚拟机/容器的电源操作卡片（由“常用”打开）。
+func NewPVEGuestActionCard(opts PVEGuestActionCardOptions) TemplateCard {
+	kind := "VM"
+	if opts.GuestType == "lxc" {
+		kind = "LXC"
+	}
+	title := fmt.Sprintf("%s %d", kind, opts.VMID)
+	if name := strings.TrimSpace(opts.Name); name != "" {
+		title += " " + name
+	}
+	desc := "请选择动作"
+	if opts.InstanceName != "" {
+		desc = "实例：" + opts.InstanceName
+	}
+
+	prefix := EventKeyPVEGuestActionPrefix + opts.GuestType + "."
+	buttons := []map[string]interface{}{
+		{"text": "启动", "style": 1, "key": prefix + "start"},
+		{"text": "关机", "style": 2, "key": prefix + "shutdown"},
+		{"text": "重启", "style": 2, "key": prefix + "reboot"},
+		{"text": "强制停止", "style": 2, "key": prefix + "stop"},
+		{"text": "返回菜单", "style": 1, "key": EventKeyPVEMenu},
+	}
+	card := TemplateCard{
+		"card_type": "button_interaction",
+		"main_title": map[string]interface{}{
+			"title": title,
+			"desc":  desc,
+		},
+		"button_list": appendButton(buttons, opts.Fav, 2),
+	}
+	return applyDefaultSource(card)
+}
+
 func NewPVEGuestSelectCard(title, instanceName string, guests []PVEGuestOption) TemplateCard {
 	desc := "请选择目标"
 	if strings.TrimSpace(instanceName) != "" {
@@ -863,6 +1492,11 @@ func NewPVEGuestSelectCard(title, instanceName string, guests []PVEGuestOption)
 }
 
 func NewConfirmCard(actionDisplayName, target string) TemplateCard {
+	return NewConfirmCardWithFavorite(actionDisplayName, target, TemplateCardButton{})
+}
+
+// NewConfirmCardWithFavorite 构建带“收藏”按钮的确认卡片（单目标操作时便于顺手收藏该目标）。
+func NewConfirmCardWithFavorite(actionDisplayName, target string, fav TemplateCardButton) TemplateCard {
 	card := TemplateCard{
 		"card_type": "button_interaction",
 		"main_title": map[string]interface{}{
@@ -882,6 +1516,96 @@ func NewConfirmCard(actionDisplayName, target string) TemplateCard {
 			},
 		},
 	}
+	card["button_list"] = appendButton(card["button_list"].([]map[string]interface{}), fav, 1)
+	return applyDefaultSource(card)
+}
+
+// NewBatchConfirmCard 构建批量确认卡片：标题给出动作与数量，副标题列出目标（过长时截断，完整列表由文本提示给出）。
+func NewBatchConfirmCard(actionDisplayName string, targets []string) TemplateCard {
+	sub := strings.Join(targets, "、")
+	if r := []rune(sub); len(r) > 100 {
+		sub = string(r[:99]) + "…"
+	}
+	card := TemplateCard{
+		"card_type": "button_interaction",
+		"main_title": map[string]interface{}{
+			"title": "确认批量执行",
+			"desc":  fmt.Sprintf("%s：共 %d 项", actionDisplayName, len(targets)),
+		},
+		"sub_title_text": sub,
+		"button_list": []map[string]interface{}{
+			{
+				"text":  "确认",
+				"style": 2,
+				"key":   EventKeyConfirm,
+			},
+			{
+				"text":  "取消",
+				"style": 1,
+				"key":   EventKeyCancel,
+			},
+		},
+	}
+	return applyDefaultSource(card)
+}
+
+// NewApprovalCard 构建审批卡片（发送给审批人），deadline 为审批截止时间的展示文本。
+func NewApprovalCard(approvalID, requester, title, deadline string) TemplateCard {
+	card := TemplateCard{
+		"card_type": "button_interaction",
+		"main_title": map[string]interface{}{
+			"title": "待审批 #" + approvalID,
+			"desc":  title,
+		},
+		"sub_title_text": "发起人：" + requester + "\n截止：" + deadline + "（超时自动作废）",
+		"button_list": []map[string]interface{}{
+			{
+				"text":  "批准",
+				"style": 2,
+				"key":   EventKeyApprovalApprovePrefix + approvalID,
+			},
+			{
+				"text":  "驳回",
+				"style": 1,
+				"key":   EventKeyApprovalRejectPrefix + approvalID,
+			},
+		},
+	}
+	return applyDefaultSource(card)
+}
+
+// HistoryOption 为“最近操作”卡片中的一条记录。
+type HistoryOption struct {
+	ID   string
+	Text string
+	Time string
+}
+
+// NewHistoryCard 构建“最近操作”卡片：副标题按时间列出操作，每条对应一个重新发起按钮。
+func NewHistoryCard(entries []HistoryOption) TemplateCard {
+	var lines []string
+	var buttons []map[string]interface{}
+	for i, e := range entries {
+		if strings.TrimSpace(e.ID) == "" {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%d. %s %s", i+1, e.Time, e.Text))
+		buttons = append(buttons, map[string]interface{}{
+			"text":  truncateButtonText(e.Text),
+			"style": 1,
+			"key":   EventKeyHistoryRerunPrefix + strings.TrimSpace(e.ID),
+		})
+	}
+
+	card := TemplateCard{
+		"card_type": "button_interaction",
+		"main_title": map[string]interface{}{
+			"title": "最近操作",
+			"desc":  "点击重新发起（执行前仍需确认）",
+		},
+		"sub_title_text": strings.Join(lines, "\n"),
+		"button_list":    buttons,
+	}
 	return applyDefaultSource(card)
 }
 